@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"os/exec"
 	"strings"
@@ -12,13 +11,18 @@ import (
 
 	"goclaw/internal/chat"
 	"goclaw/internal/config"
+	"goclaw/internal/format"
 	"goclaw/internal/memory"
+	"goclaw/internal/ollama"
 	"goclaw/internal/vector"
 )
 
 // Version info
 const Version = "0.1.0"
 
+// cliFormatter renders assistant and memory output for the terminal
+var cliFormatter format.Formatter = format.PlainFormatter{}
+
 func main() {
 	fmt.Printf("Goclaw v%s\n", Version)
 	fmt.Println("==================")
@@ -59,25 +63,43 @@ func loadConfig() *config.Config {
 }
 
 func initEmbedder(cfg *config.Config) vector.Embedder {
+	// Use the embedding provider declared in config, if any
+	if emb := cfg.Embedding; emb.API != "" {
+		embedder, err := vector.NewEmbedder(emb.API, emb.ApiKey, emb.BaseURL, emb.Model)
+		if err != nil {
+			fmt.Printf("Note: %v, embedding features will be limited\n", err)
+			return nil
+		}
+		return embedder
+	}
+
+	// Otherwise embed with an OpenAI-compatible chat provider, if any
+	if derived, ok := config.ProviderEmbedding(cfg); ok {
+		embedder, err := vector.NewEmbedder(derived.API, derived.ApiKey, derived.BaseURL, derived.Model)
+		if err != nil {
+			fmt.Printf("Note: %v, embedding features will be limited\n", err)
+			return nil
+		}
+		fmt.Printf("Using %s embeddings (%s)\n", derived.API, embedder.GetModelName())
+		return embedder
+	}
+
 	// Check if Ollama is available
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), ollama.DefaultTimeout)
 	defer cancel()
 
-	req, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:11434/api/version", nil)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+	if _, err := ollama.Ping(ctx, ollama.DefaultEndpoint); err != nil {
 		fmt.Println("Note: Ollama not detected, embedding features will be limited")
 		fmt.Println("Run 'ollama serve' to enable local embeddings")
 		return nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		fmt.Println("Connected to Ollama for embeddings")
-		return vector.NewOllamaEmbedder("", "")
+	fmt.Println("Connected to Ollama for embeddings")
+	embedder := vector.NewOllamaEmbedder("", "")
+	if err := ollama.CheckModel(ctx, embedder.Endpoint, embedder.Model); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
-
-	return nil
+	return embedder
 }
 
 func runCLI(embedder vector.Embedder, memStore *memory.MemoryStore, chatMgr *chat.ChatManager, vectorStore vector.VectorStore, cfg *config.Config) {
@@ -134,7 +156,7 @@ func runCLI(embedder vector.Embedder, memStore *memory.MemoryStore, chatMgr *cha
 		// Generate response
 		response := generateResponse(input, contextText, chatMgr, sessionID)
 
-		fmt.Printf("Assistant: %s\n", response)
+		fmt.Println(cliFormatter.FormatChat("assistant", response))
 
 		chatMgr.AddMessage(sessionID, "assistant", response)
 
@@ -190,13 +212,7 @@ func handleCommand(cmd string, embedder vector.Embedder, memStore *memory.Memory
 			return err
 		}
 
-		fmt.Println("\nMemory Search Results:")
-		for _, r := range results {
-			fmt.Printf("  [%.2f] %s\n", r.Score, r.Entry.Content)
-		}
-		if len(results) == 0 {
-			fmt.Println("  No memories found")
-		}
+		fmt.Print("\n" + cliFormatter.FormatMemoryResults(results))
 
 	case "/stats":
 		stats := memStore.Stats()
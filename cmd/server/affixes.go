@@ -0,0 +1,51 @@
+// Package main provides per-request prompt prefixes and suffixes for Goclaw
+package main
+
+import (
+	"strings"
+
+	"goclaw/internal/config"
+)
+
+// Delimiters around injected text, so the model can tell it apart from what
+// the user wrote
+const (
+	promptPrefixOpen  = "<request-prefix>"
+	promptPrefixClose = "</request-prefix>"
+	promptSuffixOpen  = "<request-suffix>"
+	promptSuffixClose = "</request-suffix>"
+)
+
+// promptAffixes is text injected before and after the user's message when the
+// prompt is built. It is never stored in the session history.
+type promptAffixes struct {
+	Prefix string
+	Suffix string
+}
+
+// resolvePromptAffixes picks the affixes for a request: values sent with the
+// request win over agent.promptPrefix and agent.promptSuffix, and an empty
+// value sent with the request turns the default off
+func resolvePromptAffixes(cfg *config.Config, prefix, suffix *string) promptAffixes {
+	affixes := promptAffixes{Prefix: cfg.Agent.PromptPrefix, Suffix: cfg.Agent.PromptSuffix}
+	if prefix != nil {
+		affixes.Prefix = *prefix
+	}
+	if suffix != nil {
+		affixes.Suffix = *suffix
+	}
+	return affixes
+}
+
+// wrap returns the user input with the affixes around it, each in delimiters
+func (a promptAffixes) wrap(input string) string {
+	var sb strings.Builder
+	if prefix := strings.TrimSpace(a.Prefix); prefix != "" {
+		sb.WriteString(promptPrefixOpen + "\n" + prefix + "\n" + promptPrefixClose + "\n")
+	}
+	sb.WriteString("User: " + input + "\n")
+	if suffix := strings.TrimSpace(a.Suffix); suffix != "" {
+		sb.WriteString(promptSuffixOpen + "\n" + suffix + "\n" + promptSuffixClose + "\n")
+	}
+	return sb.String()
+}
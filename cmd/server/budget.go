@@ -0,0 +1,152 @@
+// Package main provides per-provider context budgets for Goclaw
+package main
+
+import (
+	"fmt"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/pkg/ai"
+)
+
+const (
+	// defaultContextWindow is assumed when a provider does not declare one
+	defaultContextWindow = 8192
+
+	// defaultMaxTokens is the response reservation when a provider does not declare one
+	defaultMaxTokens = 2048
+
+	// contextSafetyMargin is extra headroom for the system prompt and formatting
+	contextSafetyMargin = 512
+)
+
+// contextBudget describes how many prompt tokens are available for a model
+type contextBudget struct {
+	Provider  string `json:"provider,omitempty"`
+	Window    int    `json:"window"`
+	MaxTokens int    `json:"maxTokens"`
+	Budget    int    `json:"budget"`              // Window - MaxTokens - contextSafetyMargin, capped by MaxPrompt
+	MaxPrompt int    `json:"maxPrompt,omitempty"` // agent.maxPromptTokens hard limit, 0 for none
+}
+
+// promptTooLargeError is returned when an assembled prompt exceeds MaxPrompt
+type promptTooLargeError struct {
+	Tokens int
+	Limit  int
+}
+
+func (e *promptTooLargeError) Error() string {
+	return fmt.Sprintf("prompt is too large: about %d tokens, limit is %d", e.Tokens, e.Limit)
+}
+
+// checkPrompt guards an assembled prompt against the hard MaxPrompt limit
+func (b contextBudget) checkPrompt(prompt string) error {
+	if b.MaxPrompt <= 0 {
+		return nil
+	}
+	if tokens := estimateTokens(prompt); tokens > b.MaxPrompt {
+		return &promptTooLargeError{Tokens: tokens, Limit: b.MaxPrompt}
+	}
+	return nil
+}
+
+// resolveContextBudget looks up the context window and response size for the
+// provider serving model in models.providers. The entry whose id is model
+// takes precedence over provider-level values, which take precedence over
+// defaultContextWindow and defaultMaxTokens.
+func resolveContextBudget(cfg *config.Config, model string) contextBudget {
+	budget := contextBudget{
+		Provider:  ai.ProviderForModel(model),
+		Window:    defaultContextWindow,
+		MaxTokens: defaultMaxTokens,
+	}
+
+	if providerConfig := providerConfigFor(cfg, budget.Provider); providerConfig != nil {
+		applyLimits(&budget, providerConfig)
+
+		if models, ok := providerConfig["models"].([]interface{}); ok {
+			for _, modelItem := range models {
+				modelMap, ok := modelItem.(map[string]interface{})
+				if ok && modelMap["id"] == model {
+					applyLimits(&budget, modelMap)
+					break
+				}
+			}
+		}
+	}
+
+	budget.MaxPrompt = cfg.Agent.MaxPromptTokens
+	budget.fill()
+	return budget
+}
+
+// forParams reserves the session's maxTokens for the response instead of the
+// provider's, when the session sets one
+func (b contextBudget) forParams(params ai.GenerationParams) contextBudget {
+	if params.MaxTokens != nil {
+		b.MaxTokens = *params.MaxTokens
+		b.fill()
+	}
+	return b
+}
+
+// fill computes Budget from Window, MaxTokens and MaxPrompt
+func (budget *contextBudget) fill() {
+	// Keep a minimum budget for memory context, but never more than the window
+	floor := defaultContextBudget
+	if floor > budget.Window {
+		floor = budget.Window
+	}
+	budget.Budget = budget.Window - budget.MaxTokens - contextSafetyMargin
+	if budget.Budget < floor {
+		budget.Budget = floor
+	}
+
+	// Trim to the hard prompt limit; checkPrompt catches what trimming can't
+	if budget.MaxPrompt > 0 && budget.Budget > budget.MaxPrompt {
+		budget.Budget = budget.MaxPrompt
+	}
+}
+
+// providerConfigFor returns the models.providers entry for a provider
+func providerConfigFor(cfg *config.Config, provider string) map[string]interface{} {
+	if provider == "" {
+		return nil
+	}
+	providers, ok := cfg.Models["providers"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	providerConfig, _ := providers[provider].(map[string]interface{})
+	return providerConfig
+}
+
+// applyLimits copies contextWindow and maxTokens from a config map when present
+func applyLimits(budget *contextBudget, values map[string]interface{}) {
+	if window, ok := values["contextWindow"].(float64); ok && window > 0 {
+		budget.Window = int(window)
+	}
+	if maxTokens, ok := values["maxTokens"].(float64); ok && maxTokens > 0 {
+		budget.MaxTokens = int(maxTokens)
+	}
+}
+
+// estimateTokens roughly estimates the token count of text (about 4 characters per token)
+func estimateTokens(text string) int {
+	return (len([]rune(text)) + 3) / 4
+}
+
+// fitHistory returns the most recent messages whose estimated size fits in budget tokens
+func fitHistory(messages []chat.Message, budget int) []chat.Message {
+	used := 0
+	start := len(messages)
+	for start > 0 {
+		cost := estimateTokens(messages[start-1].Content)
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start--
+	}
+	return messages[start:]
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+	"goclaw/internal/vector"
+	"goclaw/pkg/ai"
+)
+
+func newBudgetConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.Models["providers"] = map[string]interface{}{
+		"minimax": map[string]interface{}{
+			"contextWindow": float64(200000),
+			"models": []interface{}{
+				map[string]interface{}{"id": "MiniMax-M2.1", "maxTokens": float64(8192)},
+			},
+		},
+		"qwen": map[string]interface{}{
+			"contextWindow": float64(32000),
+			"maxTokens":     float64(4096),
+			"models": []interface{}{
+				map[string]interface{}{"id": "coder-model"},
+				map[string]interface{}{"id": "qwen-long", "contextWindow": float64(1000000)},
+			},
+		},
+	}
+	return cfg
+}
+
+func TestResolveContextBudget(t *testing.T) {
+	cfg := newBudgetConfig()
+
+	tests := []struct {
+		model    string
+		provider string
+		want     int
+	}{
+		{"MiniMax-M2.1", "minimax", 200000 - 8192 - contextSafetyMargin},
+		{"coder-model", "qwen", 32000 - 4096 - contextSafetyMargin},
+		{"qwen-long", "qwen", 1000000 - 4096 - contextSafetyMargin},
+		// Models without an entry use the provider's limits, not the first model's
+		{"qwen-plus", "qwen", 32000 - 4096 - contextSafetyMargin},
+		{"minimax-text-01", "minimax", 200000 - defaultMaxTokens - contextSafetyMargin},
+		{"glm-4", "zhipu", defaultContextWindow - defaultMaxTokens - contextSafetyMargin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			budget := resolveContextBudget(cfg, tt.model)
+			if budget.Provider != tt.provider {
+				t.Errorf("Provider = %q, want %q", budget.Provider, tt.provider)
+			}
+			if budget.Budget != tt.want {
+				t.Errorf("Budget = %d, want %d", budget.Budget, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveContextBudgetFloor(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Models["providers"] = map[string]interface{}{
+		"minimax": map[string]interface{}{"contextWindow": float64(2048), "maxTokens": float64(2048)},
+	}
+
+	if got := resolveContextBudget(cfg, "MiniMax-M2.1").Budget; got != defaultContextBudget {
+		t.Errorf("Budget = %d, want floor %d", got, defaultContextBudget)
+	}
+}
+
+func TestResolveContextBudgetFloorFitsWindow(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Models["providers"] = map[string]interface{}{
+		"minimax": map[string]interface{}{"contextWindow": float64(300), "maxTokens": float64(100)},
+	}
+
+	if got := resolveContextBudget(cfg, "MiniMax-M2.1").Budget; got != 300 {
+		t.Errorf("Budget = %d, want the floor clamped to the 300-token window", got)
+	}
+}
+
+func TestContextBudgetUsesSessionMaxTokens(t *testing.T) {
+	base := resolveContextBudget(newBudgetConfig(), "MiniMax-M2.1")
+
+	maxTokens := 50000
+	budget := base.forParams(ai.GenerationParams{MaxTokens: &maxTokens})
+	if budget.MaxTokens != maxTokens || budget.Budget != 200000-maxTokens-contextSafetyMargin {
+		t.Errorf("budget = %+v, want the session's maxTokens reserved", budget)
+	}
+
+	if got := base.forParams(ai.GenerationParams{}); got != base {
+		t.Errorf("budget without a session maxTokens = %+v, want %+v", got, base)
+	}
+}
+
+func TestGatherPassesProviderBudget(t *testing.T) {
+	budget := resolveContextBudget(newBudgetConfig(), "coder-model")
+
+	var got int
+	p := &chatPipeline{
+		retrieveContext: func(ctx context.Context, message string, budget int, limits memory.ContextLimits) (string, []memory.ContextSource, error) {
+			got = budget
+			return "", nil, nil
+		},
+		loadHistory: func(ctx context.Context, sessionID string) ([]chat.Message, error) {
+			return nil, nil
+		},
+	}
+
+	if _, err := p.gather(context.Background(), "s1", "hello", true, budget.Budget, testContextLimits); err != nil {
+		t.Fatalf("gather() error = %v", err)
+	}
+	if got != budget.Budget {
+		t.Errorf("retrieveContext budget = %d, want qwen budget %d", got, budget.Budget)
+	}
+}
+
+func TestBuildPromptTrimsHistoryToBudget(t *testing.T) {
+	history := []chat.Message{
+		{Role: "user", Content: "oldest " + strings.Repeat("x", 400)},
+		{Role: "assistant", Content: "recent reply"},
+	}
+
+	prompt := buildPrompt("hello", "", history, 20, promptAffixes{})
+	if strings.Contains(prompt, "oldest") {
+		t.Error("oldest message should be dropped when over budget")
+	}
+	if !strings.Contains(prompt, "recent reply") {
+		t.Error("recent message should be kept")
+	}
+}
+
+func TestHandleChatRejectsOversizedPrompt(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	cfg := &config.Config{}
+	cfg.Agent.MaxPromptTokens = 100
+
+	handler := handleChat(nil, memory.NewMemoryStore(memory.DefaultConfig()), chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	// History is trimmed to fit, but the message alone is over the limit
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message":   strings.Repeat("word ", 200),
+		"sessionId": "big",
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload)))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if !strings.Contains(rec.Body.String(), "limit is 100") {
+		t.Errorf("body = %q, want the computed size and limit", rec.Body.String())
+	}
+	if len(client.prompts) != 0 {
+		t.Errorf("AI was called %d times, want 0", len(client.prompts))
+	}
+}
+
+func TestResolveContextBudgetCapsAtMaxPrompt(t *testing.T) {
+	cfg := newBudgetConfig()
+	cfg.Agent.MaxPromptTokens = 4000
+
+	budget := resolveContextBudget(cfg, "MiniMax-M2.1")
+	if budget.Budget != 4000 || budget.MaxPrompt != 4000 {
+		t.Errorf("budget = %+v, want Budget and MaxPrompt capped at 4000", budget)
+	}
+}
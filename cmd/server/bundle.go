@@ -0,0 +1,245 @@
+// Package main provides conversation bundles for Goclaw: a session's
+// transcript and memories in one zip, for backup and migration
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/memory"
+)
+
+// Files of a conversation bundle
+const (
+	bundleTranscriptJSON = "transcript.json" // chat.ExportedSession, the transcript restored on import
+	bundleTranscriptMD   = "transcript.md"   // The transcript for reading, restored when transcript.json is missing
+	bundleMemories       = "memories.jsonl"  // One memory.MemoryEntry per line, tagged with the session
+)
+
+// maxBundleSize is the largest bundle accepted for import. Unlike exports,
+// imports are buffered, as a zip is read from its end.
+const maxBundleSize = 64 << 20
+
+// handleSessionBundle serves GET /api/sessions/{id}/bundle, a zip of the
+// session's transcript, as JSON and markdown, and of the memories tagged
+// with it. "?memories=false" leaves the memories out. The zip is streamed
+// as it is written.
+func handleSessionBundle(w http.ResponseWriter, r *http.Request, chatMgr *chat.ChatManager, memStore *memory.MemoryStore, sessionID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	transcriptJSON, err := chatMgr.ExportSession(sessionID, chat.ExportJSON)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	transcriptMD, err := chatMgr.ExportSession(sessionID, chat.ExportMarkdown)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var memories []memory.MemoryEntry
+	if r.URL.Query().Get("memories") != "false" && memStore != nil {
+		memories = memStore.ExportEntries(map[string]interface{}{memory.MetadataSession: sessionID})
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, sessionID))
+
+	// Headers are sent by now, so a failure can only cut the zip short
+	if err := writeBundle(w, transcriptJSON, transcriptMD, memories); err != nil {
+		fmt.Printf("Error writing bundle of session %s: %v\n", sessionID, err)
+	}
+}
+
+// writeBundle writes a bundle zip to w. Memories are left out when nil.
+func writeBundle(w io.Writer, transcriptJSON, transcriptMD string, memories []memory.MemoryEntry) error {
+	zw := zip.NewWriter(w)
+
+	for _, file := range []struct{ name, content string }{
+		{bundleTranscriptJSON, transcriptJSON},
+		{bundleTranscriptMD, transcriptMD},
+	} {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, file.content); err != nil {
+			return err
+		}
+	}
+
+	if memories != nil {
+		fw, err := zw.Create(bundleMemories)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fw)
+		for _, entry := range memories {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+// handleImportBundle serves POST /api/sessions/bundle/import, restoring the
+// transcript and memories of a bundle zip sent as the body. The session
+// keeps its exported ID unless "?sessionId=" sets another, in which case the
+// memories are retagged with it.
+func handleImportBundle(chatMgr *chat.ChatManager, memStore *memory.MemoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBundleSize))
+		if err != nil {
+			http.Error(w, "Bundle too large or unreadable: "+err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		format, transcript, err := readBundleTranscript(zr)
+		if err != nil {
+			http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		memories, err := readBundleMemories(zr)
+		if err != nil {
+			http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The exported ID, unless another is asked for
+		exportedID := ""
+		if format == chat.ExportJSON {
+			var exported chat.ExportedSession
+			if err := json.Unmarshal([]byte(transcript), &exported); err != nil {
+				http.Error(w, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			exportedID = exported.ID
+		}
+		sessionID := r.URL.Query().Get("sessionId")
+		if sessionID == "" {
+			sessionID = exportedID
+		}
+		if sessionID == "" {
+			sessionID = fmt.Sprintf("imported_%d", time.Now().UnixNano())
+		}
+
+		if _, exists := chatMgr.GetSession(sessionID); exists {
+			http.Error(w, "session already exists: "+sessionID, http.StatusConflict)
+			return
+		}
+		session, err := chatMgr.ImportSession(sessionID, format, transcript)
+		if err != nil {
+			http.Error(w, "Import failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		restored := 0
+		if len(memories) > 0 && memStore != nil {
+			if sessionID != exportedID {
+				for i := range memories {
+					if memories[i].Metadata == nil {
+						memories[i].Metadata = map[string]interface{}{}
+					}
+					memories[i].Metadata[memory.MetadataSession] = sessionID
+				}
+			}
+			if restored, err = memStore.ImportEntries(memories); err != nil {
+				chatMgr.DeleteSession(sessionID) // Restore all of the bundle or nothing
+				http.Error(w, "Import failed: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status:  "ok",
+			Message: "Bundle imported",
+			Data: map[string]interface{}{
+				"sessionId":    session.ID,
+				"messageCount": len(session.Messages),
+				"memoryCount":  restored,
+			},
+		})
+	}
+}
+
+// readBundleTranscript returns the export format and content of a bundle's
+// transcript, preferring transcript.json
+func readBundleTranscript(zr *zip.Reader) (string, string, error) {
+	for _, candidate := range []struct{ name, format string }{
+		{bundleTranscriptJSON, chat.ExportJSON},
+		{bundleTranscriptMD, chat.ExportMarkdown},
+	} {
+		content, found, err := readBundleFile(zr, candidate.name)
+		if err != nil {
+			return "", "", err
+		}
+		if found {
+			return candidate.format, string(content), nil
+		}
+	}
+	return "", "", fmt.Errorf("no %s or %s", bundleTranscriptJSON, bundleTranscriptMD)
+}
+
+// readBundleMemories returns the memories of a bundle, none when it has no
+// memories.jsonl
+func readBundleMemories(zr *zip.Reader) ([]memory.MemoryEntry, error) {
+	content, found, err := readBundleFile(zr, bundleMemories)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var memories []memory.MemoryEntry
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var entry memory.MemoryEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("%s: memory %d: %w", bundleMemories, len(memories)+1, err)
+		}
+		memories = append(memories, entry)
+	}
+	return memories, nil
+}
+
+// readBundleFile returns the content of the named file of a bundle, and
+// whether the bundle has it
+func readBundleFile(zr *zip.Reader, name string) ([]byte, bool, error) {
+	for _, file := range zr.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", name, err)
+		}
+		defer rc.Close()
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", name, err)
+		}
+		return content, true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/memory"
+)
+
+func TestSessionBundleRoundTrip(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("trip", "")
+	chatMgr.AddMessage("trip", "user", "Plan a weekend in Lisbon")
+	chatMgr.AddMessage("trip", "assistant", "Day 1: Alfama and the castle.")
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	memStore.AddShortTerm("Plan a weekend in Lisbon", map[string]interface{}{"session": "trip"})
+	memStore.AddLongTerm("the user prefers trains to flights", []float32{1, 0}, map[string]interface{}{"session": "trip"})
+	memStore.AddNote("trip", "book the castle tickets", 2)
+	memStore.AddShortTerm("another session's message", map[string]interface{}{"session": "other"})
+
+	rec := httptest.NewRecorder()
+	handleSessionRoutes(chatMgr, memStore)(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/trip/bundle", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("GET bundle: status = %d, content type = %q, body = %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	bundle := rec.Body.Bytes()
+
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatalf("bundle is not a zip: %v", err)
+	}
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+	}
+	if len(names) != 3 || names[0] != "transcript.json" || names[1] != "transcript.md" || names[2] != "memories.jsonl" {
+		t.Errorf("bundle files = %v, want the transcripts and memories", names)
+	}
+
+	// Restored into a fresh server
+	importBundle := func(chatMgr *chat.ChatManager, memStore *memory.MemoryStore, target string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handleImportBundle(chatMgr, memStore)(rec, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(bundle)))
+		var resp APIResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		data, _ := resp.Data.(map[string]interface{})
+		return rec, data
+	}
+
+	restoredChat := chat.NewChatManager(100)
+	restoredMemory := memory.NewMemoryStore(memory.DefaultConfig())
+	rec, data := importBundle(restoredChat, restoredMemory, "/api/sessions/bundle/import")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if data["sessionId"] != "trip" || data["memoryCount"] != float64(3) {
+		t.Errorf("import data = %v, want session trip with 3 memories", data)
+	}
+
+	messages, err := restoredChat.GetMessages("trip")
+	if err != nil || len(messages) != 2 || messages[1].Content != "Day 1: Alfama and the castle." {
+		t.Errorf("restored messages = %+v (%v), want the transcript", messages, err)
+	}
+	stats := restoredMemory.Stats()
+	if stats.ShortTermCount != 1 || stats.LongTermCount != 1 || stats.WorkingCount != 1 {
+		t.Errorf("restored memory stats = %+v, want one memory of each kind", stats)
+	}
+	results, _ := restoredMemory.Search(context.Background(), "", []float32{1, 0}, 1)
+	if len(results) != 1 || results[0].Entry.Content != "the user prefers trains to flights" || results[0].Score < 0.99 {
+		t.Errorf("search results = %+v, want the long-term memory with its embedding", results)
+	}
+
+	// A second import of the same session conflicts
+	if rec, _ := importBundle(restoredChat, restoredMemory, "/api/sessions/bundle/import"); rec.Code != http.StatusConflict {
+		t.Errorf("repeated import: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	// Imported next to the original under another ID, the memories follow it
+	rec, _ = importBundle(chatMgr, memStore, "/api/sessions/bundle/import?sessionId=trip-copy")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import as trip-copy: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if copied := memStore.ExportEntries(map[string]interface{}{"session": "trip-copy"}); len(copied) != 3 {
+		t.Errorf("trip-copy memories = %d, want 3", len(copied))
+	}
+	if original := memStore.ExportEntries(map[string]interface{}{"session": "trip"}); len(original) != 3 {
+		t.Errorf("trip memories = %d, want the 3 originals untouched", len(original))
+	}
+}
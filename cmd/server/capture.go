@@ -0,0 +1,55 @@
+// Package main provides memory capture of assistant responses for Goclaw
+package main
+
+import (
+	"context"
+	"strings"
+
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/vector"
+)
+
+// defaultMinCaptureChars is the shortest assistant response stored in memory
+const defaultMinCaptureChars = 40
+
+// assistantMemoryTag marks memories captured from assistant responses
+const assistantMemoryTag = "assistant"
+
+// shouldCaptureAssistant reports whether an assistant response is worth
+// remembering: memory.captureAssistant is on and the response isn't trivial
+// ("OK", "Sure!") or a tool failure notice
+func shouldCaptureAssistant(cfg config.MemoryConfig, response string) bool {
+	if !cfg.CaptureAssistant {
+		return false
+	}
+
+	minChars := cfg.MinCaptureChars
+	if minChars <= 0 {
+		minChars = defaultMinCaptureChars
+	}
+	response = strings.TrimSpace(response)
+	if len([]rune(response)) < minChars {
+		return false
+	}
+	return !strings.HasPrefix(response, "工具调用失败")
+}
+
+// captureAssistantResponse stores an assistant response in long-term memory,
+// embedded when an embedder is available so it can be recalled semantically
+func captureAssistantResponse(ctx context.Context, embedder vector.Embedder, memStore *memory.MemoryStore, sessionID, response string) error {
+	var embedding []float32
+	if embedder != nil {
+		var err error
+		if embedding, err = embedder.Embed(ctx, response); err != nil {
+			return err
+		}
+	}
+
+	return memStore.AddLongTerm(strings.TrimSpace(response), embedding, map[string]interface{}{
+		"session": sessionID,
+		"source":  "api",
+		"role":    "assistant",
+		"tags":    []string{assistantMemoryTag},
+	})
+}
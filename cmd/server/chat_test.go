@@ -0,0 +1,772 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+	"goclaw/internal/vector"
+	"goclaw/pkg/ai"
+)
+
+// fakeAIClient records prompts and returns a fixed reply
+type fakeAIClient struct {
+	mu      sync.Mutex
+	prompts []string
+	models  []string
+	reply   string
+	usage   ai.Usage
+	model   string // Model the reply claims to come from
+}
+
+func (f *fakeAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.models = append(f.models, req.Model)
+	for _, msg := range req.Messages {
+		f.prompts = append(f.prompts, msg.Content)
+	}
+
+	reply := f.reply
+	if reply == "" {
+		reply = "ok"
+	}
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: reply}}},
+		Model:   f.model,
+		Usage:   f.usage,
+	}, nil
+}
+
+func (f *fakeAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func (f *fakeAIClient) lastPrompt() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.prompts) == 0 {
+		return ""
+	}
+	return f.prompts[len(f.prompts)-1]
+}
+
+// fakeEmbedder returns a constant embedding
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0, 0, 0}, nil
+}
+
+func (e fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	for i := range texts {
+		result[i], _ = e.Embed(ctx, texts[i])
+	}
+	return result, nil
+}
+
+func (fakeEmbedder) GetModelName() string { return "fake" }
+
+// useFakeAI swaps the global AI client for the duration of a test
+func useFakeAI(t *testing.T, client ai.Client) {
+	t.Helper()
+
+	prevClient, prevAgent := aiClient, chatAgent
+	aiClient, chatAgent = client, nil
+	t.Cleanup(func() {
+		aiClient, chatAgent = prevClient, prevAgent
+	})
+}
+
+func postChat(t *testing.T, handler http.HandlerFunc, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestHandleChatUseMemory(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	memStore.AddShortTerm("the user's cat is called Biscuit", nil)
+
+	handler := handleChat(fakeEmbedder{}, memStore, chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	// With memory disabled, no context is injected and nothing is captured
+	data := postChat(t, handler, map[string]interface{}{
+		"message":   "What is the capital of France?",
+		"sessionId": "s1",
+		"useMemory": false,
+	})
+	if strings.Contains(client.lastPrompt(), "Biscuit") {
+		t.Error("prompt should not contain memory context when useMemory is false")
+	}
+	if count := memStore.Stats().ShortTermCount; count != 1 {
+		t.Errorf("short-term count = %d, want 1 (no new entry)", count)
+	}
+	if data["useMemory"] != false {
+		t.Errorf("useMemory = %v, want false", data["useMemory"])
+	}
+
+	// By default memory is used
+	data = postChat(t, handler, map[string]interface{}{
+		"message":   "What is my cat called?",
+		"sessionId": "s2",
+	})
+	if !strings.Contains(client.lastPrompt(), "Biscuit") {
+		t.Error("prompt should contain memory context by default")
+	}
+	if count := memStore.Stats().ShortTermCount; count != 2 {
+		t.Errorf("short-term count = %d, want 2", count)
+	}
+	if data["useMemory"] != true {
+		t.Errorf("useMemory = %v, want true", data["useMemory"])
+	}
+}
+
+func TestHandleChatSessionScopedMemory(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	memStore.AddShortTerm("the user's cat is called Biscuit", map[string]interface{}{"session": "s1"})
+	memStore.AddShortTerm("the user's dog is called Rex", map[string]interface{}{"session": "s2"})
+
+	cfg := &config.Config{}
+	cfg.Memory.SessionScoped = true
+	handler := handleChat(fakeEmbedder{}, memStore, chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	postChat(t, handler, map[string]interface{}{
+		"message":   "What are my pets called?",
+		"sessionId": "s1",
+	})
+	if prompt := client.lastPrompt(); !strings.Contains(prompt, "Biscuit") || strings.Contains(prompt, "Rex") {
+		t.Errorf("prompt = %q, want only the memories of session s1", prompt)
+	}
+}
+
+// countingEmbedder is a fakeEmbedder that records the texts it embeds
+type countingEmbedder struct {
+	fakeEmbedder
+	mu    sync.Mutex
+	texts []string
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.mu.Lock()
+	e.texts = append(e.texts, text)
+	e.mu.Unlock()
+	return e.fakeEmbedder.Embed(ctx, text)
+}
+
+func TestHandleChatSkipsTrivialMessages(t *testing.T) {
+	useFakeAI(t, &fakeAIClient{})
+
+	embedder := &countingEmbedder{}
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	handler := handleChat(embedder, memStore, chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	// An acknowledgment is answered but neither embedded nor remembered
+	postChat(t, handler, map[string]interface{}{"message": "ok", "sessionId": "s1"})
+	if len(embedder.texts) != 0 {
+		t.Errorf("embedded %q, want no embedding for an acknowledgment", embedder.texts)
+	}
+	if count := memStore.Stats().ShortTermCount; count != 0 {
+		t.Errorf("short-term count = %d, want 0", count)
+	}
+
+	postChat(t, handler, map[string]interface{}{"message": "What is my cat called?", "sessionId": "s1"})
+	if len(embedder.texts) != 1 || embedder.texts[0] != "What is my cat called?" {
+		t.Errorf("embedded %q, want the substantive message", embedder.texts)
+	}
+	if count := memStore.Stats().ShortTermCount; count != 1 {
+		t.Errorf("short-term count = %d, want 1", count)
+	}
+}
+
+func TestHandleChatRejectsBlankMessage(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	chatMgr := chat.NewChatManager(100)
+	handler := handleChat(fakeEmbedder{}, memStore, chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	for _, message := range []string{"", " \t\n  "} {
+		body, _ := json.Marshal(map[string]interface{}{"message": message, "sessionId": "blank"})
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("message %q: status = %d, want 400", message, rec.Code)
+		}
+	}
+
+	if len(client.prompts) != 0 {
+		t.Errorf("AI was called %d times, want 0", len(client.prompts))
+	}
+	if count := memStore.Stats().ShortTermCount; count != 0 {
+		t.Errorf("short-term count = %d, want 0", count)
+	}
+	if _, exists := chatMgr.GetSession("blank"); exists {
+		t.Error("a blank message created a session")
+	}
+
+	// Surrounding whitespace is trimmed from a real message
+	postChat(t, handler, map[string]interface{}{"message": "  hello  \n", "sessionId": "trimmed"})
+	messages, _ := chatMgr.GetMessages("trimmed")
+	if len(messages) == 0 || messages[0].Content != "hello" {
+		t.Errorf("stored messages = %+v, want the trimmed message first", messages)
+	}
+}
+
+func TestHandleChatReportsUsage(t *testing.T) {
+	// The provider leaves out the total, which is filled in
+	client := &fakeAIClient{usage: ai.Usage{PromptTokens: 120, CompletionTokens: 30}}
+	useFakeAI(t, client)
+
+	handler := handleChat(fakeEmbedder{}, memory.NewMemoryStore(memory.DefaultConfig()), chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	data := postChat(t, handler, map[string]interface{}{
+		"message":   "What is the capital of France?",
+		"sessionId": "s1",
+		"useMemory": false,
+	})
+	usage, ok := data["usage"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("usage = %v, want an object", data["usage"])
+	}
+	if usage["promptTokens"] != float64(120) || usage["completionTokens"] != float64(30) || usage["totalTokens"] != float64(150) {
+		t.Errorf("usage = %v, want 120 prompt, 30 completion and 150 total tokens", usage)
+	}
+}
+
+func TestHandleChatDebugReportsActualModel(t *testing.T) {
+	// The provider answers with another model than the one requested
+	client := &fakeAIClient{model: "MiniMax-Text-01"}
+	useFakeAI(t, client)
+
+	handler := handleChat(fakeEmbedder{}, memory.NewMemoryStore(memory.DefaultConfig()), chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	data := postChat(t, handler, map[string]interface{}{
+		"message":   "What is the capital of France?",
+		"sessionId": "s1",
+		"useMemory": false,
+		"debug":     true,
+	})
+	meta, ok := data["responseMeta"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("responseMeta = %v, want an object", data["responseMeta"])
+	}
+	if meta["model"] != "MiniMax-Text-01" || meta["requestedModel"] != primaryChatModel {
+		t.Errorf("responseMeta = %v, want model MiniMax-Text-01 for the requested %s", meta, primaryChatModel)
+	}
+
+	// Without debug the metadata is left out
+	data = postChat(t, handler, map[string]interface{}{"message": "Hi", "sessionId": "s1", "useMemory": false})
+	if _, ok := data["responseMeta"]; ok {
+		t.Errorf("responseMeta = %v without debug, want none", data["responseMeta"])
+	}
+}
+
+func TestHandleChatCapturesAssistantResponse(t *testing.T) {
+	client := &fakeAIClient{reply: "Your cat Biscuit was adopted from the shelter in 2021."}
+	useFakeAI(t, client)
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	cfg := &config.Config{}
+	handler := handleChat(fakeEmbedder{}, memStore, chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	postChat(t, handler, map[string]interface{}{"message": "When did I adopt my cat?", "sessionId": "s1"})
+	if count := memStore.Stats().LongTermCount; count != 0 {
+		t.Errorf("long-term count = %d, want 0 with captureAssistant off", count)
+	}
+
+	cfg.Memory.CaptureAssistant = true
+	postChat(t, handler, map[string]interface{}{"message": "When did I adopt my cat?", "sessionId": "s1"})
+	results, _ := memStore.Search(context.Background(), "Biscuit", []float32{1, 0, 0, 0}, 5)
+	if len(results) != 1 || results[0].Entry.Content != client.reply {
+		t.Fatalf("long-term memories = %+v, want the assistant response", results)
+	}
+
+	// Trivial responses aren't worth remembering
+	client.reply = "OK!"
+	postChat(t, handler, map[string]interface{}{"message": "Thanks", "sessionId": "s1"})
+	if count := memStore.Stats().LongTermCount; count != 1 {
+		t.Errorf("long-term count = %d, want the trivial response skipped", count)
+	}
+}
+
+func TestHandleChatRejectsInvalidContextLimits(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	chatMgr := chat.NewChatManager(100)
+	handler := handleChat(fakeEmbedder{}, memStore, chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	for _, override := range []string{"contextLongTermK", "contextShortTermK"} {
+		body, _ := json.Marshal(map[string]interface{}{"message": "hello", "sessionId": "limits", override: -1})
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s -1: status = %d, want 400", override, rec.Code)
+		}
+	}
+
+	// Rejected before the session is touched
+	if _, exists := chatMgr.GetSession("limits"); exists {
+		t.Error("a request with invalid context limits created a session")
+	}
+	if len(client.prompts) != 0 {
+		t.Errorf("AI was called %d times, want 0", len(client.prompts))
+	}
+}
+
+func TestHandleChatExplainContext(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	memStore.AddShortTerm("the user's cat is called Biscuit", nil)
+	memStore.AddWorking("planning a trip to Lisbon", 1)
+	if err := memStore.AddLongTerm("the user prefers tea over coffee", []float32{1, 0, 0, 0}, nil); err != nil {
+		t.Fatalf("AddLongTerm() error = %v", err)
+	}
+
+	handler := handleChat(fakeEmbedder{}, memStore, chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message":        "What should I drink?",
+		"sessionId":      "s1",
+		"explainContext": true,
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload)))
+
+	var resp struct {
+		Data struct {
+			ContextUsed []memory.ContextSource `json:"contextUsed"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	sources := resp.Data.ContextUsed
+	if len(sources) != 3 {
+		t.Fatalf("got %d sources, want 3: %+v", len(sources), sources)
+	}
+
+	prompt := client.lastPrompt()
+	types := make(map[memory.MemoryType]memory.ContextSource)
+	for _, source := range sources {
+		if source.ID == "" {
+			t.Errorf("source %q has no ID", source.Content)
+		}
+		if !strings.Contains(prompt, source.Content) {
+			t.Errorf("source %q was not injected into the prompt", source.Content)
+		}
+		types[source.Type] = source
+	}
+	if longTerm, ok := types[memory.MemoryTypeLong]; !ok || longTerm.Score < 0.99 {
+		t.Errorf("long-term source = %+v, want a scored match", longTerm)
+	}
+	if _, ok := types[memory.MemoryTypeWork]; !ok {
+		t.Error("working memory source missing")
+	}
+
+	// Without the option the field is omitted
+	data := postChat(t, handler, map[string]interface{}{
+		"message":   "And to eat?",
+		"sessionId": "s1",
+	})
+	if _, ok := data["contextUsed"]; ok {
+		t.Error("contextUsed should only be returned when explainContext is set")
+	}
+}
+
+// echoAIClient slowly echoes the latest user line of the prompt
+type echoAIClient struct{}
+
+func (echoAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	time.Sleep(20 * time.Millisecond)
+
+	prompt := req.Messages[len(req.Messages)-1].Content
+	reply := ""
+	for _, line := range strings.Split(prompt, "\n") {
+		if strings.HasPrefix(line, "User: ") {
+			reply = "echo: " + strings.TrimPrefix(line, "User: ")
+		}
+	}
+
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: reply}}},
+	}, nil
+}
+
+func (echoAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestHandleChatSerializesSession(t *testing.T) {
+	useFakeAI(t, echoAIClient{})
+
+	chatMgr := chat.NewChatManager(100)
+	handler := handleChat(nil, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	var wg sync.WaitGroup
+	for _, msg := range []string{"first", "second"} {
+		wg.Add(1)
+		go func(msg string) {
+			defer wg.Done()
+			payload, _ := json.Marshal(map[string]interface{}{
+				"message":   msg,
+				"sessionId": "shared",
+			})
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload)))
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+		}(msg)
+	}
+	wg.Wait()
+
+	messages, err := chatMgr.GetMessages("shared")
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("got %d messages, want 4", len(messages))
+	}
+
+	// Each user message must be directly followed by its own reply
+	for i := 0; i < len(messages); i += 2 {
+		user, assistant := messages[i], messages[i+1]
+		if user.Role != "user" || assistant.Role != "assistant" {
+			t.Fatalf("messages %d-%d have roles %s/%s, want user/assistant", i, i+1, user.Role, assistant.Role)
+		}
+		if assistant.Content != "echo: "+user.Content {
+			t.Errorf("reply %q does not answer %q", assistant.Content, user.Content)
+		}
+	}
+}
+
+// gatedAIClient holds its reply to "please wait" until release is closed
+type gatedAIClient struct {
+	started chan struct{} // Buffered; receives once the gated reply is being held
+	release chan struct{}
+}
+
+func (c gatedAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	// The prompt also carries the history, so only look at the latest user line
+	latest := ""
+	for _, line := range strings.Split(req.Messages[len(req.Messages)-1].Content, "\n") {
+		if strings.HasPrefix(line, "User: ") {
+			latest = strings.TrimPrefix(line, "User: ")
+		}
+	}
+	if latest == "please wait" {
+		c.started <- struct{}{}
+		<-c.release
+	}
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "done"}}},
+	}, nil
+}
+
+func (c gatedAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestHandleChatLocksOnlyItsSession(t *testing.T) {
+	client := gatedAIClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	useFakeAI(t, client)
+
+	chatMgr := chat.NewChatManager(100)
+	handler := handleChat(nil, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	post := func(sessionID, message string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]interface{}{"message": message, "sessionId": sessionID})
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload)))
+		return rec
+	}
+
+	slow := make(chan *httptest.ResponseRecorder)
+	go func() { slow <- post("busy", "please wait") }()
+	<-client.started
+
+	// Another session is answered while "busy" is still generating
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post("other", "hello") }()
+	select {
+	case rec := <-done:
+		if rec.Code != http.StatusOK {
+			t.Errorf("other session: status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a generating session blocked a different session")
+	}
+
+	// A second request for the busy session waits for the first to finish
+	queued := make(chan *httptest.ResponseRecorder)
+	go func() { queued <- post("busy", "follow-up") }()
+	select {
+	case <-queued:
+		t.Fatal("a request for the busy session ran before its previous turn finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(client.release)
+	<-slow
+	<-queued
+
+	messages, _ := chatMgr.GetMessages("busy")
+	var contents []string
+	for _, msg := range messages {
+		contents = append(contents, msg.Role+":"+msg.Content)
+	}
+	want := "user:please wait,assistant:done,user:follow-up,assistant:done"
+	if got := strings.Join(contents, ","); got != want {
+		t.Errorf("busy session = %s, want %s", got, want)
+	}
+}
+
+func TestHandleChatWithoutGenerate(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	chatMgr := chat.NewChatManager(100)
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	handler := handleChat(nil, memStore, chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	data := postChat(t, handler, map[string]interface{}{
+		"message":   "Note: the meeting moved to Friday",
+		"sessionId": "notes",
+		"generate":  false,
+	})
+
+	if len(client.prompts) != 0 {
+		t.Errorf("AI was called %d times, want 0", len(client.prompts))
+	}
+	if _, ok := data["response"]; ok {
+		t.Errorf("response = %v, want none", data["response"])
+	}
+
+	messages, err := chatMgr.GetMessages("notes")
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].Role != "user" || messages[0].Content != "Note: the meeting moved to Friday" {
+		t.Errorf("messages = %+v, want the single stored note", messages)
+	}
+	if returned, ok := data["messages"].([]interface{}); !ok || len(returned) != 1 {
+		t.Errorf("returned messages = %v, want 1", data["messages"])
+	}
+	if count := memStore.Stats().ShortTermCount; count != 1 {
+		t.Errorf("short-term count = %d, want 1", count)
+	}
+}
+
+func TestHandleChatRoutesByTier(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	cfg := &config.Config{Agent: config.AgentConfig{ModelTiers: map[string]string{
+		"cheap":    "glm-4-flash",
+		"standard": "glm-4",
+		"strong":   "glm-4-plus",
+	}}}
+	handler := handleChat(fakeEmbedder{}, memory.NewMemoryStore(memory.DefaultConfig()), chat.NewChatManager(100),
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	tests := []struct {
+		body  map[string]interface{}
+		model string
+		tier  string
+	}{
+		{map[string]interface{}{"message": "你好！"}, "glm-4-flash", "cheap"},
+		{map[string]interface{}{"message": "Analyze the tradeoffs between Raft and Paxos for our metadata service"}, "glm-4-plus", "strong"},
+		{map[string]interface{}{"message": "hello", "model": "qwen-max"}, "qwen-max", ""},
+	}
+	for i, tt := range tests {
+		tt.body["sessionId"] = fmt.Sprintf("route-%d", i)
+		data := postChat(t, handler, tt.body)
+
+		route, _ := data["model"].(map[string]interface{})
+		if tier, _ := route["tier"].(string); route["model"] != tt.model || tier != tt.tier {
+			t.Errorf("%q routed to %v, want %s (%s tier)", tt.body["message"], route, tt.model, tt.tier)
+		}
+		if got := client.models[len(client.models)-1]; got != tt.model {
+			t.Errorf("%q requested model %s, want %s", tt.body["message"], got, tt.model)
+		}
+	}
+}
+
+func TestHandleChatPromptAffixesStayOutOfHistory(t *testing.T) {
+	client := &fakeAIClient{}
+	useFakeAI(t, client)
+
+	cfg := &config.Config{Agent: config.AgentConfig{PromptSuffix: "Be polite."}}
+	chatMgr := chat.NewChatManager(100)
+	handler := handleChat(fakeEmbedder{}, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	postChat(t, handler, map[string]interface{}{
+		"message":      "What is Go?",
+		"sessionId":    "affixes",
+		"promptPrefix": "Answer in one sentence.",
+		"useMemory":    false,
+	})
+	prompt := client.lastPrompt()
+	want := "<request-prefix>\nAnswer in one sentence.\n</request-prefix>\nUser: What is Go?\n<request-suffix>\nBe polite.\n</request-suffix>\n"
+	if !strings.Contains(prompt, want) {
+		t.Errorf("prompt = %q, want the message wrapped in both affixes", prompt)
+	}
+
+	messages, _ := chatMgr.GetMessages("affixes")
+	for _, msg := range messages {
+		if strings.Contains(msg.Content, "one sentence") || strings.Contains(msg.Content, "polite") {
+			t.Errorf("stored message %q contains injected text", msg.Content)
+		}
+	}
+	if messages[0].Content != "What is Go?" {
+		t.Errorf("stored user message = %q, want the raw message", messages[0].Content)
+	}
+
+	// An empty suffix in the request turns the configured one off
+	postChat(t, handler, map[string]interface{}{
+		"message":      "And Rust?",
+		"sessionId":    "affixes",
+		"promptSuffix": "",
+		"useMemory":    false,
+	})
+	if strings.Contains(client.lastPrompt(), "Be polite.") {
+		t.Error("prompt should not contain the configured suffix when the request clears it")
+	}
+}
+
+// slowAIClient answers only once its context is done
+type slowAIClient struct{}
+
+func (slowAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestHandleChatTimesOutSlowClient(t *testing.T) {
+	useFakeAI(t, slowAIClient{})
+
+	cfg := &config.Config{Agent: config.AgentConfig{Timeouts: config.TimeoutsConfig{Interactive: "20ms"}}}
+	chatMgr := chat.NewChatManager(100)
+	handler := handleChat(fakeEmbedder{}, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message":   "Take your time",
+		"sessionId": "slow",
+		"useMemory": false,
+	})
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload)))
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %s, want it aborted by the 20ms timeout", elapsed)
+	}
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusGatewayTimeout, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "interactive AI call timed out after 20ms") {
+		t.Errorf("body = %q, want the timeout error", rec.Body.String())
+	}
+}
+
+type failingAIClient struct{ err error }
+
+func (c failingAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return nil, c.err
+}
+
+func (c failingAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+type timeoutNetError struct{}
+
+func (timeoutNetError) Error() string   { return "i/o timeout" }
+func (timeoutNetError) Timeout() bool   { return true }
+func (timeoutNetError) Temporary() bool { return true }
+
+func TestHandleChatReportsProviderFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   string
+	}{
+		{"rejected key", &ai.APIError{StatusCode: http.StatusUnauthorized, Body: "invalid api key"}, http.StatusBadGateway, "check its API key"},
+		{"network timeout", fmt.Errorf("request failed: %w", &url.Error{Op: "Post", URL: "https://api.example.com", Err: timeoutNetError{}}), http.StatusGatewayTimeout, "did not respond in time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeAI(t, failingAIClient{err: tt.err})
+			handler := handleChat(fakeEmbedder{}, memory.NewMemoryStore(memory.DefaultConfig()), chat.NewChatManager(100),
+				vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+			payload, _ := json.Marshal(map[string]interface{}{"message": "What's new?", "useMemory": false})
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat", bytes.NewReader(payload)))
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("response = %d %q, want %d with %q", rec.Code, rec.Body.String(), tt.status, tt.want)
+			}
+		})
+	}
+}
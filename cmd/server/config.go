@@ -0,0 +1,38 @@
+// Package main provides the configuration endpoint for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goclaw/internal/config"
+	"goclaw/internal/redact"
+	"goclaw/internal/security"
+)
+
+// configHandler serves /api/config to API keys with the config:read scope.
+// Without API key auth the endpoint is disabled, since anyone could read it.
+func configHandler(cfg *config.Config, sm *security.SecurityManager) http.Handler {
+	if sm == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `The config endpoint requires gateway.auth.mode "apikey"`, http.StatusForbidden)
+		})
+	}
+	return sm.APIKeyAuthMiddleware(security.ConfigReadScope)(handleConfig(cfg))
+}
+
+// handleConfig returns the effective configuration with secrets masked
+func handleConfig(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data:   redact.Value(cfg),
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goclaw/internal/config"
+)
+
+func getConfig(handler http.Handler, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConfigEndpointRequiresScope(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Gateway.Auth.Mode = "apikey"
+	cfg.Gateway.Auth.APIKeys = []config.APIKeyConfig{
+		{Key: "goclaw_config_reader", Name: "ops", Scopes: []string{"config:read"}},
+		{Key: "goclaw_tools_only", Name: "tools", Scopes: []string{"tools:*"}},
+	}
+	handler := configHandler(cfg, newSecurityManager(cfg))
+
+	if rec := getConfig(handler, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without key: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := getConfig(handler, "goclaw_tools_only"); rec.Code != http.StatusForbidden {
+		t.Errorf("without config:read: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := getConfig(handler, "goclaw_config_reader")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("with config:read: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	for _, secret := range []string{"goclaw_config_reader", "goclaw_tools_only"} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Errorf("config dump leaks %q: %s", secret, rec.Body.String())
+		}
+	}
+}
+
+func TestConfigEndpointDisabledWithoutAuth(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	if rec := getConfig(configHandler(cfg, newSecurityManager(cfg)), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
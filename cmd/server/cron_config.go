@@ -0,0 +1,17 @@
+// Package main provides scheduled task configuration for Goclaw
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"goclaw/internal/config"
+)
+
+// cronFile returns the file scheduled tasks are kept in, from cron.file
+func cronFile(cfg *config.Config) string {
+	if cfg.Cron.File != "" {
+		return cfg.Cron.File
+	}
+	return filepath.Join(os.Getenv("HOME"), ".openclaw", "workspace", "goclaw_cron.json")
+}
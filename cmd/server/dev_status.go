@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"goclaw/internal/tasklist"
 )
 
 // DevStatusResponse contains development status information
@@ -66,7 +68,7 @@ type TokenUsage struct {
 }
 
 // handleDevStatus provides development status information
-func handleDevStatus() http.HandlerFunc {
+func handleDevStatus(list *tasklist.List) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -74,7 +76,7 @@ func handleDevStatus() http.HandlerFunc {
 		}
 
 		// Gather development status information
-		statusData := gatherDevStatus()
+		statusData := gatherDevStatus(list)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(DevStatusResponse{
@@ -85,7 +87,7 @@ func handleDevStatus() http.HandlerFunc {
 }
 
 // gatherDevStatus collects all development status information
-func gatherDevStatus() DevStatusData {
+func gatherDevStatus(list *tasklist.List) DevStatusData {
 	data := DevStatusData{}
 
 	// Get recent activity (commit + file mod)
@@ -111,7 +113,7 @@ func gatherDevStatus() DevStatusData {
 	data.ImplementedFeatures, data.PlannedFeatures = getFeatures()
 
 	// Get project status
-	data.ProjectStatus = getProjectStatus()
+	data.ProjectStatus = getProjectStatus(list)
 
 	// Build time
 	data.BuildTime = time.Now().Format("2006-01-02 15:04:05")
@@ -324,40 +326,15 @@ func getFeatures() ([]string, []string) {
 	return implemented, planned
 }
 
-// getProjectStatus returns the overall project status
-func getProjectStatus() string {
-	// Read from goclaw_tasks.json
-	tasksFile := filepath.Join(os.Getenv("HOME"), ".openclaw", "workspace", "goclaw_tasks.json")
-	if _, err := os.Stat(tasksFile); err == nil {
-		content, err := ioutil.ReadFile(tasksFile)
-		if err == nil {
-			var tasks map[string]interface{}
-			if err := json.Unmarshal(content, &tasks); err == nil {
-				// Calculate completion percentage
-				completedCount := 0
-				totalCount := 0
-				
-				if tasksArray, ok := tasks["tasks"].([]interface{}); ok {
-					for _, task := range tasksArray {
-						if taskMap, ok := task.(map[string]interface{}); ok {
-							if completed, ok := taskMap["completed"].(bool); ok {
-								totalCount++
-								if completed {
-									completedCount++
-								}
-							}
-						}
-					}
-				}
-				
-				if totalCount > 0 {
-					percentage := float64(completedCount) / float64(totalCount) * 100
-					return fmt.Sprintf("🚀 开发中 - 完成度: %.1f%% (%d/%d 任务)", percentage, completedCount, totalCount)
-				}
-			}
-		}
+// getProjectStatus returns the overall project status, with the completion
+// of the live task list
+func getProjectStatus(list *tasklist.List) string {
+	completedCount, totalCount, err := list.Progress()
+	if err == nil && totalCount > 0 {
+		percentage := float64(completedCount) / float64(totalCount) * 100
+		return fmt.Sprintf("🚀 开发中 - 完成度: %.1f%% (%d/%d 任务)", percentage, completedCount, totalCount)
 	}
-	
+
 	return "🚀 开发中"
 }
 
@@ -0,0 +1,326 @@
+// Package main provides install diagnostics for Goclaw, served at
+// /api/diagnostics and run by the doctor command
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/internal/cron"
+	"goclaw/internal/redact"
+	"goclaw/internal/vector"
+	"goclaw/pkg/ai"
+)
+
+// diagnosticProbeTimeout bounds each reachability probe
+const diagnosticProbeTimeout = 10 * time.Second
+
+// checkStatus is the outcome of one diagnostic check
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkWarn checkStatus = "warn" // Works, with reduced functionality
+	checkFail checkStatus = "fail"
+)
+
+// diagnosticCheck is the result of one check, with a hint on how to fix it
+// when it didn't pass
+type diagnosticCheck struct {
+	Name   string      `json:"name"`
+	Status checkStatus `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+	Hint   string      `json:"hint,omitempty"`
+}
+
+// diagnosticReport lists the results of all checks
+type diagnosticReport struct {
+	Checks []diagnosticCheck `json:"checks"`
+	Failed bool              `json:"failed"` // Any check failed
+}
+
+// diagnosticSources are the components the diagnostics check
+type diagnosticSources struct {
+	cfg         *config.Config
+	client      ai.Client // nil when no provider is configured
+	embedder    vector.Embedder
+	vectorStore vector.VectorStore
+	cron        *cron.CronManager
+}
+
+// runDiagnostics runs every check. Providers are only probed when the config
+// is valid, since the server wouldn't start otherwise.
+func runDiagnostics(ctx context.Context, sources diagnosticSources) diagnosticReport {
+	configCheck := checkConfig(sources.cfg)
+	checks := []diagnosticCheck{configCheck}
+	if configCheck.Status == checkFail {
+		checks = append(checks, diagnosticCheck{
+			Name:   "providers",
+			Status: checkFail,
+			Detail: "not checked, the config is invalid",
+			Hint:   "Fix the config first",
+		})
+	} else {
+		checks = append(checks, checkProviders(ctx, sources.client)...)
+	}
+	checks = append(checks,
+		checkEmbedder(ctx, sources.embedder),
+		checkVectorStore(ctx, sources.vectorStore),
+		checkCron(sources.cron),
+		checkWorkspace(sources.cfg),
+		checkIdentity(sources.cfg),
+	)
+
+	report := diagnosticReport{Checks: checks}
+	for _, check := range checks {
+		if check.Status == checkFail {
+			report.Failed = true
+		}
+	}
+	return report
+}
+
+// checkConfig reports every invalid config value
+func checkConfig(cfg *config.Config) diagnosticCheck {
+	errs := validateConfig(cfg)
+	if len(errs) == 0 {
+		return diagnosticCheck{Name: "config", Status: checkPass}
+	}
+
+	problems := make([]string, len(errs))
+	for i, err := range errs {
+		problems[i] = err.Error()
+	}
+	return diagnosticCheck{
+		Name:   "config",
+		Status: checkFail,
+		Detail: strings.Join(problems, "; "),
+		Hint:   "Fix these values in config.json or ~/.openclaw/openclaw.json",
+	}
+}
+
+// checkProviders sends a minimal completion to each provider, bypassing the
+// circuit breakers so every provider is probed
+func checkProviders(ctx context.Context, client ai.Client) []diagnosticCheck {
+	if client == nil {
+		return []diagnosticCheck{{
+			Name:   "providers",
+			Status: checkFail,
+			Detail: "no AI provider configured",
+			Hint:   "Set zhipu.apiKey or add a provider under models.providers",
+		}}
+	}
+
+	providers := map[string]ai.Client{"default": client}
+	if multiClient, ok := client.(*ai.MultiProviderClient); ok {
+		providers = multiClient.Providers
+	}
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]diagnosticCheck, 0, len(names))
+	for _, name := range names {
+		check := diagnosticCheck{Name: "provider " + name, Status: checkPass}
+		if err := probeProvider(ctx, providers[name]); err != nil {
+			check.Status = checkFail
+			check.Detail = redact.String(err.Error())
+			check.Hint = fmt.Sprintf("Check the apiKey and baseUrl of %s and that it is reachable from this host", name)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// probeProvider asks a provider for a one-token completion
+func probeProvider(ctx context.Context, client ai.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticProbeTimeout)
+	defer cancel()
+
+	maxTokens := 1
+	_, err := client.ChatCompletion(ctx, ai.ChatCompletionRequest{
+		Messages:  []ai.Message{{Role: "user", Content: "ping"}},
+		MaxTokens: &maxTokens,
+	})
+	return err
+}
+
+// checkEmbedder embeds a short text. Without an embedder memory search falls
+// back to text matching, so that is only a warning.
+func checkEmbedder(ctx context.Context, embedder vector.Embedder) diagnosticCheck {
+	if embedder == nil {
+		return diagnosticCheck{
+			Name:   "embedder",
+			Status: checkWarn,
+			Detail: "no working embedder, memory search uses text matching",
+			Hint:   "Configure the embedding section, or run Ollama with an embedding model",
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnosticProbeTimeout)
+	defer cancel()
+	embedding, err := embedder.Embed(ctx, embeddingProbeText)
+	if err == nil && len(embedding) == 0 {
+		err = fmt.Errorf("empty embedding")
+	}
+	if err != nil {
+		return diagnosticCheck{
+			Name:   "embedder",
+			Status: checkFail,
+			Detail: fmt.Sprintf("%s cannot embed: %s", embedder.GetModelName(), redact.String(err.Error())),
+			Hint:   "Check that the embedding model is available, e.g. with ollama pull",
+		}
+	}
+	return diagnosticCheck{Name: "embedder", Status: checkPass, Detail: embedder.GetModelName()}
+}
+
+// checkVectorStore adds a probe entry to the vector store and removes it
+func checkVectorStore(ctx context.Context, store vector.VectorStore) diagnosticCheck {
+	hint := "Restart the server; check free memory and disk space"
+	if store == nil {
+		return diagnosticCheck{Name: "vector store", Status: checkFail, Detail: "no vector store", Hint: hint}
+	}
+
+	// An empty vector fits any dimension the store uses
+	id, err := store.Add(ctx, nil, vector.MemoryMetadata{
+		ID:        fmt.Sprintf("diagnostics_%d", time.Now().UnixNano()),
+		Content:   "diagnostics probe",
+		Timestamp: time.Now().Unix(),
+		Tags:      []string{"diagnostics"},
+	})
+	if err == nil {
+		err = store.Delete(ctx, id)
+	}
+	if err != nil {
+		return diagnosticCheck{Name: "vector store", Status: checkFail, Detail: redact.String(err.Error()), Hint: hint}
+	}
+	return diagnosticCheck{Name: "vector store", Status: checkPass}
+}
+
+// checkCron checks that the scheduler is running
+func checkCron(scheduler *cron.CronManager) diagnosticCheck {
+	if scheduler == nil || !scheduler.Running() {
+		return diagnosticCheck{
+			Name:   "cron",
+			Status: checkFail,
+			Detail: "the cron scheduler is not running",
+			Hint:   "Restart the server; scheduled tasks don't run until it is back",
+		}
+	}
+	return diagnosticCheck{Name: "cron", Status: checkPass, Detail: fmt.Sprintf("%d tasks", len(scheduler.ListTasks()))}
+}
+
+// checkWorkspace checks that agent.workspace is an existing directory
+func checkWorkspace(cfg *config.Config) diagnosticCheck {
+	workspace := cfg.Agent.Workspace
+	if workspace == "" {
+		return diagnosticCheck{
+			Name:   "workspace",
+			Status: checkWarn,
+			Detail: "agent.workspace is not set",
+			Hint:   "Set agent.workspace to the directory with the agent's files",
+		}
+	}
+
+	info, err := os.Stat(workspace)
+	if err != nil || !info.IsDir() {
+		return diagnosticCheck{
+			Name:   "workspace",
+			Status: checkFail,
+			Detail: fmt.Sprintf("%s is not a directory", workspace),
+			Hint:   "Create the directory or point agent.workspace at an existing one",
+		}
+	}
+	return diagnosticCheck{Name: "workspace", Status: checkPass, Detail: workspace}
+}
+
+// checkIdentity checks for the identity files in the workspace. Without them
+// the default identity is used, so that is only a warning.
+func checkIdentity(cfg *config.Config) diagnosticCheck {
+	for _, name := range []string{"IDENTITY.md", "SOUL.md"} {
+		path := filepath.Join(cfg.Agent.Workspace, name)
+		if _, err := os.Stat(path); err == nil {
+			return diagnosticCheck{Name: "identity", Status: checkPass, Detail: path}
+		}
+	}
+	return diagnosticCheck{
+		Name:   "identity",
+		Status: checkWarn,
+		Detail: "no IDENTITY.md or SOUL.md in the workspace, using the default identity",
+		Hint:   "Add IDENTITY.md or SOUL.md to agent.workspace",
+	}
+}
+
+// printDiagnostics writes a report with one line per check
+func printDiagnostics(w io.Writer, report diagnosticReport) {
+	for _, check := range report.Checks {
+		line := fmt.Sprintf("[%s] %s", strings.ToUpper(string(check.Status)), check.Name)
+		if check.Detail != "" {
+			line += ": " + check.Detail
+		}
+		fmt.Fprintln(w, line)
+		if check.Hint != "" {
+			fmt.Fprintf(w, "       %s\n", check.Hint)
+		}
+	}
+}
+
+// handleDiagnostics runs the diagnostics against the running server. It
+// responds 503 when a check failed.
+func handleDiagnostics(sources diagnosticSources) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sources.client = aiClient
+		report := runDiagnostics(r.Context(), sources)
+		response := APIResponse{Status: "ok", Data: report}
+		w.Header().Set("Content-Type", "application/json")
+		if report.Failed {
+			response.Status = "error"
+			response.Message = "Some diagnostic checks failed"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// runDoctor runs the diagnostics against a fresh setup from the config,
+// prints them and returns the exit code: 1 when a check failed
+func runDoctor() int {
+	cfg := loadConfig()
+	sources := diagnosticSources{
+		cfg:         cfg,
+		vectorStore: vector.NewInMemoryStore(nil),
+		cron:        cron.NewCronManager(nil),
+	}
+	// initializeAI exits on an invalid config, which the config check reports
+	if len(validateConfig(cfg)) == 0 {
+		initializeAI(cfg)
+		sources.client = aiClient
+	}
+	sources.embedder = configuredEmbedder(cfg)
+	sources.cron.Start()
+	defer sources.cron.Stop()
+
+	report := runDiagnostics(context.Background(), sources)
+	fmt.Println()
+	printDiagnostics(os.Stdout, report)
+	if report.Failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goclaw/internal/config"
+	"goclaw/internal/cron"
+	"goclaw/internal/vector"
+	"goclaw/pkg/ai"
+)
+
+type unreachableAIClient struct{}
+
+func (unreachableAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return nil, errors.New("dial tcp 10.0.0.1:443: connection refused")
+}
+
+func (unreachableAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func healthyDiagnosticSources(t *testing.T) diagnosticSources {
+	t.Helper()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "IDENTITY.md"), []byte("# Identity\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Agent.Workspace = workspace
+
+	scheduler := cron.NewCronManager(nil)
+	scheduler.Start()
+	t.Cleanup(func() { scheduler.Stop() })
+
+	multiClient := ai.NewMultiProviderClient()
+	multiClient.AddProvider("minimax", &fakeAIClient{})
+	return diagnosticSources{
+		cfg:         cfg,
+		client:      multiClient,
+		embedder:    fakeEmbedder{},
+		vectorStore: vector.NewInMemoryStore(nil),
+		cron:        scheduler,
+	}
+}
+
+func findCheck(report diagnosticReport, name string) (diagnosticCheck, bool) {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check, true
+		}
+	}
+	return diagnosticCheck{}, false
+}
+
+func TestDiagnosticsPassWhenHealthy(t *testing.T) {
+	report := runDiagnostics(context.Background(), healthyDiagnosticSources(t))
+	for _, check := range report.Checks {
+		if check.Status != checkPass {
+			t.Errorf("check %s = %s (%s), want pass", check.Name, check.Status, check.Detail)
+		}
+	}
+	if report.Failed {
+		t.Error("report should not be failed")
+	}
+}
+
+func TestDiagnosticsReportFailingProvider(t *testing.T) {
+	sources := healthyDiagnosticSources(t)
+	sources.client.(*ai.MultiProviderClient).AddProvider("zhipu", unreachableAIClient{})
+
+	report := runDiagnostics(context.Background(), sources)
+	if !report.Failed {
+		t.Error("a failing provider should fail the report")
+	}
+	check, ok := findCheck(report, "provider zhipu")
+	if !ok || check.Status != checkFail {
+		t.Fatalf("provider zhipu check = %+v, want a failure", check)
+	}
+	if !strings.Contains(check.Detail, "connection refused") || check.Hint == "" {
+		t.Errorf("check = %+v, want the error and a hint", check)
+	}
+	if check, _ := findCheck(report, "provider minimax"); check.Status != checkPass {
+		t.Errorf("provider minimax check = %+v, want a pass", check)
+	}
+
+	var out strings.Builder
+	printDiagnostics(&out, report)
+	if !strings.Contains(out.String(), "[FAIL] provider zhipu: ") {
+		t.Errorf("printed report = %q, want the failed provider", out.String())
+	}
+}
+
+func TestDiagnosticsWarnAndFail(t *testing.T) {
+	sources := healthyDiagnosticSources(t)
+	sources.embedder = nil
+	sources.cron.Stop()
+	os.Remove(filepath.Join(sources.cfg.Agent.Workspace, "IDENTITY.md"))
+	sources.cfg.Agent.Timeouts.Batch = "soon"
+
+	report := runDiagnostics(context.Background(), sources)
+	want := map[string]checkStatus{
+		"config":    checkFail,
+		"providers": checkFail,
+		"embedder":  checkWarn,
+		"cron":      checkFail,
+		"identity":  checkWarn,
+		"workspace": checkPass,
+	}
+	for name, status := range want {
+		if check, _ := findCheck(report, name); check.Status != status {
+			t.Errorf("check %s = %+v, want %s", name, check, status)
+		}
+	}
+}
+
+func TestHandleDiagnosticsFailsWith503(t *testing.T) {
+	sources := healthyDiagnosticSources(t)
+	useFakeAI(t, unreachableAIClient{})
+
+	rec := httptest.NewRecorder()
+	handleDiagnostics(sources)(rec, httptest.NewRequest(http.MethodGet, "/api/diagnostics", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	var resp struct {
+		Data diagnosticReport `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if check, _ := findCheck(resp.Data, "provider default"); check.Status != checkFail {
+		t.Errorf("provider check = %+v, want the running client's failure", check)
+	}
+}
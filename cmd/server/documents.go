@@ -0,0 +1,95 @@
+// Package main provides document indexing into the vector store for Goclaw
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/internal/redact"
+	"goclaw/internal/vector"
+)
+
+// maxDocumentSize is the largest document accepted for indexing
+const maxDocumentSize = 1 << 20
+
+// vectorPersistence returns the file the vector store is kept in and how
+// often it is saved, from memory.vectorFile and memory.vectorSaveInterval
+func vectorPersistence(cfg *config.Config) (string, time.Duration, error) {
+	path := cfg.Memory.VectorFile
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), ".openclaw", "workspace", "goclaw_vectors.json")
+	}
+	interval := vector.DefaultAutosaveInterval
+	if cfg.Memory.VectorSaveInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(cfg.Memory.VectorSaveInterval)
+		if err != nil || interval <= 0 {
+			return "", 0, fmt.Errorf("invalid memory.vectorSaveInterval %q: must be a positive duration such as \"5m\"", cfg.Memory.VectorSaveInterval)
+		}
+	}
+	return path, interval, nil
+}
+
+// documentIndexer is a vector store that deduplicates documents by content
+type documentIndexer interface {
+	IndexDocument(ctx context.Context, name string, content []byte) (string, bool, error)
+}
+
+// handleIndexDocument serves POST /api/documents, indexing an uploaded
+// document. Content that is already indexed isn't embedded again; the
+// response's "indexed" is false and the existing entry is returned.
+func handleIndexDocument(store vector.VectorStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		indexer, ok := store.(documentIndexer)
+		if !ok {
+			http.Error(w, "The vector store does not support indexing documents", http.StatusNotImplemented)
+			return
+		}
+
+		var req struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 2*maxDocumentSize)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Content == "" {
+			http.Error(w, "content is required", http.StatusBadRequest)
+			return
+		}
+		if len(req.Content) > maxDocumentSize {
+			http.Error(w, "Document is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		content := []byte(req.Content)
+		id, indexed, err := indexer.IndexDocument(r.Context(), req.Name, content)
+		if err != nil {
+			http.Error(w, redact.String(err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data: map[string]interface{}{
+				"id":          id,
+				"contentHash": vector.ContentHash(content),
+				"indexed":     indexed,
+			},
+		})
+	}
+}
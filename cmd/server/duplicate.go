@@ -0,0 +1,96 @@
+// Package main provides duplicate chat message suppression for Goclaw
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+)
+
+// duplicateSuppression answers a message resent shortly after the last one,
+// by a double click or a retry, with the previous response instead of a new
+// generation. The zero value suppresses nothing.
+type duplicateSuppression struct {
+	window    time.Duration // How long after a message a resend counts as a duplicate, 0 to turn suppression off
+	threshold float64       // Similarity from which messages are the same, 1 for identical only
+}
+
+// defaultDuplicateThreshold only treats identical messages, ignoring case
+// and spacing, as duplicates
+const defaultDuplicateThreshold = 1.0
+
+// duplicateConfig parses agent.duplicateWindow and agent.duplicateThreshold
+func duplicateConfig(cfg *config.Config) (duplicateSuppression, error) {
+	var d duplicateSuppression
+	if cfg.Agent.DuplicateWindow != "" {
+		window, err := time.ParseDuration(cfg.Agent.DuplicateWindow)
+		if err != nil || window < 0 {
+			return d, fmt.Errorf("invalid agent.duplicateWindow %q: must be a duration such as \"10s\", or \"0\" to turn it off", cfg.Agent.DuplicateWindow)
+		}
+		d.window = window
+	}
+
+	d.threshold = defaultDuplicateThreshold
+	if cfg.Agent.DuplicateThreshold != 0 {
+		if cfg.Agent.DuplicateThreshold < 0 || cfg.Agent.DuplicateThreshold > 1 {
+			return d, fmt.Errorf("invalid agent.duplicateThreshold %v: must be between 0 and 1", cfg.Agent.DuplicateThreshold)
+		}
+		d.threshold = cfg.Agent.DuplicateThreshold
+	}
+	return d, nil
+}
+
+// previousResponse returns the response to the session's last message when
+// message, received at the given time, duplicates it: the history ends with
+// that message and its response, the message came within the window, and
+// the two are similar enough
+func (d duplicateSuppression) previousResponse(history []chat.Message, message string, received time.Time) (string, bool) {
+	if d.window <= 0 || len(history) < 2 {
+		return "", false
+	}
+
+	last, previous := history[len(history)-1], history[len(history)-2]
+	if last.Role != "assistant" || previous.Role != "user" {
+		return "", false
+	}
+	if received.Sub(previous.Timestamp) > d.window {
+		return "", false
+	}
+	if messageSimilarity(previous.Content, message) < d.threshold {
+		return "", false
+	}
+	return last.Content, true
+}
+
+// messageSimilarity is 1 for messages that differ only in case and spacing,
+// and otherwise the share of terms the two have in common (Jaccard)
+func messageSimilarity(a, b string) float64 {
+	a = strings.ToLower(strings.Join(strings.Fields(a), " "))
+	b = strings.ToLower(strings.Join(strings.Fields(b), " "))
+	if a == b {
+		return 1
+	}
+
+	terms := make(map[string]int)
+	for _, term := range memory.Tokenize(a) {
+		terms[term] |= 1
+	}
+	for _, term := range memory.Tokenize(b) {
+		terms[term] |= 2
+	}
+	if len(terms) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for _, in := range terms {
+		if in == 3 {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(terms))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+	"goclaw/internal/vector"
+)
+
+func TestHandleChatSuppressesDuplicateMessage(t *testing.T) {
+	client := &fakeAIClient{reply: "Paris is the capital of France."}
+	useFakeAI(t, client)
+
+	cfg := &config.Config{}
+	cfg.Agent.DuplicateWindow = "10s"
+	chatMgr := chat.NewChatManager(100)
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	handler := handleChat(nil, memStore, chatMgr, vector.NewInMemoryStore(nil), tools.NewRegistry(), cfg)
+
+	message := map[string]interface{}{"message": "What is the capital of France?", "sessionId": "s1"}
+	first := postChat(t, handler, message)
+	second := postChat(t, handler, message)
+
+	if len(client.models) != 1 {
+		t.Errorf("generations = %d, want 1 for a message sent twice", len(client.models))
+	}
+	if second["duplicate"] != true || second["response"] != first["response"] {
+		t.Errorf("second reply = %v, want the first response flagged as a duplicate", second)
+	}
+	if messages, _ := chatMgr.GetMessages("s1"); len(messages) != 2 {
+		t.Errorf("history has %d messages, want the question and answer once", len(messages))
+	}
+	if count := memStore.Stats().ShortTermCount; count != 1 {
+		t.Errorf("short-term count = %d, want 1", count)
+	}
+
+	// A different message is answered
+	postChat(t, handler, map[string]interface{}{"message": "And of Italy?", "sessionId": "s1"})
+	if len(client.models) != 2 {
+		t.Errorf("generations = %d, want a new one for a different message", len(client.models))
+	}
+
+	// Without a window every message is answered
+	handler = handleChat(nil, memStore, chatMgr, vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+	postChat(t, handler, map[string]interface{}{"message": "And of Italy?", "sessionId": "s1"})
+	if len(client.models) != 3 {
+		t.Errorf("generations = %d, want suppression off by default", len(client.models))
+	}
+}
+
+func TestDuplicatePreviousResponse(t *testing.T) {
+	now := time.Now()
+	history := []chat.Message{
+		{Role: "user", Content: "What's the weather in Paris today?", Timestamp: now.Add(-5 * time.Second)},
+		{Role: "assistant", Content: "Sunny.", Timestamp: now.Add(-4 * time.Second)},
+	}
+
+	tests := []struct {
+		name      string
+		d         duplicateSuppression
+		message   string
+		received  time.Time
+		duplicate bool
+	}{
+		{"identical", duplicateSuppression{window: 10 * time.Second, threshold: 1}, "What's the weather in Paris today?", now, true},
+		{"case and spacing", duplicateSuppression{window: 10 * time.Second, threshold: 1}, "what's the weather  in paris today?", now, true},
+		{"similar below identical", duplicateSuppression{window: 10 * time.Second, threshold: 1}, "What's the weather in Paris?", now, false},
+		{"similar within threshold", duplicateSuppression{window: 10 * time.Second, threshold: 0.6}, "What's the weather in Paris?", now, true},
+		{"outside the window", duplicateSuppression{window: 10 * time.Second, threshold: 1}, "What's the weather in Paris today?", now.Add(time.Minute), false},
+		{"turned off", duplicateSuppression{threshold: 1}, "What's the weather in Paris today?", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, duplicate := tt.d.previousResponse(history, tt.message, tt.received)
+			if duplicate != tt.duplicate || (duplicate && response != "Sunny.") {
+				t.Errorf("previousResponse() = %q, %v; want duplicate %v", response, duplicate, tt.duplicate)
+			}
+		})
+	}
+
+	// A message still waiting for its answer is not repeated
+	if _, duplicate := (duplicateSuppression{window: time.Minute, threshold: 1}).previousResponse(history[:1], history[0].Content, now); duplicate {
+		t.Error("previousResponse() matched a message without a response")
+	}
+}
+
+func TestDuplicateConfig(t *testing.T) {
+	cfg := &config.Config{}
+	if d, err := duplicateConfig(cfg); err != nil || d.window != 0 || d.threshold != defaultDuplicateThreshold {
+		t.Errorf("duplicateConfig() = %+v, %v; want suppression off", d, err)
+	}
+
+	cfg.Agent.DuplicateWindow, cfg.Agent.DuplicateThreshold = "5s", 0.8
+	if d, err := duplicateConfig(cfg); err != nil || d.window != 5*time.Second || d.threshold != 0.8 {
+		t.Errorf("duplicateConfig() = %+v, %v; want 5s and 0.8", d, err)
+	}
+
+	cfg.Agent.DuplicateThreshold = 1.5
+	if _, err := duplicateConfig(cfg); err == nil {
+		t.Error("duplicateConfig() accepted a threshold above 1")
+	}
+	cfg.Agent.DuplicateWindow, cfg.Agent.DuplicateThreshold = "soon", 0
+	if _, err := duplicateConfig(cfg); err == nil {
+		t.Error("duplicateConfig() accepted an invalid window")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/vector"
+)
+
+func TestSelectEmbedder(t *testing.T) {
+	tests := []struct {
+		name      string
+		embedding config.EmbeddingConfig
+		wantModel string
+		check     func(vector.Embedder) bool
+		wantErr   bool
+	}{
+		{
+			name:      "ollama",
+			embedding: config.EmbeddingConfig{API: "ollama", BaseURL: "http://gpu-box:11434", Model: "mxbai-embed-large"},
+			wantModel: "mxbai-embed-large",
+			check: func(e vector.Embedder) bool {
+				o, ok := e.(*vector.OllamaEmbedder)
+				return ok && o.Endpoint == "http://gpu-box:11434"
+			},
+		},
+		{
+			name:      "openai compatible",
+			embedding: config.EmbeddingConfig{API: "openai", ApiKey: "sk-test", BaseURL: "https://example.com/v1", Model: "text-embedding-3-large"},
+			wantModel: "text-embedding-3-large",
+			check: func(e vector.Embedder) bool {
+				o, ok := e.(*vector.OpenAIEmbedder)
+				return ok && o.BaseURL == "https://example.com/v1" && o.ApiKey == "sk-test"
+			},
+		},
+		{
+			name:      "zhipu defaults",
+			embedding: config.EmbeddingConfig{API: "zhipu", ApiKey: "zk-test"},
+			wantModel: "embedding-2",
+			check: func(e vector.Embedder) bool {
+				o, ok := e.(*vector.OpenAIEmbedder)
+				return ok && o.BaseURL == "https://open.bigmodel.cn/api/paas/v4"
+			},
+		},
+		{
+			name:      "unknown api",
+			embedding: config.EmbeddingConfig{API: "carrier-pigeon"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder, err := selectEmbedder(&config.Config{Embedding: tt.embedding})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error for unsupported api")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectEmbedder() error = %v", err)
+			}
+			if embedder.GetModelName() != tt.wantModel {
+				t.Errorf("model = %v, want %v", embedder.GetModelName(), tt.wantModel)
+			}
+			if !tt.check(embedder) {
+				t.Errorf("unexpected embedder: %#v", embedder)
+			}
+		})
+	}
+}
+
+func TestConfiguredEmbedderFromChatProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		wantBaseURL string
+		wantModel   string
+	}{
+		{
+			name: "openai compatible provider",
+			cfg: &config.Config{Models: map[string]interface{}{"providers": map[string]interface{}{
+				"gemini": map[string]interface{}{"api": "gemini", "apiKey": "g-test"},
+				"qwen":   map[string]interface{}{"api": "openai-completions", "apiKey": "sk-test", "baseUrl": "https://qwen.example.com/v1", "embeddingModel": "text-embedding-v3"},
+			}}},
+			wantBaseURL: "https://qwen.example.com/v1",
+			wantModel:   "text-embedding-v3",
+		},
+		{
+			name:        "zhipu",
+			cfg:         &config.Config{Zhipu: config.ZhipuConfig{ApiKey: "zk-test"}},
+			wantBaseURL: "https://open.bigmodel.cn/api/paas/v4",
+			wantModel:   "embedding-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder, ok := configuredEmbedder(tt.cfg).(*vector.OpenAIEmbedder)
+			if !ok {
+				t.Fatalf("configuredEmbedder() = %#v, want an OpenAIEmbedder", embedder)
+			}
+			if embedder.BaseURL != tt.wantBaseURL || embedder.Model != tt.wantModel {
+				t.Errorf("embedder = %s %s, want %s %s", embedder.BaseURL, embedder.Model, tt.wantBaseURL, tt.wantModel)
+			}
+		})
+	}
+
+	// A provider without an OpenAI-compatible API can't embed
+	cfg := &config.Config{Models: map[string]interface{}{"providers": map[string]interface{}{
+		"claude": map[string]interface{}{"api": "anthropic-messages", "apiKey": "sk-ant"},
+	}}}
+	if embedder := configuredEmbedder(cfg); embedder != nil {
+		t.Errorf("configuredEmbedder() = %#v, want nil", embedder)
+	}
+}
+
+// chatOnlyEmbedder stands in for a chat provider that can't embed
+type chatOnlyEmbedder struct {
+	calls int
+}
+
+func (e *chatOnlyEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	return nil, fmt.Errorf("model glm-4 does not support embeddings")
+}
+
+func (e *chatOnlyEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	return nil, fmt.Errorf("model glm-4 does not support embeddings")
+}
+
+func (e *chatOnlyEmbedder) GetModelName() string { return "glm-4" }
+
+func TestEmbedderProbeFallsBackToTextSearch(t *testing.T) {
+	failing := &chatOnlyEmbedder{}
+	embedder, status := probeEmbedder(context.Background(), failing)
+	if embedder != nil || status.Available || status.Reason == "" {
+		t.Fatalf("probeEmbedder() = %v, %+v; want semantic search disabled with a reason", embedder, status)
+	}
+
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	memStore.AddLongTerm("The user prefers Go for backend services", nil, nil)
+	handler := handleMemorySearch(embedder, memStore)
+
+	for i := 0; i < 3; i++ {
+		payload, _ := json.Marshal(map[string]interface{}{"query": "backend language"})
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/memory/search", bytes.NewReader(payload)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %d: status = %d, body = %s", i, rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			Data []memory.MemorySearchResult `json:"data"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if len(resp.Data) != 1 {
+			t.Errorf("search %d: %d results, want the text match", i, len(resp.Data))
+		}
+	}
+	if failing.calls != 1 {
+		t.Errorf("Embed called %d times, want only the startup probe", failing.calls)
+	}
+
+	rec := httptest.NewRecorder()
+	handleHealth(embedder, status)(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health struct {
+		Data struct {
+			Embedding embeddingStatus `json:"embedding"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&health)
+	if health.Data.Embedding.Available || health.Data.Embedding.Model != "glm-4" {
+		t.Errorf("/health embedding = %+v, want unavailable glm-4", health.Data.Embedding)
+	}
+}
+
+func TestEmbedderProbeKeepsWorkingEmbedder(t *testing.T) {
+	embedder, status := probeEmbedder(context.Background(), fakeEmbedder{})
+	if embedder == nil || !status.Available {
+		t.Errorf("probeEmbedder() = %v, %+v; want the embedder kept", embedder, status)
+	}
+}
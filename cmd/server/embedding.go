@@ -0,0 +1,51 @@
+// Package main provides the embedding capability check for Goclaw
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"goclaw/internal/redact"
+	"goclaw/internal/vector"
+)
+
+// embeddingProbeText is embedded at startup to check the embedder works
+const embeddingProbeText = "ping"
+
+// embeddingProbeTimeout bounds the startup embedding check
+const embeddingProbeTimeout = 10 * time.Second
+
+// embeddingStatus reports whether semantic memory search is available
+type embeddingStatus struct {
+	Available bool   `json:"available"`
+	Model     string `json:"model,omitempty"`
+	Reason    string `json:"reason,omitempty"` // Why semantic search is disabled
+}
+
+// probeEmbedder embeds a short text to check that the embedder can embed at
+// all. If it can't, semantic search is disabled up front: nil is returned and
+// memory search falls back to text matching instead of failing every call.
+func probeEmbedder(ctx context.Context, embedder vector.Embedder) (vector.Embedder, embeddingStatus) {
+	if embedder == nil {
+		return nil, embeddingStatus{Reason: "no embedder configured"}
+	}
+
+	status := embeddingStatus{Model: embedder.GetModelName()}
+
+	ctx, cancel := context.WithTimeout(ctx, embeddingProbeTimeout)
+	defer cancel()
+	embedding, err := embedder.Embed(ctx, embeddingProbeText)
+	if err == nil && len(embedding) == 0 {
+		err = fmt.Errorf("empty embedding")
+	}
+	if err != nil {
+		status.Reason = fmt.Sprintf("%s cannot embed: %s", status.Model, redact.String(err.Error()))
+		log.Printf("Warning: semantic search disabled, using text search: %s", status.Reason)
+		return nil, status
+	}
+
+	status.Available = true
+	return embedder, status
+}
@@ -0,0 +1,77 @@
+// Package main provides the greeting API for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"goclaw/internal/config"
+	"goclaw/internal/identity"
+)
+
+// supportedGreetingLanguages lists the languages the greeting can be rendered in
+var supportedGreetingLanguages = []string{"zh", "en"}
+
+// defaultGreetingLanguage is used when no supported language is requested
+const defaultGreetingLanguage = "zh"
+
+func handleGreeting(identityManager *identity.IdentityManager, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		lang := negotiateLanguage(r)
+
+		greeting := cfg.Agent.Greeting
+		if greeting == "" {
+			greeting = identityManager.Greeting(lang)
+		}
+
+		data := map[string]interface{}{
+			"greeting": greeting,
+			"language": lang,
+		}
+		if id := identityManager.GetIdentity(); id != nil {
+			data["name"] = id.Name
+			data["emoji"] = id.Emoji
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data:   data,
+		})
+	}
+}
+
+// negotiateLanguage picks the greeting language from the "lang" query
+// parameter, then the Accept-Language header, then the default
+func negotiateLanguage(r *http.Request) string {
+	if lang := matchLanguage(r.URL.Query().Get("lang")); lang != "" {
+		return lang
+	}
+
+	// Accept-Language is ordered by preference in practice; q-values are ignored
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if lang := matchLanguage(tag); lang != "" {
+			return lang
+		}
+	}
+
+	return defaultGreetingLanguage
+}
+
+// matchLanguage maps a language tag like "en-US" to a supported language
+func matchLanguage(tag string) string {
+	base := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+	for _, lang := range supportedGreetingLanguages {
+		if base == lang {
+			return lang
+		}
+	}
+	return ""
+}
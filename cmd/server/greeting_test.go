@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goclaw/internal/config"
+	"goclaw/internal/identity"
+)
+
+func newTestIdentityManager(t *testing.T) *identity.IdentityManager {
+	t.Helper()
+
+	workspace := t.TempDir()
+	content := "- **Name:** Pip\n- **Emoji:** 🐧\n"
+	if err := os.WriteFile(filepath.Join(workspace, "IDENTITY.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write identity: %v", err)
+	}
+
+	im := identity.NewIdentityManager(workspace)
+	if err := im.LoadIdentityFromFiles(); err != nil {
+		t.Fatalf("failed to load identity: %v", err)
+	}
+	return im
+}
+
+func getGreeting(t *testing.T, handler http.HandlerFunc, url, acceptLanguage string) map[string]interface{} {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	var resp struct {
+		Status string                 `json:"status"`
+		Data   map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestHandleGreeting(t *testing.T) {
+	handler := handleGreeting(newTestIdentityManager(t), &config.Config{})
+
+	tests := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		wantLang       string
+		wantGreeting   string
+	}{
+		{"default", "/api/greeting", "", "zh", "您好！我是Pip 🐧。今天我能为您做些什么？"},
+		{"query param", "/api/greeting?lang=en", "", "en", "Hello! I'm Pip 🐧. What can I do for you today?"},
+		{"accept language", "/api/greeting", "fr-FR, en-US;q=0.8", "en", "Hello! I'm Pip 🐧. What can I do for you today?"},
+		{"unsupported query falls back to header", "/api/greeting?lang=de", "zh-CN", "zh", "您好！我是Pip 🐧。今天我能为您做些什么？"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := getGreeting(t, handler, tt.url, tt.acceptLanguage)
+			if data["language"] != tt.wantLang {
+				t.Errorf("language = %v, want %v", data["language"], tt.wantLang)
+			}
+			if data["greeting"] != tt.wantGreeting {
+				t.Errorf("greeting = %v, want %v", data["greeting"], tt.wantGreeting)
+			}
+		})
+	}
+}
+
+func TestHandleGreetingConfigOverride(t *testing.T) {
+	cfg := &config.Config{Agent: config.AgentConfig{Greeting: "Welcome back!"}}
+	handler := handleGreeting(newTestIdentityManager(t), cfg)
+
+	data := getGreeting(t, handler, "/api/greeting?lang=en", "")
+	if data["greeting"] != "Welcome back!" {
+		t.Errorf("greeting = %v, want config override", data["greeting"])
+	}
+}
@@ -0,0 +1,122 @@
+// Package main distills idle chat sessions into long-term memory for Goclaw
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/vector"
+	"goclaw/pkg/ai"
+)
+
+const (
+	// idleSummaryTag marks memories distilled from an idle session
+	idleSummaryTag = "summary"
+	// pinnedTag marks memories kept with sessions.idleSummaryPin
+	pinnedTag = "pinned"
+)
+
+// idleSummaryPrompt asks the model for the durable facts of a conversation
+const idleSummaryPrompt = `Extract the durable facts from the conversation below that are worth remembering in later conversations: the user's preferences, personal details, decisions and commitments. Skip small talk and anything only relevant to this conversation.
+Reply with one fact per line, each starting with "- ". Reply with NONE if there is nothing worth remembering.`
+
+// janitorConfig builds the session janitor's config from sessions.idleAfter.
+// Facts are only distilled with sessions.idleSummary, since it costs a model
+// call per idle session.
+func janitorConfig(cfg *config.Config, embedder vector.Embedder, memStore *memory.MemoryStore) (chat.JanitorConfig, error) {
+	idleAfter, err := time.ParseDuration(cfg.Sessions.IdleAfter)
+	if err != nil || idleAfter <= 0 {
+		return chat.JanitorConfig{}, fmt.Errorf("invalid sessions.idleAfter %q", cfg.Sessions.IdleAfter)
+	}
+
+	janitor := chat.JanitorConfig{IdleAfter: idleAfter}
+	if cfg.Sessions.IdleSummary {
+		janitor.OnIdle = idleSummaryHook(cfg, embedder, memStore)
+	}
+	return janitor, nil
+}
+
+// idleSummaryHook returns a hook storing an idle session's durable facts as
+// long-term memories. It reads the AI client when called, since the janitor
+// is set up before it.
+func idleSummaryHook(cfg *config.Config, embedder vector.Embedder, memStore *memory.MemoryStore) chat.IdleHook {
+	return func(session chat.ChatSession) error {
+		transcript := sessionTranscript(session)
+		if transcript == "" {
+			return nil
+		}
+		if aiClient == nil {
+			return fmt.Errorf("no AI client configured")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), aiTimeout(cfg, ai.UseBatch))
+		defer cancel()
+
+		resp, err := aiClient.ChatCompletion(ctx, ai.ChatCompletionRequest{
+			Model: primaryChatModel,
+			Messages: []ai.Message{
+				{Role: "system", Content: idleSummaryPrompt},
+				{Role: "user", Content: transcript},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("idle summary failed: %w", ai.TimeoutErr(ctx, err, ai.UseBatch, aiTimeout(cfg, ai.UseBatch)))
+		}
+		if len(resp.Choices) == 0 {
+			return fmt.Errorf("idle summary returned no choices")
+		}
+
+		tags := []string{idleSummaryTag}
+		if cfg.Sessions.IdleSummaryPin {
+			tags = append(tags, pinnedTag)
+		}
+		for _, fact := range parseFacts(resp.Choices[0].Message.Content) {
+			var embedding []float32
+			if embedder != nil {
+				if embedding, err = embedder.Embed(ctx, fact); err != nil {
+					return err
+				}
+			}
+			if err := memStore.AddLongTerm(fact, embedding, map[string]interface{}{
+				"session": session.ID,
+				"source":  "idle-summary",
+				"tags":    tags,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// sessionTranscript renders a session's user and assistant messages
+func sessionTranscript(session chat.ChatSession) string {
+	var transcript strings.Builder
+	for _, msg := range session.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, strings.TrimSpace(msg.Content))
+	}
+	return transcript.String()
+}
+
+// parseFacts reads the "- " lines of an idle summary reply
+func parseFacts(reply string) []string {
+	var facts []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "- ") {
+			continue
+		}
+		if fact := strings.TrimSpace(strings.TrimPrefix(line, "- ")); fact != "" {
+			facts = append(facts, fact)
+		}
+	}
+	return facts
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+)
+
+func TestIdleSessionFactBecomesLongTermMemory(t *testing.T) {
+	fake := &fakeAIClient{reply: "- The user's cat is named Miso\n- The user prefers tea over coffee"}
+	useFakeAI(t, fake)
+
+	cfg := &config.Config{}
+	cfg.Sessions.IdleAfter = "1h"
+	cfg.Sessions.IdleSummary = true
+	cfg.Sessions.IdleSummaryPin = true
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	janitor, err := janitorConfig(cfg, fakeEmbedder{}, memStore)
+	if err != nil {
+		t.Fatalf("janitorConfig() error = %v", err)
+	}
+
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("main", "")
+	chatMgr.CreateSession("s1", "")
+	chatMgr.AddMessage("s1", "user", "My cat Miso knocked my tea over again")
+	chatMgr.AddMessage("s1", "assistant", "Poor tea! How is Miso?")
+	session, _ := chatMgr.GetSession("s1")
+	session.UpdatedAt = time.Now().Add(-2 * time.Hour)
+
+	if _, err := chatMgr.SweepIdle(janitor.IdleAfter, janitor.OnIdle); err != nil {
+		t.Fatalf("SweepIdle() error = %v", err)
+	}
+	if _, exists := chatMgr.GetSession("s1"); exists {
+		t.Error("idle session should be archived")
+	}
+	if !strings.Contains(strings.Join(fake.prompts, "\n"), "My cat Miso") {
+		t.Error("the extraction prompt should include the conversation")
+	}
+
+	results, err := memStore.Search(context.Background(), "cat named Miso", nil, 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	var found *memory.MemoryEntry
+	for i := range results {
+		if results[i].Entry.Content == "The user's cat is named Miso" {
+			found = &results[i].Entry
+		}
+	}
+	if found == nil {
+		t.Fatalf("Search() = %+v, want the idle session's fact", results)
+	}
+	if strings.Join(found.Tags, ",") != "summary,pinned" {
+		t.Errorf("memory tags = %v, want summary and pinned", found.Tags)
+	}
+}
+
+func TestIdleSummaryIsOptIn(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sessions.IdleAfter = "30m"
+	janitor, err := janitorConfig(cfg, nil, memory.NewMemoryStore(memory.DefaultConfig()))
+	if err != nil {
+		t.Fatalf("janitorConfig() error = %v", err)
+	}
+	if janitor.OnIdle != nil {
+		t.Error("idle sessions should only be summarized with sessions.idleSummary")
+	}
+
+	cfg.Sessions.IdleAfter = "soon"
+	if _, err := janitorConfig(cfg, nil, nil); err == nil {
+		t.Error("janitorConfig() should reject an invalid sessions.idleAfter")
+	}
+}
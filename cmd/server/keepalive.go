@@ -0,0 +1,88 @@
+// Package main provides keepalive comments for streamed chat responses
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"goclaw/internal/config"
+)
+
+// DefaultStreamKeepalive is how often a waiting stream sends a keepalive
+const DefaultStreamKeepalive = 5 * time.Second
+
+// keepaliveComment is an SSE comment line, which clients ignore
+const keepaliveComment = ": keepalive\n\n"
+
+// parseStreamKeepalive reads agent.streamKeepalive: empty uses the default and
+// "0" turns keepalives off
+func parseStreamKeepalive(cfg *config.Config) (time.Duration, error) {
+	if cfg.Agent.StreamKeepalive == "" {
+		return DefaultStreamKeepalive, nil
+	}
+	interval, err := time.ParseDuration(cfg.Agent.StreamKeepalive)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid agent.streamKeepalive %q", cfg.Agent.StreamKeepalive)
+	}
+	return interval, nil
+}
+
+// streamKeepalive is the keepalive interval of streams. An invalid value,
+// which main rejects at startup, uses the default.
+func streamKeepalive(cfg *config.Config) time.Duration {
+	interval, err := parseStreamKeepalive(cfg)
+	if err != nil {
+		return DefaultStreamKeepalive
+	}
+	return interval
+}
+
+// keepalive writes SSE comments to a stream every interval while it waits for
+// the model, so proxies don't drop a slow but working generation as idle
+type keepalive struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startKeepalive starts sending keepalives to w. Nothing else may write to w
+// until Stop returns. An interval of 0 sends none.
+func startKeepalive(w http.ResponseWriter, interval time.Duration) *keepalive {
+	k := &keepalive{stop: make(chan struct{}), done: make(chan struct{})}
+	if interval <= 0 {
+		close(k.done)
+		return k
+	}
+
+	go func() {
+		defer close(k.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, keepaliveComment); err != nil {
+					return
+				}
+				if flusher, ok := w.(http.Flusher); ok {
+					flusher.Flush()
+				}
+			case <-k.stop:
+				return
+			}
+		}
+	}()
+	return k
+}
+
+// Stop stops the keepalives and waits until the last one is written. It is
+// safe to call more than once.
+func (k *keepalive) Stop() {
+	select {
+	case <-k.stop:
+	default:
+		close(k.stop)
+	}
+	<-k.done
+}
@@ -4,22 +4,36 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"goclaw/internal/agent"
 	"goclaw/internal/chat"
 	"goclaw/internal/config"
+	"goclaw/internal/cron"
+	"goclaw/internal/format"
 	"goclaw/internal/heartbeat"
 	"goclaw/internal/identity"
+	"goclaw/internal/intent"
 	"goclaw/internal/memory"
+	"goclaw/internal/ollama"
+	"goclaw/internal/redact"
+	"goclaw/internal/security"
+	"goclaw/internal/tasklist"
 	"goclaw/internal/tools"
 	"goclaw/internal/tools/builtin"
 	"goclaw/internal/vector"
 	"goclaw/pkg/ai"
+
+	"github.com/gorilla/mux"
 )
 
 // Version info
@@ -33,11 +47,23 @@ type APIResponse struct {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor())
+	}
+
+	startedAt := time.Now()
 	fmt.Printf("Goclaw Server v%s\n", Version)
-	fmt.Println("======================\n")
+	fmt.Println("======================")
+	fmt.Println()
 
 	// Load configuration
 	cfg := loadConfig()
+	if errs := validateConfig(cfg); len(errs) > 0 {
+		log.Fatalf("Invalid config: %v", errs[0])
+	}
+	if err := redact.Configure(cfg.Redaction.Fields, cfg.Redaction.Patterns); err != nil {
+		log.Fatalf("Invalid redaction config: %v", err)
+	}
 
 	// Initialize identity manager
 	identityManager := identity.NewIdentityManager(cfg.Agent.Workspace)
@@ -50,45 +76,184 @@ func main() {
 	}
 
 	// Initialize components
-	var embedder vector.Embedder
-	// Check if any AI provider is configured
-	hasAIProvider := cfg.Zhipu.ApiKey != "" || 
-		(cfg.Models["providers"] != nil && len(cfg.Models["providers"].(map[string]interface{})) > 0)
+	embedder, embeddingState := probeEmbedder(context.Background(), configuredEmbedder(cfg))
 	
-	if hasAIProvider {
-		// AI provider is configured, skip Ollama embedder
-		fmt.Println("AI provider configured - skipping Ollama embedder initialization")
-		embedder = nil
-	} else {
-		// Only try to initialize Ollama embedder if no other AI provider is configured
-		embedder = initEmbedder(cfg)
+	contextMaxAge, err := contextShortTermMaxAge(cfg)
+	if err != nil {
+		log.Fatalf("Invalid memory config: %v", err)
+	}
+	consolidateAfter, consolidateInterval, err := consolidation(cfg)
+	if err != nil {
+		log.Fatalf("Invalid memory config: %v", err)
+	}
+	shortTermExpiry, err := shortTermTTL(cfg)
+	if err != nil {
+		log.Fatalf("Invalid memory config: %v", err)
+	}
+	memoryConfig := memory.MemoryConfig{
+		ShortTermMax:           50,
+		WorkingMax:             10,
+		SimilarityCut:          0.7,
+		ConsolidateBatch:       cfg.Memory.ConsolidateBatch,
+		ConsolidateAfter:       consolidateAfter,
+		ShortTermTTL:           shortTermExpiry,
+		ContextLongTermK:       cfg.Memory.ContextLongTermK,
+		ContextShortTermK:      cfg.Memory.ContextShortTermK,
+		ContextShortTermMaxAge: contextMaxAge,
+		Language:               cfg.Memory.Language,
+		SameLanguageBoost:      float32(cfg.Memory.SameLanguageBoost),
+	}
+	if cfg.Memory.Translate {
+		memoryConfig.Translator = modelTranslator{}
+	}
+	memoryStore := memory.NewMemoryStore(memoryConfig)
+	memoryPath := memoryFile(cfg)
+	if err := memoryStore.Load(memoryPath); err != nil {
+		log.Fatalf("Failed to load memory: %v", err)
 	}
-	
-	memoryStore := memory.NewMemoryStore(memory.MemoryConfig{
-		ShortTermMax:   50,
-		WorkingMax:     10,
-		SimilarityCut:  0.7,
-	})
 	
 	chatManager := chat.NewChatManager(100)
+	if cfg.Agent.PruneMarker != nil {
+		chatManager.SetPruneMarker(*cfg.Agent.PruneMarker)
+	}
+	chatManager.SetMainSessionDefaults(mainSessionDefaults(cfg))
+
+	// Restore saved sessions and keep saving them as they change
+	var autoSaver *chat.AutoSaver
+	if cfg.Sessions.Dir != "" {
+		autoSave, err := autoSaveConfig(cfg)
+		if err != nil {
+			log.Fatalf("Failed to configure session saving: %v", err)
+		}
+		loaded, err := chatManager.LoadSessions(autoSave.Dir)
+		if err != nil {
+			log.Fatalf("Failed to load saved sessions: %v", err)
+		}
+		if autoSaver, err = chatManager.EnableAutoSave(autoSave); err != nil {
+			log.Fatalf("Failed to enable session saving: %v", err)
+		}
+		fmt.Printf("Loaded %d saved sessions from %s\n", loaded, autoSave.Dir)
+	}
+
+	// Archive idle sessions, distilling their facts into memory first
+	if cfg.Sessions.IdleAfter != "" {
+		janitor, err := janitorConfig(cfg, embedder, memoryStore)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stopJanitor := chatManager.StartJanitor(janitor)
+		defer stopJanitor()
+	}
 	
-	var vectorStore vector.VectorStore
+	var store *vector.InMemoryStore
 	if embedder != nil {
-		vectorStore = vector.NewInMemoryStore(embedder)
+		store = vector.NewInMemoryStore(embedder)
+		if err := store.SetDimensionReduction(cfg.Embedding.ReducedDimensions); err != nil {
+			log.Fatalf("Invalid embedding.reducedDimensions: %v", err)
+		}
 		fmt.Println("Vector store initialized with embedder")
 	} else {
 		// Create a minimal vector store without embedding capabilities
-		vectorStore = vector.NewInMemoryStore(nil)
+		store = vector.NewInMemoryStore(nil)
 		fmt.Println("Vector store initialized without embedder (limited functionality)")
 	}
+	var vectorStore vector.VectorStore = store
+
+	// Restore the saved vectors and keep saving them as they change
+	vectorFile, vectorSaveInterval, err := vectorPersistence(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := store.Load(context.Background(), vectorFile); err != nil {
+		log.Fatalf("Failed to load the vector store: %v", err)
+	}
+	if count, _ := store.Count(context.Background()); count > 0 {
+		fmt.Printf("Loaded %d vectors from %s\n", count, vectorFile)
+	}
+	// Files saved before vectors were stored at unit length are migrated,
+	// and written back by the next autosave
+	if migrated := store.NormalizeAll(); migrated > 0 {
+		fmt.Printf("Normalized %d vectors to unit length\n", migrated)
+	}
+	stopVectorAutosave, cancelVectorAutosave := context.WithCancel(context.Background())
+	vectorSaved := store.StartAutosave(stopVectorAutosave, vectorFile, vectorSaveInterval)
+
+	// Move aging short-term memories to long-term in the background
+	stopConsolidation, cancelConsolidation := context.WithCancel(context.Background())
+	var consolidationStopped <-chan struct{}
+	if consolidateInterval > 0 {
+		consolidationStopped = memoryStore.StartConsolidation(stopConsolidation, consolidateInterval, embedder)
+	}
+
+	flushStores := func() {
+		cancelVectorAutosave()
+		<-vectorSaved
+		cancelConsolidation()
+		if consolidationStopped != nil {
+			<-consolidationStopped
+		}
+		if err := memoryStore.Save(memoryPath); err != nil {
+			fmt.Printf("Error saving memory: %v\n", err)
+		}
+	}
 
 	// Initialize AI client
 	initializeAI(cfg)
 
 	// Initialize tools system
+	filePolicy := builtin.DefaultFilePolicy()
+	if cfg.Tools.FileDenylist != nil {
+		filePolicy.Denylist = cfg.Tools.FileDenylist
+	}
+	if cfg.Tools.RedactOutput != nil {
+		filePolicy.RedactOutput = *cfg.Tools.RedactOutput
+	}
+	if sandboxed(cfg) {
+		filePolicy.Root = cfg.Agent.Workspace
+		filePolicy.Allowlist = cfg.Tools.FileAllowlist
+	}
+	builtin.SetFilePolicy(filePolicy)
+
 	toolsManager := builtin.NewManager()
+	if err := toolsManager.SelfCheck(); err != nil {
+		log.Fatalf("Failed to initialize tools: %v", err)
+	}
 	toolsRegistry := toolsManager.GetRegistry()
-	fmt.Printf("Tools initialized: %d builtin tools available\n", toolsManager.GetToolCount())
+	if len(cfg.Tools.Webhooks) > 0 {
+		webhookTool, err := builtin.WebhookTool(cfg.Tools.Webhooks, cfg.External)
+		if err == nil {
+			err = toolsRegistry.Register(webhookTool)
+		}
+		if err != nil {
+			log.Fatalf("Failed to initialize the webhook tool: %v", err)
+		}
+	}
+	taskList := tasklist.New(defaultTasksFile())
+	builtinTools := append(builtin.TaskTools(taskList), builtin.NoteTools(memoryStore)...)
+	for _, tool := range builtinTools {
+		if err := toolsRegistry.Register(tool); err != nil {
+			log.Fatalf("Failed to initialize the %s tool: %v", tool.Name, err)
+		}
+	}
+	if err := toolsRegistry.Register(builtin.SystemInfoTool(systemInfo(cfg, toolsRegistry, memoryStore), cfg.Tools.SystemInfo)); err != nil {
+		log.Fatalf("Failed to initialize the system_info tool: %v", err)
+	}
+	fmt.Printf("Tools initialized: %d builtin tools available (%s)\n",
+		toolsManager.GetToolCount(), strings.Join(toolsManager.ToolNames(), ", "))
+
+	// Initialize the tool-using agent loop
+	if aiClient != nil {
+		chatAgent = agent.NewAgent(aiClient, toolsRegistry, agent.Config{
+			Model:                primaryChatModel,
+			MaxToolRounds:        cfg.Agent.MaxToolRounds,
+			MaxSessionToolRounds: cfg.Agent.MaxSessionToolRounds,
+			MaxToolRetries:       cfg.Agent.MaxToolRetries,
+			MaxToolDepth:         cfg.Tools.MaxDepth,
+			ToolCalling:          cfg.Agent.ToolCalling,
+		})
+		chatAgent.EnableRecovery(tools.DefaultMaxRecoveryAttempts)
+		chatAgent.SetSessionCounter(chatManager)
+	}
 
 	// Initialize heartbeat manager
 	var heartbeatManager *heartbeat.HeartbeatManager
@@ -104,6 +269,30 @@ func main() {
 		fmt.Println("Heartbeat manager disabled (enable in config to activate)")
 	}
 
+	// Initialize cron scheduler, with the tasks saved before the restart
+	cronManager, err := cron.NewCronManagerFromFile(cronFile(cfg), nil)
+	if err != nil {
+		log.Fatalf("Failed to load scheduled tasks: %v", err)
+	}
+	cronExecutor := tools.NewExecutor(toolsRegistry)
+	cronExecutor.SetMaxDepth(cfg.Tools.MaxDepth)
+	cronManager.SetToolExecutor(cronExecutor)
+	cronManager.Start()
+
+	securityManager := newSecurityManager(cfg)
+
+	stats := statsSources{
+		chatMgr:   chatManager,
+		memStore:  memoryStore,
+		security:  securityManager,
+		cron:      cronManager,
+		cfg:       cfg,
+		startedAt: startedAt,
+	}
+	if err := toolsRegistry.Register(builtin.StatusTool(agentStatus(stats))); err != nil {
+		log.Fatalf("Failed to initialize the status tool: %v", err)
+	}
+
 	// Use port 55789 based on OpenClaw's port scheme (55xxx replacing 18xxx)
 	port := "55789"
 	fmt.Printf("Starting Goclaw server on port %s\n", port)
@@ -114,17 +303,58 @@ func main() {
 	// Write web UI files
 	writeStaticFiles()
 	
-	// API Routes
-	http.HandleFunc("/api/chat", handleChat(embedder, memoryStore, chatManager, vectorStore, toolsRegistry, cfg))
-	http.HandleFunc("/api/memory/search", handleMemorySearch(embedder, memoryStore))
-	http.HandleFunc("/api/memory/stats", handleMemoryStats(memoryStore))
-	http.HandleFunc("/api/sessions", handleSessions(chatManager))
-	http.HandleFunc("/api/dev-status", handleDevStatus())
-	http.HandleFunc("/api/tools", handleToolsList(toolsRegistry))
-	http.HandleFunc("/api/tools/execute", handleToolExecute(toolsRegistry))
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(APIResponse{Status: "ok", Message: "Goclaw is running"})
-	})
+	// Partial answers of interrupted streams, for /api/chat/resume
+	streams := newStreamStore(DefaultStreamTTL)
+
+	// API Routes, grouped by the methods they accept
+	cors, err := corsPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid CORS config: %v", err)
+	}
+	proxies, err := trustedProxies(cfg)
+	if err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	read := newRouteGroup(http.DefaultServeMux, cors, readMethods...)
+	write := newRouteGroup(http.DefaultServeMux, cors, writeMethods...)
+
+	write.HandleFunc("/api/chat", handleChat(embedder, memoryStore, chatManager, vectorStore, toolsRegistry, cfg))
+	write.HandleFunc("/api/chat/stream", handleChatStream(chatManager, cfg, streams))
+	write.HandleFunc("/api/chat/resume", handleChatResume(chatManager, cfg, streams))
+	write.HandleFunc("/api/memory/search", handleMemorySearch(embedder, memoryStore))
+	read.HandleFunc("/api/memory/stats", handleMemoryStats(memoryStore))
+	write.HandleFunc("/api/memory/retag", handleMemoryRetag(memoryStore))
+	newRouteGroup(http.DefaultServeMux, cors, http.MethodGet, http.MethodPut).
+		HandleFunc("/api/memory/config", handleMemoryConfig(memoryStore))
+	write.HandleFunc("/api/documents", handleIndexDocument(vectorStore))
+	read.HandleFunc("/api/sessions", handleSessions(chatManager))
+	read.HandleFunc("/api/sessions/recent", handleRecentSessions(chatManager))
+	read.HandleFunc("/api/sessions/export", handleExportSession(chatManager))
+	write.HandleFunc("/api/sessions/import", handleImportSession(chatManager))
+	write.HandleFunc("/api/sessions/bundle/import", handleImportBundle(chatManager, memoryStore))
+	newRouteGroup(http.DefaultServeMux, cors, http.MethodGet, http.MethodPost, http.MethodPut).
+		HandleFunc("/api/sessions/", handleSessionRoutes(chatManager, memoryStore))
+	read.HandleFunc("/api/dev-status", handleDevStatus(taskList))
+	newRouteGroup(http.DefaultServeMux, cors, http.MethodGet, http.MethodPost, http.MethodPatch).
+		HandleFunc("/api/tasks", handleTasks(taskList))
+	read.HandleFunc("/api/greeting", handleGreeting(identityManager, cfg))
+	read.Handle("/api/config", configHandler(cfg, securityManager))
+	read.HandleFunc("/api/tools", handleToolsList(toolsRegistry))
+	write.Handle("/api/tools/execute", toolExecuteHandler(toolsRegistry, securityManager, cfg.Tools.Scopes, cfg.Tools.MaxDepth))
+	read.HandleFunc("/api/stats", handleStats(stats))
+	read.HandleFunc("/api/diagnostics", handleDiagnostics(diagnosticSources{
+		cfg:         cfg,
+		embedder:    embedder,
+		vectorStore: vectorStore,
+		cron:        cronManager,
+	}))
+
+	cronRouter := mux.NewRouter()
+	cron.NewHandler(cronManager).RegisterRoutes(cronRouter)
+	newRouteGroup(http.DefaultServeMux, cors, http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete).
+		Handle("/api/cron/", cronRouter)
+	read.HandleFunc("/health", handleHealth(embedder, embeddingState))
+	read.HandleFunc("/metrics", handleMetrics())
 	
 	// Static file handlers
 	fs := http.FileServer(http.Dir("./static/"))
@@ -141,7 +371,41 @@ func main() {
 		}
 	})
 
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	serve(":"+port, security.ClientIPMiddleware(proxies)(http.DefaultServeMux), autoSaver, flushStores)
+}
+
+// serve runs the HTTP server until it fails or the process is interrupted.
+// On SIGINT or SIGTERM the server shuts down, unsaved sessions are flushed
+// and flushStores saves the vectors and memory.
+func serve(addr string, handler http.Handler, autoSaver *chat.AutoSaver, flushStores func()) {
+	server := &http.Server{Addr: addr, Handler: handler}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		fmt.Println("Shutting down...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Printf("Error shutting down the server: %v\n", err)
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-stopped
+
+	if autoSaver != nil {
+		if err := autoSaver.Close(); err != nil {
+			fmt.Printf("Error saving sessions: %v\n", err)
+		}
+	}
+	flushStores()
 }
 
 // writeStaticFiles creates the necessary static files for the web UI
@@ -734,8 +998,8 @@ func writeStaticFiles() {
         
         let currentSessionId = 'web_' + new Date().getTime();
         
-        // Add welcome message
-        addMessage('assistant', '您好！我是Goclaw。今天我能为您做些什么？');
+        // Add welcome message from the server
+        loadGreeting();
         
         // Focus input field
         messageInput.focus();
@@ -762,6 +1026,21 @@ func writeStaticFiles() {
             }
         });
         
+        async function loadGreeting() {
+            try {
+                const lang = (navigator.language || '').split('-')[0];
+                const response = await fetch('/api/greeting?lang=' + encodeURIComponent(lang));
+                const result = await response.json();
+                if (result.status === 'ok' && result.data) {
+                    addMessage('assistant', result.data.greeting);
+                    return;
+                }
+            } catch (error) {
+                console.error('Error loading greeting:', error);
+            }
+            addMessage('assistant', '👋');
+        }
+        
         function showDevStatus() {
             devStatusModal.classList.add('show');
             loadDevStatus();
@@ -1070,6 +1349,68 @@ func loadConfig() *config.Config {
 	return cfg
 }
 
+// configuredEmbedder sets up the embedder the config asks for, nil when
+// there is none
+func configuredEmbedder(cfg *config.Config) vector.Embedder {
+	// Check if any AI provider is configured
+	hasAIProvider := cfg.Zhipu.ApiKey != "" ||
+		(cfg.Models["providers"] != nil && len(cfg.Models["providers"].(map[string]interface{})) > 0)
+
+	if cfg.Embedding.API != "" {
+		// Embedding provider declared explicitly in config
+		embedder, err := selectEmbedder(cfg)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize embedder: %v", err)
+			return nil
+		}
+		fmt.Printf("Using %s embeddings (%s)\n", cfg.Embedding.API, embedder.GetModelName())
+		if ollamaEmbedder, ok := embedder.(*vector.OllamaEmbedder); ok {
+			if err := ollama.CheckModel(context.Background(), ollamaEmbedder.Endpoint, ollamaEmbedder.Model); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		return embedder
+	}
+	if derived, ok := config.ProviderEmbedding(cfg); ok {
+		// Embed with the OpenAI-compatible chat provider; the startup probe
+		// falls back to text search if it can't
+		embedder, err := vector.NewEmbedder(derived.API, derived.ApiKey, derived.BaseURL, derived.Model)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize embedder: %v", err)
+			return nil
+		}
+		fmt.Printf("Using %s embeddings from the chat provider config (%s)\n", derived.API, embedder.GetModelName())
+		return embedder
+	}
+	if hasAIProvider {
+		// AI provider is configured, skip Ollama embedder
+		fmt.Println("AI provider configured - skipping Ollama embedder initialization")
+		return nil
+	}
+	// Only try to initialize Ollama embedder if no other AI provider is configured
+	return initEmbedder(cfg)
+}
+
+// selectEmbedder constructs the embedder declared in the "embedding" config
+// section, chained with its fallbacks when any are configured
+func selectEmbedder(cfg *config.Config) (vector.Embedder, error) {
+	emb := cfg.Embedding
+	primary, err := vector.NewEmbedder(emb.API, emb.ApiKey, emb.BaseURL, emb.Model)
+	if err != nil || len(emb.Fallbacks) == 0 {
+		return primary, err
+	}
+
+	embedders := []vector.Embedder{primary}
+	for i, fallback := range emb.Fallbacks {
+		embedder, err := vector.NewEmbedder(fallback.API, fallback.ApiKey, fallback.BaseURL, fallback.Model)
+		if err != nil {
+			return nil, fmt.Errorf("embedding.fallbacks[%d]: %w", i, err)
+		}
+		embedders = append(embedders, embedder)
+	}
+	return vector.NewEmbedderChain(embedders...), nil
+}
+
 func initEmbedder(cfg *config.Config) vector.Embedder {
 	// Only check for Ollama if no Zhipu AI is configured
 	if cfg.Zhipu.ApiKey != "" {
@@ -1078,27 +1419,28 @@ func initEmbedder(cfg *config.Config) vector.Embedder {
 	}
 	
 	// Check if Ollama is available
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), ollama.DefaultTimeout)
 	defer cancel()
-	
-	req, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:11434/api/version", nil)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+
+	if _, err := ollama.Ping(ctx, ollama.DefaultEndpoint); err != nil {
 		fmt.Println("Note: Ollama not detected, embedding features will be limited")
 		fmt.Println("Run 'ollama serve' to enable local embeddings")
 		return nil
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode == http.StatusOK {
-		fmt.Println("Connected to Ollama for embeddings")
-		return vector.NewOllamaEmbedder("", "")
+
+	fmt.Println("Connected to Ollama for embeddings")
+	embedder := vector.NewOllamaEmbedder("", "")
+	if err := ollama.CheckModel(ctx, embedder.Endpoint, embedder.Model); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
-	
-	return nil
+	return embedder
 }
 
 func handleChat(embedder vector.Embedder, memStore *memory.MemoryStore, chatMgr *chat.ChatManager, vectorStore vector.VectorStore, toolsRegistry *tools.Registry, cfg *config.Config) http.HandlerFunc {
+	pipeline := newChatPipeline(embedder, memStore, chatMgr, cfg.Memory.MinMessageChars, cfg.Memory.SessionScoped)
+	pipeline.serial = cfg.Agent.SerialPipeline
+	dedup, _ := duplicateConfig(cfg) // Validated at startup
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -1106,62 +1448,286 @@ func handleChat(embedder vector.Embedder, memStore *memory.MemoryStore, chatMgr
 		}
 
 		var req struct {
-			Message    string `json:"message"`
-			SessionID  string `json:"sessionId,omitempty"`
+			Message        string          `json:"message"`
+			SessionID      string          `json:"sessionId,omitempty"`
+			Attachments    []ai.Attachment `json:"attachments,omitempty"`
+			UseMemory      *bool           `json:"useMemory,omitempty"`      // Defaults to true
+			Generate       *bool           `json:"generate,omitempty"`       // Defaults to true; false only stores the message
+			User           string          `json:"user,omitempty"`           // Owner of a new session, for /api/sessions/recent
+			ExplainContext bool            `json:"explainContext,omitempty"` // Return the memories injected into the prompt as contextUsed
+			Debug          bool            `json:"debug,omitempty"`          // Return what the provider reported about the reply as responseMeta
+			Model          string          `json:"model,omitempty"`          // Answer with this model instead of the routed one
+			PromptPrefix   *string         `json:"promptPrefix,omitempty"`   // Text injected before the message in the prompt only, overriding agent.promptPrefix
+			PromptSuffix   *string         `json:"promptSuffix,omitempty"`   // Text injected after the message in the prompt only, overriding agent.promptSuffix
+			Thinking       *string         `json:"thinking,omitempty"`       // Reasoning level for this message, overriding the session's
+
+			ContextLongTermK  *int `json:"contextLongTermK,omitempty"`  // Long-term memories to inject, overriding memory.contextLongTermK
+			ContextShortTermK *int `json:"contextShortTermK,omitempty"` // Recent short-term memories to inject, overriding memory.contextShortTermK
 		}
 		
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
+		// A blank message is an accidental submission, not worth a session,
+		// a model call or a memory; attachments alone are a message
+		req.Message = strings.TrimSpace(req.Message)
+		if req.Message == "" && len(req.Attachments) == 0 {
+			http.Error(w, "Message must not be empty", http.StatusBadRequest)
+			return
+		}
+		if req.Thinking != nil {
+			if err := ai.ValidateThinkingLevel(*req.Thinking); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		// Memory context overrides, checked before the session is touched
+		limits := memStore.ContextLimits()
+		if req.ContextLongTermK != nil {
+			limits.LongTermK = *req.ContextLongTermK
+		}
+		if req.ContextShortTermK != nil {
+			limits.ShortTermK = *req.ContextShortTermK
+		}
+		if err := limits.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Validate attachments and collect references for message metadata
+		var metadata map[string]interface{}
+		if len(req.Attachments) > 0 {
+			files := make([]string, 0, len(req.Attachments))
+			for _, attachment := range req.Attachments {
+				if err := attachment.Validate(); err != nil {
+					http.Error(w, "Invalid attachment: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+				if err := attachment.CheckRemote(r.Context(), attachmentClient); err != nil {
+					http.Error(w, "Invalid attachment: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+				files = append(files, attachment.Reference())
+			}
+			metadata = map[string]interface{}{"files": files}
+		}
 
 		sessionID := req.SessionID
 		if sessionID == "" {
 			sessionID = fmt.Sprintf("api_session_%d", time.Now().Unix())
 		}
+		received := time.Now()
+
+		// Process messages for the same session one at a time: the lock covers
+		// reading history, generating and appending the reply, and is released
+		// before memory capture and writing the response
+		release := chatMgr.LockSession(sessionID)
+		var releaseOnce sync.Once
+		unlock := func() { releaseOnce.Do(release) }
+		defer unlock()
 
 		// Ensure session exists (in case sessionID was provided but doesn't exist)
 		if _, exists := chatMgr.GetSession(sessionID); !exists {
 			chatMgr.CreateSession(sessionID, cfg.Agent.Model)
+			if req.User != "" {
+				chatMgr.SetMetadata(sessionID, "user", req.User)
+			}
+		}
+
+		// A message resent right after the last one, by a double click or a
+		// retry, gets the previous response: no model call, and no duplicate
+		// history or memories
+		if len(req.Attachments) == 0 && (req.Generate == nil || *req.Generate) {
+			history, _ := chatMgr.GetMessages(sessionID)
+			if response, duplicate := dedup.previousResponse(history, req.Message, received); duplicate {
+				unlock()
+				fmt.Printf("Duplicate message in session %s, returning the previous response\n", sessionID)
+
+				if f := format.Negotiate(r, format.JSON); f.Name() != format.JSON {
+					writeFormatted(w, f, f.FormatChat("assistant", response))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(APIResponse{
+					Status: "ok",
+					Data: map[string]interface{}{
+						"sessionId": sessionID,
+						"response":  response,
+						"messages":  history,
+						"duplicate": true,
+					},
+				})
+				return
+			}
 		}
 
 		// Add user message
-		if err := chatMgr.AddMessage(sessionID, "user", req.Message); err != nil {
+		if err := chatMgr.AddMessageWithMetadata(sessionID, "user", req.Message, metadata); err != nil {
 			// Log error but continue
 			fmt.Printf("Error adding message to session %s: %v\n", sessionID, err)
 		}
 
-		// Get context from memory
-		var contextText string
-		if embedder != nil {
-			ctx := context.Background()
-			embedding, _ := embedder.Embed(ctx, req.Message)
-			contextText, _ = memStore.GetContext(ctx, req.Message, embedding, 500)
+		useMemory := req.UseMemory == nil || *req.UseMemory
+		// Acknowledgments like "ok" aren't worth remembering
+		remember := useMemory && !memory.IsTrivial(req.Message, cfg.Memory.MinMessageChars)
+
+		if !useMemory {
+			fmt.Printf("Memory disabled for request in session %s\n", sessionID)
 		}
 
-		// Generate response
-		response := generateResponse(req.Message, contextText, chatMgr, sessionID)
+		// History-only request: store the message without calling the model
+		if req.Generate != nil && !*req.Generate {
+			messages, _ := chatMgr.GetMessages(sessionID)
+			unlock()
+
+			if remember {
+				memStore.AddShortTerm(req.Message, map[string]interface{}{
+					"session": sessionID,
+					"source":  "api",
+				})
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(APIResponse{
+				Status: "ok",
+				Data: map[string]interface{}{
+					"sessionId": sessionID,
+					"messages":  messages,
+					"useMemory": useMemory,
+					"generated": false,
+				},
+			})
+			return
+		}
+
+		// Size memory context and history for the model that will answer,
+		// reserving the session's maxTokens for the response
+		params, _ := chatMgr.GetGenerationParams(sessionID)
+		if req.Thinking != nil {
+			params.Thinking = *req.Thinking
+		}
+		route := routeModel(cfg.Agent.ModelTiers, req.Message, req.Model, params)
+		params.Model = route.Model
+		budget := resolveContextBudget(cfg, route.Model).forParams(params)
+
+		// Get context from memory and conversation history concurrently
+		inputs, err := pipeline.gather(r.Context(), sessionID, req.Message, useMemory, budget.Budget, limits)
+		if err != nil {
+			fmt.Printf("Error gathering chat context for session %s: %v\n", sessionID, err)
+		}
+
+		// Capture to short-term memory while the response is generated
+		var capture sync.WaitGroup
+		if remember {
+			capture.Add(1)
+			go func() {
+				defer capture.Done()
+				memStore.AddShortTerm(req.Message, map[string]interface{}{
+					"session": sessionID,
+					"source":  "api",
+				})
+			}()
+		}
+
+		// Generate response with the session's sampling parameters
+		affixes := resolvePromptAffixes(cfg, req.PromptPrefix, req.PromptSuffix)
+		contract, _ := chatMgr.GetOutputContract(sessionID)
+		if instruction := contract.Instruction(); instruction != "" {
+			affixes.Suffix = strings.TrimSpace(affixes.Suffix + "\n" + instruction)
+		}
+		timeout := aiTimeout(cfg, ai.UseInteractive)
+		genCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		response, gen, err := generateResponse(genCtx, req.Message, inputs.ContextText, inputs.History, sessionID, req.Attachments, budget, params, affixes)
+		err = ai.TimeoutErr(genCtx, err, ai.UseInteractive, timeout)
+		cancel()
+		if err != nil {
+			capture.Wait()
+			var tooLarge *promptTooLargeError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			var timedOut *ai.TimeoutError
+			if errors.As(err, &timedOut) || errors.Is(err, errProviderTimeout) {
+				http.Error(w, redact.String(err.Error()), http.StatusGatewayTimeout)
+				return
+			}
+			if errors.Is(err, errProviderAuth) {
+				http.Error(w, redact.String(err.Error()), http.StatusBadGateway)
+				return
+			}
+			if errors.Is(err, ai.ErrTooManyRequests) {
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+			http.Error(w, redact.String(err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		gen.logMeta(sessionID)
+
+		// Hold the response to the session's output contract
+		contractCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		response, enforced, err := enforceOutputContract(contractCtx, contract, response, params, &gen)
+		cancel()
+		if err != nil {
+			capture.Wait()
+			http.Error(w, redact.String(err.Error()), http.StatusBadGateway)
+			return
+		}
 
 		// Add assistant message
 		chatMgr.AddMessage(sessionID, "assistant", response)
 
-		// Add to short-term memory
-		memStore.AddShortTerm(req.Message, map[string]interface{}{
-			"session": sessionID,
-			"source":  "api",
-		})
-
 		// Get updated messages
 		messages, _ := chatMgr.GetMessages(sessionID)
+		unlock()
+
+		// Remember the response too when memory.captureAssistant is on
+		if useMemory && shouldCaptureAssistant(cfg.Memory, response) {
+			if err := captureAssistantResponse(r.Context(), embedder, memStore, sessionID, response); err != nil {
+				fmt.Printf("Error capturing assistant response for session %s: %v\n", sessionID, err)
+			}
+		}
+		capture.Wait()
+
+		if f := format.Negotiate(r, format.JSON); f.Name() != format.JSON {
+			writeFormatted(w, f, f.FormatChat("assistant", response))
+			return
+		}
+
+		data := map[string]interface{}{
+			"sessionId": sessionID,
+			"response":  response,
+			"messages":  messages,
+			"useMemory": useMemory,
+			"model":     route,
+			"usage": map[string]int{
+				"promptTokens":     gen.Usage.PromptTokens,
+				"completionTokens": gen.Usage.CompletionTokens,
+				"totalTokens":      gen.Usage.TotalTokens,
+			},
+		}
+		if !contract.IsZero() {
+			data["contractEnforced"] = enforced
+		}
+		if req.Debug {
+			data["responseMeta"] = gen.Meta
+		}
+		if req.ExplainContext {
+			contextUsed := inputs.ContextSources
+			if contextUsed == nil {
+				contextUsed = []memory.ContextSource{}
+			}
+			data["contextUsed"] = contextUsed
+			data["contextLimits"] = limits
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(APIResponse{
 			Status: "ok",
-			Data: map[string]interface{}{
-				"sessionId": sessionID,
-				"response":  response,
-				"messages":  messages,
-			},
+			Data:   data,
 		})
 	}
 }
@@ -1183,16 +1749,16 @@ func handleMemorySearch(embedder vector.Embedder, memStore *memory.MemoryStore)
 			return
 		}
 
-		if embedder == nil {
-			http.Error(w, "No embedder available", http.StatusServiceUnavailable)
-			return
-		}
-
+		// Without an embedder the store falls back to text search
 		ctx := context.Background()
-		embedding, err := embedder.Embed(ctx, req.Query)
-		if err != nil {
-			http.Error(w, "Failed to generate embedding", http.StatusInternalServerError)
-			return
+		var embedding []float32
+		if embedder != nil {
+			var err error
+			embedding, err = embedder.Embed(ctx, req.Query)
+			if err != nil {
+				http.Error(w, "Failed to generate embedding", http.StatusInternalServerError)
+				return
+			}
 		}
 
 		limit := req.Limit
@@ -1205,6 +1771,12 @@ func handleMemorySearch(embedder vector.Embedder, memStore *memory.MemoryStore)
 			http.Error(w, "Search failed", http.StatusInternalServerError)
 			return
 		}
+		memory.AddSnippets(ctx, embedder, req.Query, embedding, results)
+
+		if f := format.Negotiate(r, format.JSON); f.Name() != format.JSON {
+			writeFormatted(w, f, f.FormatMemoryResults(results))
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(APIResponse{
@@ -1214,6 +1786,39 @@ func handleMemorySearch(embedder vector.Embedder, memStore *memory.MemoryStore)
 	}
 }
 
+// handleMemoryRetag serves POST /api/memory/retag, adding and removing tags
+// on the long-term memories matching a metadata filter
+func handleMemoryRetag(memStore *memory.MemoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Filter     map[string]interface{} `json:"filter"` // Metadata values to match; "tag" matches a tag
+			AddTags    []string               `json:"addTags,omitempty"`
+			RemoveTags []string               `json:"removeTags,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		modified, err := memStore.Retag(req.Filter, req.AddTags, req.RemoveTags)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data:   map[string]interface{}{"modified": modified},
+		})
+	}
+}
+
 func handleMemoryStats(memStore *memory.MemoryStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		stats := memStore.Stats()
@@ -1246,108 +1851,130 @@ func handleSessions(chatMgr *chat.ChatManager) http.HandlerFunc {
 	}
 }
 
-func generateResponse(input, contextText string, chatMgr *chat.ChatManager, sessionID string) string {
-	// Check for tool invocation intent first
-	inputLower := strings.ToLower(input)
-	
-	// Tool invocation: Check if user wants to read a file
-	if (strings.Contains(inputLower, "展示") || strings.Contains(inputLower, "显示") || strings.Contains(inputLower, "读取") || strings.Contains(inputLower, "查看") || strings.Contains(inputLower, "看看")) &&
-		(strings.Contains(inputLower, "前") || strings.Contains(inputLower, "开头") || strings.Contains(inputLower, "第一")) &&
-		strings.Contains(inputLower, "行") &&
-		strings.Contains(input, "/") {
-		
-		// Extract file path
-		filePath := extractFilePath(input)
-		if filePath != "" {
-			// Execute read tool
-			result, err := executeReadTool(filePath)
-			if err != nil {
-				return fmt.Sprintf("工具调用失败：%s", err.Error())
+func handleHealth(embedder vector.Embedder, embedding embeddingStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := map[string]interface{}{"embedding": embedding}
+		if multiClient, ok := aiClient.(*ai.MultiProviderClient); ok {
+			health["providers"] = multiClient.BreakerStates()
+		}
+
+		// Report Ollama status when it backs the embedder
+		if ollamaEmbedder, ok := embedder.(*vector.OllamaEmbedder); ok {
+			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+			status := ollama.CheckStatus(ctx, ollamaEmbedder.Endpoint)
+			cancel()
+
+			health["ollama"] = map[string]interface{}{
+				"status":         status,
+				"embeddingModel": ollamaEmbedder.Model,
+				"modelPulled":    ollama.HasModel(status.Models, ollamaEmbedder.Model),
 			}
-			return result
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status:  "ok",
+			Message: "Goclaw is running",
+			Data:    health,
+		})
 	}
-	
-	// Default: Get conversation history and use AI
-	messages, _ := chatMgr.GetMessages(sessionID)
-	
-	// Build prompt
-	prompt := buildPrompt(input, contextText, messages)
-	
-	// Call Claude Code CLI if available
-	response := callClaudeCode(prompt)
-	
-	return response
 }
 
-// extractFilePath extracts file path from user input
-func extractFilePath(input string) string {
-	// Find / at the start of a path
-	startIdx := strings.Index(input, "/")
-	if startIdx == -1 {
-		return ""
+// generation is what generating a reply took from the model
+type generation struct {
+	Usage ai.Usage         // Tokens of all model calls
+	Meta  *ai.ResponseMeta // What the provider reported about the answering call, nil when no model answered
+}
+
+// logMeta logs which provider and model answered a session, warning when the
+// provider picked another model than the requested one
+func (g generation) logMeta(sessionID string) {
+	meta := g.Meta
+	if meta == nil {
+		return
+	}
+	fmt.Printf("Reply for session %s from provider %q, model %q (response %q, request %q)\n",
+		sessionID, meta.Provider, meta.Model, meta.ID, meta.RequestID)
+	if meta.ModelChanged() {
+		fmt.Printf("Warning: session %s requested model %q but %q answered\n", sessionID, meta.RequestedModel, meta.Model)
 	}
+}
 
-	// Find end of path
-	endIdx := len(input)
-	
-	// Use priority-based matching: find earliest meaningful delimiter
-	// Priority 1: "只要" (highest)
-	if idx := strings.Index(input[startIdx:], "只要"); idx != -1 {
-		if startIdx+idx < endIdx {
-			endIdx = startIdx + idx
+// generateResponse answers the input, returning what the model calls took;
+// replies that need no model report no usage
+func generateResponse(ctx context.Context, input, contextText string, messages []chat.Message, sessionID string, attachments []ai.Attachment, budget contextBudget, params ai.GenerationParams, affixes promptAffixes) (string, generation, error) {
+	// Act on structured intents before falling back to the model
+	if in := intentClassifier.Classify(input); in.Action == intent.ActionReadLines {
+		result, err := executeReadTool(in.Path, in.LineCount)
+		if err != nil {
+			return fmt.Sprintf("工具调用失败：%s", err.Error()), generation{}, nil
 		}
+		return result, generation{}, nil
 	}
 	
-	// Priority 2: "，只要" (comma followed by 只要)
-	if idx := strings.Index(input[startIdx:], "，只要"); idx != -1 {
-		if startIdx+idx < endIdx {
-			endIdx = startIdx + idx
-		}
+	// Default: use conversation history and AI
+	// Build prompt
+	prompt := buildPrompt(input, contextText, messages, budget.Budget, affixes)
+	if err := budget.checkPrompt(prompt); err != nil {
+		return "", generation{}, err
 	}
 	
-	// Priority 3: "的前" (e.g., "文件的前3行")
-	if idx := strings.Index(input[startIdx:], "的前"); idx != -1 {
-		if startIdx+idx < endIdx {
-			endIdx = startIdx + idx
+	// Run the agent loop so the model can use tools
+	var usage ai.Usage
+	if chatAgent != nil {
+		result, err := chatAgent.RunWithParams(ctx, sessionID, []ai.Message{
+			{Role: "user", Content: prompt, Attachments: attachments},
+		}, params)
+		if err != nil {
+			fmt.Printf("Agent error for session %s: %v\n", sessionID, err)
+			// Out of time or over the concurrency limit: falling back to
+			// another call can't help
+			if ctx.Err() != nil || errors.Is(err, ai.ErrTooManyRequests) {
+				return "", generation{}, err
+			}
+		} else if result.Response != "" {
+			return result.Response, generation{Usage: result.Usage, Meta: result.Meta}, nil
+		} else {
+			usage = result.Usage
 		}
 	}
 	
-	// Priority 4: "这个文件"
-	if idx := strings.Index(input[startIdx:], "这个文件"); idx != -1 {
-		if startIdx+idx < endIdx {
-			endIdx = startIdx + idx
-		}
-	}
-
-	filePath := input[startIdx:endIdx]
-	return strings.TrimSpace(filePath)
+	// Call Claude Code CLI if available
+	response, gen, err := callClaudeCode(ctx, prompt, attachments, params)
+	gen.Usage = usage.Add(gen.Usage)
+	return response, gen, err
 }
 
-// executeReadTool executes the read tool and returns formatted result
-func executeReadTool(filePath string) (string, error) {
-	// Read file content
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
+// executeReadTool reads the first lines of a file with the read tool, so
+// the file denylist and output redaction apply
+func executeReadTool(filePath string, lineCount int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Get first 3 lines
-	lines := strings.Split(string(content), "\n")
-	if len(lines) > 3 {
-		lines = lines[:3]
+	data, err := builtin.ReadTool().Execute(ctx, map[string]interface{}{
+		"path":  filePath,
+		"limit": lineCount,
+	})
+	if err != nil {
+		return "", err
 	}
+	content, _ := data.(map[string]interface{})["content"].(string)
 
 	// Format output
-	result := fmt.Sprintf("已读取文件：%s\n\n前3行内容：\n", filePath)
-	for i, line := range lines {
+	result := fmt.Sprintf("已读取文件：%s\n\n前%d行内容：\n", filePath, lineCount)
+	for i, line := range strings.Split(content, "\n") {
 		result += fmt.Sprintf("%d. %s\n", i+1, line)
 	}
 
 	return result, nil
 }
 
-func buildPrompt(input, contextText string, messages []chat.Message) string {
+func buildPrompt(input, contextText string, messages []chat.Message, budget int, affixes promptAffixes) string {
+	request := affixes.wrap(input)
+
+	// Drop the oldest history that doesn't fit in the token budget
+	messages = fitHistory(messages, budget-estimateTokens(contextText)-estimateTokens(request))
+
 	var sb strings.Builder
 	
 	// Set the assistant role without overly prescriptive instructions
@@ -1363,6 +1990,10 @@ func buildPrompt(input, contextText string, messages []chat.Message) string {
 	if len(messages) > 0 {
 		sb.WriteString("Previous conversation:\n")
 		for _, msg := range messages {
+			if chat.IsPruneMarker(msg) {
+				sb.WriteString(msg.Content + "\n")
+				continue
+			}
 			if msg.Role == "system" {
 				continue
 			}
@@ -1371,8 +2002,8 @@ func buildPrompt(input, contextText string, messages []chat.Message) string {
 		sb.WriteString("\n")
 	}
 	
-	// Add the current user input as the final request
-	sb.WriteString(fmt.Sprintf("User: %s\n\n", input))
+	// Add the current user input, with any injected prefix and suffix, as the final request
+	sb.WriteString(request + "\n")
 	sb.WriteString("Please respond naturally and helpfully to the user's message.\n")
 	
 	return sb.String()
@@ -1381,13 +2012,40 @@ func buildPrompt(input, contextText string, messages []chat.Message) string {
 // Global variable to hold the AI client
 var aiClient ai.Client
 
+// Global agent loop, set when an AI client is available
+var chatAgent *agent.Agent
+
+// attachmentClient downloads URL attachments to check their size and type
+var attachmentClient = ai.NewAttachmentClient(10 * time.Second)
+
+// intentClassifier recognizes requests handled without a model call
+var intentClassifier = intent.NewIntentClassifier()
+
+// primaryChatModel is the model requested first for chat responses
+const primaryChatModel = "MiniMax-M2.1"
+
 func initializeAI(cfg *config.Config) {
 	// Initialize AI client based on configuration
 	multiClient := ai.NewMultiProviderClient()
+
+	// models.allowMockFallback makes failing providers answer with canned
+	// replies instead of errors, for demos without credentials
+	allowMockFallback, _ := cfg.Models["allowMockFallback"].(bool)
+
+	// models.retry sets how transient provider failures are retried
+	retryConfig := ai.DefaultRetryConfig()
+	if retryRaw, ok := cfg.Models["retry"].(map[string]interface{}); ok {
+		var err error
+		if retryConfig, err = ai.ParseRetryConfig(retryConfig, retryRaw); err != nil {
+			log.Fatalf("Invalid models.retry: %v", err)
+		}
+	}
 	
 	// Initialize Zhipu AI if configured
 	if cfg.Zhipu.ApiKey != "" {
 		zhipuClient := ai.NewZhipuClient(cfg.Zhipu.ApiKey, cfg.Zhipu.BaseURL, cfg.Zhipu.Model)
+		zhipuClient.AllowMockFallback = allowMockFallback
+		zhipuClient.Retry = retryConfig
 		multiClient.AddProvider("zhipu", zhipuClient)
 		fmt.Println("Using Zhipu AI model:", cfg.Zhipu.Model)
 	}
@@ -1418,20 +2076,39 @@ func initializeAI(cfg *config.Config) {
 					// Extract models information
 					if models, hasModels := providerConfigMap["models"]; hasModels {
 						if modelsSlice, ok := models.([]interface{}); ok && len(modelsSlice) > 0 {
+							added := false
 							for _, modelItem := range modelsSlice {
 								if modelMap, ok := modelItem.(map[string]interface{}); ok {
 									if modelID, exists := modelMap["id"]; exists {
 										modelStr := fmt.Sprintf("%v", modelID)
 										
 										// Choose the right client based on API type
-										if apiType == "anthropic-messages" || apiType == "openai-completions" {
+										if apiType != "anthropic-messages" && apiType != "openai-completions" && apiType != "gemini" {
+											break
+										}
+										
+										// Route every model the provider lists to it
+										multiClient.RegisterModel(modelStr, providerName)
+										if added {
+											continue // The client uses the first model by default
+										}
+										
+										if apiType == "gemini" {
+											client := ai.NewGeminiClient(apiKey, baseURL, modelStr)
+											client.AllowMockFallback = allowMockFallback
+											client.Retry = retryConfig
+											multiClient.AddProvider(providerName, client)
+										} else {
 											// For both Minimax and Qwen which use OpenAI-compatible API
 											client := ai.NewOpenAICompatibleClient(apiKey, baseURL, modelStr)
+											client.Vision = modelAcceptsImages(modelMap)
+											client.Reasoning, _ = modelMap["reasoning"].(bool)
+											client.AllowMockFallback = allowMockFallback
+											client.Retry = retryConfig
 											multiClient.AddProvider(providerName, client)
-											fmt.Printf("Using %s AI model (%s): %s at %s\n", providerName, apiType, modelStr, baseURL)
 										}
-										
-										break // Just use the first model for now
+										added = true
+										fmt.Printf("Using %s AI model (%s): %s at %s\n", providerName, apiType, modelStr, baseURL)
 									}
 								}
 							}
@@ -1442,6 +2119,60 @@ func initializeAI(cfg *config.Config) {
 		}
 	}
 	
+	// Select how requests without a known model are routed
+	routing, _ := cfg.Models["routing"].(string)
+	policy, err := ai.ParseRoutingPolicy(routing)
+	if err != nil {
+		log.Fatalf("Invalid models.routing: %v", err)
+	}
+	multiClient.SetRoutingPolicy(policy)
+
+	// models.fallback lists the providers to fall back to, in order, when the
+	// one a request is routed to fails
+	if fallbackRaw, ok := cfg.Models["fallback"]; ok {
+		fallback, err := ai.ParseFallbackOrder(fallbackRaw)
+		if err == nil {
+			err = multiClient.SetFallbackOrder(fallback)
+		}
+		if err != nil {
+			log.Fatalf("Invalid models.fallback: %v", err)
+		}
+	}
+
+	// models.breaker sets the circuit breaker thresholds of every provider,
+	// and models.providers.<name>.breaker overrides them for one provider
+	breakerConfig := ai.DefaultBreakerConfig()
+	if breakerRaw, ok := cfg.Models["breaker"].(map[string]interface{}); ok {
+		breakerConfig, err = ai.ParseBreakerConfig(breakerConfig, breakerRaw)
+		if err != nil {
+			log.Fatalf("Invalid models.breaker: %v", err)
+		}
+		multiClient.SetBreakerConfig(breakerConfig)
+	}
+	if providers, ok := cfg.Models["providers"].(map[string]interface{}); ok {
+		for providerName, providerConfig := range providers {
+			providerConfigMap, _ := providerConfig.(map[string]interface{})
+			breakerRaw, ok := providerConfigMap["breaker"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			providerBreaker, err := ai.ParseBreakerConfig(breakerConfig, breakerRaw)
+			if err != nil {
+				log.Fatalf("Invalid models.providers.%s.breaker: %v", providerName, err)
+			}
+			multiClient.SetProviderBreakerConfig(providerName, providerBreaker)
+		}
+	}
+
+	// models.concurrency bounds the AI requests in flight across providers
+	if concurrencyRaw, ok := cfg.Models["concurrency"].(map[string]interface{}); ok {
+		concurrency, err := ai.ParseConcurrencyConfig(concurrencyRaw)
+		if err != nil {
+			log.Fatalf("Invalid models.concurrency: %v", err)
+		}
+		multiClient.SetConcurrencyLimit(concurrency)
+	}
+
 	// Only set global aiClient if we have at least one provider
 	if len(multiClient.Providers) > 0 {
 		aiClient = multiClient
@@ -1451,25 +2182,53 @@ func initializeAI(cfg *config.Config) {
 	}
 }
 
-func callClaudeCode(prompt string) string {
+// modelAcceptsImages reports whether a model entry declares image input, e.g. "input": ["text", "image"]
+func modelAcceptsImages(modelMap map[string]interface{}) bool {
+	inputs, ok := modelMap["input"].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, input := range inputs {
+		if fmt.Sprintf("%v", input) == "image" {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider failures a canned reply can't paper over: the user has to fix
+// the credentials, or the provider didn't answer in time
+var (
+	errProviderAuth    = errors.New("the AI provider rejected the credentials, check its API key")
+	errProviderTimeout = errors.New("the AI provider did not respond in time")
+)
+
+// callClaudeCode asks the AI client for a reply, trying fallback models, and
+// answers with a simple response when no client can. It fails when ctx is
+// done, since no fallback can answer then, and with errProviderAuth or
+// errProviderTimeout when the last provider rejected the credentials or
+// timed out. The generation is that of the answering model call, and empty
+// for the simple response.
+func callClaudeCode(ctx context.Context, prompt string, attachments []ai.Attachment, params ai.GenerationParams) (string, generation, error) {
 	// Try to use configured AI client
 	if aiClient != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // Increase timeout
-		defer cancel()
-		
 		// Use the primary model from the configuration - based on the agents defaults in config
 		// According to config, the primary model should be qwen-portal/coder-model, but we'll try both
 		req := ai.ChatCompletionRequest{
-			Model: "MiniMax-M2.1", // Use the configured model - try Minimax first since it's loaded
+			Model: primaryChatModel, // Use the configured model - try Minimax first since it's loaded
 			Messages: []ai.Message{
-				{Role: "user", Content: prompt},
+				{Role: "user", Content: prompt, Attachments: attachments},
 			},
 			Stream: false,
 		}
+		params.Apply(&req)
 		
 		resp, err := aiClient.ChatCompletion(ctx, req)
+		if errors.Is(err, ai.ErrTooManyRequests) {
+			return "", generation{}, err
+		}
 		if err != nil {
-			fmt.Printf("AI client error for MiniMax-M2.1: %v\n", err)
+			fmt.Printf("AI client error for %s: %v\n", primaryChatModel, err)
 			// Try the other model as fallback
 			req.Model = "coder-model"
 			resp, err = aiClient.ChatCompletion(ctx, req)
@@ -1480,8 +2239,16 @@ func callClaudeCode(prompt string) string {
 				resp, err = aiClient.ChatCompletion(ctx, req)
 				if err != nil {
 					fmt.Printf("AI client generic error: %v\n", err)
+					switch {
+					case ctx.Err() != nil:
+						return "", generation{}, err
+					case ai.IsAuthError(err):
+						return "", generation{}, fmt.Errorf("%w: %v", errProviderAuth, err)
+					case ai.IsNetworkTimeout(err):
+						return "", generation{}, fmt.Errorf("%w: %v", errProviderTimeout, err)
+					}
 					// Fallback to simple response
-					return generateSimpleResponse(prompt)
+					return generateSimpleResponse(prompt), generation{}, nil
 				}
 			}
 		}
@@ -1489,13 +2256,17 @@ func callClaudeCode(prompt string) string {
 		if resp != nil && len(resp.Choices) > 0 {
 			content := strings.TrimSpace(resp.Choices[0].Message.Content)
 			if content != "" {
-				return content
+				// Adding to zero fills in a total the provider left out
+				return content, generation{
+					Usage: ai.Usage{}.Add(resp.Usage),
+					Meta:  ai.MetaOf(resp, req.Model),
+				}, nil
 			}
 		}
 	}
 	
 	// Fallback to simple response
-	return generateSimpleResponse(prompt)
+	return generateSimpleResponse(prompt), generation{}, nil
 }
 
 func generateSimpleResponse(prompt string) string {
@@ -1533,7 +2304,43 @@ func handleToolsList(registry *tools.Registry) http.HandlerFunc {
 	}
 }
 
-func handleToolExecute(registry *tools.Registry) http.HandlerFunc {
+// writeFormatted writes text produced by a non-JSON formatter. JSON clients
+// keep receiving the APIResponse envelope.
+func writeFormatted(w http.ResponseWriter, f format.Formatter, text string) {
+	w.Header().Set("Content-Type", f.ContentType())
+	fmt.Fprintln(w, text)
+}
+
+// newSecurityManager creates a security manager holding the configured API
+// keys when gateway auth mode is "apikey", or returns nil when auth is off
+func newSecurityManager(cfg *config.Config) *security.SecurityManager {
+	if cfg.Gateway.Auth.Mode != "apikey" {
+		return nil
+	}
+
+	sm := security.NewSecurityManager("")
+	for _, key := range cfg.Gateway.Auth.APIKeys {
+		sm.AddAPIKey(key.Key, key.Name, key.Scopes, 0)
+	}
+	fmt.Printf("API key auth enabled with %d keys\n", len(cfg.Gateway.Auth.APIKeys))
+	return sm
+}
+
+// toolExecuteHandler wraps handleToolExecute with API key authentication when
+// a security manager is configured
+func toolExecuteHandler(registry *tools.Registry, sm *security.SecurityManager, scopes map[string]string, maxDepth int) http.Handler {
+	handler := handleToolExecute(registry, sm, scopes, maxDepth)
+	if sm == nil {
+		return handler
+	}
+	return sm.OptionalAuthMiddleware()(handler)
+}
+
+// handleToolExecute runs a tool. With a security manager, the caller's API
+// key must hold the tool's scope (see security.ToolScope). Tools calling
+// back into the API send their depth in tools.DepthHeader, so nesting across
+// processes is limited to maxDepth too.
+func handleToolExecute(registry *tools.Registry, sm *security.SecurityManager, scopes map[string]string, maxDepth int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -1550,16 +2357,36 @@ func handleToolExecute(registry *tools.Registry) http.HandlerFunc {
 			return
 		}
 
+		// Enforce per-tool scopes
+		if sm != nil {
+			apiKey := security.GetAPIKeyFromContext(r)
+			if apiKey == nil {
+				http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+				return
+			}
+			if scope := security.ToolScope(req.ToolName, scopes); !apiKey.HasScope(scope) {
+				http.Error(w, fmt.Sprintf("API key lacks scope %q required by tool %s", scope, req.ToolName), http.StatusForbidden)
+				return
+			}
+		}
+
 		// Execute tool
 		executor := tools.NewExecutor(registry)
-		result, err := executor.Execute(r.Context(), req.ToolName, req.Params)
+		executor.SetMaxDepth(maxDepth)
+		ctx := tools.WithDepth(r.Context(), tools.ParseDepth(r.Header.Get(tools.DepthHeader)))
+		result, err := executor.Execute(ctx, req.ToolName, req.Params)
+
+		if f := format.Negotiate(r, format.JSON); f.Name() != format.JSON {
+			writeFormatted(w, f, f.FormatToolResult(req.ToolName, result))
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err != nil {
 			json.NewEncoder(w).Encode(APIResponse{
 				Status:  "error",
-				Message: err.Error(),
-				Data:    result,
+				Message: redact.String(err.Error()),
+				Data:    redact.Value(result),
 			})
 		} else {
 			json.NewEncoder(w).Encode(APIResponse{
@@ -0,0 +1,140 @@
+// Package main provides memory persistence, consolidation and the memory
+// context configuration API for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+)
+
+// memoryFile returns the file memory is kept in, from memory.file
+func memoryFile(cfg *config.Config) string {
+	if cfg.Memory.File != "" {
+		return cfg.Memory.File
+	}
+	return filepath.Join(os.Getenv("HOME"), ".openclaw", "workspace", "goclaw_memory.json")
+}
+
+// contextShortTermMaxAge parses memory.contextShortTermMaxAge, 0 when unset
+func contextShortTermMaxAge(cfg *config.Config) (time.Duration, error) {
+	if cfg.Memory.ContextShortTermMaxAge == "" {
+		return 0, nil
+	}
+	maxAge, err := time.ParseDuration(cfg.Memory.ContextShortTermMaxAge)
+	if err != nil || maxAge <= 0 {
+		return 0, fmt.Errorf("invalid memory.contextShortTermMaxAge %q: must be a positive duration such as \"1h\"", cfg.Memory.ContextShortTermMaxAge)
+	}
+	return maxAge, nil
+}
+
+// shortTermTTL parses memory.shortTermTTL, 0 when unset
+func shortTermTTL(cfg *config.Config) (time.Duration, error) {
+	if cfg.Memory.ShortTermTTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(cfg.Memory.ShortTermTTL)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid memory.shortTermTTL %q: must be a positive duration such as \"2h\"", cfg.Memory.ShortTermTTL)
+	}
+	return ttl, nil
+}
+
+// consolidation parses memory.consolidateAfter and memory.consolidateInterval.
+// An interval of 0 means memory isn't consolidated automatically.
+func consolidation(cfg *config.Config) (after, interval time.Duration, err error) {
+	after = memory.DefaultConsolidateAfter
+	if cfg.Memory.ConsolidateAfter != "" {
+		after, err = time.ParseDuration(cfg.Memory.ConsolidateAfter)
+		if err != nil || after <= 0 {
+			return 0, 0, fmt.Errorf("invalid memory.consolidateAfter %q: must be a positive duration such as \"1h\"", cfg.Memory.ConsolidateAfter)
+		}
+	}
+	interval = memory.DefaultConsolidateInterval
+	if cfg.Memory.ConsolidateInterval != "" {
+		interval, err = time.ParseDuration(cfg.Memory.ConsolidateInterval)
+		if err != nil || interval < 0 {
+			return 0, 0, fmt.Errorf("invalid memory.consolidateInterval %q: must be a duration such as \"10m\", or \"0\" to turn it off", cfg.Memory.ConsolidateInterval)
+		}
+	}
+	return after, interval, nil
+}
+
+// memoryContextConfig is how /api/memory/config shows the context limits,
+// with the age as a duration string such as "1h0m0s"
+type memoryContextConfig struct {
+	ContextLongTermK       int    `json:"contextLongTermK"`
+	ContextShortTermK      int    `json:"contextShortTermK"`
+	ContextShortTermMaxAge string `json:"contextShortTermMaxAge"` // "" for no age limit
+}
+
+// newMemoryContextConfig describes limits for the API
+func newMemoryContextConfig(limits memory.ContextLimits) memoryContextConfig {
+	cfg := memoryContextConfig{
+		ContextLongTermK:  limits.LongTermK,
+		ContextShortTermK: limits.ShortTermK,
+	}
+	if limits.ShortTermMaxAge > 0 {
+		cfg.ContextShortTermMaxAge = limits.ShortTermMaxAge.String()
+	}
+	return cfg
+}
+
+// handleMemoryConfig serves /api/memory/config: GET returns the limits of
+// the memory injected into chat prompts, and PUT changes the fields it
+// sets. A contextShortTermMaxAge of "" or "0" removes the age limit.
+func handleMemoryConfig(memStore *memory.MemoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req struct {
+				ContextLongTermK       *int    `json:"contextLongTermK,omitempty"`
+				ContextShortTermK      *int    `json:"contextShortTermK,omitempty"`
+				ContextShortTermMaxAge *string `json:"contextShortTermMaxAge,omitempty"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			limits := memStore.ContextLimits()
+			if req.ContextLongTermK != nil {
+				limits.LongTermK = *req.ContextLongTermK
+			}
+			if req.ContextShortTermK != nil {
+				limits.ShortTermK = *req.ContextShortTermK
+			}
+			if req.ContextShortTermMaxAge != nil {
+				limits.ShortTermMaxAge = 0
+				if *req.ContextShortTermMaxAge != "" {
+					maxAge, err := time.ParseDuration(*req.ContextShortTermMaxAge)
+					if err != nil {
+						http.Error(w, fmt.Sprintf("invalid contextShortTermMaxAge %q: must be a duration such as \"1h\"", *req.ContextShortTermMaxAge), http.StatusBadRequest)
+						return
+					}
+					limits.ShortTermMaxAge = maxAge
+				}
+			}
+			if err := memStore.SetContextLimits(limits); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data:   newMemoryContextConfig(memStore.ContextLimits()),
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+)
+
+func TestMemoryConfigEndpoint(t *testing.T) {
+	memStore := memory.NewMemoryStore(memory.DefaultConfig())
+	handler := handleMemoryConfig(memStore)
+
+	call := func(method, body string) (int, memoryContextConfig) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, "/api/memory/config", strings.NewReader(body)))
+		var resp struct {
+			Data memoryContextConfig `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Data
+	}
+
+	code, got := call(http.MethodGet, "")
+	if code != http.StatusOK || got.ContextShortTermK != memory.DefaultContextShortTermK || got.ContextShortTermMaxAge != "" {
+		t.Fatalf("GET = %d, %+v; want the defaults without an age limit", code, got)
+	}
+
+	code, got = call(http.MethodPut, `{"contextShortTermK": 4, "contextShortTermMaxAge": "1h"}`)
+	if code != http.StatusOK || got.ContextShortTermK != 4 || got.ContextShortTermMaxAge != "1h0m0s" {
+		t.Fatalf("PUT = %d, %+v; want 4 entries within 1h", code, got)
+	}
+	if limits := memStore.ContextLimits(); limits.ShortTermMaxAge != time.Hour || limits.LongTermK != memory.DefaultContextLongTermK {
+		t.Errorf("limits = %+v, want the age set and long-term unchanged", limits)
+	}
+
+	if code, _ := call(http.MethodPut, `{"contextShortTermMaxAge": "soon"}`); code != http.StatusBadRequest {
+		t.Errorf("PUT with an invalid age = %d, want 400", code)
+	}
+	if code, _ := call(http.MethodPut, `{"contextShortTermK": -1}`); code != http.StatusBadRequest {
+		t.Errorf("PUT with a negative count = %d, want 400", code)
+	}
+
+	if code, got = call(http.MethodPut, `{"contextShortTermMaxAge": ""}`); code != http.StatusOK || got.ContextShortTermMaxAge != "" {
+		t.Errorf("PUT clearing the age = %d, %+v; want no age limit", code, got)
+	}
+
+	cfg := &config.Config{}
+	cfg.Memory.ContextShortTermMaxAge = "-5m"
+	if _, err := contextShortTermMaxAge(cfg); err == nil {
+		t.Error("contextShortTermMaxAge() accepted a negative age")
+	}
+}
+
+func TestConsolidationConfig(t *testing.T) {
+	cfg := &config.Config{}
+	after, interval, err := consolidation(cfg)
+	if err != nil || after != memory.DefaultConsolidateAfter || interval != memory.DefaultConsolidateInterval {
+		t.Errorf("consolidation() = %v, %v, %v; want the defaults", after, interval, err)
+	}
+
+	cfg.Memory.ConsolidateAfter, cfg.Memory.ConsolidateInterval = "30m", "0"
+	if after, interval, err = consolidation(cfg); err != nil || after != 30*time.Minute || interval != 0 {
+		t.Errorf("consolidation() = %v, %v, %v; want 30m and turned off", after, interval, err)
+	}
+
+	cfg.Memory.ConsolidateAfter = "0"
+	if _, _, err := consolidation(cfg); err == nil {
+		t.Error("consolidation() accepted a zero consolidateAfter")
+	}
+}
+
+func TestShortTermTTLConfig(t *testing.T) {
+	cfg := &config.Config{}
+	if ttl, err := shortTermTTL(cfg); err != nil || ttl != 0 {
+		t.Errorf("shortTermTTL() = %v, %v; want no TTL by default", ttl, err)
+	}
+
+	cfg.Memory.ShortTermTTL = "2h"
+	if ttl, err := shortTermTTL(cfg); err != nil || ttl != 2*time.Hour {
+		t.Errorf("shortTermTTL() = %v, %v; want 2h", ttl, err)
+	}
+
+	for _, invalid := range []string{"0", "-1h", "soon"} {
+		cfg.Memory.ShortTermTTL = invalid
+		if _, err := shortTermTTL(cfg); err == nil {
+			t.Errorf("shortTermTTL() accepted %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package main provides the metrics endpoint for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goclaw/pkg/ai"
+)
+
+// handleMetrics reports runtime metrics, currently the provider routing rankings
+func handleMetrics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		metrics := make(map[string]interface{})
+		if multiClient, ok := aiClient.(*ai.MultiProviderClient); ok {
+			metrics["providers"] = map[string]interface{}{
+				"policy":   multiClient.RoutingPolicy(),
+				"rankings": multiClient.Rankings(),
+			}
+			limiter := multiClient.Limiter()
+			metrics["concurrency"] = map[string]interface{}{
+				"inFlight":    limiter.InFlight(),
+				"waiting":     limiter.Waiting(),
+				"maxRequests": limiter.MaxRequests(),
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data:   metrics,
+		})
+	}
+}
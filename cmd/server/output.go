@@ -0,0 +1,36 @@
+// Package main provides per-session output contract enforcement for Goclaw
+package main
+
+import (
+	"context"
+	"strings"
+
+	"goclaw/internal/chat"
+	"goclaw/pkg/ai"
+)
+
+// enforceOutputContract holds a response to its session's output contract,
+// asking the chat model for a rewrite when repairing it locally isn't enough.
+// The rewrite's usage is added to gen.
+func enforceOutputContract(ctx context.Context, contract chat.OutputContract, response string, params ai.GenerationParams, gen *generation) (string, bool, error) {
+	var rewrite chat.Rewriter
+	if aiClient != nil {
+		rewrite = func(ctx context.Context, instruction string) (string, error) {
+			req := ai.ChatCompletionRequest{
+				Model:    primaryChatModel,
+				Messages: []ai.Message{{Role: "user", Content: instruction}},
+			}
+			params.Apply(&req)
+			resp, err := aiClient.ChatCompletion(ctx, req)
+			if err != nil {
+				return "", err
+			}
+			gen.Usage = gen.Usage.Add(resp.Usage)
+			if len(resp.Choices) == 0 {
+				return "", nil
+			}
+			return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+		}
+	}
+	return contract.Enforce(ctx, response, rewrite)
+}
@@ -0,0 +1,131 @@
+// Package main provides the concurrent chat request pipeline for Goclaw
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/memory"
+	"goclaw/internal/vector"
+)
+
+// defaultContextBudget is the minimum memory context budget passed to GetContext
+const defaultContextBudget = 500
+
+// chatInputs holds everything gathered before the model is called
+type chatInputs struct {
+	ContextText    string
+	ContextSources []memory.ContextSource // Memory entries ContextText was built from
+	History        []chat.Message
+}
+
+// chatPipeline gathers the independent inputs of a chat turn concurrently
+type chatPipeline struct {
+	retrieveContext func(ctx context.Context, message string, budget int, limits memory.ContextLimits) (string, []memory.ContextSource, error)
+	loadHistory     func(ctx context.Context, sessionID string) ([]chat.Message, error)
+	serial          bool // Run stages one after another, set by agent.serialPipeline
+}
+
+// newChatPipeline creates a pipeline backed by the memory store and chat
+// manager. Messages memory.IsTrivial rejects under minChars aren't embedded.
+// With sessionScoped, only memories of the chat's session make up its context.
+func newChatPipeline(embedder vector.Embedder, memStore *memory.MemoryStore, chatMgr *chat.ChatManager, minChars int, sessionScoped bool) *chatPipeline {
+	p := &chatPipeline{
+		loadHistory: func(ctx context.Context, sessionID string) ([]chat.Message, error) {
+			return chatMgr.GetMessages(sessionID)
+		},
+	}
+
+	// Without an embedder, or for a message too slight to be worth an
+	// embedding call, long-term memories are matched on text
+	p.retrieveContext = func(ctx context.Context, message string, budget int, limits memory.ContextLimits) (string, []memory.ContextSource, error) {
+		var embedding []float32
+		if embedder != nil && !memory.IsTrivial(message, minChars) {
+			var err error
+			if embedding, err = embedder.Embed(ctx, message); err != nil {
+				return "", nil, err
+			}
+		}
+		var filter map[string]interface{}
+		if sessionScoped {
+			filter = map[string]interface{}{memory.MetadataSession: memory.SessionFrom(ctx)}
+		}
+		return memStore.GetContextFiltered(ctx, message, embedding, budget, limits, filter)
+	}
+
+	return p
+}
+
+// gather retrieves memory context (within budget tokens and limits) and
+// conversation history in parallel. Stage failures are aggregated; whatever succeeded is
+// still returned.
+func (p *chatPipeline) gather(ctx context.Context, sessionID, message string, useMemory bool, budget int, limits memory.ContextLimits) (*chatInputs, error) {
+	inputs := &chatInputs{}
+
+	stages := []func(context.Context) error{
+		func(ctx context.Context) error {
+			history, err := p.loadHistory(ctx, sessionID)
+			inputs.History = history
+			return err
+		},
+	}
+
+	if useMemory && p.retrieveContext != nil {
+		stages = append(stages, func(ctx context.Context) error {
+			// The agent's notes of this session are part of the context
+			ctx = memory.WithSession(ctx, sessionID)
+			contextText, sources, err := p.retrieveContext(ctx, message, budget, limits)
+			inputs.ContextText = contextText
+			inputs.ContextSources = sources
+			return err
+		})
+	}
+
+	err := runStages(ctx, p.serial, stages...)
+	return inputs, err
+}
+
+// runStages runs the stages concurrently (or serially) and aggregates their errors
+func runStages(ctx context.Context, serial bool, stages ...func(context.Context) error) error {
+	errs := make([]error, len(stages))
+
+	if serial {
+		for i, stage := range stages {
+			errs[i] = stage(ctx)
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i, stage := range stages {
+			wg.Add(1)
+			go func(i int, stage func(context.Context) error) {
+				defer wg.Done()
+				errs[i] = stage(ctx)
+			}(i, stage)
+		}
+		wg.Wait()
+	}
+
+	var failed stageErrors
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// stageErrors aggregates errors from pipeline stages
+type stageErrors []error
+
+func (e stageErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/memory"
+)
+
+const stageDelay = 50 * time.Millisecond
+
+// testContextLimits are the default memory context limits
+var testContextLimits = memory.ContextLimits{
+	LongTermK:  memory.DefaultContextLongTermK,
+	ShortTermK: memory.DefaultContextShortTermK,
+}
+
+// newSlowPipeline returns a pipeline whose stages each take stageDelay
+func newSlowPipeline(serial bool) *chatPipeline {
+	return &chatPipeline{
+		retrieveContext: func(ctx context.Context, message string, budget int, limits memory.ContextLimits) (string, []memory.ContextSource, error) {
+			time.Sleep(stageDelay)
+			return "[RECENT]: " + message, nil, nil
+		},
+		loadHistory: func(ctx context.Context, sessionID string) ([]chat.Message, error) {
+			time.Sleep(stageDelay)
+			return []chat.Message{{Role: "user", Content: "earlier"}}, nil
+		},
+		serial: serial,
+	}
+}
+
+func TestChatPipelineOverlapsStages(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Now()
+	serialInputs, err := newSlowPipeline(true).gather(ctx, "s1", "hello", true, defaultContextBudget, testContextLimits)
+	serial := time.Since(start)
+	if err != nil {
+		t.Fatalf("serial gather() error = %v", err)
+	}
+
+	start = time.Now()
+	inputs, err := newSlowPipeline(false).gather(ctx, "s1", "hello", true, defaultContextBudget, testContextLimits)
+	pipelined := time.Since(start)
+	if err != nil {
+		t.Fatalf("pipelined gather() error = %v", err)
+	}
+
+	if inputs.ContextText != serialInputs.ContextText || len(inputs.History) != len(serialInputs.History) {
+		t.Errorf("pipelined inputs %+v differ from serial %+v", inputs, serialInputs)
+	}
+	if serial < 2*stageDelay {
+		t.Errorf("serial gather took %v, expected at least %v", serial, 2*stageDelay)
+	}
+	if pipelined >= serial-stageDelay/2 {
+		t.Errorf("pipelined gather took %v, expected well under serial %v", pipelined, serial)
+	}
+}
+
+func TestChatPipelineAggregatesErrors(t *testing.T) {
+	p := &chatPipeline{
+		retrieveContext: func(ctx context.Context, message string, budget int, limits memory.ContextLimits) (string, []memory.ContextSource, error) {
+			return "", nil, errors.New("embedder down")
+		},
+		loadHistory: func(ctx context.Context, sessionID string) ([]chat.Message, error) {
+			return nil, errors.New("session not found")
+		},
+	}
+
+	_, err := p.gather(context.Background(), "s1", "hello", true, defaultContextBudget, testContextLimits)
+	if err == nil {
+		t.Fatal("expected aggregated error")
+	}
+	if !strings.Contains(err.Error(), "embedder down") || !strings.Contains(err.Error(), "session not found") {
+		t.Errorf("error %q should mention both stage failures", err)
+	}
+}
+
+func TestChatPipelineSkipsMemory(t *testing.T) {
+	p := newSlowPipeline(false)
+	p.retrieveContext = func(ctx context.Context, message string, budget int, limits memory.ContextLimits) (string, []memory.ContextSource, error) {
+		t.Error("memory retrieval should be skipped when useMemory is false")
+		return "", nil, nil
+	}
+
+	if _, err := p.gather(context.Background(), "s1", "hello", false, defaultContextBudget, testContextLimits); err != nil {
+		t.Fatalf("gather() error = %v", err)
+	}
+}
+
+func BenchmarkChatPipelineSerial(b *testing.B) {
+	p := newSlowPipeline(true)
+	for i := 0; i < b.N; i++ {
+		p.gather(context.Background(), "s1", "hello", true, defaultContextBudget, testContextLimits)
+	}
+}
+
+func BenchmarkChatPipelineConcurrent(b *testing.B) {
+	p := newSlowPipeline(false)
+	for i := 0; i < b.N; i++ {
+		p.gather(context.Background(), "s1", "hello", true, defaultContextBudget, testContextLimits)
+	}
+}
@@ -0,0 +1,81 @@
+// Package main provides route registration with per-route CORS for Goclaw
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/internal/security"
+)
+
+// Methods of the route groups, advertised in CORS preflight responses
+var (
+	readMethods  = []string{http.MethodGet}
+	writeMethods = []string{http.MethodPost}
+)
+
+// corsPolicy builds the CORS policy from gateway.cors. It is nil when no
+// origins are allowed, and the routes send no CORS headers.
+func corsPolicy(cfg *config.Config) (*security.CORSPolicy, error) {
+	cors := cfg.Gateway.CORS
+	var maxAge time.Duration
+	if cors.MaxAge != "" {
+		var err error
+		maxAge, err = time.ParseDuration(cors.MaxAge)
+		if err != nil || maxAge < time.Second {
+			return nil, fmt.Errorf("invalid gateway.cors.maxAge %q: must be a duration of at least 1s", cors.MaxAge)
+		}
+	}
+	if len(cors.AllowedOrigins) == 0 {
+		return nil, nil
+	}
+	return &security.CORSPolicy{AllowedOrigins: cors.AllowedOrigins, MaxAge: maxAge}, nil
+}
+
+// trustedProxies builds the proxies whose forwarding headers are believed
+// from gateway.trustProxy and gateway.trustedProxies. It is nil when proxies
+// aren't trusted, and clients are identified by their peer address.
+func trustedProxies(cfg *config.Config) (*security.TrustedProxies, error) {
+	if !cfg.Gateway.TrustProxy {
+		return nil, nil
+	}
+	if len(cfg.Gateway.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("gateway.trustProxy needs gateway.trustedProxies, the CIDRs of the proxies to trust")
+	}
+	proxies, err := security.ParseTrustedProxies(cfg.Gateway.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gateway.trustedProxies: %w", err)
+	}
+	return proxies, nil
+}
+
+// routeGroup registers routes that accept the same methods, so each group
+// declares its methods once for the CORS policy
+type routeGroup struct {
+	mux  *http.ServeMux
+	cors func(http.Handler) http.Handler // nil without a CORS policy
+}
+
+// newRouteGroup creates a group on mux for routes accepting methods
+func newRouteGroup(mux *http.ServeMux, policy *security.CORSPolicy, methods ...string) routeGroup {
+	group := routeGroup{mux: mux}
+	if policy != nil {
+		group.cors = policy.Middleware(methods...)
+	}
+	return group
+}
+
+// Handle registers a handler for pattern
+func (g routeGroup) Handle(pattern string, handler http.Handler) {
+	if g.cors != nil {
+		handler = g.cors(handler)
+	}
+	g.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers a handler function for pattern
+func (g routeGroup) HandleFunc(pattern string, handler http.HandlerFunc) {
+	g.Handle(pattern, handler)
+}
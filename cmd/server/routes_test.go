@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goclaw/internal/config"
+)
+
+func TestReadRoutePreflightListsOnlyGet(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Gateway.CORS = config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: "10m"}
+	policy, err := corsPolicy(cfg)
+	if err != nil {
+		t.Fatalf("corsPolicy() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	newRouteGroup(mux, policy, readMethods...).HandleFunc("/metrics", handleMetrics())
+	newRouteGroup(mux, policy, writeMethods...).HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {})
+
+	preflight := func(path string) http.Header {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("preflight of %s status = %d", path, rec.Code)
+		}
+		return rec.Header()
+	}
+
+	header := preflight("/metrics")
+	if got := header.Get("Access-Control-Allow-Methods"); got != "GET, OPTIONS" {
+		t.Errorf("read route Allow-Methods = %q, want %q", got, "GET, OPTIONS")
+	}
+	if got := header.Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age = %q, want the configured 600", got)
+	}
+	if got := header.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Allow-Origin = %q, want the allowed origin", got)
+	}
+	if got := preflight("/api/chat").Get("Access-Control-Allow-Methods"); got != "POST, OPTIONS" {
+		t.Errorf("write route Allow-Methods = %q, want %q", got, "POST, OPTIONS")
+	}
+}
+
+func TestRoutesWithoutCORSPolicy(t *testing.T) {
+	policy, err := corsPolicy(config.NewDefaultConfig())
+	if err != nil || policy != nil {
+		t.Fatalf("corsPolicy() = %v, %v, want no policy without allowed origins", policy, err)
+	}
+
+	mux := http.NewServeMux()
+	newRouteGroup(mux, policy, readMethods...).HandleFunc("/metrics", handleMetrics())
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("headers = %v, want no CORS headers", rec.Header())
+	}
+
+	cfg := config.NewDefaultConfig()
+	cfg.Gateway.CORS.MaxAge = "soon"
+	if _, err := corsPolicy(cfg); err == nil {
+		t.Error("corsPolicy() accepted an invalid maxAge")
+	}
+}
+
+func TestTrustedProxiesConfig(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Gateway.TrustedProxies = []string{"10.0.0.0/8"}
+	if proxies, err := trustedProxies(cfg); err != nil || proxies != nil {
+		t.Errorf("trustedProxies() = %v, %v, want none without trustProxy", proxies, err)
+	}
+
+	cfg.Gateway.TrustProxy = true
+	if proxies, err := trustedProxies(cfg); err != nil || proxies == nil {
+		t.Errorf("trustedProxies() = %v, %v, want the configured proxies", proxies, err)
+	}
+
+	cfg.Gateway.TrustedProxies = nil
+	if _, err := trustedProxies(cfg); err == nil {
+		t.Error("trustedProxies() accepted trustProxy without proxies")
+	}
+
+	cfg.Gateway.TrustedProxies = []string{"10.0.0.0/99"}
+	if _, err := trustedProxies(cfg); err == nil {
+		t.Error("trustedProxies() accepted an invalid CIDR")
+	}
+}
@@ -0,0 +1,48 @@
+// Package main provides intent-based model routing for chat messages
+package main
+
+import (
+	"goclaw/internal/intent"
+	"goclaw/pkg/ai"
+)
+
+// Where a routed model came from
+const (
+	routeFromRequest = "request" // The chat request named a model
+	routeFromSession = "session" // The session's generation config sets a model
+	routeFromTier    = "tier"    // agent.modelTiers for the message's tier
+	routeFromDefault = "default" // primaryChatModel
+)
+
+// modelRoute is the model chosen to answer a message
+type modelRoute struct {
+	Model  string `json:"model"`
+	Tier   string `json:"tier,omitempty"` // Set when routed by tier
+	Source string `json:"source"`
+}
+
+// routeModel picks the model for a message: a model named by the request
+// wins, then the session's model, then the model configured for the
+// message's tier in agent.modelTiers. A tier without a model falls back to
+// the standard tier, and without tiers primaryChatModel answers.
+func routeModel(tiers map[string]string, message, requested string, params ai.GenerationParams) modelRoute {
+	if requested != "" {
+		return modelRoute{Model: requested, Source: routeFromRequest}
+	}
+	if params.Model != "" {
+		return modelRoute{Model: params.Model, Source: routeFromSession}
+	}
+
+	if len(tiers) > 0 {
+		tier := intent.ClassifyTier(message)
+		model := tiers[string(tier)]
+		if model == "" {
+			model = tiers[string(intent.TierStandard)]
+		}
+		if model != "" {
+			return modelRoute{Model: model, Tier: string(tier), Source: routeFromTier}
+		}
+	}
+
+	return modelRoute{Model: primaryChatModel, Source: routeFromDefault}
+}
@@ -0,0 +1,380 @@
+// Package main provides session discovery handlers for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/pkg/ai"
+)
+
+// defaultRecentSessions is the number of sessions returned by /api/sessions/recent
+const defaultRecentSessions = 10
+
+// handleRecentSessions lists the most recently active sessions so clients
+// can offer to resume a previous conversation
+func handleRecentSessions(chatMgr *chat.ChatManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := defaultRecentSessions
+		if value := r.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		sessions := chatMgr.RecentSessions(r.URL.Query().Get("user"), limit)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data: map[string]interface{}{
+				"sessions": sessions,
+			},
+		})
+	}
+}
+
+// handleExportSession exports a session as markdown (default) or JSON
+func handleExportSession(chatMgr *chat.ChatManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		exportFormat := r.URL.Query().Get("format")
+		if exportFormat == "" {
+			exportFormat = chat.ExportMarkdown
+		}
+
+		content, err := chatMgr.ExportSession(r.URL.Query().Get("id"), exportFormat)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if exportFormat == chat.ExportJSON {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		}
+		w.Write([]byte(content))
+	}
+}
+
+// handleImportSession restores an exported conversation into a new session
+func handleImportSession(chatMgr *chat.ChatManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Format    string `json:"format,omitempty"` // "markdown" (default) or "json"
+			Content   string `json:"content"`
+			SessionID string `json:"sessionId,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		sessionID := req.SessionID
+		if sessionID == "" {
+			sessionID = fmt.Sprintf("imported_%d", time.Now().UnixNano())
+		}
+
+		session, err := chatMgr.ImportSession(sessionID, req.Format, req.Content)
+		if err != nil {
+			http.Error(w, "Import failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data: map[string]interface{}{
+				"sessionId":    session.ID,
+				"messageCount": len(session.Messages),
+			},
+		})
+	}
+}
+
+// mainSessionDefaults builds the main session defaults from configuration
+func mainSessionDefaults(cfg *config.Config) chat.MainSessionDefaults {
+	defaults := chat.DefaultMainSessionDefaults()
+	if cfg.Sessions.AutoMain != nil {
+		defaults.AutoFirst = *cfg.Sessions.AutoMain
+	}
+	defaults.SessionID = cfg.Sessions.MainSession
+	return defaults
+}
+
+// autoSaveConfig builds the session auto-save settings from configuration
+func autoSaveConfig(cfg *config.Config) (chat.AutoSaveConfig, error) {
+	autoSave := chat.AutoSaveConfig{
+		Dir:         cfg.Sessions.Dir,
+		MaxMessages: cfg.Sessions.AutoSaveMessages,
+	}
+	if cfg.Sessions.AutoSaveInterval != "" {
+		interval, err := time.ParseDuration(cfg.Sessions.AutoSaveInterval)
+		if err != nil {
+			return autoSave, fmt.Errorf("invalid sessions.autoSaveInterval: %w", err)
+		}
+		autoSave.Interval = interval
+	}
+	return autoSave, nil
+}
+
+// handleSessionRoutes serves per-session actions under /api/sessions/{id}/
+func handleSessionRoutes(chatMgr *chat.ChatManager, memStore *memory.MemoryStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sessions/"), "/")
+		if len(parts) != 2 || parts[0] == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch parts[1] {
+		case "main":
+			handleSetMainSession(w, r, chatMgr, parts[0])
+		case "config":
+			handleSessionConfig(w, r, chatMgr, parts[0])
+		case "fork":
+			handleForkSession(w, r, chatMgr, parts[0])
+		case "merge":
+			handleMergeSession(w, r, chatMgr, parts[0])
+		case "clear":
+			handleClearSession(w, r, chatMgr, parts[0])
+		case "bundle":
+			handleSessionBundle(w, r, chatMgr, memStore, parts[0])
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// handleSetMainSession serves POST /api/sessions/{id}/main
+func handleSetMainSession(w http.ResponseWriter, r *http.Request, chatMgr *chat.ChatManager, sessionID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	previous, err := chatMgr.SetMainSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Status:  "ok",
+		Message: "Main session updated",
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+			"previous":  previous,
+		},
+	})
+}
+
+// handleForkSession serves POST /api/sessions/{id}/fork, copying the session
+// into a new one. The fork ID is generated unless the body sets "forkId".
+func handleForkSession(w http.ResponseWriter, r *http.Request, chatMgr *chat.ChatManager, sessionID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ForkID string `json:"forkId,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ForkID == "" {
+		req.ForkID = fmt.Sprintf("%s_fork_%d", sessionID, time.Now().UnixNano())
+	}
+
+	fork, err := chatMgr.ForkSession(sessionID, req.ForkID)
+	if err != nil {
+		status := http.StatusNotFound
+		if _, exists := chatMgr.GetSession(sessionID); exists {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Status:  "ok",
+		Message: "Session forked",
+		Data: map[string]interface{}{
+			"sessionId":    fork.ID,
+			"forkedFrom":   sessionID,
+			"messageCount": len(fork.Messages),
+		},
+	})
+}
+
+// handleMergeSession serves POST /api/sessions/{id}/merge, appending the
+// messages of session {id} from "fromIndex" onto "target". The target
+// defaults to the session {id} was forked from.
+func handleMergeSession(w http.ResponseWriter, r *http.Request, chatMgr *chat.ChatManager, sessionID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Target    string `json:"target,omitempty"`
+		FromIndex int    `json:"fromIndex,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	source, exists := chatMgr.GetSession(sessionID)
+	if !exists {
+		http.Error(w, "session not found: "+sessionID, http.StatusNotFound)
+		return
+	}
+	if req.Target == "" {
+		req.Target, _ = source.Metadata[chat.MetadataForkedFrom].(string)
+		if req.Target == "" {
+			http.Error(w, "target is required for a session that is not a fork", http.StatusBadRequest)
+			return
+		}
+	}
+	if _, exists := chatMgr.GetSession(req.Target); !exists {
+		http.Error(w, "session not found: "+req.Target, http.StatusNotFound)
+		return
+	}
+
+	if err := chatMgr.MergeSession(sessionID, req.Target, req.FromIndex); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	messages, _ := chatMgr.GetMessages(req.Target)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Status:  "ok",
+		Message: "Session merged",
+		Data: map[string]interface{}{
+			"sessionId":    req.Target,
+			"mergedFrom":   sessionID,
+			"messageCount": len(messages),
+		},
+	})
+}
+
+// handleClearSession serves POST /api/sessions/{id}/clear, emptying the
+// conversation but keeping the session, its system prompt and its config
+func handleClearSession(w http.ResponseWriter, r *http.Request, chatMgr *chat.ChatManager, sessionID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	release := chatMgr.LockSession(sessionID)
+	defer release()
+
+	if err := chatMgr.ClearMessages(sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	messages, _ := chatMgr.GetMessages(sessionID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Status:  "ok",
+		Message: "Session cleared",
+		Data: map[string]interface{}{
+			"sessionId":    sessionID,
+			"messageCount": len(messages),
+		},
+	})
+}
+
+// handleSessionConfig serves GET and PUT /api/sessions/{id}/config, the
+// session's sampling parameters and output contract. Omitted parameters use
+// the provider default; an omitted output contract is left unchanged.
+func handleSessionConfig(w http.ResponseWriter, r *http.Request, chatMgr *chat.ChatManager, sessionID string) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			ai.GenerationParams
+			Output *chat.OutputContract `json:"output,omitempty"` // Length and format contract of the responses, unchanged when omitted
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := req.GenerationParams.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Output != nil {
+			if err := req.Output.Validate(); err != nil {
+				http.Error(w, "Invalid output contract: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := chatMgr.SetGenerationParams(sessionID, req.GenerationParams); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if req.Output != nil {
+			if err := chatMgr.SetOutputContract(sessionID, *req.Output); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params, err := chatMgr.GetGenerationParams(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	contract, _ := chatMgr.GetOutputContract(sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Status: "ok",
+		Data: map[string]interface{}{
+			"sessionId":  sessionID,
+			"generation": params,
+			"output":     contract,
+		},
+	})
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+	"goclaw/internal/vector"
+	"goclaw/pkg/ai"
+)
+
+func TestHandleRecentSessions(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Sessions are created in a different order from their activity
+	activity := map[string]time.Duration{"oldest": 0, "middle": time.Hour, "newest": 2 * time.Hour, "other-user": 3 * time.Hour}
+	for _, id := range []string{"middle", "oldest", "newest", "other-user"} {
+		chatMgr.CreateSession(id, "")
+		chatMgr.AddMessage(id, "user", "Question for "+id)
+		chatMgr.AddMessage(id, "assistant", "Answer for "+id+" "+strings.Repeat("long ", 50))
+
+		owner := "alice"
+		if id == "other-user" {
+			owner = "bob"
+		}
+		chatMgr.SetMetadata(id, "user", owner)
+
+		session, _ := chatMgr.GetSession(id)
+		session.Messages[1].Timestamp = base.Add(activity[id])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/recent?user=alice&limit=2", nil)
+	rec := httptest.NewRecorder()
+	handleRecentSessions(chatMgr)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			Sessions []chat.SessionSummary `json:"sessions"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	sessions := resp.Data.Sessions
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	if sessions[0].ID != "newest" || sessions[1].ID != "middle" {
+		t.Errorf("order = %s, %s; want newest, middle", sessions[0].ID, sessions[1].ID)
+	}
+	if sessions[0].Title != "Question for newest" {
+		t.Errorf("title = %q", sessions[0].Title)
+	}
+	if !strings.HasPrefix(sessions[0].Preview, "Answer for newest") || !strings.HasSuffix(sessions[0].Preview, "…") {
+		t.Errorf("preview = %q, want truncated last assistant message", sessions[0].Preview)
+	}
+	if !sessions[0].LastMessageAt.After(sessions[1].LastMessageAt) {
+		t.Errorf("lastMessageAt %v should be after %v", sessions[0].LastMessageAt, sessions[1].LastMessageAt)
+	}
+}
+
+func TestHandleRecentSessionsInvalidLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleRecentSessions(chat.NewChatManager(100))(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/recent?limit=abc", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, exportFormat := range []string{chat.ExportMarkdown, chat.ExportJSON} {
+		t.Run(exportFormat, func(t *testing.T) {
+			chatMgr := chat.NewChatManager(100)
+			chatMgr.CreateSession("original", "")
+			chatMgr.AddMessage("original", "user", "Plan a trip\n\n## not a heading (really)")
+			chatMgr.AddMessageWithMetadata("original", "assistant", "Where to?", map[string]interface{}{"model": "glm-4"})
+			chatMgr.AddMessage("original", "user", "Kyoto")
+
+			rec := httptest.NewRecorder()
+			handleExportSession(chatMgr)(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/export?id=original&format="+exportFormat, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("export status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+
+			payload, _ := json.Marshal(map[string]string{"format": exportFormat, "content": rec.Body.String()})
+			rec = httptest.NewRecorder()
+			handleImportSession(chatMgr)(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/import", bytes.NewReader(payload)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("import status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+
+			var resp struct {
+				Data struct {
+					SessionID string `json:"sessionId"`
+				} `json:"data"`
+			}
+			json.NewDecoder(rec.Body).Decode(&resp)
+
+			original, _ := chatMgr.GetMessages("original")
+			imported, err := chatMgr.GetMessages(resp.Data.SessionID)
+			if err != nil {
+				t.Fatalf("imported session %q: %v", resp.Data.SessionID, err)
+			}
+			if len(imported) != len(original) {
+				t.Fatalf("imported %d messages, want %d", len(imported), len(original))
+			}
+			for i := range original {
+				if imported[i].Role != original[i].Role || imported[i].Content != original[i].Content {
+					t.Errorf("message %d = %s %q, want %s %q", i, imported[i].Role, imported[i].Content, original[i].Role, original[i].Content)
+				}
+				if !imported[i].Timestamp.Equal(original[i].Timestamp) {
+					t.Errorf("message %d timestamp = %v, want %v", i, imported[i].Timestamp, original[i].Timestamp)
+				}
+			}
+			if imported[1].Metadata["model"] != "glm-4" {
+				t.Errorf("metadata = %v, want model preserved", imported[1].Metadata)
+			}
+		})
+	}
+}
+
+func TestImportSessionRejectsUnknownRole(t *testing.T) {
+	payload, _ := json.Marshal(map[string]string{"content": "## Robot (2026-01-01T00:00:00Z)\n\nbeep\n"})
+	rec := httptest.NewRecorder()
+	handleImportSession(chat.NewChatManager(100))(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/import", bytes.NewReader(payload)))
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "unknown role") {
+		t.Errorf("status = %d, body = %q; want unknown role error", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleSetMainSession(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("first", "")
+	chatMgr.CreateSession("second", "")
+
+	rec := httptest.NewRecorder()
+	handleSessionRoutes(chatMgr, nil)(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/second/main", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"previous":"first"`) {
+		t.Errorf("body = %s, want previous main reported", rec.Body.String())
+	}
+	if mainSession, _ := chatMgr.GetMainSession(); mainSession.ID != "second" {
+		t.Errorf("main session = %s, want second", mainSession.ID)
+	}
+
+	rec = httptest.NewRecorder()
+	handleSessionRoutes(chatMgr, nil)(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/missing/main", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status for unknown session = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// recordingAIClient records the requests it receives
+type recordingAIClient struct {
+	mu       sync.Mutex
+	requests []ai.ChatCompletionRequest
+}
+
+func (c *recordingAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "ok"}}},
+	}, nil
+}
+
+func (c *recordingAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func (c *recordingAIClient) last() ai.ChatCompletionRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[len(c.requests)-1]
+}
+
+func TestSessionGenerationParams(t *testing.T) {
+	client := &recordingAIClient{}
+	useFakeAI(t, client)
+
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("brainstorm", "")
+	chatMgr.CreateSession("factual", "")
+	chatMgr.CreateSession("plain", "")
+
+	routes := handleSessionRoutes(chatMgr, nil)
+	putConfig := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/sessions/"+id+"/config", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		routes(rec, req)
+		return rec
+	}
+
+	if rec := putConfig("brainstorm", `{"temperature": 1.3, "topP": 0.95}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT brainstorm config: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := putConfig("factual", `{"temperature": 0.1, "maxTokens": 256}`); rec.Code != http.StatusOK {
+		t.Fatalf("PUT factual config: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := putConfig("factual", `{"temperature": 3}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("out-of-range temperature: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := putConfig("missing", `{"temperature": 1}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown session: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// GET returns the stored parameters
+	rec := httptest.NewRecorder()
+	routes(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/factual/config", nil))
+	var resp struct {
+		Data struct {
+			Generation ai.GenerationParams `json:"generation"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got := resp.Data.Generation; got.MaxTokens == nil || *got.MaxTokens != 256 {
+		t.Errorf("GET factual config = %+v, want maxTokens 256", got)
+	}
+
+	handler := handleChat(nil, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+
+	postChat(t, handler, map[string]interface{}{"message": "ideas for a party", "sessionId": "brainstorm"})
+	brainstorm := client.last()
+	postChat(t, handler, map[string]interface{}{"message": "boiling point of water", "sessionId": "factual"})
+	factual := client.last()
+	postChat(t, handler, map[string]interface{}{"message": "hello", "sessionId": "plain"})
+	plain := client.last()
+
+	if brainstorm.Temperature == nil || *brainstorm.Temperature != 1.3 || brainstorm.TopP == nil || *brainstorm.TopP != 0.95 {
+		t.Errorf("brainstorm request params = %v/%v, want temperature 1.3 and topP 0.95", brainstorm.Temperature, brainstorm.TopP)
+	}
+	if brainstorm.MaxTokens != nil {
+		t.Errorf("brainstorm maxTokens = %d, want provider default", *brainstorm.MaxTokens)
+	}
+	if factual.Temperature == nil || *factual.Temperature != 0.1 || factual.MaxTokens == nil || *factual.MaxTokens != 256 {
+		t.Errorf("factual request params = %v/%v, want temperature 0.1 and maxTokens 256", factual.Temperature, factual.MaxTokens)
+	}
+	if plain.Temperature != nil || plain.MaxTokens != nil || plain.TopP != nil {
+		t.Errorf("plain request should use provider defaults, got %+v", plain)
+	}
+}
+
+func TestSessionThinkingLevel(t *testing.T) {
+	client := &recordingAIClient{}
+	useFakeAI(t, client)
+
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("deep", "")
+
+	routes := handleSessionRoutes(chatMgr, nil)
+	rec := httptest.NewRecorder()
+	routes(rec, httptest.NewRequest(http.MethodPut, "/api/sessions/deep/config", strings.NewReader(`{"thinking": "high"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT deep config: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if session, _ := chatMgr.GetSession("deep"); session.Config.ThinkingLevel != ai.ThinkingHigh {
+		t.Errorf("ThinkingLevel = %q, want high", session.Config.ThinkingLevel)
+	}
+
+	handler := handleChat(nil, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+	postChat(t, handler, map[string]interface{}{"message": "prove it", "sessionId": "deep"})
+	if got := client.last().Thinking; got != ai.ThinkingHigh {
+		t.Errorf("session request thinking = %q, want high", got)
+	}
+	postChat(t, handler, map[string]interface{}{"message": "quick one", "sessionId": "deep", "thinking": "off"})
+	if got := client.last().Thinking; got != ai.ThinkingOff {
+		t.Errorf("overridden request thinking = %q, want off", got)
+	}
+}
+
+func TestSessionOutputContract(t *testing.T) {
+	client := &fakeAIClient{reply: "This reply is far too long to go out as a single text message."}
+	useFakeAI(t, client)
+
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("sms", "")
+
+	routes := handleSessionRoutes(chatMgr, nil)
+	rec := httptest.NewRecorder()
+	routes(rec, httptest.NewRequest(http.MethodPut, "/api/sessions/sms/config", strings.NewReader(`{"output": {"maxChars": 20}}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT sms config: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	routes(rec, httptest.NewRequest(http.MethodPut, "/api/sessions/sms/config", strings.NewReader(`{"output": {"format": "xml"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	handler := handleChat(nil, memory.NewMemoryStore(memory.DefaultConfig()), chatMgr,
+		vector.NewInMemoryStore(nil), tools.NewRegistry(), &config.Config{})
+	data := postChat(t, handler, map[string]interface{}{"message": "status?", "sessionId": "sms"})
+
+	response, _ := data["response"].(string)
+	if chars := len([]rune(response)); chars > 20 {
+		t.Errorf("response %q has %d characters, want at most 20", response, chars)
+	}
+	if enforced, _ := data["contractEnforced"].(bool); !enforced {
+		t.Errorf("contractEnforced = %v, want true", data["contractEnforced"])
+	}
+	if !strings.Contains(client.prompts[0], "under 20 characters") {
+		t.Errorf("prompt %q should state the length limit", client.prompts[0])
+	}
+	// The stored reply is the one that was sent
+	messages, _ := chatMgr.GetMessages("sms")
+	if last := messages[len(messages)-1]; last.Content != response {
+		t.Errorf("stored reply = %q, want %q", last.Content, response)
+	}
+}
+
+func TestForkAndMergeSession(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("parent", "")
+	chatMgr.AddMessage("parent", "user", "Plan a trip to Japan")
+	chatMgr.AddMessage("parent", "assistant", "Tokyo or Kyoto?")
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		handleSessionRoutes(chatMgr, nil)(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload)))
+		return rec
+	}
+
+	if rec := post("/api/sessions/parent/fork", map[string]string{"forkId": "tangent"}); rec.Code != http.StatusOK {
+		t.Fatalf("fork status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	chatMgr.AddMessage("tangent", "user", "What about Hokkaido?")
+	chatMgr.AddMessage("tangent", "assistant", "Great for skiing in winter.")
+	chatMgr.AddMessage("parent", "user", "Kyoto")
+
+	// The fork's shared history is skipped, so merging twice adds nothing new
+	for i := 0; i < 2; i++ {
+		if rec := post("/api/sessions/tangent/merge", nil); rec.Code != http.StatusOK {
+			t.Fatalf("merge %d status = %d, body = %s", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	messages, _ := chatMgr.GetMessages("parent")
+	want := []string{"Plan a trip to Japan", "Tokyo or Kyoto?", "Kyoto", "What about Hokkaido?", "Great for skiing in winter."}
+	if len(messages) != len(want) {
+		t.Fatalf("parent has %d messages, want %d: %+v", len(messages), len(want), messages)
+	}
+	for i, content := range want {
+		if messages[i].Content != content {
+			t.Errorf("message %d = %q, want %q", i, messages[i].Content, content)
+		}
+		if i > 0 && !messages[i].Timestamp.After(messages[i-1].Timestamp) {
+			t.Errorf("message %d timestamp %v is not after the previous one", i, messages[i].Timestamp)
+		}
+	}
+	if messages[3].Metadata[chat.MetadataMergedFrom] != "tangent" {
+		t.Errorf("merged message metadata = %v, want the source session", messages[3].Metadata)
+	}
+
+	if rec := post("/api/sessions/parent/merge", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("merging a session that isn't a fork without a target: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestClearSessionKeepsSession(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("s1", "You are terse.")
+	chatMgr.AddMessage("s1", "system", "Answer in English.")
+	chatMgr.AddMessage("s1", "user", "hello")
+	chatMgr.AddMessage("s1", "assistant", "hi")
+	chatMgr.AddSessionToolRound("s1")
+
+	maxTokens := 128
+	chatMgr.SetGenerationParams("s1", ai.GenerationParams{MaxTokens: &maxTokens})
+
+	rec := httptest.NewRecorder()
+	handleSessionRoutes(chatMgr, nil)(rec, httptest.NewRequest(http.MethodPost, "/api/sessions/s1/clear", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	session, exists := chatMgr.GetSession("s1")
+	if !exists {
+		t.Fatal("session should still exist after clearing")
+	}
+	if len(session.Messages) != 1 || session.Messages[0].Content != "Answer in English." {
+		t.Errorf("messages = %+v, want only the system message", session.Messages)
+	}
+	if session.SystemPrompt != "You are terse." || session.ToolRounds != 0 {
+		t.Errorf("system prompt = %q, tool rounds = %d; want the prompt kept and rounds reset", session.SystemPrompt, session.ToolRounds)
+	}
+	if params, _ := chatMgr.GetGenerationParams("s1"); params.MaxTokens == nil || *params.MaxTokens != 128 {
+		t.Errorf("generation params = %+v, want the config kept", params)
+	}
+	if _, ok := session.Metadata[chat.MetadataClearedAt]; !ok {
+		t.Errorf("metadata = %v, want clearedAt recorded", session.Metadata)
+	}
+}
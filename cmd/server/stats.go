@@ -0,0 +1,147 @@
+// Package main provides the aggregate dashboard statistics endpoint for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/cron"
+	"goclaw/internal/memory"
+	"goclaw/internal/security"
+	"goclaw/pkg/ai"
+)
+
+// statsSources are the subsystems /api/stats reports on. Security and cron
+// may be nil when they aren't enabled.
+type statsSources struct {
+	chatMgr   *chat.ChatManager
+	memStore  *memory.MemoryStore
+	security  *security.SecurityManager
+	cron      *cron.CronManager
+	cfg       *config.Config
+	startedAt time.Time
+}
+
+// providerCost is the price of a provider's tokens, per million tokens
+type providerCost struct {
+	Input  float64
+	Output float64
+}
+
+// handleStats reports an aggregate of every subsystem for the dashboard
+func handleStats(sources statsSources) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{
+			Status: "ok",
+			Data:   gatherStats(sources),
+		})
+	}
+}
+
+// gatherStats collects the statistics of each subsystem
+func gatherStats(sources statsSources) map[string]interface{} {
+	uptime := time.Since(sources.startedAt)
+	stats := map[string]interface{}{
+		"uptime": map[string]interface{}{
+			"startedAt": sources.startedAt.Format(time.RFC3339),
+			"seconds":   int64(uptime.Seconds()),
+			"human":     uptime.Truncate(time.Second).String(),
+		},
+		"sessions": sources.chatMgr.GetSessionStatistics(),
+		"memory":   sources.memStore.Stats(),
+		"tokens":   gatherTokenStats(sources.cfg),
+	}
+
+	if sources.security != nil {
+		securityStats := sources.security.GetStats()
+		securityStats["enabled"] = true
+		stats["security"] = securityStats
+	} else {
+		stats["security"] = map[string]interface{}{"enabled": false}
+	}
+
+	cronStats := map[string]interface{}{"taskCount": 0}
+	if sources.cron != nil {
+		cronStats["taskCount"] = len(sources.cron.ListTasks())
+		if next := sources.cron.NextRun(); next != nil {
+			cronStats["nextRun"] = next.Format(time.RFC3339)
+		}
+	}
+	stats["cron"] = cronStats
+
+	return stats
+}
+
+// gatherTokenStats totals provider token usage, pricing it with each
+// provider's configured cost
+func gatherTokenStats(cfg *config.Config) map[string]interface{} {
+	totalTokens := 0
+	estimatedCost := 0.0
+	providers := make(map[string]interface{})
+
+	if multiClient, ok := aiClient.(*ai.MultiProviderClient); ok {
+		for name, usage := range multiClient.TokenUsage() {
+			cost := resolveProviderCost(cfg, name)
+			providerCost := (float64(usage.PromptTokens)*cost.Input + float64(usage.CompletionTokens)*cost.Output) / 1e6
+
+			totalTokens += usage.TotalTokens
+			estimatedCost += providerCost
+			providers[name] = map[string]interface{}{
+				"usage":         usage,
+				"estimatedCost": providerCost,
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"totalTokens":   totalTokens,
+		"estimatedCost": estimatedCost,
+		"providers":     providers,
+	}
+}
+
+// resolveProviderCost reads the cost of a provider from models.providers. The
+// first model's cost overrides the provider's, since initializeAI registers
+// each provider with its first model.
+func resolveProviderCost(cfg *config.Config, provider string) providerCost {
+	var cost providerCost
+	if cfg == nil {
+		return cost
+	}
+
+	providerConfig := providerConfigFor(cfg, provider)
+	if providerConfig == nil {
+		return cost
+	}
+	applyCost(&cost, providerConfig)
+
+	if models, ok := providerConfig["models"].([]interface{}); ok && len(models) > 0 {
+		if modelMap, ok := models[0].(map[string]interface{}); ok {
+			applyCost(&cost, modelMap)
+		}
+	}
+	return cost
+}
+
+// applyCost copies cost.input and cost.output from a config map when present
+func applyCost(cost *providerCost, values map[string]interface{}) {
+	costMap, ok := values["cost"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if input, ok := costMap["input"].(float64); ok {
+		cost.Input = input
+	}
+	if output, ok := costMap["output"].(float64); ok {
+		cost.Output = output
+	}
+}
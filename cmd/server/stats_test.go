@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/cron"
+	"goclaw/internal/memory"
+	"goclaw/internal/security"
+	"goclaw/internal/tools/builtin"
+	"goclaw/pkg/ai"
+)
+
+// usageAIClient replies with a fixed token usage
+type usageAIClient struct {
+	usage ai.Usage
+}
+
+func (c usageAIClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "ok"}}},
+		Usage:   c.usage,
+	}, nil
+}
+
+func (c usageAIClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestHandleStatsAggregatesSubsystems(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("first", "")
+	chatMgr.CreateSession("second", "")
+	chatMgr.AddMessage("first", "user", "hello")
+
+	memStore := memory.NewMemoryStore(memory.MemoryConfig{ShortTermMax: 10, WorkingMax: 10})
+	memStore.AddShortTerm("a short-term memory", nil)
+
+	securityManager := security.NewSecurityManager("secret")
+	if _, err := securityManager.GenerateAPIKey("dashboard", nil, time.Hour); err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+
+	cronManager := cron.NewCronManager(nil)
+	if _, err := cronManager.AddTask(&cron.Task{Name: "nightly", Schedule: "0 3 * * *", Enabled: true}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	cronManager.Start()
+	defer cronManager.Stop()
+
+	cfg := config.NewDefaultConfig()
+	cfg.Models = map[string]interface{}{
+		"providers": map[string]interface{}{
+			"zai": map[string]interface{}{
+				"cost": map[string]interface{}{"input": 1.0, "output": 2.0},
+			},
+		},
+	}
+
+	multiClient := ai.NewMultiProviderClient()
+	multiClient.AddProvider("zai", usageAIClient{usage: ai.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}})
+	previous := aiClient
+	aiClient = multiClient
+	defer func() { aiClient = previous }()
+	if _, err := multiClient.ChatCompletion(context.Background(), ai.ChatCompletionRequest{Model: "glm-4"}); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	rec := httptest.NewRecorder()
+	handleStats(statsSources{
+		chatMgr:   chatMgr,
+		memStore:  memStore,
+		security:  securityManager,
+		cron:      cronManager,
+		cfg:       cfg,
+		startedAt: time.Now().Add(-time.Minute),
+	})(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			Sessions map[string]interface{} `json:"sessions"`
+			Memory   memory.MemoryStats     `json:"memory"`
+			Security map[string]interface{} `json:"security"`
+			Cron     map[string]interface{} `json:"cron"`
+			Tokens   struct {
+				TotalTokens   int     `json:"totalTokens"`
+				EstimatedCost float64 `json:"estimatedCost"`
+			} `json:"tokens"`
+			Uptime struct {
+				Seconds int64 `json:"seconds"`
+			} `json:"uptime"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	data := resp.Data
+
+	if data.Sessions["totalSessions"] != float64(2) || data.Sessions["mainSessionID"] != "first" {
+		t.Errorf("sessions = %v, want 2 sessions with main session first", data.Sessions)
+	}
+	if data.Sessions["activeSessions"] != float64(2) {
+		t.Errorf("activeSessions = %v, want 2", data.Sessions["activeSessions"])
+	}
+	if data.Memory.ShortTermCount != 1 {
+		t.Errorf("memory.shortTermCount = %d, want 1", data.Memory.ShortTermCount)
+	}
+	if data.Security["enabled"] != true || data.Security["active_api_keys"] != float64(1) {
+		t.Errorf("security = %v, want enabled with 1 active key", data.Security)
+	}
+	if data.Cron["taskCount"] != float64(1) {
+		t.Errorf("cron.taskCount = %v, want 1", data.Cron["taskCount"])
+	}
+	if _, ok := data.Cron["nextRun"].(string); !ok {
+		t.Errorf("cron.nextRun missing: %v", data.Cron)
+	}
+	if data.Tokens.TotalTokens != 1500 {
+		t.Errorf("tokens.totalTokens = %d, want 1500", data.Tokens.TotalTokens)
+	}
+	// 1000 input tokens at 1.0 and 500 output tokens at 2.0 per million
+	if want := 0.002; data.Tokens.EstimatedCost < want-1e-9 || data.Tokens.EstimatedCost > want+1e-9 {
+		t.Errorf("tokens.estimatedCost = %v, want %v", data.Tokens.EstimatedCost, want)
+	}
+	if data.Uptime.Seconds < 60 {
+		t.Errorf("uptime.seconds = %d, want at least 60", data.Uptime.Seconds)
+	}
+}
+
+func TestHandleStatsWithoutOptionalSubsystems(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	rec := httptest.NewRecorder()
+	handleStats(statsSources{
+		chatMgr:   chat.NewChatManager(100),
+		memStore:  memory.NewMemoryStore(memory.MemoryConfig{ShortTermMax: 10, WorkingMax: 10}),
+		cfg:       config.NewDefaultConfig(),
+		startedAt: time.Now(),
+	})(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data map[string]map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data["security"]["enabled"] != false {
+		t.Errorf("security = %v, want disabled", resp.Data["security"])
+	}
+	if resp.Data["cron"]["taskCount"] != float64(0) {
+		t.Errorf("cron = %v, want no tasks", resp.Data["cron"])
+	}
+}
+
+func TestStatusToolReportsModelWithoutSecrets(t *testing.T) {
+	multiClient := ai.NewMultiProviderClient()
+	multiClient.AddProvider("minimax", &fakeAIClient{})
+	multiClient.AddProvider("zhipu", &fakeAIClient{})
+	useFakeAI(t, multiClient)
+
+	cfg := config.NewDefaultConfig()
+	cfg.Agent.Model = "minimax/MiniMax-M2.1"
+	cfg.Embedding.ApiKey = "embedding-secret-key"
+	cfg.Models = map[string]interface{}{
+		"providers": map[string]interface{}{
+			"minimax": map[string]interface{}{
+				"apiKey":  "sk-minimax-secret",
+				"baseUrl": "https://api.minimax.example",
+				"models":  []interface{}{map[string]interface{}{"id": "MiniMax-M2.1"}},
+			},
+		},
+	}
+
+	tool := builtin.StatusTool(agentStatus(statsSources{
+		chatMgr:   chat.NewChatManager(100),
+		memStore:  memory.NewMemoryStore(memory.MemoryConfig{ShortTermMax: 10, WorkingMax: 10}),
+		cfg:       cfg,
+		startedAt: time.Now(),
+	}))
+	result, err := tool.Execute(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	output, _ := json.Marshal(result)
+
+	for _, want := range []string{`"minimax/MiniMax-M2.1"`, `"providers":["minimax","zhipu"]`, `"minimax":["MiniMax-M2.1"]`, `"uptime"`} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("status %s is missing %s", output, want)
+		}
+	}
+	for _, secret := range []string{"sk-minimax-secret", "embedding-secret-key", "api.minimax.example"} {
+		if strings.Contains(string(output), secret) {
+			t.Errorf("status %s leaks %q", output, secret)
+		}
+	}
+}
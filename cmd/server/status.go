@@ -0,0 +1,79 @@
+// Package main provides the snapshot behind the status tool
+package main
+
+import (
+	"sort"
+
+	"goclaw/internal/config"
+	"goclaw/internal/tools/builtin"
+	"goclaw/pkg/ai"
+)
+
+// agentStatus snapshots what the status tool reports: the /api/stats
+// aggregate plus a config summary and the providers in use
+func agentStatus(sources statsSources) builtin.SystemInfoFunc {
+	return func() map[string]interface{} {
+		status := gatherStats(sources)
+		status["model"] = primaryChatModel
+		status["provider"] = ai.ProviderForModel(primaryChatModel)
+		status["providers"] = activeProviders()
+		status["config"] = configSummary(sources.cfg)
+		status["version"] = Version
+		return status
+	}
+}
+
+// activeProviders lists the providers the AI client can route to
+func activeProviders() []string {
+	names := []string{}
+	if multiClient, ok := aiClient.(*ai.MultiProviderClient); ok {
+		for name := range multiClient.Providers {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configSummary picks the settings worth explaining from the effective
+// configuration. Provider entries keep only their model IDs, leaving
+// credentials and endpoints out.
+func configSummary(cfg *config.Config) map[string]interface{} {
+	providers := make(map[string]interface{})
+	if configured, ok := cfg.Models["providers"].(map[string]interface{}); ok {
+		for name := range configured {
+			providers[name] = providerModels(cfg, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"model":      cfg.Agent.Model,
+		"modelTiers": cfg.Agent.ModelTiers,
+		"workspace":  cfg.Agent.Workspace,
+		"providers":  providers,
+		"embedding": map[string]interface{}{
+			"api":   cfg.Embedding.API,
+			"model": cfg.Embedding.Model,
+		},
+		"heartbeat": map[string]interface{}{
+			"enabled":  cfg.Heartbeat.Enabled,
+			"interval": cfg.Heartbeat.Interval,
+			"model":    cfg.Heartbeat.Model,
+			"provider": cfg.Heartbeat.Provider,
+		},
+	}
+}
+
+// providerModels returns the IDs of a configured provider's models
+func providerModels(cfg *config.Config, provider string) []string {
+	ids := []string{}
+	models, _ := providerConfigFor(cfg, provider)["models"].([]interface{})
+	for _, model := range models {
+		if modelMap, ok := model.(map[string]interface{}); ok {
+			if id, ok := modelMap["id"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,375 @@
+// Package main provides streaming chat responses that can be resumed after an interruption
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/internal/redact"
+	"goclaw/pkg/ai"
+)
+
+// DefaultStreamTTL is how long the partial answer of an interrupted stream is
+// kept for /api/chat/resume
+const DefaultStreamTTL = 5 * time.Minute
+
+// continueInstruction asks the model to pick up an interrupted answer
+const continueInstruction = "Your previous answer was cut off. Continue it exactly where it stopped, without repeating any of it."
+
+var (
+	errStreamNotFound = errors.New("stream not found or expired")
+	errStreamBusy     = errors.New("stream is still running")
+)
+
+// streamBuffer is the server-side state of one streamed answer
+type streamBuffer struct {
+	ID        string
+	SessionID string
+	Messages  []ai.Message // The request that started the stream
+	Params    ai.GenerationParams
+	Partial   string // Text received so far, across resumes
+
+	active    bool
+	updatedAt time.Time
+}
+
+// streamStore keeps stream buffers until they finish or expire
+type streamStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	buffers map[string]*streamBuffer
+	now     func() time.Time
+}
+
+// newStreamStore creates a store whose idle buffers expire after ttl
+func newStreamStore(ttl time.Duration) *streamStore {
+	if ttl <= 0 {
+		ttl = DefaultStreamTTL
+	}
+	return &streamStore{
+		ttl:     ttl,
+		buffers: make(map[string]*streamBuffer),
+		now:     time.Now,
+	}
+}
+
+// create starts an active buffer for a new stream and returns a copy of it
+func (s *streamStore) create(sessionID string, messages []ai.Message, params ai.GenerationParams) (streamBuffer, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return streamBuffer{}, fmt.Errorf("failed to generate stream id: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+
+	buffer := &streamBuffer{
+		ID:        "stream_" + hex.EncodeToString(id),
+		SessionID: sessionID,
+		Messages:  messages,
+		Params:    params,
+		active:    true,
+		updatedAt: s.now(),
+	}
+	s.buffers[buffer.ID] = buffer
+	return *buffer, nil
+}
+
+// acquire marks an interrupted stream active again so it can be resumed
+func (s *streamStore) acquire(id string) (streamBuffer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+
+	buffer, ok := s.buffers[id]
+	if !ok {
+		return streamBuffer{}, errStreamNotFound
+	}
+	if buffer.active {
+		return streamBuffer{}, errStreamBusy
+	}
+	buffer.active = true
+	buffer.updatedAt = s.now()
+	return *buffer, nil
+}
+
+// appendDelta adds streamed text to a buffer
+func (s *streamStore) appendDelta(id, delta string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if buffer, ok := s.buffers[id]; ok {
+		buffer.Partial += delta
+		buffer.updatedAt = s.now()
+	}
+}
+
+// release ends a stream attempt. A finished stream is dropped; an interrupted
+// one is kept for resuming until the TTL passes.
+func (s *streamStore) release(id string, finished bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buffer, ok := s.buffers[id]
+	if !ok {
+		return
+	}
+	if finished {
+		delete(s.buffers, id)
+		return
+	}
+	buffer.active = false
+	buffer.updatedAt = s.now()
+}
+
+// sweepLocked drops idle buffers older than the TTL; caller must hold the lock
+func (s *streamStore) sweepLocked() {
+	cutoff := s.now().Add(-s.ttl)
+	for id, buffer := range s.buffers {
+		if !buffer.active && buffer.updatedAt.Before(cutoff) {
+			delete(s.buffers, id)
+		}
+	}
+}
+
+// handleChatStream answers a chat message as server-sent events. If the
+// stream is interrupted, the partial answer is kept and the error event
+// carries the stream ID to pass to /api/chat/resume.
+func handleChatStream(chatMgr *chat.ChatManager, cfg *config.Config, streams *streamStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Message      string  `json:"message"`
+			SessionID    string  `json:"sessionId,omitempty"`
+			Model        string  `json:"model,omitempty"`        // Answer with this model instead of the routed one
+			PromptPrefix *string `json:"promptPrefix,omitempty"` // Text injected before the message in the prompt only
+			PromptSuffix *string `json:"promptSuffix,omitempty"` // Text injected after the message in the prompt only
+			Thinking     *string `json:"thinking,omitempty"`     // Reasoning level for this message, overriding the session's
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Message = strings.TrimSpace(req.Message)
+		if req.Message == "" {
+			http.Error(w, "Message must not be empty", http.StatusBadRequest)
+			return
+		}
+		if req.Thinking != nil {
+			if err := ai.ValidateThinkingLevel(*req.Thinking); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		streamer := aiClient
+		if streamer == nil {
+			http.Error(w, "No AI client configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		sessionID := req.SessionID
+		if sessionID == "" {
+			sessionID = fmt.Sprintf("api_session_%d", time.Now().Unix())
+		}
+
+		release := chatMgr.LockSession(sessionID)
+		defer release()
+
+		if _, exists := chatMgr.GetSession(sessionID); !exists {
+			chatMgr.CreateSession(sessionID, cfg.Agent.Model)
+		}
+		if err := chatMgr.AddMessage(sessionID, "user", req.Message); err != nil {
+			fmt.Printf("Error adding message to session %s: %v\n", sessionID, err)
+		}
+
+		history, _ := chatMgr.GetMessages(sessionID)
+		params, _ := chatMgr.GetGenerationParams(sessionID)
+		if req.Thinking != nil {
+			params.Thinking = *req.Thinking
+		}
+		route := routeModel(cfg.Agent.ModelTiers, req.Message, req.Model, params)
+		params.Model = route.Model
+		budget := resolveContextBudget(cfg, route.Model).forParams(params)
+		prompt := buildPrompt(req.Message, "", history, budget.Budget, resolvePromptAffixes(cfg, req.PromptPrefix, req.PromptSuffix))
+		if err := budget.checkPrompt(prompt); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		buffer, err := streams.create(sessionID, []ai.Message{{Role: "user", Content: prompt}}, params)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		streamReply(w, r, streamer, chatMgr, streams, buffer, buffer.Messages, aiTimeout(cfg, ai.UseInteractive), streamKeepalive(cfg), cfg.Agent.StreamFallbacks)
+	}
+}
+
+// handleChatResume continues an interrupted stream: the request is sent again
+// with the partial answer as assistant context and an instruction to
+// continue, and the continuation is streamed and stitched onto the partial
+func handleChatResume(chatMgr *chat.ChatManager, cfg *config.Config, streams *streamStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			StreamID string `json:"streamId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.StreamID == "" {
+			http.Error(w, "streamId is required", http.StatusBadRequest)
+			return
+		}
+
+		streamer := aiClient
+		if streamer == nil {
+			http.Error(w, "No AI client configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		buffer, err := streams.acquire(req.StreamID)
+		if errors.Is(err, errStreamNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		release := chatMgr.LockSession(buffer.SessionID)
+		defer release()
+
+		streamReply(w, r, streamer, chatMgr, streams, buffer, continuation(buffer.Messages, buffer.Partial), aiTimeout(cfg, ai.UseInteractive), streamKeepalive(cfg), cfg.Agent.StreamFallbacks)
+	}
+}
+
+// continuation is the request that continues a partial answer: the original
+// messages, the partial as assistant context and an instruction to continue
+func continuation(messages []ai.Message, partial string) []ai.Message {
+	continued := make([]ai.Message, 0, len(messages)+2)
+	continued = append(continued, messages...)
+	return append(continued,
+		ai.Message{Role: "assistant", Content: partial},
+		ai.Message{Role: "user", Content: continueInstruction},
+	)
+}
+
+// streamReply streams a completion to the client as server-sent events,
+// buffering the text so an interruption can be resumed. A finished answer,
+// including any partial from earlier attempts, is added to the session.
+// Until the first token of each attempt arrives, keepalive comments are sent
+// every keepaliveEvery.
+// When a provider fails mid-answer, the answer is continued up to fallbacks
+// times without that provider; an error event with "retrying" set tells the
+// client the partial is kept and more text follows.
+func streamReply(w http.ResponseWriter, r *http.Request, streamer ai.StreamClient, chatMgr *chat.ChatManager, streams *streamStore, buffer streamBuffer, messages []ai.Message, timeout, keepaliveEvery time.Duration, fallbacks int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeEvent(w, "start", map[string]interface{}{
+		"streamId":  buffer.ID,
+		"sessionId": buffer.SessionID,
+		"model":     buffer.Params.Model,
+	})
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	req := ai.ChatCompletionRequest{Model: primaryChatModel, Messages: messages}
+	buffer.Params.Apply(&req)
+
+	waiting := startKeepalive(w, keepaliveEvery)
+	onDelta := func(delta string) error {
+		waiting.Stop()
+		streams.appendDelta(buffer.ID, delta)
+		return writeEvent(w, "delta", map[string]interface{}{"delta": delta})
+	}
+	text, err := streamAttempt(ctx, streamer, req, onDelta)
+	for attempt := 0; err != nil && attempt < fallbacks && ctx.Err() == nil; attempt++ {
+		var streamErr *ai.StreamError
+		if !errors.As(err, &streamErr) {
+			break
+		}
+		waiting.Stop() // The provider may have failed before its first token
+		writeEvent(w, "error", map[string]interface{}{
+			"streamId": buffer.ID,
+			"error":    redact.String(err.Error()),
+			"partial":  buffer.Partial + text,
+			"retrying": true,
+		})
+
+		ctx = ai.WithoutProviders(ctx, streamErr.Provider)
+		req.Messages = continuation(buffer.Messages, buffer.Partial+text)
+		waiting = startKeepalive(w, keepaliveEvery)
+		var more string
+		more, err = streamAttempt(ctx, streamer, req, onDelta)
+		text += more
+	}
+	waiting.Stop()
+	response := buffer.Partial + text
+	err = ai.TimeoutErr(ctx, err, ai.UseInteractive, timeout)
+
+	if err != nil {
+		// Resuming can't help a client that doesn't stream
+		resumable := !errors.Is(err, ai.ErrStreamingUnsupported)
+		streams.release(buffer.ID, !resumable)
+		writeEvent(w, "error", map[string]interface{}{
+			"streamId":  buffer.ID,
+			"error":     redact.String(err.Error()),
+			"partial":   response,
+			"resumable": resumable,
+		})
+		return
+	}
+
+	streams.release(buffer.ID, true)
+	chatMgr.AddMessage(buffer.SessionID, "assistant", response)
+	writeEvent(w, "done", map[string]interface{}{
+		"streamId":  buffer.ID,
+		"sessionId": buffer.SessionID,
+		"response":  response,
+	})
+}
+
+// streamAttempt streams one completion, returning the text received before
+// it finished or failed
+func streamAttempt(ctx context.Context, streamer ai.StreamClient, req ai.ChatCompletionRequest, onDelta ai.StreamHandler) (string, error) {
+	chunks, err := streamer.ChatCompletionStream(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return ai.CollectStream(chunks, onDelta)
+}
+
+// writeEvent writes one server-sent event and flushes it to the client
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/config"
+	"goclaw/pkg/ai"
+)
+
+// droppingStreamClient drops its first stream after "Hello, " and answers the
+// resume with the rest of the reply
+type droppingStreamClient struct {
+	requests [][]ai.Message
+}
+
+func (c *droppingStreamClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return nil, fmt.Errorf("not used")
+}
+
+func (c *droppingStreamClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	c.requests = append(c.requests, req.Messages)
+	if len(c.requests) == 1 {
+		return streamOf(
+			ai.StreamChunk{Delta: "Hello, "},
+			ai.StreamChunk{Err: fmt.Errorf("%w: connection reset", ai.ErrStreamInterrupted)},
+		), nil
+	}
+	return streamOf(ai.StreamChunk{Delta: "world"}, ai.StreamChunk{Delta: "!"}, ai.StreamChunk{Done: true}), nil
+}
+
+// streamOf returns a closed stream holding the given chunks
+func streamOf(chunks ...ai.StreamChunk) <-chan ai.StreamChunk {
+	stream := make(chan ai.StreamChunk, len(chunks))
+	for _, chunk := range chunks {
+		stream <- chunk
+	}
+	close(stream)
+	return stream
+}
+
+// sseEvent is one parsed server-sent event
+type sseEvent struct {
+	Name string
+	Data map[string]interface{}
+}
+
+func postStream(t *testing.T, handler http.HandlerFunc, path string, body map[string]interface{}) []sseEvent {
+	t.Helper()
+
+	payload, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s status = %d, body = %s", path, rec.Code, rec.Body.String())
+	}
+
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.Data)
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+func TestChatStreamResumeAfterDisconnect(t *testing.T) {
+	client := &droppingStreamClient{}
+	useFakeAI(t, client)
+
+	chatMgr := chat.NewChatManager(100)
+	streams := newStreamStore(time.Minute)
+	cfg := config.NewDefaultConfig()
+
+	events := postStream(t, handleChatStream(chatMgr, cfg, streams), "/api/chat/stream", map[string]interface{}{
+		"message":   "say hello",
+		"sessionId": "stream-session",
+	})
+	last := events[len(events)-1]
+	if last.Name != "error" || last.Data["resumable"] != true || last.Data["partial"] != "Hello, " {
+		t.Fatalf("last event = %+v, want a resumable error with the partial answer", last)
+	}
+	streamID, _ := last.Data["streamId"].(string)
+
+	messages, _ := chatMgr.GetMessages("stream-session")
+	if len(messages) != 1 {
+		t.Errorf("session has %d messages, want only the user message before the resume", len(messages))
+	}
+
+	events = postStream(t, handleChatResume(chatMgr, cfg, streams), "/api/chat/resume", map[string]interface{}{
+		"streamId": streamID,
+	})
+	last = events[len(events)-1]
+	if last.Name != "done" || last.Data["response"] != "Hello, world!" {
+		t.Fatalf("last event = %+v, want done with the stitched answer", last)
+	}
+
+	// The resume carried the partial answer and the continue instruction
+	resumed := client.requests[1]
+	if n := len(resumed); n < 2 || resumed[n-2].Role != "assistant" || resumed[n-2].Content != "Hello, " || resumed[n-1].Content != continueInstruction {
+		t.Errorf("resume request = %+v, want the partial and a continue instruction", resumed)
+	}
+
+	messages, _ = chatMgr.GetMessages("stream-session")
+	if len(messages) != 2 || messages[1].Content != "Hello, world!" {
+		t.Errorf("session messages = %+v, want the full answer appended", messages)
+	}
+
+	// A finished stream can't be resumed again
+	rec := httptest.NewRecorder()
+	payload, _ := json.Marshal(map[string]interface{}{"streamId": streamID})
+	handleChatResume(chatMgr, cfg, streams)(rec, httptest.NewRequest(http.MethodPost, "/api/chat/resume", bytes.NewReader(payload)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("second resume status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestStreamStoreExpiresInterruptedStreams(t *testing.T) {
+	now := time.Now()
+	streams := newStreamStore(time.Minute)
+	streams.now = func() time.Time { return now }
+
+	buffer, err := streams.create("s1", nil, ai.GenerationParams{})
+	if err != nil {
+		t.Fatalf("create() error = %v", err)
+	}
+	if _, err := streams.acquire(buffer.ID); err != errStreamBusy {
+		t.Errorf("acquire() of a running stream error = %v, want errStreamBusy", err)
+	}
+	streams.release(buffer.ID, false)
+
+	now = now.Add(2 * time.Minute)
+	if _, err := streams.acquire(buffer.ID); err != errStreamNotFound {
+		t.Errorf("acquire() after the TTL error = %v, want errStreamNotFound", err)
+	}
+}
+
+// slowStreamClient waits before streaming its only token
+type slowStreamClient struct {
+	delay time.Duration
+}
+
+func (c slowStreamClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return nil, fmt.Errorf("not used")
+}
+
+func (c slowStreamClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	time.Sleep(c.delay)
+	return streamOf(ai.StreamChunk{Delta: "done"}, ai.StreamChunk{Done: true}), nil
+}
+
+func TestChatStreamSendsKeepalivesWhileWaiting(t *testing.T) {
+	useFakeAI(t, slowStreamClient{delay: 100 * time.Millisecond})
+
+	cfg := &config.Config{Agent: config.AgentConfig{StreamKeepalive: "10ms"}}
+	handler := handleChatStream(chat.NewChatManager(100), cfg, newStreamStore(time.Minute))
+
+	payload, _ := json.Marshal(map[string]interface{}{"message": "Hi", "sessionId": "keepalive"})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat/stream", bytes.NewReader(payload)))
+
+	body := rec.Body.String()
+	first := strings.Index(body, "event: delta")
+	if first < 0 {
+		t.Fatalf("body = %q, want a delta event", body)
+	}
+	if n := strings.Count(body[:first], keepaliveComment); n < 2 {
+		t.Errorf("%d keepalives before the first token, want several; body = %q", n, body)
+	}
+	if strings.Contains(body[first:], keepaliveComment) {
+		t.Errorf("keepalive sent after the first token; body = %q", body)
+	}
+}
+
+// providerStreamClient streams a fixed reply, optionally breaking off after
+// its first chunks, and records the requests it got
+type providerStreamClient struct {
+	chunks   []ai.StreamChunk
+	delay    time.Duration // Wait before the first chunk
+	requests [][]ai.Message
+}
+
+func (c *providerStreamClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return nil, fmt.Errorf("not used")
+}
+
+func (c *providerStreamClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	c.requests = append(c.requests, req.Messages)
+	time.Sleep(c.delay)
+	return streamOf(c.chunks...), nil
+}
+
+func TestChatStreamContinuesWithFallbackProvider(t *testing.T) {
+	broken := &providerStreamClient{chunks: []ai.StreamChunk{
+		{Delta: "Hello"},
+		{Delta: ", "},
+		{Err: fmt.Errorf("%w: connection reset", ai.ErrStreamInterrupted)},
+	}}
+	healthy := &providerStreamClient{chunks: []ai.StreamChunk{{Delta: "world!"}, {Done: true}}}
+	multiClient := ai.NewMultiProviderClient()
+	multiClient.AddProvider("minimax", broken)
+	multiClient.AddProvider("zhipu", healthy)
+
+	newClient := func() *ai.MultiProviderClient {
+		broken.requests, healthy.requests = nil, nil
+		return multiClient
+	}
+
+	t.Run("fallback continues the answer", func(t *testing.T) {
+		useFakeAI(t, newClient())
+		chatMgr := chat.NewChatManager(100)
+		cfg := config.NewDefaultConfig()
+		cfg.Agent.StreamFallbacks = 1
+
+		events := postStream(t, handleChatStream(chatMgr, cfg, newStreamStore(time.Minute)), "/api/chat/stream", map[string]interface{}{
+			"message":   "say hello",
+			"sessionId": "fallback-session",
+		})
+
+		var retrying *sseEvent
+		for i := range events {
+			if events[i].Name == "error" {
+				retrying = &events[i]
+			}
+		}
+		if retrying == nil || retrying.Data["retrying"] != true || retrying.Data["partial"] != "Hello, " {
+			t.Fatalf("events = %+v, want a retrying error with the partial answer", events)
+		}
+		if !strings.Contains(retrying.Data["error"].(string), "minimax") {
+			t.Errorf("error = %v, want it to name the failed provider", retrying.Data["error"])
+		}
+		last := events[len(events)-1]
+		if last.Name != "done" || last.Data["response"] != "Hello, world!" {
+			t.Fatalf("last event = %+v, want done with the stitched answer", last)
+		}
+
+		if len(healthy.requests) != 1 {
+			t.Fatalf("fallback got %d requests, want 1", len(healthy.requests))
+		}
+		continued := healthy.requests[0]
+		if n := len(continued); n < 2 || continued[n-2].Role != "assistant" || continued[n-2].Content != "Hello, " || continued[n-1].Content != continueInstruction {
+			t.Errorf("fallback request = %+v, want the partial and a continue instruction", continued)
+		}
+
+		messages, _ := chatMgr.GetMessages("fallback-session")
+		if len(messages) != 2 || messages[1].Content != "Hello, world!" {
+			t.Errorf("session messages = %+v, want the full answer appended", messages)
+		}
+	})
+
+	t.Run("without fallbacks the error is resumable", func(t *testing.T) {
+		useFakeAI(t, newClient())
+		chatMgr := chat.NewChatManager(100)
+		cfg := config.NewDefaultConfig()
+
+		events := postStream(t, handleChatStream(chatMgr, cfg, newStreamStore(time.Minute)), "/api/chat/stream", map[string]interface{}{
+			"message":   "say hello",
+			"sessionId": "no-fallback-session",
+		})
+		last := events[len(events)-1]
+		if last.Name != "error" || last.Data["resumable"] != true || last.Data["partial"] != "Hello, " {
+			t.Fatalf("last event = %+v, want a resumable error with the partial answer", last)
+		}
+		if len(healthy.requests) != 0 {
+			t.Errorf("fallback got %d requests, want none", len(healthy.requests))
+		}
+	})
+}
+
+func TestChatStreamFallbackBeforeFirstToken(t *testing.T) {
+	broken := &providerStreamClient{
+		chunks: []ai.StreamChunk{{Err: fmt.Errorf("%w: connection reset", ai.ErrStreamInterrupted)}},
+		delay:  20 * time.Millisecond,
+	}
+	healthy := &providerStreamClient{chunks: []ai.StreamChunk{{Delta: "Hello!"}, {Done: true}}, delay: 20 * time.Millisecond}
+	multiClient := ai.NewMultiProviderClient()
+	multiClient.AddProvider("minimax", broken)
+	multiClient.AddProvider("zhipu", healthy)
+	useFakeAI(t, multiClient)
+
+	cfg := config.NewDefaultConfig()
+	cfg.Agent.StreamFallbacks = 1
+	cfg.Agent.StreamKeepalive = "1ms"
+	handler := handleChatStream(chat.NewChatManager(100), cfg, newStreamStore(time.Minute))
+
+	// Keepalives of the failed attempt must stop before the error event is
+	// written; run with -race to catch them writing concurrently
+	payload, _ := json.Marshal(map[string]interface{}{"message": "Hi", "sessionId": "early-failure"})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/chat/stream", bytes.NewReader(payload)))
+
+	body := rec.Body.String()
+	retrying := strings.Index(body, "event: error")
+	first := strings.Index(body, "event: delta")
+	if retrying < 0 || first < retrying {
+		t.Fatalf("body = %q, want a retrying error before the fallback's delta", body)
+	}
+	if !strings.Contains(body[:retrying], keepaliveComment) {
+		t.Errorf("no keepalive while the failed provider was waited on; body = %q", body)
+	}
+	if !strings.Contains(body[retrying:first], keepaliveComment) {
+		t.Errorf("no keepalive while the fallback was waited on; body = %q", body)
+	}
+	if strings.Contains(body[first:], keepaliveComment) {
+		t.Errorf("keepalive sent after the first token; body = %q", body)
+	}
+	if !strings.Contains(body[first:], `"response":"Hello!"`) {
+		t.Errorf("body = %q, want done with the fallback's answer", body)
+	}
+}
+
+func TestChatStreamWithNonStreamingClient(t *testing.T) {
+	useFakeAI(t, &fakeAIClient{reply: "not streamed"})
+
+	events := postStream(t, handleChatStream(chat.NewChatManager(100), config.NewDefaultConfig(), newStreamStore(time.Minute)), "/api/chat/stream", map[string]interface{}{
+		"message":   "Hi",
+		"sessionId": "no-streaming",
+	})
+	last := events[len(events)-1]
+	if last.Name != "error" || last.Data["resumable"] != false {
+		t.Fatalf("last event = %+v, want an error that can't be resumed", last)
+	}
+}
@@ -0,0 +1,25 @@
+// Package main provides the runtime snapshot behind the system_info tool
+package main
+
+import (
+	"goclaw/internal/config"
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+	"goclaw/internal/tools/builtin"
+	"goclaw/pkg/ai"
+)
+
+// systemInfo snapshots the runtime facts the agent may ask about. Values are
+// read on each call, since the model and tools can change after startup.
+func systemInfo(cfg *config.Config, registry *tools.Registry, memStore *memory.MemoryStore) builtin.SystemInfoFunc {
+	return func() map[string]interface{} {
+		return map[string]interface{}{
+			"provider":  ai.ProviderForModel(primaryChatModel),
+			"model":     primaryChatModel,
+			"workspace": cfg.Agent.Workspace,
+			"toolCount": registry.Count(),
+			"memory":    memStore.Stats(),
+			"version":   Version,
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package main provides the development task list API for Goclaw
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"goclaw/internal/tasklist"
+)
+
+// defaultTasksFile is the task list shown on the dev status page
+func defaultTasksFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".openclaw", "workspace", "goclaw_tasks.json")
+}
+
+// handleTasks serves /api/tasks: GET lists the tasks with their progress,
+// POST adds a task and PATCH updates one by ID
+func handleTasks(list *tasklist.List) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			task tasklist.Task
+			err  error
+		)
+		switch r.Method {
+		case http.MethodGet:
+			tasks, err := list.ListTasks()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			completed := 0
+			for _, task := range tasks {
+				if task.Completed {
+					completed++
+				}
+			}
+			if tasks == nil {
+				tasks = []tasklist.Task{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(APIResponse{
+				Status: "ok",
+				Data: map[string]interface{}{
+					"tasks":     tasks,
+					"completed": completed,
+					"total":     len(tasks),
+				},
+			})
+			return
+
+		case http.MethodPost:
+			var req struct {
+				Title string `json:"title"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			task, err = list.AddTask(req.Title)
+
+		case http.MethodPatch:
+			var req struct {
+				ID        string  `json:"id"`
+				Title     *string `json:"title,omitempty"`
+				Completed *bool   `json:"completed,omitempty"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			task, err = list.UpdateTask(req.ID, tasklist.Update{Title: req.Title, Completed: req.Completed})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch {
+		case errors.Is(err, tasklist.ErrEmptyTitle):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, tasklist.ErrTaskNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: task})
+	}
+}
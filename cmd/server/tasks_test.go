@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goclaw/internal/tasklist"
+)
+
+func TestTasksAPI(t *testing.T) {
+	list := tasklist.New(filepath.Join(t.TempDir(), "goclaw_tasks.json"))
+	handler := handleTasks(list)
+
+	call := func(method string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, "/api/tasks", bytes.NewReader(payload)))
+		return rec
+	}
+
+	rec := call(http.MethodPost, map[string]string{"title": "Add the task API"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data tasklist.Task `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	call(http.MethodPost, map[string]string{"title": "Wire it to the dev status"})
+
+	if rec := call(http.MethodPatch, map[string]interface{}{"id": created.Data.ID, "completed": true}); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = call(http.MethodGet, nil)
+	var listed struct {
+		Data struct {
+			Tasks     []tasklist.Task `json:"tasks"`
+			Completed int             `json:"completed"`
+			Total     int             `json:"total"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &listed)
+	if listed.Data.Total != 2 || listed.Data.Completed != 1 || !listed.Data.Tasks[0].Completed {
+		t.Errorf("GET data = %+v, want the first of two tasks completed", listed.Data)
+	}
+
+	// The dev status progress comes from the same list
+	if status := getProjectStatus(list); !strings.Contains(status, "(1/2") {
+		t.Errorf("project status = %q, want 1 of 2 tasks completed", status)
+	}
+
+	if rec := call(http.MethodPatch, map[string]interface{}{"id": "missing", "completed": true}); rec.Code != http.StatusNotFound {
+		t.Errorf("PATCH of an unknown task status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := call(http.MethodPost, map[string]string{"title": ""}); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST without a title status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,47 @@
+// Package main provides per-use-case AI call timeouts for Goclaw
+package main
+
+import (
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/pkg/ai"
+)
+
+// validateTimeouts checks every configured agent.timeouts value, so a typo
+// fails at startup instead of silently using the default
+func validateTimeouts(cfg *config.Config) error {
+	timeouts := cfg.Agent.Timeouts
+	for useCase, configured := range map[string]string{
+		ai.UseInteractive: timeouts.Interactive,
+		ai.UseBatch:       timeouts.Batch,
+		ai.UseHeartbeat:   timeouts.Heartbeat,
+		ai.UseCron:        timeouts.Cron,
+	} {
+		if _, err := ai.ResolveTimeout(useCase, configured); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aiTimeout is the AI call timeout of a use case from agent.timeouts. An
+// invalid value, which validateTimeouts rejects at startup, uses the default.
+func aiTimeout(cfg *config.Config, useCase string) time.Duration {
+	var configured string
+	switch useCase {
+	case ai.UseInteractive:
+		configured = cfg.Agent.Timeouts.Interactive
+	case ai.UseBatch:
+		configured = cfg.Agent.Timeouts.Batch
+	case ai.UseHeartbeat:
+		configured = cfg.Agent.Timeouts.Heartbeat
+	case ai.UseCron:
+		configured = cfg.Agent.Timeouts.Cron
+	}
+	timeout, err := ai.ResolveTimeout(useCase, configured)
+	if err != nil {
+		return ai.DefaultTimeouts[useCase]
+	}
+	return timeout
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goclaw/internal/config"
+	"goclaw/internal/tools/builtin"
+)
+
+func newScopedToolServer(t *testing.T, scopes map[string]string) http.Handler {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Gateway.Auth.Mode = "apikey"
+	cfg.Gateway.Auth.APIKeys = []config.APIKeyConfig{
+		{Key: "goclaw_readonly", Name: "reader", Scopes: []string{"tools:read"}},
+		{Key: "goclaw_admin", Name: "admin", Scopes: []string{"tools:*"}},
+	}
+
+	return toolExecuteHandler(builtin.NewManager().GetRegistry(), newSecurityManager(cfg), scopes, 0)
+}
+
+func executeTool(t *testing.T, handler http.Handler, apiKey, tool string, params map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string]interface{}{"tool": tool, "params": params})
+	req := httptest.NewRequest(http.MethodPost, "/api/tools/execute", bytes.NewReader(payload))
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestToolExecuteScopes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	handler := newScopedToolServer(t, nil)
+
+	if rec := executeTool(t, handler, "goclaw_readonly", "read", map[string]interface{}{"path": path}); rec.Code != http.StatusOK {
+		t.Errorf("read with read-only key: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := executeTool(t, handler, "goclaw_readonly", "exec", map[string]interface{}{"command": "echo hi"}); rec.Code != http.StatusForbidden {
+		t.Errorf("exec with read-only key: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := executeTool(t, handler, "goclaw_admin", "exec", map[string]interface{}{"command": "echo hi"}); rec.Code != http.StatusOK {
+		t.Errorf("exec with tools:* key: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := executeTool(t, handler, "", "read", map[string]interface{}{"path": path}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("read without key: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestToolExecuteScopeOverride(t *testing.T) {
+	handler := newScopedToolServer(t, map[string]string{"read": "files:read"})
+
+	rec := executeTool(t, handler, "goclaw_readonly", "read", map[string]interface{}{"path": "/tmp/x"})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d with overridden scope", rec.Code, http.StatusForbidden)
+	}
+}
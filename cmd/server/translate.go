@@ -0,0 +1,43 @@
+// Package main provides memory translation with the chat model for Goclaw
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"goclaw/pkg/ai"
+)
+
+// translateTimeout bounds one translation call
+const translateTimeout = 30 * time.Second
+
+// modelTranslator translates memories and queries with the primary chat
+// model. It reads the AI client when called, since memory is set up first.
+type modelTranslator struct{}
+
+// Translate asks the model for a translation of text into language
+func (modelTranslator) Translate(ctx context.Context, text, language string) (string, error) {
+	if aiClient == nil {
+		return "", fmt.Errorf("no AI client configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, translateTimeout)
+	defer cancel()
+
+	resp, err := aiClient.ChatCompletion(ctx, ai.ChatCompletionRequest{
+		Model: primaryChatModel,
+		Messages: []ai.Message{
+			{Role: "system", Content: fmt.Sprintf("Translate the user's text into the language with ISO 639-1 code %q. Reply with the translation only.", language)},
+			{Role: "user", Content: text},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("translation failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("translation returned no choices")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,122 @@
+// Package main provides startup config validation for Goclaw
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"goclaw/internal/config"
+	"goclaw/pkg/ai"
+)
+
+// validateConfig checks the config values the server would otherwise reject
+// while starting up, returning every problem rather than only the first
+func validateConfig(cfg *config.Config) []error {
+	var errs []error
+	if err := validateTimeouts(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("invalid agent.timeouts: %w", err))
+	}
+	if _, err := parseStreamKeepalive(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Tools.MaxDepth < 0 {
+		errs = append(errs, fmt.Errorf("invalid tools.maxDepth %d: must not be negative", cfg.Tools.MaxDepth))
+	}
+	switch cfg.Agent.ToolCalling {
+	case "", ai.ToolCallFormatText, ai.ToolCallFormatOpenAI, ai.ToolCallFormatAnthropic:
+	default:
+		errs = append(errs, fmt.Errorf("invalid agent.toolCalling %q: must be \"text\", \"openai\" or \"anthropic\"", cfg.Agent.ToolCalling))
+	}
+	if cfg.Agent.StreamFallbacks < 0 {
+		errs = append(errs, fmt.Errorf("invalid agent.streamFallbacks %d: must not be negative", cfg.Agent.StreamFallbacks))
+	}
+	if _, err := duplicateConfig(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Memory.ContextLongTermK < 0 || cfg.Memory.ContextShortTermK < 0 {
+		errs = append(errs, fmt.Errorf("invalid memory.contextLongTermK or memory.contextShortTermK: must not be negative"))
+	}
+	if _, err := contextShortTermMaxAge(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := shortTermTTL(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := vectorPersistence(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := consolidation(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Sessions.Dir != "" {
+		if _, err := autoSaveConfig(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.Sessions.IdleAfter != "" {
+		if _, err := janitorConfig(cfg, nil, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := trustedProxies(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := corsPolicy(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	for _, path := range cfg.Tools.FileAllowlist {
+		if !filepath.IsAbs(path) {
+			errs = append(errs, fmt.Errorf("invalid tools.fileAllowlist entry %q: must be an absolute path", path))
+		}
+	}
+	if sandboxed(cfg) && cfg.Agent.Workspace == "" {
+		errs = append(errs, fmt.Errorf("agent.sandbox.mode %q needs agent.workspace as the sandbox root", cfg.Agent.Sandbox.Mode))
+	}
+
+	routing, _ := cfg.Models["routing"].(string)
+	if _, err := ai.ParseRoutingPolicy(routing); err != nil {
+		errs = append(errs, fmt.Errorf("invalid models.routing: %w", err))
+	}
+	if fallbackRaw, ok := cfg.Models["fallback"]; ok {
+		fallback, err := ai.ParseFallbackOrder(fallbackRaw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid models.fallback: %w", err))
+		}
+		providers, _ := cfg.Models["providers"].(map[string]interface{})
+		for _, name := range fallback {
+			if _, ok := providers[name]; !ok {
+				errs = append(errs, fmt.Errorf("models.fallback names unknown provider %q", name))
+			}
+		}
+	}
+	if retryRaw, ok := cfg.Models["retry"].(map[string]interface{}); ok {
+		if _, err := ai.ParseRetryConfig(ai.DefaultRetryConfig(), retryRaw); err != nil {
+			errs = append(errs, fmt.Errorf("invalid models.retry: %w", err))
+		}
+	}
+	breakerConfig := ai.DefaultBreakerConfig()
+	if breakerRaw, ok := cfg.Models["breaker"].(map[string]interface{}); ok {
+		var err error
+		if breakerConfig, err = ai.ParseBreakerConfig(breakerConfig, breakerRaw); err != nil {
+			errs = append(errs, fmt.Errorf("invalid models.breaker: %w", err))
+		}
+	}
+	if providers, ok := cfg.Models["providers"].(map[string]interface{}); ok {
+		for providerName, providerConfig := range providers {
+			providerConfigMap, _ := providerConfig.(map[string]interface{})
+			if breakerRaw, ok := providerConfigMap["breaker"].(map[string]interface{}); ok {
+				if _, err := ai.ParseBreakerConfig(breakerConfig, breakerRaw); err != nil {
+					errs = append(errs, fmt.Errorf("invalid models.providers.%s.breaker: %w", providerName, err))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// sandboxed reports whether agent.sandbox.mode confines the file tools to the
+// workspace
+func sandboxed(cfg *config.Config) bool {
+	return cfg.Agent.Sandbox.Mode != "" && cfg.Agent.Sandbox.Mode != "off"
+}
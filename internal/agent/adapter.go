@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"goclaw/internal/tools"
+	"goclaw/pkg/ai"
+)
+
+// ToolCallAdapter translates between the agent's tool calls and the way a
+// provider expresses them: natively, as OpenAI tool_calls or Anthropic
+// tool_use blocks, or as JSON in the reply text for basic models
+type ToolCallAdapter interface {
+	// PrepareRequest offers the registry's tools to the model
+	PrepareRequest(req *ai.ChatCompletionRequest, registry *tools.Registry)
+
+	// ExtractToolCalls returns the tool calls a response message makes, none
+	// for a direct answer
+	ExtractToolCalls(ctx context.Context, message ai.Message) []tools.ToolCall
+
+	// FormatToolResults returns the messages that continue the conversation
+	// after message: message itself, then the results of its calls in the
+	// shape the provider expects. results holds one text per call.
+	FormatToolResults(message ai.Message, calls []tools.ToolCall, results []string) []ai.Message
+}
+
+// NewToolCallAdapter returns the adapter for a format of ai.ToolCallFormatOf.
+// The executor parses tool calls written in text; unknown formats get the
+// text adapter too.
+func NewToolCallAdapter(format string, executor *tools.Executor) ToolCallAdapter {
+	switch format {
+	case ai.ToolCallFormatOpenAI:
+		return openAIToolCalls{}
+	case ai.ToolCallFormatAnthropic:
+		return anthropicToolCalls{}
+	default:
+		return textToolCalls{executor: executor}
+	}
+}
+
+// toolDefinitions describes the registry's tools for native tool calling,
+// sorted by name so requests stay the same from turn to turn
+func toolDefinitions(registry *tools.Registry) []ai.ToolDefinition {
+	var definitions []ai.ToolDefinition
+	for _, tool := range registry.List() {
+		definitions = append(definitions, ai.ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.InputSchema(),
+		})
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+// callParams decodes the JSON arguments of a native call. Arguments that
+// aren't a JSON object leave the params empty, so validation reports what
+// the model left out.
+func callParams(arguments []byte) map[string]interface{} {
+	params := map[string]interface{}{}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &params); err != nil || params == nil {
+			params = map[string]interface{}{}
+		}
+	}
+	return params
+}
+
+// openAIToolCalls adapts the tool_calls of OpenAI-compatible APIs, which
+// Zhipu, Qwen and Minimax use too. Each result goes back in a "tool"
+// message naming the call it answers.
+type openAIToolCalls struct{}
+
+func (openAIToolCalls) PrepareRequest(req *ai.ChatCompletionRequest, registry *tools.Registry) {
+	req.Tools = toolDefinitions(registry)
+}
+
+func (openAIToolCalls) ExtractToolCalls(ctx context.Context, message ai.Message) []tools.ToolCall {
+	calls := make([]tools.ToolCall, 0, len(message.ToolCalls))
+	for _, call := range message.ToolCalls {
+		calls = append(calls, tools.ToolCall{
+			ID:     call.ID,
+			Name:   call.Function.Name,
+			Params: callParams([]byte(call.Function.Arguments)),
+		})
+	}
+	return calls
+}
+
+func (openAIToolCalls) FormatToolResults(message ai.Message, calls []tools.ToolCall, results []string) []ai.Message {
+	messages := []ai.Message{message}
+	for i, call := range calls {
+		messages = append(messages, ai.Message{Role: "tool", ToolCallID: call.ID, Content: results[i]})
+	}
+	return messages
+}
+
+// anthropicToolCalls adapts the tool_use content blocks of the Anthropic
+// messages API. The results go back as tool_result blocks of one user
+// message.
+type anthropicToolCalls struct{}
+
+func (anthropicToolCalls) PrepareRequest(req *ai.ChatCompletionRequest, registry *tools.Registry) {
+	req.Tools = toolDefinitions(registry)
+}
+
+func (anthropicToolCalls) ExtractToolCalls(ctx context.Context, message ai.Message) []tools.ToolCall {
+	var calls []tools.ToolCall
+	for _, block := range message.Blocks {
+		if block.Type == "tool_use" {
+			calls = append(calls, tools.ToolCall{ID: block.ID, Name: block.Name, Params: callParams(block.Input)})
+		}
+	}
+	return calls
+}
+
+func (anthropicToolCalls) FormatToolResults(message ai.Message, calls []tools.ToolCall, results []string) []ai.Message {
+	blocks := make([]ai.AnthropicContent, len(calls))
+	for i, call := range calls {
+		blocks[i] = ai.AnthropicContent{Type: "tool_result", ToolUseID: call.ID, Content: results[i]}
+	}
+	return []ai.Message{message, {Role: "user", Blocks: blocks}}
+}
+
+// textToolCalls adapts models without native tool calls: the tools are
+// described in a system prompt, the model replies with a JSON tool call, and
+// the result goes back as a user message
+type textToolCalls struct {
+	executor *tools.Executor
+}
+
+func (t textToolCalls) PrepareRequest(req *ai.ChatCompletionRequest, registry *tools.Registry) {
+	messages := make([]ai.Message, 0, len(req.Messages)+1)
+	messages = append(messages, ai.Message{Role: "system", Content: toolPrompt(registry)})
+	req.Messages = append(messages, req.Messages...)
+}
+
+// ExtractToolCalls parses the tool call of a response. Only responses that
+// attempt the JSON tool format are parsed, so a plain answer that mentions a
+// tool is not mistaken for a call; a malformed attempt goes through recovery.
+func (t textToolCalls) ExtractToolCalls(ctx context.Context, message ai.Message) []tools.ToolCall {
+	response := message.Content
+	if !strings.Contains(response, "{") || !strings.Contains(strings.ToLower(response), "tool") {
+		return nil
+	}
+
+	call, err := t.executor.ParseToolCallWithRecovery(ctx, response)
+	if err != nil {
+		return nil
+	}
+	return []tools.ToolCall{*call}
+}
+
+func (t textToolCalls) FormatToolResults(message ai.Message, calls []tools.ToolCall, results []string) []ai.Message {
+	return []ai.Message{
+		{Role: "assistant", Content: message.Content},
+		{Role: "user", Content: strings.Join(results, "\n\n")},
+	}
+}
+
+// toolPrompt builds the system prompt describing available tools
+func toolPrompt(registry *tools.Registry) string {
+	var sb strings.Builder
+	sb.WriteString(registry.ToMarkdown())
+	sb.WriteString("\nTo call a tool, reply with ONLY a JSON object in this format:\n")
+	sb.WriteString(`{"tool": "<tool name>", "params": {"<param>": <value>}}`)
+	sb.WriteString("\n\nOtherwise, answer the user directly.\n")
+	return sb.String()
+}
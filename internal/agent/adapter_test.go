@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"goclaw/internal/tools"
+	"goclaw/pkg/ai"
+)
+
+func TestToolCallAdapters(t *testing.T) {
+	// A reply in the OpenAI shape, as Zhipu, Qwen and Minimax send it
+	var openAIResponse ai.ChatCompletionResponse
+	if err := json.Unmarshal([]byte(`{"choices": [{"message": {
+		"role": "assistant",
+		"content": null,
+		"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\": \"Paris\"}"}}]
+	}}]}`), &openAIResponse); err != nil {
+		t.Fatal(err)
+	}
+
+	// An Anthropic reply's content blocks, as the client keeps them
+	var anthropicBlocks []ai.AnthropicContent
+	if err := json.Unmarshal([]byte(`[
+		{"type": "text", "text": "Let me check."},
+		{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+	]`), &anthropicBlocks); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := tools.NewRegistry()
+	registry.Register(&tools.Tool{
+		Name:       "weather",
+		Parameters: map[string]tools.Parameter{"city": {Type: "string", Required: true}},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return "sunny", nil
+		},
+	})
+
+	tests := []struct {
+		format  string
+		message ai.Message
+		wantID  string
+		check   func(t *testing.T, followUp []ai.Message)
+	}{
+		{
+			format:  ai.ToolCallFormatOpenAI,
+			message: openAIResponse.Choices[0].Message,
+			wantID:  "call_1",
+			check: func(t *testing.T, followUp []ai.Message) {
+				if len(followUp) != 2 || len(followUp[0].ToolCalls) != 1 ||
+					followUp[1].Role != "tool" || followUp[1].ToolCallID != "call_1" || followUp[1].Content != "sunny" {
+					t.Errorf("follow-up = %+v, want the call and a tool message answering it", followUp)
+				}
+			},
+		},
+		{
+			format:  ai.ToolCallFormatAnthropic,
+			message: ai.Message{Role: "assistant", Content: "Let me check.", Blocks: anthropicBlocks},
+			wantID:  "toolu_1",
+			check: func(t *testing.T, followUp []ai.Message) {
+				want := []ai.AnthropicContent{{Type: "tool_result", ToolUseID: "toolu_1", Content: "sunny"}}
+				if len(followUp) != 2 || len(followUp[0].Blocks) != 2 ||
+					followUp[1].Role != "user" || !reflect.DeepEqual(followUp[1].Blocks, want) {
+					t.Errorf("follow-up = %+v, want the tool_use blocks and a tool_result block", followUp)
+				}
+			},
+		},
+		{
+			format:  ai.ToolCallFormatText,
+			message: ai.Message{Role: "assistant", Content: `{"tool": "weather", "params": {"city": "Paris"}}`},
+			check: func(t *testing.T, followUp []ai.Message) {
+				if len(followUp) != 2 || followUp[0].Role != "assistant" || followUp[1].Role != "user" || followUp[1].Content != "sunny" {
+					t.Errorf("follow-up = %+v, want the reply and the result as a user message", followUp)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			adapter := NewToolCallAdapter(tt.format, tools.NewExecutor(registry))
+
+			calls := adapter.ExtractToolCalls(context.Background(), tt.message)
+			if len(calls) != 1 || calls[0].ID != tt.wantID || calls[0].Name != "weather" || calls[0].Params["city"] != "Paris" {
+				t.Fatalf("ExtractToolCalls() = %+v, want weather(city=Paris) with ID %q", calls, tt.wantID)
+			}
+			tt.check(t, adapter.FormatToolResults(tt.message, calls, []string{"sunny"}))
+
+			if plain := adapter.ExtractToolCalls(context.Background(), ai.Message{Role: "assistant", Content: "It's sunny."}); len(plain) != 0 {
+				t.Errorf("ExtractToolCalls() on a direct answer = %+v, want none", plain)
+			}
+		})
+	}
+}
+
+// nativeClient calls the ping tool natively in the OpenAI format once, then
+// answers
+type nativeClient struct {
+	requests []ai.ChatCompletionRequest
+}
+
+func (c *nativeClient) ToolCallFormat(model string) string { return ai.ToolCallFormatOpenAI }
+
+func (c *nativeClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, req)
+
+	message := ai.Message{Role: "assistant", Content: "pong received"}
+	if len(c.requests) == 1 {
+		message = ai.Message{Role: "assistant", ToolCalls: []ai.ToolCall{{
+			ID: "call_1", Type: "function", Function: ai.ToolCallFunction{Name: "ping", Arguments: "{}"},
+		}}}
+	}
+	return &ai.ChatCompletionResponse{Choices: []ai.Choice{{Message: message}}}, nil
+}
+
+func (c *nativeClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestAgentNativeToolCalls(t *testing.T) {
+	client := &nativeClient{}
+	a := NewAgent(client, newPingRegistry(t), Config{})
+
+	result, err := a.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "ping please"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Response != "pong received" || len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_1" {
+		t.Errorf("result = %+v, want the ping call then the answer", result)
+	}
+
+	first := client.requests[0]
+	if len(first.Tools) != 1 || first.Tools[0].Name != "ping" || hasToolPrompt(first.Messages) {
+		t.Errorf("first request tools = %+v, want ping declared natively instead of the JSON prompt", first.Tools)
+	}
+	second := client.requests[1].Messages
+	if last := second[len(second)-1]; last.Role != "tool" || last.ToolCallID != "call_1" {
+		t.Errorf("second request ends with %+v, want the ping result for call_1", last)
+	}
+
+	// The text format can be forced for providers whose native calls misbehave
+	client = &nativeClient{}
+	a = NewAgent(client, newPingRegistry(t), Config{ToolCalling: ai.ToolCallFormatText})
+	a.Run(context.Background(), "s2", []ai.Message{{Role: "user", Content: "ping please"}})
+	if first := client.requests[0]; len(first.Tools) != 0 || !hasToolPrompt(first.Messages) {
+		t.Errorf("forced text request = %+v, want the JSON prompt and no native tools", first)
+	}
+}
@@ -0,0 +1,323 @@
+// Package agent implements the tool-using agent loop for Goclaw
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"goclaw/internal/format"
+	"goclaw/internal/tools"
+	"goclaw/pkg/ai"
+)
+
+const (
+	// DefaultMaxToolRounds is the default cap on tool-call rounds within a single turn
+	DefaultMaxToolRounds = 5
+
+	// DefaultMaxSessionToolRounds is the default cap on consecutive tool-call
+	// rounds in a session, across turns, without a direct answer
+	DefaultMaxSessionToolRounds = 20
+
+	// DefaultMaxToolRetries is the default number of times a failed tool call
+	// may be retried with adjusted parameters
+	DefaultMaxToolRetries = 2
+)
+
+// Cutoff reasons reported in Result
+const (
+	CutoffTurn      = "turn"
+	CutoffSession   = "session"
+	CutoffToolError = "tool_error"
+)
+
+// finalAnswerInstruction is sent when a tool-call cap forces a final answer
+const finalAnswerInstruction = "The tool call limit has been reached. Answer the user now using the information above, without calling any tools."
+
+// toolErrorInstruction is sent when a tool failure cannot be retried
+const toolErrorInstruction = "The tool call failed and cannot be retried. Answer the user now using the information above, without calling any tools."
+
+// Config holds agent loop settings
+type Config struct {
+	Model                string // Model to request from the client
+	MaxToolRounds        int    // Per-turn cap on tool-call rounds
+	MaxSessionToolRounds int    // Per-session cap on consecutive tool-call rounds, across turns, without a direct answer
+	MaxToolRetries       int    // Retries of a failing tool call before giving up; negative disables retries
+	MaxToolDepth         int    // How deeply tool calls may nest, tools.DefaultMaxDepth when 0
+
+	// ToolCalling forces a format of ai.ToolCallFormatOf, e.g.
+	// ai.ToolCallFormatText for a provider whose native tool calls misbehave.
+	// Empty uses the format of the provider serving Model.
+	ToolCalling string
+}
+
+// ToolAttempt records one execution of a tool call
+type ToolAttempt struct {
+	Call      tools.ToolCall `json:"call"`
+	Attempt   int            `json:"attempt"` // 1 for the first try, 2 for the first retry, ...
+	Success   bool           `json:"success"`
+	Error     string         `json:"error,omitempty"`
+	Retryable bool           `json:"retryable,omitempty"`
+}
+
+// Result is the outcome of an agent turn
+type Result struct {
+	Response  string           `json:"response"`
+	ToolCalls []tools.ToolCall `json:"toolCalls,omitempty"`
+	Attempts  []ToolAttempt    `json:"attempts,omitempty"`
+	Rounds    int              `json:"rounds"`
+	Cutoff    string           `json:"cutoff,omitempty"` // CutoffTurn, CutoffSession or CutoffToolError when the answer was forced
+	Usage     ai.Usage         `json:"usage"`            // Tokens of all model calls of the turn
+	Meta      *ai.ResponseMeta `json:"meta,omitempty"`   // What the provider reported about the last model call
+}
+
+// SessionCounter tracks consecutive tool-call rounds per session. The chat
+// manager implements it, so the count lives with the chat session.
+type SessionCounter interface {
+	SessionToolRounds(sessionID string) int
+	AddSessionToolRound(sessionID string)
+	ResetSessionToolRounds(sessionID string)
+}
+
+// memoryCounter is the SessionCounter used until SetSessionCounter is called
+type memoryCounter struct {
+	mu     sync.Mutex
+	rounds map[string]int
+}
+
+func (c *memoryCounter) SessionToolRounds(sessionID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rounds[sessionID]
+}
+
+func (c *memoryCounter) AddSessionToolRound(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rounds[sessionID]++
+}
+
+func (c *memoryCounter) ResetSessionToolRounds(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.rounds, sessionID)
+}
+
+// Agent runs the model in a loop, executing tool calls until it produces an answer
+type Agent struct {
+	client   ai.Client
+	registry *tools.Registry
+	executor *tools.Executor
+	config   Config
+	sessions SessionCounter
+}
+
+// NewAgent creates a new agent
+func NewAgent(client ai.Client, registry *tools.Registry, config Config) *Agent {
+	if config.MaxToolRounds <= 0 {
+		config.MaxToolRounds = DefaultMaxToolRounds
+	}
+	if config.MaxSessionToolRounds <= 0 {
+		config.MaxSessionToolRounds = DefaultMaxSessionToolRounds
+	}
+	if config.MaxToolRetries == 0 {
+		config.MaxToolRetries = DefaultMaxToolRetries
+	} else if config.MaxToolRetries < 0 {
+		config.MaxToolRetries = 0
+	}
+
+	executor := tools.NewExecutor(registry)
+	executor.SetMaxDepth(config.MaxToolDepth)
+
+	return &Agent{
+		client:   client,
+		registry: registry,
+		executor: executor,
+		config:   config,
+		sessions: &memoryCounter{rounds: make(map[string]int)},
+	}
+}
+
+// SetSessionCounter sets where the per-session tool-call rounds are kept
+func (a *Agent) SetSessionCounter(counter SessionCounter) {
+	a.sessions = counter
+}
+
+// Run runs one agent turn over the given conversation. Tool-call rounds add
+// up across turns until the model answers without calling a tool or the
+// session cap forces an answer, either of which resets the session's counter.
+func (a *Agent) Run(ctx context.Context, sessionID string, messages []ai.Message) (*Result, error) {
+	return a.RunWithParams(ctx, sessionID, messages, ai.GenerationParams{})
+}
+
+// RunWithParams is Run with sampling parameters applied to every model request
+func (a *Agent) RunWithParams(ctx context.Context, sessionID string, messages []ai.Message, params ai.GenerationParams) (*Result, error) {
+	// Tools such as the notes act on the session that called them
+	ctx = tools.WithSessionID(ctx, sessionID)
+
+	callFormat := a.ToolCallFormat()
+	adapter := NewToolCallAdapter(callFormat, a.executor)
+
+	conversation := make([]ai.Message, 0, len(messages))
+	conversation = append(conversation, messages...)
+
+	result := &Result{}
+	attempt := 1 // Attempt number of the next call; reset after a success
+	for {
+		if result.Rounds >= a.config.MaxToolRounds {
+			result.Cutoff = CutoffTurn
+			break
+		}
+		if a.SessionRounds(sessionID) >= a.config.MaxSessionToolRounds {
+			result.Cutoff = CutoffSession
+			break
+		}
+
+		message, resp, err := a.complete(ctx, conversation, params, adapter)
+		if err != nil {
+			return nil, err
+		}
+		result.Usage = result.Usage.Add(resp.Usage)
+		result.Meta = resp.Meta
+
+		calls := adapter.ExtractToolCalls(ctx, message)
+		if len(calls) == 0 {
+			a.ResetSession(sessionID)
+			result.Response = message.Content
+			return result, nil
+		}
+
+		result.Rounds++
+		a.sessions.AddSessionToolRound(sessionID)
+
+		// Every call of the round gets a result; the ones after a call that
+		// can't be retried aren't run
+		feedback := make([]string, len(calls))
+		for i, call := range calls {
+			if result.Cutoff != "" {
+				feedback[i] = "Not run: an earlier tool call failed."
+				continue
+			}
+
+			toolResult, execErr := a.executor.Execute(ctx, call.Name, call.Params)
+			result.ToolCalls = append(result.ToolCalls, call)
+
+			record := ToolAttempt{Call: call, Attempt: attempt, Success: execErr == nil}
+			if execErr != nil {
+				record.Error = execErr.Error()
+				record.Retryable = tools.IsRetryable(execErr)
+			}
+			result.Attempts = append(result.Attempts, record)
+
+			feedback[i] = format.PlainFormatter{}.FormatToolResult(call.Name, toolResult)
+			if execErr == nil {
+				attempt = 1
+			} else if record.Retryable && attempt <= a.config.MaxToolRetries {
+				attempt++
+				feedback[i] += fmt.Sprintf("\n\nThe tool call failed (attempt %d of %d). Correct the parameters and call the tool again, or answer the user directly.",
+					record.Attempt, a.config.MaxToolRetries+1)
+			} else {
+				result.Cutoff = CutoffToolError
+			}
+		}
+
+		conversation = append(conversation, adapter.FormatToolResults(message, calls, feedback)...)
+
+		if result.Cutoff != "" {
+			break
+		}
+	}
+
+	instruction := finalAnswerInstruction
+	if result.Cutoff == CutoffToolError {
+		instruction = toolErrorInstruction
+		log.Printf("agent: session %s tool %s failed after %d attempts, forcing final answer", sessionID, result.ToolCalls[len(result.ToolCalls)-1].Name, attempt)
+	} else {
+		log.Printf("agent: session %s hit the %s tool-call cap after %d rounds this turn, forcing final answer", sessionID, result.Cutoff, result.Rounds)
+	}
+
+	// Final turn without the tool instructions. Native tool calls in the
+	// conversation stay declared, since providers reject calls to tools the
+	// request doesn't define.
+	final := make([]ai.Message, 0, len(conversation)+1)
+	final = append(final, conversation...)
+	final = append(final, ai.Message{Role: "user", Content: instruction})
+
+	var finalAdapter ToolCallAdapter
+	if callFormat != ai.ToolCallFormatText {
+		finalAdapter = adapter
+	}
+	message, resp, err := a.complete(ctx, final, params, finalAdapter)
+	if err != nil {
+		return nil, err
+	}
+	result.Response = message.Content
+	result.Usage = result.Usage.Add(resp.Usage)
+	result.Meta = resp.Meta
+
+	// The forced answer ends the chain, so the next turn may use tools again
+	if result.Cutoff == CutoffSession {
+		a.ResetSession(sessionID)
+	}
+
+	return result, nil
+}
+
+// ToolCallFormat returns how the agent exchanges tool calls with the model:
+// Config.ToolCalling when set, else the native format of the provider
+// serving Config.Model
+func (a *Agent) ToolCallFormat() string {
+	if a.config.ToolCalling != "" {
+		return a.config.ToolCalling
+	}
+	return ai.ToolCallFormatOf(a.client, a.config.Model)
+}
+
+// EnableRecovery lets the agent re-ask the model for a strict JSON tool call
+// when a response tries to call a tool but cannot be parsed. A maxAttempts of
+// zero or less uses tools.DefaultMaxRecoveryAttempts.
+func (a *Agent) EnableRecovery(maxAttempts int) {
+	a.executor.SetRecovery(func(ctx context.Context, prompt string) (string, error) {
+		message, _, err := a.complete(ctx, []ai.Message{{Role: "user", Content: prompt}}, ai.GenerationParams{}, nil)
+		return message.Content, err
+	}, maxAttempts)
+}
+
+// SessionRounds returns the consecutive tool-call rounds recorded for a session
+func (a *Agent) SessionRounds(sessionID string) int {
+	return a.sessions.SessionToolRounds(sessionID)
+}
+
+// ResetSession clears the consecutive tool-call counter for a session
+func (a *Agent) ResetSession(sessionID string) {
+	a.sessions.ResetSessionToolRounds(sessionID)
+}
+
+// complete sends the conversation to the model, offering it the tools
+// through adapter unless that is nil, and returns the reply with its content
+// trimmed and the provider's response, whose Meta is set
+func (a *Agent) complete(ctx context.Context, messages []ai.Message, params ai.GenerationParams, adapter ToolCallAdapter) (ai.Message, *ai.ChatCompletionResponse, error) {
+	req := ai.ChatCompletionRequest{
+		Model:    a.config.Model,
+		Messages: messages,
+	}
+	params.Apply(&req)
+	if adapter != nil {
+		adapter.PrepareRequest(&req, a.registry)
+	}
+
+	resp, err := a.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return ai.Message{}, nil, err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return ai.Message{}, nil, fmt.Errorf("no choices returned from model")
+	}
+	resp.Meta = ai.MetaOf(resp, req.Model)
+
+	message := resp.Choices[0].Message
+	message.Content = strings.TrimSpace(message.Content)
+	return message, resp, nil
+}
@@ -0,0 +1,396 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goclaw/internal/chat"
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+	"goclaw/internal/tools/builtin"
+	"goclaw/pkg/ai"
+)
+
+// toolHappyClient always asks for a tool unless the tool instructions are absent
+type toolHappyClient struct {
+	requests [][]ai.Message
+}
+
+func (c *toolHappyClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, req.Messages)
+
+	content := `{"tool": "ping", "params": {}}`
+	if !hasToolPrompt(req.Messages) {
+		content = "final answer"
+	}
+
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: content}}},
+		Usage:   ai.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110},
+	}, nil
+}
+
+func (c *toolHappyClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func hasToolPrompt(messages []ai.Message) bool {
+	for _, msg := range messages {
+		if msg.Role == "system" && strings.Contains(msg.Content, `"tool"`) {
+			return true
+		}
+	}
+	return false
+}
+
+func newPingRegistry(t *testing.T) *tools.Registry {
+	t.Helper()
+
+	registry := tools.NewRegistry()
+	err := registry.Register(&tools.Tool{
+		Name:        "ping",
+		Description: "Returns pong",
+		Parameters:  map[string]tools.Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return "pong", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	return registry
+}
+
+func TestAgentSessionCapForcesFinalAnswer(t *testing.T) {
+	client := &toolHappyClient{}
+	agent := NewAgent(client, newPingRegistry(t), Config{
+		MaxToolRounds:        10,
+		MaxSessionToolRounds: 3,
+	})
+
+	ctx := context.Background()
+	result, err := agent.Run(ctx, "s1", []ai.Message{{Role: "user", Content: "ping forever"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Cutoff != CutoffSession {
+		t.Errorf("cutoff = %q, want %q", result.Cutoff, CutoffSession)
+	}
+	if result.Rounds != 3 {
+		t.Errorf("rounds = %d, want 3", result.Rounds)
+	}
+	if result.Response != "final answer" {
+		t.Errorf("response = %q, want forced final answer", result.Response)
+	}
+
+	last := client.requests[len(client.requests)-1]
+	if hasToolPrompt(last) {
+		t.Error("forced final turn should not include tool instructions")
+	}
+
+	// The forced answer ends the chain
+	if rounds := agent.SessionRounds("s1"); rounds != 0 {
+		t.Errorf("session s1 rounds after the forced answer = %d, want 0", rounds)
+	}
+
+	// Other sessions are unaffected
+	if rounds := agent.SessionRounds("s2"); rounds != 0 {
+		t.Errorf("session s2 rounds = %d, want 0", rounds)
+	}
+
+	// The next turn may use tools again
+	result, err = agent.Run(ctx, "s1", []ai.Message{{Role: "user", Content: "try again"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Rounds != 3 {
+		t.Errorf("rounds after reset = %d, want 3", result.Rounds)
+	}
+}
+
+func TestAgentSessionCapAcrossChatTurns(t *testing.T) {
+	chatMgr := chat.NewChatManager(100)
+	chatMgr.CreateSession("s1", "")
+
+	client := &toolHappyClient{}
+	agent := NewAgent(client, newPingRegistry(t), Config{
+		MaxToolRounds:        2,
+		MaxSessionToolRounds: 5,
+	})
+	agent.SetSessionCounter(chatMgr)
+
+	ctx := context.Background()
+	var cutoffs []string
+	for turn := 1; turn <= 3; turn++ {
+		result, err := agent.Run(ctx, "s1", []ai.Message{{Role: "user", Content: fmt.Sprintf("ping, turn %d", turn)}})
+		if err != nil {
+			t.Fatalf("Run() turn %d error = %v", turn, err)
+		}
+		cutoffs = append(cutoffs, result.Cutoff)
+
+		if turn < 3 && chatMgr.SessionToolRounds("s1") != 2*turn {
+			t.Errorf("rounds after turn %d = %d, want %d", turn, chatMgr.SessionToolRounds("s1"), 2*turn)
+		}
+		if turn == 3 && result.Rounds != 1 {
+			t.Errorf("turn 3 rounds = %d, want 1 before the session cap", result.Rounds)
+		}
+	}
+
+	want := []string{CutoffTurn, CutoffTurn, CutoffSession}
+	for i := range want {
+		if cutoffs[i] != want[i] {
+			t.Fatalf("cutoffs = %v, want %v", cutoffs, want)
+		}
+	}
+	if chatMgr.SessionToolRounds("s1") != 0 {
+		t.Errorf("rounds after the session cap = %d, want 0", chatMgr.SessionToolRounds("s1"))
+	}
+}
+
+func TestAgentDirectAnswerResetsSessionRounds(t *testing.T) {
+	client := &scriptedClient{responses: []string{`{"tool": "ping", "params": {}}`, "pong"}}
+	agent := NewAgent(client, newPingRegistry(t), Config{})
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "ping once"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Rounds != 1 || agent.SessionRounds("s1") != 0 {
+		t.Errorf("rounds = %d, session rounds = %d, want 1 and a reset", result.Rounds, agent.SessionRounds("s1"))
+	}
+}
+
+func TestAgentTurnCap(t *testing.T) {
+	client := &toolHappyClient{}
+	agent := NewAgent(client, newPingRegistry(t), Config{
+		MaxToolRounds:        2,
+		MaxSessionToolRounds: 10,
+	})
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "ping"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Cutoff != CutoffTurn || result.Rounds != 2 {
+		t.Errorf("expected turn cutoff after 2 rounds, got rounds=%d cutoff=%q", result.Rounds, result.Cutoff)
+	}
+	if agent.SessionRounds("s1") != 2 {
+		t.Errorf("session rounds = %d, want 2", agent.SessionRounds("s1"))
+	}
+
+	// Usage adds up both tool rounds and the forced answer
+	calls := len(client.requests)
+	want := ai.Usage{PromptTokens: 100 * calls, CompletionTokens: 10 * calls, TotalTokens: 110 * calls}
+	if calls != 3 || result.Usage != want {
+		t.Errorf("usage = %+v after %d calls, want %+v after 3", result.Usage, calls, want)
+	}
+}
+
+// scriptedClient replies with its responses in order, recording each request
+type scriptedClient struct {
+	responses []string
+	requests  [][]ai.Message
+}
+
+func (c *scriptedClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	c.requests = append(c.requests, req.Messages)
+
+	content := "final answer"
+	if len(c.requests) <= len(c.responses) {
+		content = c.responses[len(c.requests)-1]
+	}
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: content}}},
+	}, nil
+}
+
+func (c *scriptedClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func readCall(path string) string {
+	params, _ := json.Marshal(map[string]interface{}{"path": path})
+	return fmt.Sprintf(`{"tool": "read", "params": %s}`, params)
+}
+
+func TestAgentRetriesToolWithCorrectedPath(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.md")
+	if err := os.WriteFile(notes, []byte("buy milk"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	client := &scriptedClient{responses: []string{
+		readCall(filepath.Join(dir, "note.md")),
+		readCall(notes),
+		"You need to buy milk.",
+	}}
+	agent := NewAgent(client, builtin.NewManager().GetRegistry(), Config{})
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "What's in my notes?"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Response != "You need to buy milk." {
+		t.Errorf("response = %q", result.Response)
+	}
+	if result.Cutoff != "" {
+		t.Errorf("cutoff = %q, want none", result.Cutoff)
+	}
+	if len(result.Attempts) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(result.Attempts))
+	}
+
+	first, second := result.Attempts[0], result.Attempts[1]
+	if first.Success || !first.Retryable || first.Attempt != 1 {
+		t.Errorf("first attempt = %+v, want retryable failure", first)
+	}
+	if !second.Success || second.Attempt != 2 || second.Call.Params["path"] != notes {
+		t.Errorf("second attempt = %+v, want success reading %s", second, notes)
+	}
+
+	// The model was told about the failure before retrying
+	retryPrompt := client.requests[1][len(client.requests[1])-1].Content
+	if !strings.Contains(retryPrompt, "no such file") || !strings.Contains(retryPrompt, "attempt 1 of 3") {
+		t.Errorf("retry prompt = %q, want error and attempt count", retryPrompt)
+	}
+}
+
+func TestAgentStopsOnNonRetryableToolError(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&tools.Tool{
+		Name:        "secret",
+		Description: "Reads a protected file",
+		Parameters:  map[string]tools.Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("open /root/secret: %w", fs.ErrPermission)
+		},
+	})
+
+	scripted := &scriptedClient{responses: []string{`{"tool": "secret", "params": {}}`}}
+	agent := NewAgent(scripted, registry, Config{})
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "read the secret"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Cutoff != CutoffToolError {
+		t.Errorf("cutoff = %q, want %q", result.Cutoff, CutoffToolError)
+	}
+	if len(result.Attempts) != 1 || result.Attempts[0].Retryable {
+		t.Errorf("attempts = %+v, want a single non-retryable failure", result.Attempts)
+	}
+	if len(scripted.requests) != 2 || hasToolPrompt(scripted.requests[1]) {
+		t.Errorf("expected one tool turn and a final no-tool turn, got %d requests", len(scripted.requests))
+	}
+}
+
+func TestAgentGivesUpAfterMaxRetries(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.md")
+	client := &scriptedClient{responses: []string{readCall(missing), readCall(missing), readCall(missing)}}
+	agent := NewAgent(client, builtin.NewManager().GetRegistry(), Config{MaxToolRetries: 1})
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "read it"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Cutoff != CutoffToolError || len(result.Attempts) != 2 {
+		t.Errorf("cutoff = %q after %d attempts, want %q after 2", result.Cutoff, len(result.Attempts), CutoffToolError)
+	}
+}
+
+func TestAgentRecoversMalformedToolCall(t *testing.T) {
+	client := &scriptedClient{responses: []string{
+		`I'll use the tool: {"tool": "ping", "params": {}`,
+		`{"tool": "ping", "params": {}}`,
+		"pong it is",
+	}}
+	agent := NewAgent(client, newPingRegistry(t), Config{})
+	agent.EnableRecovery(1)
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "ping please"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Response != "pong it is" {
+		t.Errorf("response = %q, want the answer after the recovered call", result.Response)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Name != "ping" || !result.Attempts[0].Success {
+		t.Errorf("tool calls = %+v, attempts = %+v, want one successful ping", result.ToolCalls, result.Attempts)
+	}
+	if len(client.requests) != 3 {
+		t.Fatalf("got %d model requests, want turn, recovery and answer", len(client.requests))
+	}
+	if recovery := client.requests[1][0].Content; !strings.Contains(recovery, "could not be parsed") {
+		t.Errorf("second request = %q, want the recovery prompt", recovery)
+	}
+}
+
+func TestAgentAnswerMentioningToolIsNotACall(t *testing.T) {
+	client := &scriptedClient{responses: []string{"The ping tool replied pong."}}
+	agent := NewAgent(client, newPingRegistry(t), Config{})
+	agent.EnableRecovery(1)
+
+	result, err := agent.Run(context.Background(), "s1", []ai.Message{{Role: "user", Content: "what did ping say?"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Rounds != 0 || len(client.requests) != 1 {
+		t.Errorf("rounds = %d after %d requests, want a direct answer", result.Rounds, len(client.requests))
+	}
+}
+
+func TestAgentNotesPersistAcrossTurns(t *testing.T) {
+	store := memory.NewMemoryStore(memory.DefaultConfig())
+	registry := tools.NewRegistry()
+	for _, tool := range builtin.NoteTools(store) {
+		if err := registry.Register(tool); err != nil {
+			t.Fatalf("failed to register tool: %v", err)
+		}
+	}
+
+	client := &scriptedClient{responses: []string{
+		`{"tool": "remember_note", "params": {"note": "The user wants the report in French", "priority": 2}}`,
+		"I'll start on the report.",
+		`{"tool": "recall_notes", "params": {}}`,
+		"Voici le rapport.",
+	}}
+	agent := NewAgent(client, registry, Config{})
+
+	ctx := context.Background()
+	if _, err := agent.Run(ctx, "s1", []ai.Message{{Role: "user", Content: "Write the report in French"}}); err != nil {
+		t.Fatalf("turn 1: Run() error = %v", err)
+	}
+	result, err := agent.Run(ctx, "s1", []ai.Message{{Role: "user", Content: "Go on with the report"}})
+	if err != nil {
+		t.Fatalf("turn 2: Run() error = %v", err)
+	}
+	if result.Response != "Voici le rapport." || !result.Attempts[0].Success {
+		t.Fatalf("turn 2 = %+v, want the recalled notes answered", result)
+	}
+
+	// The recalled notes are fed back to the model
+	feedback := client.requests[len(client.requests)-1]
+	if last := feedback[len(feedback)-1].Content; !strings.Contains(last, "The user wants the report in French") {
+		t.Errorf("recall_notes feedback = %q, want the note from turn 1", last)
+	}
+
+	// Notes are scoped to their session and kept apart from user memories
+	if notes := store.Notes("s2"); len(notes) != 0 {
+		t.Errorf("session s2 notes = %+v, want none", notes)
+	}
+	if notes := store.Notes("s1"); len(notes) != 1 || !memory.IsAgentNote(notes[0]) {
+		t.Errorf("session s1 notes = %+v, want the agent note", notes)
+	}
+}
@@ -0,0 +1,257 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auto-save defaults
+const (
+	DefaultAutoSaveInterval = 5 * time.Second
+	DefaultAutoSaveMessages = 20
+)
+
+// sessionFileExt is the extension of persisted session files
+const sessionFileExt = ".json"
+
+// AutoSaveConfig controls how often changed sessions are written to disk
+type AutoSaveConfig struct {
+	Dir         string        // Directory holding one JSON file per session
+	Interval    time.Duration // Flush changed sessions this often, DefaultAutoSaveInterval when 0
+	MaxMessages int           // Flush early once this many messages were added, DefaultAutoSaveMessages when 0
+}
+
+// AutoSaver writes changed sessions of a ChatManager to disk. Sessions are
+// marked dirty as they change and flushed every Interval, or sooner once
+// MaxMessages messages were added, whichever comes first.
+type AutoSaver struct {
+	cm     *ChatManager
+	config AutoSaveConfig
+
+	mu      sync.Mutex
+	dirty   map[string]bool
+	pending int // Messages added since the last flush
+
+	trigger chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	close   sync.Once
+}
+
+// EnableAutoSave starts saving the manager's sessions to config.Dir in the
+// background. Close the returned saver on shutdown to flush the last changes
+// and stop its goroutine.
+func (cm *ChatManager) EnableAutoSave(config AutoSaveConfig) (*AutoSaver, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("auto-save needs a directory")
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultAutoSaveInterval
+	}
+	if config.MaxMessages <= 0 {
+		config.MaxMessages = DefaultAutoSaveMessages
+	}
+
+	saver := &AutoSaver{
+		cm:      cm,
+		config:  config,
+		dirty:   make(map[string]bool),
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	cm.mu.Lock()
+	cm.autoSaver = saver
+	cm.mu.Unlock()
+
+	go saver.run()
+	return saver, nil
+}
+
+// markDirty records a changed session and the messages added to it. The
+// caller holds the manager's lock.
+func (cm *ChatManager) markDirty(sessionID string, messages int) {
+	if cm.autoSaver != nil {
+		cm.autoSaver.markDirty(sessionID, messages)
+	}
+}
+
+func (a *AutoSaver) markDirty(sessionID string, messages int) {
+	a.mu.Lock()
+	a.dirty[sessionID] = true
+	a.pending += messages
+	full := a.pending >= a.config.MaxMessages
+	a.mu.Unlock()
+
+	if full {
+		select {
+		case a.trigger <- struct{}{}:
+		default: // A flush is already due
+		}
+	}
+}
+
+// forget drops a session from the pending saves
+func (a *AutoSaver) forget(sessionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.dirty, sessionID)
+}
+
+// run flushes on every tick and whenever enough messages piled up, until Close
+func (a *AutoSaver) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.trigger:
+		case <-a.stop:
+			return
+		}
+		if err := a.Flush(); err != nil {
+			fmt.Printf("Error auto-saving sessions: %v\n", err)
+		}
+	}
+}
+
+// Flush writes every dirty session to disk now, and removes the files of
+// sessions that were deleted. Sessions that fail to save stay dirty.
+func (a *AutoSaver) Flush() error {
+	a.mu.Lock()
+	ids := make([]string, 0, len(a.dirty))
+	for id := range a.dirty {
+		ids = append(ids, id)
+	}
+	a.dirty = make(map[string]bool)
+	a.pending = 0
+	a.mu.Unlock()
+
+	var failed []string
+	for _, id := range ids {
+		if err := a.cm.SaveSession(a.config.Dir, id); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", id, err))
+			a.mu.Lock()
+			a.dirty[id] = true
+			a.mu.Unlock()
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to save sessions: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Close stops auto-saving and flushes the remaining changes. It is safe to
+// call more than once.
+func (a *AutoSaver) Close() error {
+	a.close.Do(func() {
+		close(a.stop)
+		<-a.done
+
+		a.cm.mu.Lock()
+		if a.cm.autoSaver == a {
+			a.cm.autoSaver = nil
+		}
+		a.cm.mu.Unlock()
+	})
+	return a.Flush()
+}
+
+// SaveSession writes a session to its file in dir, or removes the file when
+// the session no longer exists. The file is replaced atomically, so a crash
+// mid-write leaves the previous version.
+func (cm *ChatManager) SaveSession(dir, sessionID string) error {
+	path := sessionPath(dir, sessionID)
+
+	cm.mu.RLock()
+	session, exists := cm.sessions[sessionID]
+	var data []byte
+	var err error
+	if exists {
+		data, err = json.MarshalIndent(session, "", "  ")
+	}
+	cm.mu.RUnlock()
+
+	if !exists {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove session file: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".session-*")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSessions reads the sessions saved in dir into the manager, returning
+// how many were loaded. A missing directory loads nothing.
+func (cm *ChatManager) LoadSessions(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+sessionFileExt))
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := make([]*ChatSession, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var session ChatSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if session.ID == "" {
+			continue
+		}
+		loaded = append(loaded, &session)
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, session := range loaded {
+		if session.Metadata == nil {
+			session.Metadata = make(map[string]interface{})
+		}
+		cm.sessions[session.ID] = session
+		if session.IsMain {
+			cm.mainSessionID = session.ID
+		}
+	}
+	return len(loaded), nil
+}
+
+// sessionPath is the file a session is saved to. The ID is escaped so it
+// can't point outside dir.
+func sessionPath(dir, sessionID string) string {
+	return filepath.Join(dir, url.PathEscape(sessionID)+sessionFileExt)
+}
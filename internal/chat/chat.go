@@ -3,6 +3,8 @@ package chat
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -23,13 +25,32 @@ type ChatSession struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	Metadata     map[string]interface{}
+	IsMain       bool          // Whether this is the main session
+	Config       SessionConfig // Session settings; its GenerationParams apply to the session's requests
+	ToolRounds   int           // Agent tool-call rounds since the model last answered directly
 }
 
+// ActiveSessionWindow is how recently a session must have been updated to count as active
+const ActiveSessionWindow = 30 * time.Minute
+
+// PruneCountPlaceholder is replaced with the number of omitted messages in the prune marker
+const PruneCountPlaceholder = "{count}"
+
+// DefaultPruneMarker is the note inserted where pruned messages used to be
+const DefaultPruneMarker = "[" + PruneCountPlaceholder + " earlier messages omitted]"
+
 // ChatManager manages multiple chat sessions
 type ChatManager struct {
-	mu        sync.RWMutex
-	sessions  map[string]*ChatSession
-	maxMemory int
+	mu          sync.RWMutex
+	sessions    map[string]*ChatSession
+	maxMemory   int
+	locks       *SessionLocks
+	pruneMarker string // Marker text with {count} for the omitted count; empty disables the marker
+
+	mainSessionID string
+	mainDefaults  MainSessionDefaults
+
+	autoSaver *AutoSaver // Set by EnableAutoSave; changes mark sessions dirty
 }
 
 // NewChatManager creates a new chat manager
@@ -39,11 +60,35 @@ func NewChatManager(maxMemory int) *ChatManager {
 	}
 
 	return &ChatManager{
-		sessions:  make(map[string]*ChatSession),
-		maxMemory: maxMemory,
+		sessions:     make(map[string]*ChatSession),
+		maxMemory:    maxMemory,
+		locks:        NewSessionLocks(),
+		pruneMarker:  DefaultPruneMarker,
+		mainDefaults: DefaultMainSessionDefaults(),
 	}
 }
 
+// SetPruneMarker sets the note left when history is pruned. Every {count}
+// in the text is replaced with the number of omitted messages; other text,
+// including any % signs, is kept as is. An empty marker disables it.
+func (cm *ChatManager) SetPruneMarker(marker string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.pruneMarker = marker
+}
+
+// IsPruneMarker reports whether a message is a prune marker inserted by the manager
+func IsPruneMarker(msg Message) bool {
+	marker, _ := msg.Metadata["pruneMarker"].(bool)
+	return marker
+}
+
+// LockSession serializes message processing for a session. It blocks until
+// earlier work on the same session is done and returns the unlock function.
+func (cm *ChatManager) LockSession(id string) func() {
+	return cm.locks.Lock(id)
+}
+
 // CreateSession creates a new chat session
 func (cm *ChatManager) CreateSession(id, systemPrompt string) *ChatSession {
 	cm.mu.Lock()
@@ -59,6 +104,10 @@ func (cm *ChatManager) CreateSession(id, systemPrompt string) *ChatSession {
 	}
 
 	cm.sessions[id] = session
+	if cm.mainDefaults.makesMain(id, cm.mainSessionID) {
+		cm.setMainLocked(session)
+	}
+	cm.markDirty(id, 0)
 	return session
 }
 
@@ -73,6 +122,11 @@ func (cm *ChatManager) GetSession(id string) (*ChatSession, bool) {
 
 // AddMessage adds a message to a session
 func (cm *ChatManager) AddMessage(sessionID, role, content string) error {
+	return cm.AddMessageWithMetadata(sessionID, role, content, nil)
+}
+
+// AddMessageWithMetadata adds a message with metadata (e.g. attachment references) to a session
+func (cm *ChatManager) AddMessageWithMetadata(sessionID, role, content string, metadata map[string]interface{}) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -84,6 +138,7 @@ func (cm *ChatManager) AddMessage(sessionID, role, content string) error {
 	message := Message{
 		Role:      role,
 		Content:   content,
+		Metadata:  metadata,
 		Timestamp: time.Now(),
 	}
 
@@ -92,30 +147,84 @@ func (cm *ChatManager) AddMessage(sessionID, role, content string) error {
 
 	// Prune old messages if needed
 	if len(session.Messages) > cm.maxMemory {
-		// Keep system prompt (if any) and last N messages
-		pruned := make([]Message, 0, cm.maxMemory)
-
-		// Add any system-like messages at the start
-		for _, msg := range session.Messages {
-			if msg.Role == "system" {
-				pruned = append(pruned, msg)
-			}
+		cm.prune(session)
+	}
+
+	cm.markDirty(sessionID, 1)
+	return nil
+}
+
+// prune keeps the system prompt (if any) and the last messages of a session,
+// leaving a marker where messages were dropped and recording the event in
+// the session metadata. The caller holds the lock.
+func (cm *ChatManager) prune(session *ChatSession) {
+	pruned := make([]Message, 0, cm.maxMemory)
+
+	// Add any system-like messages at the start, replacing an earlier marker
+	for _, msg := range session.Messages {
+		if msg.Role == "system" && !IsPruneMarker(msg) {
+			pruned = append(pruned, msg)
+		}
+	}
+
+	// Add last N messages, leaving room for the marker
+	remaining := cm.maxMemory - len(pruned)
+	if cm.pruneMarker != "" {
+		remaining--
+	}
+	start := len(session.Messages)
+	if remaining > 0 {
+		start = len(session.Messages) - remaining
+		if start < 0 {
+			start = 0
 		}
+	}
 
-		// Add last N messages
-		remaining := cm.maxMemory - len(pruned)
-		if remaining > 0 {
-			start := len(session.Messages) - remaining
-			if start < 0 {
-				start = 0
-			}
-			pruned = append(pruned, session.Messages[start:]...)
+	omitted := 0
+	for _, msg := range session.Messages[:start] {
+		if msg.Role != "system" {
+			omitted++
 		}
+	}
+
+	if session.Metadata == nil {
+		session.Metadata = make(map[string]interface{})
+	}
+	total := metadataCount(session.Metadata["prunedMessages"]) + omitted
+	events := metadataCount(session.Metadata["pruneEvents"])
+	session.Metadata["prunedMessages"] = total
+	session.Metadata["pruneEvents"] = events + 1
+	session.Metadata["lastPrunedAt"] = time.Now()
+
+	if cm.pruneMarker != "" && total > 0 {
+		pruned = append(pruned, Message{
+			Role:      "system",
+			Content:   strings.ReplaceAll(cm.pruneMarker, PruneCountPlaceholder, strconv.Itoa(total)),
+			Metadata:  map[string]interface{}{"pruneMarker": true, "omitted": total},
+			Timestamp: time.Now(),
+		})
+	}
 
-		session.Messages = pruned
+	for _, msg := range session.Messages[start:] {
+		if !IsPruneMarker(msg) {
+			pruned = append(pruned, msg)
+		}
 	}
 
-	return nil
+	session.Messages = pruned
+}
+
+// metadataCount reads a count stored in metadata, which is a float64 once
+// the metadata has been through JSON
+func metadataCount(value interface{}) int {
+	switch n := value.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
 }
 
 // GetMessages returns all messages in a session
@@ -163,6 +272,10 @@ func (cm *ChatManager) DeleteSession(id string) error {
 	}
 
 	delete(cm.sessions, id)
+	if cm.mainSessionID == id {
+		cm.mainSessionID = ""
+	}
+	cm.markDirty(id, 0)
 	return nil
 }
 
@@ -185,3 +298,30 @@ func (cm *ChatManager) SessionCount() int {
 	defer cm.mu.RUnlock()
 	return len(cm.sessions)
 }
+
+// GetSessionStatistics returns overall session statistics. Sessions updated
+// within ActiveSessionWindow are active, the rest idle.
+func (cm *ChatManager) GetSessionStatistics() map[string]interface{} {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	totalMessages := 0
+	activeCount := 0
+	cutoff := time.Now().Add(-ActiveSessionWindow)
+
+	for _, session := range cm.sessions {
+		totalMessages += len(session.Messages)
+		if session.UpdatedAt.After(cutoff) {
+			activeCount++
+		}
+	}
+
+	return map[string]interface{}{
+		"totalSessions":  len(cm.sessions),
+		"activeSessions": activeCount,
+		"idleSessions":   len(cm.sessions) - activeCount,
+		"totalMessages":  totalMessages,
+		"mainSessionID":  cm.mainSessionID,
+		"hasMainSession": cm.mainSessionID != "",
+	}
+}
@@ -0,0 +1,363 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAddMessagePruneMarker(t *testing.T) {
+	cm := NewChatManager(5)
+	cm.CreateSession("s1", "")
+
+	for i := 1; i <= 8; i++ {
+		cm.AddMessage("s1", "user", fmt.Sprintf("message %d", i))
+	}
+
+	messages, _ := cm.GetMessages("s1")
+	if len(messages) != 5 {
+		t.Fatalf("got %d messages, want 5", len(messages))
+	}
+
+	// 8 messages with room for a marker plus 4 recent ones: 4 omitted
+	marker := messages[0]
+	if !IsPruneMarker(marker) || marker.Role != "system" {
+		t.Fatalf("first message = %+v, want prune marker", marker)
+	}
+	if marker.Content != "[4 earlier messages omitted]" {
+		t.Errorf("marker content = %q", marker.Content)
+	}
+	if messages[1].Content != "message 5" || messages[4].Content != "message 8" {
+		t.Errorf("kept %q..%q, want message 5..message 8", messages[1].Content, messages[4].Content)
+	}
+
+	session, _ := cm.GetSession("s1")
+	if got := session.Metadata["prunedMessages"]; got != 4 {
+		t.Errorf("prunedMessages = %v, want 4", got)
+	}
+	if got := session.Metadata["pruneEvents"]; got != 3 {
+		t.Errorf("pruneEvents = %v, want 3", got)
+	}
+}
+
+func TestAddMessagePruneMarkerDisabled(t *testing.T) {
+	cm := NewChatManager(3)
+	cm.SetPruneMarker("")
+	cm.CreateSession("s1", "")
+
+	for i := 1; i <= 5; i++ {
+		cm.AddMessage("s1", "user", fmt.Sprintf("message %d", i))
+	}
+
+	messages, _ := cm.GetMessages("s1")
+	if len(messages) != 3 || messages[0].Content != "message 3" {
+		t.Errorf("messages = %+v, want the last 3 without a marker", messages)
+	}
+
+	session, _ := cm.GetSession("s1")
+	if got := session.Metadata["prunedMessages"]; got != 2 {
+		t.Errorf("prunedMessages = %v, want 2", got)
+	}
+}
+
+func TestPruneMarkerPlaceholder(t *testing.T) {
+	cm := NewChatManager(3)
+	cm.SetPruneMarker("100% sure: {count} dropped (%d %s)")
+	cm.CreateSession("s1", "")
+
+	for i := 1; i <= 4; i++ {
+		cm.AddMessage("s1", "user", fmt.Sprintf("message %d", i))
+	}
+
+	messages, _ := cm.GetMessages("s1")
+	if got := messages[0].Content; got != "100% sure: 2 dropped (%d %s)" {
+		t.Errorf("marker content = %q, want {count} replaced and the rest verbatim", got)
+	}
+}
+
+func TestPruneCountsSurviveJSONMetadata(t *testing.T) {
+	cm := NewChatManager(3)
+	session := cm.CreateSession("s1", "")
+
+	// Counts read back from JSON are float64
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"prunedMessages": 5, "pruneEvents": 2}`), &metadata); err != nil {
+		t.Fatalf("failed to decode metadata: %v", err)
+	}
+	session.Metadata = metadata
+
+	for i := 1; i <= 4; i++ {
+		cm.AddMessage("s1", "user", fmt.Sprintf("message %d", i))
+	}
+
+	if got := session.Metadata["prunedMessages"]; got != 7 {
+		t.Errorf("prunedMessages = %v, want 7", got)
+	}
+	if got := session.Metadata["pruneEvents"]; got != 3 {
+		t.Errorf("pruneEvents = %v, want 3", got)
+	}
+	messages, _ := cm.GetMessages("s1")
+	if messages[0].Content != "[7 earlier messages omitted]" {
+		t.Errorf("marker content = %q", messages[0].Content)
+	}
+}
+
+func TestFirstSessionBecomesMain(t *testing.T) {
+	cm := NewChatManager(10)
+	first := cm.CreateSession("first", "")
+	second := cm.CreateSession("second", "")
+
+	if !first.IsMain || second.IsMain {
+		t.Fatalf("IsMain = %v/%v, want only the first session main", first.IsMain, second.IsMain)
+	}
+
+	previous, err := cm.SetMainSession("second")
+	if err != nil {
+		t.Fatalf("SetMainSession() error = %v", err)
+	}
+	if previous != "first" {
+		t.Errorf("previous = %q, want first", previous)
+	}
+	if first.IsMain || !second.IsMain {
+		t.Errorf("IsMain = %v/%v, want the first session demoted", first.IsMain, second.IsMain)
+	}
+
+	main, err := cm.GetMainSession()
+	if err != nil || main.ID != "second" {
+		t.Errorf("GetMainSession() = %v, %v; want second", main, err)
+	}
+}
+
+func TestDesignatedMainSession(t *testing.T) {
+	cm := NewChatManager(10)
+	cm.SetMainSessionDefaults(MainSessionDefaults{SessionID: "home"})
+
+	web := cm.CreateSession("web", "")
+	home := cm.CreateSession("home", "")
+	if web.IsMain || !home.IsMain {
+		t.Errorf("IsMain = %v/%v, want only the designated session main", web.IsMain, home.IsMain)
+	}
+}
+
+func TestEnhancedSessionMainDefaults(t *testing.T) {
+	ecm := NewEnhancedChatManager(10)
+	first := ecm.CreateEnhancedSession("first", "", false)
+	explicit := ecm.CreateEnhancedSession("explicit", "", true)
+
+	if first.IsMainSession {
+		t.Error("first session should be demoted when another is created as main")
+	}
+	if main, err := ecm.GetMainSession(); err != nil || main != explicit {
+		t.Errorf("GetMainSession() = %v, %v; want the explicit main session", main, err)
+	}
+
+	ecm.SetMainSession("first")
+	if !first.IsMainSession || explicit.IsMainSession {
+		t.Errorf("IsMainSession = %v/%v, want only first", first.IsMainSession, explicit.IsMainSession)
+	}
+}
+
+func TestMarkdownRoundTripKeepsHeadingLines(t *testing.T) {
+	cm := NewChatManager(100)
+	cm.CreateSession("original", "")
+	cm.AddMessage("original", "user", "Summarize this transcript:\n\n## User\nhi\n\n## Assistant\nhello")
+	cm.AddMessage("original", "assistant", "## Summary (short)\nA greeting.")
+	cm.ImportSession("zero-time", ExportJSON, `{"messages":[{"role":"user","content":"no timestamp"}]}`)
+
+	for _, id := range []string{"original", "zero-time"} {
+		exported, err := cm.ExportSession(id, ExportMarkdown)
+		if err != nil {
+			t.Fatalf("ExportSession(%s) error = %v", id, err)
+		}
+		imported, err := UnmarshalMarkdown(exported)
+		if err != nil {
+			t.Fatalf("UnmarshalMarkdown(%s) error = %v", id, err)
+		}
+
+		original, _ := cm.GetMessages(id)
+		if len(imported) != len(original) {
+			t.Fatalf("%s: imported %d messages, want %d:\n%s", id, len(imported), len(original), exported)
+		}
+		for i := range original {
+			if imported[i].Role != original[i].Role || imported[i].Content != original[i].Content || !imported[i].Timestamp.Equal(original[i].Timestamp) {
+				t.Errorf("%s message %d = %+v, want %+v", id, i, imported[i], original[i])
+			}
+		}
+	}
+}
+
+// waitForSaved polls dir until a fresh manager loads session id with want
+// messages, failing after a second
+func waitForSaved(t *testing.T, dir, id string, want int) *ChatManager {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		loaded := NewChatManager(100)
+		if _, err := loaded.LoadSessions(dir); err != nil {
+			t.Fatalf("LoadSessions() error = %v", err)
+		}
+		if messages, err := loaded.GetMessages(id); err == nil && len(messages) == want {
+			return loaded
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s was not saved with %d messages", id, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAutoSaveSurvivesCrash(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewChatManager(100)
+	saver, err := cm.EnableAutoSave(AutoSaveConfig{Dir: dir, Interval: 20 * time.Millisecond, MaxMessages: 1000})
+	if err != nil {
+		t.Fatalf("EnableAutoSave() error = %v", err)
+	}
+	defer saver.Close()
+
+	cm.CreateSession("s1", "be brief")
+	cm.AddMessage("s1", "user", "remember the milk")
+	cm.AddMessage("s1", "assistant", "Noted.")
+
+	// The saver is never closed before reading the files, as in a crash
+	loaded := waitForSaved(t, dir, "s1", 2)
+	session, _ := loaded.GetSession("s1")
+	if session.SystemPrompt != "be brief" || session.Messages[0].Content != "remember the milk" {
+		t.Errorf("loaded session = %+v, want the saved conversation", session)
+	}
+}
+
+func TestAutoSaveFlushesAfterMaxMessages(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewChatManager(100)
+	saver, err := cm.EnableAutoSave(AutoSaveConfig{Dir: dir, Interval: time.Hour, MaxMessages: 3})
+	if err != nil {
+		t.Fatalf("EnableAutoSave() error = %v", err)
+	}
+	defer saver.Close()
+
+	cm.CreateSession("s1", "")
+	for i := 0; i < 3; i++ {
+		cm.AddMessage("s1", "user", fmt.Sprintf("message %d", i))
+	}
+	waitForSaved(t, dir, "s1", 3)
+}
+
+func TestAutoSaverCloseFlushesAndStops(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewChatManager(100)
+	saver, err := cm.EnableAutoSave(AutoSaveConfig{Dir: dir, Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("EnableAutoSave() error = %v", err)
+	}
+
+	cm.CreateSession("s1", "")
+	cm.CreateSession("s2", "")
+	cm.AddMessage("s1", "user", "hello")
+	cm.DeleteSession("s2")
+
+	if err := saver.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-saver.done:
+	default:
+		t.Error("Close() should stop the auto-save goroutine")
+	}
+	if err := saver.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+
+	waitForSaved(t, dir, "s1", 1)
+	if _, err := os.Stat(sessionPath(dir, "s2")); !os.IsNotExist(err) {
+		t.Errorf("deleted session file stat error = %v, want it removed", err)
+	}
+
+	// Changes after Close are no longer tracked
+	cm.AddMessage("s1", "user", "after close")
+	if len(saver.dirty) != 0 {
+		t.Errorf("dirty sessions after Close = %v, want none", saver.dirty)
+	}
+}
+
+func idleSession(cm *ChatManager, id string, idleFor time.Duration) {
+	cm.CreateSession(id, "")
+	cm.AddMessage(id, "user", "hello from "+id)
+	session, _ := cm.GetSession(id)
+	session.UpdatedAt = time.Now().Add(-idleFor)
+}
+
+func TestSweepIdleSummarizesOnceAndKeepsMain(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewChatManager(100)
+	saver, err := cm.EnableAutoSave(AutoSaveConfig{Dir: dir, Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("EnableAutoSave() error = %v", err)
+	}
+	defer saver.Close()
+
+	idleSession(cm, "main", 2*time.Hour)
+	idleSession(cm, "fresh", 0)
+	idleSession(cm, "idle", 2*time.Hour)
+	idleSession(cm, "summarized", 2*time.Hour)
+	summarized, _ := cm.GetSession("summarized")
+	summarized.Metadata[MetadataSummarizedAt] = summarized.UpdatedAt.Format(time.RFC3339Nano)
+
+	var hooked []string
+	archived, err := cm.SweepIdle(time.Hour, func(session ChatSession) error {
+		hooked = append(hooked, session.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SweepIdle() error = %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("SweepIdle() archived %d sessions, want 2", archived)
+	}
+	if len(hooked) != 1 || hooked[0] != "idle" {
+		t.Errorf("hook ran on %v, want only the unsummarized idle session", hooked)
+	}
+	for id, want := range map[string]bool{"main": true, "fresh": true, "idle": false, "summarized": false} {
+		if _, exists := cm.GetSession(id); exists != want {
+			t.Errorf("session %s in memory = %v, want %v", id, exists, want)
+		}
+	}
+
+	// Archived sessions are cold-stored and don't run the hook again
+	loaded := NewChatManager(100)
+	if _, err := loaded.LoadSessions(dir); err != nil {
+		t.Fatalf("LoadSessions() error = %v", err)
+	}
+	if _, exists := loaded.GetSession("idle"); !exists {
+		t.Fatal("archived session should be saved")
+	}
+	hooked = nil
+	if _, err := loaded.SweepIdle(0, func(session ChatSession) error {
+		hooked = append(hooked, session.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("SweepIdle() after reload error = %v", err)
+	}
+	if len(hooked) != 0 {
+		t.Errorf("hook ran again on %v after reload", hooked)
+	}
+}
+
+func TestSweepIdleKeepsSessionWhenHookFails(t *testing.T) {
+	cm := NewChatManager(100)
+	idleSession(cm, "main", 0)
+	idleSession(cm, "idle", 2*time.Hour)
+
+	archived, err := cm.SweepIdle(time.Hour, func(ChatSession) error {
+		return fmt.Errorf("model unavailable")
+	})
+	if err == nil || archived != 0 {
+		t.Errorf("SweepIdle() = %d, %v, want a failure", archived, err)
+	}
+	if _, exists := cm.GetSession("idle"); !exists {
+		t.Error("session should be kept when the hook fails")
+	}
+}
@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// MetadataClearedAt records when a session's messages were last cleared
+const MetadataClearedAt = "clearedAt"
+
+// ClearMessages empties a session's conversation while keeping the session,
+// its system prompt and its config. System messages other than the prune
+// marker are kept, and the per-conversation counters (tool rounds, prune
+// statistics) start over.
+func (cm *ChatManager) ClearMessages(sessionID string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.Messages = systemMessages(session.Messages)
+	session.ToolRounds = 0
+
+	if session.Metadata == nil {
+		session.Metadata = make(map[string]interface{})
+	}
+	for _, key := range []string{"prunedMessages", "pruneEvents", "lastPrunedAt"} {
+		delete(session.Metadata, key)
+	}
+	now := time.Now()
+	session.Metadata[MetadataClearedAt] = now
+	session.UpdatedAt = now
+	cm.markDirty(sessionID, 0)
+	return nil
+}
+
+// ClearMessages empties an enhanced session's conversation, keeping its system
+// messages, and resets its message count and token usage
+func (ecm *EnhancedChatManager) ClearMessages(sessionID string) error {
+	ecm.mu.Lock()
+	defer ecm.mu.Unlock()
+
+	session, exists := ecm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.Messages = systemMessages(session.Messages)
+	session.MessageCount = len(session.Messages)
+	session.TokenUsage = 0
+
+	if session.Metadata == nil {
+		session.Metadata = make(map[string]interface{})
+	}
+	now := time.Now()
+	session.Metadata[MetadataClearedAt] = now
+	session.UpdatedAt = now
+	return nil
+}
+
+// systemMessages returns the system messages of a conversation, without prune markers
+func systemMessages(messages []Message) []Message {
+	kept := make([]Message, 0)
+	for _, msg := range messages {
+		if msg.Role == "system" && !IsPruneMarker(msg) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"goclaw/pkg/ai"
 )
 
 // SessionState represents the current state of a session
@@ -26,6 +28,10 @@ type SessionConfig struct {
 	MaxMessages     int             // Maximum messages to keep
 	AutoCleanup     bool            // Enable auto-cleanup of old sessions
 	GroupRules      map[string]bool // Group-specific rules
+
+	ai.GenerationParams // Temperature, MaxTokens and TopP for this session; nil uses the provider default
+
+	Output OutputContract // Length and format the session's responses must satisfy
 }
 
 // EnhancedChatSession provides advanced session capabilities
@@ -56,6 +62,7 @@ type EnhancedChatManager struct {
 	config         SessionConfig
 	maxMemory      int
 	queue          []Message // For queue mode
+	mainDefaults   MainSessionDefaults
 }
 
 // NewEnhancedChatManager creates a new enhanced chat manager
@@ -68,6 +75,7 @@ func NewEnhancedChatManager(maxMemory int) *EnhancedChatManager {
 		sessions:      make(map[string]*EnhancedChatSession),
 		maxMemory:     maxMemory,
 		queue:         make([]Message, 0),
+		mainDefaults:  DefaultMainSessionDefaults(),
 		config: SessionConfig{
 			ActivationMode: "always",
 			QueueMode:       "immediate",
@@ -80,7 +88,8 @@ func NewEnhancedChatManager(maxMemory int) *EnhancedChatManager {
 	}
 }
 
-// CreateEnhancedSession creates a new enhanced session
+// CreateEnhancedSession creates a new enhanced session. The session also
+// becomes main when the main session defaults select it.
 func (ecm *EnhancedChatManager) CreateEnhancedSession(id, systemPrompt string, isMain bool) *EnhancedChatSession {
 	ecm.mu.Lock()
 	defer ecm.mu.Unlock()
@@ -97,7 +106,7 @@ func (ecm *EnhancedChatManager) CreateEnhancedSession(id, systemPrompt string, i
 		LastActiveTime: time.Now(),
 		MessageCount:   0,
 		TokenUsage:      0,
-		IsMainSession:  isMain,
+		IsMainSession:  false,
 		IsGroupSession: false,
 		GroupID:        "",
 		UserID:         "",
@@ -106,8 +115,8 @@ func (ecm *EnhancedChatManager) CreateEnhancedSession(id, systemPrompt string, i
 
 	ecm.sessions[id] = session
 
-	if isMain {
-		ecm.mainSessionID = id
+	if isMain || ecm.mainDefaults.makesMain(id, ecm.mainSessionID) {
+		ecm.setMainLocked(session)
 	}
 
 	return session
@@ -156,16 +165,8 @@ func (ecm *EnhancedChatManager) SetMainSession(id string) error {
 		return fmt.Errorf("session not found: %s", id)
 	}
 
-	// Update old main session
-	if ecm.mainSessionID != "" {
-		if oldMain, exists := ecm.sessions[ecm.mainSessionID]; exists {
-			oldMain.IsMainSession = false
-		}
-	}
-
-	// Set new main session
-	session.IsMainSession = true
-	ecm.mainSessionID = id
+	// Demote the old main session and set the new one
+	ecm.setMainLocked(session)
 
 	return nil
 }
@@ -208,9 +209,6 @@ func (ecm *EnhancedChatManager) AddEnhancedMessage(sessionID, role, content stri
 	session.MessageCount++
 	session.LastActiveTime = time.Now()
 
-	// Estimate token usage (rough estimate: 4 chars per token)
-	session.TokenUsage += int64(len(content) / 4)
-
 	// Prune old messages based on config
 	maxMessages := ecm.config.MaxMessages
 	if maxMessages <= 0 {
@@ -273,6 +271,10 @@ func (ecm *EnhancedChatManager) SetSessionConfig(id string, config SessionConfig
 	ecm.mu.Lock()
 	defer ecm.mu.Unlock()
 
+	if err := config.GenerationParams.Validate(); err != nil {
+		return err
+	}
+
 	session, exists := ecm.sessions[id]
 	if !exists {
 		return fmt.Errorf("session not found: %s", id)
@@ -334,6 +336,19 @@ func (ecm *EnhancedChatManager) ArchiveSession(id string) error {
 	return ecm.SetSessionState(id, SessionStateArchived)
 }
 
+// RecordTokenUsage adds the tokens a model call reported to a session's usage
+func (ecm *EnhancedChatManager) RecordTokenUsage(sessionID string, tokens int64) error {
+	ecm.mu.Lock()
+	defer ecm.mu.Unlock()
+
+	session, exists := ecm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	session.TokenUsage += tokens
+	return nil
+}
+
 // GetSessionStatistics returns overall session statistics
 func (ecm *EnhancedChatManager) GetSessionStatistics() map[string]interface{} {
 	ecm.mu.RLock()
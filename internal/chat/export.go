@@ -0,0 +1,218 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	ExportMarkdown = "markdown"
+	ExportJSON     = "json"
+)
+
+// roleLabels maps message roles to their markdown headings
+var roleLabels = map[string]string{
+	"user":      "User",
+	"assistant": "Assistant",
+	"system":    "System",
+}
+
+var (
+	// messageHeading matches "## User (2026-01-02T15:04:05Z)". The timestamp
+	// is required, so "## User" lines in a message body stay in the body.
+	messageHeading = regexp.MustCompile(`^## ([A-Za-z]+) \(([^)]*)\)$`)
+
+	// metadataComment matches the metadata line written under a heading
+	metadataComment = regexp.MustCompile(`^<!-- metadata: (.*) -->$`)
+)
+
+// ExportedSession is the JSON export of a session
+type ExportedSession struct {
+	ID        string                 `json:"id"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Messages  []Message              `json:"messages"`
+}
+
+// ExportSession exports a session as markdown or JSON
+func (cm *ChatManager) ExportSession(sessionID, format string) (string, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return "", fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	switch format {
+	case ExportMarkdown, "":
+		return MarshalMarkdown(session.ID, session.Messages), nil
+	case ExportJSON:
+		data, err := json.MarshalIndent(ExportedSession{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			Metadata:  session.Metadata,
+			Messages:  session.Messages,
+		}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ImportSession parses an exported conversation into a new session
+func (cm *ChatManager) ImportSession(sessionID, format, content string) (*ChatSession, error) {
+	var messages []Message
+	switch format {
+	case ExportMarkdown, "":
+		parsed, err := UnmarshalMarkdown(content)
+		if err != nil {
+			return nil, err
+		}
+		messages = parsed
+	case ExportJSON:
+		var exported ExportedSession
+		if err := json.Unmarshal([]byte(content), &exported); err != nil {
+			return nil, fmt.Errorf("invalid JSON export: %w", err)
+		}
+		for i, msg := range exported.Messages {
+			if _, ok := roleLabels[msg.Role]; !ok {
+				return nil, fmt.Errorf("message %d has unknown role: %q", i+1, msg.Role)
+			}
+		}
+		messages = exported.Messages
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in import")
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if _, exists := cm.sessions[sessionID]; exists {
+		return nil, fmt.Errorf("session already exists: %s", sessionID)
+	}
+
+	now := time.Now()
+	session := &ChatSession{
+		ID:        sessionID,
+		Messages:  messages,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Metadata:  map[string]interface{}{"imported": true},
+	}
+	cm.sessions[sessionID] = session
+	cm.markDirty(sessionID, len(messages))
+	return session, nil
+}
+
+// MarshalMarkdown renders messages as a markdown conversation
+func MarshalMarkdown(sessionID string, messages []Message) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Conversation %s\n", sessionID))
+
+	for _, msg := range messages {
+		label, ok := roleLabels[msg.Role]
+		if !ok {
+			label = msg.Role
+		}
+
+		sb.WriteString("\n## " + label + " (" + msg.Timestamp.Format(time.RFC3339Nano) + ")\n")
+
+		if len(msg.Metadata) > 0 {
+			if data, err := json.Marshal(msg.Metadata); err == nil {
+				sb.WriteString("<!-- metadata: " + string(data) + " -->\n")
+			}
+		}
+
+		sb.WriteString("\n" + msg.Content + "\n")
+	}
+
+	return sb.String()
+}
+
+// UnmarshalMarkdown parses a conversation written by MarshalMarkdown
+func UnmarshalMarkdown(content string) ([]Message, error) {
+	var messages []Message
+	var current *Message
+	var body []string
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.Trim(strings.Join(body, "\n"), "\n")
+		messages = append(messages, *current)
+		current, body = nil, nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		lineNo++
+
+		if match := messageHeading.FindStringSubmatch(line); match != nil {
+			ts, tsErr := time.Parse(time.RFC3339Nano, match[2])
+			if tsErr != nil && current != nil {
+				body = append(body, line) // "## Notes (see below)" in a message body
+				continue
+			}
+
+			role, err := parseRoleLabel(match[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if tsErr != nil {
+				return nil, fmt.Errorf("line %d: invalid timestamp %q", lineNo, match[2])
+			}
+
+			flush()
+			current = &Message{Role: role, Timestamp: ts}
+			continue
+		}
+
+		if current == nil {
+			continue // Title and anything before the first message
+		}
+
+		if len(body) == 0 && current.Metadata == nil {
+			if match := metadataComment.FindStringSubmatch(line); match != nil {
+				if err := json.Unmarshal([]byte(match[1]), &current.Metadata); err != nil {
+					return nil, fmt.Errorf("line %d: invalid metadata: %w", lineNo, err)
+				}
+				continue
+			}
+		}
+
+		body = append(body, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return messages, nil
+}
+
+// parseRoleLabel maps a markdown heading label back to a message role
+func parseRoleLabel(label string) (string, error) {
+	for role, roleLabel := range roleLabels {
+		if strings.EqualFold(label, roleLabel) {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role label: %q", label)
+}
@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// Metadata keys recorded by ForkSession and MergeSession
+const (
+	MetadataForkedFrom        = "forkedFrom"        // Session a fork was copied from
+	MetadataForkedAt          = "forkedAt"          // Number of messages the fork started with
+	MetadataMergedFrom        = "mergedFrom"        // Session a merged message came from
+	MetadataOriginalTimestamp = "originalTimestamp" // Timestamp of a merged message in its source session
+)
+
+// ForkSession copies a session's messages and settings into a new session,
+// so a tangent can be explored without changing the original
+func (cm *ChatManager) ForkSession(sourceID, forkID string) (*ChatSession, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	source, exists := cm.sessions[sourceID]
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sourceID)
+	}
+	if _, exists := cm.sessions[forkID]; exists {
+		return nil, fmt.Errorf("session already exists: %s", forkID)
+	}
+
+	now := time.Now()
+	fork := &ChatSession{
+		ID:           forkID,
+		Messages:     append([]Message(nil), source.Messages...),
+		SystemPrompt: source.SystemPrompt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Metadata: map[string]interface{}{
+			MetadataForkedFrom: sourceID,
+			MetadataForkedAt:   len(source.Messages),
+		},
+		Config: source.Config,
+	}
+	cm.sessions[forkID] = fork
+	cm.markDirty(forkID, len(fork.Messages))
+	return fork, nil
+}
+
+// MergeSession appends the source session's messages from fromIndex onto the
+// target. Merged messages are re-timestamped after the target's last message,
+// keeping their order, and remember where they came from. Messages the target
+// already has, such as those shared before a fork, are skipped.
+func (cm *ChatManager) MergeSession(sourceID, targetID string, fromIndex int) error {
+	if sourceID == targetID {
+		return fmt.Errorf("cannot merge a session into itself")
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	source, exists := cm.sessions[sourceID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sourceID)
+	}
+	target, exists := cm.sessions[targetID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", targetID)
+	}
+	if fromIndex < 0 || fromIndex > len(source.Messages) {
+		return fmt.Errorf("fromIndex %d is out of range for %d messages", fromIndex, len(source.Messages))
+	}
+
+	present := make(map[string]bool, len(target.Messages))
+	for _, msg := range target.Messages {
+		present[messageKey(msg)] = true
+	}
+
+	next := time.Now()
+	if n := len(target.Messages); n > 0 && !next.After(target.Messages[n-1].Timestamp) {
+		next = target.Messages[n-1].Timestamp.Add(time.Nanosecond)
+	}
+
+	merged := 0
+	for _, msg := range source.Messages[fromIndex:] {
+		if IsPruneMarker(msg) || present[messageKey(msg)] {
+			continue
+		}
+		present[messageKey(msg)] = true
+
+		metadata := make(map[string]interface{}, len(msg.Metadata)+2)
+		for key, value := range msg.Metadata {
+			metadata[key] = value
+		}
+		if _, ok := metadata[MetadataMergedFrom]; !ok {
+			metadata[MetadataMergedFrom] = sourceID
+			metadata[MetadataOriginalTimestamp] = msg.Timestamp.Format(time.RFC3339Nano)
+		}
+
+		target.Messages = append(target.Messages, Message{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Metadata:  metadata,
+			Timestamp: next,
+		})
+		next = next.Add(time.Nanosecond)
+		merged++
+	}
+
+	if merged > 0 {
+		cm.markDirty(targetID, merged)
+		target.UpdatedAt = time.Now()
+		if len(target.Messages) > cm.maxMemory {
+			cm.prune(target)
+		}
+	}
+	return nil
+}
+
+// messageKey identifies a message across sessions by role, content and its
+// original timestamp, so a message merged earlier is recognized again
+func messageKey(msg Message) string {
+	timestamp := msg.Timestamp.Format(time.RFC3339Nano)
+	if original, ok := msg.Metadata[MetadataOriginalTimestamp].(string); ok {
+		timestamp = original
+	}
+	return msg.Role + "\x00" + timestamp + "\x00" + msg.Content
+}
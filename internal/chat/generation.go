@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"fmt"
+
+	"goclaw/pkg/ai"
+)
+
+// SetGenerationParams sets the sampling parameters used for a session's
+// requests. The thinking level is kept as the session's ThinkingLevel.
+func (cm *ChatManager) SetGenerationParams(sessionID string, params ai.GenerationParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.Config.ThinkingLevel = params.Thinking
+	params.Thinking = ""
+	session.Config.GenerationParams = params
+	cm.markDirty(sessionID, 0)
+	return nil
+}
+
+// GetGenerationParams returns the sampling parameters of a session
+func (cm *ChatManager) GetGenerationParams(sessionID string) (ai.GenerationParams, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return ai.GenerationParams{}, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	params := session.Config.GenerationParams
+	params.Thinking = session.Config.ThinkingLevel
+	return params, nil
+}
@@ -0,0 +1,171 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MetadataSummarizedAt records the last update of a session the idle hook
+// handled, in RFC 3339 so it survives saving, so an unchanged session isn't
+// summarized twice
+const MetadataSummarizedAt = "summarizedAt"
+
+// DefaultJanitorInterval is how often the janitor looks for idle sessions
+const DefaultJanitorInterval = time.Minute
+
+// IdleHook runs on a copy of an idle session before the janitor archives it,
+// e.g. to move its durable facts into long-term memory. When it fails the
+// session is kept and the hook runs again on the next sweep.
+type IdleHook func(session ChatSession) error
+
+// JanitorConfig controls when idle sessions are archived
+type JanitorConfig struct {
+	IdleAfter time.Duration // Archive sessions not updated for this long
+	Interval  time.Duration // Sweep this often, DefaultJanitorInterval when 0
+	OnIdle    IdleHook      // Runs before a session is archived; nil archives right away
+}
+
+// StartJanitor archives idle sessions every config.Interval until the
+// returned stop function is called
+func (cm *ChatManager) StartJanitor(config JanitorConfig) (stop func()) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultJanitorInterval
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if archived, err := cm.SweepIdle(config.IdleAfter, config.OnIdle); err != nil {
+					fmt.Printf("Error archiving idle sessions: %v\n", err)
+				} else if archived > 0 {
+					fmt.Printf("Archived %d idle sessions\n", archived)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// SweepIdle archives every session other than the main one that wasn't
+// updated for idleAfter, running hook on it first unless it already ran on
+// the session's current state. It returns how many sessions were archived.
+func (cm *ChatManager) SweepIdle(idleAfter time.Duration, hook IdleHook) (int, error) {
+	cutoff := time.Now().Add(-idleAfter)
+
+	archived := 0
+	var failed []string
+	for _, id := range cm.idleSessions(cutoff) {
+		if err := cm.archiveIdle(id, cutoff, hook); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", id, err))
+			continue
+		}
+		archived++
+	}
+	if len(failed) > 0 {
+		return archived, fmt.Errorf("failed to archive sessions: %v", failed)
+	}
+	return archived, nil
+}
+
+// idleSessions lists the sessions that may be archived
+func (cm *ChatManager) idleSessions(cutoff time.Time) []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	var ids []string
+	for id, session := range cm.sessions {
+		if id != cm.mainSessionID && session.UpdatedAt.Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// archiveIdle runs the hook on a session and archives it. The session lock
+// keeps a chat request from adding to the session meanwhile.
+func (cm *ChatManager) archiveIdle(id string, cutoff time.Time, hook IdleHook) error {
+	release := cm.LockSession(id)
+	defer release()
+
+	cm.mu.RLock()
+	session, exists := cm.sessions[id]
+	var snapshot ChatSession
+	summarized := false
+	if exists {
+		snapshot = *session
+		snapshot.Messages = append([]Message(nil), session.Messages...)
+		snapshot.Metadata = make(map[string]interface{}, len(session.Metadata))
+		for key, value := range session.Metadata {
+			snapshot.Metadata[key] = value
+		}
+		summarized = summarizedSince(session.Metadata[MetadataSummarizedAt], session.UpdatedAt)
+	}
+	cm.mu.RUnlock()
+
+	// Gone or active again since the sweep started
+	if !exists || !snapshot.UpdatedAt.Before(cutoff) {
+		return nil
+	}
+
+	if hook != nil && !summarized {
+		if err := hook(snapshot); err != nil {
+			return err
+		}
+		if err := cm.SetMetadata(id, MetadataSummarizedAt, snapshot.UpdatedAt.Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	return cm.ArchiveSession(id)
+}
+
+// summarizedSince reports whether a summarizedAt value covers updatedAt
+func summarizedSince(value interface{}, updatedAt time.Time) bool {
+	text, ok := value.(string)
+	if !ok {
+		return false
+	}
+	at, err := time.Parse(time.RFC3339Nano, text)
+	return err == nil && !at.Before(updatedAt)
+}
+
+// ArchiveSession evicts a session from memory. With auto-save the session's
+// file is written and kept, so the session is cold-stored rather than
+// deleted and is loaded again on the next start.
+func (cm *ChatManager) ArchiveSession(id string) error {
+	cm.mu.RLock()
+	saver := cm.autoSaver
+	cm.mu.RUnlock()
+
+	if saver != nil {
+		if err := cm.SaveSession(saver.config.Dir, id); err != nil {
+			return err
+		}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if _, exists := cm.sessions[id]; !exists {
+		return fmt.Errorf("session not found: %s", id)
+	}
+	delete(cm.sessions, id)
+	if cm.mainSessionID == id {
+		cm.mainSessionID = ""
+	}
+	if cm.autoSaver != nil {
+		// A pending save would now remove the file
+		cm.autoSaver.forget(id)
+	}
+	return nil
+}
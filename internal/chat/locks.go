@@ -0,0 +1,47 @@
+package chat
+
+import "sync"
+
+// SessionLocks serializes work on each session while different sessions
+// proceed concurrently. Locks are released from the map when unused.
+type SessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewSessionLocks creates a new set of per-session locks
+func NewSessionLocks() *SessionLocks {
+	return &SessionLocks{
+		locks: make(map[string]*sessionLock),
+	}
+}
+
+// Lock blocks until the session's lock is held and returns a function that releases it
+func (l *SessionLocks) Lock(sessionID string) func() {
+	l.mu.Lock()
+	lock, exists := l.locks[sessionID]
+	if !exists {
+		lock = &sessionLock{}
+		l.locks[sessionID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, sessionID)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,87 @@
+package chat
+
+import "fmt"
+
+// MainSessionDefaults decides which newly created session becomes the main session
+type MainSessionDefaults struct {
+	AutoFirst bool   // The first session becomes main when none is set
+	SessionID string // Only this session becomes main automatically; overrides AutoFirst
+}
+
+// DefaultMainSessionDefaults makes the first session the main session
+func DefaultMainSessionDefaults() MainSessionDefaults {
+	return MainSessionDefaults{AutoFirst: true}
+}
+
+// makesMain reports whether a new session should become main given the current main session
+func (d MainSessionDefaults) makesMain(id, currentMain string) bool {
+	if d.SessionID != "" {
+		return id == d.SessionID
+	}
+	return d.AutoFirst && currentMain == ""
+}
+
+// SetMainSessionDefaults sets which new sessions become main automatically
+func (cm *ChatManager) SetMainSessionDefaults(defaults MainSessionDefaults) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.mainDefaults = defaults
+}
+
+// SetMainSession designates the main session, demoting the previous one.
+// It returns the ID of the previous main session, if any.
+func (cm *ChatManager) SetMainSession(id string) (string, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	session, exists := cm.sessions[id]
+	if !exists {
+		return "", fmt.Errorf("session not found: %s", id)
+	}
+
+	previous := cm.mainSessionID
+	cm.setMainLocked(session)
+	return previous, nil
+}
+
+// GetMainSession returns the main session
+func (cm *ChatManager) GetMainSession() (*ChatSession, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.mainSessionID == "" {
+		return nil, fmt.Errorf("no main session set")
+	}
+
+	session, exists := cm.sessions[cm.mainSessionID]
+	if !exists {
+		return nil, fmt.Errorf("main session not found: %s", cm.mainSessionID)
+	}
+
+	return session, nil
+}
+
+// setMainLocked makes session the only main session; the caller holds the lock
+func (cm *ChatManager) setMainLocked(session *ChatSession) {
+	if previous, exists := cm.sessions[cm.mainSessionID]; exists {
+		previous.IsMain = false
+	}
+	session.IsMain = true
+	cm.mainSessionID = session.ID
+}
+
+// SetMainSessionDefaults sets which new sessions become main automatically
+func (ecm *EnhancedChatManager) SetMainSessionDefaults(defaults MainSessionDefaults) {
+	ecm.mu.Lock()
+	defer ecm.mu.Unlock()
+	ecm.mainDefaults = defaults
+}
+
+// setMainLocked makes session the only main session; the caller holds the lock
+func (ecm *EnhancedChatManager) setMainLocked(session *EnhancedChatSession) {
+	if previous, exists := ecm.sessions[ecm.mainSessionID]; exists {
+		previous.IsMainSession = false
+	}
+	session.IsMainSession = true
+	ecm.mainSessionID = session.ID
+}
@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Response formats of an output contract
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// maxContractRewrites is how many times the model is asked to rewrite a
+// response that breaks the contract before falling back
+const maxContractRewrites = 1
+
+// ErrContractUnmet is returned when a response can't be made to satisfy its
+// session's output contract
+var ErrContractUnmet = errors.New("response does not satisfy the output contract")
+
+// OutputContract constrains a session's responses for channels that can only
+// take some shapes of reply, such as SMS or a JSON consumer. The zero value
+// accepts any response.
+type OutputContract struct {
+	MaxChars int    `json:"maxChars,omitempty"` // Longest response in characters, 0 for no limit
+	Format   string `json:"format,omitempty"`   // "text", "json" or "markdown"; empty is text
+}
+
+// Rewriter asks the model to rewrite a response following instruction
+type Rewriter func(ctx context.Context, instruction string) (string, error)
+
+// Validate checks the contract's limit and format
+func (c OutputContract) Validate() error {
+	if c.MaxChars < 0 {
+		return fmt.Errorf("maxChars must not be negative, got %d", c.MaxChars)
+	}
+	switch c.Format {
+	case "", FormatText, FormatJSON, FormatMarkdown:
+		return nil
+	}
+	return fmt.Errorf("format must be %q, %q or %q, got %q", FormatText, FormatJSON, FormatMarkdown, c.Format)
+}
+
+// IsZero reports whether the contract accepts any response
+func (c OutputContract) IsZero() bool {
+	return c.MaxChars == 0 && (c.Format == "" || c.Format == FormatText)
+}
+
+// Instruction describes the contract to the model, so the first response
+// already tends to satisfy it. It is empty for the zero contract.
+func (c OutputContract) Instruction() string {
+	var rules []string
+	switch c.Format {
+	case FormatJSON:
+		rules = append(rules, "Reply with valid JSON only, without code fences or any text around it.")
+	case FormatMarkdown:
+		rules = append(rules, "Format the reply as Markdown.")
+	}
+	if c.MaxChars > 0 {
+		rules = append(rules, fmt.Sprintf("Keep the reply under %d characters.", c.MaxChars))
+	}
+	return strings.Join(rules, " ")
+}
+
+// Check returns why response breaks the contract, or nil when it satisfies it
+func (c OutputContract) Check(response string) error {
+	if c.Format == FormatJSON && !json.Valid([]byte(response)) {
+		return fmt.Errorf("response is not valid JSON")
+	}
+	if c.MaxChars > 0 {
+		if chars := utf8.RuneCountInString(response); chars > c.MaxChars {
+			return fmt.Errorf("response is %d characters, over the limit of %d", chars, c.MaxChars)
+		}
+	}
+	return nil
+}
+
+// Enforce makes response satisfy the contract, reporting whether it had to
+// change it. It repairs JSON wrapped in code fences or prose, then asks
+// rewrite (when not nil) for a compliant version, and as a last resort
+// truncates text that is too long. JSON that stays invalid or too long fails
+// with ErrContractUnmet, since cutting it would only break it further.
+func (c OutputContract) Enforce(ctx context.Context, response string, rewrite Rewriter) (string, bool, error) {
+	if c.Check(response) == nil {
+		return response, false, nil
+	}
+	if repaired := c.repair(response); c.Check(repaired) == nil {
+		return repaired, true, nil
+	}
+
+	for attempt := 0; rewrite != nil && attempt < maxContractRewrites; attempt++ {
+		rewritten, err := rewrite(ctx, c.rewriteInstruction(response, c.Check(response)))
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", true, err
+			}
+			break
+		}
+		response = c.repair(rewritten)
+		if c.Check(response) == nil {
+			return response, true, nil
+		}
+	}
+
+	if c.Format != FormatJSON && c.MaxChars > 0 {
+		if truncated := truncateChars(strings.TrimSpace(response), c.MaxChars); c.Check(truncated) == nil {
+			return truncated, true, nil
+		}
+	}
+	return "", true, fmt.Errorf("%w: %v", ErrContractUnmet, c.Check(response))
+}
+
+// rewriteInstruction asks for response rewritten to fix violation
+func (c OutputContract) rewriteInstruction(response string, violation error) string {
+	return fmt.Sprintf("Your previous reply broke the required output format: %v. %s\nRewrite it keeping its meaning, and reply with the rewritten text only.\n\nPrevious reply:\n%s",
+		violation, c.Instruction(), response)
+}
+
+// repair fixes what can be fixed without the model: surrounding whitespace,
+// and for JSON, code fences or prose around the value
+func (c OutputContract) repair(response string) string {
+	response = strings.TrimSpace(response)
+	if c.Format != FormatJSON || json.Valid([]byte(response)) {
+		return response
+	}
+
+	if strings.HasPrefix(response, "```") {
+		unfenced := strings.TrimPrefix(response, "```")
+		unfenced = strings.TrimPrefix(unfenced, "json")
+		unfenced = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(unfenced), "```"))
+		if json.Valid([]byte(unfenced)) {
+			return unfenced
+		}
+	}
+
+	// The outermost object or array in the text
+	for _, brackets := range []string{"{}", "[]"} {
+		start := strings.IndexByte(response, brackets[0])
+		end := strings.LastIndexByte(response, brackets[1])
+		if start >= 0 && end > start && json.Valid([]byte(response[start:end+1])) {
+			return response[start : end+1]
+		}
+	}
+	return response
+}
+
+// truncateChars cuts text to at most max characters, ending with an ellipsis
+// when it was cut
+func truncateChars(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	if max == 1 {
+		return string(runes[:1])
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// SetOutputContract sets the contract a session's responses must satisfy
+func (cm *ChatManager) SetOutputContract(sessionID string, contract OutputContract) error {
+	if err := contract.Validate(); err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.Config.Output = contract
+	cm.markDirty(sessionID, 0)
+	return nil
+}
+
+// GetOutputContract returns the contract of a session's responses
+func (cm *ChatManager) GetOutputContract(sessionID string) (OutputContract, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return OutputContract{}, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	return session.Config.Output, nil
+}
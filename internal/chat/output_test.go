@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// scriptedRewriter returns replies in order and records the instructions
+type scriptedRewriter struct {
+	replies      []string
+	instructions []string
+}
+
+func (s *scriptedRewriter) rewrite(ctx context.Context, instruction string) (string, error) {
+	s.instructions = append(s.instructions, instruction)
+	if len(s.replies) == 0 {
+		return "", errors.New("no more replies")
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply, nil
+}
+
+func TestOutputContractLength(t *testing.T) {
+	contract := OutputContract{MaxChars: 20}
+	long := "This reply is far too long to go out as a single text message."
+
+	// A short reply passes untouched
+	if got, enforced, err := contract.Enforce(context.Background(), "Short enough.", nil); err != nil || enforced || got != "Short enough." {
+		t.Errorf("Enforce(short) = %q, %v, %v; want it unchanged", got, enforced, err)
+	}
+
+	// The rewrite is used when it fits
+	rewriter := &scriptedRewriter{replies: []string{"Too long, sorry."}}
+	got, enforced, err := contract.Enforce(context.Background(), long, rewriter.rewrite)
+	if err != nil || !enforced || got != "Too long, sorry." {
+		t.Errorf("Enforce(long) = %q, %v, %v; want the rewrite", got, enforced, err)
+	}
+	if len(rewriter.instructions) != 1 || !strings.Contains(rewriter.instructions[0], "under 20 characters") {
+		t.Errorf("rewrite instructions = %q, want one asking for under 20 characters", rewriter.instructions)
+	}
+
+	// A rewrite that is still too long is truncated
+	rewriter = &scriptedRewriter{replies: []string{long}}
+	got, enforced, err = contract.Enforce(context.Background(), long, rewriter.rewrite)
+	if err != nil || !enforced {
+		t.Fatalf("Enforce(long, bad rewrite) = %q, %v, %v", got, enforced, err)
+	}
+	if chars := utf8.RuneCountInString(got); chars > 20 || !strings.HasSuffix(got, "…") {
+		t.Errorf("truncated reply %q has %d characters, want at most 20 ending in an ellipsis", got, chars)
+	}
+}
+
+func TestOutputContractJSON(t *testing.T) {
+	contract := OutputContract{Format: FormatJSON}
+
+	// Code fences and prose around the value are stripped without a rewrite
+	rewriter := &scriptedRewriter{}
+	fenced := "Here you go:\n```json\n{\"ok\": true}\n```"
+	got, enforced, err := contract.Enforce(context.Background(), fenced, rewriter.rewrite)
+	if err != nil || !enforced || got != `{"ok": true}` {
+		t.Errorf("Enforce(fenced) = %q, %v, %v; want the bare object", got, enforced, err)
+	}
+	if len(rewriter.instructions) != 0 {
+		t.Errorf("local repair should not ask for a rewrite, got %d", len(rewriter.instructions))
+	}
+
+	// Invalid JSON is repaired by the rewrite retry
+	rewriter = &scriptedRewriter{replies: []string{`{"answer": 42}`}}
+	got, enforced, err = contract.Enforce(context.Background(), `{"answer": 42`, rewriter.rewrite)
+	if err != nil || !enforced || got != `{"answer": 42}` {
+		t.Errorf("Enforce(broken) = %q, %v, %v; want the rewritten object", got, enforced, err)
+	}
+	if len(rewriter.instructions) != 1 || !strings.Contains(rewriter.instructions[0], "not valid JSON") {
+		t.Errorf("rewrite instructions = %q, want one naming the JSON error", rewriter.instructions)
+	}
+
+	// JSON that stays invalid fails rather than going out broken
+	rewriter = &scriptedRewriter{replies: []string{"still not json"}}
+	if _, _, err := contract.Enforce(context.Background(), "not json", rewriter.rewrite); !errors.Is(err, ErrContractUnmet) {
+		t.Errorf("Enforce(unrepairable) error = %v, want ErrContractUnmet", err)
+	}
+}
+
+func TestOutputContractValidate(t *testing.T) {
+	for _, contract := range []OutputContract{{MaxChars: -1}, {Format: "xml"}} {
+		if err := contract.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", contract)
+		}
+	}
+
+	cm := NewChatManager(10)
+	cm.CreateSession("sms", "")
+	if err := cm.SetOutputContract("sms", OutputContract{MaxChars: 160}); err != nil {
+		t.Fatalf("SetOutputContract: %v", err)
+	}
+	if got, _ := cm.GetOutputContract("sms"); got.MaxChars != 160 {
+		t.Errorf("GetOutputContract = %+v, want maxChars 160", got)
+	}
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// titleMaxRunes is the length of a title derived from the first user message
+	titleMaxRunes = 50
+
+	// previewMaxRunes is the length of the last-message preview
+	previewMaxRunes = 120
+)
+
+// SessionSummary describes a session for resume pickers and listings
+type SessionSummary struct {
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	User          string    `json:"user,omitempty"`
+	MessageCount  int       `json:"messageCount"`
+	LastMessageAt time.Time `json:"lastMessageAt"`
+	Preview       string    `json:"preview"` // Last assistant message, truncated
+	IsMain        bool      `json:"isMain,omitempty"`
+}
+
+// SetMetadata sets a metadata value on a session (e.g. "user" or "title")
+func (cm *ChatManager) SetMetadata(sessionID, key string, value interface{}) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	session, exists := cm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	session.Metadata[key] = value
+	cm.markDirty(sessionID, 0)
+	return nil
+}
+
+// RecentSessions returns the most recently active sessions, newest first.
+// When user is non-empty only sessions tagged with that user are returned.
+// A limit of zero or less returns all matching sessions.
+func (cm *ChatManager) RecentSessions(user string, limit int) []SessionSummary {
+	cm.mu.RLock()
+	summaries := make([]SessionSummary, 0, len(cm.sessions))
+	for _, session := range cm.sessions {
+		owner, _ := session.Metadata["user"].(string)
+		if user != "" && owner != user {
+			continue
+		}
+		summaries = append(summaries, summarize(session, owner))
+	}
+	cm.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].LastMessageAt.Equal(summaries[j].LastMessageAt) {
+			return summaries[i].LastMessageAt.After(summaries[j].LastMessageAt)
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+
+	if limit > 0 && len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries
+}
+
+// summarize builds the summary of a session; the caller holds the lock
+func summarize(session *ChatSession, owner string) SessionSummary {
+	summary := SessionSummary{
+		ID:            session.ID,
+		User:          owner,
+		MessageCount:  len(session.Messages),
+		LastMessageAt: session.UpdatedAt,
+		IsMain:        session.IsMain,
+	}
+
+	if n := len(session.Messages); n > 0 {
+		summary.LastMessageAt = session.Messages[n-1].Timestamp
+	}
+
+	if title, ok := session.Metadata["title"].(string); ok && title != "" {
+		summary.Title = title
+	}
+
+	for _, msg := range session.Messages {
+		if summary.Title == "" && msg.Role == "user" {
+			summary.Title = truncateRunes(msg.Content, titleMaxRunes)
+		}
+		if msg.Role == "assistant" {
+			summary.Preview = msg.Content
+		}
+	}
+	summary.Preview = truncateRunes(summary.Preview, previewMaxRunes)
+
+	if summary.Title == "" {
+		summary.Title = session.ID
+	}
+	return summary
+}
+
+// truncateRunes shortens text to at most n runes on a single line
+func truncateRunes(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,33 @@
+package chat
+
+// SessionToolRounds returns the agent tool-call rounds recorded for a session
+// since the model last answered without calling a tool
+func (cm *ChatManager) SessionToolRounds(sessionID string) int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if session, exists := cm.sessions[sessionID]; exists {
+		return session.ToolRounds
+	}
+	return 0
+}
+
+// AddSessionToolRound records one agent tool-call round for a session
+func (cm *ChatManager) AddSessionToolRound(sessionID string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if session, exists := cm.sessions[sessionID]; exists {
+		session.ToolRounds++
+	}
+}
+
+// ResetSessionToolRounds clears the agent tool-call rounds of a session
+func (cm *ChatManager) ResetSessionToolRounds(sessionID string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if session, exists := cm.sessions[sessionID]; exists {
+		session.ToolRounds = 0
+	}
+}
@@ -4,6 +4,7 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"sort"
 )
 
 // Config represents the main configuration
@@ -13,16 +14,48 @@ type Config struct {
 	Gateway   GatewayConfig           `json:"gateway,omitempty"`
 	Models    map[string]interface{}  `json:"models,omitempty"`
 	Zhipu     ZhipuConfig             `json:"zhipu,omitempty"`
+	Embedding EmbeddingConfig         `json:"embedding,omitempty"`
 	Heartbeat HeartbeatConfig         `json:"heartbeat,omitempty"`
 	Identity  map[string]string       `json:"identity,omitempty"`
+	Redaction RedactionConfig         `json:"redaction,omitempty"`
+	Tools     ToolsConfig             `json:"tools,omitempty"`
+	Sessions  SessionsConfig          `json:"sessions,omitempty"`
+	External  ExternalConfig          `json:"external,omitempty"`
+	Memory    MemoryConfig            `json:"memory,omitempty"`
+	Cron      CronConfig              `json:"cron,omitempty"`
 }
 
 // AgentConfig holds agent-specific configuration
 type AgentConfig struct {
-	Model     string        `json:"model,omitempty"`
-	Workspace string        `json:"workspace,omitempty"`
-	Sandbox   SandboxConfig `json:"sandbox,omitempty"`
-	Defaults  AgentDefaults `json:"defaults,omitempty"`
+	Model                string            `json:"model,omitempty"`
+	Workspace            string            `json:"workspace,omitempty"`
+	Greeting             string            `json:"greeting,omitempty"`             // Custom welcome message, overrides the identity greeting
+	MaxToolRounds        int               `json:"maxToolRounds,omitempty"`        // Per-turn cap on tool-call rounds
+	MaxSessionToolRounds int               `json:"maxSessionToolRounds,omitempty"` // Per-session cap on consecutive tool-call rounds, across turns, without a direct answer
+	MaxPromptTokens      int               `json:"maxPromptTokens,omitempty"`      // Upper bound on the assembled prompt, 0 for no limit
+	MaxToolRetries       int               `json:"maxToolRetries,omitempty"`       // Retries of a failing tool call; negative disables retries
+	ToolCalling          string            `json:"toolCalling,omitempty"`          // Tool-call format: "text" (JSON in the reply), "openai" or "anthropic"; the provider's native format by default
+	PruneMarker          *string           `json:"pruneMarker,omitempty"`          // Note left when history is pruned, with {count} for the omitted messages; "" disables it
+	SerialPipeline       bool              `json:"serialPipeline,omitempty"`       // Gather memory context and history one after another instead of concurrently
+	DuplicateWindow      string            `json:"duplicateWindow,omitempty"`      // Answer a message resent within this long (e.g., "10s") with the previous response instead of generating again; off by default
+	DuplicateThreshold   float64           `json:"duplicateThreshold,omitempty"`   // Similarity (0-1, shared words) from which a resent message is a duplicate, defaults to 1 (identical)
+	PromptPrefix         string            `json:"promptPrefix,omitempty"`         // Text injected before every user message in the prompt, never stored in history
+	PromptSuffix         string            `json:"promptSuffix,omitempty"`         // Text injected after every user message in the prompt, e.g. "answer in one sentence"
+	ModelTiers           map[string]string `json:"modelTiers,omitempty"`           // Model per message tier ("cheap", "standard", "strong"); routes chat messages by tier when set
+	Timeouts             TimeoutsConfig    `json:"timeouts,omitempty"`             // AI call timeouts per use case
+	StreamKeepalive      string            `json:"streamKeepalive,omitempty"`      // Interval of keepalive comments while a stream waits for the model, "5s" by default; "0" disables them
+	StreamFallbacks      int               `json:"streamFallbacks,omitempty"`      // Times a stream that fails mid-answer is continued with another provider, 0 to end it with a resumable error
+	Sandbox              SandboxConfig     `json:"sandbox,omitempty"`
+	Defaults             AgentDefaults     `json:"defaults,omitempty"`
+}
+
+// TimeoutsConfig holds AI call timeouts per use case as durations like "90s";
+// empty values use the defaults
+type TimeoutsConfig struct {
+	Interactive string `json:"interactive,omitempty"` // Chat and streamed replies, 120s by default
+	Batch       string `json:"batch,omitempty"`       // Each item of a batch or eval run, 30s by default
+	Heartbeat   string `json:"heartbeat,omitempty"`   // Heartbeat runs, 60s by default
+	Cron        string `json:"cron,omitempty"`        // Cron-triggered generations, 60s by default
 }
 
 // AgentDefaults holds default agent settings
@@ -38,6 +71,20 @@ type GatewayConfig struct {
 	Tailscale   TailscaleConfig        `json:"tailscale,omitempty"`
 	Auth        AuthConfig             `json:"auth,omitempty"`
 	Credentials map[string]interface{} `json:"credentials,omitempty"`
+	CORS        CORSConfig             `json:"cors,omitempty"`
+
+	// TrustProxy believes the X-Forwarded-For and X-Real-IP headers of
+	// requests from TrustedProxies (CIDRs or IPs) when resolving client IPs.
+	// Leave it off unless the gateway is only reachable through those proxies.
+	TrustProxy     bool     `json:"trustProxy,omitempty"`
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+}
+
+// CORSConfig holds the CORS policy of the API; without allowed origins no
+// CORS headers are sent
+type CORSConfig struct {
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"` // Origins allowed to call the API, "*" for any
+	MaxAge         string   `json:"maxAge,omitempty"`         // How long browsers cache preflight responses (e.g., "10m"), defaults to 24h
 }
 
 // TailscaleConfig holds Tailscale-related configuration
@@ -49,10 +96,18 @@ type TailscaleConfig struct {
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	Mode           string   `json:"mode,omitempty"` // "off", "password", "oauth"
-	Password       string   `json:"password,omitempty"`
-	AllowTailscale bool     `json:"allowTailscale,omitempty"`
-	Users          []string `json:"users,omitempty"`
+	Mode           string         `json:"mode,omitempty"` // "off", "password", "oauth", "apikey"
+	Password       string         `json:"password,omitempty"`
+	AllowTailscale bool           `json:"allowTailscale,omitempty"`
+	Users          []string       `json:"users,omitempty"`
+	APIKeys        []APIKeyConfig `json:"apiKeys,omitempty"` // Keys accepted when mode is "apikey"
+}
+
+// APIKeyConfig declares an API key and the scopes it grants
+type APIKeyConfig struct {
+	Key    string   `json:"key"`
+	Name   string   `json:"name,omitempty"`
+	Scopes []string `json:"scopes,omitempty"` // e.g. "tools:read", "tools:*" or "*"
 }
 
 // SandboxConfig holds sandbox configuration
@@ -70,14 +125,147 @@ type ZhipuConfig struct {
 	BaseURL string `json:"baseUrl,omitempty"` // Custom base URL if needed
 }
 
+// EmbeddingConfig declares the embedding provider, mirroring the chat provider shape
+type EmbeddingConfig struct {
+	API     string `json:"api,omitempty"`     // "ollama", "openai" (OpenAI-compatible) or "zhipu"
+	ApiKey  string `json:"apiKey,omitempty"`  // API key for hosted providers
+	BaseURL string `json:"baseUrl,omitempty"` // Provider endpoint, uses the provider default if empty
+	Model   string `json:"model,omitempty"`   // Embedding model name
+
+	// ReducedDimensions projects stored embeddings down to this many
+	// dimensions: less memory and faster search, slightly lower recall.
+	// 0 (the default) keeps full embeddings.
+	ReducedDimensions int `json:"reducedDimensions,omitempty"`
+
+	// Fallbacks are embedders tried in order when the one above fails, e.g.
+	// a local Ollama model behind a cloud API. They must produce embeddings
+	// of the same dimension; one that doesn't is skipped.
+	Fallbacks []EmbeddingProvider `json:"fallbacks,omitempty"`
+}
+
+// EmbeddingProvider declares one fallback embedder
+type EmbeddingProvider struct {
+	API     string `json:"api,omitempty"`     // "ollama", "openai" (OpenAI-compatible) or "zhipu"
+	ApiKey  string `json:"apiKey,omitempty"`  // API key for hosted providers
+	BaseURL string `json:"baseUrl,omitempty"` // Provider endpoint, uses the provider default if empty
+	Model   string `json:"model,omitempty"`   // Embedding model name
+}
+
+// ProviderEmbedding derives an embedding provider from the chat providers
+// for configs without an "embedding" section: Zhipu AI when its API key is
+// set, else the first, by name, of models.providers speaking the OpenAI API
+// with an API key. Such a provider may set "embeddingModel"; otherwise the
+// embedder's default model is used. ok is false when no provider fits.
+func ProviderEmbedding(cfg *Config) (embedding EmbeddingConfig, ok bool) {
+	if cfg.Zhipu.ApiKey != "" {
+		return EmbeddingConfig{API: "zhipu", ApiKey: cfg.Zhipu.ApiKey, BaseURL: cfg.Zhipu.BaseURL}, true
+	}
+
+	providers, _ := cfg.Models["providers"].(map[string]interface{})
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		provider, _ := providers[name].(map[string]interface{})
+		api, _ := provider["api"].(string)
+		apiKey, _ := provider["apiKey"].(string)
+		if api != "openai-completions" || apiKey == "" {
+			continue
+		}
+		baseURL, _ := provider["baseUrl"].(string)
+		model, _ := provider["embeddingModel"].(string)
+		return EmbeddingConfig{API: "openai", ApiKey: apiKey, BaseURL: baseURL, Model: model}, true
+	}
+	return EmbeddingConfig{}, false
+}
+
+// RedactionConfig adds secret field names and patterns to the built-in ones
+type RedactionConfig struct {
+	Fields   []string `json:"fields,omitempty"`   // Field names whose values are masked
+	Patterns []string `json:"patterns,omitempty"` // Regexes for secrets in free text
+}
+
+// ToolsConfig holds builtin tool settings
+type ToolsConfig struct {
+	FileDenylist  []string          `json:"fileDenylist,omitempty"`  // Globs of files the read tool refuses; replaces the default list when set
+	FileAllowlist []string          `json:"fileAllowlist,omitempty"` // Absolute files or directories the read tool may read outside the sandboxed workspace
+	RedactOutput  *bool             `json:"redactOutput,omitempty"`  // Mask secrets in file tool output, defaults to true
+	Scopes        map[string]string `json:"scopes,omitempty"`        // Tool name to required API key scope, overriding "tools:<name>"
+	Webhooks      map[string]string `json:"webhooks,omitempty"`      // Webhook URLs by name that the webhook tool may call
+	SystemInfo    []string          `json:"systemInfo,omitempty"`    // Fields the system_info tool returns, defaults to all of them
+	MaxDepth      int               `json:"maxDepth,omitempty"`      // How deeply tool calls may nest, e.g. a command calling back into the tool API; defaults to 5
+}
+
+// SessionsConfig holds chat session defaults
+type SessionsConfig struct {
+	AutoMain         *bool  `json:"autoMain,omitempty"`         // Make the first session the main session, defaults to true
+	MainSession      string `json:"mainSession,omitempty"`      // Session ID that becomes main when created, instead of the first
+	Dir              string `json:"dir,omitempty"`              // Directory sessions are saved to and loaded from; empty keeps them in memory only
+	AutoSaveInterval string `json:"autoSaveInterval,omitempty"` // How often changed sessions are saved (e.g., "5s"), defaults to 5s
+	AutoSaveMessages int    `json:"autoSaveMessages,omitempty"` // Save sooner once this many messages were added, defaults to 20
+	IdleAfter        string `json:"idleAfter,omitempty"`        // Archive sessions idle this long (e.g., "2h"); empty keeps them
+	IdleSummary      bool   `json:"idleSummary,omitempty"`      // Distill an idle session's facts into long-term memory before archiving it; costs a model call
+	IdleSummaryPin   bool   `json:"idleSummaryPin,omitempty"`   // Pin the distilled facts
+}
+
+// CronConfig holds scheduled task settings
+type CronConfig struct {
+	File string `json:"file,omitempty"` // File tasks are loaded from at startup and saved to on every change, defaults to ~/.openclaw/workspace/goclaw_cron.json
+}
+
+// ExternalConfig holds timeout and retry settings for calls to external
+// services such as webhooks, kept separate from the AI provider settings
+type ExternalConfig struct {
+	ExternalCallConfig
+	Targets map[string]ExternalCallConfig `json:"targets,omitempty"` // Overrides by target name
+}
+
+// ExternalCallConfig is the timeout and retry policy of external calls
+type ExternalCallConfig struct {
+	Timeout    string `json:"timeout,omitempty"`    // Per-attempt timeout, e.g. "10s"
+	MaxRetries *int   `json:"maxRetries,omitempty"` // Retries of idempotent requests, 0 disables retries
+	Backoff    string `json:"backoff,omitempty"`    // Delay before the first retry, doubled for each further one
+}
+
+// MemoryConfig holds what the chat API stores in memory
+type MemoryConfig struct {
+	CaptureAssistant bool `json:"captureAssistant,omitempty"` // Also store assistant responses in long-term memory, tagged "assistant"
+	MinCaptureChars  int  `json:"minCaptureChars,omitempty"`  // Shortest assistant response worth storing, defaults to 40 characters
+	MinMessageChars  int  `json:"minMessageChars,omitempty"`  // Shortest user message embedded and stored, a CJK character counting as 3; defaults to 8, negative only skips acknowledgments like "ok"
+	ConsolidateBatch int  `json:"consolidateBatch,omitempty"` // Memories embedded per request when consolidating, defaults to 16
+	SessionScoped    bool `json:"sessionScoped,omitempty"`    // Build a chat's memory context only from memories of its session
+
+	ConsolidateAfter    string `json:"consolidateAfter,omitempty"`    // Age at which short-term memories move to long-term (e.g., "1h"), defaults to 1h
+	ConsolidateInterval string `json:"consolidateInterval,omitempty"` // How often memories are consolidated (e.g., "10m"), defaults to 10m; "0" turns it off
+	ShortTermTTL        string `json:"shortTermTTL,omitempty"`        // Age at which unconsolidated short-term memories are forgotten (e.g., "2h"), never by default
+
+	ContextLongTermK  int `json:"contextLongTermK,omitempty"`  // Long-term memories injected into a chat prompt, defaults to 5
+	ContextShortTermK int `json:"contextShortTermK,omitempty"` // Recent short-term memories injected into a chat prompt, defaults to 10
+
+	ContextShortTermMaxAge string `json:"contextShortTermMaxAge,omitempty"` // Oldest short-term memory injected into a chat prompt (e.g., "1h"), no limit by default
+
+	Language          string  `json:"language,omitempty"`          // Canonical language of long-term memory, e.g. "en"
+	Translate         bool    `json:"translate,omitempty"`         // Translate memories and queries into language with the chat model, one call each
+	SameLanguageBoost float64 `json:"sameLanguageBoost,omitempty"` // Added to the score of memories in the query's language
+
+	File string `json:"file,omitempty"` // File memory is loaded from at startup and saved to at shutdown, defaults to ~/.openclaw/workspace/goclaw_memory.json
+
+	VectorFile         string `json:"vectorFile,omitempty"`         // File the vector store is loaded from at startup and saved to, defaults to ~/.openclaw/workspace/goclaw_vectors.json
+	VectorSaveInterval string `json:"vectorSaveInterval,omitempty"` // How often a changed vector store is saved (e.g., "5m"), defaults to 5m
+}
+
 // HeartbeatConfig holds heartbeat configuration
 type HeartbeatConfig struct {
-	Enabled bool   `json:"enabled,omitempty"` // Whether heartbeat is enabled
-	Interval string `json:"interval,omitempty"` // How often to check (e.g., "30m", "1h")
-	Prompt  string `json:"prompt,omitempty"`  // The heartbeat prompt to use
-	Target  string `json:"target,omitempty"`  // Target for heartbeat responses
-	Model   string `json:"model,omitempty"`   // Model to use for heartbeat processing
-	AckMaxChars int `json:"ackMaxChars,omitempty"` // Max chars for heartbeat acknowledgments
+	Enabled     bool   `json:"enabled,omitempty"`     // Whether heartbeat is enabled
+	Interval    string `json:"interval,omitempty"`    // How often to check (e.g., "30m", "1h")
+	Prompt      string `json:"prompt,omitempty"`      // The heartbeat prompt to use
+	Target      string `json:"target,omitempty"`      // Target for heartbeat responses
+	Model       string `json:"model,omitempty"`       // Model to use for heartbeat processing
+	Provider    string `json:"provider,omitempty"`    // Provider to use for heartbeats, defaults to the main client
+	AckMaxChars int    `json:"ackMaxChars,omitempty"` // Max chars for heartbeat acknowledgments
+	CheckOff    bool   `json:"checkOff,omitempty"`    // Check off completed tasks in HEARTBEAT.md
 }
 
 // LoadConfig loads configuration from a JSON file
@@ -57,6 +57,66 @@ func MergeConfigs(global, local *Config) *Config {
 	if local.Agent.Workspace != "" {
 		merged.Agent.Workspace = local.Agent.Workspace
 	}
+	if local.Agent.Greeting != "" {
+		merged.Agent.Greeting = local.Agent.Greeting
+	}
+	if local.Agent.MaxToolRounds != 0 {
+		merged.Agent.MaxToolRounds = local.Agent.MaxToolRounds
+	}
+	if local.Agent.MaxSessionToolRounds != 0 {
+		merged.Agent.MaxSessionToolRounds = local.Agent.MaxSessionToolRounds
+	}
+	if local.Agent.MaxPromptTokens != 0 {
+		merged.Agent.MaxPromptTokens = local.Agent.MaxPromptTokens
+	}
+	if local.Agent.MaxToolRetries != 0 {
+		merged.Agent.MaxToolRetries = local.Agent.MaxToolRetries
+	}
+	if local.Agent.ToolCalling != "" {
+		merged.Agent.ToolCalling = local.Agent.ToolCalling
+	}
+	if local.Agent.PruneMarker != nil {
+		merged.Agent.PruneMarker = local.Agent.PruneMarker
+	}
+	if local.Agent.PromptPrefix != "" {
+		merged.Agent.PromptPrefix = local.Agent.PromptPrefix
+	}
+	if local.Agent.PromptSuffix != "" {
+		merged.Agent.PromptSuffix = local.Agent.PromptSuffix
+	}
+	if local.Agent.ModelTiers != nil {
+		merged.Agent.ModelTiers = local.Agent.ModelTiers
+	}
+	if local.Agent.StreamKeepalive != "" {
+		merged.Agent.StreamKeepalive = local.Agent.StreamKeepalive
+	}
+	if local.Agent.Sandbox.Mode != "" {
+		merged.Agent.Sandbox.Mode = local.Agent.Sandbox.Mode
+	}
+	if local.Agent.StreamFallbacks != 0 {
+		merged.Agent.StreamFallbacks = local.Agent.StreamFallbacks
+	}
+	if local.Agent.Timeouts.Interactive != "" {
+		merged.Agent.Timeouts.Interactive = local.Agent.Timeouts.Interactive
+	}
+	if local.Agent.Timeouts.Batch != "" {
+		merged.Agent.Timeouts.Batch = local.Agent.Timeouts.Batch
+	}
+	if local.Agent.Timeouts.Heartbeat != "" {
+		merged.Agent.Timeouts.Heartbeat = local.Agent.Timeouts.Heartbeat
+	}
+	if local.Agent.Timeouts.Cron != "" {
+		merged.Agent.Timeouts.Cron = local.Agent.Timeouts.Cron
+	}
+	if local.Agent.SerialPipeline {
+		merged.Agent.SerialPipeline = true
+	}
+	if local.Agent.DuplicateWindow != "" {
+		merged.Agent.DuplicateWindow = local.Agent.DuplicateWindow
+	}
+	if local.Agent.DuplicateThreshold != 0 {
+		merged.Agent.DuplicateThreshold = local.Agent.DuplicateThreshold
+	}
 
 	// Override with local gateway settings
 	if local.Gateway.Port != 0 {
@@ -65,6 +125,21 @@ func MergeConfigs(global, local *Config) *Config {
 	if local.Gateway.Bind != "" {
 		merged.Gateway.Bind = local.Gateway.Bind
 	}
+	if local.Gateway.Auth.Mode != "" {
+		merged.Gateway.Auth = local.Gateway.Auth
+	}
+	if local.Gateway.CORS.AllowedOrigins != nil {
+		merged.Gateway.CORS.AllowedOrigins = local.Gateway.CORS.AllowedOrigins
+	}
+	if local.Gateway.CORS.MaxAge != "" {
+		merged.Gateway.CORS.MaxAge = local.Gateway.CORS.MaxAge
+	}
+	if local.Gateway.TrustProxy {
+		merged.Gateway.TrustProxy = true
+	}
+	if local.Gateway.TrustedProxies != nil {
+		merged.Gateway.TrustedProxies = local.Gateway.TrustedProxies
+	}
 
 	// Override with local Zhipu settings
 	if local.Zhipu.ApiKey != "" {
@@ -77,6 +152,143 @@ func MergeConfigs(global, local *Config) *Config {
 		merged.Zhipu.BaseURL = local.Zhipu.BaseURL
 	}
 
+	// Override with local redaction rules
+	if len(local.Redaction.Fields) > 0 || len(local.Redaction.Patterns) > 0 {
+		merged.Redaction = local.Redaction
+	}
+
+	// Override with local tool settings
+	if local.Tools.FileDenylist != nil {
+		merged.Tools.FileDenylist = local.Tools.FileDenylist
+	}
+	if local.Tools.FileAllowlist != nil {
+		merged.Tools.FileAllowlist = local.Tools.FileAllowlist
+	}
+	if local.Tools.RedactOutput != nil {
+		merged.Tools.RedactOutput = local.Tools.RedactOutput
+	}
+	if local.Tools.Scopes != nil {
+		merged.Tools.Scopes = local.Tools.Scopes
+	}
+	if local.Tools.Webhooks != nil {
+		merged.Tools.Webhooks = local.Tools.Webhooks
+	}
+	if local.Tools.SystemInfo != nil {
+		merged.Tools.SystemInfo = local.Tools.SystemInfo
+	}
+	if local.Tools.MaxDepth != 0 {
+		merged.Tools.MaxDepth = local.Tools.MaxDepth
+	}
+
+	// Override with local session defaults
+	if local.Sessions.AutoMain != nil {
+		merged.Sessions.AutoMain = local.Sessions.AutoMain
+	}
+	if local.Sessions.MainSession != "" {
+		merged.Sessions.MainSession = local.Sessions.MainSession
+	}
+	if local.Sessions.Dir != "" {
+		merged.Sessions.Dir = local.Sessions.Dir
+	}
+	if local.Sessions.AutoSaveInterval != "" {
+		merged.Sessions.AutoSaveInterval = local.Sessions.AutoSaveInterval
+	}
+	if local.Sessions.AutoSaveMessages != 0 {
+		merged.Sessions.AutoSaveMessages = local.Sessions.AutoSaveMessages
+	}
+	if local.Sessions.IdleAfter != "" {
+		merged.Sessions.IdleAfter = local.Sessions.IdleAfter
+	}
+	if local.Sessions.IdleSummary {
+		merged.Sessions.IdleSummary = true
+	}
+	if local.Sessions.IdleSummaryPin {
+		merged.Sessions.IdleSummaryPin = true
+	}
+
+	// Override with local external call settings
+	if local.External.Timeout != "" {
+		merged.External.Timeout = local.External.Timeout
+	}
+	if local.External.MaxRetries != nil {
+		merged.External.MaxRetries = local.External.MaxRetries
+	}
+	if local.External.Backoff != "" {
+		merged.External.Backoff = local.External.Backoff
+	}
+	if local.External.Targets != nil {
+		merged.External.Targets = local.External.Targets
+	}
+
+	// Override with local memory capture settings
+	if local.Memory.CaptureAssistant {
+		merged.Memory.CaptureAssistant = true
+	}
+	if local.Memory.MinCaptureChars != 0 {
+		merged.Memory.MinCaptureChars = local.Memory.MinCaptureChars
+	}
+	if local.Memory.MinMessageChars != 0 {
+		merged.Memory.MinMessageChars = local.Memory.MinMessageChars
+	}
+	if local.Memory.ConsolidateBatch != 0 {
+		merged.Memory.ConsolidateBatch = local.Memory.ConsolidateBatch
+	}
+	if local.Memory.SessionScoped {
+		merged.Memory.SessionScoped = true
+	}
+	if local.Memory.ConsolidateAfter != "" {
+		merged.Memory.ConsolidateAfter = local.Memory.ConsolidateAfter
+	}
+	if local.Memory.ConsolidateInterval != "" {
+		merged.Memory.ConsolidateInterval = local.Memory.ConsolidateInterval
+	}
+	if local.Memory.ShortTermTTL != "" {
+		merged.Memory.ShortTermTTL = local.Memory.ShortTermTTL
+	}
+	if local.Memory.Language != "" {
+		merged.Memory.Language = local.Memory.Language
+	}
+	if local.Memory.Translate {
+		merged.Memory.Translate = true
+	}
+	if local.Memory.ContextLongTermK != 0 {
+		merged.Memory.ContextLongTermK = local.Memory.ContextLongTermK
+	}
+	if local.Memory.ContextShortTermK != 0 {
+		merged.Memory.ContextShortTermK = local.Memory.ContextShortTermK
+	}
+	if local.Memory.ContextShortTermMaxAge != "" {
+		merged.Memory.ContextShortTermMaxAge = local.Memory.ContextShortTermMaxAge
+	}
+	if local.Memory.VectorFile != "" {
+		merged.Memory.VectorFile = local.Memory.VectorFile
+	}
+	if local.Memory.File != "" {
+		merged.Memory.File = local.Memory.File
+	}
+	if local.Memory.VectorSaveInterval != "" {
+		merged.Memory.VectorSaveInterval = local.Memory.VectorSaveInterval
+	}
+	if local.Memory.SameLanguageBoost != 0 {
+		merged.Memory.SameLanguageBoost = local.Memory.SameLanguageBoost
+	}
+
+	// Override with local cron settings
+	if local.Cron.File != "" {
+		merged.Cron.File = local.Cron.File
+	}
+
+	// Override with local embedding provider
+	if local.Embedding.API != "" {
+		merged.Embedding = local.Embedding
+	}
+	if local.Embedding.ReducedDimensions != 0 {
+		merged.Embedding.ReducedDimensions = local.Embedding.ReducedDimensions
+	}
+	if local.Embedding.Fallbacks != nil {
+		merged.Embedding.Fallbacks = local.Embedding.Fallbacks
+	}
+
 	// For maps, merge them together (local takes precedence)
 	if merged.Models == nil {
 		merged.Models = make(map[string]interface{})
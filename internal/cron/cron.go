@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"goclaw/internal/tools"
+
 	"github.com/robfig/cron/v3"
 )
 
@@ -22,6 +24,10 @@ type Task struct {
 	Error       string                 `json:"error,omitempty"`
 	Enabled     bool                   `json:"enabled"`
 	Description string                 `json:"description"`
+	Timeout     string                 `json:"timeout,omitempty"` // Cancel a run after this long (e.g., "30s"), DefaultTaskTimeout when empty
+	History     []Run                  `json:"history,omitempty"` // The latest runs, oldest first
+
+	entryID cron.EntryID // The scheduler entry running the task, 0 when not scheduled
 }
 
 // CronManager manages scheduled tasks
@@ -30,6 +36,12 @@ type CronManager struct {
 	tasks     map[string]*Task
 	taskMutex sync.RWMutex
 	logger    *log.Logger
+	running   bool
+	tools     *tools.Executor // Runs the calls of tool tasks, see SetToolExecutor
+	path      string          // File the tasks are saved to after each change, see NewCronManagerFromFile
+	saveMutex sync.Mutex      // Serializes SaveTasks
+
+	executions map[string]context.CancelFunc // Cancels the run in progress, by task ID
 }
 
 // NewCronManager creates a new cron manager
@@ -42,6 +54,8 @@ func NewCronManager(logger *log.Logger) *CronManager {
 		cron:   cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger))),
 		tasks:  make(map[string]*Task),
 		logger: logger,
+
+		executions: make(map[string]context.CancelFunc),
 	}
 
 	return cm
@@ -49,6 +63,7 @@ func NewCronManager(logger *log.Logger) *CronManager {
 
 // AddTask adds a new scheduled task
 func (cm *CronManager) AddTask(task *Task) (string, error) {
+	defer cm.persist() // After the lock is released
 	cm.taskMutex.Lock()
 	defer cm.taskMutex.Unlock()
 
@@ -60,14 +75,20 @@ func (cm *CronManager) AddTask(task *Task) (string, error) {
 		return "", fmt.Errorf("task with ID %s already exists", task.ID)
 	}
 
+	if err := cm.migrateToolTask(task); err != nil {
+		return "", fmt.Errorf("invalid tool task: %w", err)
+	}
+	if _, err := taskTimeout(task); err != nil {
+		return "", err
+	}
+
 	// Only schedule the task if it's enabled
 	if task.Enabled {
-		_, err := cm.cron.AddFunc(task.Schedule, func() {
-			cm.executeTask(task)
-		})
+		entryID, err := cm.schedule(task)
 		if err != nil {
 			return "", fmt.Errorf("failed to schedule task: %w", err)
 		}
+		task.entryID = entryID
 	}
 
 	task.CreatedAt = time.Now()
@@ -84,6 +105,7 @@ func (cm *CronManager) AddTask(task *Task) (string, error) {
 
 // RemoveTask removes a scheduled task
 func (cm *CronManager) RemoveTask(taskID string) error {
+	defer cm.persist() // After the lock is released
 	cm.taskMutex.Lock()
 	defer cm.taskMutex.Unlock()
 
@@ -92,49 +114,54 @@ func (cm *CronManager) RemoveTask(taskID string) error {
 		return fmt.Errorf("task %s not found", taskID)
 	}
 
-	// For now, we'll recreate the cron scheduler
-	// In a production system, we'd store the cron.EntryID
-
+	cm.unschedule(task)
 	delete(cm.tasks, taskID)
 
-	// For now, we'll recreate the cron scheduler
-	// In a production system, we'd store the cron.EntryID
-	cm.cron.Stop()
-	cm.cron = cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger)))
-
-	// Re-add remaining tasks
-	for id, t := range cm.tasks {
-		if t.Enabled {
-			_, err := cm.cron.AddFunc(t.Schedule, func() {
-				cm.executeTask(t)
-			})
-			if err != nil {
-				cm.logger.Printf("Failed to reschedule task %s: %v", id, err)
-			}
-		}
-	}
-
-	if cm.cron.Entries() != nil {
-		cm.cron.Start()
-	}
-
 	cm.logger.Printf("Removed task %s: %s", taskID, task.Name)
 	return nil
 }
 
+// schedule adds a scheduler entry running task on its schedule
+func (cm *CronManager) schedule(task *Task) (cron.EntryID, error) {
+	return cm.cron.AddFunc(task.Schedule, func() {
+		cm.executeTask(task)
+	})
+}
+
+// unschedule removes the scheduler entry of task, if any
+func (cm *CronManager) unschedule(task *Task) {
+	if task.entryID != 0 {
+		cm.cron.Remove(task.entryID)
+		task.entryID = 0
+	}
+}
+
 // Start starts the cron scheduler
 func (cm *CronManager) Start() {
 	cm.cron.Start()
+	cm.taskMutex.Lock()
+	cm.running = true
+	cm.taskMutex.Unlock()
 	cm.logger.Println("Cron scheduler started")
 }
 
 // Stop stops the cron scheduler
 func (cm *CronManager) Stop() context.Context {
 	ctx := cm.cron.Stop()
+	cm.taskMutex.Lock()
+	cm.running = false
+	cm.taskMutex.Unlock()
 	cm.logger.Println("Cron scheduler stopped")
 	return ctx
 }
 
+// Running reports whether the scheduler was started and not stopped
+func (cm *CronManager) Running() bool {
+	cm.taskMutex.RLock()
+	defer cm.taskMutex.RUnlock()
+	return cm.running
+}
+
 // ListTasks returns all scheduled tasks
 func (cm *CronManager) ListTasks() []*Task {
 	cm.taskMutex.RLock()
@@ -147,6 +174,25 @@ func (cm *CronManager) ListTasks() []*Task {
 	return tasks
 }
 
+// NextRun returns the next time any scheduled task runs, or nil when
+// nothing is scheduled or the scheduler isn't running
+func (cm *CronManager) NextRun() *time.Time {
+	cm.taskMutex.RLock()
+	defer cm.taskMutex.RUnlock()
+
+	var next *time.Time
+	for _, entry := range cm.cron.Entries() {
+		if entry.Next.IsZero() {
+			continue
+		}
+		if next == nil || entry.Next.Before(*next) {
+			at := entry.Next
+			next = &at
+		}
+	}
+	return next
+}
+
 // GetTask returns a specific task
 func (cm *CronManager) GetTask(taskID string) (*Task, bool) {
 	cm.taskMutex.RLock()
@@ -156,37 +202,55 @@ func (cm *CronManager) GetTask(taskID string) (*Task, bool) {
 	return task, exists
 }
 
-// executeTask executes a scheduled task
+// executeTask executes a scheduled task. The run is cancelled when it
+// exceeds the task's timeout or CancelTask is called, and a run doesn't start
+// while the previous one is still going.
 func (cm *CronManager) executeTask(task *Task) {
 	startTime := time.Now()
 
+	ctx, cancel, started := cm.startExecution(task)
+	if !started {
+		cm.logger.Printf("Skipping task %s: %s, the previous run is still going", task.ID, task.Name)
+		cm.taskMutex.Lock()
+		recordRun(task, Run{StartedAt: startTime, Outcome: OutcomeSkipped})
+		cm.taskMutex.Unlock()
+		cm.persist()
+		return
+	}
+	defer cancel()
+
 	cm.logger.Printf("Executing task %s: %s", task.ID, task.Name)
 
-	// Here you would implement the actual task execution logic
-	// For now, we'll just log the execution
-	result := cm.runTaskCommand(task)
+	result := cm.runWithContext(ctx, task)
+	duration := time.Since(startTime)
+	outcome := outcomeOf(result)
 
 	// Update task status
 	cm.taskMutex.Lock()
+	delete(cm.executions, task.ID)
 	if task.LastRun == nil {
 		task.LastRun = &startTime
 	} else {
 		*task.LastRun = startTime
 	}
 
+	run := Run{StartedAt: startTime, Duration: duration, Outcome: outcome}
 	if result != nil {
 		task.Error = result.Error()
+		run.Error = result.Error()
 	} else {
 		task.Error = ""
 	}
+	recordRun(task, run)
 	cm.taskMutex.Unlock()
+	cm.persist()
 
-	duration := time.Since(startTime)
-	cm.logger.Printf("Task %s completed in %v", task.ID, duration)
+	cm.logger.Printf("Task %s completed in %v (%s)", task.ID, duration, outcome)
 }
 
-// runTaskCommand executes the actual command for the task
-func (cm *CronManager) runTaskCommand(task *Task) error {
+// runTaskCommand executes the actual command for the task. Commands should
+// stop when ctx is done.
+func (cm *CronManager) runTaskCommand(ctx context.Context, task *Task) error {
 	// This is where you'd implement the actual task logic
 	// For example:
 	// - Send a notification/reminders
@@ -199,6 +263,8 @@ func (cm *CronManager) runTaskCommand(task *Task) error {
 		return cm.handleReminder(task)
 	case "notification":
 		return cm.handleNotification(task)
+	case CommandTool:
+		return cm.handleToolTask(ctx, task)
 	default:
 		return cm.handleGenericTask(task)
 	}
@@ -247,6 +313,7 @@ func (cm *CronManager) handleGenericTask(task *Task) error {
 
 // UpdateTask updates an existing task
 func (cm *CronManager) UpdateTask(taskID string, updatedTask *Task) error {
+	defer cm.persist() // After the lock is released
 	cm.taskMutex.Lock()
 	defer cm.taskMutex.Unlock()
 
@@ -254,6 +321,26 @@ func (cm *CronManager) UpdateTask(taskID string, updatedTask *Task) error {
 	if !exists {
 		return fmt.Errorf("task %s not found", taskID)
 	}
+	if err := cm.migrateToolTask(updatedTask); err != nil {
+		return fmt.Errorf("invalid tool task: %w", err)
+	}
+	if _, err := taskTimeout(updatedTask); err != nil {
+		return err
+	}
+
+	// Schedule the new version first, so an invalid schedule leaves the task
+	// as it was. The entry runs existingTask, updated below.
+	var entryID cron.EntryID
+	if updatedTask.Enabled {
+		var err error
+		if entryID, err = cm.cron.AddFunc(updatedTask.Schedule, func() {
+			cm.executeTask(existingTask)
+		}); err != nil {
+			return fmt.Errorf("failed to schedule task: %w", err)
+		}
+	}
+	cm.unschedule(existingTask)
+	existingTask.entryID = entryID
 
 	// Update fields
 	existingTask.Name = updatedTask.Name
@@ -262,26 +349,7 @@ func (cm *CronManager) UpdateTask(taskID string, updatedTask *Task) error {
 	existingTask.Payload = updatedTask.Payload
 	existingTask.Enabled = updatedTask.Enabled
 	existingTask.Description = updatedTask.Description
-
-	// Remove and re-add the task with new schedule
-	cm.cron.Stop()
-	cm.cron = cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger)))
-
-	// Re-add all tasks
-	for id, t := range cm.tasks {
-		if t.Enabled {
-			_, err := cm.cron.AddFunc(t.Schedule, func() {
-				cm.executeTask(t)
-			})
-			if err != nil {
-				cm.logger.Printf("Failed to reschedule task %s: %v", id, err)
-			}
-		}
-	}
-
-	if cm.cron.Entries() != nil {
-		cm.cron.Start()
-	}
+	existingTask.Timeout = updatedTask.Timeout
 
 	return nil
 }
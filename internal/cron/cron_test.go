@@ -1,8 +1,18 @@
 package cron
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"goclaw/internal/tools"
+
+	"github.com/gorilla/mux"
 )
 
 func TestCronManager_BasicOperations(t *testing.T) {
@@ -224,6 +234,129 @@ func TestCronManager_MultipleTasks(t *testing.T) {
 	}
 }
 
+func TestCronManager_RemoveTaskKeepsOthersScheduled(t *testing.T) {
+	manager := NewCronManager(nil)
+
+	ids := make([]string, 3)
+	for i, name := range []string{"first", "middle", "last"} {
+		id, err := manager.AddTask(&Task{Name: name, Schedule: "@every 1s", Command: "test", Enabled: true})
+		if err != nil {
+			t.Fatalf("Failed to add task %s: %v", name, err)
+		}
+		ids[i] = id
+	}
+	manager.Start()
+	defer manager.Stop()
+
+	if err := manager.RemoveTask(ids[1]); err != nil {
+		t.Fatalf("Failed to remove the middle task: %v", err)
+	}
+	if entries := len(manager.cron.Entries()); entries != 2 {
+		t.Errorf("Expected 2 scheduler entries, got %d", entries)
+	}
+
+	// Each remaining entry runs its own task
+	lastRun := func(id string) *time.Time {
+		manager.taskMutex.RLock()
+		defer manager.taskMutex.RUnlock()
+		return manager.tasks[id].LastRun
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for (lastRun(ids[0]) == nil || lastRun(ids[2]) == nil) && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	for _, id := range []string{ids[0], ids[2]} {
+		if lastRun(id) == nil {
+			t.Errorf("Task %s never fired after the middle task was removed", id)
+		}
+	}
+
+	// An update replaces the task's entry instead of adding one
+	if err := manager.UpdateTask(ids[0], &Task{Name: "first", Schedule: "0 * * * *", Command: "test", Enabled: true}); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	if entries := len(manager.cron.Entries()); entries != 2 {
+		t.Errorf("Expected 2 scheduler entries after the update, got %d", entries)
+	}
+	if err := manager.UpdateTask(ids[0], &Task{Name: "first", Schedule: "invalid", Command: "test", Enabled: true}); err == nil {
+		t.Error("Expected an error updating to an invalid schedule")
+	}
+	if task, _ := manager.GetTask(ids[0]); task.Schedule != "0 * * * *" {
+		t.Errorf("Failed update changed the schedule to %q", task.Schedule)
+	}
+}
+
+func TestCronManager_PersistsTasks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron.json")
+
+	manager, err := NewCronManagerFromFile(path, nil)
+	if err != nil {
+		t.Fatalf("NewCronManagerFromFile() on a missing file error = %v", err)
+	}
+	reminderID, err := manager.AddTask(&Task{
+		Name:     "standup",
+		Schedule: "0 9 * * 1-5",
+		Command:  "reminder",
+		Payload:  map[string]interface{}{"message": "Standup in 5 minutes"},
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	pausedID, _ := manager.AddTask(&Task{Name: "paused", Schedule: "0 3 * * *", Command: "notification"})
+	removedID, _ := manager.AddTask(&Task{Name: "removed", Schedule: "0 4 * * *", Command: "notification", Enabled: true})
+	manager.ExecuteTaskNow(reminderID)
+	manager.RemoveTask(removedID)
+
+	// A restart restores the tasks and schedules the enabled one
+	restored, err := NewCronManagerFromFile(path, nil)
+	if err != nil {
+		t.Fatalf("NewCronManagerFromFile() error = %v", err)
+	}
+	if tasks := restored.ListTasks(); len(tasks) != 2 {
+		t.Errorf("Expected 2 restored tasks, got %d", len(tasks))
+	}
+	reminder, exists := restored.GetTask(reminderID)
+	if !exists || reminder.Schedule != "0 9 * * 1-5" || reminder.Payload["message"] != "Standup in 5 minutes" || !reminder.Enabled || reminder.LastRun == nil {
+		t.Errorf("Restored reminder = %+v, want its schedule, payload and last run", reminder)
+	}
+	if paused, _ := restored.GetTask(pausedID); paused == nil || paused.Enabled {
+		t.Errorf("Restored paused task = %+v, want it disabled", paused)
+	}
+	if entries := len(restored.cron.Entries()); entries != 1 {
+		t.Errorf("Expected 1 scheduled entry after loading, got %d", entries)
+	}
+
+	// A task whose schedule doesn't parse is kept but disabled
+	broken := `{
+		"good": {"name": "good", "schedule": "0 3 * * *", "command": "reminder", "enabled": true},
+		"bad": {"name": "bad", "schedule": "every tuesday", "command": "reminder", "enabled": true}
+	}`
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restored, err = NewCronManagerFromFile(path, nil)
+	if err != nil {
+		t.Fatalf("NewCronManagerFromFile() with a bad schedule error = %v", err)
+	}
+	if good, _ := restored.GetTask("good"); good == nil || !good.Enabled {
+		t.Errorf("Task good = %+v, want it loaded and enabled", good)
+	}
+	if bad, _ := restored.GetTask("bad"); bad == nil || bad.Enabled || bad.Error == "" {
+		t.Errorf("Task bad = %+v, want it kept, disabled, with an error", bad)
+	}
+	if entries := len(restored.cron.Entries()); entries != 1 {
+		t.Errorf("Expected only the good task scheduled, got %d entries", entries)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCronManagerFromFile(path, nil); err == nil {
+		t.Error("Expected an error loading a corrupt tasks file")
+	}
+}
+
 func TestCronManager_TaskExecution(t *testing.T) {
 	manager := NewCronManager(nil) // Use default logger
 
@@ -286,3 +419,159 @@ func TestCronManager_InvalidSchedule(t *testing.T) {
 	// Clean up
 	manager.RemoveTask(id)
 }
+
+func TestToolTaskMigratesStoredCall(t *testing.T) {
+	var paths []string
+	registry := tools.NewRegistry()
+	err := registry.Register(&tools.Tool{
+		Name:          "read",
+		Parameters:    map[string]tools.Parameter{"path": {Type: "string", Required: true}},
+		SchemaVersion: 2,
+		Migrations: map[int]tools.MigrateFunc{
+			1: func(params map[string]interface{}) (map[string]interface{}, error) {
+				params["path"] = params["file"]
+				delete(params, "file")
+				return params, nil
+			},
+		},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			paths = append(paths, params["path"].(string))
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	manager := NewCronManager(nil)
+	manager.SetToolExecutor(tools.NewExecutor(registry))
+
+	// A task stored before the tool renamed "file" to "path", as decoded from JSON
+	task := &Task{
+		Name:     "read-notes",
+		Schedule: "0 3 * * *",
+		Command:  CommandTool,
+		Payload: map[string]interface{}{
+			"tool":          "read",
+			"params":        map[string]interface{}{"file": "notes.txt"},
+			"schemaVersion": float64(1),
+		},
+	}
+	id, err := manager.AddTask(task)
+	if err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	if task.Payload["schemaVersion"] != 2 {
+		t.Errorf("payload = %v, want it upgraded to schema version 2", task.Payload)
+	}
+
+	if _, err := manager.ExecuteTaskNow(id); err != nil {
+		t.Fatalf("ExecuteTaskNow() error = %v", err)
+	}
+	stored, _ := manager.GetTask(id)
+	if len(paths) != 1 || paths[0] != "notes.txt" || stored.Error != "" {
+		t.Errorf("tool read %v (task error %q), want notes.txt", paths, stored.Error)
+	}
+}
+
+// slowToolManager returns a manager whose "slow" tool blocks until its
+// context is done, closing started when it begins
+func slowToolManager(t *testing.T, started chan<- struct{}) *CronManager {
+	t.Helper()
+	registry := tools.NewRegistry()
+	err := registry.Register(&tools.Tool{
+		Name:       "slow",
+		Parameters: map[string]tools.Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	manager := NewCronManager(nil)
+	manager.SetToolExecutor(tools.NewExecutor(registry))
+	return manager
+}
+
+func TestSlowTaskTimesOut(t *testing.T) {
+	manager := slowToolManager(t, make(chan struct{}))
+	id, err := manager.AddTask(&Task{
+		Name:     "hangs",
+		Schedule: "0 3 * * *",
+		Command:  CommandTool,
+		Payload:  map[string]interface{}{"tool": "slow"},
+		Timeout:  "50ms",
+	})
+	if err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	start := time.Now()
+	if _, err := manager.ExecuteTaskNow(id); err != nil {
+		t.Fatalf("ExecuteTaskNow() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("run took %v, want it cancelled after the 50ms timeout", elapsed)
+	}
+
+	task, _ := manager.GetTask(id)
+	if len(task.History) != 1 || task.History[0].Outcome != OutcomeTimeout || task.Error == "" {
+		t.Fatalf("history = %+v (error %q), want one timed-out run", task.History, task.Error)
+	}
+	if manager.IsExecuting(id) {
+		t.Error("task is still marked running after the timeout")
+	}
+
+	if _, err := manager.AddTask(&Task{Name: "bad", Schedule: "0 3 * * *", Command: "reminder", Timeout: "soon"}); err == nil {
+		t.Error("AddTask() accepted an invalid timeout")
+	}
+}
+
+func TestCancelRunningTask(t *testing.T) {
+	started := make(chan struct{})
+	manager := slowToolManager(t, started)
+	id, err := manager.AddTask(&Task{
+		Name:     "hangs",
+		Schedule: "0 3 * * *",
+		Command:  CommandTool,
+		Payload:  map[string]interface{}{"tool": "slow"},
+		Timeout:  "1m",
+	})
+	if err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	if err := manager.CancelTask(id); !errors.Is(err, ErrTaskNotRunning) {
+		t.Errorf("CancelTask() before a run error = %v, want ErrTaskNotRunning", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		manager.ExecuteTaskNow(id)
+		close(done)
+	}()
+	<-started
+
+	// A second run doesn't start while the first is going
+	manager.ExecuteTaskNow(id)
+
+	router := mux.NewRouter()
+	NewHandler(manager).RegisterRoutes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/cron/tasks/"+id+"/cancel", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the run didn't stop after it was cancelled")
+	}
+	task, _ := manager.GetTask(id)
+	if len(task.History) != 2 || task.History[0].Outcome != OutcomeSkipped || task.History[1].Outcome != OutcomeCancelled {
+		t.Errorf("history = %+v, want a skipped and a cancelled run", task.History)
+	}
+}
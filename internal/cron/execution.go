@@ -0,0 +1,136 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTaskTimeout bounds a task run unless the task sets its own timeout
+const DefaultTaskTimeout = 5 * time.Minute
+
+// maxTaskHistory is how many runs are kept per task
+const maxTaskHistory = 20
+
+// Outcomes of a task run
+const (
+	OutcomeSuccess   = "success"
+	OutcomeError     = "error"
+	OutcomeTimeout   = "timeout"   // The run exceeded the task's timeout and was cancelled
+	OutcomeCancelled = "cancelled" // The run was cancelled with CancelTask
+	OutcomeSkipped   = "skipped"   // The previous run was still going
+)
+
+var (
+	// ErrTaskNotFound is returned for an unknown task ID
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskNotRunning is returned when cancelling a task that isn't running
+	ErrTaskNotRunning = errors.New("task is not running")
+)
+
+// Run is the record of one execution of a task
+type Run struct {
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	Outcome   string        `json:"outcome"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// taskTimeout returns the timeout of a task's runs
+func taskTimeout(task *Task) (time.Duration, error) {
+	if task.Timeout == "" {
+		return DefaultTaskTimeout, nil
+	}
+	timeout, err := time.ParseDuration(task.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be a positive duration such as \"30s\"", task.Timeout)
+	}
+	return timeout, nil
+}
+
+// CancelTask cancels the running execution of a task. The run ends with the
+// cancelled outcome in the task's history.
+func (cm *CronManager) CancelTask(taskID string) error {
+	cm.taskMutex.Lock()
+	defer cm.taskMutex.Unlock()
+
+	if _, exists := cm.tasks[taskID]; !exists {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	cancel, running := cm.executions[taskID]
+	if !running {
+		return fmt.Errorf("%w: %s", ErrTaskNotRunning, taskID)
+	}
+	cancel()
+	return nil
+}
+
+// IsExecuting reports whether a run of the task is in progress
+func (cm *CronManager) IsExecuting(taskID string) bool {
+	cm.taskMutex.RLock()
+	defer cm.taskMutex.RUnlock()
+	_, running := cm.executions[taskID]
+	return running
+}
+
+// startExecution registers a run of task with its own cancellable context.
+// It returns false when the previous run is still going.
+func (cm *CronManager) startExecution(task *Task) (context.Context, context.CancelFunc, bool) {
+	timeout, err := taskTimeout(task)
+	if err != nil {
+		// Tasks are validated when added, fall back for ones set up directly
+		timeout = DefaultTaskTimeout
+	}
+
+	cm.taskMutex.Lock()
+	defer cm.taskMutex.Unlock()
+	if _, running := cm.executions[task.ID]; running {
+		return nil, nil, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	cm.executions[task.ID] = cancel
+	return ctx, cancel, true
+}
+
+// runWithContext runs a task's command, returning as soon as ctx is done even
+// if the command ignores it, so a hung command can't block the scheduler
+func (cm *CronManager) runWithContext(ctx context.Context, task *Task) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cm.runTaskCommand(ctx, task)
+	}()
+
+	select {
+	case err := <-done:
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// outcomeOf classifies how a run ended
+func outcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case errors.Is(err, context.Canceled):
+		return OutcomeCancelled
+	default:
+		return OutcomeError
+	}
+}
+
+// recordRun adds a run to the task's history, dropping the oldest runs past
+// maxTaskHistory; the caller must hold the task lock
+func recordRun(task *Task, run Run) {
+	task.History = append(task.History, run)
+	if len(task.History) > maxTaskHistory {
+		task.History = append([]Run(nil), task.History[len(task.History)-maxTaskHistory:]...)
+	}
+}
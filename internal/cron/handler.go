@@ -2,6 +2,7 @@ package cron
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -36,6 +37,7 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/cron/tasks/{id}", h.UpdateTask).Methods("PUT")
 	router.HandleFunc("/api/cron/tasks/{id}", h.DeleteTask).Methods("DELETE")
 	router.HandleFunc("/api/cron/tasks/{id}/execute", h.ExecuteTaskNow).Methods("POST")
+	router.HandleFunc("/api/cron/tasks/{id}/cancel", h.CancelTask).Methods("POST")
 }
 
 // ListTasks returns all scheduled tasks
@@ -238,6 +240,38 @@ func (h *Handler) ExecuteTaskNow(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, response, http.StatusOK)
 }
 
+// CancelTask cancels the running execution of a task
+func (h *Handler) CancelTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID := vars["id"]
+
+	err := h.manager.CancelTask(taskID)
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		h.writeJSON(w, APIResponse{
+			Status: "error",
+			Error:  "Task not found",
+		}, http.StatusNotFound)
+		return
+	case errors.Is(err, ErrTaskNotRunning):
+		h.writeJSON(w, APIResponse{
+			Status: "error",
+			Error:  "Task is not running",
+		}, http.StatusConflict)
+		return
+	}
+
+	response := APIResponse{
+		Status:  "ok",
+		Message: "Task cancelled",
+		Data: map[string]interface{}{
+			"taskId": taskID,
+		},
+	}
+
+	h.writeJSON(w, response, http.StatusOK)
+}
+
 // writeJSON writes a JSON response
 func (h *Handler) writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -256,6 +290,7 @@ type TaskRequest struct {
 	Payload     map[string]interface{} `json:"payload"`
 	Enabled     *bool                  `json:"enabled,omitempty"`
 	Description string                 `json:"description"`
+	Timeout     string                 `json:"timeout,omitempty"`
 }
 
 // ConvertTaskRequest converts a TaskRequest to a Task
@@ -272,5 +307,6 @@ func (h *Handler) ConvertTaskRequest(req *TaskRequest) *Task {
 		Payload:     req.Payload,
 		Enabled:     enabled,
 		Description: req.Description,
+		Timeout:     req.Timeout,
 	}
 }
@@ -0,0 +1,118 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// NewCronManagerFromFile creates a cron manager with the tasks saved in a
+// file, enabled ones scheduled, and saves its tasks back to the file after
+// every change and run. A missing file starts with no tasks.
+func NewCronManagerFromFile(path string, logger *log.Logger) (*CronManager, error) {
+	cm := NewCronManager(logger)
+	if err := cm.LoadTasks(path); err != nil {
+		return nil, err
+	}
+	cm.taskMutex.Lock()
+	cm.path = path
+	cm.taskMutex.Unlock()
+	return cm, nil
+}
+
+// SaveTasks writes every task, with its schedule, payload and run history,
+// to a JSON file keyed by task ID. The file is replaced atomically, so a
+// crash mid-save leaves the previous one intact.
+func (cm *CronManager) SaveTasks(path string) error {
+	cm.saveMutex.Lock()
+	defer cm.saveMutex.Unlock()
+
+	cm.taskMutex.RLock()
+	data, err := json.MarshalIndent(cm.tasks, "", "  ")
+	cm.taskMutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal tasks: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".cron-*")
+	if err != nil {
+		return fmt.Errorf("failed to create tasks file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tasks file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write tasks file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadTasks adds the tasks saved in a file, replacing those with the same
+// ID, and schedules the enabled ones. A task whose schedule doesn't parse is
+// kept, disabled with the error, rather than failing the load. A missing file
+// is not an error.
+func (cm *CronManager) LoadTasks(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // No file to load
+		}
+		return fmt.Errorf("failed to read tasks file: %w", err)
+	}
+
+	var saved map[string]*Task
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to unmarshal tasks: %w", err)
+	}
+
+	cm.taskMutex.Lock()
+	defer cm.taskMutex.Unlock()
+
+	for id, task := range saved {
+		if task == nil {
+			continue
+		}
+		task.ID = id
+		if existing, exists := cm.tasks[id]; exists {
+			cm.unschedule(existing)
+		}
+
+		if task.Enabled {
+			entryID, err := cm.schedule(task)
+			if err != nil {
+				task.Enabled = false
+				task.Error = fmt.Sprintf("invalid schedule %q: %v", task.Schedule, err)
+				cm.logger.Printf("Task %s: %s disabled, %s", task.ID, task.Name, task.Error)
+			}
+			task.entryID = entryID
+		}
+		cm.tasks[id] = task
+	}
+
+	cm.logger.Printf("Loaded %d tasks from %s", len(saved), path)
+	return nil
+}
+
+// persist saves the tasks to the file of NewCronManagerFromFile, if any.
+// Failures are logged: the change being saved has been made either way.
+func (cm *CronManager) persist() {
+	cm.taskMutex.RLock()
+	path := cm.path
+	cm.taskMutex.RUnlock()
+
+	if path == "" {
+		return
+	}
+	if err := cm.SaveTasks(path); err != nil {
+		cm.logger.Printf("Failed to save tasks to %s: %v", path, err)
+	}
+}
@@ -0,0 +1,82 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+
+	"goclaw/internal/tools"
+)
+
+// CommandTool is the command of tasks that run a tool call, stored in the
+// payload as {"tool": name, "params": {...}, "schemaVersion": n}
+const CommandTool = "tool"
+
+// SetToolExecutor lets tasks with the tool command run tool calls. Call it
+// before adding tasks: their calls are upgraded to the tool's current schema
+// when a task is added or updated, so a tool changing shape doesn't break it.
+func (cm *CronManager) SetToolExecutor(executor *tools.Executor) {
+	cm.taskMutex.Lock()
+	defer cm.taskMutex.Unlock()
+	cm.tools = executor
+}
+
+// migrateToolTask upgrades the stored call of a tool task and writes it back
+// to the payload with its new schema version
+func (cm *CronManager) migrateToolTask(task *Task) error {
+	if task.Command != CommandTool || cm.tools == nil {
+		return nil
+	}
+
+	call, err := toolCallFromPayload(task.Payload)
+	if err != nil {
+		return err
+	}
+	migrated, err := cm.tools.MigrateCall(call)
+	if err != nil {
+		return err
+	}
+	task.Payload["params"] = migrated.Params
+	task.Payload["schemaVersion"] = migrated.SchemaVersion
+	return nil
+}
+
+// handleToolTask runs the tool call of a task. Cancelling ctx stops the tool,
+// killing any process it started.
+func (cm *CronManager) handleToolTask(ctx context.Context, task *Task) error {
+	cm.taskMutex.RLock()
+	executor := cm.tools
+	call, err := toolCallFromPayload(task.Payload)
+	cm.taskMutex.RUnlock()
+
+	if executor == nil {
+		return fmt.Errorf("no tool executor configured for task %s", task.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := executor.ExecuteCall(ctx, call); err != nil {
+		return err
+	}
+	return nil
+}
+
+// toolCallFromPayload reads the tool call stored in a task payload
+func toolCallFromPayload(payload map[string]interface{}) (tools.ToolCall, error) {
+	name, _ := payload["tool"].(string)
+	if name == "" {
+		return tools.ToolCall{}, fmt.Errorf("tool task payload has no tool name")
+	}
+
+	call := tools.ToolCall{Name: name, Params: map[string]interface{}{}}
+	if params, ok := payload["params"].(map[string]interface{}); ok {
+		call.Params = params
+	}
+	// JSON numbers decode as float64
+	switch version := payload["schemaVersion"].(type) {
+	case int:
+		call.SchemaVersion = version
+	case float64:
+		call.SchemaVersion = int(version)
+	}
+	return call, nil
+}
@@ -0,0 +1,196 @@
+// Package external calls external services such as webhooks with their own
+// timeout and retry policy, independent of the AI provider clients
+package external
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"goclaw/internal/config"
+)
+
+const (
+	// DefaultTimeout is the per-attempt timeout of an external call
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxRetries is the number of retries of a failed idempotent call
+	DefaultMaxRetries = 2
+
+	// DefaultBackoff is the delay before the first retry
+	DefaultBackoff = 500 * time.Millisecond
+)
+
+// Policy is the timeout and retry policy of calls to one external target
+type Policy struct {
+	Timeout    time.Duration // Per attempt
+	MaxRetries int           // Retries after the first attempt; idempotent methods only
+	Backoff    time.Duration // Delay before the first retry, doubled for each further one
+}
+
+// DefaultPolicy returns the policy used when nothing is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:    DefaultTimeout,
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// PolicyFor builds the policy of a target: the defaults, overridden by the
+// external section, overridden by the target's entry in external.targets
+func PolicyFor(cfg config.ExternalConfig, target string) (Policy, error) {
+	policy := DefaultPolicy()
+	if err := policy.apply(cfg.ExternalCallConfig); err != nil {
+		return Policy{}, err
+	}
+	if override, ok := cfg.Targets[target]; ok {
+		if err := policy.apply(override); err != nil {
+			return Policy{}, fmt.Errorf("external target %s: %w", target, err)
+		}
+	}
+	return policy, nil
+}
+
+// MaxDuration is the longest a call can take: every attempt timing out, plus
+// the backoff before each retry
+func (p Policy) MaxDuration() time.Duration {
+	total := time.Duration(p.MaxRetries+1) * p.Timeout
+	backoff := p.Backoff
+	for i := 0; i < p.MaxRetries; i++ {
+		total += backoff
+		backoff *= 2
+	}
+	return total
+}
+
+// apply overrides the policy with the fields set in a config entry
+func (p *Policy) apply(call config.ExternalCallConfig) error {
+	if call.Timeout != "" {
+		timeout, err := time.ParseDuration(call.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout: %q", call.Timeout)
+		}
+		p.Timeout = timeout
+	}
+	if call.MaxRetries != nil {
+		if *call.MaxRetries < 0 {
+			return fmt.Errorf("invalid maxRetries: %d", *call.MaxRetries)
+		}
+		p.MaxRetries = *call.MaxRetries
+	}
+	if call.Backoff != "" {
+		backoff, err := time.ParseDuration(call.Backoff)
+		if err != nil || backoff < 0 {
+			return fmt.Errorf("invalid backoff: %q", call.Backoff)
+		}
+		p.Backoff = backoff
+	}
+	return nil
+}
+
+// Client sends requests to an external target under its policy
+type Client struct {
+	HTTPClient *http.Client
+	Policy     Policy
+}
+
+// NewClient creates a client with the given policy
+func NewClient(policy Policy) *Client {
+	return &Client{
+		HTTPClient: &http.Client{},
+		Policy:     policy,
+	}
+}
+
+// Do sends the request, giving each attempt Policy.Timeout. Idempotent
+// requests are retried on network errors, 429 and 5xx responses; other
+// methods are sent once so a retry can't repeat a side effect.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	retries := 0
+	if IsIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil) {
+		retries = c.Policy.MaxRetries
+	}
+
+	backoff := c.Policy.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req, attempt)
+		if attempt >= retries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt sends one try of the request. The attempt's timeout covers reading
+// the body too, so it is only cancelled once the body is closed.
+func (c *Client) attempt(ctx context.Context, req *http.Request, attempt int) (*http.Response, error) {
+	timeout := c.Policy.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+
+	try := req.Clone(attemptCtx)
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		try.Body = body
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(try)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// IsIdempotent reports whether repeating a request with this method has the
+// same effect as sending it once
+func IsIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether an attempt failed in a way worth retrying
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// cancelOnClose releases an attempt's context once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,133 @@
+package external
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goclaw/internal/config"
+)
+
+// flakyServer fails the first failures requests with 503, then answers "ok"
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(append([]byte("ok "), body...))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestClientRetriesFlakyWebhook(t *testing.T) {
+	server, calls := flakyServer(t, 2)
+
+	retries := 2
+	policy, err := PolicyFor(config.ExternalConfig{
+		ExternalCallConfig: config.ExternalCallConfig{Timeout: "1s", MaxRetries: &retries, Backoff: "1ms"},
+	}, "webhook")
+	if err != nil {
+		t.Fatalf("PolicyFor() error = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+	resp, err := NewClient(policy).Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok payload" {
+		t.Errorf("response = %d %q, want 200 with the replayed body", resp.StatusCode, body)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestClientDoesNotRetryNonIdempotent(t *testing.T) {
+	server, calls := flakyServer(t, 1)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	resp, err := NewClient(Policy{Timeout: time.Second, MaxRetries: 3, Backoff: time.Millisecond}).Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the first failure", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestClientAttemptTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := NewClient(Policy{Timeout: 50 * time.Millisecond, MaxRetries: 1}).Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("calls = %d, want the slow attempt to time out and be retried", got)
+	}
+}
+
+func TestPolicyForTargetOverrides(t *testing.T) {
+	none := 0
+	cfg := config.ExternalConfig{
+		ExternalCallConfig: config.ExternalCallConfig{Timeout: "5s"},
+		Targets: map[string]config.ExternalCallConfig{
+			"notifier": {Timeout: "2s", MaxRetries: &none},
+		},
+	}
+
+	policy, err := PolicyFor(cfg, "webhook")
+	if err != nil {
+		t.Fatalf("PolicyFor(webhook) error = %v", err)
+	}
+	if policy.Timeout != 5*time.Second || policy.MaxRetries != DefaultMaxRetries || policy.Backoff != DefaultBackoff {
+		t.Errorf("webhook policy = %+v, want the section timeout and default retries", policy)
+	}
+
+	policy, err = PolicyFor(cfg, "notifier")
+	if err != nil {
+		t.Fatalf("PolicyFor(notifier) error = %v", err)
+	}
+	if policy.Timeout != 2*time.Second || policy.MaxRetries != 0 {
+		t.Errorf("notifier policy = %+v, want the target overrides", policy)
+	}
+
+	cfg.Targets["broken"] = config.ExternalCallConfig{Timeout: "soon"}
+	if _, err := PolicyFor(cfg, "broken"); err == nil {
+		t.Error("PolicyFor() should reject an invalid timeout")
+	}
+}
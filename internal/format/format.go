@@ -0,0 +1,215 @@
+// Package format renders chat, tool and memory output for different client surfaces
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+)
+
+// Formatter names
+const (
+	Plain    = "plain"
+	Markdown = "markdown"
+	JSON     = "json"
+)
+
+// Formatter formats user-facing output for a client surface
+type Formatter interface {
+	Name() string
+	ContentType() string
+	FormatChat(role, content string) string
+	FormatToolResult(toolName string, result *tools.ToolResult) string
+	FormatMemoryResults(results []memory.MemorySearchResult) string
+}
+
+var formatters = map[string]Formatter{
+	Plain:    PlainFormatter{},
+	Markdown: MarkdownFormatter{},
+	JSON:     JSONFormatter{},
+}
+
+// Get returns the formatter with the given name
+func Get(name string) (Formatter, bool) {
+	f, ok := formatters[strings.ToLower(name)]
+	return f, ok
+}
+
+// Negotiate selects a formatter from the "format" query parameter, then the
+// Accept header, falling back to the named default
+func Negotiate(r *http.Request, fallback string) Formatter {
+	if f, ok := Get(r.URL.Query().Get("format")); ok {
+		return f
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		switch mediaType {
+		case "text/plain":
+			return formatters[Plain]
+		case "text/markdown":
+			return formatters[Markdown]
+		case "application/json":
+			return formatters[JSON]
+		}
+	}
+
+	if f, ok := Get(fallback); ok {
+		return f
+	}
+	return formatters[JSON]
+}
+
+// PlainFormatter renders plain text for terminals
+type PlainFormatter struct{}
+
+// Name returns the formatter name
+func (PlainFormatter) Name() string { return Plain }
+
+// ContentType returns the HTTP content type
+func (PlainFormatter) ContentType() string { return "text/plain; charset=utf-8" }
+
+// FormatChat formats a chat message
+func (PlainFormatter) FormatChat(role, content string) string {
+	return fmt.Sprintf("%s: %s", roleLabel(role), content)
+}
+
+// FormatToolResult formats a tool result
+func (PlainFormatter) FormatToolResult(toolName string, result *tools.ToolResult) string {
+	if result == nil || !result.Success {
+		return fmt.Sprintf("Tool %s failed: %s", toolName, resultError(result))
+	}
+	return fmt.Sprintf("Tool %s succeeded:\n%s", toolName, renderData(result.Data))
+}
+
+// FormatMemoryResults formats memory search results
+func (PlainFormatter) FormatMemoryResults(results []memory.MemorySearchResult) string {
+	var sb strings.Builder
+	sb.WriteString("Memory Search Results:\n")
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("  [%.2f] %s\n", r.Score, r.Entry.Content))
+	}
+	if len(results) == 0 {
+		sb.WriteString("  No memories found\n")
+	}
+	return sb.String()
+}
+
+// MarkdownFormatter renders Markdown for the web UI and chat channels
+type MarkdownFormatter struct{}
+
+// Name returns the formatter name
+func (MarkdownFormatter) Name() string { return Markdown }
+
+// ContentType returns the HTTP content type
+func (MarkdownFormatter) ContentType() string { return "text/markdown; charset=utf-8" }
+
+// FormatChat formats a chat message
+func (MarkdownFormatter) FormatChat(role, content string) string {
+	return fmt.Sprintf("**%s:** %s", roleLabel(role), content)
+}
+
+// FormatToolResult formats a tool result
+func (MarkdownFormatter) FormatToolResult(toolName string, result *tools.ToolResult) string {
+	if result == nil || !result.Success {
+		return fmt.Sprintf("### Tool `%s`\n\n❌ Failed: %s\n", toolName, resultError(result))
+	}
+	return fmt.Sprintf("### Tool `%s`\n\n✅ Success\n\n```\n%s\n```\n", toolName, renderData(result.Data))
+}
+
+// FormatMemoryResults formats memory search results
+func (MarkdownFormatter) FormatMemoryResults(results []memory.MemorySearchResult) string {
+	var sb strings.Builder
+	sb.WriteString("### Memory Search Results\n\n")
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("- **%.2f** %s\n", r.Score, r.Entry.Content))
+	}
+	if len(results) == 0 {
+		sb.WriteString("_No memories found_\n")
+	}
+	return sb.String()
+}
+
+// JSONFormatter renders JSON for API clients
+type JSONFormatter struct{}
+
+// Name returns the formatter name
+func (JSONFormatter) Name() string { return JSON }
+
+// ContentType returns the HTTP content type
+func (JSONFormatter) ContentType() string { return "application/json" }
+
+// FormatChat formats a chat message
+func (JSONFormatter) FormatChat(role, content string) string {
+	return marshal(map[string]interface{}{
+		"role":    role,
+		"content": content,
+	})
+}
+
+// FormatToolResult formats a tool result
+func (JSONFormatter) FormatToolResult(toolName string, result *tools.ToolResult) string {
+	if result == nil {
+		result = &tools.ToolResult{Error: resultError(nil)}
+	}
+	return marshal(map[string]interface{}{
+		"tool":    toolName,
+		"success": result.Success,
+		"data":    result.Data,
+		"error":   result.Error,
+	})
+}
+
+// FormatMemoryResults formats memory search results
+func (JSONFormatter) FormatMemoryResults(results []memory.MemorySearchResult) string {
+	if results == nil {
+		results = []memory.MemorySearchResult{}
+	}
+	return marshal(map[string]interface{}{
+		"results": results,
+	})
+}
+
+// roleLabel returns the display label for a message role
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "You"
+	case "system":
+		return "System"
+	default:
+		return "Assistant"
+	}
+}
+
+// resultError returns the error text of a failed result
+func resultError(result *tools.ToolResult) string {
+	if result == nil || result.Error == "" {
+		return "unknown error"
+	}
+	return result.Error
+}
+
+// renderData renders tool output, keeping strings as-is
+func renderData(data interface{}) string {
+	if s, ok := data.(string); ok {
+		return s
+	}
+	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", data)
+	}
+	return string(dataJSON)
+}
+
+func marshal(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	return string(data)
+}
@@ -0,0 +1,113 @@
+package format
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+)
+
+var (
+	okResult     = &tools.ToolResult{Success: true, Data: "line one\nline two"}
+	failedResult = &tools.ToolResult{Success: false, Error: "file not found"}
+	memories     = []memory.MemorySearchResult{
+		{Entry: memory.MemoryEntry{Content: "likes tea"}, Score: 0.91},
+	}
+)
+
+func TestPlainFormatter(t *testing.T) {
+	f := PlainFormatter{}
+
+	if got := f.FormatChat("assistant", "Hi there"); got != "Assistant: Hi there" {
+		t.Errorf("FormatChat() = %q", got)
+	}
+	if got := f.FormatToolResult("read", okResult); got != "Tool read succeeded:\nline one\nline two" {
+		t.Errorf("FormatToolResult() = %q", got)
+	}
+	if got := f.FormatToolResult("read", failedResult); got != "Tool read failed: file not found" {
+		t.Errorf("FormatToolResult() failure = %q", got)
+	}
+	if got := f.FormatMemoryResults(memories); !strings.Contains(got, "[0.91] likes tea") {
+		t.Errorf("FormatMemoryResults() = %q", got)
+	}
+	if got := f.FormatMemoryResults(nil); !strings.Contains(got, "No memories found") {
+		t.Errorf("FormatMemoryResults(nil) = %q", got)
+	}
+}
+
+func TestMarkdownFormatter(t *testing.T) {
+	f := MarkdownFormatter{}
+
+	if got := f.FormatChat("user", "Hello"); got != "**You:** Hello" {
+		t.Errorf("FormatChat() = %q", got)
+	}
+	if got := f.FormatToolResult("read", okResult); !strings.Contains(got, "### Tool `read`") || !strings.Contains(got, "```\nline one\nline two\n```") {
+		t.Errorf("FormatToolResult() = %q", got)
+	}
+	if got := f.FormatToolResult("read", failedResult); !strings.Contains(got, "Failed: file not found") {
+		t.Errorf("FormatToolResult() failure = %q", got)
+	}
+	if got := f.FormatMemoryResults(memories); !strings.Contains(got, "- **0.91** likes tea") {
+		t.Errorf("FormatMemoryResults() = %q", got)
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	f := JSONFormatter{}
+
+	var chat map[string]string
+	if err := json.Unmarshal([]byte(f.FormatChat("assistant", "Hi")), &chat); err != nil {
+		t.Fatalf("FormatChat() is not valid JSON: %v", err)
+	}
+	if chat["role"] != "assistant" || chat["content"] != "Hi" {
+		t.Errorf("FormatChat() = %v", chat)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(f.FormatToolResult("read", failedResult)), &result); err != nil {
+		t.Fatalf("FormatToolResult() is not valid JSON: %v", err)
+	}
+	if result["tool"] != "read" || result["success"] != false || result["error"] != "file not found" {
+		t.Errorf("FormatToolResult() = %v", result)
+	}
+
+	var search struct {
+		Results []memory.MemorySearchResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(f.FormatMemoryResults(memories)), &search); err != nil {
+		t.Fatalf("FormatMemoryResults() is not valid JSON: %v", err)
+	}
+	if len(search.Results) != 1 || search.Results[0].Entry.Content != "likes tea" {
+		t.Errorf("FormatMemoryResults() = %+v", search)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   string
+	}{
+		{"query param wins", "/api/chat?format=markdown", "text/plain", Markdown},
+		{"accept plain", "/api/chat", "text/plain", Plain},
+		{"accept markdown with params", "/api/chat", "text/markdown; charset=utf-8", Markdown},
+		{"browser accept falls back", "/api/chat", "text/html,*/*;q=0.8", JSON},
+		{"unknown query falls back to accept", "/api/chat?format=xml", "text/plain", Plain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := Negotiate(r, JSON).Name(); got != tt.want {
+				t.Errorf("Negotiate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
@@ -64,12 +63,12 @@ func IsHeartbeatContentEffectivelyEmpty(content string) bool {
 		}
 		
 		// 跳过markdown标题行 (# 后跟空格或行尾)
-		if matched, _ := regexp.MatchString(`^#+(\s|$)`, line); matched {
+		if headingPattern.MatchString(line) {
 			continue
 		}
 		
 		// 跳过空的markdown列表项
-		if matched, _ := regexp.MatchString(`^[-*+]\s*(\[[\sXx]?\]\s*)?$`, line); matched {
+		if emptyListItemPattern.MatchString(line) {
 			continue
 		}
 		
@@ -104,25 +103,75 @@ func (hm *HeartbeatManager) RunOnce(ctx context.Context) error {
 		prompt = DefaultHeartbeatPrompt
 	}
 	
-	// 构建心跳消息
+	// 构建心跳消息，附上解析出的待办任务
 	heartbeatMsg := fmt.Sprintf("%s\n\nHEARTBEAT.md content:\n%s", prompt, contentStr)
+	tasks := ParseTasks(contentStr)
+	if len(tasks) > 0 {
+		heartbeatMsg += fmt.Sprintf("\n\nPending tasks:\n%s\nFor each task you complete, list its number on a final line, e.g. \"DONE: 1, 3\".", FormatTasks(tasks))
+	}
 	
-	if hm.aiClient != nil {
-		// TODO: 实际调用AI处理心跳
-		// resp, err := hm.aiClient.SendMessage(ctx, "user", heartbeatMsg)
-		// if err != nil {
-		//     return err
-		// }
-		// 暂时模拟AI响应
-		fmt.Printf("Heartbeat processed: %s\n", heartbeatMsg)
-	} else {
+	client := hm.heartbeatClient()
+	if client == nil {
 		// 没有AI客户端，直接发送HEARTBEAT_OK
 		return hm.sendHeartbeatOK()
 	}
-	
+
+	// 按 agent.timeouts.heartbeat 限制AI调用时间，超时返回 *ai.TimeoutError
+	timeout, err := ai.ResolveTimeout(ai.UseHeartbeat, hm.cfg.Agent.Timeouts.Heartbeat)
+	if err != nil {
+		return err
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 使用心跳专用模型（未配置时由客户端选择默认模型）
+	resp, err := client.ChatCompletion(callCtx, ai.ChatCompletionRequest{
+		Model: hm.cfg.Heartbeat.Model,
+		Messages: []ai.Message{
+			{Role: "user", Content: heartbeatMsg},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("heartbeat AI call failed: %w", ai.TimeoutErr(callCtx, err, ai.UseHeartbeat, timeout))
+	}
+	if len(resp.Choices) == 0 {
+		return fmt.Errorf("heartbeat AI call returned no choices")
+	}
+
+	reply := strings.TrimSpace(resp.Choices[0].Message.Content)
+
+	// 报告已处理的任务，并按配置在文件中勾选
+	done := CompletedTasks(reply, tasks)
+	for _, task := range done {
+		fmt.Printf("Heartbeat completed task %d: %s\n", task.Number, task.Text)
+	}
+	if len(done) > 0 && hm.cfg.Heartbeat.CheckOff {
+		if err := checkOffFile(heartbeatFile, done); err != nil {
+			return fmt.Errorf("failed to check off heartbeat tasks: %w", err)
+		}
+	}
+
+	if strings.Contains(reply, "HEARTBEAT_OK") {
+		return hm.sendHeartbeatOK()
+	}
+	fmt.Printf("Heartbeat response: %s\n", reply)
+
 	return nil
 }
 
+// heartbeatClient 返回心跳使用的AI客户端：优先使用配置的心跳提供商，找不到时记录警告并回退到主客户端
+func (hm *HeartbeatManager) heartbeatClient() ai.Client {
+	if provider := hm.cfg.Heartbeat.Provider; provider != "" {
+		if multiClient, ok := hm.aiClient.(*ai.MultiProviderClient); ok {
+			if client, exists := multiClient.GetProvider(provider); exists {
+				return client
+			}
+		}
+		fmt.Printf("Warning: heartbeat provider %q is not configured, using the main AI client\n", provider)
+	}
+	return hm.aiClient
+}
+
 // sendHeartbeatOK 发送心跳确认
 func (hm *HeartbeatManager) sendHeartbeatOK() error {
 	fmt.Println("HEARTBEAT_OK")
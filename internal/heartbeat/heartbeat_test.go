@@ -0,0 +1,88 @@
+package heartbeat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goclaw/internal/config"
+	"goclaw/pkg/ai"
+)
+
+// recordingClient records the model of each request
+type recordingClient struct {
+	models []string
+}
+
+func (c *recordingClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	c.models = append(c.models, req.Model)
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: "HEARTBEAT_OK"}}},
+	}, nil
+}
+
+func (c *recordingClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func newHeartbeatWorkspace(t *testing.T) string {
+	t.Helper()
+
+	workspace := t.TempDir()
+	content := "# Tasks\n\n- [ ] Check the inbox\n"
+	if err := os.WriteFile(filepath.Join(workspace, "HEARTBEAT.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write HEARTBEAT.md: %v", err)
+	}
+	return workspace
+}
+
+func TestRunOnceUsesHeartbeatModel(t *testing.T) {
+	client := &recordingClient{}
+	cfg := &config.Config{Heartbeat: config.HeartbeatConfig{Model: "glm-4-flash"}}
+
+	hm := NewHeartbeatManager(cfg, client, newHeartbeatWorkspace(t))
+	if err := hm.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	if len(client.models) != 1 || client.models[0] != "glm-4-flash" {
+		t.Errorf("requested models = %v, want [glm-4-flash]", client.models)
+	}
+}
+
+func TestRunOnceUsesHeartbeatProvider(t *testing.T) {
+	main := &recordingClient{}
+	cheap := &recordingClient{}
+
+	multi := ai.NewMultiProviderClient()
+	multi.AddProvider("zhipu", main)
+	multi.AddProvider("cheap", cheap)
+
+	cfg := &config.Config{Heartbeat: config.HeartbeatConfig{Provider: "cheap"}}
+	hm := NewHeartbeatManager(cfg, multi, newHeartbeatWorkspace(t))
+	if err := hm.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	if len(cheap.models) != 1 {
+		t.Errorf("heartbeat provider received %d requests, want 1", len(cheap.models))
+	}
+	if len(main.models) != 0 {
+		t.Errorf("main provider received %d requests, want 0", len(main.models))
+	}
+}
+
+func TestRunOnceFallsBackToMainClient(t *testing.T) {
+	client := &recordingClient{}
+	cfg := &config.Config{Heartbeat: config.HeartbeatConfig{Provider: "missing"}}
+
+	hm := NewHeartbeatManager(cfg, client, newHeartbeatWorkspace(t))
+	if err := hm.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	if len(client.models) != 1 || client.models[0] != "" {
+		t.Errorf("requested models = %v, want one request with the default model", client.models)
+	}
+}
@@ -0,0 +1,127 @@
+package heartbeat
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// headingPattern 匹配markdown标题行 (# 后跟空格或行尾)
+	headingPattern = regexp.MustCompile(`^#+(\s|$)`)
+
+	// emptyListItemPattern 匹配空的markdown列表项
+	emptyListItemPattern = regexp.MustCompile(`^[-*+]\s*(\[[\sXx]?\]\s*)?$`)
+
+	// taskPattern 匹配清单项，捕获勾选标记和任务文本
+	taskPattern = regexp.MustCompile(`^\s*[-*+]\s*\[([\sXx]?)\]\s*(\S.*)$`)
+
+	// doneReplyPattern 匹配AI回复中的已完成任务编号，例如 "DONE: 1, 3"
+	doneReplyPattern = regexp.MustCompile(`(?im)^\s*DONE:\s*([\d,\s]+)$`)
+)
+
+// Task HEARTBEAT.md中的一个清单任务
+type Task struct {
+	Number int    // 在待办任务中的序号，从1开始
+	Line   int    // 在文件中的行号，从0开始
+	Text   string // 任务内容
+}
+
+// ParseTasks 解析HEARTBEAT.md中的清单项，只返回未勾选的任务（已勾选视为已完成）
+func ParseTasks(content string) []Task {
+	var tasks []Task
+	for i, line := range strings.Split(content, "\n") {
+		match := taskPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil || strings.TrimSpace(match[1]) != "" {
+			continue
+		}
+		tasks = append(tasks, Task{
+			Number: len(tasks) + 1,
+			Line:   i,
+			Text:   strings.TrimSpace(match[2]),
+		})
+	}
+	return tasks
+}
+
+// FormatTasks 将待办任务格式化为带编号的列表，用于心跳提示
+func FormatTasks(tasks []Task) string {
+	var sb strings.Builder
+	for _, task := range tasks {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", task.Number, task.Text))
+	}
+	return sb.String()
+}
+
+// CompletedTasks 从AI回复的 "DONE: 1, 3" 行中找出已处理的任务
+func CompletedTasks(reply string, tasks []Task) []Task {
+	byNumber := make(map[int]Task, len(tasks))
+	for _, task := range tasks {
+		byNumber[task.Number] = task
+	}
+
+	var done []Task
+	seen := make(map[int]bool)
+	for _, match := range doneReplyPattern.FindAllStringSubmatch(reply, -1) {
+		for _, field := range strings.Split(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || seen[n] {
+				continue
+			}
+			if task, ok := byNumber[n]; ok {
+				seen[n] = true
+				done = append(done, task)
+			}
+		}
+	}
+	return done
+}
+
+// CheckOffTasks 将指定任务在内容中勾选为 "- [x]"，返回新内容
+func CheckOffTasks(content string, tasks []Task) string {
+	lines := strings.Split(content, "\n")
+	for _, task := range tasks {
+		if task.Line < 0 || task.Line >= len(lines) {
+			continue
+		}
+		line := lines[task.Line]
+		if loc := taskPattern.FindStringSubmatchIndex(line); loc != nil {
+			// loc[2]:loc[3] 是勾选标记的位置
+			lines[task.Line] = line[:loc[2]] + "x" + line[loc[3]:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkOffFile 在HEARTBEAT.md文件中勾选已完成的任务。AI调用期间文件可能已被编辑，
+// 因此重新读取文件，并按任务文本而不是行号找到要勾选的任务
+func checkOffFile(path string, tasks []Task) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	current := ParseTasks(string(content))
+	used := make(map[int]bool)
+	var matched []Task
+	for _, task := range tasks {
+		for _, candidate := range current {
+			if !used[candidate.Line] && candidate.Text == task.Text {
+				used[candidate.Line] = true
+				matched = append(matched, candidate)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	return os.WriteFile(path, []byte(CheckOffTasks(string(content), matched)), info.Mode().Perm())
+}
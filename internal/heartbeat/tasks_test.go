@@ -0,0 +1,135 @@
+package heartbeat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goclaw/internal/config"
+	"goclaw/pkg/ai"
+)
+
+const mixedHeartbeat = `# Daily
+
+- [x] Water the plants
+- [ ] Check the inbox
+* [X] Back up notes
+- [ ] Review calendar
+- [ ]
+Some free-form note
+`
+
+// replyClient always answers with a fixed reply
+type replyClient struct {
+	reply string
+}
+
+func (c *replyClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: c.reply}}},
+	}, nil
+}
+
+func (c *replyClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestParseTasksReturnsUnchecked(t *testing.T) {
+	tasks := ParseTasks(mixedHeartbeat)
+
+	want := []Task{
+		{Number: 1, Line: 3, Text: "Check the inbox"},
+		{Number: 2, Line: 5, Text: "Review calendar"},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("ParseTasks() = %+v, want %+v", tasks, want)
+	}
+	for i := range want {
+		if tasks[i] != want[i] {
+			t.Errorf("task %d = %+v, want %+v", i, tasks[i], want[i])
+		}
+	}
+}
+
+func TestCompletedTasks(t *testing.T) {
+	tasks := ParseTasks(mixedHeartbeat)
+
+	done := CompletedTasks("Inbox is clear.\nDONE: 1, 7, 1", tasks)
+	if len(done) != 1 || done[0].Text != "Check the inbox" {
+		t.Errorf("CompletedTasks() = %+v, want only task 1", done)
+	}
+	if done := CompletedTasks("HEARTBEAT_OK", tasks); len(done) != 0 {
+		t.Errorf("CompletedTasks() without DONE line = %+v", done)
+	}
+}
+
+func TestRunOnceChecksOffCompletedTasks(t *testing.T) {
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "HEARTBEAT.md")
+	if err := os.WriteFile(path, []byte(mixedHeartbeat), 0644); err != nil {
+		t.Fatalf("failed to write HEARTBEAT.md: %v", err)
+	}
+
+	cfg := &config.Config{Heartbeat: config.HeartbeatConfig{CheckOff: true}}
+	hm := NewHeartbeatManager(cfg, &replyClient{reply: "Reviewed it.\nDONE: 2"}, workspace)
+	if err := hm.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read HEARTBEAT.md: %v", err)
+	}
+	tasks := ParseTasks(string(content))
+	if len(tasks) != 1 || tasks[0].Text != "Check the inbox" {
+		t.Errorf("remaining tasks = %+v, want only \"Check the inbox\"", tasks)
+	}
+}
+
+// editingClient edits HEARTBEAT.md while the heartbeat waits for the model
+type editingClient struct {
+	path   string
+	edited string
+	reply  string
+}
+
+func (c *editingClient) ChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	if err := os.WriteFile(c.path, []byte(c.edited), 0644); err != nil {
+		return nil, err
+	}
+	return &ai.ChatCompletionResponse{
+		Choices: []ai.Choice{{Message: ai.Message{Role: "assistant", Content: c.reply}}},
+	}, nil
+}
+
+func (c *editingClient) ChatCompletionStream(ctx context.Context, req ai.ChatCompletionRequest) (<-chan ai.StreamChunk, error) {
+	return nil, ai.ErrStreamingUnsupported
+}
+
+func TestRunOnceChecksOffAfterConcurrentEdit(t *testing.T) {
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "HEARTBEAT.md")
+	if err := os.WriteFile(path, []byte(mixedHeartbeat), 0644); err != nil {
+		t.Fatalf("failed to write HEARTBEAT.md: %v", err)
+	}
+
+	// The user adds a task above the others and removes "Check the inbox"
+	edited := "# Daily\n\n- [ ] Call the bank\n- [x] Water the plants\n* [X] Back up notes\n- [ ] Review calendar\n"
+	client := &editingClient{path: path, edited: edited, reply: "DONE: 1, 2"}
+
+	cfg := &config.Config{Heartbeat: config.HeartbeatConfig{CheckOff: true}}
+	hm := NewHeartbeatManager(cfg, client, workspace)
+	if err := hm.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read HEARTBEAT.md: %v", err)
+	}
+	want := "# Daily\n\n- [ ] Call the bank\n- [x] Water the plants\n* [X] Back up notes\n- [x] Review calendar\n"
+	if string(content) != want {
+		t.Errorf("HEARTBEAT.md =\n%s\nwant the edit kept and only \"Review calendar\" checked off:\n%s", content, want)
+	}
+}
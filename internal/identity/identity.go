@@ -167,6 +167,25 @@ func (im *IdentityManager) GetIdentityDescription() string {
 	return desc
 }
 
+// Greeting 根据身份信息生成指定语言的欢迎语，目前支持 "zh" 和 "en"
+func (im *IdentityManager) Greeting(lang string) string {
+	name := "Goclaw"
+	emoji := ""
+	if identity := im.GetIdentity(); identity != nil {
+		if identity.Name != "" {
+			name = identity.Name
+		}
+		if identity.Emoji != "" {
+			emoji = " " + identity.Emoji
+		}
+	}
+
+	if lang == "en" {
+		return fmt.Sprintf("Hello! I'm %s%s. What can I do for you today?", name, emoji)
+	}
+	return fmt.Sprintf("您好！我是%s%s。今天我能为您做些什么？", name, emoji)
+}
+
 // ApplyToConfig 将身份信息应用到配置
 func (im *IdentityManager) ApplyToConfig(cfg *config.Config) {
 	identity := im.GetIdentity()
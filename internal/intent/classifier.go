@@ -0,0 +1,219 @@
+// Package intent classifies chat messages into structured intents
+package intent
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Action is the kind of request a message makes
+type Action string
+
+const (
+	ActionChat      Action = "chat"       // No structured intent; answer conversationally
+	ActionReadLines Action = "read_lines" // Show the first lines of a file
+)
+
+// DefaultLineCount is used when a read request doesn't say how many lines
+const DefaultLineCount = 3
+
+// Intent is the structured result of classifying a message
+type Intent struct {
+	Action    Action `json:"action"`
+	Path      string `json:"path,omitempty"`
+	LineCount int    `json:"lineCount,omitempty"`
+}
+
+// Verbs that ask to see a file. Chinese verbs are matched as substrings,
+// English ones as whole words.
+var (
+	chineseReadVerbs = []string{"展示", "显示", "读取", "查看", "看看", "看一下", "读", "打开", "输出", "打印"}
+	englishReadVerbs = map[string]bool{
+		"show": true, "read": true, "display": true, "print": true,
+		"view": true, "open": true, "cat": true, "head": true, "see": true,
+	}
+)
+
+// Line scope patterns, e.g. "前3行", "前三行", "开头几行", "第一行", "first 5 lines"
+var (
+	chineseLinesPattern = regexp.MustCompile(`(?:前|开头|头)\s*([0-9]+|[一二两三四五六七八九十]+)?\s*几?\s*行`)
+	chineseFirstLine    = regexp.MustCompile(`第(?:一|1)行`)
+	englishLinesPattern = regexp.MustCompile(`(?i)\b(?:first|top|head|opening)\s+(?:(\d+|one|two|three|four|five|six|seven|eight|nine|ten|few|couple of)\s+)?(lines?)\b`)
+)
+
+var englishNumbers = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"couple of": 2,
+}
+
+var chineseDigits = map[rune]int{
+	'一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5,
+	'六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// IntentClassifier is a rule engine that turns a message into an Intent.
+// It tokenizes paths and numbers properly instead of relying on substring
+// positions, so punctuation and file extensions don't confuse it.
+type IntentClassifier struct {
+	DefaultLineCount int // Lines to show when the message gives no count
+}
+
+// NewIntentClassifier creates a classifier with the default line count
+func NewIntentClassifier() *IntentClassifier {
+	return &IntentClassifier{DefaultLineCount: DefaultLineCount}
+}
+
+// Classify returns the structured intent of a message. Messages that are
+// not a recognized request classify as ActionChat.
+func (c *IntentClassifier) Classify(message string) Intent {
+	chat := Intent{Action: ActionChat}
+
+	path := ExtractPath(message)
+	if path == "" || !hasReadVerb(message) {
+		return chat
+	}
+
+	count, ok := c.lineCount(message)
+	if !ok {
+		return chat
+	}
+
+	return Intent{Action: ActionReadLines, Path: path, LineCount: count}
+}
+
+// lineCount finds the line scope of a read request; ok is false when the
+// message doesn't ask for a number of lines
+func (c *IntentClassifier) lineCount(message string) (int, bool) {
+	defaultCount := c.DefaultLineCount
+	if defaultCount <= 0 {
+		defaultCount = DefaultLineCount
+	}
+
+	if m := chineseLinesPattern.FindStringSubmatch(message); m != nil {
+		if n := parseChineseNumber(m[1]); n > 0 {
+			return n, true
+		}
+		return defaultCount, true
+	}
+	if chineseFirstLine.MatchString(message) {
+		return 1, true
+	}
+
+	if m := englishLinesPattern.FindStringSubmatch(message); m != nil {
+		word := strings.ToLower(m[1])
+		if n, err := strconv.Atoi(word); err == nil && n > 0 {
+			return n, true
+		}
+		if n, ok := englishNumbers[word]; ok {
+			return n, true
+		}
+		if word == "" && strings.ToLower(m[2]) == "line" {
+			return 1, true
+		}
+		return defaultCount, true
+	}
+
+	return 0, false
+}
+
+// hasReadVerb reports whether the message asks to see something
+func hasReadVerb(message string) bool {
+	for _, verb := range chineseReadVerbs {
+		if strings.Contains(message, verb) {
+			return true
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) || r > unicode.MaxASCII
+	})
+	for _, word := range words {
+		if englishReadVerbs[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractPath returns the first absolute or home-relative file path in a
+// message. A path runs until the first character that can't be part of one,
+// such as whitespace, CJK text or quotes; trailing punctuation is dropped.
+func ExtractPath(message string) string {
+	runes := []rune(message)
+	for i, r := range runes {
+		if r != '/' && r != '~' {
+			continue
+		}
+		// A path starts the message or follows something that isn't a path
+		// character; "://" belongs to a URL
+		if i > 0 && (isPathRune(runes[i-1]) || runes[i-1] == ':') {
+			continue
+		}
+		if r == '~' && (i+1 >= len(runes) || runes[i+1] != '/') {
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isPathRune(runes[end]) {
+			end++
+		}
+
+		path := strings.TrimRight(string(runes[i:end]), ".,;:!?")
+		if len(path) > 1 {
+			return path
+		}
+	}
+	return ""
+}
+
+// isPathRune reports whether r may appear in a path mentioned in chat
+func isPathRune(r rune) bool {
+	if r > unicode.MaxASCII {
+		return false
+	}
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	return strings.ContainsRune("/._-~+@%", r)
+}
+
+// parseChineseNumber parses Arabic digits or Chinese numerals up to 99,
+// returning 0 when s is empty or not a number
+func parseChineseNumber(s string) int {
+	if s == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+
+	runes := []rune(s)
+	if !strings.ContainsRune(s, '十') {
+		if len(runes) != 1 {
+			return 0
+		}
+		return chineseDigits[runes[0]]
+	}
+
+	// "十" = 10, "十二" = 12, "二十" = 20, "二十三" = 23
+	parts := strings.SplitN(s, "十", 2)
+	n := 10
+	if parts[0] != "" {
+		before := []rune(parts[0])
+		if len(before) != 1 || chineseDigits[before[0]] == 0 {
+			return 0
+		}
+		n = chineseDigits[before[0]] * 10
+	}
+	if parts[1] != "" {
+		after := []rune(parts[1])
+		if len(after) != 1 || chineseDigits[after[0]] == 0 {
+			return 0
+		}
+		n += chineseDigits[after[0]]
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package intent
+
+import "testing"
+
+func TestClassifyReadLines(t *testing.T) {
+	classifier := NewIntentClassifier()
+
+	tests := []struct {
+		message string
+		path    string
+		lines   int
+	}{
+		// Phrasings from the integration suite
+		{"给我展示/tmp/test-read-lines.txt这个文件的前三行", "/tmp/test-read-lines.txt", 3},
+		{"读取/tmp/test-natural-lang.txt的前3行", "/tmp/test-natural-lang.txt", 3},
+		{"帮我看看/tmp/test-natural-lang.txt的开头几行", "/tmp/test-natural-lang.txt", 3},
+		{"显示/tmp/test-natural-lang.txt文件的第一部分，只要前三行", "/tmp/test-natural-lang.txt", 3},
+		{"读取/tmp/non-existent-file-12345.txt的前三行", "/tmp/non-existent-file-12345.txt", 3},
+		// Other counts and wording
+		{"查看 /var/log/app.log 前5行", "/var/log/app.log", 5},
+		{"显示/etc/hosts的前两行", "/etc/hosts", 2},
+		{"打开/tmp/a.txt看第一行", "/tmp/a.txt", 1},
+		{"读一下/tmp/data.csv的前十二行。", "/tmp/data.csv", 12},
+		{"展示~/notes/todo.md的头几行", "~/notes/todo.md", 3},
+		{"show me the first 10 lines of /tmp/server.log.", "/tmp/server.log", 10},
+		{"Read the first line of /etc/hostname", "/etc/hostname", 1},
+		{"can you display the top five lines in /tmp/a.txt?", "/tmp/a.txt", 5},
+		{"print the first few lines of /tmp/a.txt", "/tmp/a.txt", 3},
+	}
+
+	for _, tt := range tests {
+		got := classifier.Classify(tt.message)
+		want := Intent{Action: ActionReadLines, Path: tt.path, LineCount: tt.lines}
+		if got != want {
+			t.Errorf("Classify(%q) = %+v, want %+v", tt.message, got, want)
+		}
+	}
+}
+
+func TestClassifyChat(t *testing.T) {
+	classifier := NewIntentClassifier()
+
+	messages := []string{
+		// Chat phrasings from the integration suite
+		"你好，Goclaw！",
+		"你好",
+		"记住我的名字是张三",
+		"测试消息",
+		"我的名字是什么？",
+		"我喜欢什么编程语言？",
+		// Near misses
+		"读取前三行",                            // No path
+		"/tmp/a.txt 是什么文件",                 // No read request
+		"查看 https://example.com/docs 的前三行", // A URL, not a file path
+		"how many lines does /tmp/a.txt have?",
+		"the first 3 lines of a poem, please",
+	}
+
+	for _, message := range messages {
+		if got := classifier.Classify(message); got.Action != ActionChat {
+			t.Errorf("Classify(%q) = %+v, want chat", message, got)
+		}
+	}
+}
+
+func TestClassifierDefaultLineCount(t *testing.T) {
+	classifier := &IntentClassifier{DefaultLineCount: 10}
+
+	got := classifier.Classify("看看/tmp/a.txt的前几行")
+	if got.LineCount != 10 {
+		t.Errorf("LineCount = %d, want 10", got.LineCount)
+	}
+}
+
+func TestParseChineseNumber(t *testing.T) {
+	tests := map[string]int{"": 0, "3": 3, "三": 3, "两": 2, "十": 10, "十二": 12, "二十": 20, "二十三": 23, "三三": 0}
+	for input, want := range tests {
+		if got := parseChineseNumber(input); got != want {
+			t.Errorf("parseChineseNumber(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
+
+func TestClassifyTier(t *testing.T) {
+	tests := []struct {
+		message string
+		tier    Tier
+	}{
+		{"你好", TierCheap},
+		{"你好呀！", TierCheap},
+		{"Hi there!", TierCheap},
+		{"thanks", TierCheap},
+		{"你好，帮我写一个冒泡排序", TierStandard},
+		{"hi, can you tell me the time in Tokyo?", TierStandard},
+		{"history of Rome", TierStandard},
+		{"What's the capital of France?", TierStandard},
+		{"分析一下这个架构的性能瓶颈", TierStrong},
+		{"Compare Raft and Paxos and explain the tradeoffs", TierStrong},
+		{"hello, why does this panic?\n```go\nvar m map[string]int\nm[\"a\"] = 1\n```", TierStrong},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyTier(tt.message); got != tt.tier {
+			t.Errorf("ClassifyTier(%q) = %s, want %s", tt.message, got, tt.tier)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package intent
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tier is how capable a model a message needs
+type Tier string
+
+const (
+	TierCheap    Tier = "cheap"    // Greetings, thanks and other small talk
+	TierStandard Tier = "standard" // Ordinary questions
+	TierStrong   Tier = "strong"   // Long or reasoning-heavy requests
+)
+
+// Thresholds for ClassifyTier, in runes
+const (
+	smallTalkMaxLength = 24
+	smallTalkMaxExtra  = 6 // Letters allowed after the small talk phrase
+	strongMinLength    = 400
+)
+
+// Small talk phrases. A short message starting with one of these and
+// followed by little more than punctuation classifies as TierCheap.
+var smallTalk = []string{
+	"你好", "您好", "嗨", "哈喽", "早上好", "下午好", "晚上好", "晚安", "谢谢", "多谢", "再见", "拜拜", "好的", "收到",
+	"hello", "hi", "hey", "good morning", "good afternoon", "good evening", "good night",
+	"thanks", "thank you", "bye", "goodbye", "ok", "okay", "cheers",
+}
+
+// Words that signal a request needs reasoning. Chinese words are matched as
+// substrings, English ones as whole words.
+var (
+	chineseReasoningWords = []string{"分析", "设计", "为什么", "比较", "推导", "证明", "优化", "重构", "架构", "调试", "权衡", "一步一步"}
+	englishReasoningWords = map[string]bool{
+		"analyze": true, "analyse": true, "design": true, "why": true, "compare": true,
+		"prove": true, "derive": true, "optimize": true, "refactor": true, "architecture": true,
+		"debug": true, "tradeoffs": true, "trade-offs": true, "explain": true, "plan": true,
+	}
+)
+
+// ClassifyTier picks the model tier for a message with cheap heuristics:
+// short small talk needs the cheap tier, long messages, code and reasoning
+// requests the strong tier, and everything else the standard tier.
+func ClassifyTier(message string) Tier {
+	message = strings.TrimSpace(message)
+	length := utf8.RuneCountInString(message)
+
+	if length >= strongMinLength || strings.Contains(message, "```") || hasReasoningWord(message) {
+		return TierStrong
+	}
+	if length <= smallTalkMaxLength && isSmallTalk(message) {
+		return TierCheap
+	}
+	return TierStandard
+}
+
+// isSmallTalk reports whether a message is a small talk phrase with at most
+// a few more letters, e.g. "hi there!" or "你好呀", and no question
+func isSmallTalk(message string) bool {
+	lower := strings.ToLower(message)
+	if strings.ContainsAny(lower, "?？") {
+		return false
+	}
+	for _, phrase := range smallTalk {
+		if !strings.HasPrefix(lower, phrase) {
+			continue
+		}
+		rest := strings.TrimPrefix(lower, phrase)
+		// "hi" must not match "history"
+		if r, _ := utf8.DecodeRuneInString(rest); r < unicode.MaxASCII && unicode.IsLetter(r) {
+			continue
+		}
+		if letterCount(rest) <= smallTalkMaxExtra {
+			return true
+		}
+	}
+	return false
+}
+
+// letterCount counts the letters and digits in s
+func letterCount(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// hasReasoningWord reports whether a message asks for reasoning
+func hasReasoningWord(message string) bool {
+	for _, word := range chineseReasoningWords {
+		if strings.Contains(message, word) {
+			return true
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return (!unicode.IsLetter(r) && r != '-') || r > unicode.MaxASCII
+	})
+	for _, word := range words {
+		if englishReasoningWords[word] {
+			return true
+		}
+	}
+	return false
+}
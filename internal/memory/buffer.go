@@ -2,11 +2,13 @@ package memory
 
 import (
 	"container/list"
+	"time"
 )
 
 // ConversationBuffer manages short-term conversation memory
 type ConversationBuffer struct {
 	maxSize int
+	ttl     time.Duration // Age at which entries expire, 0 for never
 	buffer  *list.List
 	entries map[string]*list.Element
 }
@@ -41,17 +43,35 @@ func (cb *ConversationBuffer) Add(entry MemoryEntry) {
 
 // GetRecent returns the most recent entries
 func (cb *ConversationBuffer) GetRecent(count int) []MemoryEntry {
+	return cb.GetRecentMatching(count, nil)
+}
+
+// GetRecentMatching returns the most recent entries keep accepts; a nil
+// keep accepts every entry. Expired entries are left out.
+func (cb *ConversationBuffer) GetRecentMatching(count int, keep func(MemoryEntry) bool) []MemoryEntry {
 	results := make([]MemoryEntry, 0, count)
+	now := time.Now()
 
 	elem := cb.buffer.Back()
 	for elem != nil && len(results) < count {
-		results = append(results, elem.Value.(MemoryEntry))
+		if entry := elem.Value.(MemoryEntry); !cb.expired(entry, now) && (keep == nil || keep(entry)) {
+			results = append(results, entry)
+		}
 		elem = elem.Prev()
 	}
 
 	return results
 }
 
+// All returns every entry, oldest first
+func (cb *ConversationBuffer) All() []MemoryEntry {
+	results := make([]MemoryEntry, 0, cb.buffer.Len())
+	for elem := cb.buffer.Front(); elem != nil; elem = elem.Next() {
+		results = append(results, elem.Value.(MemoryEntry))
+	}
+	return results
+}
+
 // Remove removes an entry by ID
 func (cb *ConversationBuffer) Remove(id string) {
 	if elem, exists := cb.entries[id]; exists {
@@ -60,6 +80,32 @@ func (cb *ConversationBuffer) Remove(id string) {
 	}
 }
 
+// Expire removes the entries older than the buffer's TTL and returns how
+// many it removed
+func (cb *ConversationBuffer) Expire() int {
+	if cb.ttl <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	removed := 0
+	for elem := cb.buffer.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(MemoryEntry); cb.expired(entry, now) {
+			cb.buffer.Remove(elem)
+			delete(cb.entries, entry.ID)
+			removed++
+		}
+		elem = next
+	}
+	return removed
+}
+
+// expired reports whether an entry is older than the buffer's TTL
+func (cb *ConversationBuffer) expired(entry MemoryEntry, now time.Time) bool {
+	return cb.ttl > 0 && now.Sub(entry.Timestamp) > cb.ttl
+}
+
 // Len returns the number of entries
 func (cb *ConversationBuffer) Len() int {
 	return cb.buffer.Len()
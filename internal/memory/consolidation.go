@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goclaw/internal/vector"
+)
+
+// DefaultConsolidateInterval is how often StartConsolidation runs Consolidate
+const DefaultConsolidateInterval = 10 * time.Minute
+
+// StartConsolidation runs Consolidate with embedder every interval, so
+// short-term memories move to long-term as they age past ConsolidateAfter.
+// When ctx is done it stops, letting a run in progress end at its next
+// batch, and closes the returned channel.
+func (m *MemoryStore) StartConsolidation(ctx context.Context, interval time.Duration, embedder vector.Embedder) <-chan struct{} {
+	if interval <= 0 {
+		interval = DefaultConsolidateInterval
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			report, err := m.Consolidate(ctx, embedder)
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Printf("Error consolidating memory: %v\n", err)
+				}
+				continue
+			}
+			if report.Consolidated > 0 || report.Skipped > 0 {
+				fmt.Printf("Consolidated %d memories into long-term memory (%d skipped)\n", report.Consolidated, report.Skipped)
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ExportEntries returns the memories of all three kinds whose metadata
+// matches filter, as SearchFiltered matches it, oldest first. Long-term
+// entries carry their embeddings, so ImportEntries restores them as they were.
+func (m *MemoryStore) ExportEntries(filter map[string]interface{}) []MemoryEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []MemoryEntry
+	for _, group := range [][]MemoryEntry{m.shortTerm.All(), m.longTerm.All(), m.workingSet.GetAll()} {
+		for _, entry := range group {
+			if matchesFilter(entry, filter) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries
+}
+
+// ImportEntries adds exported memories to the store, each to the memory of
+// its Type. An entry whose ID is already taken gets a new one, so importing
+// next to the original keeps both. It returns the number of entries added.
+func (m *MemoryStore) ImportEntries(entries []MemoryEntry) (int, error) {
+	for i, entry := range entries {
+		switch entry.Type {
+		case MemoryTypeShort, MemoryTypeLong, MemoryTypeWork:
+		default:
+			return 0, fmt.Errorf("memory %d has unknown type: %q", i+1, entry.Type)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taken := make(map[string]bool)
+	for _, group := range [][]MemoryEntry{m.shortTerm.All(), m.longTerm.All(), m.workingSet.GetAll()} {
+		for _, entry := range group {
+			taken[entry.ID] = true
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.ID == "" || taken[entry.ID] {
+			entry.ID = fmt.Sprintf("%s_imported_%d_%d", entry.Type, time.Now().UnixNano(), i)
+		}
+		taken[entry.ID] = true
+
+		switch entry.Type {
+		case MemoryTypeShort:
+			m.shortTerm.Add(entry)
+		case MemoryTypeLong:
+			embedding := entry.Embedding
+			entry.Embedding = nil
+			m.longTerm.Add(entry, embedding)
+		case MemoryTypeWork:
+			// JSON numbers come back as float64, priorities are ints
+			if priority, ok := entry.Metadata["priority"].(float64); ok {
+				entry.Metadata["priority"] = int(priority)
+			}
+			m.workingSet.Add(entry)
+		}
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Languages reported by DetectLanguage
+const (
+	LanguageChinese  = "zh"
+	LanguageJapanese = "ja"
+	LanguageKorean   = "ko"
+	LanguageEnglish  = "en"
+)
+
+// Translator translates text into a language, e.g. with a model call
+type Translator interface {
+	Translate(ctx context.Context, text, language string) (string, error)
+}
+
+// DetectLanguage guesses the language of text from the script most of it is
+// written in, counting CJK characters against Latin words. Kana outweighs
+// Han, since Japanese mixes both. Latin text is reported as English; text
+// without letters as "".
+func DetectLanguage(text string) string {
+	var han, kana, hangul, latin int
+	inWord := false
+	for _, r := range text {
+		isLatin := unicode.Is(unicode.Latin, r)
+		if isLatin && !inWord {
+			latin++
+		}
+		inWord = isLatin
+
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		}
+	}
+
+	switch {
+	case kana > 0 && kana+han >= hangul && kana+han >= latin:
+		return LanguageJapanese
+	case hangul > 0 && hangul >= han && hangul >= latin:
+		return LanguageKorean
+	case han > 0 && han >= latin:
+		return LanguageChinese
+	case latin > 0:
+		return LanguageEnglish
+	}
+	return ""
+}
+
+// isCJK reports whether r is in a script written without spaces between
+// words, or whose words are best matched in parts
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Tokenize splits text into lowercase search terms suited to its scripts:
+// words for text that separates them with spaces or punctuation, and
+// overlapping character bigrams for CJK runs, which have no word breaks to
+// split on. A lone CJK character is a term of its own.
+func Tokenize(text string) []string {
+	var terms []string
+	var word, run []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	flushRun := func() {
+		if len(run) == 1 {
+			terms = append(terms, string(run))
+		}
+		for i := 0; i+1 < len(run); i++ {
+			terms = append(terms, string(run[i:i+2]))
+		}
+		run = run[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			run = append(run, r)
+		case unicode.IsSpace(r) || unicode.IsPunct(r):
+			flushWord()
+			flushRun()
+		default:
+			flushRun()
+			word = append(word, r)
+		}
+	}
+	flushWord()
+	flushRun()
+	return terms
+}
+
+// needsTranslation reports whether text in language should be translated
+// into the store's canonical language
+func (m *MemoryStore) needsTranslation(language string) bool {
+	return m.config.Translator != nil && m.config.Language != "" &&
+		language != "" && language != m.config.Language
+}
+
+// translate translates text into the canonical language, returning "" when
+// it doesn't need translating or the translation fails
+func (m *MemoryStore) translate(ctx context.Context, text, language string) string {
+	if !m.needsTranslation(language) {
+		return ""
+	}
+	translated, err := m.config.Translator.Translate(ctx, text, m.config.Language)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(translated)
+}
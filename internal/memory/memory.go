@@ -5,6 +5,7 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -27,7 +28,11 @@ type MemoryEntry struct {
 	Content   string                 `json:"content"`
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Tags      []string               `json:"tags,omitempty"`
 	Embedding []float32              `json:"embedding,omitempty"`
+
+	Language    string `json:"language,omitempty"`    // Detected with DetectLanguage
+	Translation string `json:"translation,omitempty"` // Content in the store's canonical language, when translation is enabled
 }
 
 // MemoryStore manages all types of memory
@@ -41,9 +46,69 @@ type MemoryStore struct {
 
 // MemoryConfig holds memory configuration
 type MemoryConfig struct {
-	ShortTermMax  int     // Maximum short-term memories
-	WorkingMax    int     // Maximum working memory items
-	SimilarityCut float32 // Similarity threshold for long-term memory
+	ShortTermMax     int     // Maximum short-term memories
+	WorkingMax       int     // Maximum working memory items
+	SimilarityCut    float32 // Similarity threshold for long-term memory
+	ConsolidateBatch int     // Texts per EmbedBatch call in Consolidate, DefaultConsolidateBatch when 0
+
+	// ConsolidateAfter is how old a short-term memory must be before
+	// Consolidate moves it to long-term, DefaultConsolidateAfter when 0
+	ConsolidateAfter time.Duration
+
+	// ShortTermTTL is the age at which short-term memories are forgotten,
+	// whatever their number, unless consolidated to long-term before. 0
+	// keeps them until ShortTermMax pushes them out.
+	ShortTermTTL time.Duration
+
+	// Entries GetContext injects from each memory, DefaultContextLongTermK and
+	// DefaultContextShortTermK when 0
+	ContextLongTermK  int
+	ContextShortTermK int
+
+	// ContextShortTermMaxAge leaves short-term entries older than it out of
+	// GetContext, so a conversation resumed later isn't fed stale context.
+	// 0 includes them regardless of age.
+	ContextShortTermMaxAge time.Duration
+
+	// Language is the canonical language of long-term memory. With a
+	// Translator, memories and queries in other languages are translated
+	// into it so they can be matched across languages. Translating costs a
+	// model call per memory stored and per query, so it is opt-in.
+	Language   string
+	Translator Translator
+
+	// SameLanguageBoost is added to the score of results in the query's language
+	SameLanguageBoost float32
+}
+
+// DefaultConsolidateBatch is the default number of texts embedded per batch by Consolidate
+const DefaultConsolidateBatch = 16
+
+// DefaultConsolidateAfter is the default age at which Consolidate moves short-term memories
+const DefaultConsolidateAfter = time.Hour
+
+// Default number of entries GetContext injects from each memory
+const (
+	DefaultContextLongTermK  = 5
+	DefaultContextShortTermK = 10
+)
+
+// ContextLimits caps how many entries of each memory GetContext injects
+type ContextLimits struct {
+	LongTermK  int `json:"longTermK"`  // Most relevant long-term memories
+	ShortTermK int `json:"shortTermK"` // Most recent short-term memories
+
+	// ShortTermMaxAge leaves out short-term memories older than it, 0 for
+	// no age limit. With ShortTermK, whichever is tighter wins.
+	ShortTermMaxAge time.Duration `json:"shortTermMaxAgeNs,omitempty"`
+}
+
+// Validate rejects negative limits
+func (l ContextLimits) Validate() error {
+	if l.LongTermK < 0 || l.ShortTermK < 0 || l.ShortTermMaxAge < 0 {
+		return fmt.Errorf("context limits must not be negative")
+	}
+	return nil
 }
 
 // MemorySearchResult represents a memory search result
@@ -51,13 +116,17 @@ type MemorySearchResult struct {
 	Entry   MemoryEntry `json:"entry"`
 	Score   float32     `json:"score"`
 	Reasons []string    `json:"reasons,omitempty"`
+	Snippet string      `json:"snippet,omitempty"` // Excerpt with query terms highlighted
 }
 
 // NewMemoryStore creates a new memory store
 func NewMemoryStore(config MemoryConfig) *MemoryStore {
+	shortTerm := NewConversationBuffer(config.ShortTermMax)
+	shortTerm.ttl = config.ShortTermTTL
+
 	return &MemoryStore{
 		config:     config,
-		shortTerm:  NewConversationBuffer(config.ShortTermMax),
+		shortTerm:  shortTerm,
 		longTerm:   NewVectorMemory(),
 		workingSet: NewWorkingMemory(config.WorkingMax),
 	}
@@ -83,22 +152,32 @@ func (m *MemoryStore) AddShortTerm(content string, metadata map[string]interface
 		Content:   content,
 		Timestamp: time.Now(),
 		Metadata:  metadata,
+		Language:  DetectLanguage(content),
 	}
 
 	m.shortTerm.Add(entry)
 }
 
-// AddLongTerm adds a long-term memory with embedding
+// AddLongTerm adds a long-term memory with embedding. A "tags" metadata
+// value becomes the entry's Tags. When translation is enabled, a memory in
+// another language also stores its translation.
 func (m *MemoryStore) AddLongTerm(content string, embedding []float32, metadata map[string]interface{}) error {
+	tags, metadata := tagsFromMetadata(metadata)
+	language := DetectLanguage(content)
+	translation := m.translate(context.Background(), content, language)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	entry := MemoryEntry{
-		ID:        fmt.Sprintf("lt_%d", time.Now().UnixNano()),
-		Type:      MemoryTypeLong,
-		Content:   content,
-		Timestamp: time.Now(),
-		Metadata:  metadata,
+		ID:          fmt.Sprintf("lt_%d", time.Now().UnixNano()),
+		Type:        MemoryTypeLong,
+		Content:     content,
+		Timestamp:   time.Now(),
+		Metadata:    metadata,
+		Tags:        tags,
+		Language:    language,
+		Translation: translation,
 	}
 
 	return m.longTerm.Add(entry, embedding)
@@ -117,17 +196,30 @@ func (m *MemoryStore) AddWorking(content string, priority int) {
 		Metadata: map[string]interface{}{
 			"priority": priority,
 		},
+		Language: DetectLanguage(content),
 	}
 
 	m.workingSet.Add(entry)
 }
 
-// Search searches long-term memory
+// Search searches long-term memory. Without an embedding, entries are
+// matched on the query text instead, translated into the canonical language
+// when translation is enabled.
 func (m *MemoryStore) Search(ctx context.Context, query string, embedding []float32, limit int) ([]MemorySearchResult, error) {
+	return m.SearchFiltered(ctx, query, embedding, limit, nil)
+}
+
+// SearchFiltered is Search among the memories whose metadata has every
+// key/value of filter, matched as by Retag: values compare as text, and the
+// "tag" key matches a tag. Results are still ordered by score. An empty
+// filter matches every memory.
+func (m *MemoryStore) SearchFiltered(ctx context.Context, query string, embedding []float32, limit int, filter map[string]interface{}) ([]MemorySearchResult, error) {
+	q := m.newQuery(ctx, query)
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	results, err := m.longTerm.Search(ctx, embedding, limit)
+	results, err := m.searchLongTerm(ctx, q, embedding, limit, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -139,6 +231,8 @@ func (m *MemoryStore) Search(ctx context.Context, query string, embedding []floa
 				ID:        r.ID,
 				Content:   r.Content,
 				Timestamp: time.Unix(r.Metadata.Timestamp, 0),
+				Tags:      r.Tags,
+				Language:  r.Language,
 			},
 			Score: r.Score,
 		}
@@ -147,23 +241,152 @@ func (m *MemoryStore) Search(ctx context.Context, query string, embedding []floa
 	return memoryResults, nil
 }
 
+// memoryQuery is a search query with its detected language. Text is the
+// query translated into the canonical language when translation is enabled.
+type memoryQuery struct {
+	Text     string
+	Language string
+}
+
+// newQuery detects the language of a query and translates it if needed. It
+// may call the translator, so it must be called without holding the lock.
+func (m *MemoryStore) newQuery(ctx context.Context, query string) memoryQuery {
+	q := memoryQuery{Text: query, Language: DetectLanguage(query)}
+	if translated := m.translate(ctx, query, q.Language); translated != "" {
+		q.Text = translated
+	}
+	return q
+}
+
+// searchLongTerm searches the entries matching filter by embedding, or by
+// text when there is none, and boosts results in the query's language
+func (m *MemoryStore) searchLongTerm(ctx context.Context, q memoryQuery, embedding []float32, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	keep := filterFunc(filter)
+
+	var results []SearchResult
+	if len(embedding) == 0 {
+		results = m.longTerm.SearchTextFiltered(q.Text, limit, keep)
+	} else {
+		var err error
+		if results, err = m.longTerm.SearchFiltered(ctx, embedding, limit, keep); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.config.SameLanguageBoost > 0 && q.Language != "" {
+		for i := range results {
+			if results[i].Language == q.Language {
+				results[i].Score += m.config.SameLanguageBoost
+			}
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Score > results[j].Score
+		})
+	}
+	return results, nil
+}
+
+// ContextSource is a memory entry that was injected into a prompt as context
+type ContextSource struct {
+	ID      string     `json:"id"`
+	Type    MemoryType `json:"type"`
+	Content string     `json:"content"`
+	Score   float32    `json:"score,omitempty"` // Similarity to the query, long-term memories only
+}
+
 // GetContext retrieves all relevant context for a conversation
 func (m *MemoryStore) GetContext(ctx context.Context, query string, embedding []float32, maxTokens int) (string, error) {
+	context, _, err := m.GetContextWithSources(ctx, query, embedding, maxTokens)
+	return context, err
+}
+
+// ContextLimits returns the configured context limits, with defaults for
+// unset ones
+func (m *MemoryStore) ContextLimits() ContextLimits {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	limits := ContextLimits{
+		LongTermK:       m.config.ContextLongTermK,
+		ShortTermK:      m.config.ContextShortTermK,
+		ShortTermMaxAge: m.config.ContextShortTermMaxAge,
+	}
+	if limits.LongTermK == 0 {
+		limits.LongTermK = DefaultContextLongTermK
+	}
+	if limits.ShortTermK == 0 {
+		limits.ShortTermK = DefaultContextShortTermK
+	}
+	return limits
+}
+
+// SetContextLimits changes the context limits GetContext uses; 0 restores
+// the default of a count, and an age of 0 removes the age limit
+func (m *MemoryStore) SetContextLimits(limits ContextLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.config.ContextLongTermK = limits.LongTermK
+	m.config.ContextShortTermK = limits.ShortTermK
+	m.config.ContextShortTermMaxAge = limits.ShortTermMaxAge
+	return nil
+}
+
+// GetContextWithSources is GetContext that also returns the entries the
+// context was built from, in the order they appear in it
+func (m *MemoryStore) GetContextWithSources(ctx context.Context, query string, embedding []float32, maxTokens int) (string, []ContextSource, error) {
+	return m.GetContextWithLimits(ctx, query, embedding, maxTokens, m.ContextLimits())
+}
+
+// GetContextWithLimits is GetContextWithSources injecting at most the given
+// number of entries from each memory; a limit of 0 leaves that memory out
+func (m *MemoryStore) GetContextWithLimits(ctx context.Context, query string, embedding []float32, maxTokens int, limits ContextLimits) (string, []ContextSource, error) {
+	return m.GetContextFiltered(ctx, query, embedding, maxTokens, limits, nil)
+}
+
+// GetContextFiltered is GetContextWithLimits drawing long-term and
+// short-term memories only from those matching filter, as SearchFiltered
+// matches them, e.g. {"session": id} to keep other sessions' memories out
+func (m *MemoryStore) GetContextFiltered(ctx context.Context, query string, embedding []float32, maxTokens int, limits ContextLimits, filter map[string]interface{}) (string, []ContextSource, error) {
+	if err := limits.Validate(); err != nil {
+		return "", nil, err
+	}
+	q := m.newQuery(ctx, query)
+	m.expireShortTerm()
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	var contextParts []string
+	var sources []ContextSource
 
-	// 1. Get working memory
+	// 1. Get working memory, with the agent's notes of this session only
+	sessionID := SessionFrom(ctx)
 	for _, entry := range m.workingSet.GetAll() {
 		if len(contextParts) >= maxTokens/3 {
 			break
 		}
-		contextParts = append(contextParts, fmt.Sprintf("[WORKING]: %s", entry.Content))
+		label := "WORKING"
+		if IsAgentNote(entry) {
+			if sessionID == "" || !noteOf(entry, sessionID) {
+				continue
+			}
+			label = "NOTE"
+		}
+		contextParts = append(contextParts, fmt.Sprintf("[%s]: %s", label, entry.Content))
+		sources = append(sources, ContextSource{ID: entry.ID, Type: MemoryTypeWork, Content: entry.Content})
 	}
 
 	// 2. Get relevant long-term memories
-	longTerm, err := m.longTerm.Search(ctx, embedding, 5)
+	var longTerm []SearchResult
+	var err error
+	if limits.LongTermK > 0 {
+		longTerm, err = m.searchLongTerm(ctx, q, embedding, limits.LongTermK, filter)
+	}
 	if err == nil {
 		for _, r := range longTerm {
 			if len(contextParts) >= maxTokens*2/3 {
@@ -172,15 +395,20 @@ func (m *MemoryStore) GetContext(ctx context.Context, query string, embedding []
 			if r.Score >= m.config.SimilarityCut {
 				contextParts = append(contextParts,
 					fmt.Sprintf("[MEMORY (%.2f)]: %s", r.Score, r.Content))
+				sources = append(sources, ContextSource{ID: r.ID, Type: MemoryTypeLong, Content: r.Content, Score: r.Score})
 			}
 		}
 	}
 
-	// 3. Get recent short-term memories
-	recent := m.shortTerm.GetRecent(10)
+	// 3. Get recent short-term memories, within the age limit
+	recent := m.shortTerm.GetRecentMatching(limits.ShortTermK, filterFunc(filter))
 	for _, entry := range recent {
+		if limits.ShortTermMaxAge > 0 && time.Since(entry.Timestamp) > limits.ShortTermMaxAge {
+			continue
+		}
 		contextParts = append(contextParts,
 			fmt.Sprintf("[RECENT]: %s", entry.Content))
+		sources = append(sources, ContextSource{ID: entry.ID, Type: MemoryTypeShort, Content: entry.Content})
 	}
 
 	// Combine context
@@ -192,38 +420,105 @@ func (m *MemoryStore) GetContext(ctx context.Context, query string, embedding []
 		context += part
 	}
 
-	return context, nil
+	return context, sources, nil
+}
+
+// ConsolidationReport describes a Consolidate run
+type ConsolidationReport struct {
+	Consolidated int           `json:"consolidated"`
+	Skipped      int           `json:"skipped"` // Entries that failed to embed; they stay in short-term memory
+	Batches      []BatchTiming `json:"batches,omitempty"`
 }
 
-// Consolidate moves important short-term memories to long-term
-func (m *MemoryStore) Consolidate(embedder *vector.OllamaEmbedder) error {
+// BatchTiming is how long one EmbedBatch call of a consolidation took
+type BatchTiming struct {
+	Size     int           `json:"size"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Consolidate moves short-term memories older than ConsolidateAfter to
+// long-term. Candidates are embedded with EmbedBatch in chunks of
+// ConsolidateBatch; when a batch fails, its entries are embedded one by one
+// and only the failures are skipped. The store isn't locked while embedding.
+func (m *MemoryStore) Consolidate(ctx context.Context, embedder vector.Embedder) (*ConsolidationReport, error) {
+	after := m.config.ConsolidateAfter
+	if after <= 0 {
+		after = DefaultConsolidateAfter
+	}
+
+	var candidates []MemoryEntry
+	m.mu.RLock()
+	for _, entry := range m.shortTerm.All() {
+		if time.Since(entry.Timestamp) > after {
+			candidates = append(candidates, entry)
+		}
+	}
+	m.mu.RUnlock()
+
+	report := &ConsolidationReport{}
+	embeddings := make([][]float32, len(candidates))
+	if embedder != nil {
+		batchSize := m.config.ConsolidateBatch
+		if batchSize <= 0 {
+			batchSize = DefaultConsolidateBatch
+		}
+		for start := 0; start < len(candidates); start += batchSize {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			end := start + batchSize
+			if end > len(candidates) {
+				end = len(candidates)
+			}
+			m.embedBatch(ctx, embedder, candidates[start:end], embeddings[start:end], report)
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	for i, entry := range candidates {
+		if embedder != nil && len(embeddings[i]) == 0 {
+			report.Skipped++
+			continue
+		}
+		m.longTerm.Add(entry, embeddings[i])
+		m.shortTerm.Remove(entry.ID)
+		report.Consolidated++
+	}
 
-	recent := m.shortTerm.GetRecent(20)
-	for _, entry := range recent {
-		// Check if this memory should be consolidated
-		// For now, consolidate all memories older than 1 hour
-		if time.Since(entry.Timestamp) > time.Hour {
-			// Generate embedding
-			var embedding []float32
-			if embedder != nil {
-				emb, err := embedder.Embed(context.Background(), entry.Content)
-				if err != nil {
-					continue
-				}
-				embedding = emb
-			}
+	return report, nil
+}
 
-			// Add to long-term
-			m.longTerm.Add(entry, embedding)
+// embedBatch fills embeddings for one batch of entries, recording its timing.
+// If the batch call fails, each entry is embedded on its own instead, leaving
+// the ones that still fail empty.
+func (m *MemoryStore) embedBatch(ctx context.Context, embedder vector.Embedder, entries []MemoryEntry, embeddings [][]float32, report *ConsolidationReport) {
+	texts := make([]string, len(entries))
+	for i, entry := range entries {
+		texts[i] = entry.Content
+	}
 
-			// Remove from short-term
-			m.shortTerm.Remove(entry.ID)
-		}
+	start := time.Now()
+	batch, err := embedder.EmbedBatch(ctx, texts)
+	if err == nil && len(batch) != len(texts) {
+		err = fmt.Errorf("expected %d embeddings, got %d", len(texts), len(batch))
+	}
+	timing := BatchTiming{Size: len(texts), Duration: time.Since(start)}
+	if err != nil {
+		timing.Error = err.Error()
 	}
+	report.Batches = append(report.Batches, timing)
 
-	return nil
+	if err == nil {
+		copy(embeddings, batch)
+		return
+	}
+	for i, text := range texts {
+		if embedding, err := embedder.Embed(ctx, text); err == nil {
+			embeddings[i] = embedding
+		}
+	}
 }
 
 // Clear clears all memories
@@ -236,8 +531,10 @@ func (m *MemoryStore) Clear() {
 	m.workingSet.Clear()
 }
 
-// Stats returns memory statistics
+// Stats returns memory statistics, expired short-term memories forgotten first
 func (m *MemoryStore) Stats() MemoryStats {
+	m.expireShortTerm()
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -248,6 +545,16 @@ func (m *MemoryStore) Stats() MemoryStats {
 	}
 }
 
+// expireShortTerm forgets the short-term memories older than ShortTermTTL
+func (m *MemoryStore) expireShortTerm() {
+	if m.config.ShortTermTTL <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shortTerm.Expire()
+}
+
 // MemoryStats holds statistics about memory usage
 type MemoryStats struct {
 	ShortTermCount int `json:"shortTermCount"`
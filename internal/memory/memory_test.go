@@ -0,0 +1,582 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// batchEmbedder counts calls and fails any batch containing "bad"; a single
+// "bad" text fails too
+type batchEmbedder struct {
+	batches [][]string
+	singles int
+}
+
+func (e *batchEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.singles++
+	if strings.Contains(text, "bad") {
+		return nil, fmt.Errorf("cannot embed %q", text)
+	}
+	return []float32{1, 0}, nil
+}
+
+func (e *batchEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.batches = append(e.batches, texts)
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if strings.Contains(text, "bad") {
+			return nil, fmt.Errorf("batch failed on %q", text)
+		}
+		embeddings[i] = []float32{1, 0}
+	}
+	return embeddings, nil
+}
+
+func (e *batchEmbedder) GetModelName() string { return "batch" }
+
+func addOldShortTerm(m *MemoryStore, contents ...string) {
+	for i, content := range contents {
+		m.shortTerm.Add(MemoryEntry{
+			ID:        fmt.Sprintf("st_%d", i),
+			Type:      MemoryTypeShort,
+			Content:   content,
+			Timestamp: time.Now().Add(-2 * time.Hour),
+		})
+	}
+}
+
+func TestConsolidateEmbedsInBatches(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{ShortTermMax: 50, WorkingMax: 10, ConsolidateBatch: 3})
+	addOldShortTerm(m, "a", "b", "c", "d", "e", "f", "g")
+
+	embedder := &batchEmbedder{}
+	report, err := m.Consolidate(context.Background(), embedder)
+	if err != nil {
+		t.Fatalf("Consolidate() error = %v", err)
+	}
+
+	if len(embedder.batches) != 3 || embedder.singles != 0 {
+		t.Errorf("batch calls = %d, single calls = %d; want 3 batches and no single calls", len(embedder.batches), embedder.singles)
+	}
+	if report.Consolidated != 7 || len(report.Batches) != 3 || report.Batches[2].Size != 1 {
+		t.Errorf("report = %+v, want 7 consolidated over batches of 3, 3 and 1", report)
+	}
+	if stats := m.Stats(); stats.LongTermCount != 7 || stats.ShortTermCount != 0 {
+		t.Errorf("stats = %+v, want everything moved to long-term", stats)
+	}
+}
+
+func TestConsolidateSkipsFailedEntries(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{ShortTermMax: 50, WorkingMax: 10, ConsolidateBatch: 2})
+	addOldShortTerm(m, "a", "bad", "c", "d")
+
+	embedder := &batchEmbedder{}
+	report, err := m.Consolidate(context.Background(), embedder)
+	if err != nil {
+		t.Fatalf("Consolidate() error = %v", err)
+	}
+
+	if report.Consolidated != 3 || report.Skipped != 1 {
+		t.Errorf("report = %+v, want 3 consolidated and the bad entry skipped", report)
+	}
+	failed := 0
+	for _, batch := range report.Batches {
+		if batch.Error != "" {
+			failed++
+		}
+	}
+	if len(report.Batches) != 2 || failed != 1 {
+		t.Errorf("batches = %+v, want one of the two batches to fail", report.Batches)
+	}
+	if embedder.singles != 2 {
+		t.Errorf("single calls = %d, want the failed batch retried entry by entry", embedder.singles)
+	}
+	if stats := m.Stats(); stats.LongTermCount != 3 || stats.ShortTermCount != 1 {
+		t.Errorf("stats = %+v, want the failed entry left in short-term", stats)
+	}
+}
+
+func TestConsolidateAfter(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{ShortTermMax: 50, WorkingMax: 10, ConsolidateAfter: 3 * time.Hour})
+	addOldShortTerm(m, "a", "b")
+
+	if report, _ := m.Consolidate(context.Background(), nil); report.Consolidated != 0 {
+		t.Errorf("consolidated %d memories younger than consolidateAfter, want 0", report.Consolidated)
+	}
+
+	m.config.ConsolidateAfter = 90 * time.Minute
+	if report, _ := m.Consolidate(context.Background(), nil); report.Consolidated != 2 {
+		t.Errorf("consolidated %d memories, want 2", report.Consolidated)
+	}
+}
+
+func TestStartConsolidation(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{ShortTermMax: 50, WorkingMax: 10})
+	addOldShortTerm(m, "a", "b")
+	m.AddShortTerm("fresh", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := m.StartConsolidation(ctx, 5*time.Millisecond, &batchEmbedder{})
+
+	deadline := time.Now().Add(time.Second)
+	for m.Stats().LongTermCount < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consolidation didn't stop after cancel")
+	}
+
+	if stats := m.Stats(); stats.LongTermCount != 2 || stats.ShortTermCount != 1 {
+		t.Errorf("stats = %+v, want the two old memories moved and the fresh one kept", stats)
+	}
+}
+
+// dictionaryTranslator translates the texts it knows and counts its calls
+type dictionaryTranslator struct {
+	dictionary map[string]string
+	calls      int
+}
+
+func (t *dictionaryTranslator) Translate(ctx context.Context, text, language string) (string, error) {
+	t.calls++
+	if translated, ok := t.dictionary[text]; ok {
+		return translated, nil
+	}
+	return "", fmt.Errorf("cannot translate %q into %s", text, language)
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"用户喜欢喝绿茶":                  LanguageChinese,
+		"The user likes green tea": LanguageEnglish,
+		"緑茶が好きです":                  LanguageJapanese,
+		"녹차를 좋아해요":                 LanguageKorean,
+		"Goclaw 是一个助手":             LanguageChinese,
+		"12345":                    "",
+	}
+	for text, want := range tests {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := map[string][]string{
+		"The user likes green-tea": {"the", "user", "likes", "green", "tea"},
+		"我的猫叫饼干":                   {"我的", "的猫", "猫叫", "叫饼", "饼干"},
+		"Goclaw 是助手，猫":             {"goclaw", "是助", "助手", "猫"},
+	}
+	for text, want := range tests {
+		if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestIsTrivial(t *testing.T) {
+	tests := []struct {
+		message  string
+		minChars int
+		want     bool
+	}{
+		{"ok", 0, true},
+		{"Thanks!", 0, true},
+		{"  got it.  ", 0, true},
+		{"谢谢", 0, true},
+		{"???", 0, true},
+		{"hi", 0, true},
+		{"猫叫饼干", 0, false},
+		{"What is my cat called?", 0, false},
+		{"What is my cat called?", 40, true},
+		{"hi", -1, false},
+		{"ok", -1, true},
+	}
+	for _, tt := range tests {
+		if got := IsTrivial(tt.message, tt.minChars); got != tt.want {
+			t.Errorf("IsTrivial(%q, %d) = %v, want %v", tt.message, tt.minChars, got, tt.want)
+		}
+	}
+}
+
+func TestEntriesTaggedWithLanguageAndSearchedByScript(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{})
+	m.AddLongTerm("我的猫叫饼干，它喜欢晒太阳", nil, nil)
+	m.AddLongTerm("The deploy runs on Fridays", nil, nil)
+
+	// A Chinese question shares no whole word with the memory, only bigrams
+	results, err := m.Search(context.Background(), "猫叫什么名字", nil, 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Entry.Language != LanguageChinese {
+		t.Fatalf("results = %+v, want the Chinese memory tagged %q", results, LanguageChinese)
+	}
+
+	results, _ = m.Search(context.Background(), "when does the deploy run", nil, 5)
+	if len(results) == 0 || results[0].Entry.Language != LanguageEnglish {
+		t.Fatalf("results = %+v, want the English memory tagged %q first", results, LanguageEnglish)
+	}
+}
+
+func TestSearchAcrossLanguagesWithTranslation(t *testing.T) {
+	translator := &dictionaryTranslator{dictionary: map[string]string{
+		"用户喜欢喝绿茶": "The user likes to drink green tea",
+	}}
+
+	m := NewMemoryStore(MemoryConfig{Language: LanguageEnglish, Translator: translator})
+	m.AddLongTerm("用户喜欢喝绿茶", nil, nil)
+	m.AddLongTerm("The deploy runs on Fridays", nil, nil)
+	if translator.calls != 1 {
+		t.Errorf("translator calls = %d, want only the Chinese memory translated", translator.calls)
+	}
+
+	results, err := m.Search(context.Background(), "green tea", nil, 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Entry.Content != "用户喜欢喝绿茶" || results[0].Entry.Language != LanguageChinese {
+		t.Fatalf("results = %+v, want the Chinese memory", results)
+	}
+
+	// Without a translator the memory is stored verbatim and not found
+	m = NewMemoryStore(MemoryConfig{Language: LanguageEnglish})
+	m.AddLongTerm("用户喜欢喝绿茶", nil, nil)
+	if results, _ := m.Search(context.Background(), "green tea", nil, 5); len(results) != 0 {
+		t.Errorf("results = %+v, want none without translation", results)
+	}
+}
+
+func TestSearchBoostsSameLanguage(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{SameLanguageBoost: 0.5})
+	m.AddLongTerm("绿茶 green tea", []float32{1, 0}, nil)
+	m.AddLongTerm("green tea", []float32{0.9, 0.1}, nil)
+
+	results, _ := m.Search(context.Background(), "绿茶是什么", []float32{0.9, 0.1}, 2)
+	if len(results) != 2 || results[0].Entry.Language != LanguageChinese {
+		t.Errorf("results = %+v, want the Chinese memory boosted first", results)
+	}
+}
+
+func TestRetagByMetadataFilter(t *testing.T) {
+	m := NewMemoryStore(DefaultConfig())
+	m.AddLongTerm("deploy notes", []float32{1, 0}, map[string]interface{}{"session": "work", "tags": []string{"ops"}})
+	m.AddLongTerm("release checklist", []float32{0, 1}, map[string]interface{}{"session": "work", "tags": []interface{}{"ops", "draft"}})
+	m.AddLongTerm("birthday ideas", []float32{1, 1}, map[string]interface{}{"session": "home"})
+
+	modified, err := m.Retag(map[string]interface{}{"session": "work"}, []string{"project"}, []string{"draft"})
+	if err != nil {
+		t.Fatalf("Retag() error = %v", err)
+	}
+	if modified != 2 {
+		t.Errorf("modified = %d, want 2", modified)
+	}
+
+	tagsByContent := make(map[string][]string)
+	m.longTerm.Update(func(entry *MemoryEntry) bool {
+		tagsByContent[entry.Content] = entry.Tags
+		if _, ok := entry.Metadata["tags"]; ok {
+			t.Errorf("%q still has tags in its metadata", entry.Content)
+		}
+		return false
+	})
+	want := map[string]string{
+		"deploy notes":      "ops,project",
+		"release checklist": "ops,project",
+		"birthday ideas":    "",
+	}
+	for content, tags := range want {
+		if got := strings.Join(tagsByContent[content], ","); got != tags {
+			t.Errorf("%q tags = %q, want %q", content, got, tags)
+		}
+	}
+
+	// Embeddings are untouched, so vector search still finds the entries
+	results, _ := m.Search(context.Background(), "", []float32{0, 1}, 1)
+	if len(results) != 1 || results[0].Entry.Content != "release checklist" || strings.Join(results[0].Entry.Tags, ",") != "ops,project" {
+		t.Errorf("results = %+v, want the retagged checklist", results)
+	}
+
+	// Filtering by tag, and a second identical retag changes nothing
+	if modified, _ := m.Retag(map[string]interface{}{"tag": "project"}, []string{"project"}, nil); modified != 0 {
+		t.Errorf("repeated retag modified = %d, want 0", modified)
+	}
+}
+
+func TestSearchFiltered(t *testing.T) {
+	m := NewMemoryStore(DefaultConfig())
+	m.AddLongTerm("deploy notes", []float32{1, 0}, map[string]interface{}{"session": "work", "source": "api"})
+	m.AddLongTerm("deploy window", []float32{1, 0.5}, map[string]interface{}{"session": "work", "source": "cli"})
+	m.AddLongTerm("deploy the garden shed", []float32{1, 0.1}, map[string]interface{}{"session": "home", "source": "api"})
+
+	contents := func(results []MemorySearchResult) string {
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Entry.Content)
+		}
+		return strings.Join(got, "|")
+	}
+
+	// The closest match is another session's, so the limit applies after filtering
+	results, err := m.SearchFiltered(context.Background(), "deploy", []float32{1, 0}, 2, map[string]interface{}{"session": "work"})
+	if err != nil {
+		t.Fatalf("SearchFiltered() error = %v", err)
+	}
+	if got := contents(results); got != "deploy notes|deploy window" {
+		t.Errorf("session results = %q, want the work memories by score", got)
+	}
+
+	// Every key must match, with or without an embedding
+	filter := map[string]interface{}{"session": "work", "source": "cli"}
+	if results, _ := m.SearchFiltered(context.Background(), "deploy", []float32{1, 0}, 5, filter); contents(results) != "deploy window" {
+		t.Errorf("results = %q, want only the work memory from the CLI", contents(results))
+	}
+	if results, _ := m.SearchFiltered(context.Background(), "deploy", nil, 5, filter); contents(results) != "deploy window" {
+		t.Errorf("text results = %q, want only the work memory from the CLI", contents(results))
+	}
+
+	if results, _ := m.SearchFiltered(context.Background(), "deploy", []float32{1, 0}, 5, nil); len(results) != 3 {
+		t.Errorf("unfiltered results = %q, want all 3", contents(results))
+	}
+}
+
+func TestGetContextFiltered(t *testing.T) {
+	m := NewMemoryStore(DefaultConfig())
+	m.AddLongTerm("the s1 user likes tea", []float32{1, 0}, map[string]interface{}{"session": "s1"})
+	m.AddLongTerm("the s2 user likes coffee", []float32{1, 0}, map[string]interface{}{"session": "s2"})
+	m.AddShortTerm("s1 asked about green tea", map[string]interface{}{"session": "s1"})
+	for i := 0; i < DefaultContextShortTermK; i++ {
+		m.AddShortTerm(fmt.Sprintf("s2 message %d", i), map[string]interface{}{"session": "s2"})
+	}
+
+	text, _, err := m.GetContextFiltered(context.Background(), "tea", []float32{1, 0}, 500, m.ContextLimits(), map[string]interface{}{"session": "s1"})
+	if err != nil {
+		t.Fatalf("GetContextFiltered() error = %v", err)
+	}
+	if !strings.Contains(text, "likes tea") || !strings.Contains(text, "green tea") {
+		t.Errorf("context = %q, want the s1 memories, even behind newer s2 messages", text)
+	}
+	if strings.Contains(text, "s2") {
+		t.Errorf("context = %q, want nothing from s2", text)
+	}
+}
+
+func TestGetContextHonorsContextLongTermK(t *testing.T) {
+	countLongTerm := func(k int) int {
+		m := NewMemoryStore(MemoryConfig{ShortTermMax: 10, WorkingMax: 10, ContextLongTermK: k})
+		for i := 0; i < 8; i++ {
+			m.AddLongTerm(fmt.Sprintf("tea fact %d", i), []float32{1, float32(i) / 10}, nil)
+		}
+		text, err := m.GetContext(context.Background(), "tea", []float32{1, 0}, 500)
+		if err != nil {
+			t.Fatalf("GetContext() error = %v", err)
+		}
+		return strings.Count(text, "[MEMORY")
+	}
+
+	if got := countLongTerm(0); got != DefaultContextLongTermK {
+		t.Errorf("default context has %d long-term entries, want %d", got, DefaultContextLongTermK)
+	}
+	if got := countLongTerm(2); got != 2 {
+		t.Errorf("context with ContextLongTermK 2 has %d long-term entries, want 2", got)
+	}
+	if got := countLongTerm(7); got != 7 {
+		t.Errorf("context with ContextLongTermK 7 has %d long-term entries, want 7", got)
+	}
+
+	m := NewMemoryStore(DefaultConfig())
+	if _, _, err := m.GetContextWithLimits(context.Background(), "tea", nil, 500, ContextLimits{LongTermK: -1}); err == nil {
+		t.Error("GetContextWithLimits() accepted a negative limit")
+	}
+}
+
+func TestGetContextShortTermRecencyWindow(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{ShortTermMax: 10, WorkingMax: 10, ContextShortTermK: 3, ContextShortTermMaxAge: time.Hour})
+	for i, age := range []time.Duration{72 * time.Hour, 3 * time.Hour, 50 * time.Minute, 20 * time.Minute, 5 * time.Minute, time.Minute} {
+		m.shortTerm.Add(MemoryEntry{
+			ID:        fmt.Sprintf("st_%d", i),
+			Type:      MemoryTypeShort,
+			Content:   fmt.Sprintf("said %s ago", age),
+			Timestamp: time.Now().Add(-age),
+		})
+	}
+
+	recentContents := func() []string {
+		_, sources, err := m.GetContextWithSources(context.Background(), "", nil, 500)
+		if err != nil {
+			t.Fatalf("GetContextWithSources() error = %v", err)
+		}
+		var contents []string
+		for _, source := range sources {
+			if source.Type == MemoryTypeShort {
+				contents = append(contents, source.Content)
+			}
+		}
+		return contents
+	}
+
+	// The count is tighter: 3 of the 4 entries within the hour
+	if got := strings.Join(recentContents(), "|"); got != "said 1m0s ago|said 5m0s ago|said 20m0s ago" {
+		t.Errorf("short-term context = %q, want the 3 most recent", got)
+	}
+
+	// The age is tighter: only the 2 entries within 10 minutes of 6
+	if err := m.SetContextLimits(ContextLimits{ShortTermK: 6, ShortTermMaxAge: 10 * time.Minute}); err != nil {
+		t.Fatalf("SetContextLimits() error = %v", err)
+	}
+	if got := strings.Join(recentContents(), "|"); got != "said 1m0s ago|said 5m0s ago" {
+		t.Errorf("short-term context = %q, want the entries within 10 minutes", got)
+	}
+
+	// A resumed conversation whose entries are all stale gets none
+	if err := m.SetContextLimits(ContextLimits{ShortTermMaxAge: 30 * time.Second}); err != nil {
+		t.Fatalf("SetContextLimits() error = %v", err)
+	}
+	if got := recentContents(); len(got) != 0 {
+		t.Errorf("short-term context = %q, want none", got)
+	}
+
+	if err := m.SetContextLimits(ContextLimits{ShortTermMaxAge: -time.Minute}); err == nil {
+		t.Error("SetContextLimits() accepted a negative age")
+	}
+}
+
+func TestShortTermTTL(t *testing.T) {
+	m := NewMemoryStore(MemoryConfig{ShortTermMax: 10, WorkingMax: 10, ShortTermTTL: time.Hour})
+	for i, age := range []time.Duration{3 * time.Hour, 90 * time.Minute, 10 * time.Minute} {
+		m.shortTerm.Add(MemoryEntry{
+			ID:        fmt.Sprintf("st_%d", i),
+			Type:      MemoryTypeShort,
+			Content:   fmt.Sprintf("said %s ago", age),
+			Timestamp: time.Now().Add(-age),
+		})
+	}
+	// Consolidated before it expired, so long-term keeps it
+	m.longTerm.Add(MemoryEntry{ID: "lt_0", Type: MemoryTypeLong, Content: "said long ago", Timestamp: time.Now().Add(-3 * time.Hour)}, nil)
+
+	// Expired entries are left out even before they are dropped
+	if recent := m.shortTerm.GetRecent(10); len(recent) != 1 || recent[0].Content != "said 10m0s ago" {
+		t.Errorf("GetRecent() = %+v, want only the entry within the TTL", recent)
+	}
+
+	if stats := m.Stats(); stats.ShortTermCount != 1 || stats.LongTermCount != 1 {
+		t.Errorf("Stats() = %+v, want 1 short-term and the long-term entry", stats)
+	}
+	if m.shortTerm.Len() != 1 {
+		t.Errorf("short-term buffer holds %d entries, want the expired ones dropped", m.shortTerm.Len())
+	}
+
+	// Without a TTL entries are kept regardless of age
+	m = NewMemoryStore(DefaultConfig())
+	m.shortTerm.Add(MemoryEntry{ID: "st_old", Type: MemoryTypeShort, Content: "old", Timestamp: time.Now().Add(-72 * time.Hour)})
+	if stats := m.Stats(); stats.ShortTermCount != 1 {
+		t.Errorf("Stats() without a TTL = %+v, want the old entry kept", stats)
+	}
+}
+
+func TestGetContextIncludesOnlySessionNotes(t *testing.T) {
+	m := NewMemoryStore(DefaultConfig())
+	m.AddWorking("the user is on call this week", 1)
+	m.AddNote("s1", "step 2 of 5 done", 1)
+	m.AddNote("s1", "tests must pass before the release", 3)
+	m.AddNote("s2", "another session's note", 1)
+
+	text, err := m.GetContext(WithSession(context.Background(), "s1"), "report", nil, 500)
+	if err != nil {
+		t.Fatalf("GetContext() error = %v", err)
+	}
+	if !strings.Contains(text, "[WORKING]: the user is on call") || !strings.Contains(text, "[NOTE]: step 2 of 5 done") {
+		t.Errorf("context = %q, want the working memory and the session's notes", text)
+	}
+	if strings.Contains(text, "another session") {
+		t.Errorf("context = %q, want no notes of other sessions", text)
+	}
+
+	// Without a session no agent notes are included
+	text, _ = m.GetContext(context.Background(), "report", nil, 500)
+	if strings.Contains(text, "[NOTE]") {
+		t.Errorf("context without a session = %q, want no notes", text)
+	}
+
+	notes := m.Notes("s1")
+	if len(notes) != 2 || notes[0].Content != "tests must pass before the release" {
+		t.Errorf("notes = %+v, want both notes, highest priority first", notes)
+	}
+	if removed := m.ClearNotes("s1"); removed != 2 || len(m.Notes("s1")) != 0 || len(m.Notes("s2")) != 1 {
+		t.Errorf("ClearNotes() removed %d, want only the 2 notes of s1", removed)
+	}
+	if m.Stats().WorkingCount != 2 {
+		t.Errorf("working count = %d, want the user memory and s2's note kept", m.Stats().WorkingCount)
+	}
+}
+
+func TestMemoryStoreSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory", "memory.json")
+
+	m := NewMemoryStore(DefaultConfig())
+	m.AddShortTerm("first message", nil)
+	m.AddShortTerm("second message", map[string]interface{}{"session": "s1"})
+	if err := m.AddLongTerm("the user's cat is called Biscuit", []float32{1, 0, 0}, map[string]interface{}{"tags": []string{"pets"}}); err != nil {
+		t.Fatalf("AddLongTerm() error = %v", err)
+	}
+	m.AddWorking("reviewing the release", 2)
+	m.AddNote("s1", "low priority note", 1)
+	m.AddNote("s1", "high priority note", 5)
+
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded := NewMemoryStore(DefaultConfig())
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if stats, want := loaded.Stats(), m.Stats(); stats != want {
+		t.Errorf("loaded stats = %+v, want %+v", stats, want)
+	}
+
+	recent := loaded.shortTerm.GetRecent(1)
+	if len(recent) != 1 || recent[0].Content != "second message" {
+		t.Errorf("most recent short-term entry = %+v, want the second message", recent)
+	}
+
+	// Embeddings are restored, so long-term search still works
+	results, err := loaded.Search(context.Background(), "", []float32{1, 0, 0}, 1)
+	if err != nil || len(results) != 1 || results[0].Entry.Content != "the user's cat is called Biscuit" || strings.Join(results[0].Entry.Tags, ",") != "pets" {
+		t.Errorf("Search() = %+v, %v; want the restored memory", results, err)
+	}
+
+	// Note priorities survive the JSON round trip
+	notes := loaded.Notes("s1")
+	if len(notes) != 2 || notes[0].Content != "high priority note" {
+		t.Errorf("notes = %+v, want the high priority note first", notes)
+	}
+}
+
+func TestMemoryStoreLoadMissingFile(t *testing.T) {
+	m := NewMemoryStore(DefaultConfig())
+	m.AddShortTerm("kept", nil)
+
+	if err := m.Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("Load() of a missing file error = %v", err)
+	}
+	if count := m.Stats().ShortTermCount; count != 1 {
+		t.Errorf("short-term count = %d, want the store unchanged", count)
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+	os.WriteFile(corrupt, []byte("{not json"), 0644)
+	if err := m.Load(corrupt); err == nil {
+		t.Error("Load() accepted a corrupt file")
+	}
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Metadata marking the working memory entries the agent wrote for itself,
+// which are kept apart from user memories and scoped to a session
+const (
+	MetadataSource  = "source"
+	MetadataSession = "session"
+	SourceAgentNote = "agent_note"
+)
+
+// sessionKey is the context key of WithSession
+type sessionKey struct{}
+
+// WithSession returns a context whose GetContext calls include the agent
+// notes of the session. Without it no agent notes are included.
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// SessionFrom returns the session set with WithSession, or ""
+func SessionFrom(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionKey{}).(string)
+	return sessionID
+}
+
+// IsAgentNote reports whether a memory entry is an agent note
+func IsAgentNote(entry MemoryEntry) bool {
+	return entry.Metadata[MetadataSource] == SourceAgentNote
+}
+
+// noteOf reports whether entry is an agent note of the session
+func noteOf(entry MemoryEntry, sessionID string) bool {
+	return IsAgentNote(entry) && entry.Metadata[MetadataSession] == sessionID
+}
+
+// AddNote adds a note the agent leaves itself to working memory. The note is
+// scoped to the session; notes with a higher priority come first.
+func (m *MemoryStore) AddNote(sessionID, content string, priority int) MemoryEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := MemoryEntry{
+		ID:        fmt.Sprintf("wm_%d", time.Now().UnixNano()),
+		Type:      MemoryTypeWork,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"priority":      priority,
+			MetadataSource:  SourceAgentNote,
+			MetadataSession: sessionID,
+		},
+		Language: DetectLanguage(content),
+	}
+
+	m.workingSet.Add(entry)
+	return entry
+}
+
+// Notes returns the agent notes of a session, highest priority first and
+// oldest first within a priority
+func (m *MemoryStore) Notes(sessionID string) []MemoryEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var notes []MemoryEntry
+	for _, entry := range m.workingSet.GetAll() {
+		if noteOf(entry, sessionID) {
+			notes = append(notes, entry)
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		pi, pj := notes[i].Metadata["priority"].(int), notes[j].Metadata["priority"].(int)
+		if pi != pj {
+			return pi > pj
+		}
+		return notes[i].Timestamp.Before(notes[j].Timestamp)
+	})
+	return notes
+}
+
+// ClearNotes removes the agent notes of a session, leaving other working
+// memory alone, and returns how many it removed
+func (m *MemoryStore) ClearNotes(sessionID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.workingSet.RemoveWhere(func(entry MemoryEntry) bool {
+		return noteOf(entry, sessionID)
+	})
+}
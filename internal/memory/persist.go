@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// savedMemory is the file format of MemoryStore.Save
+type savedMemory struct {
+	ShortTerm []MemoryEntry `json:"shortTerm"` // Oldest first
+	LongTerm  []MemoryEntry `json:"longTerm"`  // With their embeddings
+	Working   []MemoryEntry `json:"working"`
+}
+
+// Save writes all three memories, long-term embeddings included, to a JSON
+// file. The file is replaced atomically, so a crash mid-save leaves the
+// previous one intact.
+func (m *MemoryStore) Save(path string) error {
+	m.mu.RLock()
+	saved := savedMemory{
+		ShortTerm: m.shortTerm.All(),
+		LongTerm:  m.longTerm.All(),
+		Working:   m.workingSet.GetAll(),
+	}
+	m.mu.RUnlock()
+
+	// A stable order keeps saves of an unchanged store identical
+	sort.Slice(saved.LongTerm, func(i, j int) bool {
+		a, b := saved.LongTerm[i], saved.LongTerm[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		return a.ID < b.ID
+	})
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".memory-*")
+	if err != nil {
+		return fmt.Errorf("failed to create memory file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load replaces the store's memories with those saved in a file. A missing
+// file is not an error and leaves the store as it is.
+func (m *MemoryStore) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // No file to load
+		}
+		return fmt.Errorf("failed to read memory file: %w", err)
+	}
+
+	var saved savedMemory
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to unmarshal memory: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.shortTerm.Clear()
+	for _, entry := range saved.ShortTerm {
+		m.shortTerm.Add(entry)
+	}
+
+	m.longTerm.Clear()
+	for _, entry := range saved.LongTerm {
+		embedding := entry.Embedding
+		entry.Embedding = nil
+		m.longTerm.Add(entry, embedding)
+	}
+
+	m.workingSet.Clear()
+	for _, entry := range saved.Working {
+		// JSON numbers come back as float64, priorities are ints
+		if priority, ok := entry.Metadata["priority"].(float64); ok {
+			entry.Metadata["priority"] = int(priority)
+		}
+		m.workingSet.Add(entry)
+	}
+	return nil
+}
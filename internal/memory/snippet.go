@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"goclaw/internal/vector"
+)
+
+const (
+	// HighlightOpen and HighlightClose wrap query terms in snippets
+	HighlightOpen  = "<mark>"
+	HighlightClose = "</mark>"
+
+	// DefaultSnippetLength is the snippet length in runes used for search results
+	DefaultSnippetLength = 160
+
+	ellipsis = "…"
+)
+
+// Snippet returns an excerpt of content of at most maxLen runes, centered on
+// the first match of a query term, with every term occurrence wrapped in
+// HighlightOpen/HighlightClose. Markers don't count towards maxLen; the
+// ellipses marking cut text do.
+func Snippet(content, query string, maxLen int) string {
+	if maxLen <= 0 {
+		maxLen = DefaultSnippetLength
+	}
+
+	text := []rune(strings.TrimSpace(content))
+	lower := lowerRunes(text)
+	terms := queryTerms(query)
+
+	start, end := 0, len(text)
+	if len(text) > maxLen {
+		matchAt, matchLen := firstMatch(lower, terms)
+		if matchAt < 0 {
+			matchAt, matchLen = 0, 0
+		}
+
+		// Center the window on the match, leaving room for the ellipses
+		window := maxLen - 2
+		if window < 1 {
+			return string(text[:maxLen])
+		}
+		start = matchAt - (window-matchLen)/2
+		if start < 0 {
+			start = 0
+		}
+		end = start + window
+		if end > len(text) {
+			end = len(text)
+			start = end - window
+		}
+
+		// Only cut sides get an ellipsis, so give the room back to the text
+		if start == 0 {
+			end++
+		} else if end == len(text) {
+			start--
+		}
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString(ellipsis)
+	}
+	writeHighlighted(&sb, text[start:end], lower[start:end], terms)
+	if end < len(text) {
+		sb.WriteString(ellipsis)
+	}
+	return sb.String()
+}
+
+// AddSnippets fills in the snippet of each result. Results come from
+// embedding search and may not contain the query literally, so the snippet is
+// built from the sentence closest to the query embedding.
+func AddSnippets(ctx context.Context, embedder vector.Embedder, query string, queryEmbedding []float32, results []MemorySearchResult) {
+	for i := range results {
+		sentence := BestSentence(ctx, embedder, query, queryEmbedding, results[i].Entry.Content)
+		results[i].Snippet = Snippet(sentence, query, DefaultSnippetLength)
+	}
+}
+
+// BestSentence returns the sentence of content most relevant to the query:
+// the one whose embedding is closest to queryEmbedding, or, without an
+// embedder, the one sharing the most query terms
+func BestSentence(ctx context.Context, embedder vector.Embedder, query string, queryEmbedding []float32, content string) string {
+	sentences := SplitSentences(content)
+	if len(sentences) <= 1 {
+		return content
+	}
+
+	if embedder != nil && len(queryEmbedding) > 0 {
+		if embeddings, err := embedder.EmbedBatch(ctx, sentences); err == nil && len(embeddings) == len(sentences) {
+			best, bestScore := 0, float32(-2)
+			for i, embedding := range embeddings {
+				if score := cosineSimilarity(queryEmbedding, embedding); score > bestScore {
+					best, bestScore = i, score
+				}
+			}
+			return sentences[best]
+		}
+	}
+
+	terms := queryTerms(query)
+	best, bestCount := 0, 0
+	for i, sentence := range sentences {
+		lower := strings.ToLower(sentence)
+		count := 0
+		for _, term := range terms {
+			count += strings.Count(lower, string(term))
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return sentences[best]
+}
+
+// SplitSentences splits text after sentence-ending punctuation and newlines,
+// dropping empty sentences
+func SplitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+
+	flush := func() {
+		if sentence := strings.TrimSpace(current.String()); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+
+	for _, r := range text {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+		switch r {
+		case '.', '!', '?', '。', '！', '？', '；':
+			flush()
+		}
+	}
+	flush()
+
+	return sentences
+}
+
+// queryTerms splits a query into lowercase terms with Tokenize, longest
+// first so that highlighting prefers the longest match
+func queryTerms(query string) [][]rune {
+	seen := make(map[string]bool)
+	var terms [][]rune
+	for _, field := range Tokenize(query) {
+		if !seen[field] {
+			seen[field] = true
+			terms = append(terms, []rune(field))
+		}
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	return terms
+}
+
+// firstMatch returns the rune offset and length of the earliest term match, or -1
+func firstMatch(text []rune, terms [][]rune) (int, int) {
+	for i := range text {
+		for _, term := range terms {
+			if hasPrefixAt(text, i, term) {
+				return i, len(term)
+			}
+		}
+	}
+	return -1, 0
+}
+
+// writeHighlighted writes text, wrapping occurrences of terms; lower is the
+// lowercased text used for matching. Overlapping and adjacent occurrences,
+// such as the bigrams of a CJK phrase, are wrapped as one.
+func writeHighlighted(sb *strings.Builder, text, lower []rune, terms [][]rune) {
+	marked := make([]bool, len(text))
+	for i := range text {
+		for _, term := range terms {
+			if hasPrefixAt(lower, i, term) {
+				for j := i; j < i+len(term); j++ {
+					marked[j] = true
+				}
+				break
+			}
+		}
+	}
+
+	for i := 0; i < len(text); {
+		if !marked[i] {
+			sb.WriteRune(text[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(text) && marked[end] {
+			end++
+		}
+		sb.WriteString(HighlightOpen)
+		sb.WriteString(string(text[i:end]))
+		sb.WriteString(HighlightClose)
+		i = end
+	}
+}
+
+// hasPrefixAt reports whether text contains term at offset i
+func hasPrefixAt(text []rune, i int, term []rune) bool {
+	if len(term) == 0 || i+len(term) > len(text) {
+		return false
+	}
+	for j, r := range term {
+		if text[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerRunes lowercases rune by rune so offsets match the original text
+func lowerRunes(text []rune) []rune {
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// stripMarkers removes highlight markers so the text length can be checked
+func stripMarkers(s string) string {
+	return strings.NewReplacer(HighlightOpen, "", HighlightClose, "").Replace(s)
+}
+
+func TestSnippetHighlightsMatch(t *testing.T) {
+	content := "Goclaw keeps short-term memory in a ring buffer. Long-term memory is stored as embeddings and searched by cosine similarity."
+
+	got := Snippet(content, "Embeddings", 40)
+	if !strings.Contains(got, HighlightOpen+"embeddings"+HighlightClose) {
+		t.Errorf("Snippet() = %q, want highlighted match", got)
+	}
+	if n := utf8.RuneCountInString(stripMarkers(got)); n > 40 {
+		t.Errorf("snippet has %d runes, want at most 40", n)
+	}
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Snippet() = %q, want ellipses on both cut sides", got)
+	}
+}
+
+func TestSnippetCJKLengthCap(t *testing.T) {
+	content := strings.Repeat("今天天气很好，我们去公园散步。", 5) + "我的猫叫饼干，它喜欢晒太阳。" + strings.Repeat("晚上一起吃火锅。", 5)
+
+	for _, maxLen := range []int{10, 20, 33} {
+		got := Snippet(content, "饼干", maxLen)
+		if !strings.Contains(got, HighlightOpen+"饼干"+HighlightClose) {
+			t.Errorf("maxLen %d: Snippet() = %q, want highlighted match", maxLen, got)
+		}
+		plain := stripMarkers(got)
+		if !utf8.ValidString(plain) {
+			t.Errorf("maxLen %d: snippet is not valid UTF-8", maxLen)
+		}
+		if n := utf8.RuneCountInString(plain); n > maxLen {
+			t.Errorf("maxLen %d: snippet has %d runes", maxLen, n)
+		}
+	}
+}
+
+func TestSnippetHighlightsCJKPhraseAsOne(t *testing.T) {
+	got := Snippet("我的猫叫饼干。", "猫叫饼干是谁", 50)
+	if want := "我的" + HighlightOpen + "猫叫饼干" + HighlightClose + "。"; got != want {
+		t.Errorf("Snippet() = %q, want %q", got, want)
+	}
+}
+
+func TestSnippetShortContentAndNoMatch(t *testing.T) {
+	if got := Snippet("猫叫饼干", "饼干", 50); got != "猫叫"+HighlightOpen+"饼干"+HighlightClose {
+		t.Errorf("Snippet() = %q", got)
+	}
+
+	got := Snippet(strings.Repeat("abc ", 50), "zzz", 20)
+	if n := utf8.RuneCountInString(got); n > 20 || !strings.HasPrefix(got, "abc") {
+		t.Errorf("Snippet() without a match = %q, want the start of the text", got)
+	}
+}
+
+// sentenceEmbedder embeds texts mentioning cats close to the query vector
+type sentenceEmbedder struct{}
+
+func (sentenceEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if strings.Contains(text, "猫") || strings.Contains(text, "cat") {
+		return []float32{1, 0}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+func (e sentenceEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i], _ = e.Embed(ctx, text)
+	}
+	return result, nil
+}
+
+func (sentenceEmbedder) GetModelName() string { return "sentence" }
+
+func TestAddSnippetsPicksRelevantSentence(t *testing.T) {
+	results := []MemorySearchResult{{Entry: MemoryEntry{
+		Content: "周末去了海边。我的猫叫饼干。晚上吃了火锅。",
+	}}}
+
+	// The query shares no terms with the content; the embedding decides
+	AddSnippets(context.Background(), sentenceEmbedder{}, "pet cat", []float32{1, 0}, results)
+	if results[0].Snippet != "我的猫叫饼干。" {
+		t.Errorf("Snippet = %q, want the sentence about the cat", results[0].Snippet)
+	}
+}
+
+func TestBestSentenceLexicalFallback(t *testing.T) {
+	content := "The weather was nice. My cat is called Biscuit! We had hotpot."
+	if got := BestSentence(context.Background(), nil, "cat Biscuit", nil, content); got != "My cat is called Biscuit!" {
+		t.Errorf("BestSentence() = %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package memory
+
+import "fmt"
+
+// MetadataTags is the metadata key AddLongTerm reads an entry's initial tags from
+const MetadataTags = "tags"
+
+// FilterTag is the Retag filter key that matches entries carrying a tag
+const FilterTag = "tag"
+
+// Retag adds and removes tags on every long-term memory matching filter and
+// returns how many entries changed. Each filter key must match the entry's
+// metadata value, compared as text so JSON numbers match ints; the "tag" key
+// matches entries that have the tag instead. An empty filter matches every
+// entry. Embeddings are left untouched.
+func (m *MemoryStore) Retag(filter map[string]interface{}, addTags, removeTags []string) (int, error) {
+	if len(addTags) == 0 && len(removeTags) == 0 {
+		return 0, fmt.Errorf("no tags to add or remove")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.longTerm.Update(func(entry *MemoryEntry) bool {
+		if !matchesFilter(*entry, filter) {
+			return false
+		}
+		tags := applyTags(entry.Tags, addTags, removeTags)
+		if sameTags(tags, entry.Tags) {
+			return false
+		}
+		entry.Tags = tags
+		return true
+	}), nil
+}
+
+// matchesFilter reports whether an entry matches every key of a Retag or
+// SearchFiltered filter
+func matchesFilter(entry MemoryEntry, filter map[string]interface{}) bool {
+	for key, want := range filter {
+		if key == FilterTag {
+			if !hasTag(entry.Tags, fmt.Sprint(want)) {
+				return false
+			}
+			continue
+		}
+		got, ok := entry.Metadata[key]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterFunc returns a function accepting the entries matching filter, nil
+// for an empty filter
+func filterFunc(filter map[string]interface{}) func(MemoryEntry) bool {
+	if len(filter) == 0 {
+		return nil
+	}
+	return func(entry MemoryEntry) bool {
+		return matchesFilter(entry, filter)
+	}
+}
+
+// applyTags returns tags with removeTags dropped and new addTags appended
+func applyTags(tags, addTags, removeTags []string) []string {
+	remove := make(map[string]bool, len(removeTags))
+	for _, tag := range removeTags {
+		remove[tag] = true
+	}
+
+	result := make([]string, 0, len(tags)+len(addTags))
+	for _, tag := range tags {
+		if !remove[tag] {
+			result = append(result, tag)
+		}
+	}
+	for _, tag := range addTags {
+		if tag != "" && !remove[tag] && !hasTag(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tagsFromMetadata splits the "tags" value off metadata, accepting []string
+// or the []interface{} JSON decodes to. The metadata map is not modified.
+func tagsFromMetadata(metadata map[string]interface{}) ([]string, map[string]interface{}) {
+	raw, ok := metadata[MetadataTags]
+	if !ok {
+		return nil, metadata
+	}
+
+	var tags []string
+	switch value := raw.(type) {
+	case string:
+		tags = []string{value}
+	case []string:
+		tags = append(tags, value...)
+	case []interface{}:
+		for _, tag := range value {
+			if s, ok := tag.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+	}
+
+	rest := make(map[string]interface{}, len(metadata)-1)
+	for key, value := range metadata {
+		if key != MetadataTags {
+			rest[key] = value
+		}
+	}
+	return tags, rest
+}
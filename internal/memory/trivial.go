@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultMinMessageChars is the least content a message needs to be worth
+// embedding and remembering
+const DefaultMinMessageChars = 8
+
+// cjkCharWeight is how many characters of space-separated text a CJK
+// character counts as: one carries about as much as a short word
+const cjkCharWeight = 3
+
+// acknowledgments are replies that carry no content of their own however
+// long the message is that says them
+var acknowledgments = map[string]bool{
+	"ok": true, "okay": true, "k": true, "yes": true, "yep": true, "yeah": true,
+	"no": true, "nope": true, "sure": true, "thanks": true, "thank you": true,
+	"thx": true, "ty": true, "cool": true, "nice": true, "great": true,
+	"got it": true, "sounds good": true, "alright": true, "fine": true,
+	"好": true, "好的": true, "好吧": true, "行": true, "可以": true, "嗯": true,
+	"嗯嗯": true, "是": true, "是的": true, "对": true, "对的": true, "不": true,
+	"不是": true, "谢谢": true, "多谢": true, "收到": true, "明白": true,
+	"知道了": true, "はい": true, "いいえ": true, "ありがとう": true, "네": true,
+	"아니요": true, "감사합니다": true,
+}
+
+// IsTrivial reports whether a message is too slight to embed or remember:
+// an acknowledgment such as "ok" or "谢谢", or shorter than minChars, where
+// a CJK character counts as cjkCharWeight characters since those scripts
+// say more per character. Punctuation, symbols and spaces don't count.
+// minChars of 0 selects DefaultMinMessageChars; a negative one only
+// filters acknowledgments.
+func IsTrivial(message string, minChars int) bool {
+	normalized := strings.ToLower(strings.Join(strings.FieldsFunc(message, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " "))
+	if normalized == "" || acknowledgments[normalized] {
+		return true
+	}
+
+	if minChars == 0 {
+		minChars = DefaultMinMessageChars
+	}
+	length := 0
+	for _, r := range normalized {
+		switch {
+		case isCJK(r):
+			length += cjkCharWeight
+		case r != ' ':
+			length++
+		}
+	}
+	return length < minChars
+}
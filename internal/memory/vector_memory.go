@@ -2,6 +2,8 @@ package memory
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -10,6 +12,8 @@ type SearchResult struct {
 	ID       string
 	Score    float32
 	Content  string
+	Language string
+	Tags     []string
 	Metadata MemoryMetadata
 }
 
@@ -46,8 +50,27 @@ func (vm *VectorMemory) Add(entry MemoryEntry, embedding []float32) error {
 	return nil
 }
 
+// All returns every entry with its embedding in the Embedding field
+func (vm *VectorMemory) All() []MemoryEntry {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	entries := make([]MemoryEntry, 0, len(vm.entries))
+	for id, entry := range vm.entries {
+		entry.Embedding = vm.vectors[id]
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
 // Search searches for similar memories
 func (vm *VectorMemory) Search(ctx context.Context, query []float32, limit int) ([]SearchResult, error) {
+	return vm.SearchFiltered(ctx, query, limit, nil)
+}
+
+// SearchFiltered searches for similar memories among the entries keep
+// accepts; a nil keep accepts every entry
+func (vm *VectorMemory) SearchFiltered(ctx context.Context, query []float32, limit int, keep func(MemoryEntry) bool) ([]SearchResult, error) {
 	vm.mu.RLock()
 	defer vm.mu.RUnlock()
 
@@ -58,6 +81,9 @@ func (vm *VectorMemory) Search(ctx context.Context, query []float32, limit int)
 
 	var results []scoredEntry
 	for id, vector := range vm.vectors {
+		if keep != nil && !keep(vm.entries[id]) {
+			continue
+		}
 		score := cosineSimilarity(query, vector)
 		results = append(results, scoredEntry{
 			id:         id,
@@ -83,9 +109,11 @@ func (vm *VectorMemory) Search(ctx context.Context, query []float32, limit int)
 	for i, r := range results {
 		entry := vm.entries[r.id]
 		searchResults[i] = SearchResult{
-			ID:      r.id,
-			Score:   r.similarity,
-			Content: entry.Content,
+			ID:       r.id,
+			Score:    r.similarity,
+			Content:  entry.Content,
+			Language: entry.Language,
+			Tags:     entry.Tags,
 			Metadata: MemoryMetadata{
 				Timestamp: entry.Timestamp.Unix(),
 				Content:   entry.Content,
@@ -96,6 +124,81 @@ func (vm *VectorMemory) Search(ctx context.Context, query []float32, limit int)
 	return searchResults, nil
 }
 
+// SearchText scores entries by the share of query terms they contain, in
+// their content or its translation, for when no query embedding is
+// available. Entries without any term are skipped.
+func (vm *VectorMemory) SearchText(query string, limit int) []SearchResult {
+	return vm.SearchTextFiltered(query, limit, nil)
+}
+
+// SearchTextFiltered is SearchText among the entries keep accepts; a nil
+// keep accepts every entry
+func (vm *VectorMemory) SearchTextFiltered(query string, limit int, keep func(MemoryEntry) bool) []SearchResult {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+
+	var results []SearchResult
+	for id, entry := range vm.entries {
+		if keep != nil && !keep(entry) {
+			continue
+		}
+		lower := strings.ToLower(entry.Content + "\n" + entry.Translation)
+		matched := 0
+		for _, term := range terms {
+			if strings.Contains(lower, string(term)) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+		results = append(results, SearchResult{
+			ID:       id,
+			Score:    float32(matched) / float32(len(terms)),
+			Content:  entry.Content,
+			Language: entry.Language,
+			Tags:     entry.Tags,
+			Metadata: MemoryMetadata{
+				Timestamp: entry.Timestamp.Unix(),
+				Content:   entry.Content,
+			},
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Metadata.Timestamp > results[j].Metadata.Timestamp
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Update calls fn with each entry and keeps the changes of the ones it
+// reports as changed, returning how many changed. Embeddings aren't passed to
+// fn and stay as they are.
+func (vm *VectorMemory) Update(fn func(entry *MemoryEntry) bool) int {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	changed := 0
+	for id, entry := range vm.entries {
+		if fn(&entry) {
+			vm.entries[id] = entry
+			changed++
+		}
+	}
+	return changed
+}
+
 // Get retrieves a memory entry
 func (vm *VectorMemory) Get(id string) (*MemoryEntry, error) {
 	vm.mu.RLock()
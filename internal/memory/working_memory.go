@@ -38,6 +38,7 @@ type WorkingItem struct {
 	Content   string
 	Priority  int
 	Timestamp time.Time
+	Metadata  map[string]interface{}
 }
 
 // NewWorkingMemory creates a new working memory
@@ -69,6 +70,7 @@ func (wm *WorkingMemory) Add(entry MemoryEntry) {
 		Content:   entry.Content,
 		Priority:  priority,
 		Timestamp: entry.Timestamp,
+		Metadata:  entry.Metadata,
 	}
 
 	heap.Push(&wm.items, item)
@@ -83,8 +85,10 @@ func (wm *WorkingMemory) GetAll() []MemoryEntry {
 	for i, item := range wm.items {
 		entries[i] = MemoryEntry{
 			ID:        item.ID,
+			Type:      MemoryTypeWork,
 			Content:   item.Content,
 			Timestamp: item.Timestamp,
+			Metadata:  item.Metadata,
 		}
 	}
 
@@ -98,6 +102,23 @@ func (wm *WorkingMemory) Len() int {
 	return len(wm.items)
 }
 
+// RemoveWhere removes the items matching remove and returns how many it removed
+func (wm *WorkingMemory) RemoveWhere(remove func(MemoryEntry) bool) int {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	kept := make(WorkingHeap, 0, len(wm.items))
+	for _, item := range wm.items {
+		if !remove(MemoryEntry{ID: item.ID, Type: MemoryTypeWork, Content: item.Content, Timestamp: item.Timestamp, Metadata: item.Metadata}) {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(wm.items) - len(kept)
+	wm.items = kept
+	heap.Init(&wm.items)
+	return removed
+}
+
 // Clear clears all items
 func (wm *WorkingMemory) Clear() {
 	wm.mu.Lock()
@@ -0,0 +1,130 @@
+// Package ollama provides health checks for a local Ollama server
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the default Ollama API endpoint
+const DefaultEndpoint = "http://localhost:11434"
+
+// DefaultTimeout bounds each health check request when the context has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// Model describes a model pulled into Ollama
+type Model struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// Status summarizes Ollama availability for health reporting
+type Status struct {
+	Available bool    `json:"available"`
+	Version   string  `json:"version,omitempty"`
+	Models    []Model `json:"models,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Ping checks that Ollama is reachable and returns its version
+func Ping(ctx context.Context, endpoint string) (string, error) {
+	var result struct {
+		Version string `json:"version"`
+	}
+	if err := get(ctx, endpoint, "/api/version", &result); err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// ListModels returns the models that have been pulled into Ollama
+func ListModels(ctx context.Context, endpoint string) ([]Model, error) {
+	var result struct {
+		Models []Model `json:"models"`
+	}
+	if err := get(ctx, endpoint, "/api/tags", &result); err != nil {
+		return nil, err
+	}
+	return result.Models, nil
+}
+
+// HasModel reports whether the named model is in the list. A name without a
+// tag matches the ":latest" tag, as in the Ollama CLI.
+func HasModel(models []Model, name string) bool {
+	for _, model := range models {
+		if model.Name == name || (!strings.Contains(name, ":") && model.Name == name+":latest") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckModel returns an error if the named model has not been pulled
+func CheckModel(ctx context.Context, endpoint, name string) error {
+	models, err := ListModels(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	if !HasModel(models, name) {
+		return fmt.Errorf("model %q is not pulled, run 'ollama pull %s'", name, name)
+	}
+	return nil
+}
+
+// CheckStatus pings Ollama and lists its models
+func CheckStatus(ctx context.Context, endpoint string) Status {
+	version, err := Ping(ctx, endpoint)
+	if err != nil {
+		return Status{Error: err.Error()}
+	}
+
+	status := Status{Available: true, Version: version}
+
+	models, err := ListModels(ctx, endpoint)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Models = models
+
+	return status
+}
+
+// get performs a GET request against the Ollama API and decodes the JSON response
+func get(ctx context.Context, endpoint, path string, out interface{}) error {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(endpoint, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama API error (status %d)", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newFakeOllama(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"0.5.7"}`))
+	})
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[{"name":"nomic-embed-text:latest","size":274302450},{"name":"llama3:8b","size":4661224676}]}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPing(t *testing.T) {
+	server := newFakeOllama(t)
+
+	version, err := Ping(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if version != "0.5.7" {
+		t.Errorf("version = %v, want 0.5.7", version)
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	server := newFakeOllama(t)
+	url := server.URL
+	server.Close()
+
+	if _, err := Ping(context.Background(), url); err == nil {
+		t.Error("expected error when Ollama is not reachable")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	server := newFakeOllama(t)
+
+	models, err := ListModels(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("got %d models, want 2", len(models))
+	}
+
+	if !HasModel(models, "nomic-embed-text") {
+		t.Error("untagged name should match the :latest tag")
+	}
+	if !HasModel(models, "llama3:8b") {
+		t.Error("exact tagged name should match")
+	}
+	if HasModel(models, "llama3") {
+		t.Error("untagged name should not match a non-latest tag")
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	server := newFakeOllama(t)
+
+	status := CheckStatus(context.Background(), server.URL)
+	if !status.Available || status.Version != "0.5.7" || len(status.Models) != 2 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
@@ -0,0 +1,197 @@
+// Package redact masks secrets in log lines, error messages and config dumps
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Mask replaces redacted values
+const Mask = "[REDACTED]"
+
+// DefaultFields are field names whose values are always masked. Matching
+// ignores case, "_" and "-", so "apiKey", "api_key" and "API-KEY" all match.
+// "key" covers gateway.auth.apiKeys[].key.
+var DefaultFields = []string{"apiKey", "key", "secret", "token", "password", "authorization"}
+
+// DefaultPatterns are regexes for secrets in free text. When a pattern has a
+// capture group, the first group is kept and the rest of the match is masked.
+var DefaultPatterns = []string{
+	`goclaw_[A-Za-z0-9_]+`,                        // Goclaw API keys
+	`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`,          // Bearer tokens
+	`\bsk-[A-Za-z0-9_-]{16,}`,                     // OpenAI-style keys
+	`(?i)(session[_-]?id["']?\s*[:=]\s*["']?)\w+`, // Session IDs
+}
+
+// Redactor masks secrets by field name and pattern
+type Redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// New creates a redactor for the given field names and regex patterns
+func New(fields, patterns []string) (*Redactor, error) {
+	r := &Redactor{fields: make(map[string]bool)}
+
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		r.fields[normalize(field)] = true
+		names = append(names, fieldPattern(field))
+	}
+
+	// Mask "field: value" and "FIELD=value" pairs in free text, e.g. JSON
+	// in log lines or .env files. The field must not follow a letter, so
+	// "token" matches "ACCESS_TOKEN" but not "maxTokens".
+	if len(names) > 0 {
+		patterns = append([]string{
+			fmt.Sprintf(`(?i)((?:^|[^a-z])["']?(?:%s)["']?\s*[:=]\s*["']?(?:bearer\s+)?)[^\s"',}&]+`, strings.Join(names, "|")),
+		}, patterns...)
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// String masks secrets in free text
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		replacement := Mask
+		if re.NumSubexp() > 0 {
+			replacement = "${1}" + Mask
+		}
+		s = re.ReplaceAllString(s, replacement)
+	}
+	return s
+}
+
+// Value returns a copy of v with secret fields and strings masked. Structs
+// are converted through their JSON form, so field names follow JSON tags.
+func (r *Redactor) Value(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.String(value)
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if r.fields[normalize(key)] {
+				masked[key] = maskValue(item)
+			} else {
+				masked[key] = r.Value(item)
+			}
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(value))
+		for i, item := range value {
+			masked[i] = r.Value(item)
+		}
+		return masked
+	case bool, float64, json.Number:
+		return value
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return Mask
+		}
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return Mask
+		}
+		return r.Value(generic)
+	}
+}
+
+// maskValue masks a secret field value, leaving empty values visible
+func maskValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok && s == "" {
+		return s
+	}
+	if v == nil {
+		return nil
+	}
+	return Mask
+}
+
+// fieldPattern matches a field name in free text, allowing "_" or "-"
+// between its words, so "apiKey" also matches "api_key" and "API-KEY"
+func fieldPattern(field string) string {
+	var sb strings.Builder
+	runes := []rune(field)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-':
+			sb.WriteString("[_-]?")
+			continue
+		case i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]):
+			sb.WriteString("[_-]?")
+		}
+		sb.WriteString(regexp.QuoteMeta(string(r)))
+	}
+	return sb.String()
+}
+
+// normalize lowercases a field name and drops separators
+func normalize(field string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(field))
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultRedactor = mustNew(DefaultFields, DefaultPatterns)
+)
+
+func mustNew(fields, patterns []string) *Redactor {
+	r, err := New(fields, patterns)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Configure replaces the package redactor with the default fields and
+// patterns plus the given extra ones
+func Configure(fields, patterns []string) error {
+	r, err := New(append(append([]string{}, DefaultFields...), fields...),
+		append(append([]string{}, DefaultPatterns...), patterns...))
+	if err != nil {
+		return err
+	}
+
+	defaultMu.Lock()
+	defaultRedactor = r
+	defaultMu.Unlock()
+	return nil
+}
+
+// Default returns the package redactor
+func Default() *Redactor {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultRedactor
+}
+
+// String masks secrets in free text using the package redactor
+func String(s string) string {
+	return Default().String(s)
+}
+
+// Value masks secrets in a value using the package redactor
+func Value(v interface{}) interface{} {
+	return Default().Value(v)
+}
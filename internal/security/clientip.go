@@ -0,0 +1,122 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIPContextKey is the context key for storing the resolved client IP
+const ClientIPContextKey contextKey = "client_ip"
+
+// TrustedProxies are the reverse proxies whose X-Forwarded-For and X-Real-IP
+// headers are believed. Headers from any other peer are ignored, since any
+// client can send them.
+type TrustedProxies struct {
+	nets []*net.IPNet
+}
+
+// ParseTrustedProxies parses proxy addresses given as CIDRs or single IPs
+func ParseTrustedProxies(cidrs []string) (*TrustedProxies, error) {
+	proxies := &TrustedProxies{}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: not an IP or CIDR", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies.nets = append(proxies.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		proxies.nets = append(proxies.nets, ipNet)
+	}
+	return proxies, nil
+}
+
+// trusts reports whether ip belongs to a trusted proxy
+func (p *TrustedProxies) trusts(ip net.IP) bool {
+	if p == nil || ip == nil {
+		return false
+	}
+	for _, ipNet := range p.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the IP of the client behind r. Forwarding headers count
+// only when the peer is a trusted proxy: X-Forwarded-For is walked from the
+// nearest hop back, skipping trusted proxies, to the first address they didn't
+// add themselves, so addresses a client puts in the header can't spoof it.
+// Without X-Forwarded-For, X-Real-IP is used. A nil TrustedProxies uses the
+// peer address.
+func (p *TrustedProxies) Resolve(r *http.Request) string {
+	peer := remoteIP(r)
+	if !p.trusts(net.ParseIP(peer)) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break // A malformed hop ends what can be believed
+			}
+			client = ip.String()
+			if !p.trusts(ip) {
+				break
+			}
+		}
+		return client
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+// ClientIPMiddleware resolves the client IP of each request with proxies and
+// stores it in the request context for ClientIP
+func ClientIPMiddleware(proxies *TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ClientIPContextKey, proxies.Resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP returns the client IP resolved by ClientIPMiddleware, or the peer
+// address of requests that didn't pass through it. Per-client features
+// should key off it rather than RemoteAddr.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIPContextKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// remoteIP returns the host part of the request's peer address
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -132,13 +132,7 @@ func (sm *SecurityManager) CheckScope(key string, requiredScope string) bool {
 		return false
 	}
 
-	for _, scope := range apiKey.Scopes {
-		if scope == requiredScope || scope == "*" {
-			return true
-		}
-	}
-
-	return false
+	return apiKey.HasScope(requiredScope)
 }
 
 // CreateSession 创建会话
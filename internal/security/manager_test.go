@@ -297,3 +297,20 @@ func TestListSessions(t *testing.T) {
 		t.Errorf("Expected 2 sessions, got %d", len(sessions))
 	}
 }
+
+func TestHasScopeWildcardPrefix(t *testing.T) {
+	key := &APIKey{Scopes: []string{"tools:*"}}
+
+	if !key.HasScope("tools:exec") {
+		t.Error("tools:* should grant tools:exec")
+	}
+	if key.HasScope("memory:write") {
+		t.Error("tools:* should not grant memory:write")
+	}
+	if got := ToolScope("exec", map[string]string{"exec": "admin"}); got != "admin" {
+		t.Errorf("ToolScope() with override = %q, want admin", got)
+	}
+	if got := ToolScope("web_fetch", nil); got != "tools:web_fetch" {
+		t.Errorf("ToolScope() = %q, want tools:web_fetch", got)
+	}
+}
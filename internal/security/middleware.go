@@ -4,9 +4,14 @@ package security
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"goclaw/internal/redact"
 )
 
 // Context keys for storing security information in request context
@@ -42,7 +47,7 @@ func (sm *SecurityManager) APIKeyAuthMiddleware(requiredScope string) func(http.
 			// Validate API key
 			validatedKey, err := sm.ValidateAPIKey(apiKey)
 			if err != nil {
-				log.Printf("API key validation failed: %v", err)
+				log.Printf("API key validation failed: %s", redact.String(err.Error()))
 				respondUnauthorized(w, "Invalid API key")
 				return
 			}
@@ -75,7 +80,7 @@ func (sm *SecurityManager) SessionAuthMiddleware() func(http.Handler) http.Handl
 			// Validate session
 			session, err := sm.ValidateSession(sessionID)
 			if err != nil {
-				log.Printf("Session validation failed: %v", err)
+				log.Printf("Session validation failed: %s", redact.String(err.Error()))
 				respondUnauthorized(w, "Invalid or expired session")
 				return
 			}
@@ -123,15 +128,51 @@ func (sm *SecurityManager) OptionalAuthMiddleware() func(http.Handler) http.Hand
 	}
 }
 
+// DefaultCORSMaxAge is how long browsers may cache a preflight response
+// unless CORSPolicy.MaxAge is set
+const DefaultCORSMaxAge = 24 * time.Hour
+
+// DefaultCORSMethods are the methods CORSMiddleware advertises
+var DefaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+
+// CORSPolicy holds the CORS settings shared by all routes. The methods are
+// declared per route, see Middleware.
+type CORSPolicy struct {
+	AllowedOrigins []string
+	MaxAge         time.Duration // Preflight cache lifetime, DefaultCORSMaxAge when 0
+}
+
 // CORSMiddleware creates a middleware that handles CORS headers
 func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	return CORSPolicy{AllowedOrigins: allowedOrigins}.Middleware(DefaultCORSMethods...)
+}
+
+// Middleware creates a middleware that handles CORS headers for routes
+// accepting the given methods. Preflight responses advertise only those
+// methods and OPTIONS.
+func (p CORSPolicy) Middleware(methods ...string) func(http.Handler) http.Handler {
+	allowMethods := make([]string, 0, len(methods)+1)
+	for _, method := range methods {
+		if method != http.MethodOptions {
+			allowMethods = append(allowMethods, method)
+		}
+	}
+	allowMethods = append(allowMethods, http.MethodOptions)
+	allowMethodsHeader := strings.Join(allowMethods, ", ")
+
+	maxAge := p.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultCORSMaxAge
+	}
+	maxAgeHeader := strconv.Itoa(int(maxAge / time.Second))
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
 			// Check if origin is allowed
 			allowed := false
-			for _, allowedOrigin := range allowedOrigins {
+			for _, allowedOrigin := range p.AllowedOrigins {
 				if allowedOrigin == "*" || allowedOrigin == origin {
 					allowed = true
 					break
@@ -143,10 +184,10 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 			}
 
 			// Set other CORS headers
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", allowMethodsHeader)
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Max-Age", "86400")
+			w.Header().Set("Access-Control-Max-Age", maxAgeHeader)
 
 			// Handle preflight requests
 			if r.Method == http.MethodOptions {
@@ -163,7 +204,7 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 func LoggingMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			log.Printf("%s %s from %s", r.Method, redact.String(r.URL.RequestURI()), ClientIP(r))
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -175,7 +216,7 @@ func RecoveryMiddleware() func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					log.Printf("Panic recovered: %v", err)
+					log.Printf("Panic recovered: %s", redact.String(fmt.Sprint(err)))
 					respondError(w, http.StatusInternalServerError, "Internal server error")
 				}
 			}()
@@ -229,6 +270,6 @@ func respondError(w http.ResponseWriter, status int, message string) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":  "error",
-		"message": message,
+		"message": redact.String(message),
 	})
 }
@@ -456,3 +456,74 @@ func TestGetSessionFromContext(t *testing.T) {
 		t.Errorf("Expected user ID 'user-123', got %q", body)
 	}
 }
+
+// TestClientIPMiddleware tests client IP resolution through trusted proxies
+func TestClientIPMiddleware(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    *TrustedProxies
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"no proxy", proxies, "203.0.113.9:5000", nil, "", "203.0.113.9"},
+		{"trusted proxy", proxies, "10.0.0.1:5000", []string{"203.0.113.9"}, "", "203.0.113.9"},
+		{"single trusted IP", proxies, "192.168.1.5:5000", []string{"203.0.113.9"}, "", "203.0.113.9"},
+		{"chain of trusted proxies", proxies, "10.0.0.1:5000", []string{"203.0.113.9, 10.0.0.2"}, "", "203.0.113.9"},
+		{"split header", proxies, "10.0.0.1:5000", []string{"203.0.113.9", "10.0.0.2"}, "", "203.0.113.9"},
+		{"untrusted peer spoofing", proxies, "203.0.113.9:5000", []string{"1.2.3.4"}, "1.2.3.4", "203.0.113.9"},
+		{"spoofed hop before proxy", proxies, "10.0.0.1:5000", []string{"1.2.3.4, 203.0.113.9"}, "", "203.0.113.9"},
+		{"malformed hop", proxies, "10.0.0.1:5000", []string{"1.2.3.4, bogus, 10.0.0.2"}, "", "10.0.0.2"},
+		{"only trusted hops", proxies, "10.0.0.1:5000", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"real IP header", proxies, "10.0.0.1:5000", nil, "203.0.113.9", "203.0.113.9"},
+		{"invalid real IP header", proxies, "10.0.0.1:5000", nil, "bogus", "10.0.0.1"},
+		{"proxies not trusted", nil, "10.0.0.1:5000", []string{"203.0.113.9"}, "203.0.113.9", "10.0.0.1"},
+		{"IPv6 peer", proxies, "[2001:db8::1]:5000", []string{"1.2.3.4"}, "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientIPMiddleware(tt.proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientIPWithoutMiddleware tests that ClientIP falls back to the peer address
+func TestClientIPWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := ClientIP(req); got != "203.0.113.9" {
+		t.Errorf("ClientIP() = %q, want %q", got, "203.0.113.9")
+	}
+
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseTrustedProxies() accepted an invalid CIDR")
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("ParseTrustedProxies() accepted a host name")
+	}
+}
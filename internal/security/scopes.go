@@ -0,0 +1,63 @@
+package security
+
+import (
+	"strings"
+	"time"
+)
+
+// ToolScopePrefix 工具权限的前缀，例如 "tools:exec"
+const ToolScopePrefix = "tools:"
+
+// ConfigReadScope 读取 /api/config 所需的权限
+const ConfigReadScope = "config:read"
+
+// DefaultToolScopes 内置工具默认需要的权限
+var DefaultToolScopes = map[string]string{
+	"read":  "tools:read",
+	"write": "tools:write",
+	"exec":  "tools:exec",
+}
+
+// ToolScope 返回执行工具所需的权限：优先使用配置覆盖，其次默认映射，否则为 "tools:<name>"
+func ToolScope(toolName string, overrides map[string]string) string {
+	if scope, ok := overrides[toolName]; ok && scope != "" {
+		return scope
+	}
+	if scope, ok := DefaultToolScopes[toolName]; ok {
+		return scope
+	}
+	return ToolScopePrefix + toolName
+}
+
+// HasScope 检查密钥是否拥有指定权限，支持 "*" 和 "tools:*" 形式的通配符
+func (k *APIKey) HasScope(requiredScope string) bool {
+	for _, scope := range k.Scopes {
+		if scope == requiredScope || scope == "*" {
+			return true
+		}
+		if strings.HasSuffix(scope, ":*") && strings.HasPrefix(requiredScope, strings.TrimSuffix(scope, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAPIKey 注册一个已有的API密钥（例如来自配置文件），ttl为0表示永不过期
+func (sm *SecurityManager) AddAPIKey(key, name string, scopes []string, ttl time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	expiresAt := time.Now().AddDate(100, 0, 0)
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	sm.apiKeys[key] = APIKey{
+		Key:       key,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+		Active:    true,
+	}
+}
@@ -0,0 +1,259 @@
+// Package tasklist keeps a to-do list of development tasks in a JSON file,
+// the goclaw_tasks.json read by the dev status page
+package tasklist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTaskNotFound is returned for an unknown task ID
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrEmptyTitle is returned when a task would have no title
+	ErrEmptyTitle = errors.New("task title is required")
+)
+
+// Task is one entry of the task list
+type Task struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Completed   bool       `json:"completed"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	extra map[string]json.RawMessage // Fields this package doesn't know, kept when saving
+}
+
+// knownTaskFields are the JSON fields of Task
+var knownTaskFields = []string{"id", "title", "completed", "createdAt", "completedAt"}
+
+// UnmarshalJSON reads a task, keeping fields written by other tools
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	if err := json.Unmarshal(data, (*plain)(t)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &t.extra); err != nil {
+		return err
+	}
+	for _, field := range knownTaskFields {
+		delete(t.extra, field)
+	}
+	return nil
+}
+
+// MarshalJSON writes a task with the fields it was read with
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plain Task
+	data, err := json.Marshal(plain(t))
+	if err != nil || len(t.extra) == 0 {
+		return data, err
+	}
+
+	fields := make(map[string]json.RawMessage, len(t.extra)+len(knownTaskFields))
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for field, value := range t.extra {
+		if _, known := fields[field]; !known {
+			fields[field] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// Update holds the fields to change on a task; nil fields are left alone
+type Update struct {
+	Title     *string
+	Completed *bool
+}
+
+// List is a task list stored in a JSON file with a "tasks" array. The file is
+// read on every call, so edits made to it by hand show up at once, and other
+// top-level fields such as "nextActions" are kept when it is written.
+type List struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// New returns the task list stored at path. The file is created on the
+// first change.
+func New(path string) *List {
+	return &List{path: path, now: time.Now}
+}
+
+// Path returns the file the list is stored in
+func (l *List) Path() string {
+	return l.path
+}
+
+// ListTasks returns all tasks in the order they were added
+func (l *List) ListTasks() ([]Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, tasks, err := l.load()
+	return tasks, err
+}
+
+// Progress returns how many of the tasks are completed
+func (l *List) Progress() (completed, total int, err error) {
+	tasks, err := l.ListTasks()
+	for _, task := range tasks {
+		if task.Completed {
+			completed++
+		}
+	}
+	return completed, len(tasks), err
+}
+
+// AddTask appends an open task and returns it
+func (l *List) AddTask(title string) (Task, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return Task{}, ErrEmptyTitle
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, tasks, err := l.load()
+	if err != nil {
+		return Task{}, err
+	}
+	now := l.now()
+	task := Task{ID: nextID(tasks), Title: title, CreatedAt: &now}
+	if err := l.save(file, append(tasks, task)); err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// CompleteTask marks a task completed and returns it
+func (l *List) CompleteTask(id string) (Task, error) {
+	completed := true
+	return l.UpdateTask(id, Update{Completed: &completed})
+}
+
+// UpdateTask changes a task's title or completion and returns it
+func (l *List) UpdateTask(id string, update Update) (Task, error) {
+	if update.Title != nil && strings.TrimSpace(*update.Title) == "" {
+		return Task{}, ErrEmptyTitle
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, tasks, err := l.load()
+	if err != nil {
+		return Task{}, err
+	}
+	for i := range tasks {
+		if tasks[i].ID != id {
+			continue
+		}
+		task := &tasks[i]
+		if update.Title != nil {
+			task.Title = strings.TrimSpace(*update.Title)
+		}
+		if update.Completed != nil && *update.Completed != task.Completed {
+			task.Completed = *update.Completed
+			task.CompletedAt = nil
+			if task.Completed {
+				now := l.now()
+				task.CompletedAt = &now
+			}
+		}
+		if err := l.save(file, tasks); err != nil {
+			return Task{}, err
+		}
+		return *task, nil
+	}
+	return Task{}, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+}
+
+// load reads the file's top-level fields and its tasks. A missing file is an
+// empty list. Tasks without an ID are numbered after the highest numeric ID,
+// so they can be addressed; the IDs are saved with the next change.
+func (l *List) load() (map[string]json.RawMessage, []Task, error) {
+	file := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return file, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read task list: %w", err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse task list: %w", err)
+	}
+
+	var tasks []Task
+	if raw, ok := file["tasks"]; ok {
+		if err := json.Unmarshal(raw, &tasks); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse tasks: %w", err)
+		}
+	}
+	for i := range tasks {
+		if tasks[i].ID == "" {
+			tasks[i].ID = nextID(tasks)
+		}
+	}
+	return file, tasks, nil
+}
+
+// save writes tasks into the file's top-level fields, replacing the file
+// atomically
+func (l *List) save(file map[string]json.RawMessage, tasks []Task) error {
+	if tasks == nil {
+		tasks = []Task{}
+	}
+	raw, err := json.Marshal(tasks)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tasks: %w", err)
+	}
+	file["tasks"] = raw
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal task list: %w", err)
+	}
+
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create task list directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tasks-*")
+	if err != nil {
+		return fmt.Errorf("failed to create task list: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write task list: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write task list: %w", err)
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// nextID returns one more than the highest numeric task ID
+func nextID(tasks []Task) string {
+	highest := 0
+	for _, task := range tasks {
+		if n, err := strconv.Atoi(task.ID); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return strconv.Itoa(highest + 1)
+}
@@ -0,0 +1,94 @@
+package tasklist
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddCompleteListRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goclaw_tasks.json")
+	list := New(path)
+
+	first, err := list.AddTask("Write the session janitor")
+	if err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	second, err := list.AddTask("Add the doctor command")
+	if err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("tasks share ID %q", first.ID)
+	}
+
+	done, err := list.CompleteTask(second.ID)
+	if err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if !done.Completed || done.CompletedAt == nil {
+		t.Errorf("completed task = %+v, want it completed with a timestamp", done)
+	}
+
+	// A fresh list reads the same state back from the file
+	tasks, err := New(path).ListTasks()
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "Write the session janitor" || tasks[0].Completed || !tasks[1].Completed {
+		t.Errorf("tasks = %+v, want the first open and the second completed", tasks)
+	}
+	if completed, total, _ := list.Progress(); completed != 1 || total != 2 {
+		t.Errorf("Progress() = %d/%d, want 1/2", completed, total)
+	}
+
+	if _, err := list.CompleteTask("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("CompleteTask(missing) error = %v, want ErrTaskNotFound", err)
+	}
+	if _, err := list.AddTask("  "); err == nil {
+		t.Error("AddTask() with an empty title succeeded")
+	}
+}
+
+func TestListKeepsOtherFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goclaw_tasks.json")
+	existing := `{
+  "nextActions": ["Ship the task API"],
+  "tasks": [
+    {"name": "Legacy task", "completed": true},
+    {"name": "Another legacy task", "completed": false}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatalf("failed to write task list: %v", err)
+	}
+	list := New(path)
+
+	tasks, err := list.ListTasks()
+	if err != nil {
+		t.Fatalf("ListTasks() error = %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].ID != "1" || tasks[1].ID != "2" {
+		t.Fatalf("tasks = %+v, want tasks without IDs numbered", tasks)
+	}
+	if _, err := list.CompleteTask("2"); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var file struct {
+		NextActions []string                 `json:"nextActions"`
+		Tasks       []map[string]interface{} `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("saved task list is invalid: %v", err)
+	}
+	if len(file.NextActions) != 1 {
+		t.Errorf("nextActions = %v, want it kept", file.NextActions)
+	}
+	if len(file.Tasks) != 2 || file.Tasks[1]["name"] != "Another legacy task" || file.Tasks[1]["completed"] != true {
+		t.Errorf("tasks = %v, want the legacy fields kept and the task completed", file.Tasks)
+	}
+}
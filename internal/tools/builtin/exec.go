@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"time"
 
@@ -33,6 +34,8 @@ func ExecTool() *tools.Tool {
 				Required:    false,
 			},
 		},
+		// Upper bound; the timeout parameter usually ends the command sooner
+		Timeout: 10 * time.Minute,
 		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 			// Extract parameters
 			command, ok := params["command"].(string)
@@ -53,16 +56,16 @@ func ExecTool() *tools.Tool {
 				}
 			}
 
-			// Create context with timeout if not already set
-			if _, hasDeadline := ctx.Deadline(); !hasDeadline {
-				var cancel context.CancelFunc
-				ctx, cancel = context.WithTimeout(ctx, timeout)
-				defer cancel()
-			}
+			// Apply the requested timeout; an earlier caller deadline still wins
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
 
 			// Create command
 			cmd := exec.CommandContext(ctx, "sh", "-c", command)
 
+			// Commands that call back into the tool API continue from this depth
+			cmd.Env = append(os.Environ(), tools.DepthEnv+"="+tools.FormatDepth(ctx))
+
 			// Set working directory if provided
 			if workdir, exists := params["workdir"]; exists {
 				if dir, ok := workdir.(string); ok && dir != "" {
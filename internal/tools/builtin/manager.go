@@ -1,23 +1,37 @@
 package builtin
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"goclaw/internal/tools"
 )
 
+// ExpectedTools lists the builtin tools every manager must provide
+var ExpectedTools = []string{"read", "write", "exec"}
+
 // Manager manages all builtin tools
 type Manager struct {
-	registry *tools.Registry
+	registry    *tools.Registry
+	registerErr error
 }
 
 // NewManager creates a new builtin tools manager
 func NewManager() *Manager {
-	registry := tools.NewRegistry()
+	return NewManagerWithRegistry(tools.NewRegistry())
+}
+
+// NewManagerWithRegistry creates a builtin tools manager on an existing
+// registry. Builtins that are already registered are left in place, so
+// several managers can share one registry.
+func NewManagerWithRegistry(registry *tools.Registry) *Manager {
 	manager := &Manager{
 		registry: registry,
 	}
 
 	// Register all builtin tools
-	manager.registerBuiltinTools()
+	manager.registerErr = manager.registerBuiltinTools()
 
 	return manager
 }
@@ -27,22 +41,68 @@ func (m *Manager) GetRegistry() *tools.Registry {
 	return m.registry
 }
 
-// registerBuiltinTools registers all builtin tools
-func (m *Manager) registerBuiltinTools() {
-	// File operations
-	m.registry.Register(ReadTool())
-	m.registry.Register(WriteTool())
+// registerBuiltinTools registers all builtin tools, skipping ones that
+// already exist and aggregating any registration failures
+func (m *Manager) registerBuiltinTools() error {
+	builtins := []*tools.Tool{
+		// File operations
+		ReadTool(),
+		WriteTool(),
+
+		// System operations
+		ExecTool(),
+
+		// Note: More tools will be added here as they are implemented:
+		// - web_search
+		// - web_fetch
+		// - memory_search
+		// - browser control
+		// - messaging
+		// - etc.
+	}
+
+	var failures []string
+	for _, tool := range builtins {
+		if m.registry.Exists(tool.Name) {
+			continue
+		}
+		if err := m.registry.Register(tool); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to register builtin tools: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// SelfCheck verifies that every expected builtin tool registered successfully
+func (m *Manager) SelfCheck() error {
+	var problems []string
+	if m.registerErr != nil {
+		problems = append(problems, m.registerErr.Error())
+	}
+	for _, name := range ExpectedTools {
+		if !m.registry.Exists(name) {
+			problems = append(problems, fmt.Sprintf("builtin tool '%s' is missing", name))
+		}
+	}
 
-	// System operations
-	m.registry.Register(ExecTool())
+	if len(problems) > 0 {
+		return fmt.Errorf("tool self-check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
 
-	// Note: More tools will be added here as they are implemented:
-	// - web_search
-	// - web_fetch
-	// - memory_search
-	// - browser control
-	// - messaging
-	// - etc.
+// ToolNames returns the sorted names of all registered tools
+func (m *Manager) ToolNames() []string {
+	names := make([]string, 0, m.registry.Count())
+	for _, tool := range m.registry.List() {
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // GetAllTools returns all builtin tools
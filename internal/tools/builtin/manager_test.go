@@ -0,0 +1,41 @@
+package builtin
+
+import (
+	"strings"
+	"testing"
+
+	"goclaw/internal/tools"
+)
+
+func TestNewManagerRegistersExpectedTools(t *testing.T) {
+	manager := NewManager()
+
+	if err := manager.SelfCheck(); err != nil {
+		t.Fatalf("SelfCheck() error = %v", err)
+	}
+	for _, name := range ExpectedTools {
+		if !manager.GetRegistry().Exists(name) {
+			t.Errorf("builtin tool %q is not registered", name)
+		}
+	}
+	if got := strings.Join(manager.ToolNames(), ","); got != "exec,read,write" {
+		t.Errorf("ToolNames() = %s", got)
+	}
+}
+
+func TestNewManagerWithRegistryIsIdempotent(t *testing.T) {
+	registry := tools.NewRegistry()
+
+	first := NewManagerWithRegistry(registry)
+	second := NewManagerWithRegistry(registry)
+
+	if err := first.SelfCheck(); err != nil {
+		t.Errorf("first SelfCheck() error = %v", err)
+	}
+	if err := second.SelfCheck(); err != nil {
+		t.Errorf("second SelfCheck() error = %v", err)
+	}
+	if count := registry.Count(); count != len(ExpectedTools) {
+		t.Errorf("registry has %d tools, want %d", count, len(ExpectedTools))
+	}
+}
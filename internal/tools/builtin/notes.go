@@ -0,0 +1,81 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goclaw/internal/memory"
+	"goclaw/internal/tools"
+)
+
+// errNoSession is returned when a note tool runs outside a chat session
+var errNoSession = errors.New("notes are only available in a chat session")
+
+// NoteTools lets the model leave itself notes in working memory that persist
+// across the turns of a session without showing up in its replies
+func NoteTools(store *memory.MemoryStore) []*tools.Tool {
+	return []*tools.Tool{
+		{
+			Name:        "remember_note",
+			Description: "Save a private note for later turns of this conversation, such as progress on a long task or a fact to keep in mind. Notes are not shown to the user.",
+			Parameters: map[string]tools.Parameter{
+				"note": {
+					Type:        "string",
+					Description: "The note to save",
+					Required:    true,
+				},
+				"priority": {
+					Type:        "number",
+					Description: "Importance of the note; higher priority notes come first (default 0)",
+					Required:    false,
+				},
+			},
+			Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				sessionID := tools.SessionID(ctx)
+				if sessionID == "" {
+					return nil, tools.NewToolError("remember_note", tools.ErrorPermissionDenied, errNoSession)
+				}
+				note, _ := params["note"].(string)
+				if strings.TrimSpace(note) == "" {
+					return nil, fmt.Errorf("note parameter is required and must be a non-empty string")
+				}
+
+				priority := 0
+				switch v := params["priority"].(type) {
+				case float64:
+					priority = int(v)
+				case int:
+					priority = v
+				case int64:
+					priority = int(v)
+				}
+
+				entry := store.AddNote(sessionID, note, priority)
+				return map[string]interface{}{"id": entry.ID, "saved": true}, nil
+			},
+		},
+		{
+			Name:        "recall_notes",
+			Description: "List the private notes saved with remember_note in this conversation, most important first",
+			Parameters:  map[string]tools.Parameter{},
+			Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				sessionID := tools.SessionID(ctx)
+				if sessionID == "" {
+					return nil, tools.NewToolError("recall_notes", tools.ErrorPermissionDenied, errNoSession)
+				}
+
+				notes := make([]map[string]interface{}, 0)
+				for _, entry := range store.Notes(sessionID) {
+					notes = append(notes, map[string]interface{}{
+						"note":      entry.Content,
+						"priority":  entry.Metadata["priority"],
+						"timestamp": entry.Timestamp,
+					})
+				}
+				return map[string]interface{}{"notes": notes}, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,141 @@
+package builtin
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"goclaw/internal/redact"
+	"goclaw/internal/tools"
+)
+
+// DefaultFileDenylist lists files the read tool refuses to open
+var DefaultFileDenylist = []string{".env", ".env.*", "*.pem", "*.key", "id_rsa", "id_ed25519"}
+
+// FilePolicy controls what file tools may read and how output is sanitized
+type FilePolicy struct {
+	Denylist     []string    // Glob patterns matched against the file name and the full path
+	RedactOutput bool        // Mask secrets in file contents using the redact package
+	Root         string      // Sandbox root, may start with ~/; when set, only paths under it or the allowlist can be read
+	Allowlist    []string    // Absolute files or directories readable outside Root
+	AuditLog     *log.Logger // Where reads through the allowlist are logged, the standard logger when nil
+}
+
+// DefaultFilePolicy returns the policy used unless SetFilePolicy is called
+func DefaultFilePolicy() FilePolicy {
+	return FilePolicy{
+		Denylist:     append([]string{}, DefaultFileDenylist...),
+		RedactOutput: true,
+	}
+}
+
+var (
+	policyMu   sync.RWMutex
+	filePolicy = DefaultFilePolicy()
+)
+
+// SetFilePolicy replaces the file policy used by the builtin tools
+func SetFilePolicy(policy FilePolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	filePolicy = policy
+}
+
+// currentFilePolicy returns the active file policy
+func currentFilePolicy() FilePolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return filePolicy
+}
+
+// checkDenylist returns a permission error when path matches the denylist
+func (p FilePolicy) checkDenylist(toolName, path string) error {
+	name := filepath.Base(path)
+	for _, pattern := range p.Denylist {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return deniedError(toolName, path, pattern)
+		}
+		if strings.ContainsRune(pattern, filepath.Separator) {
+			if matched, _ := filepath.Match(pattern, filepath.Clean(path)); matched {
+				return deniedError(toolName, path, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSandbox returns a permission error when Root is set and path is
+// neither under it nor on the allowlist. Reads through the allowlist are
+// written to the audit log.
+func (p FilePolicy) checkSandbox(toolName, path string) error {
+	if p.Root == "" {
+		return nil
+	}
+	absPath, err := resolvePath(path)
+	if err != nil {
+		return tools.NewToolError(toolName, tools.ErrorInvalidParams, fmt.Errorf("invalid path %s: %w", path, err))
+	}
+	root := p.Root
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(root, "~/") {
+		root = filepath.Join(home, root[2:])
+	}
+	if root, err := resolvePath(root); err == nil && within(root, absPath) {
+		return nil
+	}
+	for _, allowed := range p.Allowlist {
+		if !filepath.IsAbs(allowed) {
+			continue
+		}
+		if allowedPath, err := resolvePath(allowed); err == nil && within(allowedPath, absPath) {
+			p.audit("%s: read %s outside the sandbox (allowlisted by %s)", toolName, absPath, allowed)
+			return nil
+		}
+	}
+	return tools.NewToolError(toolName, tools.ErrorPermissionDenied,
+		fmt.Errorf("reading %s is not allowed (outside the sandbox %s)", path, p.Root))
+}
+
+// resolvePath makes path absolute and resolves symlinks when it exists, so a
+// link can't lead out of the sandbox
+func resolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved, nil
+	}
+	return absPath, nil
+}
+
+// within reports whether path is dir itself or inside it; both must be clean
+// absolute paths
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// audit writes one line to the audit log
+func (p FilePolicy) audit(format string, args ...interface{}) {
+	logger := p.AuditLog
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("[audit] "+format, args...)
+}
+
+// sanitize masks secrets in file output when redaction is enabled
+func (p FilePolicy) sanitize(content string) string {
+	if !p.RedactOutput {
+		return content
+	}
+	return redact.String(content)
+}
+
+func deniedError(toolName, path, pattern string) error {
+	return tools.NewToolError(toolName, tools.ErrorPermissionDenied,
+		fmt.Errorf("reading %s is not allowed (matches denylist pattern %q)", path, pattern))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"goclaw/internal/tools"
 )
@@ -33,6 +34,7 @@ func ReadTool() *tools.Tool {
 				Default:     2000,
 			},
 		},
+		Timeout: 10 * time.Second,
 		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 			// Extract parameters
 			path, ok := params["path"].(string)
@@ -65,6 +67,15 @@ func ReadTool() *tools.Tool {
 				}
 			}
 
+			// Refuse files outside the sandbox or on the denylist
+			policy := currentFilePolicy()
+			if err := policy.checkSandbox("read", path); err != nil {
+				return nil, err
+			}
+			if err := policy.checkDenylist("read", path); err != nil {
+				return nil, err
+			}
+
 			// Read file
 			content, err := os.ReadFile(path)
 			if err != nil {
@@ -84,8 +95,8 @@ func ReadTool() *tools.Tool {
 				lines = lines[:limit]
 			}
 
-			// Join lines back
-			result := strings.Join(lines, "\n")
+			// Join lines back, masking any secrets
+			result := policy.sanitize(strings.Join(lines, "\n"))
 
 			// Add metadata
 			return map[string]interface{}{
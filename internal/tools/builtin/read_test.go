@@ -0,0 +1,106 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goclaw/internal/tools"
+)
+
+func TestReadToolMasksSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.yaml")
+	content := "model: glm-4\napiKey: sk-test-1234567890abcdefgh\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	result, err := ReadTool().Execute(context.Background(), map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	got := result.(map[string]interface{})["content"].(string)
+	if strings.Contains(got, "sk-test-1234567890abcdefgh") {
+		t.Errorf("content = %q, want API key masked", got)
+	}
+	if !strings.Contains(got, "model: glm-4") {
+		t.Errorf("content = %q, want other lines kept", got)
+	}
+}
+
+func TestReadToolRefusesDenylistedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("TOKEN=abc\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	_, err := ReadTool().Execute(context.Background(), map[string]interface{}{"path": path})
+	if err == nil {
+		t.Fatal("expected denylist error")
+	}
+	if tools.IsRetryable(err) {
+		t.Errorf("denylist error %v should not be retryable", err)
+	}
+
+	// An empty denylist allows the file again
+	defer SetFilePolicy(DefaultFilePolicy())
+	SetFilePolicy(FilePolicy{RedactOutput: true})
+	if _, err := ReadTool().Execute(context.Background(), map[string]interface{}{"path": path}); err != nil {
+		t.Errorf("Execute() with empty denylist error = %v", err)
+	}
+}
+
+func TestReadToolAllowlistOutsideSandbox(t *testing.T) {
+	root := t.TempDir()
+	shared := t.TempDir()
+	allowed := filepath.Join(shared, "shared.yaml")
+	sibling := filepath.Join(shared, "other.yaml")
+	inside := filepath.Join(root, "notes.md")
+	for _, path := range []string{allowed, sibling, inside} {
+		if err := os.WriteFile(path, []byte("ok\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	var auditLog bytes.Buffer
+	defer SetFilePolicy(DefaultFilePolicy())
+	policy := DefaultFilePolicy()
+	policy.Root = root
+	policy.Allowlist = []string{allowed}
+	policy.AuditLog = log.New(&auditLog, "", 0)
+	SetFilePolicy(policy)
+
+	read := func(path string) error {
+		_, err := ReadTool().Execute(context.Background(), map[string]interface{}{"path": path})
+		return err
+	}
+
+	if err := read(inside); err != nil {
+		t.Errorf("reading inside the sandbox error = %v", err)
+	}
+	if auditLog.Len() != 0 {
+		t.Errorf("audit log = %q, want reads inside the sandbox not logged", auditLog.String())
+	}
+
+	if err := read(allowed); err != nil {
+		t.Errorf("reading an allowlisted file error = %v", err)
+	}
+	if !strings.Contains(auditLog.String(), "shared.yaml") {
+		t.Errorf("audit log = %q, want the allowlisted read logged", auditLog.String())
+	}
+
+	err := read(sibling)
+	var toolErr *tools.ToolError
+	if !errors.As(err, &toolErr) || toolErr.Kind != tools.ErrorPermissionDenied {
+		t.Errorf("reading a sibling of an allowlisted file error = %v, want permission denied", err)
+	}
+	if err := read(filepath.Join(root, "..", filepath.Base(shared), "other.yaml")); err == nil {
+		t.Error("reading out of the sandbox through .. succeeded, want it denied")
+	}
+}
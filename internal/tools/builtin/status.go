@@ -0,0 +1,22 @@
+package builtin
+
+import (
+	"context"
+
+	"goclaw/internal/redact"
+	"goclaw/internal/tools"
+)
+
+// StatusTool lets the model inspect its own setup: the effective config
+// summary, active providers, memory stats and uptime, the same aggregate as
+// /api/stats. The snapshot is redacted, so no key material reaches the model.
+func StatusTool(snapshot SystemInfoFunc) *tools.Tool {
+	return &tools.Tool{
+		Name:        "status",
+		Description: "Get the assistant's own status: configured and active model, providers, memory and session stats, cron tasks and uptime. Use it to answer questions such as which model is in use instead of guessing.",
+		Parameters:  map[string]tools.Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return redact.Value(snapshot()), nil
+		},
+	}
+}
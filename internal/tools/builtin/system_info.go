@@ -0,0 +1,41 @@
+package builtin
+
+import (
+	"context"
+
+	"goclaw/internal/redact"
+	"goclaw/internal/tools"
+)
+
+// DefaultSystemInfoFields are the fields system_info returns when none are
+// configured
+var DefaultSystemInfoFields = []string{"provider", "model", "workspace", "toolCount", "memory", "version"}
+
+// SystemInfoFunc returns a snapshot of the server's runtime facts by field name
+type SystemInfoFunc func() map[string]interface{}
+
+// SystemInfoTool lets the model look up runtime facts such as the active
+// model or workspace path without reading config files. Only the listed
+// fields of the snapshot are returned, and their values are redacted, so
+// operators control what is exposed and secrets never reach the model.
+func SystemInfoTool(snapshot SystemInfoFunc, fields []string) *tools.Tool {
+	if len(fields) == 0 {
+		fields = DefaultSystemInfoFields
+	}
+
+	return &tools.Tool{
+		Name:        "system_info",
+		Description: "Get runtime information about the server: active provider and model, workspace path, tool count, memory stats and version",
+		Parameters:  map[string]tools.Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			values := snapshot()
+			info := make(map[string]interface{}, len(fields))
+			for _, field := range fields {
+				if value, ok := values[field]; ok {
+					info[field] = value
+				}
+			}
+			return redact.Value(info), nil
+		},
+	}
+}
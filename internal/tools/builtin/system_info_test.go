@@ -0,0 +1,56 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func systemInfoSnapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"provider":  "zhipu",
+		"model":     "glm-4",
+		"workspace": "/srv/goclaw",
+		"toolCount": 5,
+		"memory":    map[string]interface{}{"longTermCount": 3},
+		"version":   "0.1.0",
+		"config": map[string]interface{}{
+			"apiKey":  "zhipu-secret-key",
+			"baseUrl": "https://open.bigmodel.cn",
+			"notes":   "gateway key goclaw_live_abc123",
+		},
+	}
+}
+
+func TestSystemInfoToolExcludesSecrets(t *testing.T) {
+	for _, fields := range [][]string{nil, {"model", "config"}} {
+		result, err := SystemInfoTool(systemInfoSnapshot, fields).Execute(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+
+		output, _ := json.Marshal(result)
+		for _, secret := range []string{"zhipu-secret-key", "goclaw_live_abc123"} {
+			if strings.Contains(string(output), secret) {
+				t.Errorf("fields %v: output %s leaks %q", fields, output, secret)
+			}
+		}
+	}
+}
+
+func TestSystemInfoToolReturnsConfiguredFields(t *testing.T) {
+	result, _ := SystemInfoTool(systemInfoSnapshot, nil).Execute(context.Background(), nil)
+	info := result.(map[string]interface{})
+	if info["model"] != "glm-4" || info["workspace"] != "/srv/goclaw" || info["version"] != "0.1.0" {
+		t.Errorf("info = %v, want the default fields", info)
+	}
+	if _, ok := info["config"]; ok {
+		t.Error("config should not be returned unless configured")
+	}
+
+	result, _ = SystemInfoTool(systemInfoSnapshot, []string{"model"}).Execute(context.Background(), nil)
+	if info := result.(map[string]interface{}); len(info) != 1 || info["model"] != "glm-4" {
+		t.Errorf("info = %v, want only the model", info)
+	}
+}
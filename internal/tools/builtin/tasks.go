@@ -0,0 +1,54 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goclaw/internal/tasklist"
+	"goclaw/internal/tools"
+)
+
+// TaskTools lets the model read the task list and mark tasks done as it
+// finishes them
+func TaskTools(list *tasklist.List) []*tools.Tool {
+	return []*tools.Tool{
+		{
+			Name:        "list_tasks",
+			Description: "List the tasks on the project's to-do list with their IDs and whether they are completed",
+			Parameters:  map[string]tools.Parameter{},
+			Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				tasks, err := list.ListTasks()
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"tasks": tasks}, nil
+			},
+		},
+		{
+			Name:        "complete_task",
+			Description: "Mark a task on the project's to-do list as completed. Use list_tasks to find its ID.",
+			Parameters: map[string]tools.Parameter{
+				"id": {
+					Type:        "string",
+					Description: "ID of the task to mark completed",
+					Required:    true,
+				},
+			},
+			Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				id, ok := params["id"].(string)
+				if !ok {
+					return nil, fmt.Errorf("id parameter is required and must be a string")
+				}
+				task, err := list.CompleteTask(id)
+				if errors.Is(err, tasklist.ErrTaskNotFound) {
+					return nil, tools.NewToolError("complete_task", tools.ErrorNotFound, err)
+				}
+				if err != nil {
+					return nil, err
+				}
+				return task, nil
+			},
+		},
+	}
+}
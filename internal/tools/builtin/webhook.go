@@ -0,0 +1,115 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"goclaw/internal/config"
+	"goclaw/internal/external"
+	"goclaw/internal/tools"
+)
+
+// maxWebhookResponse is how much of a webhook's response body is returned
+const maxWebhookResponse = 4096
+
+// WebhookTool sends a payload to one of the named webhooks. Only configured
+// targets can be called, so the model can't send requests to arbitrary URLs.
+// Each target uses the timeout and retry policy of its external.targets entry.
+func WebhookTool(webhooks map[string]string, cfg config.ExternalConfig) (*tools.Tool, error) {
+	clients := make(map[string]*external.Client, len(webhooks))
+	names := make([]string, 0, len(webhooks))
+	var timeout time.Duration
+	for name := range webhooks {
+		policy, err := external.PolicyFor(cfg, name)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: %w", name, err)
+		}
+		clients[name] = external.NewClient(policy)
+		names = append(names, name)
+		if d := policy.MaxDuration(); d > timeout {
+			timeout = d
+		}
+	}
+	sort.Strings(names)
+
+	return &tools.Tool{
+		Name:        "webhook",
+		Description: "Send a payload to a configured webhook. Available targets: " + strings.Join(names, ", "),
+		Parameters: map[string]tools.Parameter{
+			"target": {
+				Type:        "string",
+				Description: "Name of the webhook to call",
+				Required:    true,
+			},
+			"payload": {
+				Type:        "string",
+				Description: "Request body; sent as JSON when it is valid JSON",
+				Required:    true,
+			},
+			"method": {
+				Type:        "string",
+				Description: "POST or PUT; PUT is retried on failure",
+				Default:     http.MethodPost,
+			},
+		},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			target, _ := params["target"].(string)
+			url, ok := webhooks[target]
+			if !ok {
+				return nil, fmt.Errorf("unknown webhook target: %q", target)
+			}
+
+			payload, ok := params["payload"].(string)
+			if !ok {
+				return nil, fmt.Errorf("payload parameter is required and must be a string")
+			}
+
+			method := http.MethodPost
+			if m, ok := params["method"].(string); ok && m != "" {
+				method = strings.ToUpper(m)
+			}
+			if method != http.MethodPost && method != http.MethodPut {
+				return nil, fmt.Errorf("unsupported webhook method: %s", method)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader([]byte(payload)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create webhook request: %w", err)
+			}
+			if json.Valid([]byte(payload)) {
+				req.Header.Set("Content-Type", "application/json")
+			} else {
+				req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+			}
+			req.Header.Set(tools.DepthHeader, tools.FormatDepth(ctx))
+
+			resp, err := clients[target].Do(ctx, req)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s failed: %w", target, err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read webhook response: %w", err)
+			}
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return nil, fmt.Errorf("webhook %s returned status %d", target, resp.StatusCode)
+			}
+
+			return map[string]interface{}{
+				"target":   target,
+				"status":   resp.StatusCode,
+				"response": string(body),
+			}, nil
+		},
+		Timeout: timeout,
+	}, nil
+}
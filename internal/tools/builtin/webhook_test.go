@@ -0,0 +1,84 @@
+package builtin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"goclaw/internal/config"
+)
+
+// flakyWebhook fails the first failures requests with 503, then echoes the
+// method, content type and body
+func flakyWebhook(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newWebhookTool(t *testing.T, url string) func(params map[string]interface{}) (interface{}, error) {
+	t.Helper()
+
+	retries := 2
+	tool, err := WebhookTool(map[string]string{"deploy": url}, config.ExternalConfig{
+		ExternalCallConfig: config.ExternalCallConfig{Timeout: "1s", MaxRetries: &retries, Backoff: "1ms"},
+	})
+	if err != nil {
+		t.Fatalf("WebhookTool() error = %v", err)
+	}
+	return func(params map[string]interface{}) (interface{}, error) {
+		return tool.Execute(context.Background(), params)
+	}
+}
+
+func TestWebhookToolRetriesFlakyPut(t *testing.T) {
+	server, calls := flakyWebhook(t, 2)
+	execute := newWebhookTool(t, server.URL)
+
+	result, err := execute(map[string]interface{}{"target": "deploy", "payload": `{"ref":"main"}`, "method": "put"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	data := result.(map[string]interface{})
+	if data["status"] != http.StatusOK || data["response"] != `PUT application/json {"ref":"main"}` {
+		t.Errorf("result = %v, want the replayed JSON body", data)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestWebhookToolDoesNotRetryPost(t *testing.T) {
+	server, calls := flakyWebhook(t, 1)
+	execute := newWebhookTool(t, server.URL)
+
+	_, err := execute(map[string]interface{}{"target": "deploy", "payload": "deploy main"})
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Errorf("Execute() error = %v, want the 503", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestWebhookToolRejectsUnknownTarget(t *testing.T) {
+	execute := newWebhookTool(t, "http://127.0.0.1:1")
+
+	if _, err := execute(map[string]interface{}{"target": "http://example.com", "payload": "x"}); err == nil {
+		t.Error("Execute() should reject a target that isn't configured")
+	}
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// DefaultMaxDepth is how deeply tool calls may nest unless configured
+const DefaultMaxDepth = 5
+
+// ErrMaxDepthExceeded is returned for a tool call nested deeper than the
+// executor allows, e.g. by a tool that keeps calling itself
+var ErrMaxDepthExceeded = errors.New("max tool nesting depth exceeded")
+
+// Carry the call depth across process boundaries: tools that run commands
+// set DepthEnv, tools that make HTTP requests set DepthHeader, and the tool
+// API continues from the depth in DepthHeader
+const (
+	DepthEnv    = "GOCLAW_TOOL_DEPTH"
+	DepthHeader = "X-Goclaw-Tool-Depth"
+)
+
+// depthKey is the context key of WithDepth
+type depthKey struct{}
+
+// WithDepth returns a context for tool calls made at the given depth, which
+// is the number of tool calls the context is already inside of
+func WithDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, depthKey{}, depth)
+}
+
+// Depth returns the number of tool calls ctx is inside of
+func Depth(ctx context.Context) int {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	return depth
+}
+
+// ParseDepth parses a depth from DepthEnv or DepthHeader; a missing or
+// invalid value is 0
+func ParseDepth(value string) int {
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return 0
+	}
+	return depth
+}
+
+// FormatDepth formats the depth of ctx for DepthEnv or DepthHeader
+func FormatDepth(ctx context.Context) string {
+	return strconv.Itoa(Depth(ctx))
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// ErrorKind classifies why a tool call failed
+type ErrorKind string
+
+// Tool error kinds
+const (
+	ErrorUnknownTool      ErrorKind = "unknown_tool"      // No tool with that name is registered
+	ErrorInvalidParams    ErrorKind = "invalid_params"    // Parameters failed validation
+	ErrorNotFound         ErrorKind = "not_found"         // A file or resource named in the parameters does not exist
+	ErrorTimeout          ErrorKind = "timeout"           // The tool did not finish in time
+	ErrorPermissionDenied ErrorKind = "permission_denied" // The tool is not allowed to perform the operation
+	ErrorExecution        ErrorKind = "execution"         // Any other failure inside the tool
+)
+
+// ToolError is a classified tool failure
+type ToolError struct {
+	Tool string
+	Kind ErrorKind
+	Err  error
+}
+
+// Error returns the underlying error message
+func (e *ToolError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the call may succeed if repeated, possibly with
+// corrected parameters. Permission and generic execution failures are not.
+func (e *ToolError) Retryable() bool {
+	switch e.Kind {
+	case ErrorUnknownTool, ErrorInvalidParams, ErrorNotFound, ErrorTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewToolError wraps err as a ToolError of the given kind
+func NewToolError(toolName string, kind ErrorKind, err error) *ToolError {
+	return &ToolError{Tool: toolName, Kind: kind, Err: err}
+}
+
+// ClassifyError wraps an error returned by a tool, inferring its kind from
+// the standard library sentinel errors it wraps
+func ClassifyError(toolName string, err error) *ToolError {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+
+	kind := ErrorExecution
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		kind = ErrorNotFound
+	case errors.Is(err, fs.ErrPermission):
+		kind = ErrorPermissionDenied
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrorTimeout
+	}
+	return NewToolError(toolName, kind, err)
+}
+
+// IsRetryable reports whether err is a retryable tool error
+func IsRetryable(err error) bool {
+	var toolErr *ToolError
+	return errors.As(err, &toolErr) && toolErr.Retryable()
+}
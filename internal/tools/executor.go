@@ -13,13 +13,19 @@ import (
 type Executor struct {
 	registry *Registry
 	timeout  time.Duration
+	maxDepth int // Tool calls a call may be nested in, including itself
+
+	recovery            RecoveryFunc // Optional follow-up model call for malformed tool calls
+	maxRecoveryAttempts int
 }
 
 // NewExecutor creates a new tool executor
 func NewExecutor(registry *Registry) *Executor {
 	return &Executor{
-		registry: registry,
-		timeout:  30 * time.Second, // Default timeout
+		registry:            registry,
+		timeout:             30 * time.Second, // Default timeout
+		maxDepth:            DefaultMaxDepth,
+		maxRecoveryAttempts: DefaultMaxRecoveryAttempts,
 	}
 }
 
@@ -28,15 +34,35 @@ func (e *Executor) SetTimeout(timeout time.Duration) {
 	e.timeout = timeout
 }
 
-// Execute executes a tool call
+// SetMaxDepth sets how deeply tool calls may nest; a tool called by a tool
+// is at depth 2. A maxDepth of zero or less uses DefaultMaxDepth.
+func (e *Executor) SetMaxDepth(maxDepth int) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	e.maxDepth = maxDepth
+}
+
+// Execute executes a tool call. Failures are returned as *ToolError.
 func (e *Executor) Execute(ctx context.Context, toolName string, params map[string]interface{}) (*ToolResult, error) {
+	// Refuse calls nested too deeply before doing anything else
+	depth := Depth(ctx) + 1
+	if depth > e.maxDepth {
+		err := fmt.Errorf("%w: %s would run at depth %d, the limit is %d", ErrMaxDepthExceeded, toolName, depth, e.maxDepth)
+		return &ToolResult{
+			Success: false,
+			Error:   err.Error(),
+		}, NewToolError(toolName, ErrorExecution, err)
+	}
+	ctx = WithDepth(ctx, depth)
+
 	// Get tool from registry
 	tool, err := e.registry.Get(toolName)
 	if err != nil {
 		return &ToolResult{
 			Success: false,
 			Error:   err.Error(),
-		}, err
+		}, NewToolError(toolName, ErrorUnknownTool, err)
 	}
 
 	// Validate parameters
@@ -44,11 +70,16 @@ func (e *Executor) Execute(ctx context.Context, toolName string, params map[stri
 		return &ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("parameter validation failed: %v", err),
-		}, err
+		}, NewToolError(toolName, ErrorInvalidParams, err)
 	}
 
-	// Create context with timeout if not already set
-	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+	// A tool's own timeout takes precedence over the executor default;
+	// otherwise apply the default if no deadline is already set
+	if tool.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tool.Timeout)
+		defer cancel()
+	} else if _, hasDeadline := ctx.Deadline(); !hasDeadline {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, e.timeout)
 		defer cancel()
@@ -78,13 +109,36 @@ func (e *Executor) Execute(ctx context.Context, toolName string, params map[stri
 		return &ToolResult{
 			Success: false,
 			Error:   err.Error(),
-		}, err
+		}, ClassifyError(toolName, err)
 	case <-ctx.Done():
 		return &ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("tool execution timed out: %v", ctx.Err()),
-		}, ctx.Err()
+		}, ClassifyError(toolName, ctx.Err())
+	}
+}
+
+// ExecuteCall executes a stored call, first upgrading its params to the
+// tool's current schema
+func (e *Executor) ExecuteCall(ctx context.Context, call ToolCall) (*ToolResult, error) {
+	tool, err := e.registry.Get(call.Name)
+	if err != nil {
+		return e.Execute(ctx, call.Name, call.Params)
+	}
+
+	params, err := tool.MigrateParams(call.SchemaVersion, call.Params)
+	if err != nil {
+		return &ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("parameter migration failed: %v", err),
+		}, NewToolError(call.Name, ErrorInvalidParams, err)
 	}
+	return e.Execute(ctx, call.Name, params)
+}
+
+// MigrateCall upgrades a stored call to its tool's current schema
+func (e *Executor) MigrateCall(call ToolCall) (ToolCall, error) {
+	return e.registry.MigrateCall(call)
 }
 
 // ExecuteMultiple executes multiple tool calls in sequence
@@ -240,13 +294,3 @@ func (e *Executor) FormatToolCall(call *ToolCall) string {
 	paramsJSON, _ := json.MarshalIndent(call.Params, "  ", "  ")
 	return fmt.Sprintf("Tool: %s\nParams: %s", call.Name, string(paramsJSON))
 }
-
-// FormatToolResult formats a tool result for display/logging
-func (e *Executor) FormatToolResult(result *ToolResult) string {
-	if result.Success {
-		dataJSON, _ := json.MarshalIndent(result.Data, "  ", "  ")
-		return fmt.Sprintf("Success: true\nData: %s", string(dataJSON))
-	}
-
-	return fmt.Sprintf("Success: false\nError: %s", result.Error)
-}
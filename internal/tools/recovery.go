@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxRecoveryAttempts is the default number of follow-up model calls
+// made when a response looks like a tool call but cannot be parsed
+const DefaultMaxRecoveryAttempts = 2
+
+// RecoveryFunc sends a prompt to the model and returns its raw response.
+// It is used to re-ask the model for a strict JSON tool call.
+type RecoveryFunc func(ctx context.Context, prompt string) (string, error)
+
+// toolIntentKeywords are hints that a response was trying to invoke a tool
+var toolIntentKeywords = []string{
+	"tool", "function", "params", "parameters", "工具", "调用",
+}
+
+// SetRecovery enables LLM-driven recovery of malformed tool calls.
+// A maxAttempts of zero or less uses DefaultMaxRecoveryAttempts.
+func (e *Executor) SetRecovery(fn RecoveryFunc, maxAttempts int) {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxRecoveryAttempts
+	}
+	e.recovery = fn
+	e.maxRecoveryAttempts = maxAttempts
+}
+
+// IntendsToolCall reports whether a response appears to be attempting a tool call
+func (e *Executor) IntendsToolCall(response string) bool {
+	if strings.Contains(response, "{") {
+		return true
+	}
+
+	lowerResponse := strings.ToLower(response)
+	for _, keyword := range toolIntentKeywords {
+		if strings.Contains(lowerResponse, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseToolCallWithRecovery parses a tool call like ParseToolCall, but when
+// parsing fails and the response seems to intend a tool, it asks the model
+// again for a strict JSON tool call, up to the configured number of attempts.
+func (e *Executor) ParseToolCallWithRecovery(ctx context.Context, aiResponse string) (*ToolCall, error) {
+	call, err := e.ParseToolCall(aiResponse)
+	if err == nil {
+		return call, nil
+	}
+
+	if e.recovery == nil || !e.IntendsToolCall(aiResponse) {
+		return nil, err
+	}
+
+	response := aiResponse
+	for attempt := 1; attempt <= e.maxRecoveryAttempts; attempt++ {
+		retry, recoverErr := e.recovery(ctx, e.buildRecoveryPrompt(response))
+		if recoverErr != nil {
+			return nil, fmt.Errorf("tool call recovery failed: %w", recoverErr)
+		}
+
+		// Only accept strict JSON on recovery, and only for known tools
+		if e.IsJSONToolCall(retry) {
+			recovered, parseErr := e.parseJSONToolCall(retry)
+			if parseErr == nil && e.registry.Exists(recovered.Name) {
+				return recovered, nil
+			}
+		}
+
+		response = retry
+	}
+
+	return nil, fmt.Errorf("no valid tool call after %d recovery attempts: %w", e.maxRecoveryAttempts, err)
+}
+
+// buildRecoveryPrompt builds the follow-up prompt asking for a strict JSON tool call
+func (e *Executor) buildRecoveryPrompt(previous string) string {
+	schema, err := e.registry.ToJSON()
+	if err != nil {
+		schema = "[]"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your previous response tried to use a tool, but the tool call could not be parsed.\n\n")
+	sb.WriteString("Previous response:\n")
+	sb.WriteString(previous)
+	sb.WriteString("\n\nAvailable tools (JSON schema):\n")
+	sb.WriteString(schema)
+	sb.WriteString("\n\nReply with ONLY a single JSON object and no other text, in this exact format:\n")
+	sb.WriteString(`{"tool": "<tool name>", "params": {"<param>": <value>}}`)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseToolCallWithRecovery(t *testing.T) {
+	registry := NewRegistry()
+
+	var executedPath string
+	registry.Register(&Tool{
+		Name:        "lookup",
+		Description: "Look up a file",
+		Parameters: map[string]Parameter{
+			"path": {
+				Type:     "string",
+				Required: true,
+			},
+		},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			executedPath = params["path"].(string)
+			return "found", nil
+		},
+	})
+
+	executor := NewExecutor(registry)
+
+	var prompts []string
+	executor.SetRecovery(func(ctx context.Context, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return `{"tool": "lookup", "params": {"path": "/tmp/notes.txt"}}`, nil
+	}, 2)
+
+	// First response is prose that clearly wants a tool but isn't parseable
+	prose := "Sure! I will use a tool to check the notes file at /tmp/notes.txt for you."
+
+	call, err := executor.ParseToolCallWithRecovery(context.Background(), prose)
+	if err != nil {
+		t.Fatalf("ParseToolCallWithRecovery() error = %v", err)
+	}
+	result, err := executor.Execute(context.Background(), call.Name, call.Params)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if call.Name != "lookup" {
+		t.Errorf("recovered call name = %v, want lookup", call.Name)
+	}
+	if !result.Success || result.Data != "found" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if executedPath != "/tmp/notes.txt" {
+		t.Errorf("tool executed with path %q, want /tmp/notes.txt", executedPath)
+	}
+	if len(prompts) != 1 {
+		t.Fatalf("expected 1 recovery call, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[0], `"lookup"`) {
+		t.Error("recovery prompt should include the tool schema")
+	}
+}
+
+func TestParseToolCallWithRecoveryCapsAttempts(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&Tool{
+		Name:       "lookup",
+		Parameters: map[string]Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	})
+
+	executor := NewExecutor(registry)
+
+	calls := 0
+	executor.SetRecovery(func(ctx context.Context, prompt string) (string, error) {
+		calls++
+		return "I still want to call a tool, honestly.", nil
+	}, 3)
+
+	_, err := executor.ParseToolCallWithRecovery(context.Background(), "Let me call a tool for that.")
+	if err == nil {
+		t.Fatal("expected error when recovery never yields valid JSON")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 recovery attempts, got %d", calls)
+	}
+}
+
+func TestParseToolCallWithRecoverySkipsPlainAnswers(t *testing.T) {
+	registry := NewRegistry()
+	executor := NewExecutor(registry)
+
+	called := false
+	executor.SetRecovery(func(ctx context.Context, prompt string) (string, error) {
+		called = true
+		return "", fmt.Errorf("should not be called")
+	}, 1)
+
+	_, err := executor.ParseToolCallWithRecovery(context.Background(), "The capital of France is Paris.")
+	if err == nil {
+		t.Error("expected parse error for a plain answer")
+	}
+	if called {
+		t.Error("recovery should not run when the response does not intend a tool")
+	}
+}
@@ -34,6 +34,10 @@ func (r *Registry) Register(tool *Tool) error {
 		return fmt.Errorf("tool execute function cannot be nil")
 	}
 
+	if err := tool.ValidateDefinition(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -139,11 +143,15 @@ func (r *Registry) ToJSON() (string, error) {
 
 	tools := make([]map[string]interface{}, 0, len(r.tools))
 	for _, tool := range r.tools {
-		tools = append(tools, map[string]interface{}{
+		entry := map[string]interface{}{
 			"name":        tool.Name,
 			"description": tool.Description,
 			"parameters":  tool.Parameters,
-		})
+		}
+		if tool.Timeout > 0 {
+			entry["timeout"] = tool.Timeout.String()
+		}
+		tools = append(tools, entry)
 	}
 
 	jsonBytes, err := json.MarshalIndent(tools, "", "  ")
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -269,3 +270,108 @@ func TestParseToolCall(t *testing.T) {
 		}
 	})
 }
+
+func TestRegistryRejectsUnknownParameterType(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register(&Tool{
+		Name:       "broken",
+		Parameters: map[string]Parameter{"path": {Type: "str"}},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error for unknown parameter type")
+	}
+	if registry.Exists("broken") {
+		t.Error("tool with an invalid definition should not be registered")
+	}
+}
+
+func TestExecutorToolTimeout(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register(&Tool{
+		Name:        "slow_tool",
+		Description: "A slow test tool",
+		Parameters:  map[string]Parameter{},
+		Timeout:     50 * time.Millisecond,
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			select {
+			case <-time.After(2 * time.Second):
+				return "done", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	executor := NewExecutor(registry)
+	executor.SetTimeout(5 * time.Second)
+
+	start := time.Now()
+	result, err := executor.Execute(context.Background(), "slow_tool", map[string]interface{}{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Execute() took %v, want the 50ms tool timeout", elapsed)
+	}
+
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Kind != ErrorTimeout {
+		t.Fatalf("Execute() error = %v, want timeout", err)
+	}
+	if result.Success {
+		t.Error("Execute() result.Success = true, want false")
+	}
+}
+
+func TestRegistryRejectsNegativeTimeout(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register(&Tool{
+		Name:    "broken",
+		Timeout: -time.Second,
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error for negative timeout")
+	}
+}
+
+func TestExecutorStopsAtMaxDepth(t *testing.T) {
+	registry := NewRegistry()
+	executor := NewExecutor(registry)
+	executor.SetMaxDepth(3)
+
+	// recurse calls itself through the executor until that fails
+	var depths []int
+	err := registry.Register(&Tool{
+		Name:        "recurse",
+		Description: "Calls itself",
+		Parameters:  map[string]Parameter{},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			depths = append(depths, Depth(ctx))
+			return executor.Execute(ctx, "recurse", params)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// The error of the innermost call propagates out through every level
+	result, err := executor.Execute(context.Background(), "recurse", map[string]interface{}{})
+	if !errors.Is(err, ErrMaxDepthExceeded) || result.Success {
+		t.Fatalf("Execute() = %+v, %v; want ErrMaxDepthExceeded", result, err)
+	}
+	if len(depths) != 3 || depths[0] != 1 || depths[2] != 3 {
+		t.Errorf("tool ran at depths %v, want 1 to 3", depths)
+	}
+
+	// A depth carried over from another process counts too
+	ctx := WithDepth(context.Background(), ParseDepth("3"))
+	if _, err := executor.Execute(ctx, "recurse", nil); !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Errorf("Execute() at depth 3 error = %v, want ErrMaxDepthExceeded", err)
+	}
+}
@@ -0,0 +1,65 @@
+package tools
+
+import "fmt"
+
+// MigrateFunc upgrades the params of a call by one schema version, e.g. by
+// renaming a parameter
+type MigrateFunc func(params map[string]interface{}) (map[string]interface{}, error)
+
+// Version returns the tool's schema version
+func (t *Tool) Version() int {
+	if t.SchemaVersion == 0 {
+		return 1
+	}
+	return t.SchemaVersion
+}
+
+// MigrateParams upgrades params of the given schema version to the tool's
+// current one. Version 0 counts as 1, since calls stored without a version
+// predate versioning.
+func (t *Tool) MigrateParams(version int, params map[string]interface{}) (map[string]interface{}, error) {
+	if version == 0 {
+		version = 1
+	}
+	if version > t.Version() {
+		return nil, fmt.Errorf("tool '%s' params have schema version %d, newer than the tool's %d", t.Name, version, t.Version())
+	}
+
+	for ; version < t.Version(); version++ {
+		migrate, ok := t.Migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("tool '%s' has no migration from schema version %d", t.Name, version)
+		}
+		migrated, err := migrate(copyParams(params))
+		if err != nil {
+			return nil, fmt.Errorf("tool '%s' migration from schema version %d failed: %w", t.Name, version, err)
+		}
+		params = migrated
+	}
+	return params, nil
+}
+
+// MigrateCall upgrades a stored call to its tool's current schema and stamps
+// it with that version
+func (r *Registry) MigrateCall(call ToolCall) (ToolCall, error) {
+	tool, err := r.Get(call.Name)
+	if err != nil {
+		return call, err
+	}
+
+	params, err := tool.MigrateParams(call.SchemaVersion, call.Params)
+	if err != nil {
+		return call, err
+	}
+	return ToolCall{Name: call.Name, Params: params, SchemaVersion: tool.Version()}, nil
+}
+
+// copyParams copies the top level of params, so a migration can't change the
+// caller's map
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		copied[key] = value
+	}
+	return copied
+}
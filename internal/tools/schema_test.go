@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// readToolV2 renamed the "file" parameter of version 1 to "path"
+func readToolV2(read *[]string) *Tool {
+	return &Tool{
+		Name: "read",
+		Parameters: map[string]Parameter{
+			"path": {Type: "string", Required: true},
+		},
+		SchemaVersion: 2,
+		Migrations: map[int]MigrateFunc{
+			1: func(params map[string]interface{}) (map[string]interface{}, error) {
+				file, ok := params["file"]
+				if !ok {
+					return nil, fmt.Errorf("missing file")
+				}
+				delete(params, "file")
+				params["path"] = file
+				return params, nil
+			},
+		},
+		Execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			*read = append(*read, params["path"].(string))
+			return "contents", nil
+		},
+	}
+}
+
+func TestExecuteCallMigratesStoredV1Call(t *testing.T) {
+	var read []string
+	registry := NewRegistry()
+	if err := registry.Register(readToolV2(&read)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	executor := NewExecutor(registry)
+
+	stored := ToolCall{Name: "read", Params: map[string]interface{}{"file": "notes.txt"}, SchemaVersion: 1}
+	result, err := executor.ExecuteCall(context.Background(), stored)
+	if err != nil || !result.Success {
+		t.Fatalf("ExecuteCall() = %+v, %v, want success", result, err)
+	}
+	if len(read) != 1 || read[0] != "notes.txt" {
+		t.Errorf("tool read %v, want notes.txt", read)
+	}
+	if _, ok := stored.Params["path"]; ok {
+		t.Error("migration should not change the stored call")
+	}
+
+	// A call stored without a version predates versioning
+	migrated, err := registry.MigrateCall(ToolCall{Name: "read", Params: map[string]interface{}{"file": "a.txt"}})
+	if err != nil || migrated.SchemaVersion != 2 || migrated.Params["path"] != "a.txt" {
+		t.Errorf("MigrateCall() = %+v, %v, want a version 2 call", migrated, err)
+	}
+
+	// Without migration the old shape fails validation
+	if _, err := executor.Execute(context.Background(), "read", map[string]interface{}{"file": "notes.txt"}); err == nil {
+		t.Error("Execute() with v1 params should fail validation")
+	}
+}
+
+func TestMigrateParamsRejectsUnknownVersions(t *testing.T) {
+	var read []string
+	tool := readToolV2(&read)
+
+	if _, err := tool.MigrateParams(3, map[string]interface{}{"path": "a"}); err == nil {
+		t.Error("MigrateParams() should reject a version newer than the tool's")
+	}
+
+	tool.SchemaVersion = 3
+	if _, err := tool.MigrateParams(1, map[string]interface{}{"file": "a"}); err == nil {
+		t.Error("MigrateParams() should fail without a migration for every step")
+	}
+
+	result, err := NewExecutor(registryWith(t, tool)).ExecuteCall(context.Background(), ToolCall{Name: "read", Params: map[string]interface{}{"file": "a"}, SchemaVersion: 1})
+	if !IsRetryable(err) || result.Success {
+		t.Errorf("ExecuteCall() = %+v, %v, want an invalid params error", result, err)
+	}
+}
+
+func registryWith(t *testing.T, tool *Tool) *Registry {
+	t.Helper()
+	registry := NewRegistry()
+	if err := registry.Register(tool); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return registry
+}
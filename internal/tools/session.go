@@ -0,0 +1,18 @@
+package tools
+
+import "context"
+
+// sessionIDKey is the context key of WithSessionID
+type sessionIDKey struct{}
+
+// WithSessionID returns a context telling the tools executed with it which
+// chat session called them
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// SessionID returns the chat session set with WithSessionID, or ""
+func SessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDKey{}).(string)
+	return sessionID
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Tool represents a callable tool that AI can use
@@ -13,6 +15,13 @@ type Tool struct {
 	Description string                 // Tool description for AI
 	Parameters  map[string]Parameter   // Parameter definitions
 	Execute     ToolExecuteFunc        // Execution function
+	Timeout     time.Duration          // Optional execution timeout; overrides the executor default
+
+	// SchemaVersion is the version of Parameters, 1 when unset. Bump it when
+	// the parameters change shape and add the step to Migrations, so stored
+	// calls of older versions keep working.
+	SchemaVersion int
+	Migrations    map[int]MigrateFunc // Migrations[n] upgrades params from version n to n+1
 }
 
 // Parameter defines a tool parameter
@@ -23,13 +32,24 @@ type Parameter struct {
 	Default     interface{} // Default value
 }
 
+// parameterTypes are the parameter types understood by Validate
+var parameterTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"boolean": true,
+	"array":   true,
+	"object":  true,
+}
+
 // ToolExecuteFunc is the function signature for tool execution
 type ToolExecuteFunc func(ctx context.Context, params map[string]interface{}) (interface{}, error)
 
 // ToolCall represents a single tool call request
 type ToolCall struct {
-	Name   string                 `json:"name"`
-	Params map[string]interface{} `json:"params"`
+	ID            string                 `json:"id,omitempty"` // Provider's ID of a native call, pairing it with its result
+	Name          string                 `json:"name"`
+	Params        map[string]interface{} `json:"params"`
+	SchemaVersion int                    `json:"schemaVersion,omitempty"` // Version of the tool's schema Params follow; set on stored calls
 }
 
 // ToolResult represents the result of a tool execution
@@ -62,6 +82,23 @@ func (t *Tool) Validate(params map[string]interface{}) error {
 	return nil
 }
 
+// ValidateDefinition checks that every parameter declares a known type
+// and that the timeout and schema version, if set, are positive
+func (t *Tool) ValidateDefinition() error {
+	if t.Timeout < 0 {
+		return fmt.Errorf("tool '%s' timeout must be positive, got %v", t.Name, t.Timeout)
+	}
+	if t.SchemaVersion < 0 {
+		return fmt.Errorf("tool '%s' schema version must be positive, got %d", t.Name, t.SchemaVersion)
+	}
+	for paramName, paramDef := range t.Parameters {
+		if !parameterTypes[paramDef.Type] {
+			return fmt.Errorf("tool '%s' parameter %s has unknown type: %q", t.Name, paramName, paramDef.Type)
+		}
+	}
+	return nil
+}
+
 // validateType checks if a value matches the expected type
 func validateType(paramName string, value interface{}, expectedType string) error {
 	switch expectedType {
@@ -103,6 +140,9 @@ func (t *Tool) ToJSON() (string, error) {
 		"description": t.Description,
 		"parameters":  t.Parameters,
 	}
+	if t.Timeout > 0 {
+		data["timeout"] = t.Timeout.String()
+	}
 
 	jsonBytes, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
@@ -112,12 +152,42 @@ func (t *Tool) ToJSON() (string, error) {
 	return string(jsonBytes), nil
 }
 
+// InputSchema returns the JSON Schema of the tool's parameters, the way
+// native tool calling declares them
+func (t *Tool) InputSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(t.Parameters))
+	var required []string
+	for name, param := range t.Parameters {
+		property := map[string]interface{}{"type": param.Type}
+		if param.Description != "" {
+			property["description"] = param.Description
+		}
+		if param.Default != nil {
+			property["default"] = param.Default
+		}
+		properties[name] = property
+		if param.Required {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
 // ToMarkdown converts the tool to Markdown representation for AI
 func (t *Tool) ToMarkdown() string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("## Tool: %s\n\n", t.Name))
 	sb.WriteString(fmt.Sprintf("**Description:** %s\n\n", t.Description))
+	if t.Timeout > 0 {
+		sb.WriteString(fmt.Sprintf("**Timeout:** %s\n\n", t.Timeout))
+	}
 	sb.WriteString("**Parameters:**\n\n")
 
 	if len(t.Parameters) == 0 {
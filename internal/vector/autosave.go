@@ -0,0 +1,63 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultAutosaveInterval is how often StartAutosave saves a changed store
+const DefaultAutosaveInterval = 5 * time.Minute
+
+// StartAutosave saves the store to path every interval, skipping the write
+// when nothing was added or deleted since the last save. When ctx is done it
+// saves once more and closes the returned channel.
+func (s *InMemoryStore) StartAutosave(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	if interval <= 0 {
+		interval = DefaultAutosaveInterval
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				// The final flush runs after ctx is done, so it can't use it
+				if err := s.saveIfDirty(context.Background(), path); err != nil {
+					fmt.Printf("Error saving vector store: %v\n", err)
+				}
+				return
+			}
+			if err := s.saveIfDirty(ctx, path); err != nil {
+				fmt.Printf("Error auto-saving vector store: %v\n", err)
+			}
+		}
+	}()
+	return done
+}
+
+// saveIfDirty saves the store when it changed since the last save. A failed
+// save leaves it dirty, so the next tick retries.
+func (s *InMemoryStore) saveIfDirty(ctx context.Context, path string) error {
+	s.mu.Lock()
+	dirty := s.dirty
+	s.dirty = false
+	s.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	if err := s.Save(ctx, path); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EmbedderChain implements Embedder by trying each embedder in order and
+// falling back to the next one when it fails, e.g. from a rate-limited cloud
+// embedder to a local Ollama model. Vectors of different dimensions can't be
+// compared, so the first successful embedding fixes the chain's dimension and
+// an embedder returning another dimension counts as failed. A batch is
+// always embedded by a single embedder.
+type EmbedderChain struct {
+	embedders []Embedder
+
+	mu        sync.Mutex
+	dimension int
+	served    map[string]int
+	last      string
+}
+
+// NewEmbedderChain creates a chain trying embedders in the given order
+func NewEmbedderChain(embedders ...Embedder) *EmbedderChain {
+	return &EmbedderChain{
+		embedders: embedders,
+		served:    make(map[string]int),
+	}
+}
+
+// Embed embeds text with the first embedder that succeeds
+func (c *EmbedderChain) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := c.embed(ctx, func(e Embedder) ([][]float32, error) {
+		vector, err := e.Embed(ctx, text)
+		return [][]float32{vector}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch embeds all texts with the first embedder that succeeds
+func (c *EmbedderChain) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return c.embed(ctx, func(e Embedder) ([][]float32, error) {
+		return e.EmbedBatch(ctx, texts)
+	})
+}
+
+// GetModelName returns the models of the chain in order, e.g. "a > b"
+func (c *EmbedderChain) GetModelName() string {
+	names := make([]string, len(c.embedders))
+	for i, e := range c.embedders {
+		names[i] = e.GetModelName()
+	}
+	return strings.Join(names, " > ")
+}
+
+// LastServed returns the model of the embedder that served the last call
+func (c *EmbedderChain) LastServed() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// Served returns how many calls each embedder's model served
+func (c *EmbedderChain) Served() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	served := make(map[string]int, len(c.served))
+	for model, count := range c.served {
+		served[model] = count
+	}
+	return served
+}
+
+// Dimension returns the dimension of the chain's embeddings, 0 until the
+// first one succeeded
+func (c *EmbedderChain) Dimension() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dimension
+}
+
+// embed runs call on each embedder until one returns vectors of the chain's
+// dimension
+func (c *EmbedderChain) embed(ctx context.Context, call func(Embedder) ([][]float32, error)) ([][]float32, error) {
+	if len(c.embedders) == 0 {
+		return nil, fmt.Errorf("embedder chain is empty")
+	}
+
+	var failures []string
+	for _, e := range c.embedders {
+		vectors, err := call(e)
+		if err == nil {
+			err = c.accept(e.GetModelName(), vectors)
+		}
+		if err == nil {
+			return vectors, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", e.GetModelName(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("all embedders failed: %s", strings.Join(failures, "; "))
+}
+
+// accept checks the vectors against the chain's dimension, fixing it on the
+// first success, and records which model served the call
+func (c *EmbedderChain) accept(model string, vectors [][]float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, vector := range vectors {
+		if len(vector) == 0 {
+			return fmt.Errorf("empty embedding")
+		}
+		dimension := c.dimension
+		if dimension == 0 {
+			dimension = len(vectors[0])
+		}
+		if len(vector) != dimension {
+			return fmt.Errorf("embedding has %d dimensions, the chain uses %d", len(vector), dimension)
+		}
+	}
+
+	if c.dimension == 0 && len(vectors) > 0 {
+		c.dimension = len(vectors[0])
+	}
+	c.served[model]++
+	c.last = model
+	return nil
+}
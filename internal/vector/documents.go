@@ -0,0 +1,98 @@
+package vector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Custom metadata keys of indexed documents
+const (
+	ContentHashKey = "contentHash" // SHA-256 of the document content, hex encoded
+	SourceKey      = "source"      // Name or path the document was first indexed from
+)
+
+// DocumentTag tags entries created by IndexDocument
+const DocumentTag = "document"
+
+// ContentHash returns the hex SHA-256 of content, which identifies a document
+// regardless of its name
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// IndexDocument embeds and stores a document once per distinct content. The
+// entry is keyed by the content's hash, so indexing content that is already
+// stored, under any name, returns the existing entry's ID without embedding
+// it again; indexed reports whether a new entry was created.
+func (s *InMemoryStore) IndexDocument(ctx context.Context, name string, content []byte) (id string, indexed bool, err error) {
+	hash := ContentHash(content)
+	if id, ok := s.findHash(hash); ok {
+		return id, false, nil
+	}
+
+	if s.embedder == nil {
+		return "", false, fmt.Errorf("indexing documents requires an embedder")
+	}
+	embedding, err := s.embedder.Embed(ctx, string(content))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another call may have indexed the same content while embedding
+	if id, ok := s.findHashLocked(hash); ok {
+		return id, false, nil
+	}
+
+	vector, err := s.reduce(embedding)
+	if err != nil {
+		return "", false, err
+	}
+	id = "doc_" + hash
+	s.vectors[id] = &VectorEntry{
+		Vector: vector,
+		Metadata: MemoryMetadata{
+			ID:        id,
+			Content:   string(content),
+			Timestamp: now(),
+			Tags:      []string{DocumentTag},
+			Custom:    map[string]string{ContentHashKey: hash, SourceKey: name},
+		},
+	}
+	s.dirty = true
+	return id, true, nil
+}
+
+// IndexFile indexes a file's content with IndexDocument, so an unchanged
+// file is only embedded the first time
+func (s *InMemoryStore) IndexFile(ctx context.Context, path string) (id string, indexed bool, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read file: %w", err)
+	}
+	return s.IndexDocument(ctx, filepath.Base(path), content)
+}
+
+// findHash returns the ID of the entry holding content with the given hash
+func (s *InMemoryStore) findHash(hash string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.findHashLocked(hash)
+}
+
+// findHashLocked is findHash for callers holding the lock
+func (s *InMemoryStore) findHashLocked(hash string) (string, bool) {
+	for id, entry := range s.vectors {
+		if entry.Metadata.Custom[ContentHashKey] == hash {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Embedding provider API names, as used in the "embedding" config section
+const (
+	EmbeddingAPIOllama = "ollama"
+	EmbeddingAPIOpenAI = "openai"
+	EmbeddingAPIZhipu  = "zhipu"
+)
+
+// OpenAIEmbedder implements Embedder for OpenAI-compatible /embeddings APIs
+type OpenAIEmbedder struct {
+	ApiKey  string
+	BaseURL string
+	Model   string
+	Client  *http.Client
+}
+
+// NewOpenAIEmbedder creates a new embedder for an OpenAI-compatible API
+func NewOpenAIEmbedder(apiKey, baseURL, model string) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+
+	return &OpenAIEmbedder{
+		ApiKey:  apiKey,
+		BaseURL: baseURL,
+		Model:   model,
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// NewZhipuEmbedder creates an embedder for Zhipu AI, which exposes an OpenAI-compatible embeddings API
+func NewZhipuEmbedder(apiKey, baseURL, model string) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = "https://open.bigmodel.cn/api/paas/v4"
+	}
+	if model == "" {
+		model = "embedding-2"
+	}
+
+	return NewOpenAIEmbedder(apiKey, baseURL, model)
+}
+
+// NewEmbedder constructs an Embedder for the named provider API
+func NewEmbedder(api, apiKey, baseURL, model string) (Embedder, error) {
+	switch strings.ToLower(api) {
+	case EmbeddingAPIOllama:
+		return NewOllamaEmbedder(baseURL, model), nil
+	case EmbeddingAPIOpenAI, "openai-completions", "openai-embeddings":
+		return NewOpenAIEmbedder(apiKey, baseURL, model), nil
+	case EmbeddingAPIZhipu:
+		return NewZhipuEmbedder(apiKey, baseURL, model), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding api: %q", api)
+	}
+}
+
+// Embed generates an embedding for the given text
+func (o *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts in a single request
+func (o *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": o.Model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := strings.TrimRight(o.BaseURL, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.ApiKey)
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call embeddings API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, item := range result.Data {
+		if item.Index >= 0 && item.Index < len(embeddings) {
+			embeddings[item.Index] = item.Embedding
+		} else {
+			embeddings[i] = item.Embedding
+		}
+	}
+
+	return embeddings, nil
+}
+
+// GetModelName returns the model name
+func (o *OpenAIEmbedder) GetModelName() string {
+	return o.Model
+}
@@ -0,0 +1,65 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Projection is a random linear projection that reduces embedding dimensions.
+// Distances are approximately preserved (Johnson-Lindenstrauss), so searching
+// in the reduced space trades a little recall for smaller storage and faster
+// comparisons. The matrix is saved with the store so reloads project queries
+// the same way.
+type Projection struct {
+	InputDim  int         `json:"inputDim"`
+	OutputDim int         `json:"outputDim"`
+	Seed      int64       `json:"seed"`
+	Matrix    [][]float32 `json:"matrix"` // OutputDim rows of InputDim values
+}
+
+// NewRandomProjection creates a Gaussian random projection from inputDim to outputDim
+func NewRandomProjection(inputDim, outputDim int, seed int64) (*Projection, error) {
+	if outputDim <= 0 || inputDim <= 0 {
+		return nil, fmt.Errorf("invalid projection dimensions: %d -> %d", inputDim, outputDim)
+	}
+	if outputDim >= inputDim {
+		return nil, fmt.Errorf("projection must reduce dimensions: %d -> %d", inputDim, outputDim)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	scale := 1 / math.Sqrt(float64(outputDim))
+
+	matrix := make([][]float32, outputDim)
+	for i := range matrix {
+		row := make([]float32, inputDim)
+		for j := range row {
+			row[j] = float32(rng.NormFloat64() * scale)
+		}
+		matrix[i] = row
+	}
+
+	return &Projection{
+		InputDim:  inputDim,
+		OutputDim: outputDim,
+		Seed:      seed,
+		Matrix:    matrix,
+	}, nil
+}
+
+// Apply projects a vector into the reduced space
+func (p *Projection) Apply(v []float32) ([]float32, error) {
+	if len(v) != p.InputDim {
+		return nil, fmt.Errorf("vector has %d dimensions, projection expects %d", len(v), p.InputDim)
+	}
+
+	reduced := make([]float32, p.OutputDim)
+	for i, row := range p.Matrix {
+		var sum float32
+		for j, x := range v {
+			sum += row[j] * x
+		}
+		reduced[i] = sum
+	}
+	return reduced, nil
+}
@@ -0,0 +1,167 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// hashEmbedder is a mock embedder producing deterministic high-dimensional
+// vectors, so similar inputs can be built by perturbing a base vector
+type hashEmbedder struct {
+	dims int
+}
+
+func (h hashEmbedder) vector(seed int64) []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vec := make([]float32, h.dims)
+	for i := range vec {
+		vec[i] = float32(rng.NormFloat64())
+	}
+	return vec
+}
+
+// perturbed returns a noisy copy of the vector for seed
+func (h hashEmbedder) perturbed(seed int64, noise float64) []float32 {
+	vec := h.vector(seed)
+	rng := rand.New(rand.NewSource(seed + 1_000_000))
+	for i := range vec {
+		vec[i] += float32(rng.NormFloat64() * noise)
+	}
+	return vec
+}
+
+// populate adds n mock embeddings with IDs doc-0..doc-(n-1)
+func populate(t testing.TB, store *InMemoryStore, h hashEmbedder, n int) {
+	for i := 0; i < n; i++ {
+		if _, err := store.Add(context.Background(), h.vector(int64(i)), MemoryMetadata{ID: fmt.Sprintf("doc-%d", i)}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+}
+
+// recallAt is the fraction of queries whose source document is in the top k
+func recallAt(t testing.TB, store *InMemoryStore, h hashEmbedder, queries, k int) float64 {
+	hits := 0
+	for q := 0; q < queries; q++ {
+		results, err := store.Search(context.Background(), h.perturbed(int64(q), 0.8), k)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		for _, r := range results {
+			if r.ID == fmt.Sprintf("doc-%d", q) {
+				hits++
+				break
+			}
+		}
+	}
+	return float64(hits) / float64(queries)
+}
+
+func TestDimensionReductionRecall(t *testing.T) {
+	h := hashEmbedder{dims: 512}
+
+	full := NewInMemoryStore(nil)
+	populate(t, full, h, 500)
+
+	reduced := NewInMemoryStore(nil)
+	if err := reduced.SetDimensionReduction(64); err != nil {
+		t.Fatalf("SetDimensionReduction() error = %v", err)
+	}
+	populate(t, reduced, h, 500)
+
+	entry, _ := reduced.Get(context.Background(), "doc-0")
+	if len(entry.Vector) != 64 {
+		t.Fatalf("stored vector has %d dimensions, want 64", len(entry.Vector))
+	}
+
+	fullRecall := recallAt(t, full, h, 100, 5)
+	reducedRecall := recallAt(t, reduced, h, 100, 5)
+	t.Logf("recall@5: full %.2f, reduced (64-d) %.2f", fullRecall, reducedRecall)
+
+	if fullRecall < 0.99 {
+		t.Errorf("full-dimension recall = %.2f, want ~1", fullRecall)
+	}
+	if reducedRecall < 0.9 {
+		t.Errorf("reduced recall = %.2f, want at least 0.9", reducedRecall)
+	}
+}
+
+func TestDimensionReductionPersistsProjection(t *testing.T) {
+	h := hashEmbedder{dims: 128}
+	path := filepath.Join(t.TempDir(), "vectors.json")
+
+	store := NewInMemoryStore(nil)
+	store.SetDimensionReduction(16)
+	populate(t, store, h, 20)
+	if err := store.Save(context.Background(), path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A store configured without reduction reuses the saved projection
+	loaded := NewInMemoryStore(nil)
+	if err := loaded.Load(context.Background(), path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p := loaded.Projection(); p == nil || p.InputDim != 128 || p.OutputDim != 16 {
+		t.Fatalf("loaded projection = %+v, want 128 -> 16", p)
+	}
+
+	query := h.perturbed(7, 0.1)
+	before, _ := store.Search(context.Background(), query, 1)
+	after, err := loaded.Search(context.Background(), query, 1)
+	if err != nil {
+		t.Fatalf("Search() after reload error = %v", err)
+	}
+	if after[0].ID != before[0].ID || after[0].Score != before[0].Score {
+		t.Errorf("after reload top result = %s (%v), want %s (%v)", after[0].ID, after[0].Score, before[0].ID, before[0].Score)
+	}
+}
+
+func TestSetDimensionReductionRejectsPopulatedStore(t *testing.T) {
+	store := NewInMemoryStore(nil)
+	populate(t, store, hashEmbedder{dims: 32}, 1)
+
+	if err := store.SetDimensionReduction(8); err == nil {
+		t.Error("expected error enabling reduction on a populated store")
+	}
+}
+
+func benchmarkSearch(b *testing.B, reduceTo int) {
+	h := hashEmbedder{dims: 1536}
+	store := NewInMemoryStore(nil)
+	store.SetDimensionReduction(reduceTo)
+	populate(b, store, h, 1000)
+	query := h.perturbed(1, 0.8)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.Search(context.Background(), query, 10)
+	}
+}
+
+func BenchmarkSearchFullDimensions(b *testing.B)    { benchmarkSearch(b, 0) }
+func BenchmarkSearchReducedDimensions(b *testing.B) { benchmarkSearch(b, 128) }
+
+// benchmarkNormalizedSearch compares the cosine and dot product paths on a
+// store of 10k entries
+func benchmarkNormalizedSearch(b *testing.B, normalized bool) {
+	h := hashEmbedder{dims: 768}
+	store := NewInMemoryStore(nil)
+	populate(b, store, h, 10000)
+	query := h.perturbed(1, 0.8)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if normalized {
+			store.SearchNormalized(context.Background(), query, 10)
+		} else {
+			store.Search(context.Background(), query, 10)
+		}
+	}
+}
+
+func BenchmarkSearchCosine10k(b *testing.B)     { benchmarkNormalizedSearch(b, false) }
+func BenchmarkSearchNormalized10k(b *testing.B) { benchmarkNormalizedSearch(b, true) }
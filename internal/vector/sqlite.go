@@ -0,0 +1,294 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // Pure Go driver, so the build needs no CGO
+)
+
+// sqliteSchema creates the table of a SQLite vector store
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS vectors (
+	id        TEXT PRIMARY KEY,
+	content   TEXT NOT NULL,
+	timestamp INTEGER NOT NULL,
+	tags      TEXT NOT NULL,
+	custom    TEXT NOT NULL,
+	vector    BLOB NOT NULL
+)`
+
+// SQLiteStore is a vector store kept in a SQLite database, so its entries
+// survive restarts without explicit saves. Search loads the vectors and
+// ranks them in Go, which suits stores of up to some tens of thousands of
+// entries.
+type SQLiteStore struct {
+	db       *sql.DB
+	embedder Embedder
+}
+
+// NewSQLiteStore opens the SQLite vector store at dbPath, creating the
+// database and its directory when missing. An empty dbPath gives an
+// in-memory store instead.
+func NewSQLiteStore(embedder Embedder, dbPath string) (VectorStore, error) {
+	if dbPath == "" {
+		return NewInMemoryStore(embedder), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector database %s: %w", dbPath, err)
+	}
+	// A single connection serializes writes, which SQLite would otherwise
+	// reject with "database is locked"
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open vector database %s: %w", dbPath, err)
+	}
+
+	return &SQLiteStore{db: db, embedder: embedder}, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Add adds a new vector to the store, replacing the entry with the same ID
+func (s *SQLiteStore) Add(ctx context.Context, vector []float32, metadata MemoryMetadata) (string, error) {
+	if metadata.ID == "" {
+		count, err := s.Count(ctx)
+		if err != nil {
+			return "", err
+		}
+		metadata.ID = fmt.Sprintf("vec_%d_%d", count, now())
+	}
+
+	if err := insertEntry(ctx, s.db, vector, metadata); err != nil {
+		return "", fmt.Errorf("failed to add vector: %w", err)
+	}
+	return metadata.ID, nil
+}
+
+// execer runs statements on the database or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertEntry writes an entry, replacing the one with the same ID
+func insertEntry(ctx context.Context, db execer, vector []float32, metadata MemoryMetadata) error {
+	tags, err := json.Marshal(metadata.Tags)
+	if err != nil {
+		return err
+	}
+	custom, err := json.Marshal(metadata.Custom)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO vectors (id, content, timestamp, tags, custom, vector) VALUES (?, ?, ?, ?, ?, ?)`,
+		metadata.ID, metadata.Content, metadata.Timestamp, string(tags), string(custom), encodeVector(vector))
+	return err
+}
+
+// AddWithEmbedding adds text and automatically generates embedding
+func (s *SQLiteStore) AddWithEmbedding(ctx context.Context, content string, tags []string, custom map[string]string) (string, error) {
+	var vector []float32
+	if s.embedder != nil {
+		emb, err := s.embedder.Embed(ctx, content)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate embedding: %w", err)
+		}
+		vector = emb
+	}
+
+	return s.Add(ctx, vector, MemoryMetadata{
+		Content:   content,
+		Timestamp: now(),
+		Tags:      tags,
+		Custom:    custom,
+	})
+}
+
+// Search finds the most similar vectors, like InMemoryStore.Search
+func (s *SQLiteStore) Search(ctx context.Context, query []float32, limit int) ([]SearchResult, error) {
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter ranks by similarity only the entries whose metadata
+// filter accepts; a nil filter accepts every entry
+func (s *SQLiteStore) SearchWithFilter(ctx context.Context, query []float32, limit int, filter func(MemoryMetadata) bool) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	entries, err := s.query(ctx, `SELECT id, content, timestamp, tags, custom, vector FROM vectors`)
+	if err != nil {
+		return nil, err
+	}
+	return rankEntries(query, entries, limit, filter, Similarity)
+}
+
+// SearchByTags ranks by similarity only the entries carrying all of tags
+func (s *SQLiteStore) SearchByTags(ctx context.Context, query []float32, limit int, tags []string) ([]SearchResult, error) {
+	return s.SearchWithFilter(ctx, query, limit, func(metadata MemoryMetadata) bool {
+		return HasTags(metadata, tags)
+	})
+}
+
+// Get retrieves a vector by ID
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*VectorEntry, error) {
+	entries, err := s.query(ctx, `SELECT id, content, timestamp, tags, custom, vector FROM vectors WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("vector not found: %s", id)
+	}
+	return entries[0], nil
+}
+
+// Delete removes a vector from the store
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM vectors WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete vector: %w", err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return fmt.Errorf("vector not found: %s", id)
+	}
+	return nil
+}
+
+// List returns vectors in insertion order with pagination
+func (s *SQLiteStore) List(ctx context.Context, limit, offset int) ([]VectorEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	entries, err := s.query(ctx, `SELECT id, content, timestamp, tags, custom, vector FROM vectors ORDER BY rowid LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]VectorEntry, len(entries))
+	for i, entry := range entries {
+		list[i] = *entry
+	}
+	return list, nil
+}
+
+// Count returns the number of vectors in the store
+func (s *SQLiteStore) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM vectors`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count vectors: %w", err)
+	}
+	return count, nil
+}
+
+// Save exports the store to a JSON file in the format of InMemoryStore.Save.
+// The database itself needs no saving.
+func (s *SQLiteStore) Save(ctx context.Context, path string) error {
+	entries, err := s.query(ctx, `SELECT id, content, timestamp, tags, custom, vector FROM vectors`)
+	if err != nil {
+		return err
+	}
+
+	snapshot := NewInMemoryStore(nil)
+	for _, entry := range entries {
+		snapshot.vectors[entry.Metadata.ID] = entry
+	}
+	return snapshot.Save(ctx, path)
+}
+
+// Load imports the entries of a JSON file written by Save or
+// InMemoryStore.Save, replacing entries with the same IDs
+func (s *SQLiteStore) Load(ctx context.Context, path string) error {
+	snapshot := NewInMemoryStore(nil)
+	if err := snapshot.Load(ctx, path); err != nil {
+		return err
+	}
+	if snapshot.Projection() != nil {
+		return fmt.Errorf("cannot import %s: its vectors are dimension-reduced", path)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to import vectors: %w", err)
+	}
+	defer tx.Rollback()
+
+	for id, entry := range snapshot.vectors {
+		entry.Metadata.ID = id
+		if err := insertEntry(ctx, tx, entry.Vector, entry.Metadata); err != nil {
+			return fmt.Errorf("failed to import vector %s: %w", id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// query returns the entries of the rows a vectors query selects
+func (s *SQLiteStore) query(ctx context.Context, query string, args ...interface{}) ([]*VectorEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*VectorEntry
+	for rows.Next() {
+		var (
+			entry        VectorEntry
+			tags, custom string
+			blob         []byte
+		)
+		if err := rows.Scan(&entry.Metadata.ID, &entry.Metadata.Content, &entry.Metadata.Timestamp, &tags, &custom, &blob); err != nil {
+			return nil, fmt.Errorf("failed to read vector: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tags), &entry.Metadata.Tags); err != nil {
+			return nil, fmt.Errorf("failed to read tags of %s: %w", entry.Metadata.ID, err)
+		}
+		if err := json.Unmarshal([]byte(custom), &entry.Metadata.Custom); err != nil {
+			return nil, fmt.Errorf("failed to read custom metadata of %s: %w", entry.Metadata.ID, err)
+		}
+		if entry.Vector, err = decodeVector(blob); err != nil {
+			return nil, fmt.Errorf("failed to read vector %s: %w", entry.Metadata.ID, err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query vectors: %w", err)
+	}
+	return entries, nil
+}
+
+// encodeVector packs a vector as little-endian float32s
+func encodeVector(vector []float32) []byte {
+	blob := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(blob[4*i:], math.Float32bits(v))
+	}
+	return blob
+}
+
+// decodeVector unpacks a vector packed by encodeVector
+func decodeVector(blob []byte) ([]float32, error) {
+	if len(blob)%4 != 0 {
+		return nil, errors.New("blob length is not a multiple of 4")
+	}
+	vector := make([]float32, len(blob)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[4*i:]))
+	}
+	return vector, nil
+}
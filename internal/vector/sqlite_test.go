@@ -0,0 +1,138 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openSQLiteStore(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(nil, path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	sqlite, ok := store.(*SQLiteStore)
+	if !ok {
+		t.Fatalf("NewSQLiteStore() = %T, want *SQLiteStore", store)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return sqlite
+}
+
+func TestSQLiteStore_SurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db", "vectors.db")
+
+	store := openSQLiteStore(t, path)
+	if _, err := store.Add(ctx, []float32{1, 0, 0}, MemoryMetadata{ID: "x", Content: "along x", Timestamp: 42, Tags: []string{"axis"}, Custom: map[string]string{"source": "test"}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := store.Add(ctx, []float32{0, 1, 0}, MemoryMetadata{ID: "y", Content: "along y"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	id, err := store.Add(ctx, []float32{0.9, 0.1, 0}, MemoryMetadata{Content: "mostly x", Tags: []string{"axis"}})
+	if err != nil || id == "" {
+		t.Fatalf("Add() = %q, %v; want a generated ID", id, err)
+	}
+	store.Close()
+
+	reopened := openSQLiteStore(t, path)
+	if count, err := reopened.Count(ctx); err != nil || count != 3 {
+		t.Fatalf("Count() after reopening = %d, %v; want 3", count, err)
+	}
+
+	entry, err := reopened.Get(ctx, "x")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if entry.Metadata.Content != "along x" || entry.Metadata.Timestamp != 42 || entry.Metadata.Custom["source"] != "test" || len(entry.Vector) != 3 || entry.Vector[0] != 1 {
+		t.Errorf("Get() = %+v, want the stored entry", entry)
+	}
+
+	results, err := reopened.Search(ctx, []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "x" || results[1].ID != id {
+		t.Errorf("Search() = %+v, want x then %s", results, id)
+	}
+
+	results, err = reopened.SearchByTags(ctx, []float32{0, 1, 0}, 10, []string{"axis"})
+	if err != nil || len(results) != 2 {
+		t.Errorf("SearchByTags() = %+v, %v; want the two axis entries", results, err)
+	}
+
+	var mismatch *DimensionMismatchError
+	if _, err := reopened.Search(ctx, []float32{1, 0}, 2); !errors.As(err, &mismatch) {
+		t.Errorf("Search() with 2 dimensions error = %v, want DimensionMismatchError", err)
+	}
+}
+
+func TestSQLiteStore_DeleteListAndExport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := openSQLiteStore(t, filepath.Join(dir, "vectors.db"))
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := store.Add(ctx, []float32{1, 2}, MemoryMetadata{ID: id, Content: id}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, "b"); err == nil {
+		t.Error("Delete() of a missing vector succeeded")
+	}
+	if _, err := store.Get(ctx, "b"); err == nil {
+		t.Error("Get() found a deleted vector")
+	}
+
+	list, err := store.List(ctx, 1, 1)
+	if err != nil || len(list) != 1 || list[0].Metadata.ID != "c" {
+		t.Errorf("List(1, 1) = %+v, %v; want c", list, err)
+	}
+
+	// Save and Load exchange the JSON format of the in-memory store
+	exported := filepath.Join(dir, "vectors.json")
+	if err := store.Save(ctx, exported); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	memory := NewInMemoryStore(nil)
+	if err := memory.Load(ctx, exported); err != nil {
+		t.Fatalf("InMemoryStore.Load() error = %v", err)
+	}
+	if count, _ := memory.Count(ctx); count != 2 {
+		t.Errorf("exported %d vectors, want 2", count)
+	}
+
+	imported := openSQLiteStore(t, filepath.Join(dir, "imported.db"))
+	if err := imported.Load(ctx, exported); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if entry, err := imported.Get(ctx, "c"); err != nil || entry.Metadata.Content != "c" {
+		t.Errorf("Get() after import = %+v, %v", entry, err)
+	}
+}
+
+func TestNewSQLiteStore_PathHandling(t *testing.T) {
+	store, err := NewSQLiteStore(nil, "")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore(\"\") error = %v", err)
+	}
+	if _, ok := store.(*InMemoryStore); !ok {
+		t.Errorf("NewSQLiteStore(\"\") = %T, want *InMemoryStore", store)
+	}
+
+	// A directory can't be opened as a database
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "vectors.db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSQLiteStore(nil, filepath.Join(dir, "vectors.db")); err == nil {
+		t.Error("NewSQLiteStore() opened a directory")
+	}
+}
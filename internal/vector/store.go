@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
@@ -39,11 +40,81 @@ type VectorStore interface {
 	Load(ctx context.Context, path string) error
 }
 
+// DimensionMismatchError is returned by Search when no stored vector has the
+// query's dimension, usually because the embedding model changed since the
+// vectors were stored
+type DimensionMismatchError struct {
+	Query  int   // Dimension of the query vector
+	Stored []int // Dimensions found in the store
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("query has %d dimensions but stored vectors have %v; reindex the store with the current embedding model", e.Query, e.Stored)
+}
+
 // InMemoryStore is a simple in-memory vector store
 type InMemoryStore struct {
 	mu       sync.RWMutex
 	vectors  map[string]*VectorEntry
 	embedder Embedder
+
+	reduceTo   int         // Target dimensions, 0 stores vectors as given
+	projection *Projection // Created from the first vector added when reducing
+
+	dirty bool // Changed since the last autosave
+}
+
+// projectionSeed seeds new projections; the matrix itself is persisted
+const projectionSeed = 1
+
+// SetDimensionReduction reduces vectors to the given number of dimensions at
+// insert time, projecting queries the same way. Fewer dimensions mean less
+// storage and faster search at some cost in recall. 0 disables reduction.
+// It must be set before vectors are added.
+func (s *InMemoryStore) SetDimensionReduction(dims int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dims < 0 {
+		return fmt.Errorf("reduced dimensions must not be negative: %d", dims)
+	}
+	if len(s.vectors) > 0 && dims != s.reduceTo {
+		return fmt.Errorf("cannot change dimension reduction of a store with %d vectors", len(s.vectors))
+	}
+
+	s.reduceTo = dims
+	if dims == 0 {
+		s.projection = nil
+	}
+	return nil
+}
+
+// Projection returns the projection used to reduce vectors, or nil
+func (s *InMemoryStore) Projection() *Projection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.projection
+}
+
+// reduce projects a vector when dimension reduction is enabled, creating the
+// projection from the first vector's dimensions. The caller holds the write lock.
+func (s *InMemoryStore) reduce(vector []float32) ([]float32, error) {
+	if s.reduceTo == 0 || len(vector) == 0 {
+		return vector, nil
+	}
+
+	if s.projection == nil {
+		if len(vector) <= s.reduceTo {
+			return vector, nil
+		}
+		projection, err := NewRandomProjection(len(vector), s.reduceTo, projectionSeed)
+		if err != nil {
+			return nil, err
+		}
+		s.projection = projection
+	}
+
+	return s.projection.Apply(vector)
 }
 
 // SearchResult represents a search match
@@ -62,7 +133,8 @@ func NewInMemoryStore(embedder Embedder) *InMemoryStore {
 	}
 }
 
-// Add adds a new vector to the store
+// Add adds a new vector to the store. Vectors are stored at unit length, so
+// SearchNormalized can rank by dot product.
 func (s *InMemoryStore) Add(ctx context.Context, vector []float32, metadata MemoryMetadata) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -72,12 +144,18 @@ func (s *InMemoryStore) Add(ctx context.Context, vector []float32, metadata Memo
 		metadata.ID = fmt.Sprintf("vec_%d_%d", len(s.vectors), now())
 	}
 
+	vector, err := s.reduce(vector)
+	if err != nil {
+		return "", err
+	}
+
 	entry := &VectorEntry{
-		Vector:   vector,
+		Vector:   Normalize(vector),
 		Metadata: metadata,
 	}
 
 	s.vectors[metadata.ID] = entry
+	s.dirty = true
 	return metadata.ID, nil
 }
 
@@ -103,8 +181,32 @@ func (s *InMemoryStore) AddWithEmbedding(ctx context.Context, content string, ta
 	return s.Add(ctx, vector, metadata)
 }
 
-// Search finds the most similar vectors
+// Search finds the most similar vectors. Stored vectors whose dimension
+// differs from the query's are skipped; if none match, a
+// *DimensionMismatchError is returned instead of zero-scored results.
 func (s *InMemoryStore) Search(ctx context.Context, query []float32, limit int) ([]SearchResult, error) {
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter ranks by similarity only the entries whose metadata
+// filter accepts; a nil filter accepts every entry. No entry passing the
+// filter is an empty result, not an error.
+func (s *InMemoryStore) SearchWithFilter(ctx context.Context, query []float32, limit int, filter func(MemoryMetadata) bool) ([]SearchResult, error) {
+	return s.search(query, limit, filter, false)
+}
+
+// SearchNormalized is Search for stores whose vectors are all unit length,
+// as Add stores them: the query is normalized once and entries are scored by
+// dot product, which then equals cosine similarity, instead of computing
+// both norms per entry. Stores loaded from files saved before vectors were
+// normalized need NormalizeAll first.
+func (s *InMemoryStore) SearchNormalized(ctx context.Context, query []float32, limit int) ([]SearchResult, error) {
+	return s.search(query, limit, nil, true)
+}
+
+// search ranks the entries filter accepts by similarity to query, by dot
+// product with the normalized query when normalized is set
+func (s *InMemoryStore) search(query []float32, limit int, filter func(MemoryMetadata) bool, normalized bool) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -112,22 +214,92 @@ func (s *InMemoryStore) Search(ctx context.Context, query []float32, limit int)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	// Compare in the reduced space
+	if s.projection != nil {
+		reduced, err := s.projection.Apply(query)
+		if err != nil {
+			return nil, err
+		}
+		query = reduced
+	}
+	score := Similarity
+	if normalized {
+		query = Normalize(query)
+		score = DotProduct
+	}
+
+	candidates := make([]*VectorEntry, 0, len(s.vectors))
+	for id, entry := range s.vectors {
+		if entry.Metadata.ID != id {
+			// Results are reported under the key the entry is stored at
+			keyed := *entry
+			keyed.Metadata.ID = id
+			entry = &keyed
+		}
+		candidates = append(candidates, entry)
+	}
+	return rankEntries(query, candidates, limit, filter, score)
+}
+
+// NormalizeAll rescales stored vectors to unit length, migrating a store
+// loaded from a file saved before Add normalized them, and returns how many
+// it changed. Scores of Search are unaffected.
+func (s *InMemoryStore) NormalizeAll() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := 0
+	for _, entry := range s.vectors {
+		if norm := DotProduct(entry.Vector, entry.Vector); norm == 0 || math.Abs(float64(norm)-1) < unitTolerance {
+			continue
+		}
+		entry.Vector = Normalize(entry.Vector)
+		changed++
+	}
+	if changed > 0 {
+		s.dirty = true
+	}
+	return changed
+}
+
+// unitTolerance is how far from 1 the squared norm of a vector counted as
+// unit length may be, allowing for float32 rounding
+const unitTolerance = 1e-4
+
+// rankEntries returns the limit entries scoring highest against query among
+// those filter accepts. Entries whose dimension differs from the query's are
+// skipped; if none match, a *DimensionMismatchError is returned.
+func rankEntries(query []float32, entries []*VectorEntry, limit int, filter func(MemoryMetadata) bool, score func(a, b []float32) float32) ([]SearchResult, error) {
 	type scoredEntry struct {
-		id         string
 		entry      *VectorEntry
 		similarity float32
 	}
 
 	var results []scoredEntry
-	for id, entry := range s.vectors {
-		score := Similarity(query, entry.Vector)
+	mismatched := make(map[int]bool)
+	for _, entry := range entries {
+		if filter != nil && !filter(entry.Metadata) {
+			continue
+		}
+		if len(entry.Vector) != len(query) {
+			mismatched[len(entry.Vector)] = true
+			continue
+		}
 		results = append(results, scoredEntry{
-			id:         id,
 			entry:      entry,
-			similarity: score,
+			similarity: score(query, entry.Vector),
 		})
 	}
 
+	if len(results) == 0 && len(mismatched) > 0 {
+		stored := make([]int, 0, len(mismatched))
+		for dims := range mismatched {
+			stored = append(stored, dims)
+		}
+		sort.Ints(stored)
+		return nil, &DimensionMismatchError{Query: len(query), Stored: stored}
+	}
+
 	// Sort by similarity (highest first)
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].similarity > results[j].similarity
@@ -142,7 +314,7 @@ func (s *InMemoryStore) Search(ctx context.Context, query []float32, limit int)
 	searchResults := make([]SearchResult, len(results))
 	for i, r := range results {
 		searchResults[i] = SearchResult{
-			ID:       r.id,
+			ID:       r.entry.Metadata.ID,
 			Score:    r.similarity,
 			Content:  r.entry.Metadata.Content,
 			Metadata: r.entry.Metadata,
@@ -152,6 +324,30 @@ func (s *InMemoryStore) Search(ctx context.Context, query []float32, limit int)
 	return searchResults, nil
 }
 
+// SearchByTags ranks by similarity only the entries carrying all of tags
+func (s *InMemoryStore) SearchByTags(ctx context.Context, query []float32, limit int, tags []string) ([]SearchResult, error) {
+	return s.SearchWithFilter(ctx, query, limit, func(metadata MemoryMetadata) bool {
+		return HasTags(metadata, tags)
+	})
+}
+
+// HasTags reports whether metadata carries every one of tags
+func HasTags(metadata MemoryMetadata, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, have := range metadata.Tags {
+			if have == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // SearchByText searches using text query (generates embedding automatically)
 func (s *InMemoryStore) SearchByText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
 	if s.embedder == nil {
@@ -189,6 +385,7 @@ func (s *InMemoryStore) Delete(ctx context.Context, id string) error {
 	}
 
 	delete(s.vectors, id)
+	s.dirty = true
 	return nil
 }
 
@@ -249,7 +446,16 @@ func (s *InMemoryStore) Save(ctx context.Context, path string) error {
 		}
 	}
 
-	data, err := json.MarshalIndent(serialized, "", "  ")
+	// Reduced stores keep the projection next to the vectors
+	var payload interface{} = serialized
+	if s.projection != nil {
+		payload = map[string]interface{}{
+			"projection": s.projection,
+			"vectors":    serialized,
+		}
+	}
+
+	data, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
@@ -272,14 +478,32 @@ func (s *InMemoryStore) Load(ctx context.Context, path string) error {
 		Metadata MemoryMetadata `json:"metadata"`
 	}
 
+	// Stores without a projection are a plain map of entries
+	var reduced struct {
+		Projection *Projection                `json:"projection"`
+		Vectors    map[string]SerializedEntry `json:"vectors"`
+	}
+	var projection *Projection
 	serialized := make(map[string]SerializedEntry)
-	if err := json.Unmarshal(data, &serialized); err != nil {
+	if err := json.Unmarshal(data, &reduced); err == nil && reduced.Projection != nil && reduced.Projection.OutputDim > 0 {
+		projection = reduced.Projection
+		serialized = reduced.Vectors
+	} else if err := json.Unmarshal(data, &serialized); err != nil {
 		return fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// The saved projection wins over the configured reduction; vectors saved
+	// at full size can't be mixed with reduced ones
+	s.projection = projection
+	if projection != nil {
+		s.reduceTo = projection.OutputDim
+	} else if len(serialized) > 0 {
+		s.reduceTo = 0
+	}
+
 	s.vectors = make(map[string]*VectorEntry)
 	for id, entry := range serialized {
 		s.vectors[id] = &VectorEntry{
@@ -287,6 +511,7 @@ func (s *InMemoryStore) Load(ctx context.Context, path string) error {
 			Metadata: entry.Metadata,
 		}
 	}
+	s.dirty = false
 
 	return nil
 }
@@ -295,11 +520,3 @@ func (s *InMemoryStore) Load(ctx context.Context, path string) error {
 func now() int64 {
 	return time.Now().Unix()
 }
-
-// NewSQLiteStore creates a new SQLite-based vector store
-// If SQLite build tag is not set, returns in-memory store
-func NewSQLiteStore(embedder Embedder, dbPath string) (VectorStore, error) {
-	// For now, return in-memory store as fallback
-	// Actual SQLite implementation would require CGO
-	return NewInMemoryStore(embedder), nil
-}
@@ -2,6 +2,10 @@ package vector
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -206,6 +210,136 @@ func TestInMemoryStore_SearchRelevance(t *testing.T) {
 	}
 }
 
+func TestInMemoryStore_DimensionMismatch(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(nil)
+	store.Add(ctx, []float32{1, 0, 0, 0}, MemoryMetadata{Content: "old model"})
+	store.Add(ctx, []float32{0, 1, 0, 0}, MemoryMetadata{Content: "old model, other"})
+	store.Add(ctx, []float32{1, 0}, MemoryMetadata{Content: "new model"})
+
+	// Vectors of another dimension are skipped
+	results, err := store.Search(ctx, []float32{1, 0}, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Content != "new model" {
+		t.Errorf("results = %+v, want only the matching dimension", results)
+	}
+
+	// With no vector of the query's dimension, the mismatch is reported
+	_, err = store.Search(ctx, []float32{1, 0, 0}, 10)
+	mismatch, ok := err.(*DimensionMismatchError)
+	if !ok {
+		t.Fatalf("Search() error = %v, want a DimensionMismatchError", err)
+	}
+	if mismatch.Query != 3 || len(mismatch.Stored) != 2 || mismatch.Stored[0] != 2 || mismatch.Stored[1] != 4 {
+		t.Errorf("error = %+v, want query 3 against stored [2 4]", mismatch)
+	}
+}
+
+func TestInMemoryStore_SearchNormalized(t *testing.T) {
+	ctx := context.Background()
+	h := hashEmbedder{dims: 64}
+	store := NewInMemoryStore(nil)
+	populate(t, store, h, 50)
+
+	entry, _ := store.Get(ctx, "doc-3")
+	if norm := DotProduct(entry.Vector, entry.Vector); math.Abs(float64(norm)-1) > unitTolerance {
+		t.Errorf("stored vector has squared norm %v, want unit length", norm)
+	}
+
+	// The dot product path ranks and scores like cosine similarity
+	query := h.perturbed(3, 0.5)
+	want, _ := store.Search(ctx, query, 5)
+	got, err := store.SearchNormalized(ctx, query, 5)
+	if err != nil {
+		t.Fatalf("SearchNormalized() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("SearchNormalized() returned %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || math.Abs(float64(got[i].Score-want[i].Score)) > 1e-4 {
+			t.Errorf("result %d = %s (%v), want %s (%v)", i, got[i].ID, got[i].Score, want[i].ID, want[i].Score)
+		}
+	}
+}
+
+func TestInMemoryStore_NormalizeAll(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(nil)
+	// As loaded from a file saved before vectors were normalized
+	store.vectors["old"] = &VectorEntry{Vector: []float32{3, 4}, Metadata: MemoryMetadata{ID: "old"}}
+	store.vectors["zero"] = &VectorEntry{Vector: []float32{0, 0}, Metadata: MemoryMetadata{ID: "zero"}}
+	store.Add(ctx, []float32{0, 2}, MemoryMetadata{ID: "new"})
+
+	if changed := store.NormalizeAll(); changed != 1 {
+		t.Errorf("NormalizeAll() = %d, want 1", changed)
+	}
+	if v := store.vectors["old"].Vector; math.Abs(float64(v[0])-0.6) > 1e-6 || math.Abs(float64(v[1])-0.8) > 1e-6 {
+		t.Errorf("normalized vector = %v, want [0.6 0.8]", v)
+	}
+	if changed := store.NormalizeAll(); changed != 0 {
+		t.Errorf("second NormalizeAll() = %d, want 0", changed)
+	}
+
+	results, err := store.SearchNormalized(ctx, []float32{6, 8}, 1)
+	if err != nil || len(results) != 1 || results[0].ID != "old" || math.Abs(float64(results[0].Score)-1) > 1e-6 {
+		t.Errorf("SearchNormalized() = %+v, %v; want old with score 1", results, err)
+	}
+}
+
+func TestInMemoryStore_SearchByTags(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(nil)
+	store.Add(ctx, []float32{1, 0}, MemoryMetadata{ID: "go-web", Tags: []string{"go", "web"}})
+	store.Add(ctx, []float32{0.9, 0.1}, MemoryMetadata{ID: "go-cli", Tags: []string{"go", "cli"}})
+	store.Add(ctx, []float32{0.5, 0.5}, MemoryMetadata{ID: "go-web-old", Tags: []string{"web", "go", "archived"}})
+	store.Add(ctx, []float32{1, 0}, MemoryMetadata{ID: "rust-web", Tags: []string{"rust", "web"}})
+
+	// Only entries with both tags, still ranked by similarity
+	results, err := store.SearchByTags(ctx, []float32{1, 0}, 10, []string{"go", "web"})
+	if err != nil {
+		t.Fatalf("SearchByTags failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "go-web" || results[1].ID != "go-web-old" {
+		t.Errorf("results = %+v, want go-web then go-web-old", results)
+	}
+
+	// No entry carries every tag
+	results, err = store.SearchByTags(ctx, []float32{1, 0}, 10, []string{"go", "rust"})
+	if err != nil {
+		t.Fatalf("SearchByTags failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("results = %+v, want none", results)
+	}
+}
+
+func TestInMemoryStore_SearchWithFilter(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(nil)
+	store.Add(ctx, []float32{1, 0}, MemoryMetadata{ID: "a", Custom: map[string]string{"session": "x"}})
+	store.Add(ctx, []float32{1, 0}, MemoryMetadata{ID: "b", Custom: map[string]string{"session": "y"}})
+	store.Add(ctx, []float32{1, 0, 0}, MemoryMetadata{ID: "c", Custom: map[string]string{"session": "z"}})
+
+	inSession := func(id string) func(MemoryMetadata) bool {
+		return func(metadata MemoryMetadata) bool { return metadata.Custom["session"] == id }
+	}
+
+	results, err := store.SearchWithFilter(ctx, []float32{1, 0}, 10, inSession("y"))
+	if err != nil || len(results) != 1 || results[0].ID != "b" {
+		t.Errorf("session y: results = %+v, err = %v; want only b", results, err)
+	}
+
+	// An empty subset is no results rather than an error, even when vectors
+	// outside it have another dimension
+	results, err = store.SearchWithFilter(ctx, []float32{1, 0}, 10, inSession("none"))
+	if err != nil || len(results) != 0 {
+		t.Errorf("unknown session: results = %+v, err = %v; want none", results, err)
+	}
+}
+
 func TestInMemoryStore_SaveLoad(t *testing.T) {
 	ctx := context.Background()
 	embedder := &MockEmbedder{}
@@ -320,3 +454,175 @@ func TestInMemoryStore_ConcurrentAccess(t *testing.T) {
 		t.Errorf("Expected at least 10 items, got %d", count)
 	}
 }
+
+// countingEmbedder is a MockEmbedder that counts Embed calls
+type countingEmbedder struct {
+	MockEmbedder
+	calls int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	c.calls++
+	return c.MockEmbedder.Embed(ctx, text)
+}
+
+func TestInMemoryStore_IndexFileOnce(t *testing.T) {
+	ctx := context.Background()
+	embedder := &countingEmbedder{}
+	store := NewInMemoryStore(embedder)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	if err := os.WriteFile(path, []byte("# Notes\nship on Friday"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	id, indexed, err := store.IndexFile(ctx, path)
+	if err != nil || !indexed {
+		t.Fatalf("first IndexFile() = %q, %v, %v; want a new entry", id, indexed, err)
+	}
+	again, indexed, err := store.IndexFile(ctx, path)
+	if err != nil || indexed || again != id {
+		t.Errorf("second IndexFile() = %q, %v, %v; want a no-op returning %q", again, indexed, err, id)
+	}
+
+	// The same content under another name is stored once too
+	copyPath := filepath.Join(dir, "copy.md")
+	os.WriteFile(copyPath, []byte("# Notes\nship on Friday"), 0644)
+	if copied, _, _ := store.IndexFile(ctx, copyPath); copied != id {
+		t.Errorf("IndexFile(copy) = %q, want the existing entry %q", copied, id)
+	}
+
+	if embedder.calls != 1 {
+		t.Errorf("embedder calls = %d, want 1", embedder.calls)
+	}
+	entry, _ := store.Get(ctx, id)
+	if entry.Metadata.Custom[ContentHashKey] != ContentHash([]byte("# Notes\nship on Friday")) || entry.Metadata.Custom[SourceKey] != "notes.md" {
+		t.Errorf("metadata = %+v, want the content hash and source", entry.Metadata.Custom)
+	}
+
+	// Changed content is indexed again
+	os.WriteFile(path, []byte("# Notes\nship on Monday"), 0644)
+	if _, indexed, _ := store.IndexFile(ctx, path); !indexed || embedder.calls != 2 {
+		t.Errorf("changed file indexed = %v with %d embedder calls, want a new entry", indexed, embedder.calls)
+	}
+}
+
+// failingEmbedder fails every call, like a rate-limited provider
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return nil, fmt.Errorf("429 too many requests")
+}
+
+func (failingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, fmt.Errorf("429 too many requests")
+}
+
+func (failingEmbedder) GetModelName() string {
+	return "cloud-embedder"
+}
+
+// wideEmbedder returns embeddings of another dimension than MockEmbedder
+type wideEmbedder struct{}
+
+func (wideEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return make([]float32, 8), nil
+}
+
+func (wideEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = make([]float32, 8)
+	}
+	return vectors, nil
+}
+
+func (wideEmbedder) GetModelName() string {
+	return "wide-embedder"
+}
+
+func TestEmbedderChain_FallsBackOnFailure(t *testing.T) {
+	ctx := context.Background()
+	chain := NewEmbedderChain(failingEmbedder{}, &MockEmbedder{})
+
+	vec, err := chain.Embed(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	want, _ := (&MockEmbedder{}).Embed(ctx, "hello")
+	if Similarity(vec, want) < 0.999 {
+		t.Errorf("Embed() = %v, want the fallback's embedding %v", vec, want)
+	}
+	if served := chain.LastServed(); served != "mock-embedder" {
+		t.Errorf("LastServed() = %q, want mock-embedder", served)
+	}
+
+	if _, err := chain.EmbedBatch(ctx, []string{"a", "b"}); err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if served := chain.Served(); served["mock-embedder"] != 2 || served["cloud-embedder"] != 0 {
+		t.Errorf("Served() = %v, want both calls served by the fallback", served)
+	}
+	if chain.Dimension() != 4 {
+		t.Errorf("Dimension() = %d, want 4", chain.Dimension())
+	}
+}
+
+func TestEmbedderChain_SkipsOtherDimensions(t *testing.T) {
+	ctx := context.Background()
+	primary := &switchableEmbedder{}
+	chain := NewEmbedderChain(primary, wideEmbedder{})
+
+	if _, err := chain.Embed(ctx, "first"); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	// With the primary down, the fallback's 8-dimension vectors would be
+	// incomparable with the stored 4-dimension ones
+	primary.down = true
+	if _, err := chain.Embed(ctx, "second"); err == nil {
+		t.Error("Embed() succeeded with a fallback of another dimension, want an error")
+	}
+}
+
+// switchableEmbedder is a MockEmbedder that can be taken down
+type switchableEmbedder struct {
+	MockEmbedder
+	down bool
+}
+
+func (s *switchableEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if s.down {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return s.MockEmbedder.Embed(ctx, text)
+}
+
+func TestInMemoryStore_AutosaveSkipsUnchangedAndFlushesOnCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	store := NewInMemoryStore(&MockEmbedder{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := store.StartAutosave(ctx, path, 10*time.Millisecond)
+
+	// Nothing was added, so ticks don't write the file
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no file for an unchanged store, got %v", err)
+	}
+
+	if _, err := store.AddWithEmbedding(context.Background(), "saved before shutdown", nil, nil); err != nil {
+		t.Fatalf("AddWithEmbedding: %v", err)
+	}
+	cancel()
+	<-done
+
+	restored := NewInMemoryStore(&MockEmbedder{})
+	if err := restored.Load(context.Background(), path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if count, _ := restored.Count(context.Background()); count != 1 {
+		t.Errorf("restored %d vectors, want 1", count)
+	}
+}
@@ -0,0 +1,276 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxAttachmentSize is the maximum size of an attachment, decoded for inline
+// (base64) data and as downloaded for URLs
+const MaxAttachmentSize = 5 * 1024 * 1024
+
+// maxAttachmentRedirects is how many redirects a URL attachment may follow
+const maxAttachmentRedirects = 5
+
+// ErrPrivateAddress is returned when a URL attachment would be fetched from an
+// address that is not publicly routable
+var ErrPrivateAddress = errors.New("attachment url resolves to a non-public address")
+
+// Attachment types
+const (
+	AttachmentImage = "image"
+	AttachmentFile  = "file"
+)
+
+// allowedImageTypes lists the MIME types accepted for image attachments
+var allowedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Attachment represents an image or file attached to a message.
+// Exactly one of URL or Data (base64) must be set.
+type Attachment struct {
+	Type     string `json:"type"` // "image" or "file"
+	URL      string `json:"url,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Name     string `json:"name,omitempty"`
+}
+
+// Validate checks the attachment type, source and size
+func (a Attachment) Validate() error {
+	if a.Type != AttachmentImage && a.Type != AttachmentFile {
+		return fmt.Errorf("unsupported attachment type: %q", a.Type)
+	}
+
+	if (a.URL == "") == (a.Data == "") {
+		return fmt.Errorf("attachment must have exactly one of url or data")
+	}
+
+	if a.URL != "" {
+		if !strings.HasPrefix(a.URL, "http://") && !strings.HasPrefix(a.URL, "https://") {
+			return fmt.Errorf("attachment url must be http or https: %q", a.URL)
+		}
+		return nil
+	}
+
+	if base64.StdEncoding.DecodedLen(len(a.Data)) > MaxAttachmentSize {
+		return fmt.Errorf("attachment exceeds maximum size of %d bytes", MaxAttachmentSize)
+	}
+	if _, err := base64.StdEncoding.DecodeString(a.Data); err != nil {
+		return fmt.Errorf("attachment data is not valid base64: %w", err)
+	}
+
+	if a.Type == AttachmentImage {
+		if a.MimeType == "" {
+			return fmt.Errorf("inline image attachments require a mimeType")
+		}
+		if !allowedImageTypes[a.MimeType] {
+			return fmt.Errorf("unsupported image type: %q", a.MimeType)
+		}
+	}
+
+	return nil
+}
+
+// CheckRemote downloads a URL attachment and rejects it when it is larger than
+// MaxAttachmentSize or, for images, not an allowed image type. Inline
+// attachments are left to Validate. The URL comes from the user, so client
+// should be one from NewAttachmentClient.
+func (a Attachment) CheckRemote(ctx context.Context, client *http.Client) error {
+	if a.URL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid attachment url: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch attachment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch attachment: %s", resp.Status)
+	}
+	if resp.ContentLength > MaxAttachmentSize {
+		return fmt.Errorf("attachment exceeds maximum size of %d bytes", MaxAttachmentSize)
+	}
+
+	// Content-Length may be missing or wrong, so count what is actually sent
+	size, err := io.Copy(io.Discard, io.LimitReader(resp.Body, MaxAttachmentSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to fetch attachment: %w", err)
+	}
+	if size > MaxAttachmentSize {
+		return fmt.Errorf("attachment exceeds maximum size of %d bytes", MaxAttachmentSize)
+	}
+
+	if a.Type == AttachmentImage {
+		mimeType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+		if !allowedImageTypes[mimeType] {
+			return fmt.Errorf("unsupported image type: %q", mimeType)
+		}
+	}
+
+	return nil
+}
+
+// NewAttachmentClient returns an HTTP client for CheckRemote that only connects
+// to public addresses. The address is checked as it is dialed, after DNS
+// resolution and for every redirect, so neither a host resolving to an
+// internal address nor a redirect to one reaches internal services.
+func NewAttachmentClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkPublicAddress(address)
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		// No proxy: the dialed address must be the attachment's own
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxAttachmentRedirects {
+				return fmt.Errorf("attachment url redirected more than %d times", maxAttachmentRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("attachment url must be http or https: %q", req.URL.String())
+			}
+			return nil
+		},
+	}
+}
+
+// checkPublicAddress rejects a dialed host:port whose IP is not public
+func checkPublicAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// isPublicIP reports whether ip is neither private, loopback, link-local,
+// multicast nor unspecified
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// Reference returns a short reference for storing the attachment in message metadata.
+// Inline data is not stored, only a description of it.
+func (a Attachment) Reference() string {
+	if a.URL != "" {
+		return a.URL
+	}
+
+	name := a.Name
+	if name == "" {
+		name = a.Type
+	}
+	return fmt.Sprintf("inline:%s (%s, %d bytes)", name, a.MimeType, base64.StdEncoding.DecodedLen(len(a.Data)))
+}
+
+// imageURL returns the URL to send in an image content part
+func (a Attachment) imageURL() string {
+	if a.URL != "" {
+		return a.URL
+	}
+	return "data:" + a.MimeType + ";base64," + a.Data
+}
+
+// VisionClient is implemented by clients that can accept image attachments
+type VisionClient interface {
+	SupportsVision() bool
+}
+
+// contentPart is a single part of an OpenAI-style multimodal content array
+type contentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *imageURLPart `json:"image_url,omitempty"`
+}
+
+type imageURLPart struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON encodes the message, using an OpenAI-style content array when
+// the message carries attachments
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Attachments) == 0 {
+		return json.Marshal(struct {
+			Role       string     `json:"role"`
+			Content    string     `json:"content"`
+			ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+			ToolCallID string     `json:"tool_call_id,omitempty"`
+		}{m.Role, m.Content, m.ToolCalls, m.ToolCallID})
+	}
+
+	parts := []contentPart{{Type: "text", Text: m.Content}}
+	for _, attachment := range m.Attachments {
+		parts = append(parts, contentPart{
+			Type:     "image_url",
+			ImageURL: &imageURLPart{URL: attachment.imageURL()},
+		})
+	}
+
+	return json.Marshal(struct {
+		Role    string        `json:"role"`
+		Content []contentPart `json:"content"`
+	}{m.Role, parts})
+}
+
+// prepareMessages adapts attachments to what the provider supports. Images are
+// kept as content parts for vision-capable providers; everything else is
+// described in the message text.
+func prepareMessages(messages []Message, vision bool) []Message {
+	prepared := make([]Message, len(messages))
+	for i, msg := range messages {
+		prepared[i] = msg
+		if len(msg.Attachments) == 0 {
+			continue
+		}
+
+		var kept []Attachment
+		var notes []string
+		for _, attachment := range msg.Attachments {
+			if vision && attachment.Type == AttachmentImage {
+				kept = append(kept, attachment)
+				continue
+			}
+			notes = append(notes, fmt.Sprintf("[Attached %s: %s]", attachment.Type, attachment.Reference()))
+		}
+
+		if len(notes) > 0 {
+			prepared[i].Content = msg.Content + "\n\n" + strings.Join(notes, "\n")
+		}
+		prepared[i].Attachments = kept
+	}
+
+	return prepared
+}
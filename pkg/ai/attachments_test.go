@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachmentValidate(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("fake png bytes"))
+
+	tests := []struct {
+		name       string
+		attachment Attachment
+		wantErr    bool
+	}{
+		{"image url", Attachment{Type: "image", URL: "https://example.com/cat.png"}, false},
+		{"inline image", Attachment{Type: "image", Data: png, MimeType: "image/png"}, false},
+		{"inline file", Attachment{Type: "file", Data: png, Name: "notes.txt"}, false},
+		{"unknown type", Attachment{Type: "video", URL: "https://example.com/a.mp4"}, true},
+		{"no source", Attachment{Type: "image"}, true},
+		{"both sources", Attachment{Type: "image", URL: "https://example.com/a.png", Data: png, MimeType: "image/png"}, true},
+		{"bad scheme", Attachment{Type: "image", URL: "file:///etc/passwd"}, true},
+		{"bad base64", Attachment{Type: "image", Data: "not base64!", MimeType: "image/png"}, true},
+		{"unsupported image type", Attachment{Type: "image", Data: png, MimeType: "image/tiff"}, true},
+		{"too large", Attachment{Type: "file", Data: strings.Repeat("A", MaxAttachmentSize*2)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.attachment.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttachmentCheckRemote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("fake png bytes"))
+		case "/huge.png":
+			// Streamed without a Content-Length, so the size is only known by reading
+			w.Header().Set("Content-Type", "image/png")
+			chunk := make([]byte, 64*1024)
+			for written := 0; written <= MaxAttachmentSize; written += len(chunk) {
+				w.Write(chunk)
+				w.(http.Flusher).Flush()
+			}
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		attachment Attachment
+		wantErr    bool
+	}{
+		{"image", Attachment{Type: "image", URL: server.URL + "/cat.png"}, false},
+		{"html as file", Attachment{Type: "file", URL: server.URL + "/page.html"}, false},
+		{"too large", Attachment{Type: "image", URL: server.URL + "/huge.png"}, true},
+		{"html as image", Attachment{Type: "image", URL: server.URL + "/page.html"}, true},
+		{"missing", Attachment{Type: "file", URL: server.URL + "/missing"}, true},
+		{"inline", Attachment{Type: "file", Data: "aGk="}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.attachment.CheckRemote(context.Background(), server.Client())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttachmentClientRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("fake png bytes"))
+	}))
+	defer server.Close()
+
+	client := NewAttachmentClient(time.Second)
+	for _, url := range []string{
+		server.URL + "/cat.png",
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/cat.png",
+	} {
+		err := Attachment{Type: "image", URL: url}.CheckRemote(context.Background(), client)
+		if !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckRemote(%s) error = %v, want ErrPrivateAddress", url, err)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:10.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
+
+func TestMessageMarshalJSON(t *testing.T) {
+	plain, err := json.Marshal(Message{Role: "user", Content: "hi"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(plain) != `{"role":"user","content":"hi"}` {
+		t.Errorf("plain message = %s", plain)
+	}
+
+	withImage, err := json.Marshal(Message{
+		Role:        "user",
+		Content:     "what is this?",
+		Attachments: []Attachment{{Type: "image", URL: "https://example.com/cat.png"}},
+	})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}`
+	if string(withImage) != want {
+		t.Errorf("multimodal message = %s, want %s", withImage, want)
+	}
+}
+
+func TestOpenAICompatibleClientAttachments(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(createMockResponse("ok"))
+	}))
+	defer server.Close()
+
+	req := ChatCompletionRequest{
+		Messages: []Message{{
+			Role:        "user",
+			Content:     "describe",
+			Attachments: []Attachment{{Type: "image", URL: "https://example.com/cat.png"}},
+		}},
+	}
+
+	// Vision-capable: image is sent as a content part
+	client := NewOpenAICompatibleClient("key", server.URL, "qwen-vl")
+	client.Vision = true
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	content := body["messages"].([]interface{})[0].(map[string]interface{})["content"]
+	if parts, ok := content.([]interface{}); !ok || len(parts) != 2 {
+		t.Errorf("expected content array with 2 parts, got %v", content)
+	}
+
+	// Text-only: attachment is noted in the prompt text
+	client.Vision = false
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	content = body["messages"].([]interface{})[0].(map[string]interface{})["content"]
+	text, ok := content.(string)
+	if !ok || !strings.Contains(text, "[Attached image: https://example.com/cat.png]") {
+		t.Errorf("expected attachment note in text content, got %v", content)
+	}
+}
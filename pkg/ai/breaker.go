@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState represents the state of a provider circuit breaker
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Requests flow normally
+	BreakerOpen     BreakerState = "open"      // Requests fail fast until the cooldown elapses
+	BreakerHalfOpen BreakerState = "half-open" // A single probe request is allowed through
+)
+
+// ErrCircuitOpen is returned when a provider is skipped because its circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerConfig holds circuit breaker thresholds
+type BreakerConfig struct {
+	FailureThreshold int           // Failures within Window that open the circuit
+	Window           time.Duration // Sliding window for counting failures
+	Cooldown         time.Duration // How long the circuit stays open before probing
+}
+
+// DefaultBreakerConfig returns the default circuit breaker configuration
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		Window:           time.Minute,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// ParseBreakerConfig reads breaker thresholds from a config map such as
+// {"failureThreshold": 3, "window": "1m", "cooldown": "30s"}. Missing fields
+// keep their value from base.
+func ParseBreakerConfig(base BreakerConfig, values map[string]interface{}) (BreakerConfig, error) {
+	config := base
+
+	if raw, ok := values["failureThreshold"]; ok {
+		threshold, ok := raw.(float64)
+		if !ok || threshold < 1 || threshold != float64(int(threshold)) {
+			return config, fmt.Errorf("failureThreshold must be a positive integer, got %v", raw)
+		}
+		config.FailureThreshold = int(threshold)
+	}
+
+	for field, target := range map[string]*time.Duration{"window": &config.Window, "cooldown": &config.Cooldown} {
+		raw, ok := values[field]
+		if !ok {
+			continue
+		}
+		text, _ := raw.(string)
+		duration, err := time.ParseDuration(text)
+		if err != nil || duration <= 0 {
+			return config, fmt.Errorf("%s must be a positive duration such as \"30s\", got %v", field, raw)
+		}
+		*target = duration
+	}
+
+	return config, nil
+}
+
+// CircuitBreaker tracks failures for a single provider
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures []time.Time
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// NewCircuitBreaker creates a new circuit breaker in the closed state
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	defaults := DefaultBreakerConfig()
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaults.Cooldown
+	}
+
+	return &CircuitBreaker{
+		config: config,
+		state:  BreakerClosed,
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent to the provider.
+// When the cooldown has elapsed on an open circuit, exactly one probe is allowed.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.Cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the circuit and resets the failure count
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = nil
+	b.probing = false
+}
+
+// RecordFailure records a failed request, opening the circuit when the
+// threshold is reached or when a half-open probe fails
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	if b.state == BreakerHalfOpen {
+		b.trip(now)
+		return
+	}
+
+	// Drop failures that fell out of the window
+	cutoff := now.Add(-b.config.Window)
+	kept := b.failures[:0]
+	for _, t := range b.failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	b.failures = append(kept, now)
+
+	if len(b.failures) >= b.config.FailureThreshold {
+		b.trip(now)
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// trip opens the circuit; caller must hold the lock
+func (b *CircuitBreaker) trip(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.failures = nil
+	b.probing = false
+}
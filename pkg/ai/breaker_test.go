@@ -0,0 +1,178 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClient is a Client that counts calls and returns a fixed result
+type fakeClient struct {
+	calls int
+	err   error
+	reply string
+}
+
+func (f *fakeClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return createMockResponse(f.reply), nil
+}
+
+func (f *fakeClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	return nil, ErrStreamingUnsupported
+}
+
+func TestMultiProviderClientBreakerFailsFast(t *testing.T) {
+	failing := &fakeClient{err: errors.New("upstream timeout")}
+	healthy := &fakeClient{reply: "from qwen"}
+
+	client := NewMultiProviderClient()
+	client.SetBreakerConfig(BreakerConfig{
+		FailureThreshold: 3,
+		Window:           time.Minute,
+		Cooldown:         time.Hour,
+	})
+	client.AddProvider("zhipu", failing)
+	client.AddProvider("qwen", healthy)
+
+	req := ChatCompletionRequest{Model: "glm-4"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.ChatCompletion(context.Background(), req); err == nil {
+			t.Fatalf("call %d: expected error from failing provider", i)
+		}
+	}
+
+	if state := client.BreakerStates()["zhipu"]; state != BreakerOpen {
+		t.Fatalf("zhipu breaker state = %v, want %v", state, BreakerOpen)
+	}
+
+	// Subsequent calls should skip zhipu and go straight to the fallback
+	for i := 0; i < 5; i++ {
+		resp, err := client.ChatCompletion(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected fallback to succeed, got %v", err)
+		}
+		if resp.Choices[0].Message.Content != "from qwen" {
+			t.Errorf("unexpected response: %v", resp.Choices[0].Message.Content)
+		}
+	}
+
+	if failing.calls != 3 {
+		t.Errorf("failing provider called %d times, want 3", failing.calls)
+	}
+	if healthy.calls != 5 {
+		t.Errorf("healthy provider called %d times, want 5", healthy.calls)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 2,
+		Window:           time.Minute,
+		Cooldown:         10 * time.Second,
+	})
+	breaker.now = func() time.Time { return now }
+
+	breaker.RecordFailure()
+	breaker.RecordFailure()
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("state = %v, want open", breaker.State())
+	}
+	if breaker.Allow() {
+		t.Fatal("open breaker should not allow requests before cooldown")
+	}
+
+	now = now.Add(11 * time.Second)
+	if !breaker.Allow() {
+		t.Fatal("breaker should allow a probe after cooldown")
+	}
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("state = %v, want half-open", breaker.State())
+	}
+	if breaker.Allow() {
+		t.Error("only a single probe should be allowed while half-open")
+	}
+
+	// A failed probe re-opens the circuit
+	breaker.RecordFailure()
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("state = %v, want open after failed probe", breaker.State())
+	}
+
+	now = now.Add(11 * time.Second)
+	breaker.Allow()
+	breaker.RecordSuccess()
+	if breaker.State() != BreakerClosed {
+		t.Errorf("state = %v, want closed after successful probe", breaker.State())
+	}
+}
+
+func TestCircuitBreakerWindowExpiresFailures(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 2,
+		Window:           time.Minute,
+		Cooldown:         time.Minute,
+	})
+	breaker.now = func() time.Time { return now }
+
+	breaker.RecordFailure()
+	now = now.Add(2 * time.Minute)
+	breaker.RecordFailure()
+
+	if breaker.State() != BreakerClosed {
+		t.Errorf("state = %v, want closed when failures are outside the window", breaker.State())
+	}
+}
+
+func TestParseBreakerConfig(t *testing.T) {
+	base := BreakerConfig{FailureThreshold: 5, Window: time.Minute, Cooldown: 30 * time.Second}
+
+	config, err := ParseBreakerConfig(base, map[string]interface{}{
+		"failureThreshold": float64(2),
+		"cooldown":         "5s",
+	})
+	if err != nil {
+		t.Fatalf("ParseBreakerConfig() error = %v", err)
+	}
+	want := BreakerConfig{FailureThreshold: 2, Window: time.Minute, Cooldown: 5 * time.Second}
+	if config != want {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"failureThreshold": float64(0)},
+		{"failureThreshold": 1.5},
+		{"window": "soon"},
+		{"cooldown": float64(30)},
+	} {
+		if _, err := ParseBreakerConfig(base, bad); err == nil {
+			t.Errorf("ParseBreakerConfig(%v) should fail", bad)
+		}
+	}
+}
+
+func TestProviderBreakerConfigOverride(t *testing.T) {
+	flaky := &fakeClient{err: errors.New("boom")}
+	steady := &fakeClient{err: errors.New("boom")}
+
+	m := NewMultiProviderClient()
+	m.AddProvider("flaky", flaky)
+	m.AddProvider("steady", steady)
+	m.SetBreakerConfig(BreakerConfig{FailureThreshold: 3, Window: time.Minute, Cooldown: time.Minute})
+	m.SetProviderBreakerConfig("flaky", BreakerConfig{FailureThreshold: 1, Window: time.Minute, Cooldown: time.Minute})
+
+	m.breakers["flaky"].RecordFailure()
+	m.breakers["steady"].RecordFailure()
+
+	states := m.BreakerStates()
+	if states["flaky"] != BreakerOpen || states["steady"] != BreakerClosed {
+		t.Errorf("states = %v, want flaky open after one failure and steady still closed", states)
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,12 +18,38 @@ type ChatCompletionRequest struct {
 	Model    string    `json:"model"`
 	Messages []Message `json:"messages"`
 	Stream   bool      `json:"stream"`
+
+	// Sampling parameters; nil uses the provider default
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+
+	// Thinking is the reasoning level, one of the Thinking constants. Clients
+	// map it to their reasoning parameter, or describe it in the prompt
+	// when the model has none; empty uses the provider default.
+	Thinking        string `json:"-"`
+	ReasoningEffort string `json:"reasoning_effort,omitempty"` // Set from Thinking by clients of reasoning models
+
+	// Tools are the tools the model may call natively, see ToolCallFormatOf
+	Tools []ToolDefinition `json:"tools,omitempty"`
+
+	// Provider picks the provider of a MultiProviderClient by name, without
+	// falling back to others; empty routes by model. It isn't sent upstream.
+	Provider string `json:"-"`
 }
 
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"` // "user", "assistant", "system"
-	Content string `json:"content"`
+	Role        string       `json:"role"` // "user", "assistant", "system", "tool"
+	Content     string       `json:"content"`
+	Attachments []Attachment `json:"-"` // Encoded as content parts by MarshalJSON
+
+	// Native tool calls: the calls an assistant message makes in the OpenAI
+	// format, the call a "tool" message answers, and the content blocks of
+	// Anthropic messages that call tools or carry their results
+	ToolCalls  []ToolCall         `json:"tool_calls,omitempty"`
+	ToolCallID string             `json:"tool_call_id,omitempty"`
+	Blocks     []AnthropicContent `json:"-"`
 }
 
 // ChatCompletionResponse represents a response from a chat completion API
@@ -33,6 +60,12 @@ type ChatCompletionResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+
+	// Meta is what the provider reported about the response, nil for
+	// responses that didn't come from a provider
+	Meta *ResponseMeta `json:"-"`
 }
 
 // Choice represents a choice in the response
@@ -49,9 +82,25 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
-// Client interface for AI model providers
+// Add returns the sum of two usages. A missing total counts as the prompt
+// plus completion tokens, since some providers leave it out.
+func (u Usage) Add(other Usage) Usage {
+	total := other.TotalTokens
+	if total == 0 {
+		total = other.PromptTokens + other.CompletionTokens
+	}
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + total,
+	}
+}
+
+// Client interface for AI model providers. Clients that can't stream return
+// ErrStreamingUnsupported from ChatCompletionStream.
 type Client interface {
 	ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error)
+	ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error)
 }
 
 // ZhipuClient implements Client for Zhipu AI
@@ -60,6 +109,11 @@ type ZhipuClient struct {
 	BaseURL string
 	Model   string
 	Client  *http.Client
+
+	// AllowMockFallback answers with a canned reply instead of an error when
+	// the API is unreachable or fails, for demos without credentials
+	AllowMockFallback bool
+	Retry             RetryConfig // Retries of transient failures
 }
 
 // NewZhipuClient creates a new Zhipu AI client
@@ -79,6 +133,7 @@ func NewZhipuClient(apiKey, baseURL, model string) *ZhipuClient {
 		Client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		Retry: DefaultRetryConfig(),
 	}
 }
 
@@ -87,6 +142,8 @@ func (z *ZhipuClient) ChatCompletion(ctx context.Context, req ChatCompletionRequ
 	if req.Model == "" {
 		req.Model = z.Model
 	}
+	req = withThinkingInstruction(req)
+	req.Messages = prepareMessages(req.Messages, z.SupportsVision())
 
 	// Prepare the request body
 	requestBody, err := json.Marshal(req)
@@ -105,17 +162,24 @@ func (z *ZhipuClient) ChatCompletion(ctx context.Context, req ChatCompletionRequ
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Make the request
-	resp, err := z.Client.Do(httpReq)
+	resp, err := doWithRetry(ctx, z.Retry, resendable(z.Client, httpReq))
 	if err != nil {
-		// Return a mock response for demo purposes when API is not accessible
-		return createMockResponse("I'm the Zhipu AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		if z.AllowMockFallback {
+			// Return a mock response for demo purposes when API is not accessible
+			return createMockResponse("I'm the Zhipu AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// Return a mock response for demo purposes when API returns error
-		return createMockResponse("I'm the Zhipu AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		apiErr := newAPIError(resp)
+		if z.AllowMockFallback {
+			// Return a mock response for demo purposes when API returns error
+			return createMockResponse("I'm the Zhipu AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, apiErr
 	}
 
 	// Decode response
@@ -123,10 +187,16 @@ func (z *ZhipuClient) ChatCompletion(ctx context.Context, req ChatCompletionRequ
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	apiResp.Meta = newResponseMeta(resp, &apiResp, req.Model)
 
 	return &apiResp, nil
 }
 
+// SupportsVision reports whether the configured model accepts images (e.g. glm-4v)
+func (z *ZhipuClient) SupportsVision() bool {
+	return strings.Contains(strings.ToLower(z.Model), "4v")
+}
+
 // SendMessage sends a simple message and gets a response
 func (z *ZhipuClient) SendMessage(ctx context.Context, role, content string) (string, error) {
 	req := ChatCompletionRequest{
@@ -151,14 +221,27 @@ func (z *ZhipuClient) SendMessage(ctx context.Context, role, content string) (st
 
 // AnthropicMessage represents a chat message for Anthropic API
 type AnthropicMessage struct {
-	Role    string `json:"role"` // "user", "assistant"
-	Content string `json:"content"`
+	Role    string      `json:"role"`    // "user", "assistant"
+	Content interface{} `json:"content"` // The text, or []AnthropicContent blocks
 }
 
-// AnthropicContent represents content in Anthropic response
+// AnthropicContent represents a content block of an Anthropic message
 type AnthropicContent struct {
-	Type string `json:"type"` // Usually "text"
-	Text string `json:"text"`
+	Type string `json:"type"` // "text", "tool_use" or "tool_result"
+	Text string `json:"text,omitempty"`
+
+	ID        string          `json:"id,omitempty"`          // Of a tool_use block
+	Name      string          `json:"name,omitempty"`        // Tool a tool_use block calls
+	Input     json.RawMessage `json:"input,omitempty"`       // Arguments of a tool_use block
+	ToolUseID string          `json:"tool_use_id,omitempty"` // Call a tool_result block answers
+	Content   string          `json:"content,omitempty"`     // Result of a tool_result block
+}
+
+// AnthropicTool describes a tool in an Anthropic messages request
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
 }
 
 // AnthropicUsage represents token usage in Anthropic API
@@ -170,31 +253,60 @@ type AnthropicUsage struct {
 // AnthropicMessageRequest represents a request to an Anthropic-compatible API
 type AnthropicMessageRequest struct {
 	Model     string             `json:"model"`
+	System    string             `json:"system,omitempty"` // The system messages, which Anthropic takes outside the conversation
 	Messages  []AnthropicMessage `json:"messages"`
 	MaxTokens int                `json:"max_tokens"`
 	Stream    bool               `json:"stream"`
+	Thinking  *AnthropicThinking `json:"thinking,omitempty"`
+	Tools     []AnthropicTool    `json:"tools,omitempty"`
+}
+
+// AnthropicThinking enables extended thinking with a token budget
+type AnthropicThinking struct {
+	Type         string `json:"type"` // "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
 // AnthropicMessageResponse represents a response from an Anthropic-compatible API
 type AnthropicMessageResponse struct {
-	ID      string             `json:"id"`
-	Type    string             `json:"type"`
-	Role    string             `json:"role"`
-	Model   string             `json:"model"`
-	Content []AnthropicContent `json:"content"`
-	Usage   AnthropicUsage     `json:"usage"`
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	Role       string             `json:"role"`
+	Model      string             `json:"model"`
+	Content    []AnthropicContent `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Usage      AnthropicUsage     `json:"usage"`
 }
 
+// WireFormat is the request format an AnthropicCompatibleClient sends
+type WireFormat string
+
+const (
+	// WireFormatOpenAI posts OpenAI-style requests to {BaseURL}/chat/completions,
+	// which Minimax expects
+	WireFormatOpenAI WireFormat = "openai"
+	// WireFormatAnthropic posts Anthropic messages requests to {BaseURL}/v1/messages
+	WireFormatAnthropic WireFormat = "anthropic"
+)
+
+// anthropicVersion is the API version sent with Anthropic messages requests
+const anthropicVersion = "2023-06-01"
+
 // AnthropicCompatibleClient implements Client for Anthropic-compatible APIs like Minimax
 type AnthropicCompatibleClient struct {
 	ApiKey  string
 	BaseURL string
 	Model   string
+	Format  WireFormat // WireFormatOpenAI when empty
 	Client  *http.Client
+
+	AllowMockFallback bool        // See ZhipuClient.AllowMockFallback
+	Retry             RetryConfig // Retries of transient failures
 }
 
-// NewAnthropicCompatibleClient creates a new client for Anthropic-compatible APIs
-func NewAnthropicCompatibleClient(apiKey, baseURL, model string) *AnthropicCompatibleClient {
+// NewAnthropicCompatibleClient creates a new client for Anthropic-compatible
+// APIs that sends requests in format
+func NewAnthropicCompatibleClient(apiKey, baseURL, model string, format WireFormat) *AnthropicCompatibleClient {
 	if model == "" {
 		model = "claude-3-sonnet-20240229" // default model
 	}
@@ -203,50 +315,58 @@ func NewAnthropicCompatibleClient(apiKey, baseURL, model string) *AnthropicCompa
 		ApiKey:  apiKey,
 		BaseURL: baseURL,
 		Model:   model,
+		Format:  format,
 		Client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		Retry: DefaultRetryConfig(),
 	}
 }
 
-// ChatCompletion makes a chat completion request to an OpenAI-compatible API
+// ChatCompletion makes a chat completion request in the client's wire format
 func (a *AnthropicCompatibleClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
-	// Use OpenAI format directly since Minimax actually uses OpenAI-compatible format
-	// (as verified by successful API test against /v1/chat/completions endpoint)
+	// Minimax takes the OpenAI format (as verified by successful API test
+	// against /v1/chat/completions endpoint), real Anthropic endpoints don't
 	if req.Model == "" {
 		req.Model = a.Model
 	}
+	req = a.applyThinking(req)
+	req.Messages = prepareMessages(req.Messages, false)
 
-	// Prepare the request body
-	requestBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create HTTP request - use the full BaseURL as it already includes the path
-	endpoint := strings.TrimRight(a.BaseURL, "/") + "/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(requestBody))
+	httpReq, err := a.newRequest(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	// Set headers for OpenAI-compatible API
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+a.ApiKey)
-
 	// Make the request
-	resp, err := a.Client.Do(httpReq)
+	resp, err := doWithRetry(ctx, a.Retry, resendable(a.Client, httpReq))
 	if err != nil {
-		// Return a mock response for demo purposes when API is not accessible
-		return createMockResponse("I'm the Minimax AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		if a.AllowMockFallback {
+			// Return a mock response for demo purposes when API is not accessible
+			return createMockResponse("I'm the Minimax AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		_, _ = io.ReadAll(resp.Body)
-		// Return a mock response for demo purposes when API returns error
-		return createMockResponse("I'm the Minimax AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		apiErr := newAPIError(resp)
+		if a.AllowMockFallback {
+			// Return a mock response for demo purposes when API returns error
+			return createMockResponse("I'm the Minimax AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, apiErr
+	}
+
+	if a.Format == WireFormatAnthropic {
+		var anthropicResp AnthropicMessageResponse
+		if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		apiResp := fromAnthropicResponse(anthropicResp)
+		apiResp.Meta = newResponseMeta(resp, apiResp, req.Model)
+		return apiResp, nil
 	}
 
 	// Decode response in OpenAI format
@@ -254,29 +374,155 @@ func (a *AnthropicCompatibleClient) ChatCompletion(ctx context.Context, req Chat
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	apiResp.Meta = newResponseMeta(resp, &apiResp, req.Model)
 
 	return &apiResp, nil
 }
 
-// convertToAnthropicMessages converts OpenAI messages to Anthropic format
-func convertToAnthropicMessages(messages []Message) []AnthropicMessage {
+// newRequest builds the HTTP request of a chat completion in the client's
+// wire format
+func (a *AnthropicCompatibleClient) newRequest(ctx context.Context, req ChatCompletionRequest) (*http.Request, error) {
+	var (
+		body     interface{} = req
+		endpoint             = strings.TrimRight(a.BaseURL, "/")
+	)
+	if a.Format == WireFormatAnthropic {
+		body = newAnthropicMessageRequest(req)
+		endpoint = strings.TrimSuffix(endpoint, "/v1") + "/v1/messages"
+	} else {
+		// Use the full BaseURL as it already includes the path
+		endpoint += "/chat/completions"
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.Format == WireFormatAnthropic {
+		httpReq.Header.Set("x-api-key", a.ApiKey)
+		httpReq.Header.Set("anthropic-version", anthropicVersion)
+	} else {
+		httpReq.Header.Set("Authorization", "Bearer "+a.ApiKey)
+	}
+	return httpReq, nil
+}
+
+// fromAnthropicResponse converts an Anthropic messages response to a chat
+// completion response, joining its text blocks into one choice
+func fromAnthropicResponse(resp AnthropicMessageResponse) *ChatCompletionResponse {
+	var text strings.Builder
+	var blocks []AnthropicContent
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "", "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			// Tool calls are kept with the text as native blocks
+			blocks = resp.Content
+		}
+	}
+
+	finishReason := resp.StopReason
+	switch resp.StopReason {
+	case "end_turn", "stop_sequence":
+		finishReason = "stop"
+	case "max_tokens":
+		finishReason = "length"
+	case "tool_use":
+		finishReason = "tool_calls"
+	}
+
+	return &ChatCompletionResponse{
+		ID:     resp.ID,
+		Object: "chat.completion",
+		Model:  resp.Model,
+		Choices: []Choice{{
+			Message:      Message{Role: "assistant", Content: text.String(), Blocks: blocks},
+			FinishReason: finishReason,
+		}},
+		Usage: Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}
+}
+
+// defaultAnthropicMaxTokens is the max_tokens of Anthropic requests that
+// don't set one, since the field is required there
+const defaultAnthropicMaxTokens = 4096
+
+// applyThinking keeps the thinking level for the Anthropic format, which
+// maps it to a thinking budget, and describes it in the prompt for the
+// OpenAI format
+func (a *AnthropicCompatibleClient) applyThinking(req ChatCompletionRequest) ChatCompletionRequest {
+	if a.Format == WireFormatAnthropic {
+		return req
+	}
+	return withThinkingInstruction(req)
+}
+
+// newAnthropicMessageRequest converts a chat completion request to the
+// Anthropic messages format
+func newAnthropicMessageRequest(req ChatCompletionRequest) AnthropicMessageRequest {
+	system, messages := convertToAnthropicMessages(req.Messages)
+	anthropicReq := AnthropicMessageRequest{
+		Model:     req.Model,
+		System:    system,
+		Messages:  messages,
+		MaxTokens: defaultAnthropicMaxTokens,
+		Stream:    req.Stream,
+	}
+	if req.MaxTokens != nil {
+		anthropicReq.MaxTokens = *req.MaxTokens
+	}
+	for _, tool := range req.Tools {
+		anthropicReq.Tools = append(anthropicReq.Tools, AnthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+	// The answer is written after the thinking, within max_tokens
+	if budget := ThinkingBudget(req.Thinking); budget > 0 {
+		anthropicReq.Thinking = &AnthropicThinking{Type: "enabled", BudgetTokens: budget}
+		if anthropicReq.MaxTokens <= budget {
+			anthropicReq.MaxTokens = budget + defaultAnthropicMaxTokens
+		}
+	}
+	return anthropicReq
+}
+
+// convertToAnthropicMessages converts OpenAI messages to Anthropic format.
+// Anthropic has no system role: system messages are joined with newlines
+// into the returned system prompt, and the other messages keep their roles.
+// Messages with content blocks send those instead of their text.
+func convertToAnthropicMessages(messages []Message) (string, []AnthropicMessage) {
+	var system []string
 	var anthropicMessages []AnthropicMessage
 
 	for _, msg := range messages {
-		// Anthropic requires role to be either "user" or "assistant"
-		role := msg.Role
-		if role == "system" {
-			// Anthropic doesn't have a system role, so prepend to first user message
-			// For simplicity, we'll treat system messages as user messages
-			role = "user"
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		var content interface{} = msg.Content
+		if len(msg.Blocks) > 0 {
+			content = msg.Blocks
 		}
 		anthropicMessages = append(anthropicMessages, AnthropicMessage{
-			Role:    role,
-			Content: msg.Content,
+			Role:    msg.Role,
+			Content: content,
 		})
 	}
 
-	return anthropicMessages
+	return strings.Join(system, "\n"), anthropicMessages
 }
 
 // OpenAICompatibleClient implements Client for OpenAI-compatible APIs like Qwen
@@ -284,7 +530,13 @@ type OpenAICompatibleClient struct {
 	ApiKey  string
 	BaseURL string
 	Model   string
+	Vision  bool // Whether the model accepts image content parts
 	Client  *http.Client
+
+	Reasoning bool // Whether the model takes reasoning_effort; others get the thinking level as a prompt instruction
+
+	AllowMockFallback bool        // See ZhipuClient.AllowMockFallback
+	Retry             RetryConfig // Retries of transient failures
 }
 
 // NewOpenAICompatibleClient creates a new client for OpenAI-compatible APIs
@@ -300,6 +552,7 @@ func NewOpenAICompatibleClient(apiKey, baseURL, model string) *OpenAICompatibleC
 		Client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		Retry: DefaultRetryConfig(),
 	}
 }
 
@@ -308,6 +561,8 @@ func (o *OpenAICompatibleClient) ChatCompletion(ctx context.Context, req ChatCom
 	if req.Model == "" {
 		req.Model = o.Model
 	}
+	req = o.applyThinking(req)
+	req.Messages = prepareMessages(req.Messages, o.Vision)
 
 	// Prepare the request body
 	requestBody, err := json.Marshal(req)
@@ -327,17 +582,24 @@ func (o *OpenAICompatibleClient) ChatCompletion(ctx context.Context, req ChatCom
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Make the request
-	resp, err := o.Client.Do(httpReq)
+	resp, err := doWithRetry(ctx, o.Retry, resendable(o.Client, httpReq))
 	if err != nil {
-		// Return a mock response for demo purposes when API is not accessible
-		return createMockResponse("I'm the Qwen AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		if o.AllowMockFallback {
+			// Return a mock response for demo purposes when API is not accessible
+			return createMockResponse("I'm the Qwen AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// Return a mock response for demo purposes when API returns error
-		return createMockResponse("I'm the Qwen AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		apiErr := newAPIError(resp)
+		if o.AllowMockFallback {
+			// Return a mock response for demo purposes when API returns error
+			return createMockResponse("I'm the Qwen AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, apiErr
 	}
 
 	// Decode response
@@ -345,10 +607,27 @@ func (o *OpenAICompatibleClient) ChatCompletion(ctx context.Context, req ChatCom
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	apiResp.Meta = newResponseMeta(resp, &apiResp, req.Model)
 
 	return &apiResp, nil
 }
 
+// applyThinking sets reasoning_effort from the thinking level for reasoning
+// models, and describes the level in the prompt for the others
+func (o *OpenAICompatibleClient) applyThinking(req ChatCompletionRequest) ChatCompletionRequest {
+	if !o.Reasoning {
+		return withThinkingInstruction(req)
+	}
+	req.ReasoningEffort = ReasoningEffort(req.Thinking)
+	req.Thinking = ""
+	return req
+}
+
+// SupportsVision reports whether the client was configured for image input
+func (o *OpenAICompatibleClient) SupportsVision() bool {
+	return o.Vision
+}
+
 // SendMessage sends a simple message and gets a response
 func (o *OpenAICompatibleClient) SendMessage(ctx context.Context, role, content string) (string, error) {
 	req := ChatCompletionRequest{
@@ -371,53 +650,231 @@ func (o *OpenAICompatibleClient) SendMessage(ctx context.Context, role, content
 	return resp.Choices[0].Message.Content, nil
 }
 
+// ErrUnknownProvider is returned for a request naming a provider the
+// MultiProviderClient doesn't have
+var ErrUnknownProvider = errors.New("unknown AI provider")
+
 // MultiProviderClient manages multiple AI providers and selects the appropriate one
 type MultiProviderClient struct {
 	Providers map[string]Client
+
+	modelRoutes map[string]string // Model ID to the provider serving it
+
+	breakerConfig BreakerConfig
+	breakers      map[string]*CircuitBreaker
+
+	policy        RoutingPolicy
+	fallbackOrder []string // Providers tried in turn when the routed one fails
+	stats         map[string]*providerStats
+	limiter       *ConcurrencyLimiter // Bounds requests in flight across providers, nil when unlimited
+	mu            sync.Mutex          // Guards next
+	next          int                 // Round-robin position
 }
 
 // NewMultiProviderClient creates a new client that can handle multiple providers
 func NewMultiProviderClient() *MultiProviderClient {
 	return &MultiProviderClient{
-		Providers: make(map[string]Client),
+		Providers:     make(map[string]Client),
+		modelRoutes:   make(map[string]string),
+		breakerConfig: DefaultBreakerConfig(),
+		breakers:      make(map[string]*CircuitBreaker),
+		policy:        RoutingByName,
+		stats:         make(map[string]*providerStats),
 	}
 }
 
 // AddProvider adds a provider to the multi-provider client
 func (m *MultiProviderClient) AddProvider(name string, client Client) {
 	m.Providers[name] = client
+	m.breakers[name] = NewCircuitBreaker(m.breakerConfig)
+	m.stats[name] = &providerStats{}
+}
+
+// RegisterModel routes requests for modelID to the named provider, ahead of
+// the name-based guess of ProviderForModel
+func (m *MultiProviderClient) RegisterModel(modelID, providerName string) {
+	m.modelRoutes[modelID] = providerName
+}
+
+// GetProvider returns the provider added under name
+func (m *MultiProviderClient) GetProvider(name string) (Client, bool) {
+	client, ok := m.Providers[name]
+	return client, ok
+}
+
+// providerFor returns the provider a model is routed to: the one it was
+// registered with, else the one its name suggests, or "" if unknown
+func (m *MultiProviderClient) providerFor(model string) string {
+	if name, ok := m.modelRoutes[model]; ok {
+		return name
+	}
+	return ProviderForModel(model)
+}
+
+// SetConcurrencyLimit bounds the requests and streams in flight at once
+// across all providers
+func (m *MultiProviderClient) SetConcurrencyLimit(config ConcurrencyConfig) {
+	m.limiter = NewConcurrencyLimiter(config)
+}
+
+// Limiter returns the concurrency limiter, nil when requests are unlimited
+func (m *MultiProviderClient) Limiter() *ConcurrencyLimiter {
+	return m.limiter
+}
+
+// SetBreakerConfig sets the circuit breaker configuration and resets all provider breakers
+func (m *MultiProviderClient) SetBreakerConfig(config BreakerConfig) {
+	m.breakerConfig = config
+	for name := range m.Providers {
+		m.breakers[name] = NewCircuitBreaker(config)
+	}
+}
+
+// SetProviderBreakerConfig overrides the circuit breaker configuration of one
+// provider and resets its breaker
+func (m *MultiProviderClient) SetProviderBreakerConfig(name string, config BreakerConfig) {
+	if _, ok := m.Providers[name]; ok {
+		m.breakers[name] = NewCircuitBreaker(config)
+	}
+}
+
+// BreakerStates returns the circuit breaker state of each provider
+func (m *MultiProviderClient) BreakerStates() map[string]BreakerState {
+	states := make(map[string]BreakerState, len(m.breakers))
+	for name, breaker := range m.breakers {
+		states[name] = breaker.State()
+	}
+	return states
+}
+
+// ProviderForModel returns the provider name a model is routed to, or "" if unknown
+func ProviderForModel(model string) string {
+	model = strings.ToLower(model)
+	if strings.Contains(model, "minimax") {
+		return "minimax"
+	} else if strings.Contains(model, "qwen") || strings.Contains(model, "coder-model") {
+		return "qwen"
+	} else if strings.Contains(model, "zhipu") || strings.Contains(model, "glm") {
+		return "zhipu"
+	}
+	return ""
 }
 
 // ChatCompletion makes a request using the appropriate provider
 func (m *MultiProviderClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
-	// Determine which provider to use based on the model name
-	providerName := ""
-	if strings.Contains(strings.ToLower(req.Model), "minimax") || strings.Contains(strings.ToLower(req.Model), "minimax-m2") {
-		providerName = "minimax"
-	} else if strings.Contains(strings.ToLower(req.Model), "qwen") || strings.Contains(strings.ToLower(req.Model), "coder-model") {
-		providerName = "qwen"
-	} else if strings.Contains(strings.ToLower(req.Model), "zhipu") || strings.Contains(strings.ToLower(req.Model), "glm") {
-		providerName = "zhipu"
-	}
-
-	// If a specific provider was identified, try to use it
-	if providerName != "" {
-		client, exists := m.Providers[providerName]
-		if exists {
-			return client.ChatCompletion(ctx, req)
-		}
+	release, err := m.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	// If no specific provider was found or the specific one doesn't exist,
-	// try to use any available provider
-	for _, client := range m.Providers {
-		// Just use the first available client as fallback
-		return client.ChatCompletion(ctx, req)
+	order, err := m.route(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var failures []ProviderError
+	for _, name := range order {
+		if !m.breakers[name].Allow() {
+			continue
+		}
+		resp, err := m.callProvider(ctx, name, req)
+		if err == nil {
+			return resp, nil
+		}
+		// Without a fallback chain the first provider's error is final, and
+		// a cancelled request isn't worth retrying elsewhere
+		if len(m.fallbackOrder) == 0 || ctx.Err() != nil {
+			return nil, err
+		}
+		failures = append(failures, ProviderError{Provider: name, Err: err})
 	}
 
+	if len(failures) > 0 {
+		return nil, &FallbackError{Errors: failures}
+	}
+	if len(m.Providers) > 0 {
+		return nil, fmt.Errorf("no AI provider available: %w", ErrCircuitOpen)
+	}
 	return nil, fmt.Errorf("no AI provider available")
 }
 
+// route orders the providers to try for a request. A request naming its
+// provider gets only that one, and an error when there is no such provider.
+// Otherwise the one serving the model comes first, then the others in the
+// fallback order when one is set, else in the order the routing policy
+// prefers. Providers excluded with WithoutProviders are left out.
+func (m *MultiProviderClient) route(ctx context.Context, req ChatCompletionRequest) ([]string, error) {
+	excluded := excludedProviders(ctx)
+	if req.Provider != "" {
+		if _, exists := m.Providers[req.Provider]; !exists {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, req.Provider)
+		}
+		if excluded[req.Provider] {
+			return nil, nil
+		}
+		return []string{req.Provider}, nil
+	}
+
+	providerName := m.providerFor(req.Model)
+	order := make([]string, 0, len(m.Providers))
+	if _, exists := m.Providers[providerName]; exists && !excluded[providerName] {
+		order = append(order, providerName)
+	}
+	rest := m.fallbackOrder
+	if len(rest) == 0 {
+		rest = m.candidates()
+	}
+	for _, name := range rest {
+		if name != providerName && !excluded[name] {
+			order = append(order, name)
+		}
+	}
+	return order, nil
+}
+
+// excludedProvidersKey is the context key of WithoutProviders
+type excludedProvidersKey struct{}
+
+// WithoutProviders returns a context in which a MultiProviderClient doesn't
+// route to the named providers, e.g. to continue a failed stream elsewhere
+func WithoutProviders(ctx context.Context, names ...string) context.Context {
+	excluded := make(map[string]bool)
+	for name := range excludedProviders(ctx) {
+		excluded[name] = true
+	}
+	for _, name := range names {
+		excluded[name] = true
+	}
+	return context.WithValue(ctx, excludedProvidersKey{}, excluded)
+}
+
+// excludedProviders returns the providers excluded from ctx
+func excludedProviders(ctx context.Context) map[string]bool {
+	excluded, _ := ctx.Value(excludedProvidersKey{}).(map[string]bool)
+	return excluded
+}
+
+// callProvider calls a single provider and records the outcome on its breaker
+// and in its latency and usage stats
+func (m *MultiProviderClient) callProvider(ctx context.Context, name string, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	start := time.Now()
+	resp, err := m.Providers[name].ChatCompletion(ctx, req)
+	m.stats[name].record(time.Since(start), err == nil)
+	if err != nil {
+		m.breakers[name].RecordFailure()
+		return nil, err
+	}
+
+	m.breakers[name].RecordSuccess()
+	if resp != nil {
+		m.stats[name].addUsage(resp.Usage)
+		if resp.Meta != nil {
+			resp.Meta.Provider = name
+		}
+	}
+	return resp, nil
+}
+
 // Helper function to create mock responses for demo purposes
 func createMockResponse(content string) *ChatCompletionResponse {
 	return &ChatCompletionResponse{
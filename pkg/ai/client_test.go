@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicRequestMovesSystemMessages(t *testing.T) {
+	req := newAnthropicMessageRequest(ChatCompletionRequest{
+		Model: "MiniMax-M2.1",
+		Messages: []Message{
+			{Role: "system", Content: "You are Goclaw."},
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Hello!"},
+			{Role: "system", Content: "Answer briefly."},
+			{Role: "user", Content: "How are you?"},
+		},
+	})
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got struct {
+		Model     string             `json:"model"`
+		System    string             `json:"system"`
+		Messages  []AnthropicMessage `json:"messages"`
+		MaxTokens int                `json:"max_tokens"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("request body %s is invalid: %v", body, err)
+	}
+
+	if got.System != "You are Goclaw.\nAnswer briefly." {
+		t.Errorf("system = %q, want the system messages joined", got.System)
+	}
+	want := []AnthropicMessage{
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello!"},
+		{Role: "user", Content: "How are you?"},
+	}
+	if len(got.Messages) != len(want) {
+		t.Fatalf("messages = %+v, want %+v", got.Messages, want)
+	}
+	for i := range want {
+		if got.Messages[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got.Messages[i], want[i])
+		}
+	}
+	if got.Model != "MiniMax-M2.1" || got.MaxTokens != defaultAnthropicMaxTokens {
+		t.Errorf("model = %q, max_tokens = %d", got.Model, got.MaxTokens)
+	}
+}
+
+func TestAnthropicCompatibleClientMessagesFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %q, want /v1/messages", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("headers = %v, want the API key and version", r.Header)
+		}
+		var req AnthropicMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("request body is invalid: %v", err)
+		}
+		if req.System != "You are Goclaw." || len(req.Messages) != 1 || req.MaxTokens != 256 {
+			t.Errorf("request = %+v, want the system prompt hoisted and max_tokens 256", req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-3-sonnet-20240229",
+			"content": [{"type": "text", "text": "Hello"}, {"type": "text", "text": " there"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 12, "output_tokens": 3}
+		}`))
+	}))
+	defer server.Close()
+
+	maxTokens := 256
+	client := NewAnthropicCompatibleClient("key", server.URL, "", WireFormatAnthropic)
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{
+		Messages: []Message{
+			{Role: "system", Content: "You are Goclaw."},
+			{Role: "user", Content: "Hi"},
+		},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hello there" || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("choices = %+v, want the text blocks joined into one stopped choice", resp.Choices)
+	}
+	if resp.Usage != (Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}) {
+		t.Errorf("usage = %+v, want 12 prompt and 3 completion tokens", resp.Usage)
+	}
+}
+
+func TestChatCompletionCapturesResponseMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-request-id", "req_42")
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-remaining-requests", "499")
+		w.Header().Set("x-ratelimit-reset-tokens", "6ms")
+		w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"model": "qwen-turbo",
+			"system_fingerprint": "fp_1",
+			"choices": [{"message": {"role": "assistant", "content": "hi"}}]
+		}`))
+	}))
+	defer server.Close()
+
+	m := NewMultiProviderClient()
+	m.AddProvider("qwen", NewOpenAICompatibleClient("key", server.URL, "qwen-max"))
+	resp, err := m.ChatCompletion(context.Background(), ChatCompletionRequest{
+		Model:    "qwen-max",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+
+	meta := resp.Meta
+	if meta == nil {
+		t.Fatal("response has no metadata")
+	}
+	want := ResponseMeta{
+		ID:                "chatcmpl-1",
+		RequestedModel:    "qwen-max",
+		Model:             "qwen-turbo",
+		Provider:          "qwen",
+		SystemFingerprint: "fp_1",
+		RequestID:         "req_42",
+	}
+	got := *meta
+	got.RateLimit = nil
+	if got != want {
+		t.Errorf("meta = %+v, want %+v", got, want)
+	}
+	if !meta.ModelChanged() {
+		t.Error("ModelChanged() = false, want the substituted model noticed")
+	}
+	if meta.RateLimit == nil || *meta.RateLimit != (RateLimit{LimitRequests: 500, RemainingRequests: 499, ResetTokens: "6ms"}) {
+		t.Errorf("rate limit = %+v, want the header values", meta.RateLimit)
+	}
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody caps how much of an error response is kept
+const maxErrorBody = 4096
+
+// APIError is returned when a provider answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string // Start of the response body, usually the provider's error message
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("API request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// newAPIError reads the status and the start of the body of a failed response
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
+
+// IsAuthError reports whether a provider rejected the request's credentials
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// IsNetworkTimeout reports whether a request timed out before the provider
+// answered, e.g. on the HTTP client's timeout
+func IsNetworkTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
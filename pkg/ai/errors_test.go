@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClientsReturnAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	clients := map[string]Client{
+		"zhipu":     NewZhipuClient("key", server.URL, ""),
+		"openai":    NewOpenAICompatibleClient("key", server.URL, "model"),
+		"anthropic": NewAnthropicCompatibleClient("key", server.URL, "model", WireFormatOpenAI),
+	}
+	for name, client := range clients {
+		resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: ChatCompletion() = %v, %v, want an APIError", name, resp, err)
+			continue
+		}
+		if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Body != `{"error":"invalid api key"}` {
+			t.Errorf("%s: APIError = %+v, want the status and body", name, apiErr)
+		}
+	}
+}
+
+func TestClientsReturnConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := NewOpenAICompatibleClient("key", url, "model")
+	client.Retry = RetryConfig{}
+	if resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{}); err == nil {
+		t.Errorf("ChatCompletion() = %+v, want an error for an unreachable API", resp)
+	}
+}
+
+func TestAllowMockFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewZhipuClient("key", server.URL, "")
+	client.AllowMockFallback = true
+	client.Retry = RetryConfig{}
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+	if err != nil || resp.Model != "mock-model" {
+		t.Errorf("ChatCompletion() = %+v, %v, want the mock reply", resp, err)
+	}
+}
+
+func TestStreamReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewOpenAICompatibleClient("key", server.URL, "model")
+	client.Retry = RetryConfig{}
+	_, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("ChatCompletionStream() error = %v, want a 429 APIError", err)
+	}
+}
+
+type timeoutNetError struct{}
+
+func (timeoutNetError) Error() string   { return "i/o timeout" }
+func (timeoutNetError) Timeout() bool   { return true }
+func (timeoutNetError) Temporary() bool { return true }
+
+func TestClassifyProviderErrors(t *testing.T) {
+	auth := fmt.Errorf("minimax: %w", &APIError{StatusCode: http.StatusUnauthorized})
+	timeout := fmt.Errorf("request failed: %w", &url.Error{Op: "Post", URL: "https://api.example.com", Err: timeoutNetError{}})
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	if !IsAuthError(auth) || IsAuthError(timeout) || IsAuthError(unavailable) {
+		t.Error("IsAuthError should only match 401 and 403 responses")
+	}
+	if !IsNetworkTimeout(timeout) || IsNetworkTimeout(auth) || IsNetworkTimeout(unavailable) {
+		t.Error("IsNetworkTimeout should only match timed out requests")
+	}
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProviderError is the failure of one provider in a fallback chain
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+// FallbackError is returned when every provider of the fallback chain failed.
+// It lists the failures in the order the providers were tried.
+type FallbackError struct {
+	Errors []ProviderError
+}
+
+func (e *FallbackError) Error() string {
+	failures := make([]string, 0, len(e.Errors))
+	for _, failure := range e.Errors {
+		failures = append(failures, fmt.Sprintf("%s: %v", failure.Provider, failure.Err))
+	}
+	return "all AI providers failed: " + strings.Join(failures, "; ")
+}
+
+// Unwrap returns the error of the last provider tried, so errors.As finds
+// e.g. its APIError
+func (e *FallbackError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[len(e.Errors)-1].Err
+}
+
+// ParseFallbackOrder reads a fallback chain of provider names, e.g.
+// ["minimax", "qwen", "zhipu"]
+func ParseFallbackOrder(raw interface{}) ([]string, error) {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a list of provider names")
+	}
+	order := make([]string, 0, len(list))
+	for _, item := range list {
+		name, ok := item.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("must be a list of provider names, got %v", item)
+		}
+		order = append(order, name)
+	}
+	return order, nil
+}
+
+// SetFallbackOrder sets the providers tried, in order, when the provider a
+// request is routed to fails. The chain replaces the routing policy's order
+// for fallback; providers left out of it aren't fallen back to. An empty
+// order restores returning the first provider's error.
+func (m *MultiProviderClient) SetFallbackOrder(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, exists := m.Providers[name]; !exists {
+			return fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("provider %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	m.fallbackOrder = append([]string(nil), names...)
+	return nil
+}
+
+// FallbackOrder returns the fallback chain, empty when none is set
+func (m *MultiProviderClient) FallbackOrder() []string {
+	return append([]string(nil), m.fallbackOrder...)
+}
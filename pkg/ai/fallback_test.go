@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMultiProviderClientFallbackOrder(t *testing.T) {
+	minimax := &fakeClient{err: errors.New("minimax down")}
+	qwen := &fakeClient{err: &APIError{StatusCode: 503, Body: "qwen overloaded"}}
+	zhipu := &fakeClient{reply: "from zhipu"}
+	gemini := &fakeClient{reply: "from gemini"}
+
+	client := NewMultiProviderClient()
+	client.AddProvider("minimax", minimax)
+	client.AddProvider("qwen", qwen)
+	client.AddProvider("zhipu", zhipu)
+	client.AddProvider("gemini", gemini)
+	if err := client.SetFallbackOrder([]string{"minimax", "qwen", "zhipu"}); err != nil {
+		t.Fatalf("SetFallbackOrder() error = %v", err)
+	}
+
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "MiniMax-M2.1"})
+	if err != nil || resp.Choices[0].Message.Content != "from zhipu" {
+		t.Fatalf("ChatCompletion() = %v, %v; want zhipu's reply", resp, err)
+	}
+	if minimax.calls != 1 || qwen.calls != 1 || gemini.calls != 0 {
+		t.Errorf("calls minimax=%d qwen=%d gemini=%d, want 1, 1, 0", minimax.calls, qwen.calls, gemini.calls)
+	}
+
+	// The model's provider goes first even when it is later in the chain
+	zhipu.calls = 0
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "glm-4"}); err != nil {
+		t.Fatalf("ChatCompletion(glm-4) error = %v", err)
+	}
+	if zhipu.calls != 1 || minimax.calls != 1 {
+		t.Errorf("calls zhipu=%d minimax=%d, want zhipu alone", zhipu.calls, minimax.calls)
+	}
+}
+
+func TestMultiProviderClientFallbackCollectsErrors(t *testing.T) {
+	client := NewMultiProviderClient()
+	client.AddProvider("minimax", &fakeClient{err: errors.New("minimax down")})
+	client.AddProvider("qwen", &fakeClient{err: &APIError{StatusCode: 503, Body: "qwen overloaded"}})
+	client.AddProvider("zhipu", &fakeClient{reply: "unused"})
+	if err := client.SetFallbackOrder([]string{"minimax", "qwen"}); err != nil {
+		t.Fatalf("SetFallbackOrder() error = %v", err)
+	}
+
+	_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "MiniMax-M2.1"})
+	var fallbackErr *FallbackError
+	if !errors.As(err, &fallbackErr) {
+		t.Fatalf("error = %v, want a FallbackError", err)
+	}
+	if len(fallbackErr.Errors) != 2 || fallbackErr.Errors[0].Provider != "minimax" || fallbackErr.Errors[1].Provider != "qwen" {
+		t.Errorf("failures = %+v, want minimax then qwen", fallbackErr.Errors)
+	}
+	if !strings.Contains(err.Error(), "minimax down") {
+		t.Errorf("error %q doesn't mention minimax's failure", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 503 {
+		t.Errorf("errors.As(APIError) = %v, want the last provider's error", apiErr)
+	}
+}
+
+func TestMultiProviderClientWithoutFallbackOrder(t *testing.T) {
+	minimax := &fakeClient{err: errors.New("minimax down")}
+	qwen := &fakeClient{reply: "from qwen"}
+
+	client := NewMultiProviderClient()
+	client.AddProvider("minimax", minimax)
+	client.AddProvider("qwen", qwen)
+
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "MiniMax-M2.1"}); err == nil || err.Error() != "minimax down" {
+		t.Errorf("error = %v, want minimax's error as is", err)
+	}
+	if qwen.calls != 0 {
+		t.Errorf("qwen calls = %d, want 0 without a fallback chain", qwen.calls)
+	}
+}
+
+func TestSetFallbackOrderRejectsUnknownProviders(t *testing.T) {
+	client := NewMultiProviderClient()
+	client.AddProvider("minimax", &fakeClient{})
+
+	if err := client.SetFallbackOrder([]string{"minimax", "openai"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("SetFallbackOrder(openai) = %v, want ErrUnknownProvider", err)
+	}
+	if err := client.SetFallbackOrder([]string{"minimax", "minimax"}); err == nil {
+		t.Error("SetFallbackOrder() accepted a duplicate provider")
+	}
+	if _, err := ParseFallbackOrder([]interface{}{"minimax", 3}); err == nil {
+		t.Error("ParseFallbackOrder() accepted a non-string entry")
+	}
+	order, err := ParseFallbackOrder([]interface{}{"minimax", "qwen"})
+	if err != nil || len(order) != 2 || order[1] != "qwen" {
+		t.Errorf("ParseFallbackOrder() = %v, %v", order, err)
+	}
+}
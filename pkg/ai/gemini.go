@@ -0,0 +1,270 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultGeminiBaseURL is the Google Generative Language API
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiPart is one piece of a Gemini message
+type GeminiPart struct {
+	Text string `json:"text"`
+}
+
+// GeminiContent is a message of a Gemini conversation
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"` // "user" or "model"
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiGenerationConfig holds the sampling parameters of a Gemini request
+type GeminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+
+	ThinkingConfig *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+// GeminiThinkingConfig sets the reasoning token budget; 0 turns thinking off
+type GeminiThinkingConfig struct {
+	ThinkingBudget int `json:"thinkingBudget"`
+}
+
+// GeminiRequest represents a generateContent request
+type GeminiRequest struct {
+	Contents          []GeminiContent         `json:"contents"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"` // The system messages, which Gemini takes outside the conversation
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiCandidate is one reply of a generateContent response
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+	Index        int           `json:"index"`
+}
+
+// GeminiUsageMetadata represents token usage in the Gemini API
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// GeminiResponse represents a generateContent response
+type GeminiResponse struct {
+	Candidates    []GeminiCandidate   `json:"candidates"`
+	UsageMetadata GeminiUsageMetadata `json:"usageMetadata"`
+	ModelVersion  string              `json:"modelVersion"`
+	ResponseID    string              `json:"responseId"`
+}
+
+// GeminiClient implements Client for Google's Generative Language API
+type GeminiClient struct {
+	ApiKey  string
+	BaseURL string
+	Model   string
+	Client  *http.Client
+
+	AllowMockFallback bool        // See ZhipuClient.AllowMockFallback
+	Retry             RetryConfig // Retries of transient failures
+}
+
+// NewGeminiClient creates a new Gemini client
+func NewGeminiClient(apiKey, baseURL, model string) *GeminiClient {
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+
+	if model == "" {
+		model = "gemini-1.5-flash"
+	}
+
+	return &GeminiClient{
+		ApiKey:  apiKey,
+		BaseURL: baseURL,
+		Model:   model,
+		Client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		Retry: DefaultRetryConfig(),
+	}
+}
+
+// ChatCompletion makes a generateContent request to the Gemini API
+func (g *GeminiClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if req.Model == "" {
+		req.Model = g.Model
+	}
+	req.Messages = prepareMessages(req.Messages, false)
+
+	httpReq, err := g.newRequest(ctx, req, ":generateContent")
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the request
+	resp, err := doWithRetry(ctx, g.Retry, resendable(g.Client, httpReq))
+	if err != nil {
+		if g.AllowMockFallback {
+			// Return a mock response for demo purposes when API is not accessible
+			return createMockResponse("I'm the Gemini AI model. Due to authentication or connectivity issues, I'm providing a simulated response. In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check status code
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := newAPIError(resp)
+		if g.AllowMockFallback {
+			// Return a mock response for demo purposes when API returns error
+			return createMockResponse("I'm the Gemini AI model. I encountered an issue processing your request (status: " + fmt.Sprintf("%d", resp.StatusCode) + "). In a properly configured environment with valid credentials, I would provide a real response to your query."), nil
+		}
+		return nil, apiErr
+	}
+
+	var geminiResp GeminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	apiResp := fromGeminiResponse(geminiResp, req.Model)
+	apiResp.Meta = newResponseMeta(resp, apiResp, req.Model)
+	return apiResp, nil
+}
+
+// ChatCompletionStream streams a completion with streamGenerateContent, whose
+// server-sent events each carry a GeminiResponse with the next text
+func (g *GeminiClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	if req.Model == "" {
+		req.Model = g.Model
+	}
+	req.Messages = prepareMessages(req.Messages, false)
+
+	httpReq, err := g.newRequest(ctx, req, ":streamGenerateContent?alt=sse")
+	if err != nil {
+		return nil, err
+	}
+	return startStream(ctx, g.Client, g.Retry, httpReq, geminiEvent)
+}
+
+// newRequest builds a request to the model's method, e.g. ":generateContent"
+func (g *GeminiClient) newRequest(ctx context.Context, req ChatCompletionRequest, method string) (*http.Request, error) {
+	requestBody, err := json.Marshal(newGeminiRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// The key goes in a header rather than the URL, which ends up in logs
+	endpoint := strings.TrimRight(g.BaseURL, "/") + "/models/" + url.PathEscape(req.Model) + method
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("x-goog-api-key", g.ApiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// geminiEvent parses streamGenerateContent events, partial responses whose
+// candidate has a finishReason once the reply is finished
+func geminiEvent(data string) (string, bool, error) {
+	var chunk GeminiResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", false, fmt.Errorf("failed to decode stream chunk: %w", err)
+	}
+	var delta strings.Builder
+	done := false
+	for _, candidate := range chunk.Candidates {
+		for _, part := range candidate.Content.Parts {
+			delta.WriteString(part.Text)
+		}
+		if candidate.FinishReason != "" {
+			done = true
+		}
+	}
+	return delta.String(), done, nil
+}
+
+// newGeminiRequest converts a chat completion request to the Gemini format.
+// Gemini calls the assistant "model" and has no system role: system messages
+// are joined into the system instruction.
+func newGeminiRequest(req ChatCompletionRequest) GeminiRequest {
+	var system []string
+	geminiReq := GeminiRequest{}
+	for _, msg := range req.Messages {
+		role := "user"
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+			continue
+		case "assistant":
+			role = "model"
+		}
+		geminiReq.Contents = append(geminiReq.Contents, GeminiContent{
+			Role:  role,
+			Parts: []GeminiPart{{Text: msg.Content}},
+		})
+	}
+
+	if len(system) > 0 {
+		geminiReq.SystemInstruction = &GeminiContent{Parts: []GeminiPart{{Text: strings.Join(system, "\n")}}}
+	}
+	if req.Temperature != nil || req.MaxTokens != nil || req.TopP != nil || req.Thinking != "" {
+		geminiReq.GenerationConfig = &GeminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+			TopP:            req.TopP,
+		}
+	}
+	if req.Thinking != "" {
+		geminiReq.GenerationConfig.ThinkingConfig = &GeminiThinkingConfig{ThinkingBudget: ThinkingBudget(req.Thinking)}
+	}
+	return geminiReq
+}
+
+// fromGeminiResponse converts a Gemini response to a chat completion
+// response, with a choice per candidate
+func fromGeminiResponse(resp GeminiResponse, model string) *ChatCompletionResponse {
+	if resp.ModelVersion != "" {
+		model = resp.ModelVersion
+	}
+	apiResp := &ChatCompletionResponse{
+		ID:     resp.ResponseID,
+		Object: "chat.completion",
+		Model:  model,
+		Usage: Usage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		},
+	}
+
+	for _, candidate := range resp.Candidates {
+		var text strings.Builder
+		for _, part := range candidate.Content.Parts {
+			text.WriteString(part.Text)
+		}
+
+		finishReason := strings.ToLower(candidate.FinishReason)
+		if candidate.FinishReason == "MAX_TOKENS" {
+			finishReason = "length"
+		}
+		apiResp.Choices = append(apiResp.Choices, Choice{
+			Index:        candidate.Index,
+			Message:      Message{Role: "assistant", Content: text.String()},
+			FinishReason: finishReason,
+		})
+	}
+	return apiResp
+}
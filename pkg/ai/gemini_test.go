@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGeminiClientChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-1.5-pro:generateContent" {
+			t.Errorf("path = %q, want the model's generateContent", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "key" || r.URL.Query().Get("key") != "" {
+			t.Error("the API key should be sent in the header only")
+		}
+
+		var req GeminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("request body is invalid: %v", err)
+		}
+		if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "You are Goclaw." {
+			t.Errorf("system instruction = %+v, want the system message", req.SystemInstruction)
+		}
+		if len(req.Contents) != 3 || req.Contents[0].Role != "user" || req.Contents[1].Role != "model" || req.Contents[2].Parts[0].Text != "How are you?" {
+			t.Errorf("contents = %+v, want user, model, user", req.Contents)
+		}
+		if req.GenerationConfig == nil || req.GenerationConfig.MaxOutputTokens == nil || *req.GenerationConfig.MaxOutputTokens != 100 {
+			t.Errorf("generation config = %+v, want maxOutputTokens 100", req.GenerationConfig)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"candidates": [{
+				"content": {"role": "model", "parts": [{"text": "Fine, "}, {"text": "thanks!"}]},
+				"finishReason": "STOP",
+				"index": 0
+			}],
+			"usageMetadata": {"promptTokenCount": 20, "candidatesTokenCount": 4, "totalTokenCount": 24},
+			"modelVersion": "gemini-1.5-pro-002",
+			"responseId": "resp_1"
+		}`))
+	}))
+	defer server.Close()
+
+	maxTokens := 100
+	client := NewGeminiClient("key", server.URL, "gemini-1.5-pro")
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{
+		Messages: []Message{
+			{Role: "system", Content: "You are Goclaw."},
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Hello!"},
+			{Role: "user", Content: "How are you?"},
+		},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Fine, thanks!" || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("choices = %+v, want the parts joined into one stopped choice", resp.Choices)
+	}
+	if resp.Usage != (Usage{PromptTokens: 20, CompletionTokens: 4, TotalTokens: 24}) {
+		t.Errorf("usage = %+v, want the usage metadata", resp.Usage)
+	}
+	if resp.Meta == nil || resp.Meta.Model != "gemini-1.5-pro-002" || resp.Meta.ID != "resp_1" {
+		t.Errorf("meta = %+v, want the model version and response ID", resp.Meta)
+	}
+}
+
+func TestGeminiClientChatCompletionStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-1.5-pro:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("url = %q, want the model's streamGenerateContent as server-sent events", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Fine, "}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "thanks!"}]}, "finishReason": "STOP"}]}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := NewGeminiClient("key", server.URL, "gemini-1.5-pro")
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "How are you?"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+
+	var deltas []string
+	text, err := CollectStream(chunks, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CollectStream() error = %v", err)
+	}
+	if text != "Fine, thanks!" || len(deltas) != 2 {
+		t.Errorf("text = %q from %d deltas, want %q from 2", text, len(deltas), "Fine, thanks!")
+	}
+}
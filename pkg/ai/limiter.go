@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTooManyRequests is returned when an AI request waited for a free slot
+// longer than the concurrency limit allows
+var ErrTooManyRequests = errors.New("too many concurrent AI requests")
+
+// ConcurrencyConfig bounds how many AI requests are in flight at once, so a
+// burst of chats doesn't hit provider rate limits or exhaust connections
+type ConcurrencyConfig struct {
+	MaxRequests int           // Requests in flight at once; 0 is unlimited
+	MaxWait     time.Duration // How long a request queues for a free slot before failing with ErrTooManyRequests; 0 fails at once
+}
+
+// ParseConcurrencyConfig reads a concurrency limit from a config map such as
+// {"maxRequests": 8, "maxWait": "10s"}
+func ParseConcurrencyConfig(values map[string]interface{}) (ConcurrencyConfig, error) {
+	var config ConcurrencyConfig
+
+	if raw, ok := values["maxRequests"]; ok {
+		maxRequests, ok := raw.(float64)
+		if !ok || maxRequests < 0 || maxRequests != float64(int(maxRequests)) {
+			return config, fmt.Errorf("maxRequests must be a non-negative integer, got %v", raw)
+		}
+		config.MaxRequests = int(maxRequests)
+	}
+
+	if raw, ok := values["maxWait"]; ok {
+		text, _ := raw.(string)
+		maxWait, err := time.ParseDuration(text)
+		if err != nil || maxWait < 0 {
+			return config, fmt.Errorf("maxWait must be a duration such as \"10s\", got %v", raw)
+		}
+		config.MaxWait = maxWait
+	}
+
+	return config, nil
+}
+
+// ConcurrencyLimiter is a semaphore over AI requests. Requests beyond the
+// limit queue for a slot in arrival order. A nil limiter is unlimited.
+type ConcurrencyLimiter struct {
+	slots   chan struct{}
+	maxWait time.Duration
+	waiting int64
+}
+
+// NewConcurrencyLimiter creates a limiter, or returns nil when the config
+// sets no limit
+func NewConcurrencyLimiter(config ConcurrencyConfig) *ConcurrencyLimiter {
+	if config.MaxRequests <= 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{
+		slots:   make(chan struct{}, config.MaxRequests),
+		maxWait: config.MaxWait,
+	}
+}
+
+// Acquire takes a slot, waiting up to the limiter's max wait, and returns the
+// function releasing it. It fails with ErrTooManyRequests when no slot frees
+// up in time, or with ctx's error when ctx is done first.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	atomic.AddInt64(&l.waiting, 1)
+	defer atomic.AddInt64(&l.waiting, -1)
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: all %d slots stayed busy for %s", ErrTooManyRequests, cap(l.slots), l.maxWait)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns how many requests hold a slot
+func (l *ConcurrencyLimiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// Waiting returns how many requests are queued for a slot
+func (l *ConcurrencyLimiter) Waiting() int {
+	if l == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&l.waiting))
+}
+
+// MaxRequests returns the limit, 0 when unlimited
+func (l *ConcurrencyLimiter) MaxRequests() int {
+	if l == nil {
+		return 0
+	}
+	return cap(l.slots)
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingClient is a Client whose calls signal started and then wait for
+// unblock
+type blockingClient struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (b *blockingClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	b.started <- struct{}{}
+	<-b.unblock
+	return createMockResponse("done"), nil
+}
+
+func (b *blockingClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	return nil, ErrStreamingUnsupported
+}
+
+// saturate starts max calls on client and waits until they all hold a slot
+func saturate(t *testing.T, client *MultiProviderClient, provider *blockingClient, max int) chan error {
+	t.Helper()
+	results := make(chan error, max)
+	for i := 0; i < max; i++ {
+		go func() {
+			_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+			results <- err
+		}()
+		<-provider.started
+	}
+	if inFlight := client.Limiter().InFlight(); inFlight != max {
+		t.Fatalf("InFlight() = %d, want %d", inFlight, max)
+	}
+	return results
+}
+
+func TestConcurrencyLimitRejectsAfterMaxWait(t *testing.T) {
+	provider := &blockingClient{started: make(chan struct{}), unblock: make(chan struct{})}
+	client := NewMultiProviderClient()
+	client.AddProvider("zhipu", provider)
+	client.SetConcurrencyLimit(ConcurrencyConfig{MaxRequests: 2, MaxWait: 20 * time.Millisecond})
+
+	results := saturate(t, client, provider, 2)
+
+	_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests past the limit, got %v", err)
+	}
+
+	close(provider.unblock)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("saturating call failed: %v", err)
+		}
+	}
+	if inFlight := client.Limiter().InFlight(); inFlight != 0 {
+		t.Errorf("InFlight() = %d after all calls ended, want 0", inFlight)
+	}
+}
+
+func TestConcurrencyLimitQueuesUntilSlotFrees(t *testing.T) {
+	provider := &blockingClient{started: make(chan struct{}), unblock: make(chan struct{})}
+	client := NewMultiProviderClient()
+	client.AddProvider("zhipu", provider)
+	client.SetConcurrencyLimit(ConcurrencyConfig{MaxRequests: 1, MaxWait: time.Minute})
+
+	results := saturate(t, client, provider, 1)
+
+	queued := make(chan error, 1)
+	go func() {
+		_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+		queued <- err
+	}()
+	for client.Limiter().Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Freeing the slot lets the queued call through
+	provider.unblock <- struct{}{}
+	if err := <-results; err != nil {
+		t.Fatalf("saturating call failed: %v", err)
+	}
+	<-provider.started
+	provider.unblock <- struct{}{}
+	if err := <-queued; err != nil {
+		t.Fatalf("expected queued call to succeed, got %v", err)
+	}
+}
+
+func TestParseConcurrencyConfig(t *testing.T) {
+	config, err := ParseConcurrencyConfig(map[string]interface{}{"maxRequests": float64(8), "maxWait": "10s"})
+	if err != nil {
+		t.Fatalf("ParseConcurrencyConfig: %v", err)
+	}
+	if config.MaxRequests != 8 || config.MaxWait != 10*time.Second {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	for _, values := range []map[string]interface{}{
+		{"maxRequests": float64(-1)},
+		{"maxRequests": 1.5},
+		{"maxWait": "soon"},
+	} {
+		if _, err := ParseConcurrencyConfig(values); err == nil {
+			t.Errorf("expected error for %v", values)
+		}
+	}
+}
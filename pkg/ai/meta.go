@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ResponseMeta is what a provider reported about a response besides its
+// content, for tracing what the provider actually did
+type ResponseMeta struct {
+	ID                string     `json:"id,omitempty"`
+	RequestedModel    string     `json:"requestedModel,omitempty"`
+	Model             string     `json:"model,omitempty"` // The model that answered, which providers sometimes pick differently from the requested one
+	Provider          string     `json:"provider,omitempty"`
+	SystemFingerprint string     `json:"systemFingerprint,omitempty"`
+	RequestID         string     `json:"requestId,omitempty"` // The provider's request ID header, to quote in support requests
+	RateLimit         *RateLimit `json:"rateLimit,omitempty"`
+}
+
+// ModelChanged reports whether the provider answered with a model other
+// than the one requested
+func (m *ResponseMeta) ModelChanged() bool {
+	return m.RequestedModel != "" && m.Model != "" && m.Model != m.RequestedModel
+}
+
+// RateLimit is the provider's rate limit state after a request, from its
+// response headers. Fields the provider didn't send are zero.
+type RateLimit struct {
+	LimitRequests     int    `json:"limitRequests,omitempty"`
+	RemainingRequests int    `json:"remainingRequests,omitempty"`
+	LimitTokens       int    `json:"limitTokens,omitempty"`
+	RemainingTokens   int    `json:"remainingTokens,omitempty"`
+	ResetRequests     string `json:"resetRequests,omitempty"`
+	ResetTokens       string `json:"resetTokens,omitempty"`
+}
+
+// rateLimitHeaders names the rate limit headers of OpenAI-style providers
+// and of Anthropic, in that order
+var rateLimitHeaders = []struct {
+	limitRequests, remainingRequests, resetRequests string
+	limitTokens, remainingTokens, resetTokens       string
+}{
+	{
+		"x-ratelimit-limit-requests", "x-ratelimit-remaining-requests", "x-ratelimit-reset-requests",
+		"x-ratelimit-limit-tokens", "x-ratelimit-remaining-tokens", "x-ratelimit-reset-tokens",
+	},
+	{
+		"anthropic-ratelimit-requests-limit", "anthropic-ratelimit-requests-remaining", "anthropic-ratelimit-requests-reset",
+		"anthropic-ratelimit-tokens-limit", "anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-tokens-reset",
+	},
+}
+
+// newResponseMeta collects the metadata of a decoded response from its body
+// fields and the HTTP response headers
+func newResponseMeta(httpResp *http.Response, resp *ChatCompletionResponse, requestedModel string) *ResponseMeta {
+	meta := MetaOf(resp, requestedModel)
+	header := httpResp.Header
+	for _, name := range []string{"x-request-id", "request-id"} {
+		if id := header.Get(name); id != "" {
+			meta.RequestID = id
+			break
+		}
+	}
+
+	for _, names := range rateLimitHeaders {
+		limit := RateLimit{
+			LimitRequests:     headerInt(header, names.limitRequests),
+			RemainingRequests: headerInt(header, names.remainingRequests),
+			LimitTokens:       headerInt(header, names.limitTokens),
+			RemainingTokens:   headerInt(header, names.remainingTokens),
+			ResetRequests:     header.Get(names.resetRequests),
+			ResetTokens:       header.Get(names.resetTokens),
+		}
+		if limit != (RateLimit{}) {
+			meta.RateLimit = &limit
+			break
+		}
+	}
+	return meta
+}
+
+// headerInt returns an integer header, or 0 when it is missing or invalid
+func headerInt(header http.Header, name string) int {
+	n, _ := strconv.Atoi(header.Get(name))
+	return n
+}
+
+// MetaOf returns the metadata of a response to a request for requestedModel.
+// Responses from clients that don't collect it, such as test doubles, get
+// metadata from their body fields alone.
+func MetaOf(resp *ChatCompletionResponse, requestedModel string) *ResponseMeta {
+	if resp.Meta != nil {
+		return resp.Meta
+	}
+	return &ResponseMeta{
+		ID:                resp.ID,
+		RequestedModel:    requestedModel,
+		Model:             resp.Model,
+		SystemFingerprint: resp.SystemFingerprint,
+	}
+}
@@ -0,0 +1,51 @@
+package ai
+
+import "fmt"
+
+// GenerationParams are optional sampling parameters for a chat completion.
+// Nil fields are left to the provider default.
+type GenerationParams struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"maxTokens,omitempty"`
+	TopP        *float64 `json:"topP,omitempty"`
+	Model       string   `json:"model,omitempty"`    // Replaces the request's model when set
+	Thinking    string   `json:"thinking,omitempty"` // Reasoning level, one of the Thinking constants
+}
+
+// Validate checks that the parameters are within the ranges providers accept
+func (p GenerationParams) Validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *p.Temperature)
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		return fmt.Errorf("maxTokens must be positive, got %d", *p.MaxTokens)
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return fmt.Errorf("topP must be greater than 0 and at most 1, got %g", *p.TopP)
+	}
+	return ValidateThinkingLevel(p.Thinking)
+}
+
+// IsZero reports whether no parameter is set
+func (p GenerationParams) IsZero() bool {
+	return p.Temperature == nil && p.MaxTokens == nil && p.TopP == nil && p.Model == "" && p.Thinking == ""
+}
+
+// Apply sets the parameters that are set on the request, leaving the others
+func (p GenerationParams) Apply(req *ChatCompletionRequest) {
+	if p.Temperature != nil {
+		req.Temperature = p.Temperature
+	}
+	if p.MaxTokens != nil {
+		req.MaxTokens = p.MaxTokens
+	}
+	if p.TopP != nil {
+		req.TopP = p.TopP
+	}
+	if p.Model != "" {
+		req.Model = p.Model
+	}
+	if p.Thinking != "" {
+		req.Thinking = p.Thinking
+	}
+}
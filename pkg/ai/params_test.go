@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerationParamsValidate(t *testing.T) {
+	temperature, topP, maxTokens := 0.7, 0.9, 512
+	tooHot, zeroTopP, noTokens := 2.5, 0.0, 0
+
+	tests := []struct {
+		name    string
+		params  GenerationParams
+		wantErr bool
+	}{
+		{"empty", GenerationParams{}, false},
+		{"valid", GenerationParams{Temperature: &temperature, TopP: &topP, MaxTokens: &maxTokens}, false},
+		{"temperature too high", GenerationParams{Temperature: &tooHot}, true},
+		{"zero topP", GenerationParams{TopP: &zeroTopP}, true},
+		{"zero maxTokens", GenerationParams{MaxTokens: &noTokens}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerationParamsApply(t *testing.T) {
+	temperature := 0.2
+	req := ChatCompletionRequest{Model: "glm-4"}
+	GenerationParams{Temperature: &temperature}.Apply(&req)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(body), `"temperature":0.2`) {
+		t.Errorf("request body %s should contain the temperature", body)
+	}
+	// Unset parameters are omitted so the provider default applies
+	if strings.Contains(string(body), "max_tokens") || strings.Contains(string(body), "top_p") {
+		t.Errorf("request body %s should omit unset parameters", body)
+	}
+}
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryConfig controls how a client retries transient failures: network
+// errors, 429 and 5xx responses. Other responses fail at once.
+type RetryConfig struct {
+	MaxRetries int           // Retries after the first attempt, 0 for none
+	BaseDelay  time.Duration // Delay before the first retry, doubled for each further one
+	MaxDelay   time.Duration // Upper bound on a single delay, including Retry-After
+}
+
+// DefaultRetryConfig returns the retry configuration of new clients
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: 2,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// ParseRetryConfig reads retry settings from a config map such as
+// {"maxRetries": 3, "baseDelay": "500ms", "maxDelay": "10s"}. Missing fields
+// keep their value from base.
+func ParseRetryConfig(base RetryConfig, values map[string]interface{}) (RetryConfig, error) {
+	config := base
+
+	if raw, ok := values["maxRetries"]; ok {
+		retries, ok := raw.(float64)
+		if !ok || retries < 0 || retries != float64(int(retries)) {
+			return config, fmt.Errorf("maxRetries must be a non-negative integer, got %v", raw)
+		}
+		config.MaxRetries = int(retries)
+	}
+
+	for field, target := range map[string]*time.Duration{"baseDelay": &config.BaseDelay, "maxDelay": &config.MaxDelay} {
+		raw, ok := values[field]
+		if !ok {
+			continue
+		}
+		text, _ := raw.(string)
+		duration, err := time.ParseDuration(text)
+		if err != nil || duration <= 0 {
+			return config, fmt.Errorf("%s must be a positive duration such as \"500ms\", got %v", field, raw)
+		}
+		*target = duration
+	}
+
+	return config, nil
+}
+
+// retryable reports whether a response status is worth retrying
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// delay is the wait before retry number attempt (0-based): the response's
+// Retry-After when it has one, else the exponential backoff
+func (c RetryConfig) delay(attempt int, resp *http.Response) time.Duration {
+	delay := c.BaseDelay << attempt
+	if resp != nil {
+		if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			delay = after
+		}
+	}
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	return delay
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// doWithRetry calls fn until it returns a response that isn't a transient
+// failure or the retries are used up, returning the last result. It doesn't
+// wait past ctx's deadline: when the next delay wouldn't fit, the last
+// result is returned right away.
+func doWithRetry(ctx context.Context, config RetryConfig, fn func() (*http.Response, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := fn()
+		if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+			return resp, err
+		}
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= config.MaxRetries {
+			return resp, err
+		}
+
+		delay := config.delay(attempt, resp)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// resendable returns a function sending req with client, rewinding the body
+// for every call after the first so the request can be retried
+func resendable(client *http.Client, req *http.Request) func() (*http.Response, error) {
+	first := true
+	return func() (*http.Response, error) {
+		if !first && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		first = false
+		return client.Do(req)
+	}
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusServer answers with the given statuses in turn, then 200 with a reply
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		if call <= len(statuses) {
+			http.Error(w, "try again", statuses[call-1])
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"recovered"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func fastRetries(retries int) RetryConfig {
+	return RetryConfig{MaxRetries: retries, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	server, calls := statusServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	client := NewZhipuClient("key", server.URL, "")
+	client.Retry = fastRetries(2)
+
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil || resp.Choices[0].Message.Content != "recovered" {
+		t.Fatalf("ChatCompletion() = %+v, %v, want the reply after retries", resp, err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	server, calls := statusServer(t, 502, 502, 502, 502)
+	client := NewOpenAICompatibleClient("key", server.URL, "model")
+	client.Retry = fastRetries(2)
+
+	_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("ChatCompletion() error = %v, want the last 502", err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want the first attempt and 2 retries", *calls)
+	}
+}
+
+func TestClientFailsFastOnAuthErrors(t *testing.T) {
+	server, calls := statusServer(t, http.StatusUnauthorized, http.StatusUnauthorized)
+	client := NewAnthropicCompatibleClient("key", server.URL, "model", WireFormatOpenAI)
+	client.Retry = fastRetries(3)
+
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{}); !IsAuthError(err) {
+		t.Errorf("ChatCompletion() error = %v, want the 401", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want no retries", *calls)
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	server, calls := statusServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	client := NewZhipuClient("key", server.URL, "")
+	client.Retry = RetryConfig{MaxRetries: 3, BaseDelay: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := client.ChatCompletion(ctx, ChatCompletionRequest{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ChatCompletion() took %s, want it to give up when the delay passes the deadline", elapsed)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || *calls != 1 {
+		t.Errorf("ChatCompletion() error = %v after %d calls, want the first 503", err, *calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	config := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		if got := config.delay(attempt, nil); got != want {
+			t.Errorf("delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	if got := config.delay(0, resp); got != 3*time.Second {
+		t.Errorf("delay with Retry-After: 3 = %s, want 3s", got)
+	}
+	resp.Header.Set("Retry-After", "120")
+	if got := config.delay(0, resp); got != 5*time.Second {
+		t.Errorf("delay with Retry-After: 120 = %s, want MaxDelay", got)
+	}
+	if got := config.delay(10, nil); got != 5*time.Second {
+		t.Errorf("delay(10) = %s, want MaxDelay", got)
+	}
+}
+
+func TestParseRetryConfig(t *testing.T) {
+	config, err := ParseRetryConfig(DefaultRetryConfig(), map[string]interface{}{"maxRetries": float64(4), "baseDelay": "1s"})
+	if err != nil {
+		t.Fatalf("ParseRetryConfig() error = %v", err)
+	}
+	if config.MaxRetries != 4 || config.BaseDelay != time.Second || config.MaxDelay != DefaultRetryConfig().MaxDelay {
+		t.Errorf("ParseRetryConfig() = %+v", config)
+	}
+
+	for _, values := range []map[string]interface{}{
+		{"maxRetries": float64(-1)},
+		{"maxRetries": 1.5},
+		{"baseDelay": "soon"},
+	} {
+		if _, err := ParseRetryConfig(DefaultRetryConfig(), values); err == nil {
+			t.Errorf("ParseRetryConfig(%v) should fail", values)
+		}
+	}
+}
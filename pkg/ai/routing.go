@@ -0,0 +1,213 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RoutingPolicy selects the provider for requests that don't name a known model
+type RoutingPolicy string
+
+const (
+	RoutingByName     RoutingPolicy = "name"        // Route by model name, falling back to any available provider
+	RoutingFastest    RoutingPolicy = "fastest"     // Prefer the healthy provider with the lowest p50 latency
+	RoutingRoundRobin RoutingPolicy = "round-robin" // Rotate through available providers
+)
+
+const (
+	// statsWindow is the number of recent calls kept per provider
+	statsWindow = 20
+	// minHealthySuccessRate is the success rate below which a provider is unhealthy
+	minHealthySuccessRate = 0.5
+)
+
+// ParseRoutingPolicy validates a policy name; "" selects RoutingByName
+func ParseRoutingPolicy(name string) (RoutingPolicy, error) {
+	switch policy := RoutingPolicy(name); policy {
+	case "":
+		return RoutingByName, nil
+	case RoutingByName, RoutingFastest, RoutingRoundRobin:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown routing policy: %q", name)
+	}
+}
+
+// ProviderRanking describes a provider's recent performance
+type ProviderRanking struct {
+	Name        string        `json:"name"`
+	State       BreakerState  `json:"state"`
+	Healthy     bool          `json:"healthy"`
+	SuccessRate float64       `json:"successRate"`
+	P50Latency  time.Duration `json:"p50LatencyNs"`
+	Samples     int           `json:"samples"`
+}
+
+// callSample is the outcome of a single provider call
+type callSample struct {
+	latency time.Duration
+	success bool
+}
+
+// providerStats keeps the most recent call outcomes of a provider and its
+// total token usage
+type providerStats struct {
+	mu      sync.Mutex
+	samples []callSample
+	usage   ProviderUsage
+}
+
+// ProviderUsage is the token usage of a provider since startup
+type ProviderUsage struct {
+	Requests         int `json:"requests"`
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// record adds a call outcome, dropping the oldest beyond statsWindow
+func (s *providerStats) record(latency time.Duration, success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = append(s.samples, callSample{latency: latency, success: success})
+	if len(s.samples) > statsWindow {
+		s.samples = s.samples[len(s.samples)-statsWindow:]
+	}
+}
+
+// addUsage adds the token usage of a successful call
+func (s *providerStats) addUsage(usage Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	s.usage.Requests++
+	s.usage.PromptTokens += usage.PromptTokens
+	s.usage.CompletionTokens += usage.CompletionTokens
+	s.usage.TotalTokens += total
+}
+
+// summary returns the success rate, median latency of successful calls and sample count
+func (s *providerStats) summary() (float64, time.Duration, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) == 0 {
+		return 1, 0, 0
+	}
+
+	var latencies []time.Duration
+	for _, sample := range s.samples {
+		if sample.success {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+
+	successRate := float64(len(latencies)) / float64(len(s.samples))
+	if len(latencies) == 0 {
+		return successRate, 0, len(s.samples)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return successRate, latencies[len(latencies)/2], len(s.samples)
+}
+
+// SetRoutingPolicy sets how requests without a known model are routed
+func (m *MultiProviderClient) SetRoutingPolicy(policy RoutingPolicy) error {
+	if _, err := ParseRoutingPolicy(string(policy)); err != nil {
+		return err
+	}
+	m.policy = policy
+	return nil
+}
+
+// RoutingPolicy returns the current routing policy
+func (m *MultiProviderClient) RoutingPolicy() RoutingPolicy {
+	if m.policy == "" {
+		return RoutingByName
+	}
+	return m.policy
+}
+
+// Rankings returns providers ordered by preference: healthy before unhealthy,
+// then by p50 latency. Providers without samples come first so they get measured.
+func (m *MultiProviderClient) Rankings() []ProviderRanking {
+	rankings := make([]ProviderRanking, 0, len(m.Providers))
+	for name := range m.Providers {
+		successRate, p50, samples := m.stats[name].summary()
+		state := m.breakers[name].State()
+		rankings = append(rankings, ProviderRanking{
+			Name:        name,
+			State:       state,
+			Healthy:     state != BreakerOpen && successRate >= minHealthySuccessRate,
+			SuccessRate: successRate,
+			P50Latency:  p50,
+			Samples:     samples,
+		})
+	}
+
+	sort.Slice(rankings, func(i, j int) bool {
+		a, b := rankings[i], rankings[j]
+		if a.Healthy != b.Healthy {
+			return a.Healthy
+		}
+		if a.P50Latency != b.P50Latency {
+			return a.P50Latency < b.P50Latency
+		}
+		return a.Name < b.Name
+	})
+	return rankings
+}
+
+// TokenUsage returns the token usage of each provider since startup
+func (m *MultiProviderClient) TokenUsage() map[string]ProviderUsage {
+	usage := make(map[string]ProviderUsage, len(m.stats))
+	for name, stats := range m.stats {
+		stats.mu.Lock()
+		usage[name] = stats.usage
+		stats.mu.Unlock()
+	}
+	return usage
+}
+
+// candidates returns provider names in the order the routing policy tries them
+func (m *MultiProviderClient) candidates() []string {
+	switch m.RoutingPolicy() {
+	case RoutingFastest:
+		names := make([]string, 0, len(m.Providers))
+		for _, ranking := range m.Rankings() {
+			names = append(names, ranking.Name)
+		}
+		return names
+	case RoutingRoundRobin:
+		names := make([]string, 0, len(m.Providers))
+		for name := range m.Providers {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return names
+		}
+		sort.Strings(names)
+
+		m.mu.Lock()
+		start := m.next % len(names)
+		m.next++
+		m.mu.Unlock()
+
+		rotated := make([]string, 0, len(names))
+		rotated = append(rotated, names[start:]...)
+		return append(rotated, names[:start]...)
+	default:
+		names := make([]string, 0, len(m.Providers))
+		for name := range m.Providers {
+			names = append(names, name)
+		}
+		return names
+	}
+}
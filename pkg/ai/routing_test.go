@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowClient replies after a fixed delay
+type slowClient struct {
+	fakeClient
+	delay time.Duration
+}
+
+func (s *slowClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	time.Sleep(s.delay)
+	return s.fakeClient.ChatCompletion(ctx, req)
+}
+
+func (s *slowClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	return nil, ErrStreamingUnsupported
+}
+
+func TestMultiProviderClientFastestPolicy(t *testing.T) {
+	slow := &slowClient{fakeClient: fakeClient{reply: "slow"}, delay: 40 * time.Millisecond}
+	fast := &slowClient{fakeClient: fakeClient{reply: "fast"}, delay: time.Millisecond}
+
+	client := NewMultiProviderClient()
+	if err := client.SetRoutingPolicy(RoutingFastest); err != nil {
+		t.Fatalf("SetRoutingPolicy() error = %v", err)
+	}
+	client.AddProvider("slow", slow)
+	client.AddProvider("fast", fast)
+
+	// Unmeasured providers are tried first, so both get a sample
+	req := ChatCompletionRequest{}
+	for i := 0; i < 2; i++ {
+		if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("warm-up call %d: %v", i, err)
+		}
+	}
+	if slow.calls != 1 || fast.calls != 1 {
+		t.Fatalf("warm-up calls = slow %d, fast %d; want 1 each", slow.calls, fast.calls)
+	}
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.ChatCompletion(context.Background(), req)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if got := resp.Choices[0].Message.Content; got != "fast" {
+			t.Errorf("call %d routed to %q, want fast", i, got)
+		}
+	}
+
+	rankings := client.Rankings()
+	if rankings[0].Name != "fast" || rankings[0].P50Latency >= rankings[1].P50Latency {
+		t.Errorf("rankings = %+v, want fast first", rankings)
+	}
+}
+
+func TestMultiProviderClientFastestSkipsUnhealthy(t *testing.T) {
+	failing := &fakeClient{err: errors.New("upstream error")}
+	slow := &slowClient{fakeClient: fakeClient{reply: "slow"}, delay: 5 * time.Millisecond}
+
+	client := NewMultiProviderClient()
+	client.SetRoutingPolicy(RoutingFastest)
+	client.AddProvider("failing", failing)
+	client.AddProvider("slow", slow)
+
+	// The failing provider answers instantly but never succeeds
+	for i := 0; i < 5; i++ {
+		client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+	}
+	if failing.calls != 1 {
+		t.Errorf("failing provider called %d times, want 1", failing.calls)
+	}
+	if rankings := client.Rankings(); rankings[1].Name != "failing" || rankings[1].Healthy {
+		t.Errorf("rankings = %+v, want failing last and unhealthy", rankings)
+	}
+}
+
+func TestMultiProviderClientRoundRobinPolicy(t *testing.T) {
+	a, b := &fakeClient{reply: "a"}, &fakeClient{reply: "b"}
+
+	client := NewMultiProviderClient()
+	client.SetRoutingPolicy(RoutingRoundRobin)
+	client.AddProvider("a", a)
+	client.AddProvider("b", b)
+
+	for i := 0; i < 4; i++ {
+		client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+	}
+	if a.calls != 2 || b.calls != 2 {
+		t.Errorf("calls = a %d, b %d; want 2 each", a.calls, b.calls)
+	}
+
+	// Requests naming a known model still route by name
+	zhipu := &fakeClient{reply: "zhipu"}
+	client.AddProvider("zhipu", zhipu)
+	for i := 0; i < 3; i++ {
+		client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "glm-4"})
+	}
+	if zhipu.calls != 3 {
+		t.Errorf("zhipu calls = %d, want 3", zhipu.calls)
+	}
+}
+
+func TestMultiProviderClientRoutesRegisteredModels(t *testing.T) {
+	qwen, deepseek := &fakeClient{reply: "qwen"}, &fakeClient{reply: "deepseek"}
+
+	client := NewMultiProviderClient()
+	client.AddProvider("qwen", qwen)
+	client.AddProvider("deepseek", deepseek)
+	// The name suggests qwen, the registration wins
+	client.RegisterModel("qwen-distill-r1", "deepseek")
+	client.RegisterModel("deepseek-chat", "deepseek")
+
+	for _, model := range []string{"qwen-distill-r1", "deepseek-chat"} {
+		resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: model})
+		if err != nil {
+			t.Fatalf("ChatCompletion(%s) error = %v", model, err)
+		}
+		if got := resp.Choices[0].Message.Content; got != "deepseek" {
+			t.Errorf("%s routed to %q, want deepseek", model, got)
+		}
+	}
+
+	// Unregistered models still route by name
+	client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "qwen-max"})
+	if qwen.calls != 1 {
+		t.Errorf("qwen calls = %d, want 1", qwen.calls)
+	}
+
+	if provider, ok := client.GetProvider("deepseek"); !ok || provider != deepseek {
+		t.Errorf("GetProvider(deepseek) = %v, %v; want the deepseek client", provider, ok)
+	}
+	if _, ok := client.GetProvider("missing"); ok {
+		t.Error("GetProvider(missing) found a provider")
+	}
+}
+
+func TestMultiProviderClientRoutesExplicitProvider(t *testing.T) {
+	minimax := &fakeClient{err: errors.New("upstream down")}
+	zhipu := &fakeClient{reply: "zhipu"}
+
+	client := NewMultiProviderClient()
+	client.AddProvider("minimax", minimax)
+	client.AddProvider("zhipu", zhipu)
+
+	// The named provider wins over the model's name
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "minimax-custom", Provider: "zhipu"})
+	if err != nil || resp.Choices[0].Message.Content != "zhipu" {
+		t.Fatalf("ChatCompletion(provider zhipu) = %v, %v; want zhipu's reply", resp, err)
+	}
+	if minimax.calls != 0 {
+		t.Errorf("minimax calls = %d, want 0", minimax.calls)
+	}
+
+	// A failing named provider doesn't fall back to the others
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Provider: "minimax"}); err == nil {
+		t.Error("expected minimax's error without fallback")
+	}
+	if zhipu.calls != 1 {
+		t.Errorf("zhipu calls = %d, want 1", zhipu.calls)
+	}
+
+	_, err = client.ChatCompletion(context.Background(), ChatCompletionRequest{Provider: "openai"})
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("unknown provider error = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func TestParseRoutingPolicy(t *testing.T) {
+	if policy, err := ParseRoutingPolicy(""); err != nil || policy != RoutingByName {
+		t.Errorf("ParseRoutingPolicy(\"\") = %q, %v; want name", policy, err)
+	}
+	if _, err := ParseRoutingPolicy("cheapest"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,374 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrStreamInterrupted is returned when a stream ends before the provider
+// finished the reply
+var ErrStreamInterrupted = errors.New("stream interrupted")
+
+// ErrStreamingUnsupported is returned by ChatCompletionStream of clients that
+// can't stream
+var ErrStreamingUnsupported = errors.New("streaming is not supported by this client")
+
+// StreamChunk is one piece of a streamed completion. The last chunk of a
+// stream has Done set when the reply finished, or Err when it failed; the
+// channel is closed after it.
+type StreamChunk struct {
+	Delta string
+	Done  bool
+	Err   error
+}
+
+// StreamError is the failure of a MultiProviderClient stream, naming the
+// provider that served it so a retry can avoid it
+type StreamError struct {
+	Provider string
+	Err      error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+// Unwrap returns the provider's error
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// StreamHandler receives each piece of text as it is streamed
+type StreamHandler func(delta string) error
+
+// StreamClient is the streaming half of Client, for code that only streams.
+// The returned error only reports a stream that couldn't start; failures
+// after that arrive as a chunk with Err. The channel is closed when the reply
+// finishes, fails or ctx is cancelled.
+type StreamClient interface {
+	ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error)
+}
+
+// CollectStream passes each delta of a stream to onDelta and returns the
+// whole text, even when an error cut the stream short. When onDelta fails
+// CollectStream returns early; cancel the stream's context to stop it.
+func CollectStream(chunks <-chan StreamChunk, onDelta StreamHandler) (string, error) {
+	var text strings.Builder
+	for chunk := range chunks {
+		if chunk.Delta != "" {
+			text.WriteString(chunk.Delta)
+			if err := onDelta(chunk.Delta); err != nil {
+				return text.String(), err
+			}
+		}
+		if chunk.Err != nil {
+			return text.String(), chunk.Err
+		}
+		if chunk.Done {
+			return text.String(), nil
+		}
+	}
+	return text.String(), fmt.Errorf("%w: stream closed before the reply finished", ErrStreamInterrupted)
+}
+
+// streamEvent parses the data of one server-sent event into its delta, and
+// reports whether the reply is finished
+type streamEvent func(data string) (delta string, done bool, err error)
+
+// openAIChunk is one server-sent event of an OpenAI-style stream
+type openAIChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// anthropicChunk is one server-sent event of an Anthropic messages stream
+type anthropicChunk struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicEvent parses Anthropic messages stream events, whose text comes
+// in content_block_delta events and which end with message_stop
+func anthropicEvent(data string) (string, bool, error) {
+	var chunk anthropicChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", false, fmt.Errorf("failed to decode stream chunk: %w", err)
+	}
+	switch chunk.Type {
+	case "content_block_delta":
+		if chunk.Delta.Type == "text_delta" {
+			return chunk.Delta.Text, false, nil
+		}
+	case "message_stop":
+		return "", true, nil
+	case "error":
+		return "", false, fmt.Errorf("stream error: %s", chunk.Error.Message)
+	}
+	return "", false, nil
+}
+
+// openAIEvent parses OpenAI-style stream events, which end with a
+// finish_reason or the [DONE] sentinel
+func openAIEvent(data string) (string, bool, error) {
+	if data == "[DONE]" {
+		return "", true, nil
+	}
+
+	var chunk openAIChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", false, fmt.Errorf("failed to decode stream chunk: %w", err)
+	}
+	var delta strings.Builder
+	done := false
+	for _, choice := range chunk.Choices {
+		delta.WriteString(choice.Delta.Content)
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			done = true
+		}
+	}
+	return delta.String(), done, nil
+}
+
+// startStream sends a streaming request, retrying transient failures, and
+// reads its server-sent events in the background, parsing each one's data
+// with parse
+func startStream(ctx context.Context, client *http.Client, retry RetryConfig, httpReq *http.Request, parse streamEvent) (<-chan StreamChunk, error) {
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// Only opening the stream is retried, before any text was delivered
+	resp, err := doWithRetry(ctx, retry, resendable(client, httpReq))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start stream: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+
+	chunks := make(chan StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+		readStream(ctx, resp.Body, parse, chunks)
+	}()
+	return chunks, nil
+}
+
+// readStream sends the chunks of an event stream until it finishes, fails or
+// ctx is cancelled
+func readStream(ctx context.Context, body io.Reader, parse streamEvent, chunks chan<- StreamChunk) {
+	send := func(chunk StreamChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		delta, done, err := parse(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		if err != nil {
+			send(StreamChunk{Err: err})
+			return
+		}
+		if delta != "" && !send(StreamChunk{Delta: delta}) {
+			return
+		}
+		if done {
+			send(StreamChunk{Done: true})
+			return
+		}
+	}
+
+	err := scanner.Err()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		send(StreamChunk{Err: fmt.Errorf("%w: %v", ErrStreamInterrupted, err)})
+		return
+	}
+	send(StreamChunk{Err: fmt.Errorf("%w: connection closed before the reply finished", ErrStreamInterrupted)})
+}
+
+// newStreamRequest builds a streaming chat completion request to endpoint
+func newStreamRequest(ctx context.Context, endpoint, apiKey string, req ChatCompletionRequest) (*http.Request, error) {
+	req.Stream = true
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// ChatCompletionStream streams a chat completion from an OpenAI-compatible API
+func (o *OpenAICompatibleClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	if req.Model == "" {
+		req.Model = o.Model
+	}
+	req = o.applyThinking(req)
+	req.Messages = prepareMessages(req.Messages, o.Vision)
+
+	endpoint := strings.TrimRight(o.BaseURL, "/") + "/chat/completions"
+	httpReq, err := newStreamRequest(ctx, endpoint, o.ApiKey, req)
+	if err != nil {
+		return nil, err
+	}
+	return startStream(ctx, o.Client, o.Retry, httpReq, openAIEvent)
+}
+
+// ChatCompletionStream streams a chat completion from Zhipu AI, whose stream
+// uses the OpenAI format
+func (z *ZhipuClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	if req.Model == "" {
+		req.Model = z.Model
+	}
+	req = withThinkingInstruction(req)
+	req.Messages = prepareMessages(req.Messages, z.SupportsVision())
+
+	httpReq, err := newStreamRequest(ctx, z.BaseURL, z.ApiKey, req)
+	if err != nil {
+		return nil, err
+	}
+	return startStream(ctx, z.Client, z.Retry, httpReq, openAIEvent)
+}
+
+// ChatCompletionStream streams from Minimax and other Anthropic-compatible
+// providers in the client's wire format, like ChatCompletion
+func (a *AnthropicCompatibleClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	if req.Model == "" {
+		req.Model = a.Model
+	}
+	req = a.applyThinking(req)
+	req.Messages = prepareMessages(req.Messages, false)
+	req.Stream = true
+
+	httpReq, err := a.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	parse := openAIEvent
+	if a.Format == WireFormatAnthropic {
+		parse = anthropicEvent
+	}
+	return startStream(ctx, a.Client, a.Retry, httpReq, parse)
+}
+
+// ChatCompletionStream streams a completion from the provider ChatCompletion
+// would route the request to, skipping providers that can't stream. Once a
+// stream has started it is not failed over, since text was already delivered;
+// a failure is reported as a StreamError naming the provider instead.
+func (m *MultiProviderClient) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest) (<-chan StreamChunk, error) {
+	// A stream holds its slot until it ends
+	release, err := m.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := m.route(ctx, req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	for _, name := range order {
+		if !m.breakers[name].Allow() {
+			continue
+		}
+
+		start := time.Now()
+		chunks, err := m.Providers[name].ChatCompletionStream(ctx, req)
+		if errors.Is(err, ErrStreamingUnsupported) {
+			continue
+		}
+		if err != nil {
+			m.recordStream(name, start, err)
+			release()
+			return nil, err
+		}
+		return m.trackStream(ctx, name, start, chunks, release), nil
+	}
+
+	release()
+	return nil, fmt.Errorf("no AI provider available for streaming")
+}
+
+// trackStream forwards a provider's stream and records its outcome on the
+// provider's breaker and stats once it ends, then calls release
+func (m *MultiProviderClient) trackStream(ctx context.Context, name string, start time.Time, chunks <-chan StreamChunk, release func()) <-chan StreamChunk {
+	tracked := make(chan StreamChunk)
+	go func() {
+		defer close(tracked)
+		defer release()
+
+		var err error = &StreamError{Provider: name, Err: fmt.Errorf("%w: stream closed before the reply finished", ErrStreamInterrupted)}
+		ended := false
+		for chunk := range chunks {
+			if chunk.Done {
+				err, ended = nil, true
+			} else if chunk.Err != nil {
+				chunk.Err = &StreamError{Provider: name, Err: chunk.Err}
+				err, ended = chunk.Err, true
+			}
+			select {
+			case tracked <- chunk:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if !ended && ctx.Err() == nil {
+			// Name the provider whose stream broke off
+			select {
+			case tracked <- StreamChunk{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+		m.recordStream(name, start, err)
+	}()
+	return tracked
+}
+
+// recordStream records the outcome of a stream on its provider
+func (m *MultiProviderClient) recordStream(name string, start time.Time, err error) {
+	m.stats[name].record(time.Since(start), err == nil)
+	if err != nil {
+		m.breakers[name].RecordFailure()
+	} else {
+		m.breakers[name].RecordSuccess()
+	}
+}
@@ -0,0 +1,272 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseServer streams the given deltas and, when finish is set, a final [DONE]
+func sseServer(t *testing.T, deltas []string, finish bool) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			t.Error("request should ask for a stream")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range deltas {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", delta)
+			w.(http.Flusher).Flush()
+		}
+		if finish {
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAICompatibleClientStream(t *testing.T) {
+	server := sseServer(t, []string{"Hello", ", ", "world"}, true)
+	client := NewOpenAICompatibleClient("key", server.URL, "model")
+
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	var deltas []string
+	text, err := CollectStream(chunks, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	if text != "Hello, world" || len(deltas) != 3 {
+		t.Errorf("text = %q from %d deltas, want %q from 3", text, len(deltas), "Hello, world")
+	}
+}
+
+func TestOpenAICompatibleClientStreamInterrupted(t *testing.T) {
+	server := sseServer(t, []string{"Hello", ", "}, false)
+	client := NewOpenAICompatibleClient("key", server.URL, "model")
+
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	text, err := CollectStream(chunks, func(string) error { return nil })
+	if !errors.Is(err, ErrStreamInterrupted) {
+		t.Fatalf("error = %v, want ErrStreamInterrupted", err)
+	}
+	if text != "Hello, " {
+		t.Errorf("partial text = %q, want %q", text, "Hello, ")
+	}
+}
+
+func TestMultiProviderClientStreamSkipsNonStreamingProviders(t *testing.T) {
+	server := sseServer(t, []string{"streamed"}, true)
+
+	m := NewMultiProviderClient()
+	m.AddProvider("zhipu", &fakeClient{reply: "not streamed"})
+	m.AddProvider("qwen", NewOpenAICompatibleClient("key", server.URL, "model"))
+
+	chunks, err := m.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "glm-4"})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	text, err := CollectStream(chunks, func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	if text != "streamed" {
+		t.Errorf("text = %q, want the streaming provider's reply", text)
+	}
+}
+
+func TestZhipuClientStream(t *testing.T) {
+	server := sseServer(t, []string{"你好", "!"}, true)
+	client := NewZhipuClient("key", server.URL, "glm-4")
+
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+
+	var got []StreamChunk
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+	if len(got) != 3 || got[0].Delta != "你好" || got[1].Delta != "!" || !got[2].Done {
+		t.Errorf("chunks = %+v, want two deltas and a final Done", got)
+	}
+}
+
+func TestStreamSurfacesMidStreamErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {not json\n\n")
+	}))
+	defer server.Close()
+	client := NewOpenAICompatibleClient("key", server.URL, "model")
+
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	text, err := CollectStream(chunks, func(string) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "decode stream chunk") {
+		t.Errorf("error = %v, want the decode failure", err)
+	}
+	if text != "Hel" {
+		t.Errorf("text = %q, want the partial %q", text, "Hel")
+	}
+}
+
+func TestAnthropicCompatibleClientStream(t *testing.T) {
+	server := sseServer(t, []string{"Hi", " there"}, true)
+	client := NewAnthropicCompatibleClient("key", server.URL, "MiniMax-M2.1", WireFormatOpenAI)
+
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	text, err := CollectStream(chunks, func(string) error { return nil })
+	if err != nil || text != "Hi there" {
+		t.Errorf("CollectStream() = %q, %v, want %q", text, err, "Hi there")
+	}
+}
+
+func TestAnthropicCompatibleClientStreamMessagesFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %q, want /v1/messages", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_1"}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewAnthropicCompatibleClient("key", server.URL+"/v1", "claude-3-sonnet-20240229", WireFormatAnthropic)
+	chunks, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	text, err := CollectStream(chunks, func(string) error { return nil })
+	if err != nil || text != "Hi there" {
+		t.Errorf("CollectStream() = %q, %v, want %q", text, err, "Hi there")
+	}
+}
+
+func TestMultiProviderClientStreamRoutesByModel(t *testing.T) {
+	qwen := sseServer(t, []string{"from qwen"}, true)
+	minimax := sseServer(t, []string{"from minimax"}, true)
+
+	m := NewMultiProviderClient()
+	m.AddProvider("qwen", NewOpenAICompatibleClient("key", qwen.URL, "coder-model"))
+	m.AddProvider("minimax", NewAnthropicCompatibleClient("key", minimax.URL, "MiniMax-M2.1", WireFormatOpenAI))
+
+	for model, want := range map[string]string{"MiniMax-M2.1": "from minimax", "coder-model": "from qwen"} {
+		chunks, err := m.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: model})
+		if err != nil {
+			t.Fatalf("ChatCompletionStream(%s) error = %v", model, err)
+		}
+		if text, _ := CollectStream(chunks, func(string) error { return nil }); text != want {
+			t.Errorf("ChatCompletionStream(%s) = %q, want %q", model, text, want)
+		}
+	}
+}
+
+func TestStreamStopsOnCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	m := NewMultiProviderClient()
+	m.AddProvider("qwen", NewOpenAICompatibleClient("key", server.URL, "coder-model"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks, err := m.ChatCompletionStream(ctx, ChatCompletionRequest{Model: "coder-model"})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	if chunk := <-chunks; chunk.Delta != "Hel" {
+		t.Fatalf("first chunk = %+v, want the delta", chunk)
+	}
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		for range chunks {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream channel not closed after the context was cancelled")
+	}
+}
+
+func TestMultiProviderClientStreamErrorNamesProvider(t *testing.T) {
+	qwen := sseServer(t, []string{"Hello, "}, false)
+	minimax := sseServer(t, []string{"from minimax"}, true)
+
+	m := NewMultiProviderClient()
+	m.AddProvider("qwen", NewOpenAICompatibleClient("key", qwen.URL, "coder-model"))
+	m.AddProvider("minimax", NewAnthropicCompatibleClient("key", minimax.URL, "MiniMax-M2.1", WireFormatOpenAI))
+
+	chunks, err := m.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "coder-model"})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	_, err = CollectStream(chunks, func(string) error { return nil })
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Provider != "qwen" || !errors.Is(err, ErrStreamInterrupted) {
+		t.Fatalf("error = %v, want an interrupted StreamError from qwen", err)
+	}
+
+	// Without the failed provider the same model routes to the other one
+	chunks, err = m.ChatCompletionStream(WithoutProviders(context.Background(), "qwen"), ChatCompletionRequest{Model: "coder-model"})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream() error = %v", err)
+	}
+	if text, _ := CollectStream(chunks, func(string) error { return nil }); text != "from minimax" {
+		t.Errorf("text = %q, want the other provider's reply", text)
+	}
+}
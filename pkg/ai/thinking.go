@@ -0,0 +1,90 @@
+package ai
+
+import "fmt"
+
+// Thinking levels: how much a model reasons before it answers
+const (
+	ThinkingOff     = "off"
+	ThinkingMinimal = "minimal"
+	ThinkingLow     = "low"
+	ThinkingMedium  = "medium"
+	ThinkingHigh    = "high"
+)
+
+// thinkingBudgets are the reasoning token budgets of the levels, for
+// providers that take a budget
+var thinkingBudgets = map[string]int{
+	ThinkingMinimal: 1024, // The smallest budget Anthropic accepts
+	ThinkingLow:     2048,
+	ThinkingMedium:  8192,
+	ThinkingHigh:    16384,
+}
+
+// thinkingInstructions stand in for the levels with models that have no
+// reasoning parameter
+var thinkingInstructions = map[string]string{
+	ThinkingOff:     "Answer directly, without working through your reasoning first.",
+	ThinkingMinimal: "Think only briefly before answering.",
+	ThinkingLow:     "Think the problem through briefly before answering.",
+	ThinkingMedium:  "Think the problem through step by step before answering.",
+	ThinkingHigh:    "Think the problem through carefully and thoroughly, step by step, and check your reasoning before answering.",
+}
+
+// ValidateThinkingLevel checks that level is one of the thinking levels; ""
+// leaves thinking to the provider default
+func ValidateThinkingLevel(level string) error {
+	if _, ok := thinkingInstructions[level]; ok || level == "" {
+		return nil
+	}
+	return fmt.Errorf("thinking must be %q, %q, %q, %q or %q, got %q",
+		ThinkingOff, ThinkingMinimal, ThinkingLow, ThinkingMedium, ThinkingHigh, level)
+}
+
+// ThinkingBudget returns the reasoning token budget of level, 0 when it is
+// off or unset
+func ThinkingBudget(level string) int {
+	return thinkingBudgets[level]
+}
+
+// ReasoningEffort returns the OpenAI reasoning_effort of level, "" when it is
+// off or unset. Minimal maps to low, the least effort all reasoning models
+// accept.
+func ReasoningEffort(level string) string {
+	switch level {
+	case ThinkingMinimal, ThinkingLow:
+		return "low"
+	case ThinkingMedium, ThinkingHigh:
+		return level
+	}
+	return ""
+}
+
+// ThinkingInstruction returns the prompt text standing in for level with
+// models that have no reasoning parameter, "" when it is unset
+func ThinkingInstruction(level string) string {
+	return thinkingInstructions[level]
+}
+
+// withThinkingInstruction moves the request's thinking level into the
+// prompt, appended to the leading system message or as a new one, for
+// clients that can't send it as a parameter
+func withThinkingInstruction(req ChatCompletionRequest) ChatCompletionRequest {
+	instruction := ThinkingInstruction(req.Thinking)
+	req.Thinking = ""
+	if instruction == "" {
+		return req
+	}
+
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+		system := req.Messages[0]
+		system.Content += "\n\n" + instruction
+		messages = append(messages, system)
+		messages = append(messages, req.Messages[1:]...)
+	} else {
+		messages = append(messages, Message{Role: "system", Content: instruction})
+		messages = append(messages, req.Messages...)
+	}
+	req.Messages = messages
+	return req
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureOpenAI serves OpenAI-format completions and keeps the last request
+// body
+func captureOpenAI(t *testing.T, body *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*body = nil
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			t.Errorf("request body is invalid: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// systemPrompt returns the content of the leading system message of body
+func systemPrompt(body map[string]interface{}) string {
+	messages, _ := body["messages"].([]interface{})
+	if len(messages) == 0 {
+		return ""
+	}
+	first, _ := messages[0].(map[string]interface{})
+	if first["role"] != "system" {
+		return ""
+	}
+	content, _ := first["content"].(string)
+	return content
+}
+
+func TestThinkingLevelOnReasoningModel(t *testing.T) {
+	var body map[string]interface{}
+	client := NewOpenAICompatibleClient("key", captureOpenAI(t, &body).URL, "o3-mini")
+	client.Reasoning = true
+
+	want := map[string]interface{}{
+		ThinkingOff:     nil,
+		ThinkingMinimal: "low",
+		ThinkingLow:     "low",
+		ThinkingMedium:  "medium",
+		ThinkingHigh:    "high",
+	}
+	for level, effort := range want {
+		req := ChatCompletionRequest{Messages: []Message{{Role: "user", Content: "Hi"}}}
+		GenerationParams{Thinking: level}.Apply(&req)
+		if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("%s: ChatCompletion() error = %v", level, err)
+		}
+		if got := body["reasoning_effort"]; got != effort {
+			t.Errorf("%s: reasoning_effort = %v, want %v", level, got, effort)
+		}
+		if prompt := systemPrompt(body); prompt != "" {
+			t.Errorf("%s: reasoning model got a system instruction %q", level, prompt)
+		}
+	}
+}
+
+func TestThinkingLevelAsInstruction(t *testing.T) {
+	var body map[string]interface{}
+	client := NewOpenAICompatibleClient("key", captureOpenAI(t, &body).URL, "qwen-plus")
+
+	for _, level := range []string{ThinkingOff, ThinkingMinimal, ThinkingLow, ThinkingMedium, ThinkingHigh} {
+		req := ChatCompletionRequest{Messages: []Message{
+			{Role: "system", Content: "You are Goclaw."},
+			{Role: "user", Content: "Hi"},
+		}}
+		GenerationParams{Thinking: level}.Apply(&req)
+		if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("%s: ChatCompletion() error = %v", level, err)
+		}
+		if _, ok := body["reasoning_effort"]; ok {
+			t.Errorf("%s: reasoning_effort sent to a model without reasoning", level)
+		}
+		prompt := systemPrompt(body)
+		if !strings.HasPrefix(prompt, "You are Goclaw.") || !strings.HasSuffix(prompt, ThinkingInstruction(level)) {
+			t.Errorf("%s: system prompt = %q, want the instruction appended", level, prompt)
+		}
+	}
+}
+
+func TestThinkingLevelAnthropicBudget(t *testing.T) {
+	for _, level := range []string{ThinkingMinimal, ThinkingLow, ThinkingMedium, ThinkingHigh} {
+		req := newAnthropicMessageRequest(ChatCompletionRequest{
+			Messages: []Message{{Role: "user", Content: "Hi"}},
+			Thinking: level,
+		})
+		if req.Thinking == nil || req.Thinking.BudgetTokens != ThinkingBudget(level) {
+			t.Errorf("%s: thinking = %+v, want a budget of %d", level, req.Thinking, ThinkingBudget(level))
+		} else if req.MaxTokens <= req.Thinking.BudgetTokens {
+			t.Errorf("%s: max_tokens %d leaves no room after the budget", level, req.MaxTokens)
+		}
+	}
+	if req := newAnthropicMessageRequest(ChatCompletionRequest{Thinking: ThinkingOff}); req.Thinking != nil {
+		t.Errorf("off: thinking = %+v, want none", req.Thinking)
+	}
+}
+
+func TestValidateThinkingLevel(t *testing.T) {
+	if err := (GenerationParams{Thinking: "extreme"}).Validate(); err == nil {
+		t.Error("expected an unknown thinking level to be invalid")
+	}
+	if err := (GenerationParams{Thinking: ThinkingHigh}).Validate(); err != nil {
+		t.Errorf("Validate(high) = %v", err)
+	}
+}
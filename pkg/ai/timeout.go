@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Use cases that get their own AI call timeout
+const (
+	UseInteractive = "interactive" // Chat replies, including streamed ones
+	UseBatch       = "batch"       // Each item of a batch or eval run
+	UseHeartbeat   = "heartbeat"   // Heartbeat runs
+	UseCron        = "cron"        // Generations triggered by cron tasks
+)
+
+// DefaultTimeouts are the AI call timeouts of each use case when none is configured
+var DefaultTimeouts = map[string]time.Duration{
+	UseInteractive: 120 * time.Second,
+	UseBatch:       30 * time.Second,
+	UseHeartbeat:   60 * time.Second,
+	UseCron:        60 * time.Second,
+}
+
+// TimeoutError is returned when an AI call runs past its use case's timeout,
+// so callers can tell it apart from provider failures
+type TimeoutError struct {
+	UseCase string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s AI call timed out after %s", e.UseCase, e.Timeout)
+}
+
+// Unwrap lets errors.Is match context.DeadlineExceeded
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ResolveTimeout parses a configured timeout such as "90s", falling back to
+// the use case's default when it is empty
+func ResolveTimeout(useCase, configured string) (time.Duration, error) {
+	if configured == "" {
+		return DefaultTimeouts[useCase], nil
+	}
+	timeout, err := time.ParseDuration(configured)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s timeout: %w", useCase, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid %s timeout: must be positive", useCase)
+	}
+	return timeout, nil
+}
+
+// TimeoutErr converts the error of a call made with ctx into a *TimeoutError
+// when ctx's deadline passed; other errors are returned unchanged
+func TimeoutErr(ctx context.Context, err error, useCase string, timeout time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{UseCase: useCase, Timeout: timeout}
+	}
+	return err
+}
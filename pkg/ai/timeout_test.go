@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolveTimeout(t *testing.T) {
+	if got, err := ResolveTimeout(UseBatch, ""); err != nil || got != DefaultTimeouts[UseBatch] {
+		t.Errorf("ResolveTimeout(batch, \"\") = %s, %v, want the default", got, err)
+	}
+	if got, err := ResolveTimeout(UseHeartbeat, "90s"); err != nil || got != 90*time.Second {
+		t.Errorf("ResolveTimeout(heartbeat, 90s) = %s, %v, want 90s", got, err)
+	}
+	for _, bad := range []string{"soon", "-1s", "0s"} {
+		if _, err := ResolveTimeout(UseCron, bad); err == nil {
+			t.Errorf("ResolveTimeout(cron, %q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestTimeoutErr(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	err := TimeoutErr(ctx, errors.New("request failed"), UseBatch, time.Millisecond)
+	var timedOut *TimeoutError
+	if !errors.As(err, &timedOut) || timedOut.UseCase != UseBatch {
+		t.Fatalf("TimeoutErr = %v, want a batch *TimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("TimeoutErr = %v, want it to match context.DeadlineExceeded", err)
+	}
+
+	other := errors.New("bad gateway")
+	if err := TimeoutErr(context.Background(), other, UseBatch, time.Second); err != other {
+		t.Errorf("TimeoutErr = %v, want other errors unchanged", err)
+	}
+}
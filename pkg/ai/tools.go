@@ -0,0 +1,100 @@
+package ai
+
+import "encoding/json"
+
+// How providers express tool calls, as reported by ToolCallFormatOf
+const (
+	ToolCallFormatText      = "text"      // JSON written in the reply, for models without native tool calls
+	ToolCallFormatOpenAI    = "openai"    // tool_calls on the message, used by Zhipu, Qwen and Minimax too
+	ToolCallFormatAnthropic = "anthropic" // tool_use content blocks
+)
+
+// ToolDefinition describes a tool the model may call natively. It encodes
+// as an OpenAI function tool; clients of other formats convert it.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema of the arguments
+}
+
+// MarshalJSON encodes the definition as an OpenAI function tool
+func (d ToolDefinition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        d.Name,
+			"description": d.Description,
+			"parameters":  d.Parameters,
+		},
+	})
+}
+
+// ToolCall is a native tool call in the OpenAI tool_calls shape
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"` // "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function a ToolCall calls
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // The arguments as a JSON object
+}
+
+// ToolCaller is implemented by clients whose providers call tools natively
+type ToolCaller interface {
+	// ToolCallFormat returns the tool-call format of the provider serving model
+	ToolCallFormat(model string) string
+}
+
+// ToolCallFormatOf returns the tool-call format client uses for model:
+// ToolCallFormatText for clients that don't implement ToolCaller
+func ToolCallFormatOf(client Client, model string) string {
+	if caller, ok := client.(ToolCaller); ok {
+		if format := caller.ToolCallFormat(model); format != "" {
+			return format
+		}
+	}
+	return ToolCallFormatText
+}
+
+// ToolCallFormat reports that Zhipu AI takes OpenAI tool calls
+func (z *ZhipuClient) ToolCallFormat(model string) string {
+	return ToolCallFormatOpenAI
+}
+
+// ToolCallFormat reports that OpenAI-compatible APIs take OpenAI tool calls
+func (o *OpenAICompatibleClient) ToolCallFormat(model string) string {
+	return ToolCallFormatOpenAI
+}
+
+// ToolCallFormat returns the format matching the client's wire format
+func (a *AnthropicCompatibleClient) ToolCallFormat(model string) string {
+	if a.Format == WireFormatAnthropic {
+		return ToolCallFormatAnthropic
+	}
+	return ToolCallFormatOpenAI
+}
+
+// ToolCallFormat returns the format of the provider serving model. A model
+// no provider is known for may be routed to any of them, so it gets a
+// native format only when all providers share it.
+func (m *MultiProviderClient) ToolCallFormat(model string) string {
+	if client, ok := m.Providers[m.providerFor(model)]; ok {
+		return ToolCallFormatOf(client, model)
+	}
+
+	format := ""
+	for _, client := range m.Providers {
+		providerFormat := ToolCallFormatOf(client, model)
+		if format != "" && providerFormat != format {
+			return ToolCallFormatText
+		}
+		format = providerFormat
+	}
+	if format == "" {
+		return ToolCallFormatText
+	}
+	return format
+}
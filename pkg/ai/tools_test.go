@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var weatherTool = ToolDefinition{
+	Name:        "weather",
+	Description: "Current weather of a city",
+	Parameters: map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		"required":   []string{"city"},
+	},
+}
+
+func TestOpenAIToolCallsRoundTrip(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"model": "qwen-max",
+			"choices": [{
+				"index": 0,
+				"message": {
+					"role": "assistant",
+					"content": null,
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\": \"Paris\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewOpenAICompatibleClient("key", server.URL, "qwen-max")
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{
+		Messages: []Message{
+			{Role: "user", Content: "Weather in Paris?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Type: "function", Function: ToolCallFunction{Name: "weather", Arguments: `{"city":"Rome"}`}}}},
+			{Role: "tool", ToolCallID: "call_0", Content: "sunny"},
+		},
+		Tools: []ToolDefinition{weatherTool},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+
+	tools, _ := body["tools"].([]interface{})
+	if len(tools) != 1 || !strings.Contains(mustJSON(t, tools[0]), `"type":"function"`) || !strings.Contains(mustJSON(t, tools[0]), `"name":"weather"`) {
+		t.Errorf("tools = %v, want the weather function", body["tools"])
+	}
+	messages, _ := body["messages"].([]interface{})
+	if len(messages) != 3 || !strings.Contains(mustJSON(t, messages[1]), `"tool_calls"`) || !strings.Contains(mustJSON(t, messages[2]), `"tool_call_id":"call_0"`) {
+		t.Errorf("messages = %v, want the call and its result sent", body["messages"])
+	}
+
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"city": "Paris"}` {
+		t.Errorf("tool calls = %+v, want call_1 with its arguments", calls)
+	}
+}
+
+func TestAnthropicToolUseRoundTrip(t *testing.T) {
+	var req struct {
+		Tools    []AnthropicTool `json:"tools"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-3-sonnet-20240229",
+			"content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 20, "output_tokens": 8}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewAnthropicCompatibleClient("key", server.URL, "", WireFormatAnthropic)
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{
+		Messages: []Message{
+			{Role: "user", Content: "Weather in Paris?"},
+			{Role: "user", Blocks: []AnthropicContent{{Type: "tool_result", ToolUseID: "toolu_0", Content: "sunny"}}},
+		},
+		Tools: []ToolDefinition{weatherTool},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+
+	if len(req.Tools) != 1 || req.Tools[0].Name != "weather" || req.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("tools = %+v, want weather with its input schema", req.Tools)
+	}
+	if len(req.Messages) != 2 || string(req.Messages[0].Content) != `"Weather in Paris?"` ||
+		string(req.Messages[1].Content) != `[{"type":"tool_result","tool_use_id":"toolu_0","content":"sunny"}]` {
+		t.Errorf("messages = %+v, want text content and a tool_result block", req.Messages)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Let me check." || choice.FinishReason != "tool_calls" || len(choice.Message.Blocks) != 2 {
+		t.Fatalf("choice = %+v, want the text with the tool_use block kept", choice)
+	}
+	if block := choice.Message.Blocks[1]; block.ID != "toolu_1" || block.Name != "weather" || string(block.Input) != `{"city": "Paris"}` {
+		t.Errorf("tool_use block = %+v", block)
+	}
+}
+
+func TestToolCallFormatOf(t *testing.T) {
+	multi := NewMultiProviderClient()
+	multi.AddProvider("qwen", NewOpenAICompatibleClient("key", "", "qwen-max"))
+	multi.AddProvider("claude", NewAnthropicCompatibleClient("key", "", "", WireFormatAnthropic))
+	multi.RegisterModel("qwen-max", "qwen")
+	multi.RegisterModel("claude-3-sonnet", "claude")
+
+	tests := []struct {
+		client Client
+		model  string
+		want   string
+	}{
+		{NewZhipuClient("key", "", ""), "glm-4", ToolCallFormatOpenAI},
+		{NewAnthropicCompatibleClient("key", "", "", WireFormatOpenAI), "MiniMax-M2.1", ToolCallFormatOpenAI},
+		{NewGeminiClient("key", "", ""), "gemini-pro", ToolCallFormatText},
+		{multi, "qwen-max", ToolCallFormatOpenAI},
+		{multi, "claude-3-sonnet", ToolCallFormatAnthropic},
+		{multi, "mystery-model", ToolCallFormatText}, // Could go to either provider
+	}
+	for _, tt := range tests {
+		if got := ToolCallFormatOf(tt.client, tt.model); got != tt.want {
+			t.Errorf("ToolCallFormatOf(%T, %q) = %q, want %q", tt.client, tt.model, got, tt.want)
+		}
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return string(data)
+}
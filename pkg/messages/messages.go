@@ -2,6 +2,9 @@
 package messages
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -36,8 +39,10 @@ type Session struct {
 	Title     string    `json:"title,omitempty"`
 }
 
-// Manager handles message and session operations
+// Manager handles message and session operations. It is safe for
+// concurrent use; sessions it returns are copies.
 type Manager struct {
+	mu       sync.RWMutex
 	sessions map[string]*Session
 }
 
@@ -48,8 +53,11 @@ func NewManager() *Manager {
 	}
 }
 
-// CreateSession creates a new session
+// CreateSession creates a new session, replacing any session with the same ID
 func (m *Manager) CreateSession(id, model string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	session := &Session{
 		ID:        id,
 		CreatedAt: time.Now(),
@@ -59,24 +67,33 @@ func (m *Manager) CreateSession(id, model string) *Session {
 		Active:    true,
 	}
 	m.sessions[id] = session
-	return session
+	return session.clone()
 }
 
-// GetSession retrieves a session by ID
+// GetSession retrieves a copy of a session by ID
 func (m *Manager) GetSession(id string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	session, exists := m.sessions[id]
-	return session, exists
+	if !exists {
+		return nil, false
+	}
+	return session.clone(), true
 }
 
 // AddMessage adds a message to a session
 func (m *Manager) AddMessage(sessionID string, role, content string) error {
-	session, exists := m.GetSession(sessionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, exists := m.sessions[sessionID]
 	if !exists {
 		return ErrSessionNotFound
 	}
 
 	message := Message{
-		ID:        generateID(), // In a real implementation, this would use a proper ID generator
+		ID:        generateID(),
 		SessionID: sessionID,
 		Role:      role,
 		Content:   content,
@@ -91,7 +108,10 @@ func (m *Manager) AddMessage(sessionID string, role, content string) error {
 
 // GetMessage retrieves a specific message by ID from a session
 func (m *Manager) GetMessage(sessionID, messageID string) (*Message, error) {
-	session, exists := m.GetSession(sessionID)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, exists := m.sessions[sessionID]
 	if !exists {
 		return nil, ErrSessionNotFound
 	}
@@ -105,20 +125,32 @@ func (m *Manager) GetMessage(sessionID, messageID string) (*Message, error) {
 	return nil, ErrMessageNotFound
 }
 
-// ListMessages returns all messages in a session
+// ListMessages returns a copy of all messages in a session
 func (m *Manager) ListMessages(sessionID string) ([]Message, error) {
-	session, exists := m.GetSession(sessionID)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, exists := m.sessions[sessionID]
 	if !exists {
 		return nil, ErrSessionNotFound
 	}
 
-	return session.Messages, nil
+	return append([]Message(nil), session.Messages...), nil
 }
 
-// generateID generates a unique ID (placeholder implementation)
+// clone copies a session so callers can read it without holding the lock
+func (s *Session) clone() *Session {
+	copied := *s
+	copied.Messages = append([]Message{}, s.Messages...)
+	return &copied
+}
+
+// idCounter makes IDs generated in the same nanosecond unique
+var idCounter uint64
+
+// generateID generates a message ID that is unique within the process
 func generateID() string {
-	// In a real implementation, this would use a proper UUID generator
-	return "msg_" + time.Now().String()
+	return fmt.Sprintf("msg_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&idCounter, 1))
 }
 
 // Errors
@@ -0,0 +1,66 @@
+package messages
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestManagerConcurrentSessionsAndMessages(t *testing.T) {
+	m := NewManager()
+
+	const sessions, messages = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		id := fmt.Sprintf("s%d", i)
+		m.CreateSession(id, "glm-4")
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				if err := m.AddMessage(id, "user", fmt.Sprintf("message %d", j)); err != nil {
+					t.Errorf("AddMessage(%s) error = %v", id, err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				m.CreateSession(fmt.Sprintf("%s-extra-%d", id, j), "glm-4")
+				m.GetSession(id)
+				m.ListMessages(id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ids := make(map[string]bool)
+	for i := 0; i < sessions; i++ {
+		list, err := m.ListMessages(fmt.Sprintf("s%d", i))
+		if err != nil || len(list) != messages {
+			t.Fatalf("ListMessages(s%d) = %d messages, %v; want %d", i, len(list), err, messages)
+		}
+		for _, msg := range list {
+			if ids[msg.ID] {
+				t.Fatalf("duplicate message ID %s", msg.ID)
+			}
+			ids[msg.ID] = true
+		}
+	}
+}
+
+func TestManagerReturnsCopies(t *testing.T) {
+	m := NewManager()
+	m.CreateSession("s1", "glm-4")
+	m.AddMessage("s1", "user", "hello")
+
+	session, _ := m.GetSession("s1")
+	session.Messages[0].Content = "changed"
+	session.Messages = append(session.Messages, Message{Content: "extra"})
+
+	list, _ := m.ListMessages("s1")
+	if len(list) != 1 || list[0].Content != "hello" {
+		t.Errorf("messages = %+v, want the stored session unchanged", list)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"goclaw/internal/chat"
+	"goclaw/internal/intent"
 	"goclaw/internal/tools"
 )
 
@@ -77,15 +78,9 @@ func (ts *TestSuite) generateTestResponse(input, sessionID string) string {
 	// 在测试环境中，我们简化处理，直接检测意图并执行工具
 
 	// 检测"读取文件前N行"的意图
-	if ts.containsFileReadIntent(inputLower) && strings.Contains(input, "/") {
-		// 提取文件路径
-		filePath := ts.extractFilePath(input)
-		if filePath != "" {
-			// 根据请求确定行数
-			lineCount := ts.extractLineCount(input)
-			// 直接执行工具并返回结果
-			return ts.executeToolAndFormatResult(filePath, lineCount)
-		}
+	if in := ts.intentClassifier.Classify(input); in.Action == intent.ActionReadLines {
+		// 直接执行工具并返回结果
+		return ts.executeToolAndFormatResult(in.Path, in.LineCount)
 	}
 
 	// 简单的测试响应逻辑
@@ -109,149 +104,6 @@ func (ts *TestSuite) generateTestResponse(input, sessionID string) string {
 	return fmt.Sprintf("我收到了你的消息：%s\n这是测试环境下的模拟响应。", input)
 }
 
-// containsFileReadIntent checks if the input contains file reading intent
-func (ts *TestSuite) containsFileReadIntent(inputLower string) bool {
-	// 检查是否包含读取文件的关键词组合
-	reads := []string{"展示", "显示", "读取", "查看", "看看", "读", "打开"}
-	lines := []string{"前", "前几", "开头", "第一", "头", "几行", "行", "内容"}
-
-	// 检查是否存在读取关键词
-	hasReadKeyword := false
-	for _, read := range reads {
-		if strings.Contains(inputLower, strings.ToLower(read)) {
-			hasReadKeyword = true
-			break
-		}
-	}
-
-	if !hasReadKeyword {
-		return false
-	}
-
-	// 检查是否存在行数相关关键词
-	for _, line := range lines {
-		if strings.Contains(inputLower, strings.ToLower(line)) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// extractLineCount extracts the number of lines to read from input
-func (ts *TestSuite) extractLineCount(input string) int {
-	// 默认3行
-	defaultLines := 3
-
-	// 检查是否明确指定了行数
-	if strings.Contains(input, "前1行") || strings.Contains(input, "第一行") {
-		return 1
-	} else if strings.Contains(input, "前2行") || strings.Contains(input, "前两行") {
-		return 2
-	} else if strings.Contains(input, "前3行") || strings.Contains(input, "前三行") {
-		return 3
-	} else if strings.Contains(input, "前4行") || strings.Contains(input, "前四行") {
-		return 4
-	} else if strings.Contains(input, "前5行") || strings.Contains(input, "前五行") {
-		return 5
-	} else if strings.Contains(input, "开头几行") || strings.Contains(input, "前几行") {
-		return 3 // 默认3行
-	}
-
-	return defaultLines
-}
-
-// extractFilePath extracts file path from user input
-func (ts *TestSuite) extractFilePath(input string) string {
-	// 查找 / 开头的路径
-	startIdx := strings.Index(input, "/")
-	if startIdx == -1 {
-		return ""
-	}
-
-	// 从起始位置开始寻找路径结束位置
-	endIdx := len(input)
-	
-	// 查找所有可能的结束位置，并选择最早的
-	possibleEnds := []int{}
-	
-	// 高优先级：明确的结束标志
-	// 查找 "只要" (例如 "...只要前三行")
-	if idx := strings.Index(input[startIdx:], "只要"); idx != -1 {
-		possibleEnds = append(possibleEnds, startIdx+idx)
-	}
-	
-	// 查找 "，只要" (例如 "...，只要前三行")
-	if idx := strings.Index(input[startIdx:], "，只要"); idx != -1 {
-		possibleEnds = append(possibleEnds, startIdx+idx)
-	}
-	
-	// 中等优先级：特定模式 - 更精确地匹配
-	// 查找包含"文件"的模式
-	if idx := strings.Index(input[startIdx:], "文件的前"); idx != -1 {
-		possibleEnds = append(possibleEnds, startIdx+idx)
-	}
-	
-	if idx := strings.Index(input[startIdx:], "文件的开头几行"); idx != -1 {
-		possibleEnds = append(possibleEnds, startIdx+idx)
-	}
-	
-	if idx := strings.Index(input[startIdx:], "文件的第一部分"); idx != -1 {
-		possibleEnds = append(possibleEnds, startIdx+idx)
-	}
-	
-	if idx := strings.Index(input[startIdx:], "这个文件"); idx != -1 {
-		possibleEnds = append(possibleEnds, startIdx+idx)
-	}
-	
-	// 查找通用模式，但要小心避免扩展名误匹配
-	// 查找 "的" + 数字 + "行" 模式（但要确保不是在文件名扩展中）
-	remaining := input[startIdx:]
-	for i := 0; i < len(remaining)-5; i++ {
-		if remaining[i:i+1] == "的" {
-			// 检查后面是否有数字和"行"
-			afterOf := remaining[i+1:]
-			if len(afterOf) >= 2 {
-				// 检查第一个字符是否是数字
-				firstChar := afterOf[0:1]
-				if firstChar >= "0" && firstChar <= "9" {
-					// 检查是否包含"行"
-					if strings.Contains(afterOf, "行") {
-						// 检查是否可能在路径扩展名中（如".txt的"）
-						// 如果"的"前是字母数字，则可能是在扩展名中
-						if i > 0 {
-							prevChar := remaining[i-1:i]
-							// 如果前一个字符是"."，那么很可能是路径扩展名
-							if prevChar == "." {
-								// 这是典型的路径结束标志，如 "path.txt的前3行"
-								possibleEnds = append(possibleEnds, startIdx+i) // 在"的"处结束
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-	
-	// 句子结束符（较低优先级）
-	for i := startIdx; i < len(input); i++ {
-		if input[i:i+1] == "。" || input[i:i+1] == "." {
-			possibleEnds = append(possibleEnds, i)
-			break
-		}
-	}
-	
-	// 选择最早出现的结束位置
-	for _, pos := range possibleEnds {
-		if pos > startIdx && pos < endIdx { // 确保有效位置
-			endIdx = pos
-		}
-	}
-
-	filePath := input[startIdx:endIdx]
-	return strings.TrimSpace(filePath)
-}
-
 // executeToolAndFormatResult executes a tool and formats the result
 func (ts *TestSuite) executeToolAndFormatResult(filePath string, lineCount int) string {
 	// 创建执行器
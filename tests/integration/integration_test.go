@@ -14,6 +14,7 @@ import (
 
 	"goclaw/internal/chat"
 	"goclaw/internal/config"
+	"goclaw/internal/intent"
 	"goclaw/internal/memory"
 	"goclaw/internal/tools"
 	"goclaw/internal/tools/builtin"
@@ -21,13 +22,14 @@ import (
 
 // TestSuite represents the integration test suite
 type TestSuite struct {
-	server       *httptest.Server
-	chatManager  *chat.ChatManager
-	memoryStore  *memory.MemoryStore
-	toolsRegistry *tools.Registry
-	toolsManager *builtin.Manager
-	cfg          *config.Config
-	baseURL      string
+	server           *httptest.Server
+	chatManager      *chat.ChatManager
+	memoryStore      *memory.MemoryStore
+	toolsRegistry    *tools.Registry
+	toolsManager     *builtin.Manager
+	intentClassifier *intent.IntentClassifier
+	cfg              *config.Config
+	baseURL          string
 }
 
 // SetupTestSuite creates a new test suite with all necessary components
@@ -45,14 +47,15 @@ func SetupTestSuite(t *testing.T) *TestSuite {
 
 	// Initialize memory store
 	suite.memoryStore = memory.NewMemoryStore(memory.MemoryConfig{
-		ShortTermMax:   50,
-		WorkingMax:     10,
-		SimilarityCut:  0.7,
+		ShortTermMax:  50,
+		WorkingMax:    10,
+		SimilarityCut: 0.7,
 	})
 
 	// Initialize tools
 	suite.toolsManager = builtin.NewManager()
 	suite.toolsRegistry = suite.toolsManager.GetRegistry()
+	suite.intentClassifier = intent.NewIntentClassifier()
 
 	// Create test server
 	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {